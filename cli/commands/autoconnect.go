@@ -25,6 +25,7 @@ package commands
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/ivpn/desktop-app/cli/flags"
@@ -36,6 +37,7 @@ type CmdAutoConnect struct {
 	flags.CmdInfo
 	status        bool
 	on_launch_val string // on/off
+	profile       string // name of connection profile ('none' - use last connection parameters)
 }
 
 func (c *CmdAutoConnect) Init() {
@@ -44,6 +46,7 @@ func (c *CmdAutoConnect) Init() {
 	c.Initialize("autoconnect", "Manage VPN auto-connection parameters")
 	c.BoolVar(&c.status, "status", false, "(default) Show settings")
 	c.StringVar(&c.on_launch_val, "on_launch", "", "[on/off]", "Autoconnect on daemon launch\nThis enables the VPN tunnel to startup as quickly as possible\nas the daemon is started early in the operating system boot process\nand before the IVPN app (The GUI)")
+	c.StringVar(&c.profile, "profile", "", "NAME", "Connection profile to use for automatic connections\n(use 'none' to connect with the last used connection parameters)")
 
 }
 
@@ -63,6 +66,17 @@ func (c *CmdAutoConnect) Run() error {
 		isChanged = true
 	}
 
+	if len(c.profile) > 0 {
+		profileName := c.profile
+		if strings.EqualFold(profileName, "none") {
+			profileName = ""
+		}
+		if err := _proto.SetPreferences(string(service_types.Prefs_AutoconnectProfile), profileName); err != nil {
+			return err
+		}
+		isChanged = true
+	}
+
 	// -status
 
 	// request updated daemon settings
@@ -119,6 +133,12 @@ func (c *CmdAutoConnect) printAutoconnectSettings(w *tabwriter.Writer) *tabwrite
 	}
 	fmt.Fprintf(w, "Autoconnect on daemon launch\t:\t%v\n", aol)
 
+	profile := "Last used connection parameters"
+	if len(daemonSettings.AutoconnectProfile) > 0 {
+		profile = daemonSettings.AutoconnectProfile
+	}
+	fmt.Fprintf(w, "Autoconnect profile\t:\t%v\n", profile)
+
 	//inBackground := "Disabled"
 	//if daemonSettings.IsAutoconnectOnLaunchDaemon {
 	//	inBackground = "Enabled"
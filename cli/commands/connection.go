@@ -27,8 +27,10 @@ import (
 	"fmt"
 	"math/big"
 	"net"
+	"os"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/ivpn/desktop-app/cli/flags"
 	apitypes "github.com/ivpn/desktop-app/daemon/api/types"
@@ -39,6 +41,7 @@ import (
 	service_types "github.com/ivpn/desktop-app/daemon/service/types"
 	"github.com/ivpn/desktop-app/daemon/v2r"
 	"github.com/ivpn/desktop-app/daemon/vpn"
+	"golang.org/x/term"
 )
 
 type port struct {
@@ -145,6 +148,16 @@ type CmdConnect struct {
 	multihopExitSvr string
 
 	fastest bool
+	best    bool
+
+	group     string // name of the server group (entry server)
+	exitGroup string // name of the server group (Multi-Hop exit server)
+
+	profile string // name of the connection profile
+
+	wgConfig   string // path to the custom WireGuard configuration file
+	ovpnConfig string // path to the custom OpenVPN configuration file ('.ovpn' profile)
+	ovpnUser   string // username for the custom OpenVPN configuration
 }
 
 func (c *CmdConnect) Init() {
@@ -167,11 +180,18 @@ func (c *CmdConnect) Init() {
 	// Automatic server selection flags
 	c.BoolVar(&c.fastest, "f", false, "Connect to fastest server")
 	c.BoolVar(&c.fastest, "fastest", false, "Connect to fastest server")
+	c.BoolVar(&c.best, "best", false, "Connect to the best server (by score: latency, load, distance and connection history)\n  Tip: use `ivpn servers -score` command to show servers scores")
+	c.StringVar(&c.group, "group", "", "NAME", "Connect to the fastest server of the group (the best server: with '-best'; a random server: with '-any')\n  (LOCATION, if defined, is applied as a filter to the servers of the group)\n  Tip: use `ivpn group` command to manage server groups and favourite servers ('favourites' group)")
 	c.BoolVar(&c.last, "last", false, "Connect with the last used connection parameters")
+	c.StringVar(&c.profile, "profile", "", "NAME", "Connect with the parameters from the connection profile\n  Tip: use `ivpn profile` command to manage connection profiles")
 	c.BoolVar(&c.any, "any", false, "Use a random server from the found results to connect")
+	c.StringVar(&c.wgConfig, "wg-config", "", "FILE", "Connect using the custom WireGuard configuration file ('wg-quick' format)\n  (e.g. self-hosted WireGuard server; the configuration is stored by the daemon)")
+	c.StringVar(&c.ovpnConfig, "ovpn-config", "", "FILE", "Connect using the custom OpenVPN configuration file ('.ovpn' profile)\n  (client directives, inline certificates and keys only; the profile with unsupported directives is rejected;\n   scripts are not allowed; the configuration is stored by the daemon)")
+	c.StringVar(&c.ovpnUser, "ovpn-user", "", "USERNAME", "Username for the '-ovpn-config' profile which requires authentication ('auth-user-pass')\n  (the password will be requested)")
 
 	// Multi-Hop
 	c.StringVar(&c.multihopExitSvr, "exit_svr", "", "LOCATION", "Exit-server for Multi-Hop connection\n  (use full serverID as a parameter, servers filtering not applicable for it)")
+	c.StringVar(&c.exitGroup, "exit_group", "", "NAME", "Exit-server for Multi-Hop connection: the fastest server of the group\n  (the best server: with '-best'; a random server: with '-any')\n  Note: the latency of the exit servers is measured from this device, not from the entry server")
 
 	// Protocol flags
 	c.StringVar(&c.filter_proto, "protocol", "", "PROTOCOL", "Protocol type (OpenVPN|ovpn|WireGuard|wg)")
@@ -241,9 +261,27 @@ func (c *CmdConnect) preParse(arguments []string) ([]string, error) {
 // Run executes command
 func (c *CmdConnect) Run() (retError error) {
 
-	if len(c.gateway) == 0 && !c.fastest && !c.any && !c.last && !c.portsShow {
+	if len(c.wgConfig) > 0 || len(c.ovpnConfig) > 0 {
+		return c.connectCustomConfig()
+	}
+	if len(c.ovpnUser) > 0 {
+		return flags.BadParameter{Message: "'-ovpn-user' is applicable only for '-ovpn-config'"}
+	}
+	if len(c.gateway) == 0 && !c.fastest && !c.best && !c.any && !c.last && !c.portsShow && len(c.profile) == 0 && len(c.group) == 0 {
 		return flags.BadParameter{}
 	}
+	if (len(c.group) > 0 || len(c.exitGroup) > 0) && (c.last || len(c.profile) > 0) {
+		return flags.BadParameter{Message: "server groups are not applicable for '-last' and '-profile' options"}
+	}
+	if len(c.multihopExitSvr) > 0 && len(c.exitGroup) > 0 {
+		return flags.BadParameter{Message: "cannot use both '-exit_svr' and '-exit_group' options"}
+	}
+	if c.fastest && c.best {
+		return flags.BadParameter{Message: "cannot use both '-fastest' and '-best' options"}
+	}
+	if c.last && len(c.profile) > 0 {
+		return flags.BadParameter{Message: "cannot use both '-last' and '-profile' options"}
+	}
 	if c.v2rayProxy != "" && c.obfsproxy != "" {
 		return flags.BadParameter{Message: "cannot use both '-v2ray' and '-obfsproxy' options"}
 	}
@@ -360,35 +398,84 @@ func (c *CmdConnect) Run() (retError error) {
 		fmt.Println("Enabled '-last' parameter. Using parameters from last used configuration")
 		req.Params = defaultConnSettings.Params
 		req.Params.FirewallOnDuringConnection = true
+	} else if len(c.profile) > 0 {
+		profile, err := getConnectionProfile(c.profile)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Using parameters from connection profile '%s'\n", profile.Name)
+		req.Params = profile.Params
+		req.Params.FirewallOnDuringConnection = true
 	} else {
+		// SERVER GROUP (entry server)
+		allSvrs := svrs
+		if len(c.group) > 0 {
+			group, err := getServerGroup(c.group)
+			if err != nil {
+				return err
+			}
+			if svrs = serversFilterByGroup(svrs, group); len(svrs) == 0 {
+				return fmt.Errorf("no servers of the group '%s' found in servers list", group.Name)
+			}
+		}
+
 		// MULTI\SINGLE -HOP
 		// Check if the parameters are correct and define correct values for c.gateway and c.multihopExitSvr
-		if len(c.multihopExitSvr) > 0 {
+		if len(c.multihopExitSvr) > 0 || len(c.exitGroup) > 0 {
 			// MULTI-HOP
 
 			if err := helloResp.Account.IsCanConnectMultiHop(); err != nil {
 				return err
 			}
 
-			if c.fastest {
-				return flags.BadParameter{Message: "'fastest' flag is not applicable for Multi-Hop connection [exit_svr]"}
+			if len(c.group) == 0 && len(c.exitGroup) == 0 {
+				if c.fastest {
+					return flags.BadParameter{Message: "'fastest' flag is not applicable for Multi-Hop connection [exit_svr]"}
+				}
+				if c.best {
+					return flags.BadParameter{Message: "'best' flag is not applicable for Multi-Hop connection [exit_svr]"}
+				}
 			}
 
 			if c.filter_location || c.filter_city || c.filter_countryCode || c.filter_country || c.filter_invert {
 				fmt.Println("WARNING: filtering flags are ignored for Multi-Hop connection [exit_svr]")
 			}
 
+			var entrySvr, exitSvr serverDesc
 			entrySvrs := serversFilter(isWgDisabled, isOpenVPNDisabled, svrs, c.gateway, c.filter_proto, false, false, false, false, false)
-			if len(entrySvrs) == 0 || len(entrySvrs) > 1 {
-				return flags.BadParameter{Message: "specify correct entry server ID for multi-hop connection"}
+			if len(c.group) > 0 {
+				if entrySvr, err = c.groupServer(entrySvrs, isWgDisabled); err != nil {
+					return err
+				}
+			} else {
+				if len(entrySvrs) == 0 || len(entrySvrs) > 1 {
+					return flags.BadParameter{Message: "specify correct entry server ID for multi-hop connection"}
+				}
+				entrySvr = entrySvrs[0]
 			}
 
-			exitSvrs := serversFilter(isWgDisabled, isOpenVPNDisabled, svrs, c.multihopExitSvr, c.filter_proto, false, false, false, false, false)
-			if len(exitSvrs) == 0 || len(exitSvrs) > 1 {
-				return flags.BadParameter{Message: "specify correct exit server ID for multi-hop connection"}
+			if len(c.exitGroup) > 0 {
+				group, err := getServerGroup(c.exitGroup)
+				if err != nil {
+					return err
+				}
+				// exit server: the same protocol as entry server; not from the entry server country
+				exitSvrs := make([]serverDesc, 0)
+				for _, s := range serversFilterByGroup(allSvrs, group) {
+					if s.protocol == entrySvr.protocol && s.countryCode != entrySvr.countryCode {
+						exitSvrs = append(exitSvrs, s)
+					}
+				}
+				if exitSvr, err = c.groupServer(exitSvrs, isWgDisabled); err != nil {
+					return fmt.Errorf("exit server: %w", err)
+				}
+			} else {
+				exitSvrs := serversFilter(isWgDisabled, isOpenVPNDisabled, allSvrs, c.multihopExitSvr, c.filter_proto, false, false, false, false, false)
+				if len(exitSvrs) == 0 || len(exitSvrs) > 1 {
+					return flags.BadParameter{Message: "specify correct exit server ID for multi-hop connection"}
+				}
+				exitSvr = exitSvrs[0]
 			}
-			entrySvr := entrySvrs[0]
-			exitSvr := exitSvrs[0]
 
 			if entrySvr.gateway == exitSvr.gateway {
 				return flags.BadParameter{Message: "unable to use same entry- and exit- servers"}
@@ -405,8 +492,17 @@ func (c *CmdConnect) Run() (retError error) {
 
 			srvID := ""
 
+			// Server of the group (fastest, best or random)
+			if len(c.group) > 0 {
+				groupSvr, err := c.groupServer(svrs, isWgDisabled)
+				if err != nil {
+					return err
+				}
+				srvID = groupSvr.gateway
+			}
+
 			// Fastest server
-			if c.fastest && len(svrs) > 1 {
+			if len(srvID) == 0 && c.fastest && len(svrs) > 1 {
 				var vpnType *vpn.Type = nil
 				if len(c.filter_proto) > 0 {
 					if p, err := getVpnTypeByFlag(c.filter_proto); err == nil {
@@ -427,7 +523,19 @@ func (c *CmdConnect) Run() (retError error) {
 				srvID = fastestSrv.gateway
 			}
 
-			// if we not found required server before (by 'fastest' option)
+			// Best server (by score)
+			if len(srvID) == 0 && c.best && len(svrs) > 1 {
+				bestSrv, err := c.bestServer(svrs, isWgDisabled)
+				if err != nil {
+					if !c.any {
+						return err
+					}
+					fmt.Printf("Error: Failed to determine the best server: %s\n", err)
+				}
+				srvID = bestSrv
+			}
+
+			// if we not found required server before (by 'fastest' or 'best' option)
 			if len(srvID) == 0 {
 				showTipsServerFilterError := func() {
 					fmt.Println()
@@ -466,18 +574,8 @@ func (c *CmdConnect) Run() (retError error) {
 		}
 
 		// Firewall for current connection
-		req.Params.FirewallOnDuringConnection = true
-		if c.firewallOff {
-			// check current FW state
-			state, err := _proto.FirewallStatus()
-			if err != nil {
-				return fmt.Errorf("unable to check Firewall state: %w", err)
-			}
-			if !state.IsEnabled {
-				req.Params.FirewallOnDuringConnection = false
-			} else {
-				fmt.Println("WARNING! Firewall option ignored (Firewall already enabled manually)")
-			}
+		if req.Params.FirewallOnDuringConnection, err = c.firewallOnDuringConnection(); err != nil {
+			return err
 		}
 
 		// Looking for connection server
@@ -715,8 +813,20 @@ func (c *CmdConnect) Run() (retError error) {
 		}
 
 		// metadata
-		if c.fastest {
+		if len(c.group) > 0 || len(c.exitGroup) > 0 {
+			// the server is chosen from the group members (the daemon uses it for reconnections and auto-connect)
+			if len(c.group) > 0 {
+				req.Params.Metadata.ServerSelectionEntry = c.groupSelection()
+				req.Params.Metadata.ServerGroupEntry = c.group
+			}
+			if len(c.exitGroup) > 0 {
+				req.Params.Metadata.ServerSelectionExit = c.groupSelection()
+				req.Params.Metadata.ServerGroupExit = c.exitGroup
+			}
+		} else if c.fastest {
 			req.Params.Metadata.ServerSelectionEntry = service_types.Fastest
+		} else if c.best {
+			req.Params.Metadata.ServerSelectionEntry = service_types.Best
 		} else if c.any {
 			req.Params.Metadata.ServerSelectionEntry = service_types.Random
 		}
@@ -736,6 +846,227 @@ func (c *CmdConnect) Run() (retError error) {
 	return nil
 }
 
+// preferredVpnType returns the VPN protocol for automatic server selection ('-protocol' option; WireGuard by default)
+func (c *CmdConnect) preferredVpnType(isWgDisabled bool) (vpnType vpn.Type, protoName string, err error) {
+	vpnType = vpn.WireGuard
+	if len(c.filter_proto) > 0 {
+		if vpnType, err = getVpnTypeByFlag(c.filter_proto); err != nil {
+			return vpnType, "", err
+		}
+	} else if isWgDisabled {
+		vpnType = vpn.OpenVPN
+	}
+
+	protoName = ProtoName_WireGuard
+	if vpnType == vpn.OpenVPN {
+		protoName = ProtoName_OpenVPN
+	}
+	return vpnType, protoName, nil
+}
+
+// groupSelection returns the method of choosing the server from the group members:
+// Fastest (default), Best ('-best' option) or Random ('-any' option)
+func (c *CmdConnect) groupSelection() service_types.ServerSelectionEnum {
+	if c.best {
+		return service_types.Best
+	}
+	if c.any && !c.fastest {
+		return service_types.Random
+	}
+	return service_types.Fastest
+}
+
+// groupServer chooses the server from the group members (see groupSelection())
+func (c *CmdConnect) groupServer(svrs []serverDesc, isWgDisabled bool) (serverDesc, error) {
+	vpnType, protoName, err := c.preferredVpnType(isWgDisabled)
+	if err != nil {
+		return serverDesc{}, err
+	}
+
+	candidates := make([]serverDesc, 0, len(svrs))
+	for _, s := range svrs {
+		if s.protocol == protoName {
+			candidates = append(candidates, s)
+		}
+	}
+	if len(candidates) == 0 {
+		return serverDesc{}, fmt.Errorf("no applicable %s servers in the group", protoName)
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	switch c.groupSelection() {
+	case service_types.Best:
+		gw, err := c.bestServer(candidates, isWgDisabled)
+		if err != nil {
+			return serverDesc{}, err
+		}
+		for _, s := range candidates {
+			if s.gateway == gw {
+				return s, nil
+			}
+		}
+		return serverDesc{}, fmt.Errorf("server '%s' not found in the group", gw)
+	case service_types.Random:
+		rnd, err := rand.Int(rand.Reader, big.NewInt(int64(len(candidates))))
+		if err != nil {
+			return serverDesc{}, err
+		}
+		return candidates[rnd.Int64()], nil
+	default:
+		if err := serversPing(candidates, true, &vpnType); err != nil {
+			return serverDesc{}, err
+		}
+		fastestSrv := candidates[len(candidates)-1]
+		if fastestSrv.pingMs == 0 {
+			fmt.Println("WARNING! Servers pinging problem.")
+		}
+		return fastestSrv, nil
+	}
+}
+
+// bestServer returns the gateway ID of the server with the best score (the score is calculated by the daemon)
+func (c *CmdConnect) bestServer(svrs []serverDesc, isWgDisabled bool) (string, error) {
+	vpnType, protoName, err := c.preferredVpnType(isWgDisabled)
+	if err != nil {
+		return "", err
+	}
+
+	gateways := make([]string, 0, len(svrs))
+	for _, s := range svrs {
+		if s.protocol == protoName {
+			gateways = append(gateways, s.gateway)
+		}
+	}
+	if len(gateways) == 0 {
+		return "", fmt.Errorf("no %s servers found by your filter", protoName)
+	}
+
+	scores, err := _proto.ServerScores(vpnType, gateways)
+	if err != nil {
+		return "", err
+	}
+	if len(scores) == 0 {
+		return "", fmt.Errorf("no servers scores received")
+	}
+
+	best := scores[0]
+	fmt.Printf("Best server: %s (score %.1f)\n", best.Gateway, best.Score)
+	return best.Gateway, nil
+}
+
+// firewallOnDuringConnection returns value for 'FirewallOnDuringConnection' connection parameter
+// (according to '-fw_off' option and current firewall state)
+func (c *CmdConnect) firewallOnDuringConnection() (bool, error) {
+	if !c.firewallOff {
+		return true, nil
+	}
+	// check current FW state
+	state, err := _proto.FirewallStatus()
+	if err != nil {
+		return false, fmt.Errorf("unable to check Firewall state: %w", err)
+	}
+	if state.IsEnabled {
+		fmt.Println("WARNING! Firewall option ignored (Firewall already enabled manually)")
+		return true, nil
+	}
+	return false, nil
+}
+
+// connectWgCustomConfig connects using the custom WireGuard configuration file
+func (c *CmdConnect) connectCustomConfig() (retError error) {
+	if len(c.wgConfig) > 0 && len(c.ovpnConfig) > 0 {
+		return flags.BadParameter{Message: "cannot use both '-wg-config' and '-ovpn-config' options"}
+	}
+	if len(c.ovpnUser) > 0 && len(c.ovpnConfig) == 0 {
+		return flags.BadParameter{Message: "'-ovpn-user' is applicable only for '-ovpn-config'"}
+	}
+	if len(c.gateway) > 0 || c.fastest || c.best || c.any || c.last || c.portsShow || len(c.profile) > 0 || len(c.multihopExitSvr) > 0 || len(c.group) > 0 || len(c.exitGroup) > 0 ||
+		len(c.filter_proto) > 0 || len(c.port) > 0 || c.mtu > 0 || c.isIPv6Tunnel || len(c.obfsproxy) > 0 || len(c.v2rayProxy) > 0 {
+		return flags.BadParameter{Message: "server selection and protocol options are not applicable for custom configurations (all parameters are defined by the configuration file)"}
+	}
+	if c.antitracker || c.antitrackerHard {
+		return flags.BadParameter{Message: "AntiTracker is not applicable for custom configurations"}
+	}
+
+	req := types.Connect{}
+	var (
+		protoName  string
+		configFile string
+	)
+
+	if len(c.wgConfig) > 0 {
+		protoName, configFile = "WireGuard", c.wgConfig
+		data, err := os.ReadFile(c.wgConfig)
+		if err != nil {
+			return fmt.Errorf("failed to read WireGuard configuration: %w", err)
+		}
+		if err := _proto.WGCustomConfigSet(string(data)); err != nil {
+			return err
+		}
+		req.Params.VpnType = vpn.WireGuard
+		req.Params.WireGuardParameters.CustomConfig = true
+	} else {
+		protoName, configFile = "OpenVPN", c.ovpnConfig
+		data, err := os.ReadFile(c.ovpnConfig)
+		if err != nil {
+			return fmt.Errorf("failed to read OpenVPN configuration: %w", err)
+		}
+
+		password := ""
+		if len(c.ovpnUser) > 0 {
+			fmt.Print("Enter password: ")
+			pass, err := term.ReadPassword(int(syscall.Stdin))
+			fmt.Println("")
+			if err != nil {
+				return fmt.Errorf("failed to read password: %w", err)
+			}
+			password = string(pass)
+		}
+
+		removed, err := _proto.OpenVpnCustomConfigSet(string(data), c.ovpnUser, password)
+		if err != nil {
+			return err
+		}
+		if len(removed) > 0 {
+			fmt.Printf("WARNING! Directives removed from the OpenVPN profile: %s\n", strings.Join(removed, ", "))
+		}
+		req.Params.VpnType = vpn.OpenVPN
+		req.Params.OpenVpnParameters.CustomConfig = true
+	}
+
+	var err error
+	if req.Params.FirewallOnDuringConnection, err = c.firewallOnDuringConnection(); err != nil {
+		return err
+	}
+	if len(c.dns) > 0 {
+		dnsIp := net.ParseIP(c.dns)
+		if dnsIp == nil {
+			return flags.BadParameter{}
+		}
+		req.Params.ManualDNS = dns.DnsSettings{DnsHost: dnsIp.String(), Encryption: dns.EncryptionNone}
+	}
+
+	// show current state after on finished
+	defer func() {
+		if retError == nil {
+			showState()
+		}
+	}()
+
+	fmt.Printf("[%s] Connecting using custom configuration '%s'...\n", protoName, configFile)
+	if _, err = _proto.ConnectVPN(req); err != nil {
+		err = fmt.Errorf("failed to connect: %w", err)
+		fmt.Printf("Disconnecting...\n")
+		if err2 := _proto.DisconnectVPN(); err2 != nil {
+			fmt.Printf("Failed to disconnect: %v\n", err2)
+		}
+		return err
+	}
+	return nil
+}
+
 func getPort(portInfo string, allowedPorts []apitypes.PortInfo) (port, error) {
 	var err error
 	var portPtr *int
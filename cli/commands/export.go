@@ -0,0 +1,70 @@
+//
+//  IVPN command line interface (CLI)
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the IVPN command line interface.
+//
+//  The IVPN command line interface is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The IVPN command line interface is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the IVPN command line interface. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ivpn/desktop-app/cli/flags"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+type CmdExportConfig struct {
+	flags.CmdInfo
+	outFile     string
+	credentials bool
+}
+
+func (c *CmdExportConfig) Init() {
+	c.Initialize("export-config", "Export the current connection parameters as a standalone configuration\n('wg-quick' configuration for WireGuard or '.ovpn' profile for OpenVPN; e.g. for headless servers or routers)\nBy default, the configuration is printed to stdout")
+	c.StringVar(&c.outFile, "out", "", "FILE", "Save configuration to the file\n  (use '.' to save into the current directory using suggested file name, e.g. 'ivpn-nl4.conf')")
+	c.BoolVar(&c.credentials, "credentials", false, "(OpenVPN) Include account credentials into the profile\n  (the inline '<auth-user-pass>' block requires OpenVPN 2.6 or newer)")
+}
+
+func (c *CmdExportConfig) Run() error {
+	vpnType, fileName, config, err := _proto.ExportConnectionConfig(c.credentials)
+	if err != nil {
+		return err
+	}
+
+	if len(c.outFile) == 0 {
+		fmt.Print(config)
+		return nil
+	}
+
+	outFile := c.outFile
+	if outFile == "." {
+		outFile = fileName
+	}
+	// the configuration contains private keys: read\write only for current user
+	if err := os.WriteFile(outFile, []byte(config), 0600); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+	fmt.Printf("Configuration saved: %s\n", outFile)
+
+	if vpnType == vpn.WireGuard {
+		fmt.Println("NOTE: The configuration uses WireGuard keys of this device. The keys will stop working after rotation (see 'ivpn wgkeys').")
+	}
+	return nil
+}
@@ -24,8 +24,19 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/ivpn/desktop-app/cli/flags"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
+)
+
+const (
+	LinuxFirewallBackend_Script   = "script"
+	LinuxFirewallBackend_Nftables = "nftables"
 )
 
 type CmdFirewall struct {
@@ -40,6 +51,9 @@ type CmdFirewall struct {
 	persistentOn       bool
 	persistentOff      bool
 	exceptions         string
+	linuxBackend       string
+	showRules          bool
+	dryRun             bool
 	//allowLanMulticast bool
 	//blockLanMulticast bool
 }
@@ -57,7 +71,22 @@ func (c *CmdFirewall) Init() {
 	c.BoolVar(&c.ivpnSvrAccessBlock, "ivpn_access_block", false, "Block access to IVPN servers when Firewall is enabled")
 	c.BoolVar(&c.persistentOff, "persistent_off", false, "Persistent firewall (Always-on firewall): disable")
 	c.BoolVar(&c.persistentOn, "persistent_on", false, "Persistent firewall (Always-on firewall): enable. When the option is enabled the IVPN Firewall is started during system boot")
-	c.StringVar(&c.exceptions, "exceptions", StringValueNoData, "EXCEPTIONS", "Set configuration: comma-separated list of IP addresses or subnets (using CIDR notation)\nthat will be allowed through the firewall when enabled\nExamples:\n\tivpn firewall -exceptions '192.0.2.0/24, 198.51.100.1'\n\tivpn firewall -exceptions ''")
+	c.StringVar(&c.exceptions, "exceptions", StringValueNoData, "EXCEPTIONS", "Set configuration: comma-separated list of IP addresses or subnets (using CIDR notation)\nthat will be allowed through the firewall when enabled\n"+
+		"Optionally, the exception can be restricted by protocol, port (range) and direction:\n"+
+		"\t[tcp|udp:]ADDRESS[:PORT[-PORT]][:in|out]\n"+
+		"\t(IPv6 address must be enclosed in square brackets when followed by port or direction)\n"+
+		"The hostname can be used instead of IP address (the daemon resolves it periodically;\nthe hostname exception can not be restricted by protocol, port or direction)\n"+
+		"Examples:\n\tivpn firewall -exceptions '192.0.2.0/24, 198.51.100.1'\n\tivpn firewall -exceptions 'tcp:10.0.0.5:22, udp:192.168.1.0/24:5353, tcp:[2001:db8::1]:443:out, git.example.com'\n\tivpn firewall -exceptions ''")
+	if runtime.GOOS == "linux" {
+		c.StringVarEx(&c.linuxBackend, "backend", "", "BACKEND",
+			fmt.Sprintf("Set configuration: firewall implementation (can be changed only when firewall disabled)\n  Possible values: %s (default; based on iptables); %s (native nftables implementation)\n  Example: ivpn firewall -backend %s",
+				LinuxFirewallBackend_Script, LinuxFirewallBackend_Nftables, LinuxFirewallBackend_Nftables),
+			func() bool {
+				return _proto == nil || len(_proto.GetHelloResponse().DisabledFunctions.Platform.Linux.NftablesBackendError) == 0
+			})
+	}
+	c.BoolVar(&c.showRules, "show-rules", false, "Show description of the currently applied firewall rules")
+	c.BoolVar(&c.dryRun, "dry-run", false, "Show description of the firewall rules which would be applied when the firewall is enabled\n(the rules are not applied)")
 	//c.BoolVar(&c.allowLanMulticast, "lan_multicast_allow", false, "Same as 'lan_allow' + allow multicast communication ")
 	//c.BoolVar(&c.blockLanMulticast, "lan_multicast_block", false, "Same as 'lan_block' + block multicast communication")
 }
@@ -82,6 +111,16 @@ func (c *CmdFirewall) Run() error {
 	//	return flags.BadParameter{}
 	//}
 
+	if c.showRules && c.dryRun {
+		return flags.BadParameter{}
+	}
+
+	if len(c.linuxBackend) > 0 {
+		if err := c.setLinuxBackend(); err != nil {
+			return err
+		}
+	}
+
 	if c.ivpnSvrAccessAllow {
 		if err := _proto.FirewallAllowApiServers(true); err != nil {
 			return err
@@ -113,7 +152,11 @@ func (c *CmdFirewall) Run() error {
 	//}
 
 	if c.exceptions != StringValueNoData {
-		if err := _proto.FirewallSetUserExceptions(c.exceptions); err != nil {
+		exceptions, err := service_types.ParseFirewallExceptions(c.exceptions, false)
+		if err != nil {
+			return flags.BadParameter{Message: err.Error()}
+		}
+		if err := _proto.FirewallSetUserExceptionsList(exceptions); err != nil {
 			return err
 		}
 	}
@@ -154,8 +197,25 @@ func (c *CmdFirewall) Run() error {
 	}
 
 	w := printFirewallState(nil, state.IsEnabled, state.IsPersistent, state.IsAllowLAN, state.IsAllowMulticast, state.IsAllowApiServers, state.UserExceptions, nil)
+	if runtime.GOOS == "linux" {
+		backend := LinuxFirewallBackend_Script
+		if _proto.GetHelloResponse().DaemonSettings.UserPrefs.Linux.FirewallBackend == preferences.LinuxFirewallBackendNftables {
+			backend = LinuxFirewallBackend_Nftables
+		}
+		fmt.Fprintf(w, "    Backend\t:\t%s\n", backend)
+	}
+	printFirewallHostExceptions(w, state.UserExceptionsHosts)
 	w.Flush()
 
+	if c.showRules || c.dryRun {
+		rules, err := _proto.FirewallRules(c.dryRun)
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		printFirewallRules(rules)
+	}
+
 	// TIPS
 	tips := make([]TipType, 0, 2)
 	if state.IsEnabled == false {
@@ -166,3 +226,96 @@ func (c *CmdFirewall) Run() error {
 	PrintTips(tips)
 	return nil
 }
+
+func (c *CmdFirewall) setLinuxBackend() error {
+	var backend preferences.LinuxFirewallBackend
+	switch strings.ToLower(strings.TrimSpace(c.linuxBackend)) {
+	case LinuxFirewallBackend_Script:
+		backend = preferences.LinuxFirewallBackendScript
+	case LinuxFirewallBackend_Nftables:
+		if errStr := _proto.GetHelloResponse().DisabledFunctions.Platform.Linux.NftablesBackendError; len(errStr) > 0 {
+			return fmt.Errorf("the nftables firewall backend is not applicable for current environment: %s", errStr)
+		}
+		backend = preferences.LinuxFirewallBackendNftables
+	default:
+		return flags.BadParameter{Message: fmt.Sprintf("unknown firewall backend '%s'", c.linuxBackend)}
+	}
+
+	uPrefs := _proto.GetHelloResponse().DaemonSettings.UserPrefs
+	if uPrefs.Linux.FirewallBackend == backend {
+		return nil
+	}
+	uPrefs.Linux.FirewallBackend = backend
+	if err := _proto.SetUserPreferences(uPrefs); err != nil {
+		return err
+	}
+
+	// trigger daemon to send HelloResponse with updated user preferences
+	_, err := _proto.SendHello()
+	return err
+}
+
+func printFirewallRules(rules service_types.KillSwitchRules) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+
+	title := "Firewall rules"
+	if rules.IsDryRun {
+		title = "Firewall rules (dry-run: not applied)"
+	}
+	fmt.Fprintf(w, "%s\t:\t\n", title)
+	fmt.Fprintf(w, "    Backend\t:\t%s\n", rules.Backend)
+	if !rules.IsEnabled && !rules.IsDryRun {
+		fmt.Fprintf(w, "    (firewall disabled: no rules applied)\t\t\n")
+		w.Flush()
+		return
+	}
+
+	printOptional := func(name, val string) {
+		if len(val) > 0 {
+			fmt.Fprintf(w, "    %s\t:\t%s\n", name, val)
+		}
+	}
+	printList := func(name string, vals []string) {
+		for i, v := range vals {
+			if i == 0 {
+				fmt.Fprintf(w, "    %s\t:\t%s\n", name, v)
+			} else {
+				fmt.Fprintf(w, "\t\t%s\n", v)
+			}
+		}
+	}
+
+	printOptional("VPN interface", rules.VpnInterface)
+	printOptional("VPN server", rules.VpnServer)
+	if len(rules.DnsServer) > 0 {
+		printOptional("DNS server", rules.DnsServer)
+	} else {
+		printOptional("DNS server", "<blocked outside VPN>")
+	}
+	printList("Allowed hosts", rules.AllowedHosts)
+	printList("Allowed hosts (persistent)", rules.AllowedHostsPersistent)
+	printList("ICMP exceptions", rules.IcmpExceptions)
+	printList("LAN ranges", rules.LanRanges)
+	printList("User exceptions", rules.UserExceptions)
+	w.Flush()
+
+	if len(rules.RawRules) > 0 {
+		fmt.Println()
+		fmt.Println(strings.TrimRight(rules.RawRules, "\n"))
+	}
+}
+
+func printFirewallHostExceptions(w *tabwriter.Writer, hosts []service_types.FirewallHostExceptionStatus) {
+	for _, h := range hosts {
+		resolved := strings.Join(h.IPs, ", ")
+		if len(h.Error) > 0 {
+			if len(resolved) > 0 {
+				resolved += " "
+			}
+			resolved += fmt.Sprintf("(resolving error: %s)", h.Error)
+		} else if len(resolved) == 0 {
+			resolved = "(not resolved yet)"
+		}
+		fmt.Fprintf(w, "    Host '%s'\t:\t%s\n", h.Hostname, resolved)
+	}
+}
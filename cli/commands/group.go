@@ -0,0 +1,194 @@
+//
+//  IVPN command line interface (CLI)
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the IVPN command line interface.
+//
+//  The IVPN command line interface is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The IVPN command line interface is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the IVPN command line interface. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ivpn/desktop-app/cli/flags"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+)
+
+type CmdGroup struct {
+	flags.CmdInfo
+	list      bool
+	set       string
+	servers   string
+	delete    string
+	favAdd    string
+	favRemove string
+}
+
+func (c *CmdGroup) Init() {
+	c.KeepArgsOrderInHelp = true
+
+	c.Initialize("group", "Manage favourite servers and server groups\nTip: use 'ivpn connect -group NAME' to connect to the fastest (or the best) server of the group")
+	c.BoolVar(&c.list, "list", false, "(default) Show favourite servers and server groups")
+	c.StringVar(&c.set, "set", "", "NAME", "Save the server group (use in combination with '-servers')\n  (if the group with the same name exists - it will be overwritten)")
+	c.StringVar(&c.servers, "servers", "", "LOCATIONS", "Comma-separated list of servers of the group (e.g. 'nl-ams,de-fra,ch-zrh'; use in combination with '-set')\n  Tip: use 'ivpn servers' command to get the list of servers")
+	c.StringVar(&c.delete, "delete", "", "NAME", "Remove the server group")
+	c.StringVar(&c.favAdd, "fav_add", "", "LOCATION", fmt.Sprintf("Add the server to favourites\n  (favourite servers are available as the group '%s')", preferences.FavouritesGroupName))
+	c.StringVar(&c.favRemove, "fav_remove", "", "LOCATION", "Remove the server from favourites (use 'all' to clear favourites)")
+}
+
+func (c *CmdGroup) Run() error {
+	if len(c.set) > 0 != (len(c.servers) > 0) {
+		return flags.BadParameter{Message: "options '-set' and '-servers' must be used together"}
+	}
+
+	if len(c.set) > 0 {
+		gateways, err := resolveGateways(strings.Split(c.servers, ","))
+		if err != nil {
+			return err
+		}
+		if err := _proto.ServerGroupSet(preferences.ServerGroup{Name: c.set, Gateways: gateways}); err != nil {
+			return err
+		}
+		fmt.Printf("Server group '%s' saved\n", c.set)
+	}
+
+	if len(c.delete) > 0 {
+		if err := _proto.ServerGroupDelete(c.delete); err != nil {
+			return err
+		}
+		fmt.Printf("Server group '%s' removed\n", c.delete)
+	}
+
+	if len(c.favAdd) > 0 || len(c.favRemove) > 0 {
+		resp, err := _proto.ServerGroups()
+		if err != nil {
+			return err
+		}
+		favourites := resp.Favourites
+
+		if len(c.favRemove) > 0 {
+			if strings.EqualFold(c.favRemove, "all") {
+				favourites = nil
+			} else {
+				fav := preferences.ServerGroup{Gateways: favourites}
+				if !fav.Contains(c.favRemove) {
+					return flags.BadParameter{Message: fmt.Sprintf("server '%s' is not in favourites", c.favRemove)}
+				}
+				gw := preferences.NormalizeGatewayID(c.favRemove)
+				favourites = nil
+				for _, f := range resp.Favourites {
+					if preferences.NormalizeGatewayID(f) != gw {
+						favourites = append(favourites, f)
+					}
+				}
+			}
+		}
+		if len(c.favAdd) > 0 {
+			gateways, err := resolveGateways([]string{c.favAdd})
+			if err != nil {
+				return err
+			}
+			favourites = append(favourites, gateways...)
+		}
+
+		if err := _proto.FavouriteServersSet(favourites); err != nil {
+			return err
+		}
+		fmt.Println("Favourite servers saved")
+	}
+
+	if c.list || (len(c.set) == 0 && len(c.delete) == 0 && len(c.favAdd) == 0 && len(c.favRemove) == 0) {
+		resp, err := _proto.ServerGroups()
+		if err != nil {
+			return err
+		}
+
+		if len(resp.Favourites) == 0 && len(resp.Groups) == 0 {
+			fmt.Println("No favourite servers and server groups defined")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		if len(resp.Favourites) > 0 {
+			fmt.Fprintf(w, "%s\t:\t%s\n", preferences.FavouritesGroupName, strings.Join(resp.Favourites, ", "))
+		}
+		for _, g := range resp.Groups {
+			fmt.Fprintf(w, "%s\t:\t%s\n", g.Name, strings.Join(g.Gateways, ", "))
+		}
+		w.Flush()
+	}
+
+	return nil
+}
+
+// getServerGroup returns the server group by name (case-insensitive; 'favourites' - the group of favourite servers)
+func getServerGroup(name string) (preferences.ServerGroup, error) {
+	resp, err := _proto.ServerGroups()
+	if err != nil {
+		return preferences.ServerGroup{}, err
+	}
+	prefs := preferences.Preferences{FavouriteServers: resp.Favourites, ServerGroups: resp.Groups}
+	group, ok := prefs.ServerGroupGet(name)
+	if !ok {
+		return preferences.ServerGroup{}, fmt.Errorf("server group '%s' not found (or empty)", name)
+	}
+	return group, nil
+}
+
+// resolveGateways returns normalized gateway IDs ("nl-ams.wg.ivpn.net" => "nl-ams")
+// or error when the server not exists in servers list
+func resolveGateways(locations []string) ([]string, error) {
+	servers, err := _proto.GetServers()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]struct{})
+	for _, s := range serversList(servers) {
+		known[preferences.NormalizeGatewayID(s.gateway)] = struct{}{}
+	}
+
+	var ret []string
+	for _, l := range locations {
+		gw := preferences.NormalizeGatewayID(l)
+		if len(gw) == 0 {
+			continue
+		}
+		if _, ok := known[gw]; !ok {
+			return nil, flags.BadParameter{Message: fmt.Sprintf("server '%s' not found (use 'ivpn servers' to get the list of servers)", strings.TrimSpace(l))}
+		}
+		ret = append(ret, gw)
+	}
+	if len(ret) == 0 {
+		return nil, flags.BadParameter{Message: "servers are not defined"}
+	}
+	return ret, nil
+}
+
+// serversFilterByGroup returns only the servers which are members of the group
+func serversFilterByGroup(servers []serverDesc, group preferences.ServerGroup) []serverDesc {
+	ret := make([]serverDesc, 0, len(group.Gateways))
+	for _, s := range servers {
+		if group.Contains(s.gateway) {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,280 @@
+//
+//  IVPN command line interface (CLI)
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the IVPN command line interface.
+//
+//  The IVPN command line interface is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The IVPN command line interface is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the IVPN command line interface. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ivpn/desktop-app/cli/flags"
+	"github.com/ivpn/desktop-app/cli/helpers"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+)
+
+type CmdNetwork struct {
+	flags.CmdInfo
+	status               bool
+	current              bool
+	trusted_control      string // [on/off]
+	default_trust_status string // [none/trusted/untrusted]
+	add_rule             string // [name:status] (status: trusted/untrusted; e.g. office:trusted)
+	iface                string
+	gateway_ip           string
+	gateway_mac          string
+	dhcp_domain          string
+	delete_rule          string
+}
+
+func (c *CmdNetwork) Init() {
+	c.KeepArgsOrderInHelp = true
+
+	c.Initialize("network", "Trusted/Untrusted wired network control (rules based on the default route)")
+	c.BoolVar(&c.status, "status", false, "(default) Show settings")
+	c.BoolVar(&c.current, "current", false, "Show info about the current network (the network which is in use by the default route)")
+	c.StringVar(&c.trusted_control, "trusted_control", "", "[on/off]",
+		`Trusted/Untrusted wired network control
+		By enabling this feature you can define rules to mark a network as trusted or
+		untrusted. The actions to take when joining the network are common with
+		WiFi control (see 'ivpn wifi -set_trusted_action').
+		Note: WiFi rules have priority over wired network rules`)
+	c.StringVar(&c.default_trust_status, "default_trust_status", "", "STATUS",
+		`Default trust status for networks not matching any rule
+		Acceptable status values:
+			* trusted   - apply 'trusted' actions when joining a network
+			* untrusted - apply 'untrusted' actions when joining a network
+			* none 	    - no actions will be applied when joining a network`)
+	c.StringVar(&c.add_rule, "add_rule", "", "CONFIG",
+		`Add (or replace) network trust rule
+		CONFIG parameter format: <RULE_NAME>:<VALUE>
+			VALUE: [trusted/untrusted]
+		Use the options '-interface', '-gateway_ip', '-gateway_mac', '-dhcp_domain' to define rule
+		conditions (all defined conditions must match the network).
+		If no conditions defined - the rule is created for the current network
+		(by the gateway hardware address, if known; otherwise - by interface name and gateway IP)
+		Example:
+				ivpn network -add_rule office:trusted
+				ivpn network -add_rule office:trusted -dhcp_domain office.example.com`)
+	c.StringVar(&c.iface, "interface", "", "NAME", "Rule condition: name of the default-route network interface (use with '-add_rule')")
+	c.StringVar(&c.gateway_ip, "gateway_ip", "", "IP", "Rule condition: IP address of the default gateway (use with '-add_rule')")
+	c.StringVar(&c.gateway_mac, "gateway_mac", "", "MAC", "Rule condition: hardware address of the default gateway (use with '-add_rule')")
+	c.StringVar(&c.dhcp_domain, "dhcp_domain", "", "DOMAIN", "Rule condition: domain name received by DHCP (use with '-add_rule')")
+	c.StringVar(&c.delete_rule, "delete_rule", "", "RULE_NAME", "Remove network trust rule")
+}
+
+func (c *CmdNetwork) Run() error {
+	helloResp := _proto.GetHelloResponse()
+	params := helloResp.DaemonSettings.NetworkTrust
+	// copy rules (do not modify the daemon settings cached in Hello response)
+	params.Rules = append([]preferences.NetworkTrustRule{}, params.Rules...)
+
+	isSettingsChanged := false
+
+	if len(c.add_rule) == 0 && (len(c.iface) > 0 || len(c.gateway_ip) > 0 || len(c.gateway_mac) > 0 || len(c.dhcp_domain) > 0) {
+		return flags.BadParameter{Message: "rule conditions can be used only in combination with '-add_rule'"}
+	}
+
+	if len(c.trusted_control) > 0 {
+		val, err := helpers.BoolParameterParse(c.trusted_control) // [on/off]
+		if err != nil {
+			return err
+		}
+		if val && helloResp.ParanoidMode.IsEnabled {
+			return EaaEnabledOptionNotApplicable{}
+		}
+		params.IsEnabled = val
+		isSettingsChanged = true
+	}
+
+	if len(c.default_trust_status) > 0 {
+		val, isNull, err := helpers.BoolParameterParseEx(c.default_trust_status, []string{"trusted"}, []string{"untrusted"}, []string{"none"})
+		if err != nil {
+			return err
+		}
+		if isNull {
+			params.DefaultTrustStatusTrusted = nil
+		} else {
+			params.DefaultTrustStatusTrusted = &val
+		}
+		isSettingsChanged = true
+	}
+
+	if len(c.add_rule) > 0 {
+		dividerIdx := strings.LastIndex(c.add_rule, ":")
+		if dividerIdx <= 0 {
+			return flags.BadParameter{Message: "rule"}
+		}
+		rule := preferences.NetworkTrustRule{
+			Name:          helpers.TrimSpacesAndRemoveQuotes(c.add_rule[:dividerIdx]),
+			InterfaceName: c.iface,
+			GatewayIP:     c.gateway_ip,
+			GatewayMAC:    c.gateway_mac,
+			DhcpDomain:    c.dhcp_domain,
+		}
+
+		valueStr := helpers.TrimSpacesAndRemoveQuotes(c.add_rule[dividerIdx+1:])
+		switch strings.ToLower(valueStr) {
+		case string(Trusted):
+			rule.IsTrusted = true
+		case string(Untrusted):
+			rule.IsTrusted = false
+		default:
+			return flags.BadParameter{
+				Message: fmt.Sprintf("not supported trust state '%s' (acceptable values: %s, %s)", valueStr, string(Trusted), string(Untrusted))}
+		}
+
+		if len(rule.InterfaceName) == 0 && len(rule.GatewayIP) == 0 && len(rule.GatewayMAC) == 0 && len(rule.DhcpDomain) == 0 {
+			curNet, err := _proto.GetNetworkCurrentInfo()
+			if err != nil {
+				return fmt.Errorf("failed to obtain info about the current network: %w", err)
+			}
+			if len(curNet.GatewayMAC) > 0 {
+				rule.GatewayMAC = curNet.GatewayMAC
+			} else {
+				rule.InterfaceName = curNet.InterfaceName
+				rule.GatewayIP = curNet.GatewayIP
+			}
+			fmt.Printf("Rule conditions not defined. Using current network: %s\n", networkRuleConditions(rule))
+		}
+
+		isReplaced := false
+		for i, r := range params.Rules {
+			if r.Name == rule.Name {
+				rule.Rule = r.Rule // keep rule-specific parameters
+				params.Rules[i] = rule
+				isReplaced = true
+				break
+			}
+		}
+		if !isReplaced {
+			params.Rules = append(params.Rules, rule)
+		}
+		isSettingsChanged = true
+	}
+
+	if len(c.delete_rule) > 0 {
+		isFound := false
+		for i, r := range params.Rules {
+			if r.Name == c.delete_rule {
+				params.Rules = append(params.Rules[:i], params.Rules[i+1:]...)
+				isFound = true
+				break
+			}
+		}
+		if !isFound {
+			return fmt.Errorf("network rule '%s' not found", c.delete_rule)
+		}
+		isSettingsChanged = true
+	}
+
+	// send updated settings
+	if isSettingsChanged {
+		fmt.Print("Applying changes... ")
+		if err := _proto.SetNetworkTrustSettings(params); err != nil {
+			fmt.Println()
+			return err
+		}
+		fmt.Println("Done")
+	}
+
+	if c.current {
+		curNet, err := _proto.GetNetworkCurrentInfo()
+		if err != nil {
+			return fmt.Errorf("failed to obtain info about the current network: %w", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		fmt.Fprintf(w, "Interface\t:\t%v\n", curNet.InterfaceName)
+		fmt.Fprintf(w, "Gateway IP\t:\t%v\n", curNet.GatewayIP)
+		fmt.Fprintf(w, "Gateway MAC\t:\t%v\n", valueOrUnknown(curNet.GatewayMAC))
+		fmt.Fprintf(w, "DHCP domain\t:\t%v\n", valueOrUnknown(curNet.DhcpDomain))
+		fmt.Fprintf(w, "Matching rule\t:\t%v\n", valueOrUnknown(curNet.MatchedRule))
+		w.Flush()
+		return nil
+	}
+
+	// Status
+	if c.status || !isSettingsChanged {
+		c.printStatus()
+	}
+	return nil
+}
+
+func (c *CmdNetwork) printStatus() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	params := _proto.GetHelloResponse().DaemonSettings.NetworkTrust
+
+	enabledStr := "Disabled"
+	if params.IsEnabled {
+		enabledStr = "Enabled"
+	}
+	defStatus := "No status"
+	if params.DefaultTrustStatusTrusted != nil {
+		if *params.DefaultTrustStatusTrusted {
+			defStatus = "Trusted"
+		} else {
+			defStatus = "Untrusted"
+		}
+	}
+
+	fmt.Fprintf(w, "Trusted/Untrusted wired network control\t:\t%v\n", enabledStr)
+	fmt.Fprintf(w, "Default trust status for networks not matching any rule\t:\t%v\n", defStatus)
+	if len(params.Rules) == 0 {
+		fmt.Fprintf(w, "Rules\t:\tnot defined\n")
+	} else {
+		fmt.Fprintf(w, "Rules:\t\n")
+		for _, r := range params.Rules {
+			status := "Untrusted"
+			if r.IsTrusted {
+				status = "Trusted"
+			}
+			fmt.Fprintf(w, "        %s\t:\t%v (%s)\n", r.Name, status, networkRuleConditions(r))
+		}
+	}
+	w.Flush()
+}
+
+// networkRuleConditions returns short description of the rule conditions
+func networkRuleConditions(r preferences.NetworkTrustRule) string {
+	conditions := make([]string, 0, 4)
+	if len(r.InterfaceName) > 0 {
+		conditions = append(conditions, "interface="+r.InterfaceName)
+	}
+	if len(r.GatewayIP) > 0 {
+		conditions = append(conditions, "gateway_ip="+r.GatewayIP)
+	}
+	if len(r.GatewayMAC) > 0 {
+		conditions = append(conditions, "gateway_mac="+r.GatewayMAC)
+	}
+	if len(r.DhcpDomain) > 0 {
+		conditions = append(conditions, "dhcp_domain="+r.DhcpDomain)
+	}
+	return strings.Join(conditions, " ")
+}
+
+func valueOrUnknown(v string) string {
+	if len(v) == 0 {
+		return "-"
+	}
+	return v
+}
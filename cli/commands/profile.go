@@ -0,0 +1,175 @@
+//
+//  IVPN command line interface (CLI)
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the IVPN command line interface.
+//
+//  The IVPN command line interface is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The IVPN command line interface is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the IVPN command line interface. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ivpn/desktop-app/cli/flags"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
+	"github.com/ivpn/desktop-app/daemon/v2r"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+type CmdProfile struct {
+	flags.CmdInfo
+	list    bool
+	save    string
+	rename  string
+	newName string
+	delete  string
+}
+
+func (c *CmdProfile) Init() {
+	c.KeepArgsOrderInHelp = true
+
+	c.Initialize("profile", "Manage connection profiles (named sets of connection parameters)")
+	c.BoolVar(&c.list, "list", false, "(default) Show saved connection profiles")
+	c.StringVar(&c.save, "save", "", "NAME", "Save the last used connection parameters as a profile\n  (if the profile with the same name exists - it will be overwritten)\n  Tip: use 'ivpn connect ...' to establish connection with required parameters before saving the profile")
+	c.StringVar(&c.rename, "rename", "", "NAME", "Rename the profile (use in combination with '-new_name')")
+	c.StringVar(&c.newName, "new_name", "", "NEW_NAME", "New name of the profile (use in combination with '-rename')")
+	c.StringVar(&c.delete, "delete", "", "NAME", "Remove the profile")
+}
+
+func (c *CmdProfile) Run() error {
+	if len(c.rename) > 0 != (len(c.newName) > 0) {
+		return flags.BadParameter{Message: "options '-rename' and '-new_name' must be used together"}
+	}
+
+	if len(c.save) > 0 {
+		settings, err := _proto.GetDefConnectionParams()
+		if err != nil {
+			return err
+		}
+		if err := settings.Params.CheckIsDefined(); err != nil {
+			return fmt.Errorf("the last used connection parameters are not defined; please connect VPN at least once: %w", err)
+		}
+
+		profile := preferences.ConnectionProfile{Name: c.save, Params: settings.Params}
+		if existing, err := getConnectionProfile(c.save); err == nil {
+			err = _proto.ConnectionProfileUpdate(existing.Name, profile)
+		} else {
+			err = _proto.ConnectionProfileCreate(profile)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Connection profile '%s' saved\n", c.save)
+	}
+
+	if len(c.rename) > 0 {
+		profile, err := getConnectionProfile(c.rename)
+		if err != nil {
+			return err
+		}
+		oldName := profile.Name
+		profile.Name = c.newName
+		if err := _proto.ConnectionProfileUpdate(oldName, profile); err != nil {
+			return err
+		}
+		fmt.Printf("Connection profile '%s' renamed to '%s'\n", oldName, c.newName)
+	}
+
+	if len(c.delete) > 0 {
+		if err := _proto.ConnectionProfileDelete(c.delete); err != nil {
+			return err
+		}
+		fmt.Printf("Connection profile '%s' removed\n", c.delete)
+	}
+
+	if c.list || (len(c.save) == 0 && len(c.rename) == 0 && len(c.delete) == 0) {
+		resp, err := _proto.ConnectionProfiles()
+		if err != nil {
+			return err
+		}
+
+		if len(resp.Profiles) == 0 {
+			fmt.Println("No connection profiles defined")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		for _, p := range resp.Profiles {
+			suffix := ""
+			if strings.EqualFold(p.Name, resp.AutoconnectProfile) {
+				suffix = " (auto-connect)"
+			}
+			fmt.Fprintf(w, "%s%s\t:\t%s\n", p.Name, suffix, connectionParamsDescription(p.Params))
+		}
+		w.Flush()
+	}
+
+	return nil
+}
+
+// getConnectionProfile returns connection profile by name (case-insensitive)
+func getConnectionProfile(name string) (preferences.ConnectionProfile, error) {
+	resp, err := _proto.ConnectionProfiles()
+	if err != nil {
+		return preferences.ConnectionProfile{}, err
+	}
+	for _, p := range resp.Profiles {
+		if p.IsNameEqual(name) {
+			return p, nil
+		}
+	}
+	return preferences.ConnectionProfile{}, fmt.Errorf("connection profile '%s' not found", name)
+}
+
+// connectionParamsDescription returns short description of connection parameters
+// (e.g. "WireGuard us-ny1.wg.ivpn.net -> ch1 UDP:2049")
+func connectionParamsDescription(params service_types.ConnectionParams) string {
+	entryHost := ""
+	exitSrv := ""
+	if params.VpnType == vpn.WireGuard {
+		if len(params.WireGuardParameters.EntryVpnServer.Hosts) > 0 {
+			entryHost = params.WireGuardParameters.EntryVpnServer.Hosts[0].Hostname
+		}
+		exitSrv = params.WireGuardParameters.MultihopExitServer.ExitSrvID
+	} else {
+		if len(params.OpenVpnParameters.EntryVpnServer.Hosts) > 0 {
+			entryHost = params.OpenVpnParameters.EntryVpnServer.Hosts[0].Hostname
+		}
+		exitSrv = params.OpenVpnParameters.MultihopExitServer.ExitSrvID
+	}
+
+	ret := fmt.Sprintf("%s %s", params.VpnType, entryHost)
+	if params.IsMultiHop() && len(exitSrv) > 0 {
+		ret += " -> " + exitSrv
+	}
+
+	portNum, isTcp := params.Port()
+	portStr := (&port{port: portNum, tcp: isTcp}).String()
+	ret += " " + portStr
+
+	if v2ray := params.V2Ray(); v2ray != v2r.None {
+		ret += " (V2Ray " + v2ray.ToString() + ")"
+	} else if params.VpnType == vpn.OpenVPN && params.OpenVpnParameters.Obfs4proxy.IsObfsproxy() {
+		ret += " (" + params.OpenVpnParameters.Obfs4proxy.ToString() + ")"
+	}
+	return ret
+}
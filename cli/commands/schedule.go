@@ -0,0 +1,245 @@
+//
+//  IVPN command line interface (CLI)
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the IVPN command line interface.
+//
+//  The IVPN command line interface is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The IVPN command line interface is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the IVPN command line interface. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ivpn/desktop-app/cli/flags"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/service/scheduler"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
+)
+
+type CmdSchedule struct {
+	flags.CmdInfo
+	list     bool
+	add      string
+	at       string
+	action   string
+	profile  string
+	server   string
+	location string
+	enable   string
+	disable  string
+	delete   string
+}
+
+func (c *CmdSchedule) Init() {
+	c.KeepArgsOrderInHelp = true
+
+	actions := make([]string, 0, len(preferences.ScheduleActions))
+	for _, a := range preferences.ScheduleActions {
+		actions = append(actions, string(a))
+	}
+
+	c.Initialize("schedule", "Manage scheduled actions (connect/disconnect VPN, enable/disable firewall ...)")
+	c.BoolVar(&c.list, "list", false, "(default) Show scheduler rules")
+	c.StringVar(&c.add, "add", "", "NAME",
+		`Add (or replace) scheduler rule
+		Use in combination with '-at' and '-action' (and optional '-profile', '-server', '-location')
+		Example:
+			ivpn schedule -add work -at '0 8 * * mon-fri' -action connect -profile nl-fastest
+			ivpn schedule -add morning -at '0 8 * * *' -action connect -server fastest -location NL
+			ivpn schedule -add night -at '0 22 * * *' -action firewall_persistent_on
+			ivpn schedule -add resume -at @midnight -action resume`)
+	c.StringVar(&c.at, "at", "", "SCHEDULE",
+		`Cron-like schedule: 'MINUTE HOUR DAY_OF_MONTH MONTH DAY_OF_WEEK'
+		Each field can contain: '*', value ('5'), range ('1-5'), list ('1,3,5'), step ('*/15')
+		Months and days of week can be defined by names ('jan'...'dec'; 'sun'...'sat')
+		Predefined: @hourly, @daily (@midnight), @weekly, @monthly, @weekdays, @weekends`)
+	c.StringVar(&c.action, "action", "", "ACTION", "Action to perform: "+strings.Join(actions, ", "))
+	c.StringVar(&c.profile, "profile", "", "PROFILE", "Connection profile for 'connect' action (default - the last used connection parameters)\n  Tip: use 'ivpn profile' command to manage connection profiles")
+	c.StringVar(&c.server, "server", "", "SELECTION",
+		`Server selection for 'connect' action: fastest, best, random
+		(default - the server of the connection profile or of the last used connection parameters)`)
+	c.StringVar(&c.location, "location", "", "LOCATION",
+		`Location of the servers for '-server' option: country code ('NL'), country, city or gateway ID ('nl-ams')`)
+	c.StringVar(&c.enable, "enable", "", "NAME", "Enable scheduler rule")
+	c.StringVar(&c.disable, "disable", "", "NAME", "Disable scheduler rule")
+	c.StringVar(&c.delete, "delete", "", "NAME", "Remove scheduler rule")
+}
+
+func (c *CmdSchedule) Run() error {
+	if len(c.add) == 0 && (len(c.at) > 0 || len(c.action) > 0 || len(c.profile) > 0 || len(c.server) > 0 || len(c.location) > 0) {
+		return flags.BadParameter{Message: "options '-at', '-action', '-profile', '-server' and '-location' can be used only in combination with '-add'"}
+	}
+	if len(c.location) > 0 && len(c.server) == 0 {
+		return flags.BadParameter{Message: "option '-location' can be used only in combination with '-server'"}
+	}
+
+	if len(c.add) > 0 {
+		if len(c.at) == 0 || len(c.action) == 0 {
+			return flags.BadParameter{Message: "options '-at' and '-action' are required for '-add'"}
+		}
+		serverSelection, err := parseScheduleServerSelection(c.server)
+		if err != nil {
+			return err
+		}
+		rule := preferences.ScheduleRule{
+			Name:              c.add,
+			IsEnabled:         true,
+			Schedule:          c.at,
+			Action:            preferences.ScheduleActionType(strings.ToLower(c.action)),
+			ConnectionProfile: c.profile,
+			ServerSelection:   serverSelection,
+			ServerLocation:    c.location,
+		}
+		if err := rule.Validate(); err != nil {
+			return flags.BadParameter{Message: err.Error()}
+		}
+
+		if existing, e := getScheduleRule(c.add); e == nil {
+			err = _proto.ScheduleRuleUpdate(existing.Name, rule)
+		} else {
+			err = _proto.ScheduleRuleCreate(rule)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Scheduler rule '%s' saved\n", c.add)
+	}
+
+	for _, name := range []string{c.enable, c.disable} {
+		if len(name) == 0 {
+			continue
+		}
+		rule, err := getScheduleRule(name)
+		if err != nil {
+			return err
+		}
+		rule.IsEnabled = name == c.enable
+		if err := _proto.ScheduleRuleUpdate(rule.Name, rule); err != nil {
+			return err
+		}
+		if rule.IsEnabled {
+			fmt.Printf("Scheduler rule '%s' enabled\n", rule.Name)
+		} else {
+			fmt.Printf("Scheduler rule '%s' disabled\n", rule.Name)
+		}
+	}
+
+	if len(c.delete) > 0 {
+		if err := _proto.ScheduleRuleDelete(c.delete); err != nil {
+			return err
+		}
+		fmt.Printf("Scheduler rule '%s' removed\n", c.delete)
+	}
+
+	if c.list || (len(c.add) == 0 && len(c.enable) == 0 && len(c.disable) == 0 && len(c.delete) == 0) {
+		resp, err := _proto.ScheduleRules()
+		if err != nil {
+			return err
+		}
+
+		if len(resp.Rules) == 0 {
+			fmt.Println("No scheduler rules defined")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		fmt.Fprintf(w, "NAME\tSCHEDULE\tACTION\tNEXT RUN\t\n")
+		for _, r := range resp.Rules {
+			action := string(r.Action)
+			if details := scheduleRuleConnectDetails(r); len(details) > 0 {
+				action += " (" + details + ")"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", r.Name, r.Schedule, action, scheduleNextRun(r))
+		}
+		w.Flush()
+	}
+
+	return nil
+}
+
+// getScheduleRule returns scheduler rule by name (case-insensitive)
+func getScheduleRule(name string) (preferences.ScheduleRule, error) {
+	resp, err := _proto.ScheduleRules()
+	if err != nil {
+		return preferences.ScheduleRule{}, err
+	}
+	for _, r := range resp.Rules {
+		if r.IsNameEqual(name) {
+			return r, nil
+		}
+	}
+	return preferences.ScheduleRule{}, fmt.Errorf("scheduler rule '%s' not found", name)
+}
+
+// parseScheduleServerSelection converts '-server' option value to the server selection type
+func parseScheduleServerSelection(val string) (service_types.ServerSelectionEnum, error) {
+	switch strings.ToLower(strings.TrimSpace(val)) {
+	case "":
+		return service_types.Default, nil
+	case "fastest":
+		return service_types.Fastest, nil
+	case "best":
+		return service_types.Best, nil
+	case "random":
+		return service_types.Random, nil
+	}
+	return service_types.Default, flags.BadParameter{Message: fmt.Sprintf("unsupported server selection '%s' (expected: fastest, best, random)", val)}
+}
+
+// scheduleRuleConnectDetails returns the description of connection parameters of the 'connect' rule (e.g. "work; fastest NL")
+func scheduleRuleConnectDetails(r preferences.ScheduleRule) string {
+	var details []string
+	if len(r.ConnectionProfile) > 0 {
+		details = append(details, r.ConnectionProfile)
+	}
+	server := ""
+	switch r.ServerSelection {
+	case service_types.Fastest:
+		server = "fastest"
+	case service_types.Best:
+		server = "best"
+	case service_types.Random:
+		server = "random"
+	}
+	if len(server) > 0 {
+		if len(r.ServerLocation) > 0 {
+			server += " " + r.ServerLocation
+		}
+		details = append(details, server)
+	}
+	return strings.Join(details, "; ")
+}
+
+func scheduleNextRun(r preferences.ScheduleRule) string {
+	if !r.IsEnabled {
+		return "disabled"
+	}
+	s, err := scheduler.Parse(r.Schedule)
+	if err != nil {
+		return "-"
+	}
+	next := s.Next(time.Now())
+	if next.IsZero() {
+		return "-"
+	}
+	return next.Format("2006-01-02 15:04 Mon")
+}
@@ -26,10 +26,13 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 
 	apitypes "github.com/ivpn/desktop-app/daemon/api/types"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
 	"github.com/ivpn/desktop-app/daemon/vpn"
 
 	"github.com/ivpn/desktop-app/cli/flags"
@@ -52,6 +55,14 @@ type CmdServers struct {
 	hosts        bool
 	load         bool
 	filterInvert bool
+	group        string
+	score        bool
+	scoreWeights string
+
+	pinHost         string
+	unpinHost       string
+	blacklistHost   string
+	unblacklistHost string
 }
 
 func (c *CmdServers) Init() {
@@ -75,14 +86,47 @@ func (c *CmdServers) Init() {
 	c.BoolVar(&c.ping, "ping", false, "Ping servers and view ping result")
 
 	c.BoolVar(&c.hosts, "hosts", false, "Show location hosts")
-	c.BoolVar(&c.load, "load", false, "Show load info for each host")
+	c.BoolVar(&c.load, "load", false, "Update and show load info for each host")
 
 	c.BoolVar(&c.filterInvert, "filter_invert", false, "Invert filtering result")
+
+	c.StringVar(&c.group, "group", "", "NAME", "Show only the servers of the group ('favourites' - favourite servers)\n  Tip: use `ivpn group` command to manage server groups and favourite servers")
+
+	c.BoolVar(&c.score, "score", false, "Show servers sorted by score (the servers selection for 'ivpn connect -best')")
+	c.StringVar(&c.scoreWeights, "score_weights", "", "LATENCY:LOAD:DISTANCE:HISTORY", "Set weights of the factors for the servers score (e.g. '0.5:0.25:0.1:0.15')\n  Use 'default' to restore default weights. A weight of 0 disables the factor")
+
+	c.StringVar(&c.pinHost, "pin_host", "", "HOSTNAME", "Pin the host: only pinned hosts of the server are in use for connections to this server\n  (use 'ivpn servers -hosts' to get the list of hosts)")
+	c.StringVar(&c.unpinHost, "unpin_host", "", "HOSTNAME", "Remove the host from the pinned hosts (use 'all' to unpin all hosts)")
+	c.StringVar(&c.blacklistHost, "blacklist_host", "", "HOSTNAME", "Blacklist the host: it will never be in use for connections")
+	c.StringVar(&c.unblacklistHost, "unblacklist_host", "", "HOSTNAME", "Remove the host from the blacklist (use 'all' to clear the blacklist)")
 }
 func (c *CmdServers) Run() error {
 	var servers apitypes.ServersInfoResponse
 	var err error
 
+	if len(c.scoreWeights) > 0 {
+		weights, err := parseScoreWeights(c.scoreWeights)
+		if err != nil {
+			return err
+		}
+		if err := _proto.SetServerScoringWeights(weights); err != nil {
+			return err
+		}
+		printScoreWeights(weights)
+		if !c.score {
+			return nil
+		}
+	}
+
+	if len(c.pinHost) > 0 || len(c.unpinHost) > 0 || len(c.blacklistHost) > 0 || len(c.unblacklistHost) > 0 {
+		if err := c.updateHostsSelection(); err != nil {
+			return err
+		}
+		if !c.hosts && !c.load {
+			return nil
+		}
+	}
+
 	isServersLoaded := false
 	if c.load {
 		fmt.Println("Updating servers load info...")
@@ -104,6 +148,18 @@ func (c *CmdServers) Run() error {
 
 	slist := serversList(servers)
 
+	if len(c.group) > 0 {
+		group, err := getServerGroup(c.group)
+		if err != nil {
+			return err
+		}
+		slist = serversFilterByGroup(slist, group)
+	}
+
+	if c.score {
+		return c.showScores(slist)
+	}
+
 	if c.ping {
 		var vpnType *vpn.Type = nil
 		if len(c.proto) > 0 {
@@ -126,9 +182,7 @@ func (c *CmdServers) Run() error {
 	}
 	if c.hosts {
 		hostsHeader = "HOSTS\t"
-		if c.load {
-			hostsLoadHeader = "LOAD\t"
-		}
+		hostsLoadHeader = "LOAD\t"
 	}
 
 	fmt.Fprintln(w, "PROTOCOL\tLOCATION\tCITY\tCOUNTRY\tISP\tIPv? tunnel\t"+pingHeader+hostsHeader+hostsLoadHeader)
@@ -136,6 +190,7 @@ func (c *CmdServers) Run() error {
 	helloResp := _proto.GetHelloResponse()
 	isWgDisabled := len(helloResp.DisabledFunctions.WireGuardError) > 0
 	isOpenVPNDisabled := len(helloResp.DisabledFunctions.OpenVPNError) > 0
+	hostsSelection := helloResp.DaemonSettings.HostsSelection
 
 	svrs := serversFilter(isWgDisabled, isOpenVPNDisabled,
 		slist, c.filter, c.proto, c.location, c.city, c.countryCode, c.country, c.filterInvert)
@@ -159,11 +214,10 @@ func (c *CmdServers) Run() error {
 		if c.hosts {
 			firstHostStr = "\t"
 			if len(s.hosts) > 0 {
-				firstHostStr = s.hosts[0].hostname + "\t"
-				if c.load {
-
-					firstHostLoadStr = fmt.Sprintf("%d", int(s.hosts[0].load+0.5)) + "%\t"
-				}
+				firstHostStr = hostnameWithSelectionMark(hostsSelection, s.hosts[0].hostname) + "\t"
+				firstHostLoadStr = fmt.Sprintf("%d", int(s.hosts[0].load+0.5)) + "%\t"
+			} else {
+				firstHostLoadStr = "\t"
 			}
 		}
 
@@ -179,11 +233,8 @@ func (c *CmdServers) Run() error {
 					}
 				}
 
-				loadStr := ""
-				if c.load {
-					loadStr = fmt.Sprintf("%d", int(h.load+0.5)) + "%\t"
-				}
-				str = fmt.Sprintf("%s\t%s\t%s %s\t %s\t%s\t%s\t%s%s%s", "", "", "", "", "", "", "", pingStr, h.hostname+"\t", loadStr)
+				loadStr := fmt.Sprintf("%d", int(h.load+0.5)) + "%\t"
+				str = fmt.Sprintf("%s\t%s\t%s %s\t %s\t%s\t%s\t%s%s%s", "", "", "", "", "", "", "", pingStr, hostnameWithSelectionMark(hostsSelection, h.hostname)+"\t", loadStr)
 				fmt.Fprintln(w, str)
 			}
 		}
@@ -202,6 +253,214 @@ func (c *CmdServers) Run() error {
 	return nil
 }
 
+// updateHostsSelection modifies the pinned/blacklisted hosts according to the command flags and sends them to the daemon
+func (c *CmdServers) updateHostsSelection() error {
+	servers, err := _proto.GetServers()
+	if err != nil {
+		return err
+	}
+	slist := serversList(servers)
+
+	params := _proto.GetHelloResponse().DaemonSettings.HostsSelection
+
+	if len(c.unpinHost) > 0 {
+		if strings.EqualFold(c.unpinHost, "all") {
+			params.Pinned = nil
+		} else {
+			if !params.IsPinned(c.unpinHost) {
+				return flags.BadParameter{Message: fmt.Sprintf("host '%s' is not pinned", c.unpinHost)}
+			}
+			params.Pinned = removeHostname(params.Pinned, c.unpinHost)
+		}
+	}
+	if len(c.unblacklistHost) > 0 {
+		if strings.EqualFold(c.unblacklistHost, "all") {
+			params.Blacklisted = nil
+		} else {
+			if !params.IsBlacklisted(c.unblacklistHost) {
+				return flags.BadParameter{Message: fmt.Sprintf("host '%s' is not blacklisted", c.unblacklistHost)}
+			}
+			params.Blacklisted = removeHostname(params.Blacklisted, c.unblacklistHost)
+		}
+	}
+	if len(c.pinHost) > 0 {
+		hostname, err := findHostname(slist, c.pinHost)
+		if err != nil {
+			return err
+		}
+		params.Blacklisted = removeHostname(params.Blacklisted, hostname)
+		if !params.IsPinned(hostname) {
+			params.Pinned = append(params.Pinned, hostname)
+		}
+	}
+	if len(c.blacklistHost) > 0 {
+		hostname, err := findHostname(slist, c.blacklistHost)
+		if err != nil {
+			return err
+		}
+		params.Pinned = removeHostname(params.Pinned, hostname)
+		if !params.IsBlacklisted(hostname) {
+			params.Blacklisted = append(params.Blacklisted, hostname)
+		}
+	}
+
+	if err := params.Validate(); err != nil {
+		return flags.BadParameter{Message: err.Error()}
+	}
+	if err := _proto.SetHostsSelection(params); err != nil {
+		return err
+	}
+
+	printHostsSelection(params)
+	return nil
+}
+
+// findHostname returns the host name (as defined in the servers list) or error when the host not exists
+func findHostname(servers []serverDesc, hostname string) (string, error) {
+	hostname = strings.TrimSpace(hostname)
+	for _, s := range servers {
+		for _, h := range s.hosts {
+			if strings.EqualFold(h.hostname, hostname) {
+				return h.hostname, nil
+			}
+		}
+	}
+	return "", flags.BadParameter{Message: fmt.Sprintf("host '%s' not found (use 'ivpn servers -hosts' to get the list of hosts)", hostname)}
+}
+
+func removeHostname(list []string, hostname string) []string {
+	var ret []string
+	for _, h := range list {
+		if !strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(hostname)) {
+			ret = append(ret, h)
+		}
+	}
+	return ret
+}
+
+func printHostsSelection(params preferences.HostsSelectionParams) {
+	pinned := "none"
+	if len(params.Pinned) > 0 {
+		pinned = strings.Join(params.Pinned, ", ")
+	}
+	blacklisted := "none"
+	if len(params.Blacklisted) > 0 {
+		blacklisted = strings.Join(params.Blacklisted, ", ")
+	}
+	fmt.Printf("Pinned hosts     : %s\n", pinned)
+	fmt.Printf("Blacklisted hosts: %s\n", blacklisted)
+}
+
+func hostnameWithSelectionMark(params preferences.HostsSelectionParams, hostname string) string {
+	if params.IsPinned(hostname) {
+		return hostname + " (pinned)"
+	}
+	if params.IsBlacklisted(hostname) {
+		return hostname + " (blacklisted)"
+	}
+	return hostname
+}
+
+// showScores prints servers sorted by score (the scores are calculated by the daemon)
+func (c *CmdServers) showScores(slist []serverDesc) error {
+	helloResp := _proto.GetHelloResponse()
+	isWgDisabled := len(helloResp.DisabledFunctions.WireGuardError) > 0
+	isOpenVPNDisabled := len(helloResp.DisabledFunctions.OpenVPNError) > 0
+
+	svrs := serversFilter(isWgDisabled, isOpenVPNDisabled,
+		slist, c.filter, c.proto, c.location, c.city, c.countryCode, c.country, c.filterInvert)
+
+	svrsByGateway := make(map[string]serverDesc, len(svrs))
+	gatewaysWg := []string{}
+	gatewaysOvpn := []string{}
+	for _, s := range svrs {
+		svrsByGateway[s.gateway] = s
+		if s.protocol == ProtoName_WireGuard {
+			gatewaysWg = append(gatewaysWg, s.gateway)
+		} else {
+			gatewaysOvpn = append(gatewaysOvpn, s.gateway)
+		}
+	}
+
+	fmt.Println("Calculating servers score ...")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight|tabwriter.Debug)
+	fmt.Fprintln(w, "PROTOCOL\tLOCATION\tCITY\tSCORE\tPING\tLOAD\tDISTANCE\tCONNECTED\t")
+
+	for _, p := range []struct {
+		vpnType  vpn.Type
+		gateways []string
+	}{{vpn.WireGuard, gatewaysWg}, {vpn.OpenVPN, gatewaysOvpn}} {
+		if len(p.gateways) == 0 {
+			continue
+		}
+		scores, err := _proto.ServerScores(p.vpnType, p.gateways)
+		if err != nil {
+			return err
+		}
+		for _, sc := range scores {
+			s := svrsByGateway[sc.Gateway]
+
+			pingStr := " ? "
+			if sc.LatencyMs > 0 {
+				pingStr = fmt.Sprintf("%dms", sc.LatencyMs)
+			}
+			distanceStr := " ? "
+			if sc.DistanceKm >= 0 {
+				distanceStr = fmt.Sprintf("%dkm", int(sc.DistanceKm+0.5))
+			}
+			connStr := "-"
+			if sc.ConnectAttempts > 0 {
+				connStr = fmt.Sprintf("%d/%d", sc.ConnectSuccesses, sc.ConnectAttempts)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s (%s)\t%.1f\t%s\t%d%%\t%s\t%s\t\n",
+				s.protocol, sc.Gateway, s.city, s.countryCode, sc.Score, pingStr, int(sc.Load+0.5), distanceStr, connStr)
+		}
+	}
+	w.Flush()
+
+	printScoreWeights(helloResp.DaemonSettings.ServerScoring)
+	return nil
+}
+
+func parseScoreWeights(value string) (service_types.ServerScoringWeights, error) {
+	var weights service_types.ServerScoringWeights
+	if strings.ToLower(strings.TrimSpace(value)) == "default" {
+		return weights, nil
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 4 {
+		return weights, flags.BadParameter{Message: "expected four weights: LATENCY:LOAD:DISTANCE:HISTORY"}
+	}
+	values := make([]float64, len(parts))
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return weights, flags.BadParameter{Message: fmt.Sprintf("bad weight value '%s'", p)}
+		}
+		values[i] = v
+	}
+	weights = service_types.ServerScoringWeights{Latency: values[0], Load: values[1], Distance: values[2], History: values[3]}
+	if err := weights.Validate(); err != nil {
+		return weights, flags.BadParameter{Message: err.Error()}
+	}
+	if weights.IsDefault() {
+		return weights, flags.BadParameter{Message: "at least one weight must be greater than 0 (use 'default' to restore default weights)"}
+	}
+	return weights, nil
+}
+
+func printScoreWeights(weights service_types.ServerScoringWeights) {
+	defaultStr := ""
+	if weights.IsDefault() {
+		defaultStr = " (default)"
+	}
+	w := weights.Effective()
+	fmt.Printf("Score weights%s: latency=%g; load=%g; distance=%g; history=%g\n", defaultStr, w.Latency, w.Load, w.Distance, w.History)
+}
+
 // ---------------------
 
 func getVpnTypeByFlag(proto string) (t vpn.Type, err error) {
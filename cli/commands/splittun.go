@@ -26,14 +26,17 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 
 	"github.com/ivpn/desktop-app/cli/cliplatform"
 	"github.com/ivpn/desktop-app/cli/flags"
 	"github.com/ivpn/desktop-app/cli/helpers"
 	"github.com/ivpn/desktop-app/daemon/protocol/types"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
 )
 
 type Exclude struct {
@@ -170,6 +173,13 @@ type SplitTun struct {
 	off               bool
 	reset             bool
 
+	ruleAdd      string
+	ruleRemove   string
+	addRoute     string
+	removeRoute  string
+	addDomain    string
+	removeDomain string
+
 	appremove  string
 	appadd     string // this parameter is not in use. We need it just for help info (using 'appaddArgs' parsed with specific logic)
 	appaddArgs []string
@@ -202,8 +212,15 @@ func (c *SplitTun) Init() {
 		c.BoolVar(&c.reset, "clean", false, "Erase configuration (remove applications from configuration and disable Split Tunnel)")
 		c.StringVar(&c.appadd, "appadd", "", "COMMAND", "Execute command (binary) in Split Tunnel environment\nInfo: short version of this command is 'ivpn exclude <command>'\nExamples:\n    ivpn splittun -appadd firefox\n    ivpn splittun -appadd ping 1.1.1.1\n    ivpn splittun -appadd /usr/bin/google-chrome")
 		c.StringVar(&c.appremove, "appremove", "", "PID", "Remove application from Split Tunnel environment\n(argument: Process ID)")
+		c.StringVar(&c.ruleAdd, "ruleadd", "", "PATH", "Add persistent rule: any process of the application is added to Split Tunnel environment automatically\n(argument: absolute path to the executable or desktop file ID)\nExamples:\n    ivpn splittun -ruleadd /usr/bin/firefox\n    ivpn splittun -ruleadd org.gnome.Epiphany.desktop")
+		c.StringVar(&c.ruleRemove, "ruleremove", "", "PATH", "Remove persistent rule from configuration")
 	}
 
+	c.StringVar(&c.addRoute, "add-route", "", "CIDR", "Add destination network (IP address or CIDR) to configuration\n(traffic to this network does not use the VPN; in inverse mode - only this traffic uses the VPN)\nNote! Supported only on Linux with cgroup v2\nExample:\n    ivpn splittun -add-route 192.168.10.0/24")
+	c.StringVar(&c.removeRoute, "remove-route", "", "CIDR", "Remove destination network from configuration")
+	c.StringVar(&c.addDomain, "add-domain", "", "DOMAIN", "Add destination domain to configuration\n(the addresses of the domain and its subdomains are taken from the DNS answers received by applications\nand processed the same way as networks; the DNS answers are tracked only while VPN is connected)\nNote! Supported only on Linux with cgroup v2\nExample:\n    ivpn splittun -add-domain example.com")
+	c.StringVar(&c.removeDomain, "remove-domain", "", "DOMAIN", "Remove destination domain from configuration")
+
 	c.BoolVar(&c.onInverse, cmd_name_on_inverse, false,
 		`Enable inverse mode. Only specified applications utilize the VPN connection,
 		while all other traffic circumvents the VPN, using the default connection.`)
@@ -241,6 +258,15 @@ func (c *SplitTun) Run() error {
 	if len(c.appadd) > 0 && len(c.appremove) > 0 {
 		return flags.ConflictingParameters{}
 	}
+	configOps := 0
+	for _, v := range []string{c.ruleAdd, c.ruleRemove, c.addRoute, c.removeRoute, c.addDomain, c.removeDomain} {
+		if len(v) > 0 {
+			configOps++
+		}
+	}
+	if configOps > 1 {
+		return flags.ConflictingParameters{}
+	}
 
 	cfg, err := _proto.GetSplitTunnelStatus()
 	if err != nil {
@@ -309,6 +335,31 @@ func (c *SplitTun) Run() error {
 		return c.doShowStatusShort(cfg)
 	}
 
+	if len(c.ruleAdd) > 0 || len(c.ruleRemove) > 0 || len(c.addRoute) > 0 || len(c.removeRoute) > 0 || len(c.addDomain) > 0 || len(c.removeDomain) > 0 {
+		if len(c.ruleAdd) > 0 {
+			err = _proto.SplitTunnelAddAppRule(c.ruleAdd)
+		} else if len(c.ruleRemove) > 0 {
+			err = _proto.SplitTunnelRemoveAppRule(c.ruleRemove)
+		} else if len(c.addRoute) > 0 {
+			err = _proto.SplitTunnelAddRoute(c.addRoute)
+		} else if len(c.removeRoute) > 0 {
+			err = _proto.SplitTunnelRemoveRoute(c.removeRoute)
+		} else if len(c.addDomain) > 0 {
+			err = _proto.SplitTunnelAddDomain(c.addDomain)
+		} else {
+			err = _proto.SplitTunnelRemoveDomain(c.removeDomain)
+		}
+		if err != nil {
+			return err
+		}
+
+		cfg, err = _proto.GetSplitTunnelStatus()
+		if err != nil {
+			return err
+		}
+		return c.doShowStatus(cfg, c.statusFull)
+	}
+
 	if len(c.appaddArgs) > 0 || len(c.appremove) > 0 {
 		if len(c.appaddArgs) > 0 {
 			if err = doAddApp(c.appaddArgs, "", false); err != nil {
@@ -331,6 +382,10 @@ func (c *SplitTun) Run() error {
 
 func (c *SplitTun) doShowStatus(cfg types.SplitTunnelStatus, isFull bool) error {
 	w := printSplitTunState(nil, false, isFull, cfg.IsEnabled, cfg.IsInversed, cfg.IsAnyDns, cfg.IsAllowWhenNoVpn, cfg.SplitTunnelApps, cfg.RunningApps)
+	printSplitTunDestinations(w, cfg)
+	if isFull && cfg.IsEnabled {
+		printSplitTunTraffic(w, cfg)
+	}
 	w.Flush()
 	return nil
 }
@@ -348,3 +403,70 @@ func (c *SplitTun) specialParse(arguments []string) bool {
 	}
 	return false
 }
+
+func printSplitTunDestinations(w *tabwriter.Writer, cfg types.SplitTunnelStatus) {
+	for i, r := range cfg.SplitTunnelRoutes {
+		if i == 0 {
+			fmt.Fprintf(w, "Split Tunnel networks\t:\t%v\n", r)
+		} else {
+			fmt.Fprintf(w, "\t\t%v\n", r)
+		}
+	}
+
+	resolved := make(map[string]service_types.SplitTunnelDomainStatus, len(cfg.SplitTunnelDomainsResolved))
+	for _, st := range cfg.SplitTunnelDomainsResolved {
+		resolved[st.Domain] = st
+	}
+	for i, d := range cfg.SplitTunnelDomains {
+		info := d
+		if st, ok := resolved[d]; ok {
+			if len(st.IPs) > 0 {
+				info += " (" + strings.Join(st.IPs, ", ") + ")"
+			}
+			if len(st.Error) > 0 {
+				info += " (" + st.Error + ")"
+			}
+		}
+		if i == 0 {
+			fmt.Fprintf(w, "Split Tunnel domains\t:\t%v\n", info)
+		} else {
+			fmt.Fprintf(w, "\t\t%v\n", info)
+		}
+	}
+}
+
+func printSplitTunTraffic(w *tabwriter.Writer, cfg types.SplitTunnelStatus) {
+	if len(cfg.AppsTrafficError) > 0 {
+		fmt.Fprintf(w, "Split Tunnel traffic\t:\t(not available: %s)\n", cfg.AppsTrafficError)
+		return
+	}
+
+	apps := cfg.AppsTraffic
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].Pid < apps[j].Pid
+	})
+
+	for i, a := range apps {
+		info := fmt.Sprintf("[pid:%d] sent: %s (%d packets); received: %s (%d packets); %s",
+			a.Pid, formatBytes(a.BytesSent), a.PacketsSent, formatBytes(a.BytesReceived), a.PacketsReceived, a.Cmdline)
+		if i == 0 {
+			fmt.Fprintf(w, "Split Tunnel traffic\t:\t%v\n", info)
+		} else {
+			fmt.Fprintf(w, "\t\t%v\n", info)
+		}
+	}
+}
+
+// formatBytes returns human-readable representation of the data size (e.g. "1.5 MiB")
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -24,26 +24,41 @@ package commands
 
 import (
 	"fmt"
+	"os"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/ivpn/desktop-app/cli/flags"
 	apitypes "github.com/ivpn/desktop-app/daemon/api/types"
 	"github.com/ivpn/desktop-app/daemon/service/srverrors"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
 	"github.com/ivpn/desktop-app/daemon/vpn"
 )
 
 type CmdState struct {
 	flags.CmdInfo
+	stats bool
+	watch bool
 }
 
 func (c *CmdState) Init() {
 	c.Initialize("status", "Prints full info about IVPN state")
+	c.BoolVar(&c.stats, "stats", false, "Show traffic statistics of the VPN tunnel (received/sent data, throughput, latest handshake)")
+	c.BoolVar(&c.watch, "watch", false, "Continuously print traffic statistics of the VPN tunnel every second (press Ctrl+C to stop)")
 }
 func (c *CmdState) Run() error {
-	return showState()
+	if c.watch {
+		return watchTunnelStatistics()
+	}
+	return showStateEx(c.stats)
 }
 
 func showState() error {
+	return showStateEx(false)
+}
+
+func showStateEx(isShowStatistics bool) error {
 	fwstate, err := _proto.FirewallStatus()
 	if err != nil {
 		return err
@@ -77,6 +92,13 @@ func showState() error {
 	printState(w, state, connected, serverInfo, exitServerInfo, _proto.GetHelloResponse())
 	if state == vpn.CONNECTED {
 		printDNSState(w, connected.Dns, &servers)
+		if isShowStatistics {
+			if stats, err := _proto.TunnelStatistics(); err != nil {
+				fmt.Fprintf(w, "Traffic\t:\t(not available: %v)\n", err)
+			} else {
+				printTunnelStatistics(w, stats)
+			}
+		}
 	}
 	if !stStatus.IsFunctionalityNotAvailable {
 		printSplitTunState(w, true, false, stStatus.IsEnabled, stStatus.IsInversed, stStatus.IsAnyDns, stStatus.IsAllowWhenNoVpn, stStatus.SplitTunnelApps, stStatus.RunningApps)
@@ -139,3 +161,76 @@ func getServerInfoByHostName(servers []serverDesc, hostname string) string {
 func ConnectedServerInfo(s serverDesc, host hostDesc) string {
 	return fmt.Sprintf("%s [%s], %s (%s), %s", s.gateway, host.hostname, s.city, s.countryCode, s.country)
 }
+
+func printTunnelStatistics(w *tabwriter.Writer, stats service_types.TunnelStatistics) *tabwriter.Writer {
+	if w == nil {
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	}
+
+	if !stats.IsConnected {
+		fmt.Fprintf(w, "Traffic\t:\tVPN is not connected\n")
+		return w
+	}
+
+	fmt.Fprintf(w, "Traffic\t:\treceived %s (%s/s); sent %s (%s/s)\n",
+		formatBytes(stats.RxBytes), formatBytes(stats.RxBytesPerSec), formatBytes(stats.TxBytes), formatBytes(stats.TxBytesPerSec))
+
+	for _, p := range stats.Peers {
+		peer := p.Endpoint
+		if len(p.PublicKey) > 0 {
+			peer = strings.TrimSpace(fmt.Sprintf("%s [key: %s]", p.Endpoint, p.PublicKey))
+		}
+		if len(peer) == 0 {
+			peer = "(unknown)"
+		}
+		fmt.Fprintf(w, "Peer\t:\t%s\n", peer)
+		if len(stats.Peers) > 1 {
+			fmt.Fprintf(w, "\t\treceived %s (%s/s); sent %s (%s/s)\n",
+				formatBytes(p.RxBytes), formatBytes(p.RxBytesPerSec), formatBytes(p.TxBytes), formatBytes(p.TxBytesPerSec))
+		}
+		if stats.VpnType == vpn.WireGuard {
+			fmt.Fprintf(w, "\t\tlatest handshake: %s\n", formatHandshakeAge(p.LastHandshakeAgeSec))
+		}
+	}
+	return w
+}
+
+func watchTunnelStatistics() error {
+	const intervalSec = 1
+	isConnectedPrev := true
+
+	return _proto.TunnelStatisticsWatch(intervalSec, func(stats service_types.TunnelStatistics) bool {
+		now := time.Now().Format("15:04:05")
+		if !stats.IsConnected {
+			if isConnectedPrev {
+				fmt.Printf("%s  VPN is not connected\n", now)
+			}
+			isConnectedPrev = false
+			return true
+		}
+		isConnectedPrev = true
+
+		handshake := ""
+		if stats.VpnType == vpn.WireGuard {
+			// the most recent handshake of all peers
+			age := int64(-1)
+			for _, p := range stats.Peers {
+				if p.LastHandshakeAgeSec >= 0 && (age < 0 || p.LastHandshakeAgeSec < age) {
+					age = p.LastHandshakeAgeSec
+				}
+			}
+			handshake = "  handshake: " + formatHandshakeAge(age)
+		}
+
+		fmt.Printf("%s  rx: %s/s (%s)  tx: %s/s (%s)%s\n", now,
+			formatBytes(stats.RxBytesPerSec), formatBytes(stats.RxBytes), formatBytes(stats.TxBytesPerSec), formatBytes(stats.TxBytes), handshake)
+		return true
+	})
+}
+
+func formatHandshakeAge(ageSec int64) string {
+	if ageSec < 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%v ago", time.Duration(ageSec)*time.Second)
+}
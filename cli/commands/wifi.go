@@ -32,6 +32,7 @@ import (
 	"github.com/ivpn/desktop-app/cli/flags"
 	"github.com/ivpn/desktop-app/cli/helpers"
 	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
 )
 
 type actionType string
@@ -60,6 +61,9 @@ type CmdWiFi struct {
 	default_trust_status string //[none/trusted/untrusted]
 	set_trusted_action   string // [action:value] // actions: 'trusted_vpn_off:[true/false]', 'trusted_firewall_off', 'untrusted_vpn_on', 'untrusted_firewall_on', untrusted_block_lan
 	set_trusted_network  string // [network:status] (status: none/trusted/untrusted; e.g. 'my_home_wifi':trusted)
+	untrusted_profile    string // name of connection profile for 'untrusted_connect_vpn' action ('none' - use last connection parameters)
+	network_params       string // [network:source] (source: profile name/last/none; e.g. 'airport':multihop-tcp)
+	network_firewall     string // [network:policy] (policy: on/off/block_lan/none; e.g. 'airport':block_lan)
 	reset_settings       bool
 }
 
@@ -107,6 +111,37 @@ func (c *CmdWiFi) Init() {
 					Define current WiFi network as 'untrusted':
 						ivpn wifi -set_trusted_network untrusted`)
 
+	c.StringVar(&c.untrusted_profile, "untrusted_profile", "", "NAME",
+		`Connection profile to use when connecting to VPN on untrusted WiFi
+			(use 'none' to connect with the last used connection parameters)
+			Tip: use 'ivpn profile' command to manage connection profiles`)
+
+	c.StringVar(&c.network_params, "network_params", "", "CONFIG",
+		`Set connection parameters for WiFi network
+			The parameters are used when connecting to VPN on joining the network
+			(instead of the common connection parameters).
+			The network must be already defined by '-set_trusted_network'.
+			CONFIG parameter format: '<NETWORK_NAME>':<VALUE>
+				NETWORK_NAME: if empty - will be used WiFi network name which is currently connected
+				VALUE:
+					* <PROFILE_NAME> - copy parameters from connection profile
+					* last           - copy the last used connection parameters
+					* none           - remove network-specific connection parameters
+			Example:
+					ivpn wifi -network_params airport:multihop-tcp
+					ivpn wifi -network_params 'my home network':none`)
+
+	c.StringVar(&c.network_firewall, "network_firewall", "", "CONFIG",
+		`Set firewall policy for WiFi network
+			The policy is applied on joining the network (instead of the common actions).
+			The network must be already defined by '-set_trusted_network'.
+			CONFIG parameter format: '<NETWORK_NAME>':<VALUE>
+				NETWORK_NAME: if empty - will be used WiFi network name which is currently connected
+				VALUE: [on/off/block_lan/none]
+					(Set the value to "none" to use the common actions)
+			Example:
+					ivpn wifi -network_firewall airport:block_lan`)
+
 	c.BoolVar(&c.reset_settings, "reset_settings", false, "Reset WiFi settings to defaults")
 }
 
@@ -204,22 +239,11 @@ func (c *CmdWiFi) Run() error {
 	}
 
 	if len(c.set_trusted_network) > 0 {
-		//c.set_trusted_network = helpers.TrimSpacesAndRemoveQuotes(c.set_trusted_network)
-		dividerIdx := strings.LastIndex(c.set_trusted_network, ":")
+		netName, valueStr := parseNetworkConfig(c.set_trusted_network)
 
-		netName := ""
 		isTrusted := false
 		isUndefined := false
 
-		valueStr := ""
-		if dividerIdx >= 0 {
-			netName = c.set_trusted_network[:dividerIdx]
-		}
-		valueStr = c.set_trusted_network[dividerIdx+1:]
-
-		netName = helpers.TrimSpacesAndRemoveQuotes(netName)
-		valueStr = helpers.TrimSpacesAndRemoveQuotes(valueStr)
-
 		switch strings.ToLower(valueStr) {
 		case string(NoTrustState):
 			isUndefined = true
@@ -235,16 +259,9 @@ func (c *CmdWiFi) Run() error {
 					string(Untrusted))}
 		}
 
-		if len(netName) == 0 {
-			curNet, err := _proto.GetWiFiCurrentNetwork()
-			if err != nil {
-				return fmt.Errorf("failed to obtain info about the currently connected WiFi network: %w", err)
-			}
-			netName = curNet.SSID
-			if len(netName) == 0 {
-				return fmt.Errorf("Unable to obtain info about currently connected WiFi network. Please, specify network name")
-			}
-			fmt.Printf("WiFi network not defined. Using current network: '%s'\n", netName)
+		netName, err := ensureNetworkName(netName)
+		if err != nil {
+			return err
 		}
 
 		// check if network already exists
@@ -265,6 +282,78 @@ func (c *CmdWiFi) Run() error {
 		isSettingsChanged = true
 	}
 
+	if len(c.untrusted_profile) > 0 {
+		if strings.EqualFold(c.untrusted_profile, "none") {
+			wifiSettings.Actions.UnTrustedConnectVpnProfile = ""
+		} else {
+			wifiSettings.Actions.UnTrustedConnectVpnProfile = c.untrusted_profile
+		}
+		isSettingsChanged = true
+	}
+
+	if len(c.network_params) > 0 {
+		netName, valueStr := parseNetworkConfig(c.network_params)
+
+		var params *service_types.ConnectionParams
+		switch {
+		case strings.EqualFold(valueStr, "none"):
+			params = nil
+		case strings.EqualFold(valueStr, "last"):
+			settings, err := _proto.GetDefConnectionParams()
+			if err != nil {
+				return err
+			}
+			if err := settings.Params.CheckIsDefined(); err != nil {
+				return fmt.Errorf("the last used connection parameters are not defined; please connect VPN at least once: %w", err)
+			}
+			params = &settings.Params
+		default:
+			profile, err := getConnectionProfile(valueStr)
+			if err != nil {
+				return err
+			}
+			params = &profile.Params
+		}
+
+		idx, err := findNetworkIdx(wifiSettings.Networks, netName)
+		if err != nil {
+			return err
+		}
+		// copy networks list (do not modify the daemon settings cached in Hello response)
+		wifiSettings.Networks = append([]preferences.WiFiNetwork{}, wifiSettings.Networks...)
+		wifiSettings.Networks[idx].Rule = wifiNetworkRuleCopy(wifiSettings.Networks[idx].Rule)
+		wifiSettings.Networks[idx].Rule.ConnectionParams = params
+		isSettingsChanged = true
+	}
+
+	if len(c.network_firewall) > 0 {
+		netName, valueStr := parseNetworkConfig(c.network_firewall)
+
+		var policy *preferences.WiFiFirewallPolicy
+		switch strings.ToLower(valueStr) {
+		case "none":
+			policy = nil
+		case "on":
+			policy = &preferences.WiFiFirewallPolicy{IsEnabled: true}
+		case "off":
+			policy = &preferences.WiFiFirewallPolicy{IsEnabled: false}
+		case "block_lan":
+			policy = &preferences.WiFiFirewallPolicy{IsEnabled: true, BlockLan: true}
+		default:
+			return flags.BadParameter{
+				Message: fmt.Sprintf("not supported firewall policy '%s' (acceptable values: on, off, block_lan, none)", valueStr)}
+		}
+
+		idx, err := findNetworkIdx(wifiSettings.Networks, netName)
+		if err != nil {
+			return err
+		}
+		wifiSettings.Networks = append([]preferences.WiFiNetwork{}, wifiSettings.Networks...)
+		wifiSettings.Networks[idx].Rule = wifiNetworkRuleCopy(wifiSettings.Networks[idx].Rule)
+		wifiSettings.Networks[idx].Rule.Firewall = policy
+		isSettingsChanged = true
+	}
+
 	// reset all settings
 	if c.reset_settings {
 		fmt.Println("Resetting settings...")
@@ -293,6 +382,57 @@ func (c *CmdWiFi) Run() error {
 	return nil
 }
 
+// parseNetworkConfig splits network configuration parameter '<NETWORK_NAME>':<VALUE>
+// (NETWORK_NAME can be empty)
+func parseNetworkConfig(cfg string) (netName, value string) {
+	dividerIdx := strings.LastIndex(cfg, ":")
+	if dividerIdx >= 0 {
+		netName = cfg[:dividerIdx]
+	}
+	value = cfg[dividerIdx+1:]
+
+	return helpers.TrimSpacesAndRemoveQuotes(netName), helpers.TrimSpacesAndRemoveQuotes(value)
+}
+
+// ensureNetworkName returns network name or (if it is empty) name of the currently connected WiFi network
+func ensureNetworkName(netName string) (string, error) {
+	if len(netName) > 0 {
+		return netName, nil
+	}
+	curNet, err := _proto.GetWiFiCurrentNetwork()
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain info about the currently connected WiFi network: %w", err)
+	}
+	netName = curNet.SSID
+	if len(netName) == 0 {
+		return "", fmt.Errorf("Unable to obtain info about currently connected WiFi network. Please, specify network name")
+	}
+	fmt.Printf("WiFi network not defined. Using current network: '%s'\n", netName)
+	return netName, nil
+}
+
+// findNetworkIdx returns index of the network in the list
+func findNetworkIdx(networks []preferences.WiFiNetwork, netName string) (int, error) {
+	netName, err := ensureNetworkName(netName)
+	if err != nil {
+		return -1, err
+	}
+	for i, n := range networks {
+		if n.SSID == netName {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("WiFi network '%s' is not defined (use '-set_trusted_network' to define it)", netName)
+}
+
+func wifiNetworkRuleCopy(rule *preferences.WiFiNetworkRule) *preferences.WiFiNetworkRule {
+	if rule == nil {
+		return &preferences.WiFiNetworkRule{}
+	}
+	ret := *rule
+	return &ret
+}
+
 func isInsecureNetworksSuppported() bool {
 	return runtime.GOOS != "linux"
 }
@@ -348,6 +488,9 @@ func (c *CmdWiFi) printStatus(w *tabwriter.Writer) *tabwriter.Writer {
 	fmt.Fprintf(w, "Actions:\t\n")
 	fmt.Fprintf(w, "    Actions for Untrusted WiFi:\t\n")
 	fmt.Fprintf(w, "        Connect to VPN\t:\t%v\n", boolToStr(wifiSettings.Actions.UnTrustedConnectVpn))
+	if len(wifiSettings.Actions.UnTrustedConnectVpnProfile) > 0 {
+		fmt.Fprintf(w, "            Connection profile\t:\t%v\n", wifiSettings.Actions.UnTrustedConnectVpnProfile)
+	}
 	fmt.Fprintf(w, "        Enable firewall\t:\t%v\n", boolToStr(wifiSettings.Actions.UnTrustedEnableFirewall))
 	fmt.Fprintf(w, "        Block LAN traffic\t:\t%v\n", boolToStr(wifiSettings.Actions.UnTrustedBlockLan))
 	fmt.Fprintf(w, "    Actions for Trusted WiFi:\t\n")
@@ -360,6 +503,22 @@ func (c *CmdWiFi) printStatus(w *tabwriter.Writer) *tabwriter.Writer {
 		fmt.Fprintf(w, "Networks:\t\n")
 		for _, n := range wifiSettings.Networks {
 			fmt.Fprintf(w, "        %s\t:\t%v\n", n.SSID, boolToStrEx(&n.IsTrusted, "Trusted", "Untrusted", "No status", ""))
+			if n.Rule == nil {
+				continue
+			}
+			if n.Rule.ConnectionParams != nil {
+				fmt.Fprintf(w, "            Connection parameters\t:\t%v\n", connectionParamsDescription(*n.Rule.ConnectionParams))
+			}
+			if fw := n.Rule.Firewall; fw != nil {
+				fwPolicy := "Disable"
+				if fw.IsEnabled {
+					fwPolicy = "Enable"
+					if fw.BlockLan {
+						fwPolicy = "Enable and block LAN traffic"
+					}
+				}
+				fmt.Fprintf(w, "            Firewall\t:\t%v\n", fwPolicy)
+			}
 		}
 	}
 	return w
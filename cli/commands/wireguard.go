@@ -25,10 +25,14 @@ package commands
 import (
 	"fmt"
 	"os"
+	"runtime"
+	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/ivpn/desktop-app/cli/flags"
+	"github.com/ivpn/desktop-app/cli/helpers"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
 	"github.com/ivpn/desktop-app/daemon/service/srverrors"
 )
 
@@ -37,13 +41,33 @@ type CmdWireGuard struct {
 	state            bool
 	regenerate       bool
 	rotationInterval int
+	linuxBackend     string
+	healthCheck      string // [on/off]
+	handshakeTimeout int
+	pingGateway      string // [on/off]
 }
 
+const (
+	LinuxWireGuardBackend_Auto      = "auto"
+	LinuxWireGuardBackend_Kernel    = "kernel"
+	LinuxWireGuardBackend_Userspace = "userspace"
+)
+
 func (c *CmdWireGuard) Init() {
 	c.Initialize("wgkeys", "WireGuard keys management")
 	c.BoolVar(&c.state, "status", false, "(default) Show WireGuard configuration")
 	c.IntVar(&c.rotationInterval, "rotation_interval", 0, "DAYS", "Set WireGuard keys rotation interval. [1-30] days")
 	c.BoolVar(&c.regenerate, "regenerate", false, "Regenerate WireGuard keys")
+	c.StringVar(&c.healthCheck, "health_check", "", "[on/off]", "Set configuration: reconnect when the connection is stale (no handshakes while sending data; e.g. after NAT rebinding or server restart)")
+	c.IntVar(&c.handshakeTimeout, "handshake_timeout", -1, "SECONDS",
+		fmt.Sprintf("Set configuration: max age of the latest handshake before reconnection. [%d-%d] seconds (0 - default: %d)",
+			preferences.MinWireGuardHandshakeTimeoutSec, preferences.MaxWireGuardHandshakeTimeoutSec, preferences.DefaultWireGuardHandshakeTimeoutSec))
+	c.StringVar(&c.pingGateway, "ping_gateway", "", "[on/off]", "Set configuration: additionally check the connection health by pinging the VPN server gateway through the tunnel")
+	if runtime.GOOS == "linux" {
+		c.StringVar(&c.linuxBackend, "backend", "", "BACKEND",
+			fmt.Sprintf("Set configuration: WireGuard implementation (can be changed only when disconnected)\n  Possible values: %s (default; kernel module if available, otherwise userspace); %s (kernel module and 'wg-quick'); %s (embedded userspace implementation, requires only TUN device)\n  Example: ivpn wgkeys -backend %s",
+				LinuxWireGuardBackend_Auto, LinuxWireGuardBackend_Kernel, LinuxWireGuardBackend_Userspace, LinuxWireGuardBackend_Userspace))
+	}
 }
 func (c *CmdWireGuard) Run() error {
 	if c.rotationInterval < 0 || c.rotationInterval > 30 {
@@ -60,6 +84,18 @@ func (c *CmdWireGuard) Run() error {
 		}
 	}()
 
+	if len(c.linuxBackend) > 0 {
+		if err := c.setLinuxBackend(); err != nil {
+			return err
+		}
+	}
+
+	if len(c.healthCheck) > 0 || c.handshakeTimeout >= 0 || len(c.pingGateway) > 0 {
+		if err := c.setHealthCheck(); err != nil {
+			return err
+		}
+	}
+
 	resp, err := _proto.SendHello()
 	if err != nil {
 		return err
@@ -98,6 +134,63 @@ func (c *CmdWireGuard) setRotateInterval(interval int64) error {
 	return _proto.WGKeysRotationInterval(interval)
 }
 
+func (c *CmdWireGuard) setLinuxBackend() error {
+	var backend preferences.LinuxWireGuardBackend
+	switch strings.ToLower(strings.TrimSpace(c.linuxBackend)) {
+	case LinuxWireGuardBackend_Auto:
+		backend = preferences.LinuxWireGuardBackendAuto
+	case LinuxWireGuardBackend_Kernel:
+		backend = preferences.LinuxWireGuardBackendKernel
+	case LinuxWireGuardBackend_Userspace:
+		if errStr := _proto.GetHelloResponse().DisabledFunctions.Platform.Linux.WireGuardUserspaceError; len(errStr) > 0 {
+			return fmt.Errorf("the WireGuard userspace implementation is not applicable for current environment: %s", errStr)
+		}
+		backend = preferences.LinuxWireGuardBackendUserspace
+	default:
+		return flags.BadParameter{Message: fmt.Sprintf("unknown WireGuard implementation '%s'", c.linuxBackend)}
+	}
+
+	uPrefs := _proto.GetHelloResponse().DaemonSettings.UserPrefs
+	if uPrefs.Linux.WireGuardBackend == backend {
+		return nil
+	}
+	uPrefs.Linux.WireGuardBackend = backend
+	if err := _proto.SetUserPreferences(uPrefs); err != nil {
+		return err
+	}
+
+	// trigger daemon to send HelloResponse with updated user preferences
+	_, err := _proto.SendHello()
+	return err
+}
+
+func (c *CmdWireGuard) setHealthCheck() error {
+	params := _proto.GetHelloResponse().DaemonSettings.WireGuardHealthCheck
+
+	if len(c.healthCheck) > 0 {
+		val, err := helpers.BoolParameterParse(c.healthCheck)
+		if err != nil {
+			return flags.BadParameter{Message: "health_check: " + err.Error()}
+		}
+		params.IsDisabled = !val
+	}
+	if c.handshakeTimeout >= 0 {
+		params.HandshakeTimeoutSec = c.handshakeTimeout
+	}
+	if len(c.pingGateway) > 0 {
+		val, err := helpers.BoolParameterParse(c.pingGateway)
+		if err != nil {
+			return flags.BadParameter{Message: "ping_gateway: " + err.Error()}
+		}
+		params.IsPingGateway = val
+	}
+
+	if err := params.Validate(); err != nil {
+		return flags.BadParameter{Message: err.Error()}
+	}
+	return _proto.SetWireGuardHealthCheckSettings(params)
+}
+
 func (c *CmdWireGuard) getState() error {
 	resp, err := _proto.SendHello()
 	if err != nil {
@@ -119,6 +212,23 @@ func (c *CmdWireGuard) getState() error {
 	fmt.Fprintf(w, "Quantum Resistance:\t%v\n", quantumResistanceStatus)
 	fmt.Fprintf(w, "Generated:\t%v\n", time.Unix(resp.Session.WgKeyGenerated, 0))
 	fmt.Fprintf(w, "Rotation interval:\t%v\n", time.Duration(time.Second*time.Duration(resp.Session.WgKeysRegenInerval)))
+	healthCheck := resp.DaemonSettings.WireGuardHealthCheck
+	if healthCheck.IsDisabled {
+		fmt.Fprintf(w, "Health check:\tDisabled\n")
+	} else {
+		pingInfo := ""
+		if healthCheck.IsPingGateway {
+			pingInfo = "; ping gateway"
+		}
+		fmt.Fprintf(w, "Health check:\tEnabled (handshake timeout %v%s)\n", healthCheck.HandshakeTimeout(), pingInfo)
+	}
+	if runtime.GOOS == "linux" {
+		backend := string(resp.DaemonSettings.UserPrefs.Linux.WireGuardBackend)
+		if backend == string(preferences.LinuxWireGuardBackendAuto) {
+			backend = LinuxWireGuardBackend_Auto
+		}
+		fmt.Fprintf(w, "Implementation:\t%v\n", backend)
+	}
 	w.Flush()
 
 	return nil
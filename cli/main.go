@@ -97,6 +97,8 @@ func main() {
 	addCommand(&stateCmd)
 	addCommand(&commands.CmdConnect{})
 	addCommand(&commands.CmdDisconnect{})
+	addCommand(&commands.CmdProfile{})
+	addCommand(&commands.CmdGroup{})
 	addCommand(&commands.CmdConnectionControl{})
 	addCommand(&commands.CmdServers{})
 	addCommand(&commands.CmdFirewall{})
@@ -108,6 +110,7 @@ func main() {
 		}
 	}
 	addCommand(&commands.CmdWireGuard{})
+	addCommand(&commands.CmdExportConfig{})
 	addCommand(&commands.CmdDns{})
 	addCommand(&commands.CmdAntitracker{})
 	addCommand(&commands.CmdLogs{})
@@ -117,6 +120,8 @@ func main() {
 	addCommand(&commands.CmdParanoidMode{})
 	addCommand(&commands.CmdAutoConnect{})
 	addCommand(&commands.CmdWiFi{})
+	addCommand(&commands.CmdNetwork{})
+	addCommand(&commands.CmdSchedule{})
 
 	if len(os.Args) >= 2 {
 		arg1 := strings.TrimLeft(strings.ToLower(os.Args[1]), "-")
@@ -153,20 +158,9 @@ func main() {
 	}
 
 	// initialize command handler
-	port, secret, err := readDaemonPort()
+	proto, err := connectToDaemon()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: Unable to connect to service: %s\n", err)
-		printServStartInstructions()
-		os.Exit(1)
-	}
-
-	proto := protocol.CreateClient(port, secret)
-
-	proto.SetParanoidModeSecretRequestFunc(RequestParanoidModePassword)
-	proto.SetPrintFunc(PrintToConsoleFunc)
-
-	if err := proto.Connect(); err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: Failed to connect to service : %s\n", err)
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
 		printServStartInstructions()
 		os.Exit(1)
 	}
@@ -255,6 +249,38 @@ func runCommand(c ICommand, args []string) {
 	}
 }
 
+// connect to a daemon
+// The unix domain socket has priority (if available); otherwise - TCP port (port+secret from connection-info file)
+func connectToDaemon() (*protocol.Client, error) {
+	initClient := func(proto *protocol.Client) *protocol.Client {
+		proto.SetParanoidModeSecretRequestFunc(RequestParanoidModePassword)
+		proto.SetPrintFunc(PrintToConsoleFunc)
+		return proto
+	}
+
+	if socketFile := platform.ServiceSocketFile(); len(socketFile) > 0 {
+		if _, err := os.Stat(socketFile); err == nil {
+			proto := initClient(protocol.CreateClientUnixSocket(socketFile))
+			if err := proto.Connect(); err == nil {
+				return proto, nil
+			}
+			// unable to connect over unix socket (e.g. current user is not allowed): trying TCP
+			proto.Close()
+		}
+	}
+
+	port, secret, err := readDaemonPort()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to service: %w", err)
+	}
+
+	proto := initClient(protocol.CreateClient(port, secret))
+	if err := proto.Connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to service : %w", err)
+	}
+	return proto, nil
+}
+
 // read port+secret to be able to connect to a daemon
 func readDaemonPort() (port int, secret uint64, err error) {
 	file := platform.ServicePortFile()
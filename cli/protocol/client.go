@@ -52,6 +52,9 @@ type Client struct {
 	_secret uint64
 	_conn   net.Conn
 
+	// path to the daemon unix domain socket (when defined - it is used instead of TCP port)
+	_unixSocketPath string
+
 	_requestIdx int
 
 	_defaultTimeout  time.Duration
@@ -83,6 +86,14 @@ func CreateClient(port int, secret uint64) *Client {
 		_receivers:      make(map[*receiverChannel]struct{})}
 }
 
+// CreateClientUnixSocket initialising new client for IVPN daemon which is using unix domain socket.
+// The secret is not required: the daemon checks the credentials of the client process.
+func CreateClientUnixSocket(socketPath string) *Client {
+	c := CreateClient(0, 0)
+	c._unixSocketPath = socketPath
+	return c
+}
+
 // Connect is connecting to daemon
 func (c *Client) Connect() (err error) {
 	if c._conn != nil {
@@ -91,7 +102,11 @@ func (c *Client) Connect() (err error) {
 
 	logger.Info("Connecting...")
 
-	c._conn, err = net.Dial("tcp", fmt.Sprintf(":%d", c._port))
+	if len(c._unixSocketPath) > 0 {
+		c._conn, err = net.Dial("unix", c._unixSocketPath)
+	} else {
+		c._conn, err = net.Dial("tcp", fmt.Sprintf(":%d", c._port))
+	}
 	if err != nil {
 		return fmt.Errorf("failed to connect to IVPN daemon (does IVPN daemon/service running?): %w", err)
 	}
@@ -108,6 +123,14 @@ func (c *Client) Connect() (err error) {
 	return nil
 }
 
+// Close closes the connection to the daemon
+func (c *Client) Close() error {
+	if c._conn == nil {
+		return nil
+	}
+	return c._conn.Close()
+}
+
 func paranoidModeSecretHash(secret string) string {
 	if len(secret) <= 0 {
 		return ""
@@ -150,6 +173,7 @@ func (c *Client) SendHelloEx(isSendResponseToAllClients bool) (helloResponse typ
 		ClientType:               types.ClientCli,
 		GetStatus:                true,
 		Version:                  ver + ": CLI",
+		ProtocolVersion:          types.ProtocolVersion,
 		SendResponseToAllClients: isSendResponseToAllClients,
 	}
 
@@ -167,6 +191,19 @@ func (c *Client) GetHelloResponse() types.HelloResp {
 	return c._helloResponse
 }
 
+// GetProtocolSchema returns machine-readable description of the daemon protocol
+func (c *Client) GetProtocolSchema() (schema types.ProtocolSchema, err error) {
+	if err := c.ensureConnected(); err != nil {
+		return schema, err
+	}
+
+	var resp types.ProtocolSchemaResp
+	if err := c.sendRecv(&types.GetProtocolSchema{}, &resp); err != nil {
+		return schema, err
+	}
+	return resp.Schema, nil
+}
+
 // SessionNew creates new session
 func (c *Client) SessionNew(accountID string, forceLogin bool, the2FA string) (resp types.SessionNewResp, err error) {
 	if err := c.ensureConnected(); err != nil {
@@ -307,14 +344,13 @@ func (c *Client) FirewallAllowLan(allow bool) error {
 	return nil
 }
 
-// FirewallAllowLan set configuration 'firewall exceptions' (comma separated list of IP addresses/masks in format: x.x.x.x[/xx])
-func (c *Client) FirewallSetUserExceptions(exceptions string) error {
+// FirewallSetUserExceptionsList set configuration 'firewall exceptions' (optionally restricted by protocol, ports and direction)
+func (c *Client) FirewallSetUserExceptionsList(exceptions []service_types.FirewallException) error {
 	if err := c.ensureConnected(); err != nil {
 		return err
 	}
 
-	// changing killswitch configuration
-	req := types.KillSwitchSetUserExceptions{UserExceptions: exceptions, FailOnParsingError: true}
+	req := types.KillSwitchSetUserExceptionsList{Exceptions: exceptions, FailOnError: true}
 	var resp types.EmptyResp
 	if err := c.sendRecv(&req, &resp); err != nil {
 		return err
@@ -370,6 +406,22 @@ func (c *Client) FirewallStatus() (state types.KillSwitchStatusResp, err error)
 	return state, nil
 }
 
+// FirewallRules get description of the firewall rules
+// (if isDryRun - description of rules which would be applied when the firewall is enabled)
+func (c *Client) FirewallRules(isDryRun bool) (rules service_types.KillSwitchRules, err error) {
+	if err := c.ensureConnected(); err != nil {
+		return rules, err
+	}
+
+	req := types.KillSwitchGetRules{IsDryRun: isDryRun}
+	var resp types.KillSwitchRulesResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return rules, err
+	}
+
+	return resp.Rules, nil
+}
+
 // GetSplitTunnelStatus requests the Split-Tunnelling configuration
 func (c *Client) GetSplitTunnelStatus() (cfg types.SplitTunnelStatus, err error) {
 	if err := c.ensureConnected(); err != nil {
@@ -499,6 +551,96 @@ func (c *Client) SplitTunnelRemoveApp(cmdOrPid string) error {
 	return nil
 }
 
+// SplitTunnelAddAppRule adds persistent application rule (executable path or desktop file ID) to the Split Tunnel configuration
+func (c *Client) SplitTunnelAddAppRule(rule string) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.SplitTunnelAddAppRule{Rule: rule}
+	var resp types.EmptyResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SplitTunnelRemoveAppRule removes persistent application rule from the Split Tunnel configuration
+func (c *Client) SplitTunnelRemoveAppRule(rule string) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.SplitTunnelRemoveAppRule{Rule: rule}
+	var resp types.EmptyResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SplitTunnelAddRoute adds destination network (IP address or CIDR) to the Split Tunnel configuration
+func (c *Client) SplitTunnelAddRoute(route string) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.SplitTunnelAddRoute{Route: route}
+	var resp types.EmptyResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SplitTunnelRemoveRoute removes destination network from the Split Tunnel configuration
+func (c *Client) SplitTunnelRemoveRoute(route string) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.SplitTunnelRemoveRoute{Route: route}
+	var resp types.EmptyResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SplitTunnelAddDomain adds destination domain to the Split Tunnel configuration
+func (c *Client) SplitTunnelAddDomain(domain string) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.SplitTunnelAddDomain{Domain: domain}
+	var resp types.EmptyResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SplitTunnelRemoveDomain removes destination domain from the Split Tunnel configuration
+func (c *Client) SplitTunnelRemoveDomain(domain string) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.SplitTunnelRemoveDomain{Domain: domain}
+	var resp types.EmptyResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // GetServers gets servers list
 func (c *Client) GetServers() (apitypes.ServersInfoResponse, error) {
 	if err := c.ensureConnected(); err != nil {
@@ -642,6 +784,165 @@ func (c *Client) WGKeysRotationInterval(uinxTimeInterval int64) error {
 	return nil
 }
 
+// WGCustomConfigSet validates and saves the custom WireGuard configuration (empty string - remove configuration)
+func (c *Client) WGCustomConfigSet(config string) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.WireGuardCustomConfigSet{Config: config}
+	var resp types.EmptyResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetWireGuardHealthCheckSettings sets parameters of the WireGuard connection monitoring (stale handshake detection)
+func (c *Client) SetWireGuardHealthCheckSettings(params preferences.WireGuardHealthCheckParams) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.WireGuardHealthCheckSettings{Params: params}
+	var resp types.EmptyResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetServerScoringWeights sets weights of the factors for the 'Best' server selection
+func (c *Client) SetServerScoringWeights(weights service_types.ServerScoringWeights) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.ServerScoringSettings{Weights: weights}
+	var resp types.EmptyResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetHostsSelection sets pinned and blacklisted hosts of the servers
+func (c *Client) SetHostsSelection(params preferences.HostsSelectionParams) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.HostsSelectionSettings{Params: params}
+	var resp types.EmptyResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// OpenVpnCustomConfigSet validates and saves the custom OpenVPN configuration (empty string - remove configuration)
+// Returns the list of directives removed from the profile by the daemon
+func (c *Client) OpenVpnCustomConfigSet(config, username, password string) (removedDirectives []string, err error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	req := types.OpenVpnCustomConfigSet{Config: config, Username: username, Password: password}
+	var resp types.OpenVpnCustomConfigSetResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.RemovedDirectives, nil
+}
+
+// ExportConnectionConfig returns the standalone configuration for current connection parameters
+func (c *Client) ExportConnectionConfig(includeCredentials bool) (vpnType vpn.Type, fileName string, config string, err error) {
+	if err := c.ensureConnected(); err != nil {
+		return 0, "", "", err
+	}
+
+	req := types.ExportConnectionConfig{IncludeCredentials: includeCredentials}
+	var resp types.ExportConnectionConfigResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return 0, "", "", err
+	}
+
+	return resp.VpnType, resp.FileName, resp.Config, nil
+}
+
+// TunnelStatistics returns traffic statistics of the active VPN tunnel
+func (c *Client) TunnelStatistics() (service_types.TunnelStatistics, error) {
+	if err := c.ensureConnected(); err != nil {
+		return service_types.TunnelStatistics{}, err
+	}
+
+	req := types.TunnelStatistics{}
+	var resp types.TunnelStatisticsResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return service_types.TunnelStatistics{}, err
+	}
+
+	return resp.Statistics, nil
+}
+
+// TunnelStatisticsWatch subscribes to the tunnel statistics events (sent by daemon every 'intervalSec' seconds)
+// and calls 'onStatistics' for each received event.
+// The function returns on error or when 'onStatistics' returns false.
+func (c *Client) TunnelStatisticsWatch(intervalSec int, onStatistics func(service_types.TunnelStatistics) bool) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	var resp types.TunnelStatisticsResp
+	var receiver *receiverChannel
+	var reqIdx int
+
+	removeReceiver := func() {
+		c._receiversLocker.Lock()
+		defer c._receiversLocker.Unlock()
+		delete(c._receivers, receiver)
+	}
+
+	// thread-safe receiver registration
+	// (the receiver accepts the response to the request and all following events)
+	func() {
+		c._receiversLocker.Lock()
+		defer c._receiversLocker.Unlock()
+
+		c._requestIdx++
+		reqIdx = c._requestIdx
+		receiver = createReceiver(reqIdx, true, &resp)
+		c._receivers[receiver] = struct{}{}
+	}()
+	defer removeReceiver()
+
+	if err := c.send(&types.TunnelStatistics{IntervalSec: intervalSec}, reqIdx); err != nil {
+		return err
+	}
+
+	timeout := c._defaultTimeout + time.Duration(intervalSec)*time.Second
+	for {
+		if err := receiver.Wait(timeout); err != nil {
+			return err
+		}
+		if !onStatistics(resp.Statistics) {
+			break
+		}
+		resp = types.TunnelStatisticsResp{}
+	}
+
+	// stop receiving events (the receiver must be removed to not intercept the response)
+	removeReceiver()
+	req := types.TunnelStatistics{Unsubscribe: true}
+	var unsubscribeResp types.TunnelStatisticsResp
+	return c.sendRecv(&req, &unsubscribeResp)
+}
+
 func (c *Client) Pause(durationSec uint32) error {
 	if err := c.ensureConnected(); err != nil {
 		return err
@@ -663,6 +964,22 @@ func (c *Client) Pause(durationSec uint32) error {
 	return nil
 }
 
+// ServerScores returns servers sorted by score (the best server is the first one)
+// gateways - (optional) the servers to take into account; empty - all servers
+func (c *Client) ServerScores(vpnType vpn.Type, gateways []string) ([]service_types.ServerScore, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	req := types.ServerScores{VpnType: vpnType, Gateways: gateways}
+	var resp types.ServerScoresResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Scores, nil
+}
+
 // PingServers
 func (c *Client) PingServers(vpnTypePrioritized *vpn.Type) (pingResults []types.PingResultType, err error) {
 	if err := c.ensureConnected(); err != nil {
@@ -760,6 +1077,31 @@ func (c *Client) SetWiFiSettings(params preferences.WiFiParams) error {
 	return nil
 }
 
+// SetNetworkTrustSettings sets trust rules for wired (non-WiFi) networks
+func (c *Client) SetNetworkTrustSettings(params preferences.NetworkTrustParams) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.NetworkTrustSettings{Params: params}
+	var resp types.EmptyResp
+	if _, _, err := c.sendRecvAny(&req, &resp); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetNetworkCurrentInfo returns info about the network which is in use by the default route
+func (c *Client) GetNetworkCurrentInfo() (types.NetworkCurrentInfoResp, error) {
+	if err := c.ensureConnected(); err != nil {
+		return types.NetworkCurrentInfoResp{}, err
+	}
+
+	var resp types.NetworkCurrentInfoResp
+	err := c.sendRecv(&types.NetworkCurrentInfo{}, &resp)
+	return resp, err
+}
+
 func (c *Client) SetDefConnectionParams(params types.ConnectSettings) error {
 	if err := c.ensureConnected(); err != nil {
 		return err
@@ -772,6 +1114,129 @@ func (c *Client) SetDefConnectionParams(params types.ConnectSettings) error {
 	return nil
 }
 
+// ConnectionProfiles returns saved connection profiles
+func (c *Client) ConnectionProfiles() (types.ConnectionProfilesResp, error) {
+	if err := c.ensureConnected(); err != nil {
+		return types.ConnectionProfilesResp{}, err
+	}
+
+	var resp types.ConnectionProfilesResp
+	err := c.sendRecv(&types.ConnectionProfiles{}, &resp)
+	return resp, err
+}
+
+// ConnectionProfileCreate saves new connection profile
+func (c *Client) ConnectionProfileCreate(profile preferences.ConnectionProfile) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	var resp types.EmptyResp
+	return c.sendRecv(&types.ConnectionProfileCreate{Profile: profile}, &resp)
+}
+
+// ConnectionProfileUpdate updates (or renames) existing connection profile
+func (c *Client) ConnectionProfileUpdate(name string, profile preferences.ConnectionProfile) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	var resp types.EmptyResp
+	return c.sendRecv(&types.ConnectionProfileUpdate{ProfileName: name, Profile: profile}, &resp)
+}
+
+// ConnectionProfileDelete removes connection profile
+func (c *Client) ConnectionProfileDelete(name string) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	var resp types.EmptyResp
+	return c.sendRecv(&types.ConnectionProfileDelete{ProfileName: name}, &resp)
+}
+
+// ServerGroups returns favourite servers and server groups
+func (c *Client) ServerGroups() (types.ServerGroupsResp, error) {
+	if err := c.ensureConnected(); err != nil {
+		return types.ServerGroupsResp{}, err
+	}
+
+	var resp types.ServerGroupsResp
+	err := c.sendRecv(&types.ServerGroups{}, &resp)
+	return resp, err
+}
+
+// FavouriteServersSet saves the list of favourite servers
+func (c *Client) FavouriteServersSet(gateways []string) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	var resp types.EmptyResp
+	return c.sendRecv(&types.FavouriteServersSet{Gateways: gateways}, &resp)
+}
+
+// ServerGroupSet saves (creates or replaces) the server group
+func (c *Client) ServerGroupSet(group preferences.ServerGroup) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	var resp types.EmptyResp
+	return c.sendRecv(&types.ServerGroupSet{Group: group}, &resp)
+}
+
+// ServerGroupDelete removes the server group
+func (c *Client) ServerGroupDelete(name string) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	var resp types.EmptyResp
+	return c.sendRecv(&types.ServerGroupDelete{GroupName: name}, &resp)
+}
+
+// ScheduleRules returns scheduler rules
+func (c *Client) ScheduleRules() (types.ScheduleRulesResp, error) {
+	if err := c.ensureConnected(); err != nil {
+		return types.ScheduleRulesResp{}, err
+	}
+
+	var resp types.ScheduleRulesResp
+	err := c.sendRecv(&types.ScheduleRules{}, &resp)
+	return resp, err
+}
+
+// ScheduleRuleCreate saves new scheduler rule
+func (c *Client) ScheduleRuleCreate(rule preferences.ScheduleRule) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	var resp types.EmptyResp
+	return c.sendRecv(&types.ScheduleRuleCreate{Rule: rule}, &resp)
+}
+
+// ScheduleRuleUpdate updates (or renames) existing scheduler rule
+func (c *Client) ScheduleRuleUpdate(name string, rule preferences.ScheduleRule) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	var resp types.EmptyResp
+	return c.sendRecv(&types.ScheduleRuleUpdate{RuleName: name, Rule: rule}, &resp)
+}
+
+// ScheduleRuleDelete removes scheduler rule
+func (c *Client) ScheduleRuleDelete(name string) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	var resp types.EmptyResp
+	return c.sendRecv(&types.ScheduleRuleDelete{RuleName: name}, &resp)
+}
+
 func (c *Client) GetDefConnectionParams() (types.ConnectSettings, error) {
 	if err := c.ensureConnected(); err != nil {
 		return types.ConnectSettings{}, err
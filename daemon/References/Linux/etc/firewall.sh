@@ -211,6 +211,7 @@ function enable_firewall {
 
       # Split Tunnel: Allow packets from/to cgroup (bypass IVPN firewall)
       ${IPv6BIN} -w ${LOCKWAITTIME} -I ${OUT_IVPN} -m cgroup --cgroup ${_splittun_cgroup_classid} -m comment --comment  "${_splittun_comment}" -j ACCEPT || echo "Failed to add OUTPUT (cgroup) rule for split-tunnel"
+      ${IPv6BIN} -w ${LOCKWAITTIME} -I ${OUT_IVPN} -m mark --mark ${_splittun_packets_fwmark_value} -m comment --comment  "${_splittun_comment}" -j ACCEPT || echo "Failed to add OUTPUT (mark) rule for split-tunnel"  # cgroup v2 Split Tunnel: packets are marked in the nftables 'output' route chain
       ${IPv6BIN} -w ${LOCKWAITTIME} -I ${IN_IVPN} -m cgroup --cgroup ${_splittun_cgroup_classid} -m comment --comment  "${_splittun_comment}" -j ACCEPT || echo "Failed to add INPUT (cgroup) rule for split-tunnel"  # this rule is not effective, so we use 'mark' (see the next rule)
       ${IPv6BIN} -w ${LOCKWAITTIME} -I ${IN_IVPN} -m mark --mark ${_splittun_packets_fwmark_value} -m comment --comment  "${_splittun_comment}" -j ACCEPT  || echo "Failed to add INPUT (mark) rule for split-tunnel"
 
@@ -289,6 +290,7 @@ function enable_firewall {
 
     # Split Tunnel: Allow packets from/to cgroup (bypass IVPN firewall)
     ${IPv4BIN} -w ${LOCKWAITTIME} -I ${OUT_IVPN} -m cgroup --cgroup ${_splittun_cgroup_classid} -m comment --comment  "${_splittun_comment}" -j ACCEPT || echo "Failed to add OUTPUT (cgroup) rule for split-tunnel"
+    ${IPv4BIN} -w ${LOCKWAITTIME} -I ${OUT_IVPN} -m mark --mark ${_splittun_packets_fwmark_value} -m comment --comment  "${_splittun_comment}" -j ACCEPT || echo "Failed to add OUTPUT (mark) rule for split-tunnel"  # cgroup v2 Split Tunnel: packets are marked in the nftables 'output' route chain
     ${IPv4BIN} -w ${LOCKWAITTIME} -I ${IN_IVPN} -m cgroup --cgroup ${_splittun_cgroup_classid} -m comment --comment  "${_splittun_comment}" -j ACCEPT || echo "Failed to add INPUT (cgroup) rule for split-tunnel"  # this rule is not effective, so we use 'mark' (see the next rule)
     ${IPv4BIN} -w ${LOCKWAITTIME} -I ${IN_IVPN} -m mark --mark ${_splittun_packets_fwmark_value} -m comment --comment  "${_splittun_comment}" -j ACCEPT || echo "Failed to add INPUT (mark) rule for split-tunnel"
 
@@ -502,6 +504,42 @@ function remove_exceptions {
   ${BIN} -w ${LOCKWAITTIME} -D ${OUT_CH} -d $@ -j ACCEPT
 }
 
+# Add user exception restricted by protocol, ports or direction
+# Arguments: <BIN> <IN_CHAIN> <OUT_CHAIN> <PROTOCOL> <ADDRESS> <PORTS> <DIRECTION>
+#   PROTOCOL  - tcp | udp | all
+#   PORTS     - port | from:to | any (remote port for outgoing connections; local port for incoming connections)
+#   DIRECTION - in | out | both
+function add_user_exception_ex {
+  BIN=$1
+  IN_CH=$2
+  OUT_CH=$3
+  PROTOCOL=$4
+  ADDR=$5
+  PORTS=$6
+  DIRECTION=$7
+
+  create_chain ${BIN} ${IN_CH}
+  create_chain ${BIN} ${OUT_CH}
+
+  DPORT=""
+  SPORT=""
+  if [ "${PORTS}" != "any" ]; then
+    DPORT="--dport ${PORTS}"
+    SPORT="--sport ${PORTS}"
+  fi
+
+  if [ "${DIRECTION}" != "in" ]; then
+    # outgoing connections to the remote host (and replies)
+    ${BIN} -w ${LOCKWAITTIME} -A ${OUT_CH} -d ${ADDR} -p ${PROTOCOL} ${DPORT} -j ACCEPT
+    ${BIN} -w ${LOCKWAITTIME} -A ${IN_CH}  -s ${ADDR} -p ${PROTOCOL} ${SPORT} -m state --state ESTABLISHED,RELATED -j ACCEPT
+  fi
+  if [ "${DIRECTION}" != "out" ]; then
+    # incoming connections from the remote host (and replies)
+    ${BIN} -w ${LOCKWAITTIME} -A ${IN_CH}  -s ${ADDR} -p ${PROTOCOL} ${DPORT} -j ACCEPT
+    ${BIN} -w ${LOCKWAITTIME} -A ${OUT_CH} -d ${ADDR} -p ${PROTOCOL} ${SPORT} -m state --state ESTABLISHED,RELATED -j ACCEPT
+  fi
+}
+
 function add_direction_exception {
   IN_CH=$1
   OUT_CH=$2
@@ -611,6 +649,20 @@ function main {
         add_exceptions ${IPv6BIN} ${IN_IVPN_STAT_USER_EXP} ${OUT_IVPN_STAT_USER_EXP} $@
       fi
 
+    # User exceptions restricted by protocol, ports or direction
+    # (must be called after '-set_user_exceptions_static...': it is appending rules to the same chains)
+    elif [[ $1 = "-add_user_exception_ex" ]]; then
+
+      shift
+      add_user_exception_ex ${IPv4BIN} ${IN_IVPN_STAT_USER_EXP} ${OUT_IVPN_STAT_USER_EXP} $@
+
+    elif [[ $1 = "-add_user_exception_ex_ipv6" ]]; then
+
+      if [ -f /proc/net/if_inet6 ]; then
+        shift
+        add_user_exception_ex ${IPv6BIN} ${IN_IVPN_STAT_USER_EXP} ${OUT_IVPN_STAT_USER_EXP} $@
+      fi
+
     # DNS rules
     elif [[ $1 = "-set_dns" ]]; then
 
@@ -26,6 +26,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/http"
 	"runtime"
 	"strings"
 	"sync"
@@ -43,6 +44,7 @@ const (
 	_apiHost               = "api.ivpn.net"
 	_updateHost            = "repo.ivpn.net"
 	_serversPath           = "v5/servers.json"
+	_serversSignPath       = _serversPath + ".sign.sha256.base64"
 	_apiPathPrefix         = "v4"
 	_sessionNewPath        = _apiPathPrefix + "/session/new"
 	_sessionStatusPath     = _apiPathPrefix + "/session/status"
@@ -129,6 +131,9 @@ type API struct {
 	lastGoodAlternateIPv6 net.IP
 	connectivityChecker   IConnectivityInfo
 
+	// custom API backend (nil - default IVPN servers); see SetEndpoint()
+	endpoint *customEndpoint
+
 	// last geolookups result
 	geolookupV4 geolookup
 	geolookupV6 geolookup
@@ -242,16 +247,41 @@ func (a *API) doSetAlternateIPs(IPv6 bool, IPs []string) error {
 	return nil
 }
 
-// DownloadServersList - download servers list form API IVPN server
-func (a *API) DownloadServersList() (*types.ServersInfoResponse, error) {
-	servers := new(types.ServersInfoResponse)
-	if err := a.request("", _serversPath, "GET", "", nil, servers); err != nil {
-		return nil, err
+// DownloadServersList - download servers list form API IVPN server.
+// The signature of the servers list is verified.
+// Returns also the raw signed data (can be used to cache the servers list and verify it later; see VerifyServersList())
+func (a *API) DownloadServersList() (*types.ServersInfoResponse, SignedData, error) {
+	data, _, err := a.requestRaw(protocolTypes.IPvAny, "", _serversPath, "GET", "", nil, 0, 0)
+	if err != nil {
+		return nil, SignedData{}, err
+	}
+	signature, httpResp, err := a.requestRaw(protocolTypes.IPvAny, "", _serversSignPath, "GET", "", nil, 0, 0)
+	if err != nil {
+		return nil, SignedData{}, fmt.Errorf("failed to download servers list signature: %w", err)
+	}
+
+	var servers *types.ServersInfoResponse
+	signed := SignedData{Data: data, Signature: signature}
+	switch httpResp.StatusCode {
+	case http.StatusOK:
+		if servers, err = a.VerifyServersList(signed); err != nil {
+			return nil, SignedData{}, err
+		}
+	case http.StatusNotFound:
+		// The backend does not publish the servers list signature (yet).
+		// The unsigned list is returned (empty 'Signature'): the caller decides whether it can be accepted.
+		signed.Signature = nil
+		servers = new(types.ServersInfoResponse)
+		if err := json.Unmarshal(data, servers); err != nil {
+			return nil, SignedData{}, fmt.Errorf("failed to deserialize servers list: %w", err)
+		}
+	default:
+		return nil, SignedData{}, fmt.Errorf("failed to download servers list signature: HTTP status %d", httpResp.StatusCode)
 	}
 
 	// save info about alternate API hosts
 	a.SetAlternateIPs(servers.Config.API.IPAddresses, servers.Config.API.IPv6Addresses)
-	return servers, nil
+	return servers, signed, nil
 }
 
 // DoRequestByAlias do API request (by API endpoint alias). Returns raw data of response
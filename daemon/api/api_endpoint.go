@@ -0,0 +1,191 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package api
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Endpoint - custom API backend (replaces the IVPN API and update servers).
+// Intended for testing and debugging only (e.g. with the mock API server from 'api/mockapi').
+type Endpoint struct {
+	// Base URL of the API server (e.g. "https://127.0.0.1:8443")
+	// All requests (API and update-info) are sent to this URL.
+	URL string
+	// Trust anchor: PEM-encoded CA certificate(s) used to verify the server certificate.
+	// If empty - the system root certificates are used.
+	CaCertificatePEM []byte
+	// Optional: base64-encoded SHA256 hashes of the server public keys (certificate key pinning).
+	// If empty - no pinning applied.
+	PinnedKeyHashes []string
+	// Optional: PEM-encoded RSA public key to verify the servers list signature.
+	// If empty - the default IVPN key is used.
+	ServersSignPublicKeyPEM []byte
+}
+
+// customEndpoint - parsed and validated Endpoint
+type customEndpoint struct {
+	baseURL    *url.URL
+	serverName string
+	rootCAs    *x509.CertPool
+	pinnedKeys []string
+
+	serversSignKey *rsa.PublicKey
+}
+
+// LoadEndpoint creates Endpoint object for the base URL, the CA certificate file
+// and the servers list signing public key file (file paths can be empty)
+func LoadEndpoint(baseURL string, caCertFile string, serversSignKeyFile string) (*Endpoint, error) {
+	ep := &Endpoint{URL: baseURL}
+	if len(caCertFile) > 0 {
+		data, err := os.ReadFile(caCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate file: %w", err)
+		}
+		ep.CaCertificatePEM = data
+	}
+	if len(serversSignKeyFile) > 0 {
+		data, err := os.ReadFile(serversSignKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read servers list signing key file: %w", err)
+		}
+		ep.ServersSignPublicKeyPEM = data
+	}
+	return ep, nil
+}
+
+// SetEndpoint - use custom API backend instead of the IVPN servers.
+// nil - restore default behaviour.
+func (a *API) SetEndpoint(ep *Endpoint) error {
+	if ep == nil {
+		a.mutex.Lock()
+		a.endpoint = nil
+		a.mutex.Unlock()
+		return nil
+	}
+
+	u, err := url.Parse(strings.TrimSpace(ep.URL))
+	if err != nil {
+		return fmt.Errorf("bad API endpoint URL: %w", err)
+	}
+	if u.Scheme != "https" || len(u.Host) == 0 {
+		return fmt.Errorf("bad API endpoint URL '%s': expected 'https://<host>[:port][/path]'", ep.URL)
+	}
+
+	custom := &customEndpoint{
+		baseURL:    u,
+		serverName: u.Hostname(),
+		pinnedKeys: ep.PinnedKeyHashes,
+	}
+
+	if len(ep.CaCertificatePEM) > 0 {
+		custom.rootCAs = x509.NewCertPool()
+		if !custom.rootCAs.AppendCertsFromPEM(ep.CaCertificatePEM) {
+			return fmt.Errorf("bad API endpoint CA certificate: no valid PEM certificates found")
+		}
+	}
+
+	if len(ep.ServersSignPublicKeyPEM) > 0 {
+		if custom.serversSignKey, err = parseRSAPublicKeyPEM(ep.ServersSignPublicKeyPEM); err != nil {
+			return fmt.Errorf("bad servers list signing key: %w", err)
+		}
+	}
+
+	a.mutex.Lock()
+	a.endpoint = custom
+	a.mutex.Unlock()
+
+	log.Warning(fmt.Sprintf("Using custom API endpoint: %s (TESTING ONLY!)", u.String()))
+	return nil
+}
+
+// IsCustomEndpoint returns true when custom API backend in use
+func (a *API) IsCustomEndpoint() bool {
+	return a.getEndpoint() != nil
+}
+
+func (a *API) getEndpoint() *customEndpoint {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.endpoint
+}
+
+func (e *customEndpoint) getURL(urlPath string) string {
+	return strings.TrimRight(e.baseURL.String(), "/") + "/" + strings.TrimLeft(urlPath, "/")
+}
+
+func (a *API) doRequestCustomEndpoint(ep *customEndpoint, urlPath string, method string, contentType string, request interface{}, timeoutMs int, timeoutDialMs int) (resp *http.Response, err error) {
+	timeout := _defaultRequestTimeout
+	if timeoutMs > 0 {
+		timeout = time.Millisecond * time.Duration(timeoutMs)
+	}
+	timeoutDial := _defaultDialTimeout
+	if timeoutDialMs > 0 {
+		timeoutDial = time.Millisecond * time.Duration(timeoutDialMs)
+	}
+
+	transCfg := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ServerName: ep.serverName,
+			RootCAs:    ep.rootCAs,
+		},
+		DialContext: (&net.Dialer{Timeout: timeoutDial}).DialContext,
+	}
+	if len(ep.pinnedKeys) > 0 {
+		transCfg.DialTLS = makeDialerEx(ep.pinnedKeys, ep.serverName, timeoutDial, ep.rootCAs)
+	}
+
+	client := &http.Client{Transport: transCfg, Timeout: timeout}
+
+	data := []byte{}
+	if request != nil {
+		data, err = json.Marshal(request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := newRequest(ep.getURL(urlPath), method, contentType, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err = client.Do(req)
+	if err != nil {
+		log.Warning("Failed to access " + ep.baseURL.Host)
+		return resp, fmt.Errorf("unable to access API server (custom endpoint): %w", err)
+	}
+	return resp, nil
+}
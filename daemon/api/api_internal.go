@@ -80,8 +80,13 @@ func findPinnedKey(certHashes []string, certBase64hash256 string) bool {
 type dialer func(network, addr string) (net.Conn, error)
 
 func makeDialer(certHashes []string, serverName string, dialTimeout time.Duration) dialer {
+	return makeDialerEx(certHashes, serverName, dialTimeout, nil)
+}
+
+// makeDialerEx - same as makeDialer but allows to define custom root certificates (nil - system roots)
+func makeDialerEx(certHashes []string, serverName string, dialTimeout time.Duration, rootCAs *x509.CertPool) dialer {
 	if len(certHashes) == 0 {
-		log.Warning("No pinned certificates for ", _apiHost)
+		log.Warning("No pinned certificates for ", serverName)
 		return nil
 	}
 
@@ -101,6 +106,7 @@ func makeDialer(certHashes []string, serverName string, dialTimeout time.Duratio
 			// NOTE: Can't use TLSv1.1 because of RC4 cipher usage
 			MinVersion: tls.VersionTLS12,
 			ServerName: serverName,
+			RootCAs:    rootCAs,
 		}
 
 		c, err := tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, network, addr, tlsConfig)
@@ -144,6 +150,14 @@ func (a *API) doRequest(ipTypeRequired types.RequiredIPProtocol, host string, ur
 		}
 	}
 
+	// custom API backend (testing): all requests going to the same base URL
+	if ep := a.getEndpoint(); ep != nil {
+		if len(host) == 0 || host == _apiHost || host == _updateHost {
+			return a.doRequestCustomEndpoint(ep, urlPath, method, contentType, request, timeoutMs, timeoutDialMs)
+		}
+		return nil, fmt.Errorf("unknown host type")
+	}
+
 	if len(host) == 0 || host == _apiHost {
 		if ipTypeRequired != types.IPvAny {
 			// The specific IP version required to use
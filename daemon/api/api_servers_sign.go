@@ -0,0 +1,117 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package api
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ivpn/desktop-app/daemon/api/types"
+)
+
+// SignedData - raw data and its signature (base64-encoded RSA-SHA256 signature)
+type SignedData struct {
+	Data      []byte
+	Signature []byte
+}
+
+// IsSigned returns true if the data has a signature
+// (the signature is not verified here, see VerifyServersList())
+func (d SignedData) IsSigned() bool {
+	return len(d.Signature) > 0
+}
+
+var (
+	defaultServersSignKeyOnce sync.Once
+	defaultServersSignKey     *rsa.PublicKey
+	defaultServersSignKeyErr  error
+)
+
+func parseRSAPublicKeyPEM(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported public key type (RSA expected)")
+	}
+	return rsaKey, nil
+}
+
+// VerifySignature checks the base64-encoded RSA-SHA256 signature of the data
+// (compatible with: 'openssl dgst -sha256 -sign private.pem data | openssl base64')
+func VerifySignature(key *rsa.PublicKey, data []byte, signatureBase64 []byte) error {
+	if key == nil {
+		return fmt.Errorf("public key not defined")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(signatureBase64)), ""))
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+	hash := sha256.Sum256(data)
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig)
+}
+
+func (a *API) getServersSignKey() (*rsa.PublicKey, error) {
+	if ep := a.getEndpoint(); ep != nil && ep.serversSignKey != nil {
+		return ep.serversSignKey, nil
+	}
+
+	defaultServersSignKeyOnce.Do(func() {
+		defaultServersSignKey, defaultServersSignKeyErr = parseRSAPublicKeyPEM([]byte(ServersListSignPublicKeyPEM))
+	})
+	return defaultServersSignKey, defaultServersSignKeyErr
+}
+
+// VerifyServersList checks the servers list signature and parses the data
+func (a *API) VerifyServersList(signed SignedData) (*types.ServersInfoResponse, error) {
+	key, err := a.getServersSignKey()
+	if err != nil {
+		return nil, fmt.Errorf("servers list signature verification failed: %w", err)
+	}
+	if len(signed.Signature) == 0 {
+		return nil, fmt.Errorf("servers list signature verification failed: no signature")
+	}
+	if err := VerifySignature(key, signed.Data, signed.Signature); err != nil {
+		return nil, fmt.Errorf("servers list signature verification failed: %w", err)
+	}
+
+	servers := new(types.ServersInfoResponse)
+	if err := json.Unmarshal(signed.Data, servers); err != nil {
+		return nil, fmt.Errorf("failed to deserialize servers list: %w", err)
+	}
+	return servers, nil
+}
@@ -0,0 +1,444 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// Package mockapi implements a local mock of the IVPN API server.
+// It allows to test API-related functionality (login, session status, WireGuard keys, servers list, geo-lookup)
+// without network access to the real backend.
+//
+// Usage:
+//
+//	srv, _ := mockapi.Start("")
+//	defer srv.Close()
+//	apiObj, _ := api.CreateAPI()
+//	apiObj.SetEndpoint(srv.Endpoint())
+//
+// The servers list is signed by the key generated on the server start (see Endpoint(), ServersSignPublicKeyPEM()).
+// The KEM ciphers (WireGuard PresharedKey exchange) are not supported: the mock server never responds with them.
+package mockapi
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/api"
+	"github.com/ivpn/desktop-app/daemon/api/types"
+)
+
+// DefaultAccountID - active account available on the mock server by default
+const DefaultAccountID = "i-TEST-TEST-TEST"
+
+//go:embed servers.json
+var defaultServersJSON []byte
+
+// Account - account data known by the mock server
+type Account struct {
+	ID              string
+	IsActive        bool
+	ActiveUntil     time.Time
+	Plan            string
+	Capabilities    []string
+	SessionsLimit   int    // maximum number of sessions (0 - no limit)
+	Confirmation2FA string // if defined - two-factor authentication required on login
+}
+
+type session struct {
+	accountID   string
+	wgPublicKey string
+	wgIPAddress string
+}
+
+// Server - mock IVPN API server
+type Server struct {
+	srv *httptest.Server
+
+	mutex       sync.Mutex
+	accounts    map[string]Account
+	sessions    map[string]*session // sessionToken -> session
+	wgIPCounter int
+	serversJSON []byte
+	serversSign []byte
+	signKey     *rsa.PrivateKey
+	location    types.GeoLookupResponse
+}
+
+// Start starts new mock API server (HTTPS) on the specified address.
+// Empty address - listen on random port on the loopback interface.
+func Start(addr string) (*Server, error) {
+	if len(addr) == 0 {
+		addr = "127.0.0.1:0"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start mock API server: %w", err)
+	}
+
+	signKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to generate servers list signing key: %w", err)
+	}
+
+	s := &Server{
+		accounts: make(map[string]Account),
+		sessions: make(map[string]*session),
+		signKey:  signKey,
+		location: types.GeoLookupResponse{Latitude: 52.37, Longitude: 4.89},
+	}
+	s.SetServersJSON(defaultServersJSON)
+	s.AddAccount(Account{
+		ID:           DefaultAccountID,
+		IsActive:     true,
+		ActiveUntil:  time.Now().Add(time.Hour * 24 * 365),
+		Plan:         "IVPN Pro",
+		Capabilities: []string{"multihop", "port-forwarding"},
+	})
+
+	s.srv = httptest.NewUnstartedServer(s.handler())
+	s.srv.Listener.Close()
+	s.srv.Listener = listener
+	s.srv.StartTLS()
+	return s, nil
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// URL returns base URL of the server (e.g. "https://127.0.0.1:34567")
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// CaCertificatePEM returns PEM-encoded certificate of the server (trust anchor for clients)
+func (s *Server) CaCertificatePEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.srv.Certificate().Raw})
+}
+
+// ServersSignPublicKeyPEM returns PEM-encoded public key to verify the servers list signature
+func (s *Server) ServersSignPublicKeyPEM() []byte {
+	der, _ := x509.MarshalPKIXPublicKey(&s.signKey.PublicKey)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// Endpoint returns API endpoint configuration to access the server (see api.SetEndpoint())
+func (s *Server) Endpoint() *api.Endpoint {
+	return &api.Endpoint{URL: s.URL(), CaCertificatePEM: s.CaCertificatePEM(), ServersSignPublicKeyPEM: s.ServersSignPublicKeyPEM()}
+}
+
+// AddAccount adds (or replaces) account
+func (s *Server) AddAccount(acc Account) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.accounts[acc.ID] = acc
+}
+
+// SetServersJSON sets the content of servers.json response (the data is signed by the server key)
+func (s *Server) SetServersJSON(data []byte) {
+	hash := sha256.Sum256(data)
+	sig, _ := rsa.SignPKCS1v15(rand.Reader, s.signKey, crypto.SHA256, hash[:])
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.serversJSON = data
+	s.serversSign = []byte(base64.StdEncoding.EncodeToString(sig))
+}
+
+// SetServersSignature overrides the signature of servers.json (e.g. to test tampered data).
+// Empty value - the signature is not published (HTTP 404), as on the backends which do not sign the servers list.
+func (s *Server) SetServersSignature(signatureBase64 []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.serversSign = signatureBase64
+}
+
+// SetGeoLookup sets the geo-lookup response
+func (s *Server) SetGeoLookup(location types.GeoLookupResponse) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.location = location
+}
+
+// SessionsCount returns number of active sessions for the account
+func (s *Server) SessionsCount(accountID string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.sessionsCount(accountID)
+}
+
+// SessionWireGuardKey returns WireGuard public key registered for the session
+func (s *Server) SessionWireGuardKey(sessionToken string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sess, ok := s.sessions[sessionToken]
+	if !ok {
+		return "", false
+	}
+	return sess.wgPublicKey, true
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v5/servers.json", s.handleServers)
+	mux.HandleFunc("/v5/servers.json.sign.sha256.base64", s.handleServersSignature)
+	mux.HandleFunc("/v4/session/new", s.handleSessionNew)
+	mux.HandleFunc("/v4/session/status", s.handleSessionStatus)
+	mux.HandleFunc("/v4/session/delete", s.handleSessionDelete)
+	mux.HandleFunc("/v4/session/wg/set", s.handleWireGuardKeySet)
+	mux.HandleFunc("/v4/geo-lookup", s.handleGeoLookup)
+	return mux
+}
+
+func (s *Server) handleServers(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	data := s.serversJSON
+	s.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (s *Server) handleServersSignature(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	sig := s.serversSign
+	s.mutex.Unlock()
+
+	if len(sig) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write(sig)
+}
+
+func (s *Server) handleGeoLookup(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	location := s.location
+	s.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, location)
+}
+
+func (s *Server) handleSessionNew(w http.ResponseWriter, r *http.Request) {
+	var req types.SessionNewRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	acc, ok := s.accounts[req.AccountID]
+	if !ok {
+		writeError(w, types.Unauthorized, "Invalid Credentials")
+		return
+	}
+	if len(acc.Confirmation2FA) > 0 {
+		if len(req.Confirmation2FA) == 0 {
+			writeError(w, types.The2FARequired, "Account has two-factor authentication enabled. Please enter TOTP token to login")
+			return
+		}
+		if req.Confirmation2FA != acc.Confirmation2FA {
+			writeError(w, types.The2FAInvalidToken, "Specified two-factor authentication token is not valid")
+			return
+		}
+	}
+	if !acc.IsActive {
+		writeError(w, types.AccountNotActive, "Account is not active")
+		return
+	}
+
+	if acc.SessionsLimit > 0 && s.sessionsCount(acc.ID) >= acc.SessionsLimit {
+		if !req.ForceLogin {
+			resp := types.SessionNewErrorLimitResponse{SessionLimitData: serviceStatus(acc)}
+			resp.Status = types.CodeSessionsLimitReached
+			resp.Message = "You've reached the session limit, log out from other device"
+			writeJSON(w, http.StatusOK, resp)
+			return
+		}
+		// force login: remove all existing sessions of the account
+		for token, sess := range s.sessions {
+			if sess.accountID == acc.ID {
+				delete(s.sessions, token)
+			}
+		}
+	}
+
+	sess := &session{accountID: acc.ID}
+	token := newToken()
+	s.sessions[token] = sess
+
+	resp := types.SessionNewResponse{
+		Token:         token,
+		VpnUsername:   "ivpn" + token[:8],
+		VpnPassword:   token[8:24],
+		ServiceStatus: serviceStatus(acc),
+		DeviceName:    "mock-device-" + token[:4],
+	}
+	resp.Status = types.CodeSuccess
+
+	if len(req.PublicKey) > 0 {
+		sess.wgPublicKey = req.PublicKey
+		sess.wgIPAddress = s.nextWireGuardIP()
+		resp.WireGuard.Status = types.CodeSuccess
+		resp.WireGuard.IPAddress = sess.wgIPAddress
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleSessionStatus(w http.ResponseWriter, r *http.Request) {
+	var req types.SessionStatusRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sess, ok := s.sessions[req.Session]
+	if !ok {
+		writeError(w, types.SessionNotFound, "Session not found")
+		return
+	}
+
+	resp := types.SessionStatusResponse{ServiceStatus: serviceStatus(s.accounts[sess.accountID])}
+	resp.Status = types.CodeSuccess
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleSessionDelete(w http.ResponseWriter, r *http.Request) {
+	var req types.SessionDeleteRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.sessions[req.Session]; !ok {
+		writeError(w, types.SessionNotFound, "Session not found")
+		return
+	}
+	delete(s.sessions, req.Session)
+
+	writeJSON(w, http.StatusOK, types.APIErrorResponse{APIResponse: types.APIResponse{Status: types.CodeSuccess}})
+}
+
+func (s *Server) handleWireGuardKeySet(w http.ResponseWriter, r *http.Request) {
+	var req types.SessionWireGuardKeySetRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sess, ok := s.sessions[req.Session]
+	if !ok {
+		writeError(w, types.SessionNotFound, "Session not found")
+		return
+	}
+	if len(req.ConnectedPublicKey) > 0 && req.ConnectedPublicKey != sess.wgPublicKey {
+		writeError(w, types.WGPublicKeyNotFound, "WireGuard Public Key not found")
+		return
+	}
+
+	sess.wgPublicKey = req.PublicKey
+	if len(sess.wgIPAddress) == 0 {
+		sess.wgIPAddress = s.nextWireGuardIP()
+	}
+
+	resp := types.SessionsWireGuardResponse{IPAddress: sess.wgIPAddress}
+	resp.Status = types.CodeSuccess
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// sessionsCount - must be called under locked mutex
+func (s *Server) sessionsCount(accountID string) int {
+	cnt := 0
+	for _, sess := range s.sessions {
+		if sess.accountID == accountID {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// nextWireGuardIP - must be called under locked mutex
+func (s *Server) nextWireGuardIP() string {
+	s.wgIPCounter++
+	return fmt.Sprintf("172.%d.%d.%d", 16+(s.wgIPCounter>>16)&0x0f, (s.wgIPCounter>>8)&0xff, s.wgIPCounter&0xff)
+}
+
+func serviceStatus(acc Account) types.ServiceStatusAPIResp {
+	return types.ServiceStatusAPIResp{
+		Active:       acc.IsActive,
+		ActiveUntil:  acc.ActiveUntil.Unix(),
+		CurrentPlan:  acc.Plan,
+		IsRenewable:  true,
+		Capabilities: acc.Capabilities,
+		Limit:        acc.SessionsLimit,
+	}
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, http.StatusOK, types.APIErrorResponse{APIResponse: types.APIResponse{Status: code}, Message: message})
+}
+
+func writeJSON(w http.ResponseWriter, httpStatus int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	json.NewEncoder(w).Encode(v)
+}
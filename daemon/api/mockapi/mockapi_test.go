@@ -0,0 +1,204 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package mockapi_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/api"
+	"github.com/ivpn/desktop-app/daemon/api/mockapi"
+	"github.com/ivpn/desktop-app/daemon/api/types"
+	protocolTypes "github.com/ivpn/desktop-app/daemon/protocol/types"
+)
+
+func startMock(t *testing.T) (*mockapi.Server, *api.API) {
+	srv, err := mockapi.Start("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+
+	apiObj, err := api.CreateAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := apiObj.SetEndpoint(srv.Endpoint()); err != nil {
+		t.Fatal(err)
+	}
+	return srv, apiObj
+}
+
+func TestServersAndGeoLookup(t *testing.T) {
+	_, apiObj := startMock(t)
+
+	servers, signed, err := apiObj.DownloadServersList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if servers.Timestamp == 0 {
+		t.Fatalf("servers list timestamp not defined")
+	}
+	// cached data verification
+	if _, err := apiObj.VerifyServersList(signed); err != nil {
+		t.Fatal(err)
+	}
+	signed.Data = append([]byte{' '}, signed.Data...)
+	if _, err := apiObj.VerifyServersList(signed); err == nil {
+		t.Fatalf("expected signature verification error for modified data")
+	}
+	if len(servers.WireguardServers) == 0 || len(servers.OpenvpnServers) == 0 {
+		t.Fatalf("empty servers list")
+	}
+	if len(servers.WireguardServers[0].Hosts[0].PublicKey) == 0 || len(servers.Config.Ports.WireGuard) == 0 {
+		t.Fatalf("incomplete servers list")
+	}
+
+	location, _, err := apiObj.GeoLookup(0, protocolTypes.IPvAny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Latitude == 0 || location.Longitude == 0 {
+		t.Fatalf("unexpected location: %v", location)
+	}
+}
+
+func TestServersSignature(t *testing.T) {
+	srv, apiObj := startMock(t)
+
+	// signature is not published by the backend: the unsigned list is returned
+	srv.SetServersSignature(nil)
+	servers, signed, err := apiObj.DownloadServersList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.IsSigned() || len(signed.Data) == 0 || len(servers.WireguardServers) == 0 {
+		t.Fatalf("unexpected unsigned servers list")
+	}
+
+	// tampered signature
+	srv.SetServersSignature([]byte("AAAA"))
+	if _, _, err := apiObj.DownloadServersList(); err == nil {
+		t.Fatalf("expected signature verification error")
+	}
+
+	// signed by the key which is not trusted by the client (default IVPN key)
+	srv.SetServersJSON([]byte(`{"timestamp":1}`))
+	ep := srv.Endpoint()
+	ep.ServersSignPublicKeyPEM = nil
+	if err := apiObj.SetEndpoint(ep); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := apiObj.DownloadServersList(); err == nil {
+		t.Fatalf("expected signature verification error")
+	}
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	srv, apiObj := startMock(t)
+
+	resp, _, _, _, err := apiObj.SessionNew(mockapi.DefaultAccountID, "WG_KEY_1", types.KemPublicKeys{}, false, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Token) == 0 || len(resp.WireGuard.IPAddress) == 0 || !resp.ServiceStatus.Active {
+		t.Fatalf("unexpected session response: %+v", resp)
+	}
+
+	if _, _, err := apiObj.SessionStatus(resp.Token); err != nil {
+		t.Fatal(err)
+	}
+
+	wgResp, err := apiObj.WireGuardKeySet(resp.Token, "WG_KEY_2", "WG_KEY_1", types.KemPublicKeys{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wgResp.IPAddress != resp.WireGuard.IPAddress {
+		t.Fatalf("WireGuard IP address changed after key rotation")
+	}
+	if key, _ := srv.SessionWireGuardKey(resp.Token); key != "WG_KEY_2" {
+		t.Fatalf("WireGuard key not updated")
+	}
+
+	if err := apiObj.SessionDelete(resp.Token); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = apiObj.SessionStatus(resp.Token)
+	var apiErr types.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode != types.SessionNotFound {
+		t.Fatalf("expected 'session not found' error, got: %v", err)
+	}
+}
+
+func TestSessionErrors(t *testing.T) {
+	srv, apiObj := startMock(t)
+	srv.AddAccount(mockapi.Account{ID: "i-LIMIT", IsActive: true, SessionsLimit: 1, Confirmation2FA: "123456"})
+
+	checkCode := func(err error, code int) {
+		t.Helper()
+		var apiErr types.APIError
+		if !errors.As(err, &apiErr) || apiErr.ErrorCode != code {
+			t.Fatalf("expected API error %d, got: %v", code, err)
+		}
+	}
+
+	_, _, _, _, err := apiObj.SessionNew("i-UNKNOWN", "", types.KemPublicKeys{}, false, "", "", "")
+	checkCode(err, types.Unauthorized)
+
+	_, _, _, _, err = apiObj.SessionNew("i-LIMIT", "", types.KemPublicKeys{}, false, "", "", "")
+	checkCode(err, types.The2FARequired)
+
+	if _, _, _, _, err = apiObj.SessionNew("i-LIMIT", "", types.KemPublicKeys{}, false, "", "", "123456"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, limitResp, _, _, err := apiObj.SessionNew("i-LIMIT", "", types.KemPublicKeys{}, false, "", "", "123456")
+	checkCode(err, types.CodeSessionsLimitReached)
+	if limitResp == nil || limitResp.SessionLimitData.Limit != 1 {
+		t.Fatalf("expected session limit details")
+	}
+
+	if _, _, _, _, err = apiObj.SessionNew("i-LIMIT", "", types.KemPublicKeys{}, true, "", "", "123456"); err != nil {
+		t.Fatal(err)
+	}
+	if cnt := srv.SessionsCount("i-LIMIT"); cnt != 1 {
+		t.Fatalf("expected 1 session after forced login, got %d", cnt)
+	}
+}
+
+func TestEndpointTrustAnchor(t *testing.T) {
+	srv, _ := startMock(t)
+
+	// no trust anchor: the self-signed server certificate must be rejected
+	apiObj, _ := api.CreateAPI()
+	if err := apiObj.SetEndpoint(&api.Endpoint{URL: srv.URL()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := apiObj.DownloadServersList(); err == nil {
+		t.Fatalf("expected certificate verification error")
+	}
+
+	if err := apiObj.SetEndpoint(&api.Endpoint{URL: "http://127.0.0.1"}); err == nil {
+		t.Fatalf("expected error for non-HTTPS endpoint")
+	}
+}
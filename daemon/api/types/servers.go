@@ -226,6 +226,10 @@ type ConfigInfo struct {
 
 // ServersInfoResponse all info from servers.json
 type ServersInfoResponse struct {
+	// Generation time of the servers list (Unix time, seconds).
+	// Monotonically increasing: in use to reject rollback to an older servers list.
+	Timestamp int64 `json:"timestamp"`
+
 	WireguardServers []WireGuardServerInfo `json:"wireguard"`
 	OpenvpnServers   []OpenvpnServerInfo   `json:"openvpn"`
 	Config           ConfigInfo            `json:"config"`
@@ -162,7 +162,11 @@ func Launch() {
 	startedOnPortChan := make(chan int, 1)
 	go func() {
 		// waiting for port number info
-		openedPort := <-startedOnPortChan
+		// (channel closed without port number: TCP listener not in use, only unix domain socket is available)
+		openedPort, ok := <-startedOnPortChan
+		if !ok {
+			return
+		}
 
 		// save port info into a file (UI/CLI clients is able to read it)
 		file, err := os.Create(platform.ServicePortFile())
@@ -232,7 +236,7 @@ func doCleanup() (osExitCode int) {
 		}
 
 		// API object
-		apiObj, err := api.CreateAPI()
+		apiObj, err := createAPI()
 		if err != nil {
 			return fmt.Errorf("the API object initialization failed: %w", err)
 		}
@@ -253,10 +257,57 @@ func doCleanup() (osExitCode int) {
 	return 0
 }
 
+// createAPI creates API object.
+// The custom API backend can be defined by command line arguments (for testing only; e.g. with the mock API server):
+//
+//	-api_url=<https://host[:port]>  - base URL of the API server
+//	-api_ca=<file>                  - PEM file with CA certificate(s) of the API server (default: system root certificates)
+//	-api_pin=<hash1,hash2...>       - base64-encoded SHA256 hashes of the server public keys (optional)
+//	-api_servers_key=<file>         - PEM file with RSA public key to verify the servers list signature (default: IVPN key)
+func createAPI() (*api.API, error) {
+	apiObj, err := api.CreateAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	var apiURL, apiCaFile, apiServersKeyFile string
+	var apiPins []string
+	for _, arg := range os.Args {
+		name, value, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		switch strings.ToLower(name) {
+		case "api_url":
+			apiURL = value
+		case "api_ca":
+			apiCaFile = value
+		case "api_servers_key":
+			apiServersKeyFile = value
+		case "api_pin":
+			for _, h := range strings.Split(value, ",") {
+				if h = strings.TrimSpace(h); len(h) > 0 {
+					apiPins = append(apiPins, h)
+				}
+			}
+		}
+	}
+	if len(apiURL) == 0 {
+		return apiObj, nil
+	}
+
+	endpoint, err := api.LoadEndpoint(apiURL, apiCaFile, apiServersKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	endpoint.PinnedKeyHashes = apiPins
+	if err := apiObj.SetEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	return apiObj, nil
+}
+
 // initialize and start service
 func launchService(secret uint64, startedOnPort chan<- int) {
 	// API object
-	apiObj, err := api.CreateAPI()
+	apiObj, err := createAPI()
 	if err != nil {
 		log.Panic("API object initialization failed: ", err)
 	}
@@ -269,6 +320,8 @@ func launchService(secret uint64, startedOnPort chan<- int) {
 
 	// network change detector
 	netDetector := netchange.Create()
+	// network change detector for 'trusted network' rules
+	netTrustDetector := netchange.Create()
 
 	// WireGuard keys manager
 	wgKeysMgr := wgkeys.CreateKeysManager(apiObj, platform.WgToolBinaryPath())
@@ -279,6 +332,9 @@ func launchService(secret uint64, startedOnPort chan<- int) {
 		log.Panic("Protocol object initialization failed: ", err)
 	}
 
+	// unix domain socket listener (if enabled)
+	protocol.SetUnixSocketConfig(doGetUnixSocketConfig())
+
 	// save protocol (to be able to stop it)
 	activeProtocol = protocol
 
@@ -287,6 +343,7 @@ func launchService(secret uint64, startedOnPort chan<- int) {
 		apiObj,
 		updater,
 		netDetector,
+		netTrustDetector,
 		wgKeysMgr,
 		serviceEventsChan,
 		systemLog)
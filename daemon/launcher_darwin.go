@@ -30,6 +30,7 @@ import (
 	"os"
 	"path"
 
+	"github.com/ivpn/desktop-app/daemon/protocol"
 	"github.com/ivpn/desktop-app/daemon/shell"
 )
 
@@ -75,3 +76,8 @@ func doCheckIsAdmin() bool {
 
 	return true
 }
+
+// doGetUnixSocketConfig returns configuration of the unix domain socket listener (not supported on this platform)
+func doGetUnixSocketConfig() *protocol.UnixSocketConfig {
+	return nil
+}
@@ -26,10 +26,15 @@
 package main
 
 import (
+	"fmt"
 	"log/syslog"
 	"os"
+	"strconv"
+	"strings"
 
+	"github.com/ivpn/desktop-app/daemon/protocol"
 	"github.com/ivpn/desktop-app/daemon/service"
+	"github.com/ivpn/desktop-app/daemon/service/platform"
 )
 
 func doPrepareToRun() error {
@@ -68,3 +73,61 @@ func doCheckIsAdmin() bool {
 
 func doStartedOnPort(port int, secret uint64) {
 }
+
+// doGetUnixSocketConfig returns configuration of the unix domain socket listener (or nil if it is not enabled)
+// The listener is enabled by command line arguments:
+//
+//	-unix_socket                  - enable unix socket (only 'root' is allowed to connect)
+//	-unix_socket_uids=1000,1001   - users allowed to connect
+//	-unix_socket_gids=1001        - groups allowed to connect
+//	-unix_socket_keep_tcp         - keep TCP listener (port+secret) enabled for clients which do not support
+//	                                the unix socket (e.g. UI application); by default, the unix socket replaces TCP
+func doGetUnixSocketConfig() *protocol.UnixSocketConfig {
+	isEnabled := false
+	cfg := protocol.UnixSocketConfig{Path: platform.ServiceSocketFile()}
+
+	for _, arg := range os.Args {
+		arg = strings.ToLower(strings.TrimLeft(arg, "-"))
+		name, value, _ := strings.Cut(arg, "=")
+
+		var err error
+		switch name {
+		case "unix_socket":
+			isEnabled = true
+		case "unix_socket_uids":
+			isEnabled = true
+			cfg.AllowedUids, err = parseIdsList(value)
+		case "unix_socket_gids":
+			isEnabled = true
+			cfg.AllowedGids, err = parseIdsList(value)
+		case "unix_socket_keep_tcp":
+			isEnabled = true
+			cfg.KeepTCP = true
+		}
+		if err != nil {
+			log.Error(fmt.Sprintf("Failed to parse argument '%s': %s", arg, err))
+		}
+	}
+
+	if !isEnabled || len(cfg.Path) == 0 {
+		return nil
+	}
+	return &cfg
+}
+
+// parseIdsList parses comma-separated list of uid/gid values (e.g. "1000,1001")
+func parseIdsList(value string) ([]uint32, error) {
+	var ret []uint32
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if len(v) == 0 {
+			continue
+		}
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, uint32(id))
+	}
+	return ret, nil
+}
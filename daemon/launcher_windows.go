@@ -28,6 +28,7 @@ package main
 import (
 	"fmt"
 
+	"github.com/ivpn/desktop-app/daemon/protocol"
 	"github.com/ivpn/desktop-app/daemon/service"
 	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/svc"
@@ -261,3 +262,8 @@ loop:
 
 	return
 }
+
+// doGetUnixSocketConfig returns configuration of the unix domain socket listener (not supported on this platform)
+func doGetUnixSocketConfig() *protocol.UnixSocketConfig {
+	return nil
+}
@@ -22,18 +22,156 @@
 
 package netchange
 
-// structure contains properties required for for macOS implementation
+import (
+	"encoding/binary"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// structure contains properties required for for Linux implementation
+// (all fields are protected by 'Detector.locker')
 type osSpecificProperties struct {
+	// eventfd: signals the reader routine to stop
+	stopFd int
+	// closed when the reader routine exits (nil - the routine is not running)
+	stopped chan struct{}
 }
 
 func (d *Detector) isRoutingChanged() (bool, error) {
 	// TODO: not implemented
+	// (Linux VPN implementations do not replace the 'default' route: only general routing changes are notified)
 	return false, nil
 }
 
+// doStart starts monitoring of route, address and link changes (netlink).
+//
+// The monitoring is enabled only for the general network change detection ('interfaceToProtect' not defined;
+// e.g. 'trusted network' rules). The VPN route change detection ('interfaceToProtect' defined) keeps doing nothing on Linux:
+// isRoutingChanged() is not implemented, so the only effect of the notifications would be needless updates of the V2Ray route.
 func (d *Detector) doStart() {
-	// TODO: not implemented
+	d.locker.Lock()
+	sock, stopFd, stopped, ok := d.linuxStartMonitoring()
+	d.locker.Unlock()
+	if !ok {
+		return
+	}
+
+	log.Info("Route change detector started")
+	defer func() {
+		unix.Close(sock)
+		close(stopped)
+		log.Info("Route change detector stopped")
+	}()
+
+	// Loop waiting for messages.
+	b := make([]byte, unix.Getpagesize())
+	fds := []unix.PollFd{{Fd: int32(sock), Events: unix.POLLIN}, {Fd: int32(stopFd), Events: unix.POLLIN}}
+	for {
+		fds[0].Revents, fds[1].Revents = 0, 0
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if err == unix.EINTR {
+				continue // Interrupted by a signal, retry
+			}
+			log.Error("Route change detector (poll error):", err)
+			return
+		}
+		if fds[1].Revents != 0 {
+			return // Manually stopped
+		}
+		if fds[0].Revents&unix.POLLIN == 0 {
+			if fds[0].Revents&(unix.POLLERR|unix.POLLHUP|unix.POLLNVAL) != 0 {
+				log.Error("Route change detector (socket error)")
+				return
+			}
+			continue
+		}
+
+		nr, _, err := unix.Recvfrom(sock, b, unix.MSG_DONTWAIT)
+		if err != nil {
+			switch err {
+			case unix.EINTR, unix.EAGAIN:
+				continue
+			case unix.ENOBUFS:
+				// socket buffer overflow: some messages are lost, but we know that something changed
+				d.notifyRoutingChangeWithDelay()
+				continue
+			}
+			log.Error("Route change detector (error on socket read):", err)
+			return
+		}
+
+		messages, err := syscall.ParseNetlinkMessage(b[:nr])
+		if err != nil {
+			continue
+		}
+
+		for _, msg := range messages {
+			switch msg.Header.Type {
+			case unix.RTM_NEWROUTE, unix.RTM_DELROUTE,
+				unix.RTM_NEWADDR, unix.RTM_DELADDR,
+				unix.RTM_NEWLINK, unix.RTM_DELLINK:
+				d.notifyRoutingChangeWithDelay()
+			}
+		}
+	}
+}
+
+// linuxStartMonitoring opens the netlink socket and the stop signal (eventfd). Must be called under 'd.locker'.
+func (d *Detector) linuxStartMonitoring() (sock int, stopFd int, stopped chan struct{}, ok bool) {
+	if !d.isStarted || d.props.stopped != nil {
+		return 0, 0, nil, false // stopped before the routine started OR already running
+	}
+	if d.interfaceToProtect != nil {
+		return 0, 0, nil, false // VPN route change detection is not implemented for Linux (see doStart())
+	}
+
+	// netlink socket subscribed to route, address and link changes
+	sock, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		log.Error("Failed to start route change detector:", err)
+		return 0, 0, nil, false
+	}
+
+	addr := &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV4_ROUTE | unix.RTMGRP_IPV6_IFADDR | unix.RTMGRP_IPV6_ROUTE,
+	}
+	if err := unix.Bind(sock, addr); err != nil {
+		unix.Close(sock)
+		log.Error("Failed to start route change detector (bind):", err)
+		return 0, 0, nil, false
+	}
+
+	stopFd, err = unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK)
+	if err != nil {
+		unix.Close(sock)
+		log.Error("Failed to start route change detector (eventfd):", err)
+		return 0, 0, nil, false
+	}
+
+	stopped = make(chan struct{})
+	d.props.stopFd = stopFd
+	d.props.stopped = stopped
+	return sock, stopFd, stopped, true
 }
 
+// doStop stops the reader routine and waits until it exits. Must be called under 'd.locker'.
 func (d *Detector) doStop() {
+	stopped := d.props.stopped
+	if stopped == nil {
+		return
+	}
+	stopFd := d.props.stopFd
+	d.props.stopped = nil
+	d.props.stopFd = 0
+
+	// wake up the reader routine (blocked in poll()) and wait until it exits (the routine closes the netlink socket)
+	var val [8]byte
+	binary.NativeEndian.PutUint64(val[:], 1)
+	if _, err := unix.Write(stopFd, val[:]); err != nil {
+		log.Error("Route change detector (failed to signal stop):", err)
+	}
+	<-stopped
+	unix.Close(stopFd)
 }
@@ -0,0 +1,83 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package netinfo
+
+import (
+	"fmt"
+	"net"
+)
+
+// DefaultNetworkInfo - identity of the network which is in use by the default route
+type DefaultNetworkInfo struct {
+	InterfaceName string           // name of the network interface used by the default route
+	GatewayIP     net.IP           // IP address of the default gateway
+	GatewayMAC    net.HardwareAddr // hardware address of the default gateway (nil - unknown)
+	DhcpDomain    string           // domain name received by DHCP (empty - unknown)
+}
+
+// GetDefaultNetworkInfo returns identity of the network which is in use by the default route.
+// Note: the VPN interface is not taken into account (the default gateway belongs to the physical network)
+func GetDefaultNetworkInfo() (DefaultNetworkInfo, error) {
+	gwIP, err := DefaultGatewayIP()
+	if err != nil {
+		return DefaultNetworkInfo{}, err
+	}
+
+	iface, err := interfaceByNetworkAddr(gwIP)
+	if err != nil {
+		return DefaultNetworkInfo{}, err
+	}
+
+	ret := DefaultNetworkInfo{InterfaceName: iface.Name, GatewayIP: gwIP}
+
+	// gateway MAC address and DHCP domain are optional (not supported on all platforms)
+	if mac, err := doGatewayHardwareAddr(gwIP, iface); err == nil {
+		ret.GatewayMAC = mac
+	}
+	if domain, err := doDhcpDomain(iface); err == nil {
+		ret.DhcpDomain = domain
+	}
+
+	return ret, nil
+}
+
+// interfaceByNetworkAddr - get network interface which local network contains the IP address
+func interfaceByNetworkAddr(ip net.IP) (*net.Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network interfaces: %w", err)
+	}
+
+	for _, ifs := range ifaces {
+		if ifs.Flags&net.FlagUp == 0 || ifs.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, _ := ifs.Addrs()
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.Contains(ip) {
+				return &ifs, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("not found network interface for address: %s", ip.String())
+}
@@ -23,10 +23,14 @@
 package netinfo
 
 import (
+	"bufio"
 	"fmt"
 	"net"
+	"os"
+	"path"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/ivpn/desktop-app/daemon/shell"
 )
@@ -83,3 +87,63 @@ func doDefaultGatewayIP() (defGatewayIP net.IP, err error) {
 
 	return defGatewayIP, retErr
 }
+
+// doGatewayHardwareAddr - returns hardware address of the gateway (from the ARP cache)
+func doGatewayHardwareAddr(gwIP net.IP, iface *net.Interface) (net.HardwareAddr, error) {
+	// Expected content of "/proc/net/arp":
+	//
+	// IP address       HW type     Flags       HW address            Mask     Device
+	// 192.168.1.1      0x1         0x2         aa:bb:cc:dd:ee:ff     *        enp0s3
+	file, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[5] != iface.Name {
+			continue
+		}
+		if ip := net.ParseIP(fields[0]); ip == nil || !ip.Equal(gwIP) {
+			continue
+		}
+		mac, err := net.ParseMAC(fields[3])
+		if err != nil {
+			return nil, err
+		}
+		return mac, nil
+	}
+	return nil, fmt.Errorf("hardware address of %s not found", gwIP.String())
+}
+
+// doDhcpDomain - returns domain name received by DHCP for the interface
+// (NetworkManager and systemd-networkd are supported)
+func doDhcpDomain(iface *net.Interface) (string, error) {
+	// NetworkManager
+	domain := ""
+	outParse := func(text string, isError bool) {
+		if !isError && len(domain) == 0 {
+			domain = strings.TrimSpace(text)
+		}
+	}
+	if err := shell.ExecAndProcessOutput(nil, outParse, "", "nmcli", "-t", "-g", "IP4.DOMAIN", "device", "show", iface.Name); err == nil && len(domain) > 0 {
+		return domain, nil
+	}
+
+	// systemd-networkd
+	file, err := os.Open(path.Join("/run/systemd/netif/leases", strconv.Itoa(iface.Index)))
+	if err != nil {
+		return "", fmt.Errorf("DHCP domain not found for interface %s", iface.Name)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if val, found := strings.CutPrefix(scanner.Text(), "DOMAINNAME="); found {
+			return strings.TrimSpace(val), nil
+		}
+	}
+	return "", fmt.Errorf("DHCP domain not found for interface %s", iface.Name)
+}
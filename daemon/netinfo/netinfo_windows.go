@@ -100,3 +100,13 @@ func DefaultGatewayEx(isIpv6 bool) (defGatewayIP net.IP, inf *net.Interface, err
 	}
 	return bestNextHop, &bestIf, nil
 }
+
+// doGatewayHardwareAddr - returns hardware address of the gateway (not implemented for this platform)
+func doGatewayHardwareAddr(gwIP net.IP, iface *net.Interface) (net.HardwareAddr, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// doDhcpDomain - returns domain name received by DHCP for the interface (not implemented for this platform)
+func doDhcpDomain(iface *net.Interface) (string, error) {
+	return "", fmt.Errorf("not implemented")
+}
@@ -257,3 +257,44 @@ func parseDesktopFile(filepath string, XDG_CURRENT_DESKTOP map[string]struct{})
 	}
 	return ret, nil
 }
+
+// GetDesktopEntryBinary returns the absolute path to the binary of the application defined by the desktop file ID
+// (e.g. 'firefox.desktop'). The standard applications folders are in use
+// (and the '$HOME/.local/share/applications' folder when 'evHOME' is defined).
+func GetDesktopEntryBinary(desktopID string, evHOME string) (string, error) {
+	if !strings.HasSuffix(desktopID, ".desktop") {
+		desktopID += ".desktop"
+	}
+	if strings.ContainsRune(desktopID, '/') {
+		return "", fmt.Errorf("bad desktop file ID '%s'", desktopID)
+	}
+
+	var lookupFolders []string
+	if len(evHOME) > 0 {
+		lookupFolders = append(lookupFolders, path.Join(evHOME, ".local", "share"))
+	}
+	lookupFolders = append(lookupFolders, "/usr/local/share/", "/usr/share/", "/var/lib/snapd/desktop/", "/var/lib/flatpak/exports/share/")
+
+	for _, dir := range lookupFolders {
+		entryPath := path.Join(dir, "applications", desktopID)
+		if _, err := os.Stat(entryPath); err != nil {
+			continue
+		}
+		entry, err := parseDesktopFile(entryPath, map[string]struct{}{})
+		if err != nil {
+			return "", fmt.Errorf("failed to parse '%s': %w", entryPath, err)
+		}
+
+		// skip the 'env' command and environment variables (e.g. 'env VAR=value /usr/bin/app')
+		for _, f := range strings.Fields(entry.Exec) {
+			f = strings.Trim(f, "\"")
+			if f == "env" || strings.Contains(f, "=") {
+				continue
+			}
+			return exec.LookPath(f)
+		}
+		return "", fmt.Errorf("no command defined in '%s'", entryPath)
+	}
+
+	return "", fmt.Errorf("desktop file '%s' not found", desktopID)
+}
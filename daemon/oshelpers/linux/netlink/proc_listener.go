@@ -0,0 +1,157 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"encoding/binary"
+	"fmt"
+	"syscall"
+)
+
+// Process events connector (see linux/cn_proc.h and linux/connector.h)
+const (
+	netlinkConnector = 11 // NETLINK_CONNECTOR
+	cnIdxProc        = 1  // CN_IDX_PROC
+	cnValProc        = 1  // CN_VAL_PROC
+
+	procCnMcastListen = 1 // PROC_CN_MCAST_LISTEN
+	procCnMcastIgnore = 2 // PROC_CN_MCAST_IGNORE
+
+	procEventExec = 0x00000002 // PROC_EVENT_EXEC
+
+	cnMsgHeaderLen = 20 // sizeof(struct cn_msg)
+)
+
+// ProcListener provides possibility to receive notifications about new executed processes
+// (the kernel process events connector is in use; root privileges required)
+//
+// Usage example:
+//
+//	l, err := CreateProcListener()
+//	if err != nil {
+//		return err
+//	}
+//	defer l.Close()
+//	for {
+//		pids, err := l.ReadExecEvents()
+//		if err != nil {
+//			return err
+//		}
+//		for _, pid := range pids {
+//			fmt.Println("Process executed a new binary: ", pid)
+//		}
+//	}
+type ProcListener struct {
+	fd int
+}
+
+// CreateProcListener creates new ProcListener object and subscribes for the process events
+func CreateProcListener() (*ProcListener, error) {
+	s, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, netlinkConnector)
+	if err != nil {
+		return nil, fmt.Errorf("socket initialization error: %w", err)
+	}
+
+	addr := &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Pid:    0,
+		Groups: cnIdxProc,
+	}
+	if err = syscall.Bind(s, addr); err != nil {
+		syscall.Close(s)
+		return nil, fmt.Errorf("socket binding error: %w", err)
+	}
+
+	l := &ProcListener{fd: s}
+	if err := l.sendOp(procCnMcastListen); err != nil {
+		syscall.Close(s)
+		return nil, fmt.Errorf("failed to subscribe for process events: %w", err)
+	}
+	return l, nil
+}
+
+// Close unsubscribes from the process events and closes the socket
+func (l *ProcListener) Close() error {
+	l.sendOp(procCnMcastIgnore)
+	return syscall.Close(l.fd)
+}
+
+// ReadExecEvents blocks until the process events are received.
+// Returns PIDs of the processes which executed a new binary.
+func (l *ProcListener) ReadExecEvents() ([]int, error) {
+	pkt := make([]byte, 4096)
+
+	n, err := syscall.Read(l.fd, pkt)
+	if err != nil {
+		return nil, fmt.Errorf("ProcListener read error: %w", err)
+	}
+
+	msgs, err := syscall.ParseNetlinkMessage(pkt[:n])
+	if err != nil {
+		return nil, fmt.Errorf("ProcListener parse error: %w", err)
+	}
+
+	return parseExecEvents(msgs), nil
+}
+
+func (l *ProcListener) sendOp(op uint32) error {
+	const msgLen = syscall.NLMSG_HDRLEN + cnMsgHeaderLen + 4
+
+	buf := make([]byte, msgLen)
+	// struct nlmsghdr
+	binary.NativeEndian.PutUint32(buf[0:], msgLen)
+	binary.NativeEndian.PutUint16(buf[4:], syscall.NLMSG_DONE)
+	// struct cn_msg
+	cn := buf[syscall.NLMSG_HDRLEN:]
+	binary.NativeEndian.PutUint32(cn[0:], cnIdxProc)
+	binary.NativeEndian.PutUint32(cn[4:], cnValProc)
+	binary.NativeEndian.PutUint16(cn[16:], 4) // data length
+	// data: enum proc_cn_mcast_op
+	binary.NativeEndian.PutUint32(cn[cnMsgHeaderLen:], op)
+
+	return syscall.Sendto(l.fd, buf, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK})
+}
+
+// parseExecEvents returns the process IDs (TGID) from the PROC_EVENT_EXEC messages
+func parseExecEvents(msgs []syscall.NetlinkMessage) []int {
+	var pids []int
+	for _, m := range msgs {
+		// struct cn_msg + struct proc_event {what; cpu; timestamp_ns; exec{process_pid; process_tgid}}
+		data := m.Data
+		if len(data) < cnMsgHeaderLen+24 {
+			continue
+		}
+		if binary.NativeEndian.Uint32(data[0:]) != cnIdxProc || binary.NativeEndian.Uint32(data[4:]) != cnValProc {
+			continue
+		}
+		ev := data[cnMsgHeaderLen:]
+		if binary.NativeEndian.Uint32(ev[0:]) != procEventExec {
+			continue
+		}
+		pids = append(pids, int(binary.NativeEndian.Uint32(ev[20:])))
+	}
+	return pids
+}
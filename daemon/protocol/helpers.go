@@ -38,15 +38,40 @@ func (p *Protocol) createSettingsResponse() *types.SettingsResp {
 	return &types.SettingsResp{
 		IsAutoconnectOnLaunch:       prefs.IsAutoconnectOnLaunch,
 		IsAutoconnectOnLaunchDaemon: prefs.IsAutoconnectOnLaunchDaemon,
+		AutoconnectProfile:          prefs.AutoconnectProfile,
 		UserDefinedOvpnFile:         platform.OpenvpnUserParamsFile(),
 		UserPrefs:                   prefs.UserPrefs,
 		WiFi:                        prefs.WiFiControl,
+		NetworkTrust:                prefs.NetworkTrust,
+		WireGuardHealthCheck:        prefs.WireGuardHealthCheck,
+		ServerScoring:               prefs.ServerScoring,
+		HostsSelection:              prefs.HostsSelection,
 		IsLogging:                   prefs.IsLogging,
 		AntiTracker:                 p._service.GetAntiTrackerStatus(),
 		// TODO: implement the rest of daemon settings
 	}
 }
 
+func (p *Protocol) createConnectionProfilesResponse() *types.ConnectionProfilesResp {
+	prefs := p._service.Preferences()
+	return &types.ConnectionProfilesResp{
+		Profiles:           p._service.ConnectionProfiles(),
+		AutoconnectProfile: prefs.AutoconnectProfile,
+	}
+}
+
+func (p *Protocol) createServerGroupsResponse() *types.ServerGroupsResp {
+	prefs := p._service.Preferences()
+	return &types.ServerGroupsResp{
+		Favourites: prefs.FavouriteServers,
+		Groups:     prefs.ServerGroups,
+	}
+}
+
+func (p *Protocol) createScheduleRulesResponse() *types.ScheduleRulesResp {
+	return &types.ScheduleRulesResp{Rules: p._service.ScheduleRules()}
+}
+
 func (p *Protocol) createHelloResponse() *types.HelloResp {
 	prefs := p._service.Preferences()
 
@@ -72,7 +97,9 @@ func (p *Protocol) createHelloResponse() *types.HelloResp {
 			CanUseDnsOverTls:   dnsOverTls,
 			CanUseDnsOverHttps: dnsOverHttps,
 		},
-		DaemonSettings: *p.createSettingsResponse(),
+		DaemonSettings:        *p.createSettingsResponse(),
+		ProtocolVersion:       types.ProtocolVersion,
+		ProtocolVersionLatest: types.ProtocolVersion,
 	}
 	return &helloResp
 }
@@ -37,6 +37,7 @@ import (
 
 	api_types "github.com/ivpn/desktop-app/daemon/api/types"
 	"github.com/ivpn/desktop-app/daemon/logger"
+	"github.com/ivpn/desktop-app/daemon/netinfo"
 	"github.com/ivpn/desktop-app/daemon/oshelpers"
 	"github.com/ivpn/desktop-app/daemon/protocol/eaa"
 	"github.com/ivpn/desktop-app/daemon/protocol/types"
@@ -75,27 +76,54 @@ type Service interface {
 	ServersListForceUpdate() (*api_types.ServersInfoResponse, error)
 
 	PingServers(timeoutMs int, vpnTypePrioritized vpn.Type, skipSecondPhase bool) (map[string]int, error)
+	ServerScores(vpnType vpn.Type, gateways []string) ([]service_types.ServerScore, error)
+	SetServerScoringWeights(weights service_types.ServerScoringWeights) error
+	SetHostsSelection(params preferences.HostsSelectionParams) error
 
 	APIRequest(apiAlias string, ipTypeRequired types.RequiredIPProtocol) (responseData []byte, err error)
 	DetectAccessiblePorts(portsToTest []api_types.PortInfo) (retPorts []api_types.PortInfo, err error)
 
 	KillSwitchState() (status service_types.KillSwitchStatus, err error)
+	KillSwitchRules(isDryRun bool) (rules service_types.KillSwitchRules, err error)
 	SetKillSwitchState(bool) error
 	SetKillSwitchIsPersistent(isPersistant bool) error
 	SetKillSwitchAllowLANMulticast(isAllowLanMulticast bool) error
 	SetKillSwitchAllowLAN(isAllowLan bool) error
 	SetKillSwitchAllowAPIServers(isAllowAPIServers bool) error
 	SetKillSwitchUserExceptions(exceptions string, ignoreParsingErrors bool) error
+	SetKillSwitchUserExceptionsList(exceptions []service_types.FirewallException, ignoreErrors bool) error
 
 	GetConnectionParams() service_types.ConnectionParams
 	SetConnectionParams(params service_types.ConnectionParams) error
 	SetWiFiSettings(params preferences.WiFiParams) error
+	SetNetworkTrustSettings(params preferences.NetworkTrustParams) error
+	GetNetworkCurrentInfo() (info netinfo.DefaultNetworkInfo, matchedRule *preferences.NetworkTrustRule, err error)
+
+	ConnectionProfiles() []preferences.ConnectionProfile
+	ConnectionProfileCreate(profile preferences.ConnectionProfile) error
+	ConnectionProfileUpdate(name string, profile preferences.ConnectionProfile) error
+	ConnectionProfileDelete(name string) error
+
+	FavouriteServersSet(gateways []string) error
+	ServerGroupSet(group preferences.ServerGroup) error
+	ServerGroupDelete(name string) error
+
+	ScheduleRules() []preferences.ScheduleRule
+	ScheduleRuleCreate(rule preferences.ScheduleRule) error
+	ScheduleRuleUpdate(name string, rule preferences.ScheduleRule) error
+	ScheduleRuleDelete(name string) error
 
 	SplitTunnelling_SetConfig(isEnabled, isInversed, isAnyDns, isAllowWhenNoVpn, reset bool) error
 	SplitTunnelling_GetStatus() (types.SplitTunnelStatus, error)
 	SplitTunnelling_AddApp(exec string) (cmdToExecute string, isAlreadyRunning bool, err error)
 	SplitTunnelling_RemoveApp(pid int, exec string) (err error)
 	SplitTunnelling_AddedPidInfo(pid int, exec string, cmdToExecute string) error
+	SplitTunnelling_AddAppRule(rule string) error
+	SplitTunnelling_RemoveAppRule(rule string) error
+	SplitTunnelling_AddRoute(route string) error
+	SplitTunnelling_RemoveRoute(route string) error
+	SplitTunnelling_AddDomain(domain string) error
+	SplitTunnelling_RemoveDomain(domain string) error
 
 	GetInstalledApps(extraArgsJSON string) ([]oshelpers.AppInfo, error)
 	GetBinaryIcon(binaryPath string) (string, error)
@@ -138,6 +166,11 @@ type Service interface {
 
 	WireGuardGenerateKeys(updateIfNecessary bool) error
 	WireGuardSetKeysRotationInterval(interval int64)
+	WireGuardCustomConfigSet(config string) error
+	SetWireGuardHealthCheckSettings(params preferences.WireGuardHealthCheckParams) error
+	OpenVpnCustomConfigSet(config, username, password string) (removedDirectives []string, err error)
+	ExportConnectionConfig(includeCredentials bool) (vpnType vpn.Type, fileName string, config string, err error)
+	TunnelStatistics() (service_types.TunnelStatistics, error)
 
 	GetWiFiCurrentState() (wifiNotifier.WifiInfo, error)
 	GetWiFiAvailableNetworks() ([]string, error)
@@ -165,10 +198,17 @@ type Protocol struct {
 
 	// connections listener
 	_connListener *net.TCPListener
+	// unix domain socket listener (optional; nil when not in use)
+	_unixListener  *net.UnixListener
+	_unixSocketCfg *UnixSocketConfig
 
 	_connectionsMutex sync.RWMutex
 	_connections      map[net.Conn]connectionInfo
 
+	// clients subscribed to the tunnel statistics events (value: channel to stop sending events)
+	_tunnelStatsSubscriptionsMutex sync.Mutex
+	_tunnelStatsSubscriptions      map[net.Conn]chan struct{}
+
 	// Only last connect request will be processed (if there are more then one received in short period of time)
 	_connRequestMutex sync.Mutex
 	_connRequestChan  chan service_types.ConnectionParams
@@ -199,18 +239,25 @@ func (p *Protocol) Stop() {
 	// (do it only if stopping was requested by Stop() )
 	p.notifyClientsDaemonExiting()
 
+	// keep info that stop command requested
+	p._isRunning = false
+
 	listener := p._connListener
 	if listener != nil {
-		// keep info that stop command requested
-		p._isRunning = false
 		// do not accept new incoming connections
 		listener.Close()
 
 		// Do not use any send\receive communications with connected clients after listener stopped
 	}
+
+	if unixListener := p._unixListener; unixListener != nil {
+		unixListener.Close()
+	}
 }
 
 // Start - starts TCP interface to communicate with IVPN application (server to listen incoming connections)
+// When the unix domain socket is enabled (see SetUnixSocketConfig()) - it replaces the TCP interface
+// (the 'startedOnPort' channel is closed without sending the port), unless 'UnixSocketConfig.KeepTCP' is set.
 func (p *Protocol) Start(secret uint64, startedOnPort chan<- int, service Service) error {
 	if p._service != nil {
 		return errors.New("unable to start protocol communication. It is already initialized")
@@ -227,6 +274,20 @@ func (p *Protocol) Start(secret uint64, startedOnPort chan<- int, service Servic
 		p._service.UnInitialise()
 	}()
 
+	// Start listening on unix domain socket (if enabled)
+	unixListener, err := p.startUnixSocketListener()
+	if err != nil {
+		log.Error(err)
+	}
+	if unixListener != nil && !p._unixSocketCfg.KeepTCP {
+		// the unix socket replaces the TCP+secret channel: do not open the TCP port
+		close(startedOnPort)
+		log.Info("IVPN service started (unix socket only; TCP listener disabled)")
+
+		go p.processConnectionRequests()
+		return p.acceptUnixSocketConnections(unixListener)
+	}
+
 	addr := "127.0.0.1:0"
 	// Initializing listener
 	tcpAddr, err := net.ResolveTCPAddr("tcp4", addr)
@@ -257,6 +318,10 @@ func (p *Protocol) Start(secret uint64, startedOnPort chan<- int, service Servic
 		log.Info("Listener closed")
 	}()
 
+	if unixListener != nil {
+		go p.acceptUnixSocketConnections(unixListener)
+	}
+
 	// Start processing of new connection requests
 	// (connection requests collecting in to chain and processing in order they were received.
 	// See also "RegisterConnectionRequest()" for details)
@@ -272,13 +337,14 @@ func (p *Protocol) Start(secret uint64, startedOnPort chan<- int, service Servic
 			log.Error("Server: failed to accept incoming connection:", err)
 			return fmt.Errorf("(server) failed to accept incoming connection: %w", err)
 		}
-		go p.processClient(conn)
+		go p.processClient(conn, false)
 	}
 }
 
-func (p *Protocol) processClient(conn net.Conn) {
+func (p *Protocol) processClient(conn net.Conn, isPeerCredentialsVerified bool) {
 	// The first request from a client should be 'Hello' request with correct secret
 	// In case of wrong secret - the daemon drops connection
+	// (secret is not required for unix socket clients: they are already verified by the peer credentials)
 	isAuthenticated := false
 
 	clientRemoteAddr := conn.RemoteAddr()
@@ -345,7 +411,7 @@ func (p *Protocol) processClient(conn net.Conn) {
 				p.sendErrorResponse(conn, cmd, fmt.Errorf("connection authentication error: %w", err))
 				return
 			}
-			if hello.Secret != p._secret {
+			if !isPeerCredentialsVerified && hello.Secret != p._secret {
 				log.Warning(fmt.Errorf("refusing connection: secret verification error"))
 				p.sendErrorResponse(conn, cmd, fmt.Errorf("secret verification error"))
 				return
@@ -397,23 +463,6 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 
 	log.Info("[<--] ", p.connLogID(conn), reqCmd.Command, fmt.Sprintf(" [%d]%s", reqCmd.Idx, cmdExtraInfo))
 
-	isDoSkipParanoidMode := func(commandName string) bool {
-		switch commandName {
-		case "Hello",
-			"GetVPNState",
-			"GetServers",
-			"PingServers",
-			"APIRequest",
-			"WiFiAvailableNetworks",
-			"KillSwitchGetStatus",
-			"SplitTunnelGetStatus",
-			"GetDnsPredefinedConfigs",
-			"AccountStatus":
-			return true
-		}
-		return false
-	}
-
 	sendState := func(reqIdx int, isOnlyIfConnected bool) {
 		vpnState := p._lastVPNState
 		if vpnState.State == vpn.CONNECTED {
@@ -431,7 +480,7 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		// EAA is disabled. So, mark connection as authenticated
 		p.clientSetAuthenticated(conn)
 	} else {
-		if !isDoSkipParanoidMode(reqCmd.Command) {
+		if !types.IsParanoidModeFreeCommand(reqCmd.Command) {
 			isOK, err := p._eaa.CheckSecret(reqCmd.ProtocolSecret)
 			if !isOK {
 				// ParanoidMode: wrong password
@@ -476,11 +525,21 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 			p.sendErrorResponse(conn, reqCmd, err)
 		}
 
-		log.Info(fmt.Sprintf("%sConnected client version: '%s'", p.connLogID(conn), req.Version))
+		// negotiate protocol version
+		protocolVersion, err := types.NegotiateProtocolVersion(req.ProtocolVersion)
+		if err != nil {
+			log.Warning(fmt.Sprintf("%sClient version: '%s': %s", p.connLogID(conn), req.Version, err))
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		log.Info(fmt.Sprintf("%sConnected client version: '%s' (protocol version: %d)", p.connLogID(conn), req.Version, protocolVersion))
 
 		// send back Hello message with account session info
 		helloResponse := p.createHelloResponse()
-		p.sendResponse(conn, helloResponse, req.Idx)
+		helloResponseForClient := *helloResponse
+		helloResponseForClient.ProtocolVersion = protocolVersion
+		p.sendResponse(conn, &helloResponseForClient, req.Idx)
 		if req.SendResponseToAllClients {
 			p.notifyClients(helloResponse)
 		}
@@ -513,6 +572,9 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 			p.OnWiFiChanged(p._service.GetWiFiCurrentState())
 		}
 
+	case "GetProtocolSchema":
+		p.sendResponse(conn, &types.ProtocolSchemaResp{Schema: types.CreateProtocolSchema()}, reqCmd.Idx)
+
 	case "ParanoidModeSetPasswordReq":
 		var req types.ParanoidModeSetPasswordReq
 		if err := json.Unmarshal(messageData, &req); err != nil {
@@ -585,6 +647,47 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 
 		p.sendResponse(conn, &types.PingServersResp{PingResults: results}, req.Idx)
 
+	case "ServerScores":
+		var req types.ServerScores
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		scores, err := p._service.ServerScores(req.VpnType, req.Gateways)
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.ServerScoresResp{Scores: scores}, req.Idx)
+
+	case "ServerScoringSettings":
+		var req types.ServerScoringSettings
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.SetServerScoringWeights(req.Weights); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// notify all clients about changed settings
+		p.notifyClients(p.createHelloResponse())
+
+	case "HostsSelectionSettings":
+		var req types.HostsSelectionSettings
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.SetHostsSelection(req.Params); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// notify all clients about changed settings
+		p.notifyClients(p.createHelloResponse())
+
 	case "APIRequest":
 		var req types.APIRequest
 		if err := json.Unmarshal(messageData, &req); err != nil {
@@ -620,6 +723,19 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 				&types.KillSwitchStatusResp{KillSwitchStatus: status}, reqCmd.Idx)
 		}
 
+	case "KillSwitchGetRules":
+		var req types.KillSwitchGetRules
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if rules, err := p._service.KillSwitchRules(req.IsDryRun); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+		} else {
+			p.sendResponse(conn, &types.KillSwitchRulesResp{Rules: rules}, reqCmd.Idx)
+		}
+
 	case "KillSwitchSetEnabled":
 		var req types.KillSwitchSetEnabled
 		if err := json.Unmarshal(messageData, &req); err != nil {
@@ -673,6 +789,20 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		}
 		// all clients will be notified in case of successful change by OnKillSwitchStateChanged() handler
 
+	case "KillSwitchSetUserExceptionsList":
+		var req types.KillSwitchSetUserExceptionsList
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if err := p._service.SetKillSwitchUserExceptionsList(req.Exceptions, !req.FailOnError); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+		} else {
+			p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		}
+		// all clients will be notified in case of successful change by OnKillSwitchStateChanged() handler
+
 	case "KillSwitchSetIsPersistent":
 		var req types.KillSwitchSetIsPersistent
 		if err := json.Unmarshal(messageData, &req); err != nil {
@@ -833,6 +963,84 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		}
 		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
 
+	case "SplitTunnelAddAppRule":
+		var req types.SplitTunnelAddAppRule
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.SplitTunnelling_AddAppRule(req.Rule); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// all clients will be notified about configuration change by service in OnSplitTunnelStatusChanged() handler
+
+	case "SplitTunnelRemoveAppRule":
+		var req types.SplitTunnelRemoveAppRule
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.SplitTunnelling_RemoveAppRule(req.Rule); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// all clients will be notified about configuration change by service in OnSplitTunnelStatusChanged() handler
+
+	case "SplitTunnelAddRoute":
+		var req types.SplitTunnelAddRoute
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.SplitTunnelling_AddRoute(req.Route); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// all clients will be notified about configuration change by service in OnSplitTunnelStatusChanged() handler
+
+	case "SplitTunnelRemoveRoute":
+		var req types.SplitTunnelRemoveRoute
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.SplitTunnelling_RemoveRoute(req.Route); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// all clients will be notified about configuration change by service in OnSplitTunnelStatusChanged() handler
+
+	case "SplitTunnelAddDomain":
+		var req types.SplitTunnelAddDomain
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.SplitTunnelling_AddDomain(req.Domain); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// all clients will be notified about configuration change by service in OnSplitTunnelStatusChanged() handler
+
+	case "SplitTunnelRemoveDomain":
+		var req types.SplitTunnelRemoveDomain
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.SplitTunnelling_RemoveDomain(req.Domain); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// all clients will be notified about configuration change by service in OnSplitTunnelStatusChanged() handler
+
 	case "GenerateDiagnostics":
 		if log, log0, extraInfo, err := p._service.GetDiagnosticLogs(); err != nil {
 			p.sendErrorResponse(conn, reqCmd, err)
@@ -989,7 +1197,7 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 			// set AllowLan and exceptions according to default values
 			p._service.SetKillSwitchAllowLAN(prefs.IsFwAllowLAN)
 			p._service.SetKillSwitchAllowLANMulticast(prefs.IsFwAllowLANMulticast)
-			p._service.SetKillSwitchUserExceptions(prefs.FwUserExceptions, true)
+			p._service.SetKillSwitchUserExceptionsList(prefs.FwUserExceptionsList, true)
 		}
 
 		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
@@ -1038,6 +1246,81 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		p._service.WireGuardSetKeysRotationInterval(req.Interval)
 		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
 
+	case "WireGuardHealthCheckSettings":
+		var req types.WireGuardHealthCheckSettings
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.SetWireGuardHealthCheckSettings(req.Params); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// notify all clients about changed settings
+		p.notifyClients(p.createHelloResponse())
+
+	case "WireGuardCustomConfigSet":
+		var req types.WireGuardCustomConfigSet
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.WireGuardCustomConfigSet(req.Config); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+
+	case "OpenVpnCustomConfigSet":
+		var req types.OpenVpnCustomConfigSet
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		removed, err := p._service.OpenVpnCustomConfigSet(req.Config, req.Username, req.Password)
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.OpenVpnCustomConfigSetResp{RemovedDirectives: removed}, reqCmd.Idx)
+
+	case "ExportConnectionConfig":
+		var req types.ExportConnectionConfig
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		vpnType, fileName, config, err := p._service.ExportConnectionConfig(req.IncludeCredentials)
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.ExportConnectionConfigResp{VpnType: vpnType, FileName: fileName, Config: config}, reqCmd.Idx)
+
+	case "TunnelStatistics":
+		var req types.TunnelStatistics
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if req.Unsubscribe {
+			p.tunnelStatisticsUnsubscribe(conn)
+		} else if req.IntervalSec > 0 {
+			if err := p.tunnelStatisticsSubscribe(conn, req.IntervalSec); err != nil {
+				p.sendErrorResponse(conn, reqCmd, err)
+				break
+			}
+		}
+
+		stats, err := p._service.TunnelStatistics()
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.TunnelStatisticsResp{Statistics: stats}, reqCmd.Idx)
+
 	case "GetAppIcon":
 		var req types.GetAppIcon
 		if err := json.Unmarshal(messageData, &req); err != nil {
@@ -1092,6 +1375,37 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		// notify all clients about changed wifi settings
 		p.notifyClients(p.createHelloResponse())
 
+	case "NetworkTrustSettings":
+		var r types.NetworkTrustSettings
+		if err := json.Unmarshal(messageData, &r); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			return
+		}
+		if err := p._service.SetNetworkTrustSettings(r.Params); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			return
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// notify all clients about changed settings
+		p.notifyClients(p.createHelloResponse())
+
+	case "NetworkCurrentInfo":
+		info, rule, err := p._service.GetNetworkCurrentInfo()
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			return
+		}
+		resp := types.NetworkCurrentInfoResp{
+			InterfaceName: info.InterfaceName,
+			GatewayIP:     info.GatewayIP.String(),
+			GatewayMAC:    info.GatewayMAC.String(),
+			DhcpDomain:    info.DhcpDomain,
+		}
+		if rule != nil {
+			resp.MatchedRule = rule.Name
+		}
+		p.sendResponse(conn, &resp, reqCmd.Idx)
+
 	case "Disconnect":
 		p._disconnectRequested = true
 		p._lastConnectionErrorToNotifyClient = ""
@@ -1130,6 +1444,145 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		// send request confirmation to client
 		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
 
+	case "ConnectionProfiles":
+		p.sendResponse(conn, p.createConnectionProfilesResponse(), reqCmd.Idx)
+
+	case "ConnectionProfileCreate":
+		var req types.ConnectionProfileCreate
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.ConnectionProfileCreate(req.Profile); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// notify all connected clients about changed profiles
+		p.notifyClients(p.createConnectionProfilesResponse())
+
+	case "ConnectionProfileUpdate":
+		var req types.ConnectionProfileUpdate
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.ConnectionProfileUpdate(req.ProfileName, req.Profile); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// notify all connected clients about changed profiles (and settings: profile references could be changed)
+		p.notifyClients(p.createConnectionProfilesResponse())
+		p.notifyClients(p.createSettingsResponse())
+		p.notifyClients(p.createScheduleRulesResponse())
+
+	case "ConnectionProfileDelete":
+		var req types.ConnectionProfileDelete
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.ConnectionProfileDelete(req.ProfileName); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// notify all connected clients about changed profiles (and settings: profile references could be changed)
+		p.notifyClients(p.createConnectionProfilesResponse())
+		p.notifyClients(p.createSettingsResponse())
+		p.notifyClients(p.createScheduleRulesResponse())
+
+	case "ServerGroups":
+		p.sendResponse(conn, p.createServerGroupsResponse(), reqCmd.Idx)
+
+	case "FavouriteServersSet":
+		var req types.FavouriteServersSet
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.FavouriteServersSet(req.Gateways); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// notify all connected clients about changed favourites
+		p.notifyClients(p.createServerGroupsResponse())
+
+	case "ServerGroupSet":
+		var req types.ServerGroupSet
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.ServerGroupSet(req.Group); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// notify all connected clients about changed groups
+		p.notifyClients(p.createServerGroupsResponse())
+
+	case "ServerGroupDelete":
+		var req types.ServerGroupDelete
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.ServerGroupDelete(req.GroupName); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// notify all connected clients about changed groups
+		p.notifyClients(p.createServerGroupsResponse())
+
+	case "ScheduleRules":
+		p.sendResponse(conn, p.createScheduleRulesResponse(), reqCmd.Idx)
+
+	case "ScheduleRuleCreate":
+		var req types.ScheduleRuleCreate
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.ScheduleRuleCreate(req.Rule); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// notify all connected clients about changed rules
+		p.notifyClients(p.createScheduleRulesResponse())
+
+	case "ScheduleRuleUpdate":
+		var req types.ScheduleRuleUpdate
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.ScheduleRuleUpdate(req.RuleName, req.Rule); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// notify all connected clients about changed rules
+		p.notifyClients(p.createScheduleRulesResponse())
+
+	case "ScheduleRuleDelete":
+		var req types.ScheduleRuleDelete
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.ScheduleRuleDelete(req.RuleName); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+		// notify all connected clients about changed rules
+		p.notifyClients(p.createScheduleRulesResponse())
+
 	case "Connect":
 		// parse request
 		var connectRequest types.Connect
@@ -31,6 +31,9 @@ import (
 )
 
 func getConnectionName(c net.Conn) string {
+	if isUnixSocketConnection(c) {
+		return fmt.Sprintf("unix:%p", c)
+	}
 	return strings.TrimSpace(strings.Replace(c.RemoteAddr().String(), "127.0.0.1:", "", 1))
 }
 
@@ -87,6 +90,8 @@ func (p *Protocol) clientDisconnected(c net.Conn) (disconnectedClientInfo *conne
 	delete(p._connections, c)
 	c.Close()
 
+	p.tunnelStatisticsUnsubscribe(c)
+
 	return disconnectedClientInfo
 }
 
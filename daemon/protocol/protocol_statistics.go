@@ -0,0 +1,102 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package protocol
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/protocol/types"
+)
+
+// limits for the interval of tunnel statistics events
+const (
+	tunnelStatsIntervalMinSec = 1
+	tunnelStatsIntervalMaxSec = 60 * 60
+)
+
+// tunnelStatisticsSubscribe starts sending 'TunnelStatisticsResp' events to the client every 'intervalSec' seconds
+// (the previous subscription of the client, if exists, is replaced)
+func (p *Protocol) tunnelStatisticsSubscribe(conn net.Conn, intervalSec int) error {
+	if intervalSec < tunnelStatsIntervalMinSec || intervalSec > tunnelStatsIntervalMaxSec {
+		return fmt.Errorf("statistics interval must be in range %d-%d seconds", tunnelStatsIntervalMinSec, tunnelStatsIntervalMaxSec)
+	}
+
+	p.tunnelStatisticsUnsubscribe(conn)
+
+	stop := make(chan struct{})
+	func() {
+		p._tunnelStatsSubscriptionsMutex.Lock()
+		defer p._tunnelStatsSubscriptionsMutex.Unlock()
+		if p._tunnelStatsSubscriptions == nil {
+			p._tunnelStatsSubscriptions = make(map[net.Conn]chan struct{})
+		}
+		p._tunnelStatsSubscriptions[conn] = stop
+	}()
+
+	log.Info(fmt.Sprintf("%sSubscribed to tunnel statistics (interval %ds)", p.connLogID(conn), intervalSec))
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(intervalSec) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			stats, err := p._service.TunnelStatistics()
+			if err != nil {
+				log.Debug("Failed to get tunnel statistics: ", err)
+				continue
+			}
+			// The events are sent directly (not using 'sendResponse()') to avoid flooding the log
+			if err := Send(conn, &types.TunnelStatisticsResp{Statistics: stats}, 0); err != nil {
+				log.Info(fmt.Sprintf("%sStopped sending tunnel statistics: %s", p.connLogID(conn), err))
+				// remove the subscription (only if it was not replaced by a new one)
+				p._tunnelStatsSubscriptionsMutex.Lock()
+				if p._tunnelStatsSubscriptions[conn] == stop {
+					delete(p._tunnelStatsSubscriptions, conn)
+				}
+				p._tunnelStatsSubscriptionsMutex.Unlock()
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// tunnelStatisticsUnsubscribe stops sending tunnel statistics events to the client
+func (p *Protocol) tunnelStatisticsUnsubscribe(conn net.Conn) {
+	p._tunnelStatsSubscriptionsMutex.Lock()
+	defer p._tunnelStatsSubscriptionsMutex.Unlock()
+
+	if stop, ok := p._tunnelStatsSubscriptions[conn]; ok {
+		close(stop)
+		delete(p._tunnelStatsSubscriptions, conn)
+	}
+}
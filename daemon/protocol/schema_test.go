@@ -0,0 +1,107 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package protocol_test
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/protocol/types"
+)
+
+// Ensure all requests processed by the daemon are described in the protocol schema (and vice versa)
+func TestProtocolSchemaCoversAllCommands(t *testing.T) {
+	src, err := os.ReadFile("protocol.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	processed := make(map[string]struct{})
+	for _, m := range regexp.MustCompile(`(?m)^\tcase "(\w+)":`).FindAllSubmatch(src, -1) {
+		processed[string(m[1])] = struct{}{}
+	}
+	if len(processed) == 0 {
+		t.Fatal("no commands found in protocol.go")
+	}
+
+	described := make(map[string]struct{})
+	for _, c := range types.GetProtocolCommandNames() {
+		if _, ok := described[c]; ok {
+			t.Errorf("command '%s' described more than once", c)
+		}
+		described[c] = struct{}{}
+		if _, ok := processed[c]; !ok {
+			t.Errorf("command '%s' described in schema but not processed by the daemon", c)
+		}
+	}
+	for c := range processed {
+		if _, ok := described[c]; !ok {
+			t.Errorf("command '%s' is not described in protocol schema", c)
+		}
+	}
+}
+
+func TestProtocolSchemaDefinitions(t *testing.T) {
+	schema := types.CreateProtocolSchema()
+
+	for _, c := range schema.Commands {
+		for _, name := range append(append([]string{c.Request}, c.Responses...), c.Errors...) {
+			if _, ok := schema.Definitions[name]; !ok {
+				t.Errorf("command '%s': type '%s' is not defined", c.Command, name)
+			}
+		}
+	}
+
+	hello, ok := schema.Definitions["Hello"]
+	if !ok {
+		t.Fatal("'Hello' is not defined")
+	}
+	for _, field := range []string{"Command", "Idx", "ProtocolSecret", "Secret", "ProtocolVersion"} {
+		if _, ok := hello.Properties[field]; !ok {
+			t.Errorf("'Hello' definition does not contain field '%s'", field)
+		}
+	}
+
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNegotiateProtocolVersion(t *testing.T) {
+	if v, err := types.NegotiateProtocolVersion(0); err != nil || v != types.ProtocolVersionMin {
+		t.Errorf("old client: expected %d, got %d (err: %v)", types.ProtocolVersionMin, v, err)
+	}
+	if v, err := types.NegotiateProtocolVersion(types.ProtocolVersion + 1); err != nil || v != types.ProtocolVersion {
+		t.Errorf("newer client: expected %d, got %d (err: %v)", types.ProtocolVersion, v, err)
+	}
+	if v, err := types.NegotiateProtocolVersion(types.ProtocolVersion); err != nil || v != types.ProtocolVersion {
+		t.Errorf("same version: expected %d, got %d (err: %v)", types.ProtocolVersion, v, err)
+	}
+	if types.ProtocolVersionMin > 1 {
+		if _, err := types.NegotiateProtocolVersion(types.ProtocolVersionMin - 1); err == nil {
+			t.Error("outdated client: expected error")
+		}
+	}
+}
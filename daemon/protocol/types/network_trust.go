@@ -0,0 +1,50 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types
+
+import (
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+)
+
+// NetworkTrustSettings (request) sets trust rules for wired (non-WiFi) networks
+type NetworkTrustSettings struct {
+	RequestBase
+	Params preferences.NetworkTrustParams
+}
+
+// NetworkCurrentInfo (request) requests info about the network which is in use by the default route
+type NetworkCurrentInfo struct {
+	RequestBase
+}
+
+// NetworkCurrentInfoResp (response) contains info about the network which is in use by the default route
+type NetworkCurrentInfoResp struct {
+	CommandBase
+	InterfaceName string
+	GatewayIP     string
+	GatewayMAC    string // empty - unknown
+	DhcpDomain    string // empty - unknown
+
+	// name of the trust rule which is matching the network (empty - no rule matches)
+	MatchedRule string
+}
@@ -0,0 +1,61 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types
+
+import (
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+)
+
+// ConnectionProfiles (request) requests all saved connection profiles
+type ConnectionProfiles struct {
+	RequestBase
+}
+
+// ConnectionProfilesResp (response) contains all saved connection profiles.
+// Also, it is sent to all clients when profiles are changed.
+type ConnectionProfilesResp struct {
+	CommandBase
+	Profiles []preferences.ConnectionProfile
+	// name of the profile used for automatic connections (empty - last connection parameters are in use)
+	AutoconnectProfile string
+}
+
+// ConnectionProfileCreate (request) saves new connection profile
+type ConnectionProfileCreate struct {
+	RequestBase
+	Profile preferences.ConnectionProfile
+}
+
+// ConnectionProfileUpdate (request) updates existing connection profile
+// (the profile can be renamed: 'ProfileName' is the current profile name, 'Profile.Name' is the new one)
+type ConnectionProfileUpdate struct {
+	RequestBase
+	ProfileName string
+	Profile     preferences.ConnectionProfile
+}
+
+// ConnectionProfileDelete (request) removes connection profile
+type ConnectionProfileDelete struct {
+	RequestBase
+	ProfileName string
+}
@@ -49,6 +49,8 @@ type Hello struct {
 	ClientType ClientTypeEnum
 	// connected client version
 	Version string
+	// the latest protocol version supported by the client (0 - for old clients; see 'NegotiateProtocolVersion()')
+	ProtocolVersion int
 
 	Secret uint64
 
@@ -94,6 +96,27 @@ type PingServers struct {
 	SkipSecondPhase       bool
 }
 
+// ServerScores - get servers sorted by score (the best server is the first one).
+// The score is calculated using latency, servers load, distance and connection history (see 'ServerScoringWeights')
+type ServerScores struct {
+	RequestBase
+	VpnType vpn.Type
+	// (optional) the servers to take into account (e.g. "us-tx.wg.ivpn.net"); empty - all servers
+	Gateways []string
+}
+
+// ServerScoringSettings - set weights of the factors for the 'Best' server selection
+type ServerScoringSettings struct {
+	RequestBase
+	Weights service_types.ServerScoringWeights
+}
+
+// HostsSelectionSettings - set pinned and blacklisted hosts of the servers
+type HostsSelectionSettings struct {
+	RequestBase
+	Params preferences.HostsSelectionParams
+}
+
 // KillSwitchSetAllowLANMulticast enable\disable LAN multicast acces for kill-switch
 type KillSwitchSetAllowLANMulticast struct {
 	RequestBase
@@ -114,6 +137,15 @@ type KillSwitchSetUserExceptions struct {
 	FailOnParsingError bool
 }
 
+// KillSwitchSetUserExceptionsList set the list of exceptions (optionally restricted by protocol, ports and direction)
+// to exclude from firewall blocking rules
+type KillSwitchSetUserExceptionsList struct {
+	RequestBase
+	Exceptions []service_types.FirewallException
+	// FailOnError - if false: the invalid (or not supported on current platform) exceptions are skipped
+	FailOnError bool
+}
+
 type KillSwitchSetAllowApiServers struct {
 	RequestBase
 	IsAllowApiServers bool
@@ -130,6 +162,13 @@ type KillSwitchGetStatus struct {
 	RequestBase
 }
 
+// KillSwitchGetRules get description of the firewall rules
+type KillSwitchGetRules struct {
+	RequestBase
+	// IsDryRun - get description of rules which would be applied when the firewall is enabled
+	IsDryRun bool
+}
+
 // KillSwitchSetIsPersistent request to mark kill-switch persistant
 type KillSwitchSetIsPersistent struct {
 	RequestBase
@@ -260,6 +299,46 @@ type WireGuardSetKeysRotationInterval struct {
 	Interval int64
 }
 
+// WireGuardHealthCheckSettings - set parameters of the WireGuard connection monitoring (stale handshake detection)
+type WireGuardHealthCheckSettings struct {
+	RequestBase
+	Params preferences.WireGuardHealthCheckParams
+}
+
+// WireGuardCustomConfigSet - validate and save the custom WireGuard configuration ('wg-quick' format)
+// To connect using this configuration, use 'Connect' request with 'Params.WireGuardParameters.CustomConfig = true'
+type WireGuardCustomConfigSet struct {
+	RequestBase
+	Config string // configuration file content (empty - remove configuration)
+}
+
+// OpenVpnCustomConfigSet - validate, sanitise and save the custom OpenVPN configuration ('.ovpn' profile)
+// To connect using this configuration, use 'Connect' request with 'Params.OpenVpnParameters.CustomConfig = true'
+type OpenVpnCustomConfigSet struct {
+	RequestBase
+	Config string // profile content (empty - remove configuration)
+	// Credentials (optional): required only when profile contains 'auth-user-pass' without inline '<auth-user-pass>' block
+	Username string
+	Password string
+}
+
+// ExportConnectionConfig - render the current connection parameters into a standalone configuration
+// ('wg-quick' configuration for WireGuard or '.ovpn' profile for OpenVPN)
+type ExportConnectionConfig struct {
+	RequestBase
+	// OpenVPN: inline credentials into the profile ('<auth-user-pass>' block; requires OpenVPN 2.6 or newer)
+	IncludeCredentials bool
+}
+
+// TunnelStatistics - request traffic statistics of the active VPN tunnel (response: 'TunnelStatisticsResp').
+// When 'IntervalSec' > 0, the client is subscribed to statistics events: the daemon sends 'TunnelStatisticsResp'
+// (with zero index) every 'IntervalSec' seconds until the client disconnects or sends the request with 'Unsubscribe' = true
+type TunnelStatistics struct {
+	RequestBase
+	IntervalSec int  // (optional) events interval; 0 - no events (one-time request)
+	Unsubscribe bool // stop sending statistics events to this client
+}
+
 // IPProtocol - VPN type
 type RequiredIPProtocol int
 
@@ -283,6 +362,11 @@ type ParanoidModeSetPasswordReq struct {
 	NewSecret string
 }
 
+// GetProtocolSchema request machine-readable description of the daemon protocol
+type GetProtocolSchema struct {
+	RequestBase
+}
+
 type CheckAccessiblePorts struct {
 	RequestBase
 	PortsToTest []api_types.PortInfo // in case of empty - will be tested all known ports
@@ -88,6 +88,14 @@ type DisabledFunctionalityLinux struct {
 	//	- there is no 'resolvectl' binary on target system
 	//	- 'resolvectl' initialisation try was failed
 	DnsMgmtNewResolvectlError string
+
+	// If not empty - it is not possible to use the native nftables firewall backend
+	// (e.g. the kernel does not support nf_tables)
+	NftablesBackendError string
+
+	// If not empty - it is not possible to use the embedded userspace WireGuard implementation
+	// (e.g. the TUN device is not available)
+	WireGuardUserspaceError string
 }
 
 type DisabledFunctionalityForPlatform struct {
@@ -128,9 +136,14 @@ type SettingsResp struct {
 
 	IsAutoconnectOnLaunch       bool
 	IsAutoconnectOnLaunchDaemon bool
+	AutoconnectProfile          string
 	UserDefinedOvpnFile         string
 	UserPrefs                   preferences.UserPreferences
 	WiFi                        preferences.WiFiParams
+	NetworkTrust                preferences.NetworkTrustParams
+	WireGuardHealthCheck        preferences.WireGuardHealthCheckParams
+	ServerScoring               service_types.ServerScoringWeights
+	HostsSelection              preferences.HostsSelectionParams
 	IsLogging                   bool
 	AntiTracker                 service_types.AntiTrackerMetadata
 
@@ -161,6 +174,12 @@ type HelloResp struct {
 	ParanoidMode ParanoidModeStatus
 
 	DaemonSettings SettingsResp
+
+	// ProtocolVersion - protocol version negotiated for the connection
+	// (in notifications to all clients - it is equal to ProtocolVersionLatest)
+	ProtocolVersion int
+	// ProtocolVersionLatest - the latest protocol version supported by the daemon
+	ProtocolVersionLatest int
 }
 
 // SessionResp information about session
@@ -213,6 +232,12 @@ type KillSwitchStatusResp struct {
 	service_types.KillSwitchStatus
 }
 
+// KillSwitchRulesResp returns description of the firewall rules
+type KillSwitchRulesResp struct {
+	CommandBase
+	Rules service_types.KillSwitchRules
+}
+
 // KillSwitchGetIsPestistentResp returns kill-switch persistance status
 type KillSwitchGetIsPestistentResp struct {
 	CommandBase
@@ -227,6 +252,26 @@ type DiagnosticsGeneratedResp struct {
 	ExtraInfo   string // Extra info for logging (e.g. ifconfig, netstat -nr ... etc.)
 }
 
+// OpenVpnCustomConfigSetResp - result of saving the custom OpenVPN configuration
+type OpenVpnCustomConfigSetResp struct {
+	CommandBase
+	RemovedDirectives []string // directives removed from the profile by sanitising (e.g. 'up', 'script-security')
+}
+
+// ExportConnectionConfigResp - the standalone configuration for current connection parameters
+type ExportConnectionConfigResp struct {
+	CommandBase
+	VpnType  vpn.Type
+	FileName string // suggested file name (e.g. "ivpn-nl4.conf")
+	Config   string
+}
+
+// TunnelStatisticsResp - traffic statistics of the active VPN tunnel
+type TunnelStatisticsResp struct {
+	CommandBase
+	Statistics service_types.TunnelStatistics
+}
+
 type DnsStatus struct {
 	Dns               dns.DnsSettings
 	AntiTrackerStatus service_types.AntiTrackerMetadata
@@ -309,6 +354,12 @@ type PingServersResp struct {
 	PingResults []PingResultType
 }
 
+// ServerScoresResp returns servers sorted by score (the best server is the first one)
+type ServerScoresResp struct {
+	CommandBase
+	Scores []service_types.ServerScore
+}
+
 // WiFiNetworkInfo - information about WIFI network
 type WiFiNetworkInfo struct {
 	SSID string
@@ -366,6 +417,12 @@ func (r APIResponse) LogExtraInfo() string {
 	return fmt.Sprint(r.APIPath)
 }
 
+// ProtocolSchemaResp - machine-readable description of the daemon protocol
+type ProtocolSchemaResp struct {
+	CommandBase
+	Schema ProtocolSchema
+}
+
 type CheckAccessiblePortsResponse struct {
 	RequestBase
 	Ports []api_types.PortInfo
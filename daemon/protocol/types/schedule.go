@@ -0,0 +1,59 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types
+
+import (
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+)
+
+// ScheduleRules (request) requests all scheduler rules
+type ScheduleRules struct {
+	RequestBase
+}
+
+// ScheduleRulesResp (response) contains all scheduler rules.
+// Also, it is sent to all clients when rules are changed.
+type ScheduleRulesResp struct {
+	CommandBase
+	Rules []preferences.ScheduleRule
+}
+
+// ScheduleRuleCreate (request) saves new scheduler rule
+type ScheduleRuleCreate struct {
+	RequestBase
+	Rule preferences.ScheduleRule
+}
+
+// ScheduleRuleUpdate (request) updates existing scheduler rule
+// (the rule can be renamed: 'RuleName' is the current rule name, 'Rule.Name' is the new one)
+type ScheduleRuleUpdate struct {
+	RequestBase
+	RuleName string
+	Rule     preferences.ScheduleRule
+}
+
+// ScheduleRuleDelete (request) removes scheduler rule
+type ScheduleRuleDelete struct {
+	RequestBase
+	RuleName string
+}
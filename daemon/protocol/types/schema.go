@@ -0,0 +1,298 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ProtocolVersion - current version of the daemon protocol.
+// Must be increased on each change of the protocol (new commands, new fields, changed behavior).
+// The version history is described in 'ProtocolVersionHistory'.
+const ProtocolVersion = 22
+
+// ProtocolVersionMin - the oldest protocol version supported by the daemon.
+// The daemon does not adapt requests/responses to the older protocol versions, so it must be increased
+// on each backward-incompatible change of the protocol (e.g. changed type of a field).
+// Clients which do not inform about protocol version (old clients) are considered to use this version.
+const ProtocolVersionMin = 22
+
+// ProtocolVersionHistory - short description of changes for each protocol version
+var ProtocolVersionHistory = map[int]string{
+	1:  "initial versioned protocol: protocol version negotiation in 'Hello'; 'GetProtocolSchema' request",
+	2:  "connection profiles: 'ConnectionProfiles', 'ConnectionProfileCreate', 'ConnectionProfileUpdate', 'ConnectionProfileDelete'; 'autoconnect_profile' preference",
+	3:  "per-network rules in WiFi settings: 'WiFiNetwork.rule' (connection parameters and firewall policy)",
+	4:  "trust rules for wired networks: 'NetworkTrustSettings', 'NetworkCurrentInfo'; 'SettingsResp.NetworkTrust'",
+	5:  "scheduler: 'ScheduleRules', 'ScheduleRuleCreate', 'ScheduleRuleUpdate', 'ScheduleRuleDelete'",
+	6:  "Linux firewall backend: 'UserPreferences.Linux.FirewallBackend'; 'DisabledFunctionalityLinux.NftablesBackendError'",
+	7:  "firewall rules inspection: 'KillSwitchGetRules'",
+	8:  "firewall exceptions restricted by protocol/ports/direction: 'KillSwitchSetUserExceptionsList'; 'KillSwitchStatusResp.UserExceptionsList'",
+	9:  "firewall exceptions defined by hostnames: 'KillSwitchStatusResp.UserExceptionsHosts'",
+	10: "split tunnel destinations: 'SplitTunnelAddRoute', 'SplitTunnelRemoveRoute', 'SplitTunnelAddDomain', 'SplitTunnelRemoveDomain'; 'SplitTunnelStatus.SplitTunnelRoutes/SplitTunnelDomains/SplitTunnelDomainsResolved'",
+	11: "persistent split tunnel rules for applications: 'SplitTunnelAddAppRule', 'SplitTunnelRemoveAppRule'",
+	12: "split tunnel traffic counters: 'SplitTunnelStatus.AppsTraffic', 'SplitTunnelStatus.AppsTrafficError'",
+	13: "custom WireGuard configuration: 'WireGuardCustomConfigSet'; 'Connect.Params.WireGuardParameters.CustomConfig'",
+	14: "custom OpenVPN configuration: 'OpenVpnCustomConfigSet'; 'Connect.Params.OpenVpnParameters.CustomConfig'",
+	15: "export current connection parameters as standalone configuration: 'ExportConnectionConfig'",
+	16: "Linux userspace WireGuard implementation: 'UserPreferences.Linux.WireGuardBackend'; 'DisabledFunctionalityLinux.WireGuardUserspaceError'",
+	17: "tunnel traffic statistics: 'TunnelStatistics' request and 'TunnelStatisticsResp' events",
+	18: "WireGuard stale handshake detection: 'WireGuardHealthCheckSettings'; 'SettingsResp.WireGuardHealthCheck'",
+	19: "scored server selection: 'ServerSelectionEnum.Best' (3); 'ServerScores', 'ServerScoringSettings'; 'SettingsResp.ServerScoring'",
+	20: "pinned and blacklisted hosts: 'HostsSelectionSettings'; 'SettingsResp.HostsSelection'",
+	21: "favourite servers and server groups: 'ServerGroups', 'FavouriteServersSet', 'ServerGroupSet', 'ServerGroupDelete'; 'ConnectMetadata.ServerGroupEntry/ServerGroupExit'",
+	22: "scheduler rules: 'ScheduleRule.ServerSelection/ServerLocation'; 'ConnectMetadata.ServerLocationEntry'; 'ConnectionProfileDelete' fails for profiles in use by scheduler rules",
+}
+
+// NegotiateProtocolVersion returns protocol version to be used for communication with a client
+// (the latest version supported by both sides).
+// Returns error when the client supports only the protocol versions older than 'ProtocolVersionMin'.
+func NegotiateProtocolVersion(clientVersion int) (int, error) {
+	if clientVersion == 0 {
+		return ProtocolVersionMin, nil // old client (protocol version not defined)
+	}
+	if clientVersion < ProtocolVersionMin {
+		return 0, fmt.Errorf("protocol version %d is not supported (the oldest supported version: %d)", clientVersion, ProtocolVersionMin)
+	}
+	if clientVersion > ProtocolVersion {
+		return ProtocolVersion, nil
+	}
+	return clientVersion, nil
+}
+
+// SchemaType - JSON-schema-like description of a data type
+type SchemaType struct {
+	Type                 string                 `json:"type,omitempty"` // "object", "array", "string", "integer", "number", "boolean" (empty - any type)
+	Format               string                 `json:"format,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"` // reference to a type definition: "#/definitions/<name>"
+	Items                *SchemaType            `json:"items,omitempty"`
+	Properties           map[string]*SchemaType `json:"properties,omitempty"`
+	AdditionalProperties *SchemaType            `json:"additionalProperties,omitempty"`
+}
+
+// CommandSchema - description of a request to the daemon
+type CommandSchema struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+	// Request - name of the request type definition
+	Request string `json:"request"`
+	// Responses - possible response types (the first one is the main response type)
+	Responses []string `json:"responses"`
+	// Errors - possible error response types
+	Errors []string `json:"errors"`
+	// Events - notifications which can be sent to all clients as a result of the request
+	Events []string `json:"events,omitempty"`
+	// IsParanoidModeProtected - when 'true' the request requires 'ProtocolSecret' (if Paranoid Mode is enabled)
+	IsParanoidModeProtected bool `json:"isParanoidModeProtected"`
+}
+
+// EventSchema - description of a message which the daemon can send to clients without request
+type EventSchema struct {
+	Event       string `json:"event"`
+	Description string `json:"description"`
+}
+
+// ProtocolSchema - machine-readable description of the daemon protocol
+type ProtocolSchema struct {
+	Version        int                    `json:"version"`
+	VersionMin     int                    `json:"versionMin"`
+	VersionHistory map[int]string         `json:"versionHistory"`
+	Commands       []CommandSchema        `json:"commands"`
+	Events         []EventSchema          `json:"events"`
+	Definitions    map[string]*SchemaType `json:"definitions"`
+}
+
+// CreateProtocolSchema returns description of all supported commands, events and data types
+func CreateProtocolSchema() ProtocolSchema {
+	gen := schemaGenerator{definitions: make(map[string]*SchemaType)}
+
+	ret := ProtocolSchema{
+		Version:        ProtocolVersion,
+		VersionMin:     ProtocolVersionMin,
+		VersionHistory: ProtocolVersionHistory,
+		Commands:       make([]CommandSchema, 0, len(protocolCommands)),
+		Events:         make([]EventSchema, 0, len(protocolEvents)),
+	}
+
+	typeNames := func(objs []interface{}) []string {
+		names := make([]string, 0, len(objs))
+		for _, o := range objs {
+			names = append(names, gen.define(reflect.TypeOf(o)))
+		}
+		return names
+	}
+
+	for _, c := range protocolCommands {
+		ret.Commands = append(ret.Commands, CommandSchema{
+			Command:                 c.Command,
+			Description:             c.Description,
+			Request:                 gen.define(reflect.TypeOf(c.Request)),
+			Responses:               typeNames(c.Responses),
+			Errors:                  typeNames([]interface{}{ErrorResp{}}),
+			Events:                  typeNames(c.Events),
+			IsParanoidModeProtected: !IsParanoidModeFreeCommand(c.Command),
+		})
+	}
+
+	for _, e := range protocolEvents {
+		ret.Events = append(ret.Events, EventSchema{
+			Event:       gen.define(reflect.TypeOf(e.Event)),
+			Description: e.Description,
+		})
+	}
+
+	ret.Definitions = gen.definitions
+	return ret
+}
+
+// GetProtocolCommandNames returns names of all commands described in protocol schema
+func GetProtocolCommandNames() []string {
+	ret := make([]string, 0, len(protocolCommands))
+	for _, c := range protocolCommands {
+		ret = append(ret, c.Command)
+	}
+	return ret
+}
+
+// IsParanoidModeFreeCommand returns 'true' for requests which are allowed without 'ProtocolSecret' (even if Paranoid Mode is enabled)
+func IsParanoidModeFreeCommand(commandName string) bool {
+	switch commandName {
+	case "Hello",
+		"GetVPNState",
+		"GetServers",
+		"PingServers",
+		"APIRequest",
+		"WiFiAvailableNetworks",
+		"KillSwitchGetStatus",
+		"SplitTunnelGetStatus",
+		"GetDnsPredefinedConfigs",
+		"GetProtocolSchema",
+		"AccountStatus":
+		return true
+	}
+	return false
+}
+
+type schemaGenerator struct {
+	definitions map[string]*SchemaType
+}
+
+// definitionName returns name of type definition.
+// Types from the protocol package are named by the type name only (it is equal to the command name),
+// all other types have prefix of the package path (e.g. 'api/types.ServersInfoResponse')
+func definitionName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if pkg == reflect.TypeOf(CommandBase{}).PkgPath() {
+		return t.Name()
+	}
+	const modulePrefix = "github.com/ivpn/desktop-app/daemon/"
+	return strings.TrimPrefix(pkg, modulePrefix) + "." + t.Name()
+}
+
+// define adds definition of the struct type (if not added yet) and returns its name
+func (g *schemaGenerator) define(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	name := definitionName(t)
+	if _, ok := g.definitions[name]; ok {
+		return name
+	}
+	def := &SchemaType{Type: "object", Properties: make(map[string]*SchemaType)}
+	g.definitions[name] = def // register before processing fields (avoid infinite recursion)
+	g.addStructFields(def, t)
+	return name
+}
+
+func (g *schemaGenerator) addStructFields(def *SchemaType, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(tag, ",")
+
+		// embedded structs: fields are serialized as fields of the parent object
+		if f.Anonymous && len(jsonName) == 0 {
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addStructFields(def, ft)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+		if len(jsonName) == 0 {
+			jsonName = f.Name
+		}
+		def.Properties[jsonName] = g.typeOf(f.Type)
+	}
+}
+
+func (g *schemaGenerator) typeOf(t reflect.Type) *SchemaType {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return &SchemaType{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &SchemaType{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &SchemaType{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &SchemaType{Type: "number"}
+	case reflect.String:
+		return &SchemaType{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &SchemaType{Type: "string", Format: "byte"} // []byte is serialized as base64 string
+		}
+		return &SchemaType{Type: "array", Items: g.typeOf(t.Elem())}
+	case reflect.Map:
+		return &SchemaType{Type: "object", AdditionalProperties: g.typeOf(t.Elem())}
+	case reflect.Struct:
+		if len(t.Name()) == 0 {
+			// anonymous struct
+			def := &SchemaType{Type: "object", Properties: make(map[string]*SchemaType)}
+			g.addStructFields(def, t)
+			return def
+		}
+		return &SchemaType{Ref: "#/definitions/" + g.define(t)}
+	}
+	// interface{} or unsupported type: any value
+	return &SchemaType{}
+}
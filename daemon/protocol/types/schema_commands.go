@@ -0,0 +1,267 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types
+
+// commandInfo - description of a protocol command (used to generate protocol schema)
+type commandInfo struct {
+	Command     string
+	Description string
+	Request     interface{}
+	Responses   []interface{}
+	Events      []interface{}
+}
+
+type eventInfo struct {
+	Event       interface{}
+	Description string
+}
+
+// vpnStateResponses - possible responses which are describing current VPN state
+var vpnStateResponses = []interface{}{ConnectedResp{}, DisconnectedResp{}, VpnStateResp{}}
+
+// protocolCommands - all requests supported by the daemon.
+// IMPORTANT! Each new request must be registered here (and 'ProtocolVersion' must be increased).
+var protocolCommands = []commandInfo{
+	{Command: "EmptyReq", Request: EmptyReq{}, Responses: []interface{}{EmptyResp{}},
+		Description: "Test request (e.g. checking Paranoid Mode password)"},
+	{Command: "Hello", Request: Hello{}, Responses: append([]interface{}{HelloResp{}, ServerListResp{}, KillSwitchStatusResp{}}, vpnStateResponses...),
+		Events:      []interface{}{HelloResp{}, SplitTunnelStatus{}, WiFiCurrentNetworkResp{}},
+		Description: "Initial request. Must be the first request of the connection. Negotiates the protocol version (fails when the client protocol version is older than the oldest supported one)"},
+	{Command: "GetProtocolSchema", Request: GetProtocolSchema{}, Responses: []interface{}{ProtocolSchemaResp{}},
+		Description: "Get machine-readable description of the daemon protocol"},
+	{Command: "ParanoidModeSetPasswordReq", Request: ParanoidModeSetPasswordReq{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{HelloResp{}},
+		Description: "Set (or reset) Paranoid Mode password"},
+	{Command: "GetVPNState", Request: GetVPNState{}, Responses: vpnStateResponses,
+		Description: "Get current VPN state"},
+	{Command: "GetServers", Request: GetServers{}, Responses: []interface{}{ServerListResp{}},
+		Description: "Get servers list"},
+	{Command: "PingServers", Request: PingServers{}, Responses: []interface{}{PingServersResp{}},
+		Events:      []interface{}{PingServersResp{}},
+		Description: "Ping servers"},
+	{Command: "ServerScores", Request: ServerScores{}, Responses: []interface{}{ServerScoresResp{}},
+		Description: "Get servers sorted by score: latency, load, distance and connection history (the best server is the first one)"},
+	{Command: "ServerScoringSettings", Request: ServerScoringSettings{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{HelloResp{}},
+		Description: "Set weights of the factors for the 'Best' server selection"},
+	{Command: "HostsSelectionSettings", Request: HostsSelectionSettings{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{HelloResp{}},
+		Description: "Set pinned and blacklisted hosts of the servers (an active connection is re-established)"},
+	{Command: "APIRequest", Request: APIRequest{}, Responses: []interface{}{APIResponse{}},
+		Description: "Request to IVPN API (performed by the daemon)"},
+	{Command: "CheckAccessiblePorts", Request: CheckAccessiblePorts{}, Responses: []interface{}{CheckAccessiblePortsResponse{}},
+		Description: "Check which ports are accessible"},
+
+	{Command: "KillSwitchGetStatus", Request: KillSwitchGetStatus{}, Responses: []interface{}{KillSwitchStatusResp{}},
+		Description: "Get firewall status"},
+	{Command: "KillSwitchGetRules", Request: KillSwitchGetRules{}, Responses: []interface{}{KillSwitchRulesResp{}},
+		Description: "Get description of the firewall rules (applied or, in dry-run mode, the rules to be applied when firewall enabled)"},
+	{Command: "KillSwitchSetEnabled", Request: KillSwitchSetEnabled{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{KillSwitchStatusResp{}},
+		Description: "Enable/disable firewall"},
+	{Command: "KillSwitchSetAllowLANMulticast", Request: KillSwitchSetAllowLANMulticast{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{KillSwitchStatusResp{}},
+		Description: "Allow/block multicast in local network"},
+	{Command: "KillSwitchSetAllowLAN", Request: KillSwitchSetAllowLAN{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{KillSwitchStatusResp{}},
+		Description: "Allow/block local network"},
+	{Command: "KillSwitchSetUserExceptions", Request: KillSwitchSetUserExceptions{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{KillSwitchStatusResp{}},
+		Description: "Set firewall exceptions"},
+	{Command: "KillSwitchSetUserExceptionsList", Request: KillSwitchSetUserExceptionsList{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{KillSwitchStatusResp{}},
+		Description: "Set firewall exceptions (optionally restricted by protocol, ports and direction)"},
+	{Command: "KillSwitchSetIsPersistent", Request: KillSwitchSetIsPersistent{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{KillSwitchStatusResp{}},
+		Description: "Enable/disable always-on firewall"},
+	{Command: "KillSwitchSetAllowApiServers", Request: KillSwitchSetAllowApiServers{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{KillSwitchStatusResp{}},
+		Description: "Allow/block access to IVPN servers when firewall is enabled"},
+
+	{Command: "SetPreference", Request: SetPreference{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{SettingsResp{}},
+		Description: "Set daemon preference"},
+	{Command: "SetUserPreferences", Request: SetUserPreferences{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{SettingsResp{}},
+		Description: "Set user preferences"},
+
+	{Command: "ConnectionProfiles", Request: ConnectionProfiles{}, Responses: []interface{}{ConnectionProfilesResp{}},
+		Description: "Get saved connection profiles"},
+	{Command: "ConnectionProfileCreate", Request: ConnectionProfileCreate{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{ConnectionProfilesResp{}},
+		Description: "Save new connection profile"},
+	{Command: "ConnectionProfileUpdate", Request: ConnectionProfileUpdate{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{ConnectionProfilesResp{}, SettingsResp{}, ScheduleRulesResp{}},
+		Description: "Update (or rename) connection profile"},
+	{Command: "ConnectionProfileDelete", Request: ConnectionProfileDelete{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{ConnectionProfilesResp{}, SettingsResp{}, ScheduleRulesResp{}},
+		Description: "Remove connection profile"},
+
+	{Command: "ServerGroups", Request: ServerGroups{}, Responses: []interface{}{ServerGroupsResp{}},
+		Description: "Get favourite servers and server groups"},
+	{Command: "FavouriteServersSet", Request: FavouriteServersSet{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{ServerGroupsResp{}},
+		Description: "Save the list of favourite servers"},
+	{Command: "ServerGroupSet", Request: ServerGroupSet{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{ServerGroupsResp{}},
+		Description: "Save (create or replace) server group"},
+	{Command: "ServerGroupDelete", Request: ServerGroupDelete{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{ServerGroupsResp{}},
+		Description: "Remove server group"},
+
+	{Command: "ScheduleRules", Request: ScheduleRules{}, Responses: []interface{}{ScheduleRulesResp{}},
+		Description: "Get scheduler rules"},
+	{Command: "ScheduleRuleCreate", Request: ScheduleRuleCreate{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{ScheduleRulesResp{}},
+		Description: "Save new scheduler rule"},
+	{Command: "ScheduleRuleUpdate", Request: ScheduleRuleUpdate{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{ScheduleRulesResp{}},
+		Description: "Update (or rename) scheduler rule"},
+	{Command: "ScheduleRuleDelete", Request: ScheduleRuleDelete{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{ScheduleRulesResp{}},
+		Description: "Remove scheduler rule"},
+
+	{Command: "SplitTunnelGetStatus", Request: SplitTunnelGetStatus{}, Responses: []interface{}{SplitTunnelStatus{}},
+		Description: "Get Split Tunnel status"},
+	{Command: "SplitTunnelSetConfig", Request: SplitTunnelSetConfig{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{SplitTunnelStatus{}},
+		Description: "Set Split Tunnel configuration"},
+	{Command: "SplitTunnelAddApp", Request: SplitTunnelAddApp{}, Responses: []interface{}{EmptyResp{}, SplitTunnelAddAppCmdResp{}},
+		Events:      []interface{}{SplitTunnelStatus{}},
+		Description: "Add application to Split Tunnel configuration"},
+	{Command: "SplitTunnelRemoveApp", Request: SplitTunnelRemoveApp{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{SplitTunnelStatus{}},
+		Description: "Remove application from Split Tunnel configuration"},
+	{Command: "SplitTunnelAddedPidInfo", Request: SplitTunnelAddedPidInfo{}, Responses: []interface{}{EmptyResp{}},
+		Description: "Inform daemon about process started in Split Tunnel environment"},
+	{Command: "SplitTunnelAddAppRule", Request: SplitTunnelAddAppRule{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{SplitTunnelStatus{}},
+		Description: "Add persistent application rule (executable path or desktop file ID) to Split Tunnel configuration"},
+	{Command: "SplitTunnelRemoveAppRule", Request: SplitTunnelRemoveAppRule{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{SplitTunnelStatus{}},
+		Description: "Remove persistent application rule from Split Tunnel configuration"},
+	{Command: "SplitTunnelAddRoute", Request: SplitTunnelAddRoute{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{SplitTunnelStatus{}},
+		Description: "Add destination network to Split Tunnel configuration"},
+	{Command: "SplitTunnelRemoveRoute", Request: SplitTunnelRemoveRoute{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{SplitTunnelStatus{}},
+		Description: "Remove destination network from Split Tunnel configuration"},
+	{Command: "SplitTunnelAddDomain", Request: SplitTunnelAddDomain{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{SplitTunnelStatus{}},
+		Description: "Add destination domain to Split Tunnel configuration"},
+	{Command: "SplitTunnelRemoveDomain", Request: SplitTunnelRemoveDomain{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{SplitTunnelStatus{}},
+		Description: "Remove destination domain from Split Tunnel configuration"},
+	{Command: "GetAppIcon", Request: GetAppIcon{}, Responses: []interface{}{AppIconResp{}},
+		Description: "Get application icon"},
+	{Command: "GetInstalledApps", Request: GetInstalledApps{}, Responses: []interface{}{InstalledAppsResp{}},
+		Description: "Get list of installed applications"},
+
+	{Command: "GenerateDiagnostics", Request: RequestBase{}, Responses: []interface{}{DiagnosticsGeneratedResp{}},
+		Description: "Get diagnostic logs"},
+
+	{Command: "SetAlternateDns", Request: SetAlternateDns{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{SetAlternateDNSResp{}},
+		Description: "Set custom DNS (or AntiTracker) configuration"},
+	{Command: "GetDnsPredefinedConfigs", Request: GetDnsPredefinedConfigs{}, Responses: []interface{}{DnsPredefinedConfigsResp{}},
+		Description: "Get predefined DNS configurations"},
+
+	{Command: "PauseConnection", Request: PauseConnection{}, Responses: []interface{}{ConnectedResp{}},
+		Description: "Pause VPN connection"},
+	{Command: "ResumeConnection", Request: ResumeConnection{}, Responses: []interface{}{EmptyResp{}},
+		Description: "Resume paused VPN connection"},
+
+	{Command: "SessionNew", Request: SessionNew{}, Responses: []interface{}{SessionNewResp{}},
+		Events:      []interface{}{HelloResp{}},
+		Description: "Login (create new session)"},
+	{Command: "SessionDelete", Request: SessionDelete{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{HelloResp{}},
+		Description: "Logout (delete session)"},
+	{Command: "SessionStatus", Request: SessionStatus{}, Responses: []interface{}{SessionStatusResp{}},
+		Description: "Get session status"},
+
+	{Command: "WireGuardGenerateNewKeys", Request: WireGuardGenerateNewKeys{}, Responses: []interface{}{EmptyResp{}},
+		Description: "Regenerate WireGuard keys"},
+	{Command: "WireGuardSetKeysRotationInterval", Request: WireGuardSetKeysRotationInterval{}, Responses: []interface{}{EmptyResp{}},
+		Description: "Set WireGuard keys rotation interval"},
+	{Command: "WireGuardHealthCheckSettings", Request: WireGuardHealthCheckSettings{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{HelloResp{}},
+		Description: "Set parameters of the WireGuard connection monitoring (reconnect when no handshakes detected)"},
+	{Command: "WireGuardCustomConfigSet", Request: WireGuardCustomConfigSet{}, Responses: []interface{}{EmptyResp{}},
+		Description: "Validate and save the custom WireGuard configuration ('wg-quick' format); use it with 'Connect' ('WireGuardParameters.CustomConfig')"},
+	{Command: "OpenVpnCustomConfigSet", Request: OpenVpnCustomConfigSet{}, Responses: []interface{}{OpenVpnCustomConfigSetResp{}},
+		Description: "Validate, sanitise and save the custom OpenVPN configuration ('.ovpn' profile); use it with 'Connect' ('OpenVpnParameters.CustomConfig')"},
+	{Command: "ExportConnectionConfig", Request: ExportConnectionConfig{}, Responses: []interface{}{ExportConnectionConfigResp{}},
+		Description: "Render the current connection parameters into a standalone configuration ('wg-quick' for WireGuard or '.ovpn' for OpenVPN)"},
+	{Command: "TunnelStatistics", Request: TunnelStatistics{}, Responses: []interface{}{TunnelStatisticsResp{}},
+		Events:      []interface{}{TunnelStatisticsResp{}},
+		Description: "Get traffic statistics of the active VPN tunnel; with 'IntervalSec' > 0 - subscribe to periodic statistics events"},
+
+	{Command: "WiFiCurrentNetwork", Request: WiFiCurrentNetwork{}, Responses: []interface{}{},
+		Events:      []interface{}{WiFiCurrentNetworkResp{}},
+		Description: "Request info about current WiFi network (the info is sent to all clients)"},
+	{Command: "WiFiAvailableNetworks", Request: WiFiAvailableNetworks{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{WiFiAvailableNetworksResp{}},
+		Description: "Request available WiFi networks (the info is sent to all clients)"},
+	{Command: "WiFiSettings", Request: WiFiSettings{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{HelloResp{}},
+		Description: "Set WiFi control settings"},
+	{Command: "NetworkTrustSettings", Request: NetworkTrustSettings{}, Responses: []interface{}{EmptyResp{}},
+		Events:      []interface{}{HelloResp{}},
+		Description: "Set trust rules for wired (non-WiFi) networks"},
+	{Command: "NetworkCurrentInfo", Request: NetworkCurrentInfo{}, Responses: []interface{}{NetworkCurrentInfoResp{}},
+		Description: "Request info about the network which is in use by the default route (and the matching trust rule)"},
+
+	{Command: "ConnectSettingsGet", Request: ConnectSettingsGet{}, Responses: []interface{}{ConnectSettings{}},
+		Description: "Get default connection parameters"},
+	{Command: "ConnectSettings", Request: ConnectSettings{}, Responses: []interface{}{EmptyResp{}},
+		Description: "Set default connection parameters"},
+	{Command: "Connect", Request: Connect{}, Responses: []interface{}{EmptyResp{}},
+		Events:      vpnStateResponses,
+		Description: "Connect VPN"},
+	{Command: "Disconnect", Request: Disconnect{}, Responses: []interface{}{EmptyResp{}, DisconnectedResp{}},
+		Events:      vpnStateResponses,
+		Description: "Disconnect VPN"},
+}
+
+// protocolEvents - messages which the daemon can send to clients without request
+var protocolEvents = []eventInfo{
+	{Event: HelloResp{}, Description: "Account/session/settings changed"},
+	{Event: SettingsResp{}, Description: "Daemon settings changed"},
+	{Event: SessionStatusResp{}, Description: "Session status received from the server"},
+	{Event: KillSwitchStatusResp{}, Description: "Firewall status changed"},
+	{Event: ConnectedResp{}, Description: "VPN connected"},
+	{Event: DisconnectedResp{}, Description: "VPN disconnected"},
+	{Event: VpnStateResp{}, Description: "VPN state changed"},
+	{Event: SetAlternateDNSResp{}, Description: "DNS configuration changed"},
+	{Event: ServerListResp{}, Description: "Servers list updated"},
+	{Event: PingServersResp{}, Description: "Servers ping results"},
+	{Event: SplitTunnelStatus{}, Description: "Split Tunnel status changed"},
+	{Event: ConnectionProfilesResp{}, Description: "Connection profiles changed"},
+	{Event: ServerGroupsResp{}, Description: "Favourite servers or server groups changed"},
+	{Event: ScheduleRulesResp{}, Description: "Scheduler rules changed"},
+	{Event: WiFiCurrentNetworkResp{}, Description: "Current WiFi network changed"},
+	{Event: WiFiAvailableNetworksResp{}, Description: "Available WiFi networks"},
+	{Event: ErrorRespDelayed{}, Description: "Error which happened when no clients were connected"},
+	{Event: ServiceExitingResp{}, Description: "Daemon is stopping"},
+}
@@ -0,0 +1,59 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types
+
+import (
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+)
+
+// ServerGroups (request) requests favourite servers and server groups
+type ServerGroups struct {
+	RequestBase
+}
+
+// ServerGroupsResp (response) contains favourite servers and server groups.
+// Also, it is sent to all clients when favourites or groups are changed.
+type ServerGroupsResp struct {
+	CommandBase
+	// favourite servers (gateway IDs, e.g. "nl-ams")
+	Favourites []string
+	Groups     []preferences.ServerGroup
+}
+
+// FavouriteServersSet (request) saves the list of favourite servers
+type FavouriteServersSet struct {
+	RequestBase
+	Gateways []string
+}
+
+// ServerGroupSet (request) saves the server group (the existing group with the same name will be replaced)
+type ServerGroupSet struct {
+	RequestBase
+	Group preferences.ServerGroup
+}
+
+// ServerGroupDelete (request) removes the server group
+type ServerGroupDelete struct {
+	RequestBase
+	GroupName string
+}
@@ -24,6 +24,7 @@ package types
 
 import (
 	"github.com/ivpn/desktop-app/daemon/oshelpers"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
 	"github.com/ivpn/desktop-app/daemon/splittun"
 )
 
@@ -92,11 +93,27 @@ type SplitTunnelStatus struct {
 	// (true - if commands GetAppIcon/AppIconResp  applicable for this platform)
 	IsCanGetAppIconForBinary bool
 	// Information about applications added to ST configuration
-	// (applicable for Windows)
+	// Windows: full paths to the app binaries
+	// Linux: persistent rules (absolute path to the executable or desktop file ID)
 	SplitTunnelApps []string
 	// Information about active applications running in Split-Tunnel environment
 	// (applicable for Linux)
 	RunningApps []splittun.RunningApp
+
+	// Destination networks (CIDR) and domains added to ST configuration
+	// (applicable for Linux; cgroup v2 implementation only)
+	SplitTunnelRoutes  []string
+	SplitTunnelDomains []string
+	// Addresses of the 'SplitTunnelDomains'
+	SplitTunnelDomainsResolved []service_types.SplitTunnelDomainStatus
+	// Not empty when destinations are not supported by the current platform/implementation
+	DestinationsError string
+
+	// Traffic counters of applications running in Split-Tunnel environment
+	// (applicable for Linux; cgroup v2 implementation only)
+	AppsTraffic []splittun.AppTraffic
+	// Not empty when traffic counters are not available
+	AppsTrafficError string
 }
 
 // SplitTunnelAddApp (request) add application to SplitTunneling
@@ -155,3 +172,47 @@ type SplitTunnelRemoveApp struct {
 	// (applicable for Windows) full path to the app binary to be excluded from ST
 	Exec string
 }
+
+// SplitTunnelAddAppRule (request) adds persistent application rule to SplitTunneling.
+// Linux: all processes executing the binary defined by the rule are added to ST environment automatically
+// (the rule is kept in 'SplitTunnelStatus.SplitTunnelApps').
+// Windows: identical to SplitTunnelAddApp
+type SplitTunnelAddAppRule struct {
+	RequestBase
+	// absolute path to the executable or desktop file ID (e.g. "firefox.desktop"; applicable for Linux)
+	Rule string
+}
+
+// SplitTunnelRemoveAppRule (request) removes persistent application rule from SplitTunneling
+type SplitTunnelRemoveAppRule struct {
+	RequestBase
+	Rule string
+}
+
+// SplitTunnelAddRoute (request) adds destination network to SplitTunneling
+// (traffic to this network does not use VPN; for Inverse Split Tunnel - only this traffic uses VPN)
+type SplitTunnelAddRoute struct {
+	RequestBase
+	// IP address or network in CIDR notation (e.g. "192.168.1.0/24")
+	Route string
+}
+
+// SplitTunnelRemoveRoute (request) removes destination network from SplitTunneling
+type SplitTunnelRemoveRoute struct {
+	RequestBase
+	Route string
+}
+
+// SplitTunnelAddDomain (request) adds destination domain to SplitTunneling.
+// The addresses of the domain (and its subdomains) are taken from the DNS answers received by applications
+// and processed the same way as routes.
+type SplitTunnelAddDomain struct {
+	RequestBase
+	Domain string
+}
+
+// SplitTunnelRemoveDomain (request) removes destination domain from SplitTunneling
+type SplitTunnelRemoveDomain struct {
+	RequestBase
+	Domain string
+}
@@ -0,0 +1,133 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package protocol
+
+import (
+	"fmt"
+	"net"
+)
+
+// UnixSocketConfig - configuration of the local AF_UNIX socket listener.
+// Clients connected over the unix socket are authenticated by the peer credentials (SO_PEERCRED)
+// instead of the secret from the service port file.
+// The 'root' user is always allowed.
+// When the unix socket is enabled, it replaces the TCP listener (port+secret) unless 'KeepTCP' is set.
+type UnixSocketConfig struct {
+	// Path to the socket file
+	Path string
+	// Users (uid) allowed to connect
+	AllowedUids []uint32
+	// Groups (gid) allowed to connect (primary or supplementary group of the peer process)
+	AllowedGids []uint32
+	// Keep listening on TCP port (port+secret) for clients which do not support the unix socket (e.g. UI application)
+	KeepTCP bool
+}
+
+func (c UnixSocketConfig) String() string {
+	return fmt.Sprintf("%s (uids:%v gids:%v keepTCP:%v)", c.Path, c.AllowedUids, c.AllowedGids, c.KeepTCP)
+}
+
+// isPeerAllowed returns 'true' when peer with a given uid and groups is allowed to connect
+func (c UnixSocketConfig) isPeerAllowed(uid uint32, gids []uint32) bool {
+	if uid == 0 {
+		return true
+	}
+	for _, u := range c.AllowedUids {
+		if u == uid {
+			return true
+		}
+	}
+	for _, allowedGid := range c.AllowedGids {
+		for _, g := range gids {
+			if g == allowedGid {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetUnixSocketConfig - enables the unix domain socket listener (must be called before Start())
+// Unix domain socket is supported only on Linux. Use 'nil' to disable it.
+func (p *Protocol) SetUnixSocketConfig(cfg *UnixSocketConfig) {
+	p._unixSocketCfg = cfg
+}
+
+// startUnixSocketListener starts listening on the unix domain socket
+// Returns nil listener when the unix socket is not enabled.
+func (p *Protocol) startUnixSocketListener() (*net.UnixListener, error) {
+	cfg := p._unixSocketCfg
+	if cfg == nil || len(cfg.Path) == 0 {
+		return nil, nil
+	}
+
+	listener, err := listenUnixSocket(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start unix socket listener: %w", err)
+	}
+	p._unixListener = listener
+
+	log.Info(fmt.Sprintf("Listening on unix socket: %s", cfg))
+	return listener, nil
+}
+
+// acceptUnixSocketConnections accepts connections on the unix domain socket (blocking; returns when the listener closed)
+func (p *Protocol) acceptUnixSocketConnections(listener *net.UnixListener) error {
+	cfg := p._unixSocketCfg
+
+	defer func() {
+		listener.Close()
+		log.Info("Unix socket listener closed")
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !p._isRunning {
+				return nil // it is expected to get error here (we are requested protocol to stop): "use of closed network connection"
+			}
+			log.Error("Server: failed to accept incoming unix socket connection:", err)
+			return fmt.Errorf("(server) failed to accept incoming unix socket connection: %w", err)
+		}
+
+		uid, gids, err := getPeerCredentials(conn)
+		if err != nil {
+			log.Warning(fmt.Errorf("refusing unix socket connection: unable to get peer credentials: %w", err))
+			conn.Close()
+			continue
+		}
+		if !cfg.isPeerAllowed(uid, gids) {
+			log.Warning(fmt.Sprintf("refusing unix socket connection: peer not allowed (uid:%d gids:%v)", uid, gids))
+			conn.Close()
+			continue
+		}
+
+		const isPeerCredentialsVerified = true
+		go p.processClient(conn, isPeerCredentialsVerified)
+	}
+}
+
+func isUnixSocketConnection(c net.Conn) bool {
+	_, ok := c.(*net.UnixConn)
+	return ok
+}
@@ -0,0 +1,121 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package protocol
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func listenUnixSocket(socketPath string) (*net.UnixListener, error) {
+	// remove socket file which could stay from previous daemon run
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	listener.SetUnlinkOnClose(true)
+
+	// everyone can open the socket, the access is controlled by the peer credentials
+	if err := os.Chmod(socketPath, 0666); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// getPeerCredentials returns uid and groups (primary and supplementary) of the connected peer process
+func getPeerCredentials(conn net.Conn) (uid uint32, gids []uint32, err error) {
+	uconn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, nil, fmt.Errorf("not a unix socket connection")
+	}
+
+	rawConn, err := uconn.SyscallConn()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var cred *unix.Ucred
+	var credErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil {
+		return 0, nil, err
+	}
+	if credErr != nil {
+		return 0, nil, credErr
+	}
+
+	gids = append(gids, cred.Gid)
+	if groups, err := getProcessSupplementaryGroups(int(cred.Pid)); err != nil {
+		log.Warning(fmt.Sprintf("unable to get supplementary groups of process %d: %s", cred.Pid, err))
+	} else {
+		gids = append(gids, groups...)
+	}
+
+	return cred.Uid, gids, nil
+}
+
+// getProcessSupplementaryGroups reads supplementary groups of the process ('Groups:' line from '/proc/<pid>/status')
+func getProcessSupplementaryGroups(pid int) ([]uint32, error) {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Groups:") {
+			continue
+		}
+		var ret []uint32
+		for _, f := range strings.Fields(strings.TrimPrefix(line, "Groups:")) {
+			g, err := strconv.ParseUint(f, 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, uint32(g))
+		}
+		return ret, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, syscall.ENOENT
+}
@@ -30,6 +30,7 @@ import (
 
 	"github.com/ivpn/desktop-app/daemon/logger"
 	"github.com/ivpn/desktop-app/daemon/service/dns/dnscryptproxy"
+	"github.com/ivpn/desktop-app/daemon/service/dns/dnsforwarder"
 	"github.com/ivpn/desktop-app/daemon/service/platform"
 )
 
@@ -251,6 +252,21 @@ func DeleteManual(defaultDns net.IP, localInterfaceIP net.IP) error {
 	return wrapErrorIfFailed(notifyFirewall(DnsSettingsCreate(defaultDns)))
 }
 
+// SetAnswersHandler - enables tracking of the DNS answers (or disables it, when 'handler' is nil).
+// When enabled, the DNS requests of the system are passed through the local DNS forwarder
+// and the handler is called for each DNS answer (before the answer is passed to the application).
+// The DNS answers are tracked only while the DNS configuration is changed by the daemon (VPN connected).
+// Currently, it is supported only on Linux.
+func SetAnswersHandler(handler dnsforwarder.AnswerHandler) error {
+	return wrapErrorIfFailed(implSetAnswersHandler(handler))
+}
+
+// AnswersTrackingError returns the error when the DNS answers can not be tracked
+// (e.g. the local DNS forwarder failed to start)
+func AnswersTrackingError() error {
+	return implAnswersTrackingError()
+}
+
 // GetLastManualDNS - returns information about current manual DNS
 func GetLastManualDNS() DnsSettings {
 	// TODO: get real DNS configuration of the OS
@@ -27,6 +27,7 @@ import (
 	"net"
 
 	"github.com/ivpn/desktop-app/daemon/service/dns/dnscryptproxy"
+	"github.com/ivpn/desktop-app/daemon/service/dns/dnsforwarder"
 	"github.com/ivpn/desktop-app/daemon/service/platform"
 	"github.com/ivpn/desktop-app/daemon/shell"
 )
@@ -124,3 +125,14 @@ func implUpdateDnsIfWrongSettings() error {
 	}
 	return nil
 }
+
+func implSetAnswersHandler(handler dnsforwarder.AnswerHandler) error {
+	if handler != nil {
+		return fmt.Errorf("tracking of DNS answers is not supported on this platform")
+	}
+	return nil
+}
+
+func implAnswersTrackingError() error {
+	return nil
+}
@@ -28,8 +28,10 @@ package dns
 import (
 	"fmt"
 	"net"
+	"sync"
 
 	"github.com/ivpn/desktop-app/daemon/service/dns/dnscryptproxy"
+	"github.com/ivpn/desktop-app/daemon/service/dns/dnsforwarder"
 	"github.com/ivpn/desktop-app/daemon/service/platform"
 )
 
@@ -53,6 +55,20 @@ var (
 var (
 	isPaused  bool = false
 	manualDNS DnsSettings
+	// DNS server in use by the OS (or by the local DNS forwarder) for the current manual DNS configuration
+	// (e.g. "127.0.0.1" when dnscrypt-proxy is in use)
+	upstreamDNS          DnsSettings
+	lastLocalInterfaceIP net.IP
+)
+
+// The local DNS forwarder (in use when the DNS answers are tracked; see SetAnswersHandler())
+const dnsForwarderIP = "127.0.2.53"
+
+var (
+	forwarderMutex   sync.Mutex
+	forwarderHandler dnsforwarder.AnswerHandler
+	forwarder        *dnsforwarder.Forwarder
+	forwarderErr     error
 )
 
 func init() {
@@ -119,6 +135,7 @@ func implGetPredefinedDnsConfigurations() ([]DnsSettings, error) {
 
 func implPause(localInterfaceIP net.IP) error {
 	dnscryptproxy.Stop()
+	forwarderStop()
 	isPaused = true
 	return f_implPause(localInterfaceIP)
 }
@@ -128,12 +145,12 @@ func implResume(defaultDNS DnsSettings, localInterfaceIP net.IP) error {
 
 	if !manualDNS.IsEmpty() {
 		// set manual DNS (if defined)
-		_, err := f_implSetManual(manualDNS, localInterfaceIP)
+		_, err := f_implSetManual(forwarderStart(manualDNS), localInterfaceIP)
 		return err
 	}
 
 	if !defaultDNS.IsEmpty() {
-		_, err := f_implSetManual(defaultDNS, localInterfaceIP)
+		_, err := f_implSetManual(forwarderStart(defaultDNS), localInterfaceIP)
 		return err
 	}
 
@@ -145,13 +162,16 @@ func implSetManual(dnsCfg DnsSettings, localInterfaceIP net.IP) (dnsInfoForFirew
 	defer func() {
 		if retErr != nil {
 			dnscryptproxy.Stop()
+			forwarderStop()
 		}
 	}()
 
 	// keep info about current manual DNS configuration (can be used for pause/resume/restore)
 	manualDNS = dnsCfg
+	lastLocalInterfaceIP = localInterfaceIP
 
 	dnscryptproxy.Stop()
+	forwarderStop()
 
 	if isPaused {
 		// in case of PAUSED state -> just save manualDNS config
@@ -167,15 +187,23 @@ func implSetManual(dnsCfg DnsSettings, localInterfaceIP net.IP) (dnsInfoForFirew
 		// the local DNS must be configured to the dnscrypt-proxy (localhost)
 		dnsCfg = DnsSettings{DnsHost: "127.0.0.1"}
 	}
+	upstreamDNS = dnsCfg
 
-	return f_implSetManual(dnsCfg, localInterfaceIP)
+	// when the DNS answers are tracked - the OS uses the local DNS forwarder
+	// (the firewall must still allow the upstream DNS server: the forwarder sends requests to it)
+	if _, err := f_implSetManual(forwarderStart(dnsCfg), localInterfaceIP); err != nil {
+		return DnsSettings{}, err
+	}
+	return dnsCfg, nil
 }
 
 // DeleteManual - reset manual DNS configuration to default
 // 'localInterfaceIP' (obligatory only for Windows implementation) - local IP of VPN interface
 func implDeleteManual(localInterfaceIP net.IP) error {
 	manualDNS = DnsSettings{}
+	upstreamDNS = DnsSettings{}
 	dnscryptproxy.Stop()
+	forwarderStop()
 
 	if isPaused {
 		// in case of PAUSED state -> just save manualDNS config
@@ -192,3 +220,68 @@ func implUpdateDnsIfWrongSettings() error {
 	// We are using platform-specific implementation of DNS change monitor for Linux
 	return nil
 }
+
+func implSetAnswersHandler(handler dnsforwarder.AnswerHandler) error {
+	forwarderMutex.Lock()
+	isChanged := (handler == nil) != (forwarderHandler == nil)
+	forwarderHandler = handler
+	forwarderMutex.Unlock()
+
+	if !isChanged || isPaused || upstreamDNS.IsEmpty() {
+		return nil // will be applied on the next DNS change
+	}
+	// re-apply current DNS configuration: to start (or stop) the local DNS forwarder
+	_, err := f_implSetManual(forwarderStart(upstreamDNS), lastLocalInterfaceIP)
+	return err
+}
+
+func implAnswersTrackingError() error {
+	forwarderMutex.Lock()
+	defer forwarderMutex.Unlock()
+	return forwarderErr
+}
+
+// forwarderStart starts the local DNS forwarder to the 'dnsCfg' server (only if the DNS answers are tracked).
+// Returns the DNS configuration to be applied to the OS: the address of the forwarder or 'dnsCfg' (if forwarder not in use).
+func forwarderStart(dnsCfg DnsSettings) DnsSettings {
+	forwarderMutex.Lock()
+	defer forwarderMutex.Unlock()
+
+	forwarderStopLocked()
+	if forwarderHandler == nil || dnsCfg.IsEmpty() {
+		return dnsCfg
+	}
+
+	onAnswer := func(name string, addrs []dnsforwarder.Address) {
+		forwarderMutex.Lock()
+		h := forwarderHandler
+		forwarderMutex.Unlock()
+		if h != nil {
+			h(name, addrs)
+		}
+	}
+
+	f, err := dnsforwarder.Start(net.JoinHostPort(dnsForwarderIP, "53"), net.JoinHostPort(dnsCfg.DnsHost, "53"), onAnswer)
+	if err != nil {
+		// do not break the DNS functionality: use the DNS server directly
+		log.Error("DNS answers are not tracked: ", err)
+		forwarderErr = err
+		return dnsCfg
+	}
+	forwarder = f
+	return DnsSettings{DnsHost: dnsForwarderIP}
+}
+
+func forwarderStop() {
+	forwarderMutex.Lock()
+	defer forwarderMutex.Unlock()
+	forwarderStopLocked()
+}
+
+func forwarderStopLocked() {
+	forwarderErr = nil
+	if forwarder != nil {
+		forwarder.Stop()
+		forwarder = nil
+	}
+}
@@ -34,6 +34,7 @@ import (
 
 	"github.com/ivpn/desktop-app/daemon/netinfo"
 	"github.com/ivpn/desktop-app/daemon/service/dns/dnscryptproxy"
+	"github.com/ivpn/desktop-app/daemon/service/dns/dnsforwarder"
 	"github.com/ivpn/desktop-app/daemon/service/platform"
 )
 
@@ -374,3 +375,14 @@ func getInterfacesIPsWhichContainsIP(addr net.IP, localAddrToSkip net.IP) (ret [
 
 	return ret, nil
 }
+
+func implSetAnswersHandler(handler dnsforwarder.AnswerHandler) error {
+	if handler != nil {
+		return fmt.Errorf("tracking of DNS answers is not supported on this platform")
+	}
+	return nil
+}
+
+func implAnswersTrackingError() error {
+	return nil
+}
@@ -0,0 +1,289 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// Package dnsforwarder implements a simple local DNS forwarder.
+// It passes DNS requests to the upstream DNS server and reports the addresses from the DNS answers
+// (it is in use to track the addresses which applications receive for the specific domains).
+package dnsforwarder
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/logger"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var log *logger.Logger
+
+func init() {
+	log = logger.NewLogger("dnsfwd")
+}
+
+const (
+	upstreamTimeout  = 5 * time.Second
+	tcpIdleTimeout   = 10 * time.Second
+	maxUdpMsgSize    = 65535
+	maxParallelQuery = 256
+)
+
+// Address - IP address from the DNS answer and TTL of the DNS record
+type Address struct {
+	IP  net.IP
+	TTL time.Duration
+}
+
+// AnswerHandler is called for each successful DNS answer which contains addresses (A/AAAA records).
+// 'name' - the requested domain name (lowercase, without trailing dot).
+// The answer is passed to the client only after the handler returns.
+type AnswerHandler func(name string, addrs []Address)
+
+// Forwarder - local DNS forwarder (UDP and TCP)
+type Forwarder struct {
+	upstream string
+	onAnswer AnswerHandler
+
+	udpConn     *net.UDPConn
+	tcpListener *net.TCPListener
+	querySem    chan struct{}
+	wg          sync.WaitGroup
+}
+
+// Start starts the DNS forwarder on 'listenAddr' (e.g. "127.0.2.53:53").
+// The requests are forwarded to 'upstreamAddr' (e.g. "10.0.254.1:53").
+func Start(listenAddr, upstreamAddr string, onAnswer AnswerHandler) (*Forwarder, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", listenAddr)
+	if err != nil {
+		return nil, err
+	}
+	udpConn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start DNS forwarder: %w", err)
+	}
+	// use the same port for TCP (important when the port is chosen by the system: "127.0.0.1:0")
+	tcpListener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: udpAddr.IP, Port: udpConn.LocalAddr().(*net.UDPAddr).Port})
+	if err != nil {
+		udpConn.Close()
+		return nil, fmt.Errorf("failed to start DNS forwarder: %w", err)
+	}
+
+	f := &Forwarder{
+		upstream:    upstreamAddr,
+		onAnswer:    onAnswer,
+		udpConn:     udpConn,
+		tcpListener: tcpListener,
+		querySem:    make(chan struct{}, maxParallelQuery),
+	}
+
+	f.wg.Add(2)
+	go f.serveUdp()
+	go f.serveTcp()
+
+	log.Info(fmt.Sprintf("DNS forwarder started: %s -> %s", udpConn.LocalAddr(), upstreamAddr))
+	return f, nil
+}
+
+// Addr returns the address the forwarder is listening on
+func (f *Forwarder) Addr() string {
+	return f.udpConn.LocalAddr().String()
+}
+
+// Stop stops the forwarder.
+// Note: it does not wait until the requests which are in progress are finished.
+func (f *Forwarder) Stop() {
+	f.udpConn.Close()
+	f.tcpListener.Close()
+	f.wg.Wait()
+	log.Info("DNS forwarder stopped")
+}
+
+func (f *Forwarder) serveUdp() {
+	defer f.wg.Done()
+
+	buf := make([]byte, maxUdpMsgSize)
+	for {
+		n, clientAddr, err := f.udpConn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Error("DNS forwarder (UDP): ", err)
+			continue
+		}
+
+		select {
+		case f.querySem <- struct{}{}:
+		default:
+			continue // too many requests in progress: drop the request (the client will retry)
+		}
+
+		req := append([]byte{}, buf[:n]...)
+		go func() {
+			defer func() { <-f.querySem }()
+
+			resp, err := f.exchangeUdp(req)
+			if err != nil {
+				log.Debug("DNS forwarder (UDP): ", err)
+				return
+			}
+			f.processAnswer(resp)
+			f.udpConn.WriteToUDP(resp, clientAddr)
+		}()
+	}
+}
+
+func (f *Forwarder) serveTcp() {
+	defer f.wg.Done()
+
+	for {
+		conn, err := f.tcpListener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Error("DNS forwarder (TCP): ", err)
+			continue
+		}
+		go f.serveTcpConn(conn)
+	}
+}
+
+func (f *Forwarder) serveTcpConn(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		conn.SetDeadline(time.Now().Add(tcpIdleTimeout))
+		req, err := readTcpMsg(conn)
+		if err != nil {
+			return
+		}
+		resp, err := f.exchangeTcp(req)
+		if err != nil {
+			log.Debug("DNS forwarder (TCP): ", err)
+			return
+		}
+		f.processAnswer(resp)
+
+		conn.SetDeadline(time.Now().Add(tcpIdleTimeout))
+		if err := writeTcpMsg(conn, resp); err != nil {
+			return
+		}
+	}
+}
+
+func (f *Forwarder) exchangeUdp(req []byte) ([]byte, error) {
+	conn, err := net.DialTimeout("udp", f.upstream, upstreamTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(upstreamTimeout))
+
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, maxUdpMsgSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+func (f *Forwarder) exchangeTcp(req []byte) ([]byte, error) {
+	conn, err := net.DialTimeout("tcp", f.upstream, upstreamTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(upstreamTimeout))
+
+	if err := writeTcpMsg(conn, req); err != nil {
+		return nil, err
+	}
+	return readTcpMsg(conn)
+}
+
+func (f *Forwarder) processAnswer(resp []byte) {
+	if f.onAnswer == nil {
+		return
+	}
+	name, addrs, ok := ParseAnswer(resp)
+	if !ok || len(addrs) == 0 {
+		return
+	}
+	f.onAnswer(name, addrs)
+}
+
+// ParseAnswer returns the requested domain name (lowercase, without trailing dot)
+// and the addresses from the DNS answer (A/AAAA records, including the records of CNAME targets).
+// Returns ok=false when the message is not a successful DNS answer.
+func ParseAnswer(msg []byte) (name string, addrs []Address, ok bool) {
+	var m dnsmessage.Message
+	if err := m.Unpack(msg); err != nil {
+		return "", nil, false
+	}
+	if !m.Header.Response || m.Header.RCode != dnsmessage.RCodeSuccess || len(m.Questions) == 0 {
+		return "", nil, false
+	}
+
+	name = strings.ToLower(strings.TrimSuffix(m.Questions[0].Name.String(), "."))
+	for _, a := range m.Answers {
+		ttl := time.Duration(a.Header.TTL) * time.Second
+		switch r := a.Body.(type) {
+		case *dnsmessage.AResource:
+			addrs = append(addrs, Address{IP: net.IP(append([]byte{}, r.A[:]...)), TTL: ttl})
+		case *dnsmessage.AAAAResource:
+			addrs = append(addrs, Address{IP: net.IP(append([]byte{}, r.AAAA[:]...)), TTL: ttl})
+		}
+	}
+	return name, addrs, true
+}
+
+func readTcpMsg(r io.Reader) ([]byte, error) {
+	var l [2]byte
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(l[:]))
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func writeTcpMsg(w io.Writer, msg []byte) error {
+	if len(msg) > 0xFFFF {
+		return fmt.Errorf("DNS message is too big")
+	}
+	buf := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	copy(buf[2:], msg)
+	_, err := w.Write(buf)
+	return err
+}
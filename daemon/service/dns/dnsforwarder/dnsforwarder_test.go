@@ -0,0 +1,187 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package dnsforwarder_test
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/service/dns/dnsforwarder"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func newQuery(t *testing.T, id uint16, name string) []byte {
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+	}
+	b, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// newAnswer creates the answer for the query: "<name> CNAME cdn.example.net" + "cdn.example.net A 192.0.2.10"
+func newAnswer(t *testing.T, query []byte) []byte {
+	var q dnsmessage.Message
+	if err := q.Unpack(query); err != nil {
+		t.Fatal(err)
+	}
+	cdn := dnsmessage.MustNewName("cdn.example.net.")
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: q.Header.ID, Response: true, RecursionAvailable: true},
+		Questions: q.Questions,
+		Answers: []dnsmessage.Resource{
+			{Header: dnsmessage.ResourceHeader{Name: q.Questions[0].Name, Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET, TTL: 300}, Body: &dnsmessage.CNAMEResource{CNAME: cdn}},
+			{Header: dnsmessage.ResourceHeader{Name: cdn, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60}, Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 10}}},
+		},
+	}
+	b, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParseAnswer(t *testing.T) {
+	query := newQuery(t, 1, "WWW.Example.com.")
+	if _, _, ok := dnsforwarder.ParseAnswer(query); ok {
+		t.Error("the request must not be parsed as an answer")
+	}
+
+	name, addrs, ok := dnsforwarder.ParseAnswer(newAnswer(t, query))
+	if !ok {
+		t.Fatal("failed to parse the answer")
+	}
+	if name != "www.example.com" {
+		t.Errorf("unexpected name: %s", name)
+	}
+	if len(addrs) != 1 || !addrs[0].IP.Equal(net.IPv4(192, 0, 2, 10)) || addrs[0].TTL != time.Minute {
+		t.Errorf("unexpected addresses: %v", addrs)
+	}
+}
+
+// startUpstream starts the test DNS server (UDP and TCP on the same port)
+func startUpstream(t *testing.T) string {
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcpListener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: udpConn.LocalAddr().(*net.UDPAddr).Port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { udpConn.Close(); tcpListener.Close() })
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := udpConn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			udpConn.WriteToUDP(newAnswer(t, buf[:n]), addr)
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcpListener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var l [2]byte
+				if _, err := conn.Read(l[:]); err != nil {
+					return
+				}
+				req := make([]byte, int(l[0])<<8|int(l[1]))
+				if _, err := conn.Read(req); err != nil {
+					return
+				}
+				resp := newAnswer(t, req)
+				conn.Write(append([]byte{byte(len(resp) >> 8), byte(len(resp))}, resp...))
+			}()
+		}
+	}()
+	return udpConn.LocalAddr().String()
+}
+
+func TestForwarder(t *testing.T) {
+	upstream := startUpstream(t)
+
+	var mutex sync.Mutex
+	answers := make(map[string][]dnsforwarder.Address)
+	f, err := dnsforwarder.Start("127.0.0.1:0", upstream, func(name string, addrs []dnsforwarder.Address) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		answers[name] = addrs
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Stop()
+
+	for _, network := range []string{"udp", "tcp"} {
+		name := network + ".example.com."
+		conn, err := net.DialTimeout(network, f.Addr(), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		query := newQuery(t, 1234, name)
+		if network == "tcp" {
+			query = append([]byte{byte(len(query) >> 8), byte(len(query))}, query...)
+		}
+		if _, err := conn.Write(query); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 65535)
+		n, err := conn.Read(buf)
+		conn.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", network, err)
+		}
+		resp := buf[:n]
+		if network == "tcp" {
+			resp = resp[2:]
+		}
+
+		// the answer is passed to the client unchanged
+		var m dnsmessage.Message
+		if err := m.Unpack(resp); err != nil || m.Header.ID != 1234 || len(m.Answers) != 2 {
+			t.Errorf("%s: unexpected response (err: %v)", network, err)
+		}
+		// the handler was called before the answer was passed to the client
+		mutex.Lock()
+		addrs := answers[network+".example.com"]
+		mutex.Unlock()
+		if len(addrs) != 1 || !addrs[0].IP.Equal(net.IPv4(192, 0, 2, 10)) {
+			t.Errorf("%s: unexpected addresses: %v", network, addrs)
+		}
+	}
+}
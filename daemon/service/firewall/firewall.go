@@ -25,12 +25,11 @@ package firewall
 import (
 	"fmt"
 	"net"
-	"strings"
 	"sync"
-	"unicode"
 
 	"github.com/ivpn/desktop-app/daemon/logger"
 	"github.com/ivpn/desktop-app/daemon/service/dns"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
 )
 
 var log *logger.Logger
@@ -39,6 +38,14 @@ func init() {
 	log = logger.NewLogger("frwl")
 }
 
+type FuncGetUserSettings func() FirewallExtraSettings
+
+type FirewallExtraSettings struct {
+	// If true - use the native nftables implementation
+	// instead of the firewall script (iptables)
+	Linux_IsNftablesBackend bool
+}
+
 var (
 	connectedClientInterfaceIP   net.IP
 	connectedClientInterfaceIPv6 net.IP
@@ -50,19 +57,34 @@ var (
 	isClientPaused               bool
 	dnsConfig                    *dns.DnsSettings
 
-	// List of IP masks that are allowed for any communication
-	userExceptions []net.IPNet
+	// User-defined exceptions (in canonical form)
+	userExceptions []service_types.FirewallException
+	// Addresses resolved from the user-defined hostname exceptions (see SetHostnameExceptions())
+	hostnameExceptions []service_types.FirewallException
 
 	stateAllowLan          bool
 	stateAllowLanMulticast bool
+
+	funcGetUserSettings FuncGetUserSettings
 )
 
 // Initialize is doing initialization stuff
 // Must be called on application start
-func Initialize() error {
+func Initialize(getUserSettingsFunc FuncGetUserSettings) error {
+	funcGetUserSettings = getUserSettingsFunc
+	if funcGetUserSettings == nil {
+		logger.Debug("WARNING! getUserSettingsFunc() function not defined!")
+	}
 	return implInitialize()
 }
 
+func GetExtraSettings() FirewallExtraSettings {
+	if funcGetUserSettings != nil {
+		return funcGetUserSettings()
+	}
+	return FirewallExtraSettings{}
+}
+
 // SetEnabled - change firewall state
 func SetEnabled(enable bool) error {
 	mutex.Lock()
@@ -303,46 +325,83 @@ func OnChangeDNS(newDnsCfg *dns.DnsSettings) error {
 	return err
 }
 
-// SetUserExceptions set ip/mask to be excluded from FW block
+// SetUserExceptions set the user-defined exceptions to be excluded from FW block
 // Parameters:
-//   - exceptions - comma separated list of IP addresses in format: x.x.x.x[/xx]
-func SetUserExceptions(exceptions string, ignoreParseErrors bool) error {
-	userExceptions = []net.IPNet{}
+//   - exceptions - list of exceptions (IP addresses or subnets; optionally restricted by protocol, ports and direction)
+//   - ignoreErrors - skip invalid (or not supported on current platform) exceptions instead of returning an error
+//
+// Returns the list of applied exceptions (in canonical form)
+func SetUserExceptions(exceptions []service_types.FirewallException, ignoreErrors bool) ([]service_types.FirewallException, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
 
-	splitFunc := func(c rune) bool {
-		return !unicode.IsLetter(c) && !unicode.IsNumber(c) && c != rune('/') && c != rune('.') && c != rune(':')
-	}
-	exceptionsArr := strings.FieldsFunc(exceptions, splitFunc)
-	for _, exp := range exceptionsArr {
-		exp = strings.TrimSpace(exp)
-
-		var err error
-		var n *net.IPNet
-
-		if strings.Contains(exp, "/") {
-			_, n, err = net.ParseCIDR(exp)
-		} else {
-			addr := net.ParseIP(exp)
-			if addr == nil {
-				err = fmt.Errorf("%s not a IP address", exp)
-			} else {
-				if addr.To4() == nil {
-					// IPv6 single address
-					_, n, err = net.ParseCIDR(addr.String() + "/128")
-				} else {
-					// IPv4 single address
-					_, n, err = net.ParseCIDR(addr.String() + "/32")
-				}
-			}
+	newExceptions := make([]service_types.FirewallException, 0, len(exceptions))
+	for _, exp := range exceptions {
+		e, err := exp.Normalize()
+		if err == nil {
+			err = implCheckUserException(e)
 		}
 		if err != nil {
-			if !ignoreParseErrors {
-				return fmt.Errorf("unable to parse firewall exceptions ('%s'): %w", exceptions, err)
+			if !ignoreErrors {
+				return nil, fmt.Errorf("bad firewall exception '%s': %w", exp.String(), err)
 			}
+			log.Warning(fmt.Sprintf("firewall exception '%s' skipped: %v", exp.String(), err))
 			continue
 		}
-		userExceptions = append(userExceptions, *n)
+		newExceptions = append(newExceptions, e)
 	}
 
+	userExceptions = newExceptions
+	return newExceptions, implOnUserExceptionsUpdated()
+}
+
+// SetHostnameExceptions sets the addresses resolved from the user-defined hostname exceptions.
+// The addresses are applied as user exceptions, separately from the hosts allowed by AddHostsToExceptions()
+// (so, updating them does not affect the exceptions required by other components: API servers, VPN servers etc.)
+func SetHostnameExceptions(IPs []net.IP) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	exceptions := make([]service_types.FirewallException, 0, len(IPs))
+	for _, ip := range IPs {
+		if ip == nil {
+			continue
+		}
+		exceptions = append(exceptions, service_types.FirewallException{Network: ip.String()})
+	}
+	hostnameExceptions = exceptions
 	return implOnUserExceptionsUpdated()
 }
+
+// getAllUserExceptions returns the user-defined exceptions together with the addresses resolved from the hostname exceptions
+func getAllUserExceptions() []service_types.FirewallException {
+	ret := make([]service_types.FirewallException, 0, len(userExceptions)+len(hostnameExceptions))
+	ret = append(ret, userExceptions...)
+	return append(ret, hostnameExceptions...)
+}
+
+// getUserExceptionNets returns networks of the user exceptions
+// Parameters:
+//   - ipv4, ipv6 - the address families to include
+//   - isRestricted - false: exceptions which allow all communication with the host; true: exceptions restricted by protocol, ports or direction
+func getUserExceptionNets(ipv4, ipv6 bool, isRestricted bool) []net.IPNet {
+	ret := []net.IPNet{}
+	for _, e := range getAllUserExceptions() {
+		if e.IsRestricted() != isRestricted {
+			continue
+		}
+		n, err := e.IPNet()
+		if err != nil {
+			continue
+		}
+		isIPv6 := n.IP.To4() == nil
+		isIPv4 := !isIPv6
+
+		if !(isIPv4 && ipv4) && !(isIPv6 && ipv6) {
+			continue
+		}
+
+		ret = append(ret, *n)
+	}
+	return ret
+}
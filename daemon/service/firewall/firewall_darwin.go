@@ -29,6 +29,7 @@ import (
 
 	"github.com/ivpn/desktop-app/daemon/netinfo"
 	"github.com/ivpn/desktop-app/daemon/service/platform"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
 	"github.com/ivpn/desktop-app/daemon/shell"
 )
 
@@ -194,12 +195,15 @@ func implOnChangeDNS(addr net.IP, isInternal bool) error {
 
 // implOnUserExceptionsUpdated() called when 'userExceptions' value were updated. Necessary to update firewall rules.
 func implOnUserExceptionsUpdated() error {
-	var expMasks []string
-	for _, mask := range userExceptions {
-		expMasks = append(expMasks, mask.String())
-	}
+	return applySetUserExceptions(ipNetListToStrings(getUserExceptionNets(true, true, false)))
+}
 
-	return applySetUserExceptions(expMasks)
+// implCheckUserException returns error if the exception is not supported by the platform
+func implCheckUserException(e service_types.FirewallException) error {
+	if e.IsRestricted() {
+		return fmt.Errorf("exceptions restricted by protocol, port or direction are not supported on this platform")
+	}
+	return nil
 }
 
 //---------------------------------------------------------------------
@@ -356,3 +360,21 @@ func implSingleDnsRuleOff() (retErr error) {
 func implSingleDnsRuleOn(dnsAddr net.IP) (retErr error) {
 	return nil // nothing to do for this platform
 }
+
+// implGetRulesInfo fills the platform-specific part of the rules description
+func implGetRulesInfo(info *service_types.KillSwitchRules, isFullInfo bool) {
+	info.Backend = "pf"
+	if !isFullInfo {
+		return
+	}
+
+	localRanges := ipNetListToStrings(netinfo.GetNonRoutableLocalAddrRanges())
+	multicastRanges := ipNetListToStrings(netinfo.GetMulticastAddresses())
+	if stateAllowLan {
+		info.LanRanges = localRanges
+		if stateAllowLanMulticast {
+			info.LanRanges = append(info.LanRanges, multicastRanges...)
+		}
+	}
+	info.AllowedHosts, info.AllowedHostsPersistent = splitAllowedHosts(allowedHosts, append(localRanges, multicastRanges...))
+}
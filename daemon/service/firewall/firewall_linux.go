@@ -25,12 +25,16 @@ package firewall
 import (
 	"fmt"
 	"net"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/ivpn/desktop-app/daemon/netinfo"
+	"github.com/ivpn/desktop-app/daemon/service/firewall/nftables"
 	"github.com/ivpn/desktop-app/daemon/service/platform"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
 	"github.com/ivpn/desktop-app/daemon/shell"
 )
 
@@ -53,10 +57,15 @@ func init() {
 }
 
 func implInitialize() error {
+	nftInitialize()
 	return nil
 }
 
 func implGetEnabled() (bool, error) {
+	if isNftBackend() {
+		return nftables.IsEnabled()
+	}
+
 	err := shell.Exec(nil, platform.FirewallScript(), "-status")
 
 	if err != nil {
@@ -75,6 +84,15 @@ func implGetEnabled() (bool, error) {
 func implSetEnabled(isEnabled bool) error {
 	curStateEnabled = isEnabled
 
+	if isNftBackend() {
+		if !isEnabled {
+			curAllowedLanIPs = nil
+			isPersistant = false
+			allowedForICMP = nil
+		}
+		return nftSetEnabled(isEnabled)
+	}
+
 	if isEnabled {
 		err := shell.Exec(nil, platform.FirewallScript(), "-enable")
 		if err != nil {
@@ -145,6 +163,10 @@ func implClientConnected(clientLocalIPAddress net.IP, clientLocalIPv6Address net
 		return fmt.Errorf("failed to get local interface by IP: %w", err)
 	}
 
+	if isNftBackend() {
+		return nftClientConnected(inf.Name, serverIP, serverPort, isTCP)
+	}
+
 	protocol := "udp"
 	if isTCP {
 		protocol = "tcp"
@@ -168,6 +190,10 @@ func implClientConnected(clientLocalIPAddress net.IP, clientLocalIPv6Address net
 
 // ClientDisconnected - Disable communication for local vpn/client IP address
 func implClientDisconnected() error {
+	if isNftBackend() {
+		return nftClientDisconnected()
+	}
+
 	// remove all exceptions related to current connection (all non-persistant exceptions)
 	err := removeAllHostsFromExceptions()
 	if err != nil {
@@ -193,6 +219,10 @@ func doAllowLAN(isAllowLAN, isAllowLanMulticast bool) error {
 		return nil // do nothing if firewall disabled
 	}
 
+	if isNftBackend() {
+		return nftApply() // LAN ranges are calculated on ruleset build
+	}
+
 	// constants
 	const persistant = true
 	const notOnlyForICMP = false
@@ -211,19 +241,22 @@ func doAllowLAN(isAllowLAN, isAllowLanMulticast bool) error {
 
 	// LAN ALLOWED
 
+	curAllowedLanIPs = getLanIPs(isAllowLanMulticast)
+
+	// allow LAN
+	return addHostsToExceptions(curAllowedLanIPs, persistant, notOnlyForICMP)
+}
+
+// getLanIPs returns local address ranges (and multicast ranges if necessary) to be allowed for 'Allow LAN' functionality
+func getLanIPs(isAllowLanMulticast bool) []string {
 	// TODO: implement LAN access also for IPv6 addresses
 	const ipV4 = false
-	localRanges := ipNetListToStrings(filterIPNetList(netinfo.GetNonRoutableLocalAddrRanges(), ipV4))
-	multicastRanges := ipNetListToStrings(filterIPNetList(netinfo.GetMulticastAddresses(), ipV4))
-
-	curAllowedLanIPs = localRanges
+	ret := ipNetListToStrings(filterIPNetList(netinfo.GetNonRoutableLocalAddrRanges(), ipV4))
 	if isAllowLanMulticast {
 		// allow LAN + multicast
-		curAllowedLanIPs = append(curAllowedLanIPs, multicastRanges...)
+		ret = append(ret, ipNetListToStrings(filterIPNetList(netinfo.GetMulticastAddresses(), ipV4))...)
 	}
-
-	// allow LAN
-	return addHostsToExceptions(curAllowedLanIPs, persistant, notOnlyForICMP)
+	return ret
 }
 
 // implAddHostsToExceptions - allow communication with this hosts
@@ -265,12 +298,21 @@ func implOnChangeDNS(addr net.IP, isInternal bool) error {
 		addrStr = addr.String()
 	}
 
+	if isNftBackend() {
+		nftDnsIP = addr
+		log.Info("DNS rule: ", addrStr)
+		return nftApply()
+	}
+
 	log.Info("-set_dns", " ", addrStr)
 	return shell.Exec(nil, platform.FirewallScript(), "-set_dns", addrStr)
 }
 
 // implOnUserExceptionsUpdated() called when 'userExceptions' value were updated. Necessary to update firewall rules.
 func implOnUserExceptionsUpdated() error {
+	if isNftBackend() {
+		return nftApply()
+	}
 
 	applyFunc := func(isIpv4 bool) error {
 		userExceptions := getUserExceptions(isIpv4, !isIpv4)
@@ -296,19 +338,98 @@ func implOnUserExceptionsUpdated() error {
 		return shell.Exec(nil, platform.FirewallScript(), scriptCommand, ipList)
 	}
 
+	// exceptions restricted by protocol, ports or direction
+	// (the script is adding them to the same chains, so they must be applied after the chains were cleaned)
+	applyRestrictedFunc := func() error {
+		var retErr error
+		for _, e := range userExceptions {
+			if !e.IsRestricted() {
+				continue
+			}
+			if err := scriptAddUserExceptionEx(e); err != nil && retErr == nil {
+				retErr = err
+			}
+		}
+		return retErr
+	}
+
 	err := applyFunc(false)
 	errIpv6 := applyFunc(true)
+	errRestricted := applyRestrictedFunc()
 	if err == nil && errIpv6 != nil {
 		return errIpv6
 	}
+	if err == nil && errRestricted != nil {
+		return errRestricted
+	}
 	return err
 }
 
+// scriptAddUserExceptionEx adds the user exception restricted by protocol, ports or direction (firewall script)
+func scriptAddUserExceptionEx(e service_types.FirewallException) error {
+	n, err := e.IPNet()
+	if err != nil {
+		return err
+	}
+
+	scriptCommand := "-add_user_exception_ex"
+	if n.IP.To4() == nil {
+		scriptCommand = "-add_user_exception_ex_ipv6"
+	}
+
+	ports := "any"
+	if e.PortFrom > 0 {
+		ports = strconv.Itoa(int(e.PortFrom))
+		if e.PortTo > e.PortFrom {
+			ports += ":" + strconv.Itoa(int(e.PortTo))
+		}
+	}
+
+	direction := "both"
+	if e.Direction != service_types.FirewallExceptionDirectionBoth {
+		direction = string(e.Direction)
+	}
+
+	protocols := []string{string(e.Protocol)}
+	if e.Protocol == service_types.FirewallExceptionProtocolAny {
+		protocols = []string{"all"}
+		if e.PortFrom > 0 {
+			protocols = []string{"tcp", "udp"} // ports are applicable only for TCP/UDP
+		}
+	}
+
+	for _, proto := range protocols {
+		log.Info(scriptCommand, " ", proto, " ", n.String(), " ", ports, " ", direction)
+		if err := shell.Exec(nil, platform.FirewallScript(), scriptCommand, proto, n.String(), ports, direction); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// implCheckUserException returns error if the exception is not supported by the platform
+func implCheckUserException(e service_types.FirewallException) error {
+	return nil
+}
+
 func implSingleDnsRuleOff() (retErr error) {
+	if isNftBackend() {
+		return nftSingleDnsRuleOff()
+	}
 	return shell.Exec(log, platform.FirewallScript(), "-only_dns_off")
 }
 
 func implSingleDnsRuleOn(dnsAddr net.IP) (retErr error) {
+	if isNftBackend() {
+		// We can not apply this rule when firewall enabled
+		if enabled, err := nftables.IsEnabled(); err != nil {
+			return err
+		} else if enabled {
+			return fmt.Errorf("failed to apply specific DNS rule: Firewall already enabled")
+		}
+		return nftSingleDnsRuleOn(dnsAddr)
+	}
+
 	exceptions := ""
 	if prioritized, _ := getAllowedIpExceptions(); len(prioritized) > 0 {
 		exceptions = strings.Join(prioritized, ",")
@@ -320,6 +441,10 @@ func implSingleDnsRuleOn(dnsAddr net.IP) (retErr error) {
 //---------------------------------------------------------------------
 
 func applyAddHostsToExceptions(hostsIPs []string, isPersistant bool, onlyForICMP bool) error {
+	if isNftBackend() {
+		return nftApply() // the state is already updated: just rebuild the ruleset
+	}
+
 	ipList := strings.Join(hostsIPs, ",")
 
 	if len(ipList) > 0 {
@@ -343,6 +468,10 @@ func applyAddHostsToExceptions(hostsIPs []string, isPersistant bool, onlyForICMP
 }
 
 func applyRemoveHostsFromExceptions(hostsIPs []string, isPersistant bool, onlyForICMP bool) error {
+	if isNftBackend() {
+		return nftApply() // the state is already updated: just rebuild the ruleset
+	}
+
 	ipList := strings.Join(hostsIPs, ",")
 
 	if len(ipList) > 0 {
@@ -525,16 +654,29 @@ func getAllowedIpExceptions() (prioritized, persistant []string) {
 }
 
 func getUserExceptions(ipv4, ipv6 bool) []net.IPNet {
-	ret := []net.IPNet{}
-	for _, e := range userExceptions {
-		isIPv6 := e.IP.To4() == nil
-		isIPv4 := !isIPv6
+	return getUserExceptionNets(ipv4, ipv6, false)
+}
 
-		if !(isIPv4 && ipv4) && !(isIPv6 && ipv6) {
-			continue
-		}
+// implGetRulesInfo fills the platform-specific part of the rules description
+func implGetRulesInfo(info *service_types.KillSwitchRules, isFullInfo bool) {
+	info.Backend = "iptables (firewall script)"
+	if isNftBackend() {
+		info.Backend = "nftables"
+	}
+	if !isFullInfo {
+		return
+	}
 
-		ret = append(ret, e)
+	if curStateAllowLAN {
+		info.LanRanges = getLanIPs(curStateAllowLanMulticast)
+	}
+	info.AllowedHosts, info.AllowedHostsPersistent = splitAllowedHosts(allowedHosts, curAllowedLanIPs)
+	for ip := range allowedForICMP {
+		info.IcmpExceptions = append(info.IcmpExceptions, ip)
+	}
+	sort.Strings(info.IcmpExceptions)
+
+	if isNftBackend() {
+		info.RawRules = nftRawRules(info.IsDryRun)
 	}
-	return ret
 }
@@ -0,0 +1,243 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package firewall
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/ivpn/desktop-app/daemon/netinfo"
+	"github.com/ivpn/desktop-app/daemon/service/firewall/nftables"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
+)
+
+// The state of the native nftables backend.
+// The complete ruleset is rebuilt from the current state on each change
+// and applied in a single atomic transaction.
+var (
+	// not nil - nftables is not available in the system (the firewall script is in use)
+	nftAvailabilityErr error = errors.New("not initialized")
+
+	nftVpnInterface string             // VPN interface name (empty - when VPN is not connected)
+	nftVpnServer    *nftables.Endpoint // VPN server
+	nftDnsIP        net.IP             // allowed DNS server
+	nftSingleDnsIP  net.IP             // the only allowed DNS server when firewall is disabled (Inverse Split Tunnel mode)
+)
+
+func nftInitialize() {
+	nftAvailabilityErr = nftables.CheckAvailable()
+	if nftAvailabilityErr != nil {
+		if GetExtraSettings().Linux_IsNftablesBackend {
+			log.Warning("The native nftables backend is not available (the firewall script will be in use): ", nftAvailabilityErr)
+		} else {
+			log.Info("The native nftables backend is not available: ", nftAvailabilityErr)
+		}
+	}
+}
+
+// isNftBackend returns true if the native nftables backend is in use
+func isNftBackend() bool {
+	return nftAvailabilityErr == nil && GetExtraSettings().Linux_IsNftablesBackend
+}
+
+// NftablesAvailabilityError returns error if the native nftables backend is not available
+func NftablesAvailabilityError() error {
+	return nftAvailabilityErr
+}
+
+func nftSetEnabled(isEnabled bool) error {
+	nftSingleDnsIP = nil // the firewall state change always removes the 'single DNS' rule
+	if isEnabled {
+		if addr, _ := getDnsIP(); addr != nil && addr.To4() != nil {
+			nftDnsIP = addr
+		}
+	} else {
+		nftVpnInterface = ""
+		nftVpnServer = nil
+	}
+	return nftApply()
+}
+
+func nftClientConnected(vpnInterface string, serverIP net.IP, serverPort int, isTCP bool) error {
+	if curStateEnabled {
+		nftVpnInterface = vpnInterface
+		nftVpnServer = &nftables.Endpoint{IP: serverIP, Port: serverPort, IsTCP: isTCP}
+	}
+
+	// Connection already established. The rule for VPN interface is defined.
+	// Removing host IP from exceptions
+	if isPersistant, exists := allowedHosts[serverIP.String()]; exists && !isPersistant {
+		delete(allowedHosts, serverIP.String())
+	}
+	return nftApply()
+}
+
+func nftClientDisconnected() error {
+	nftVpnInterface = ""
+	nftVpnServer = nil
+
+	// remove all exceptions related to current connection (all non-persistant exceptions)
+	for ip, isPersistant := range allowedHosts {
+		if !isPersistant {
+			delete(allowedHosts, ip)
+		}
+	}
+	return nftApply()
+}
+
+func nftSingleDnsRuleOn(dnsAddr net.IP) error {
+	nftSingleDnsIP = dnsAddr
+	return nftApply()
+}
+
+func nftSingleDnsRuleOff() error {
+	if nftSingleDnsIP == nil {
+		return nil
+	}
+	nftSingleDnsIP = nil
+	return nftApply()
+}
+
+// nftConfig returns the current firewall state for the ruleset builder
+func nftConfig() nftables.Config {
+	prioritized, persistant := getAllowedIpExceptions()
+	sort.Strings(prioritized)
+	sort.Strings(persistant)
+
+	staticExceptions := parseIPNets(persistant)
+	if curStateAllowLAN {
+		staticExceptions = append(staticExceptions, parseIPNets(getLanIPs(curStateAllowLanMulticast))...)
+	}
+
+	icmpExceptions := make([]string, 0, len(allowedForICMP))
+	for ip := range allowedForICMP {
+		icmpExceptions = append(icmpExceptions, ip)
+	}
+	sort.Strings(icmpExceptions)
+
+	cfg := nftables.Config{
+		IsEnabled:        curStateEnabled,
+		VpnInterface:     nftVpnInterface,
+		VpnServer:        nftVpnServer,
+		DnsIP:            nftDnsIP,
+		Exceptions:       parseIPNets(prioritized),
+		StaticExceptions: staticExceptions,
+		UserExceptions:   nftUserExceptions(),
+		IcmpExceptions:   parseIPs(icmpExceptions),
+		SingleDnsIP:      nftSingleDnsIP,
+	}
+	if nftSingleDnsIP != nil {
+		cfg.SingleDnsExceptions = parseIPs(prioritized)
+	}
+	return cfg
+}
+
+func nftUserExceptions() []nftables.UserException {
+	exceptions := getAllUserExceptions()
+	ret := make([]nftables.UserException, 0, len(exceptions))
+	for _, e := range exceptions {
+		n, err := e.IPNet()
+		if err != nil {
+			continue
+		}
+		exp := nftables.UserException{Net: *n, PortFrom: e.PortFrom, PortTo: e.PortTo}
+		switch e.Protocol {
+		case service_types.FirewallExceptionProtocolTCP:
+			exp.Protocol = nftables.ProtoTCP
+		case service_types.FirewallExceptionProtocolUDP:
+			exp.Protocol = nftables.ProtoUDP
+		}
+		switch e.Direction {
+		case service_types.FirewallExceptionDirectionIn:
+			exp.Direction = nftables.DirectionIn
+		case service_types.FirewallExceptionDirectionOut:
+			exp.Direction = nftables.DirectionOut
+		}
+		ret = append(ret, exp)
+	}
+	return ret
+}
+
+// nftRawRules returns the ruleset in the 'nft' syntax.
+// If isDryRun - returns the ruleset which would be applied when the firewall is enabled
+func nftRawRules(isDryRun bool) string {
+	cfg := nftConfig()
+	if isDryRun && !cfg.IsEnabled {
+		cfg.IsEnabled = true
+		if addr, _ := getDnsIP(); addr != nil && addr.To4() != nil {
+			cfg.DnsIP = addr
+		}
+		if connectedClientInterfaceIP != nil && !isClientPaused {
+			if inf, err := netinfo.InterfaceByIPAddr(connectedClientInterfaceIP); err == nil {
+				cfg.VpnInterface = inf.Name
+				cfg.VpnServer = &nftables.Endpoint{IP: connectedHostIP, Port: connectedHostPort, IsTCP: connectedIsTCP}
+			}
+		}
+	}
+	if rs := nftables.Build(cfg); rs != nil {
+		return rs.String()
+	}
+	return ""
+}
+
+func nftApply() error {
+	return nftables.Apply(nftables.Build(nftConfig()))
+}
+
+// parseIPNets converts list of strings (IP addresses or CIDR) to list of net.IPNet
+func parseIPNets(ips []string) []net.IPNet {
+	ret := make([]net.IPNet, 0, len(ips))
+	for _, s := range ips {
+		if strings.Contains(s, "/") {
+			if _, n, err := net.ParseCIDR(s); err == nil {
+				ret = append(ret, *n)
+				continue
+			}
+		} else if ip := net.ParseIP(s); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			ret = append(ret, net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		log.Warning("unable to parse IP address: ", s)
+	}
+	return ret
+}
+
+// parseIPs converts list of strings to list of net.IP (CIDR ranges are ignored)
+func parseIPs(ips []string) []net.IP {
+	ret := make([]net.IP, 0, len(ips))
+	for _, s := range ips {
+		if ip := net.ParseIP(s); ip != nil {
+			ret = append(ret, ip)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,91 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package firewall
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ivpn/desktop-app/daemon/netinfo"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
+)
+
+// GetRulesInfo returns description of the firewall rules.
+// Arguments:
+//   - isDryRun - false: description of currently applied rules (all lists are empty when the firewall is disabled);
+//     true: description of rules which would be applied when the firewall is enabled
+func GetRulesInfo(isDryRun bool) (service_types.KillSwitchRules, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	enabled, err := implGetEnabled()
+	if err != nil {
+		return service_types.KillSwitchRules{}, fmt.Errorf("failed to get firewall status: %w", err)
+	}
+
+	info := service_types.KillSwitchRules{IsEnabled: enabled, IsDryRun: isDryRun}
+	if !enabled && !isDryRun {
+		implGetRulesInfo(&info, false)
+		return info, nil
+	}
+
+	if connectedClientInterfaceIP != nil && !isClientPaused && connectedHostIP != nil {
+		proto := "UDP"
+		if connectedIsTCP {
+			proto = "TCP"
+		}
+		info.VpnServer = fmt.Sprintf("%s:%d %s", connectedHostIP, connectedHostPort, proto)
+		if inf, err := netinfo.InterfaceByIPAddr(connectedClientInterfaceIP); err == nil {
+			info.VpnInterface = inf.Name
+		}
+	}
+	if addr, _ := getDnsIP(); addr != nil {
+		info.DnsServer = addr.String()
+	}
+	for _, e := range userExceptions {
+		info.UserExceptions = append(info.UserExceptions, e.String())
+	}
+
+	implGetRulesInfo(&info, true)
+	return info, nil
+}
+
+// splitAllowedHosts returns sorted lists of allowed hosts: related to the current connection and persistent ones
+// (the LAN ranges are excluded from the persistent list)
+func splitAllowedHosts(hosts map[string]bool, lanRanges []string) (prioritized, persistent []string) {
+	isLan := make(map[string]struct{}, len(lanRanges))
+	for _, r := range lanRanges {
+		isLan[r] = struct{}{}
+	}
+
+	for ip, isPersistent := range hosts {
+		if !isPersistent {
+			prioritized = append(prioritized, ip)
+		} else if _, ok := isLan[ip]; !ok {
+			persistent = append(persistent, ip)
+		}
+	}
+	sort.Strings(prioritized)
+	sort.Strings(persistent)
+	return prioritized, persistent
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/ivpn/desktop-app/daemon/netinfo"
 	"github.com/ivpn/desktop-app/daemon/service/firewall/winlib"
 	"github.com/ivpn/desktop-app/daemon/service/platform"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
 )
 
 var (
@@ -601,18 +602,15 @@ func doRemoveClientIPFilters() (retErr error) {
 }
 
 func getUserExceptions(ipv4, ipv6 bool) []net.IPNet {
-	ret := []net.IPNet{}
-	for _, e := range userExceptions {
-		isIPv6 := e.IP.To4() == nil
-		isIPv4 := !isIPv6
-
-		if !(isIPv4 && ipv4) && !(isIPv6 && ipv6) {
-			continue
-		}
+	return getUserExceptionNets(ipv4, ipv6, false)
+}
 
-		ret = append(ret, e)
+// implCheckUserException returns error if the exception is not supported by the platform
+func implCheckUserException(e service_types.FirewallException) error {
+	if e.IsRestricted() {
+		return fmt.Errorf("exceptions restricted by protocol, port or direction are not supported on this platform")
 	}
-	return ret
+	return nil
 }
 
 func implSingleDnsRuleOff() (retErr error) {
@@ -752,3 +750,18 @@ func implSingleDnsRuleOn(dnsAddr net.IP) (retErr error) {
 	}
 	return nil
 }
+
+// implGetRulesInfo fills the platform-specific part of the rules description
+func implGetRulesInfo(info *service_types.KillSwitchRules, isFullInfo bool) {
+	info.Backend = "WFP"
+	if !isFullInfo {
+		return
+	}
+
+	if isAllowLAN {
+		info.LanRanges = ipNetListToStrings(netinfo.GetNonRoutableLocalAddrRanges())
+		if isAllowLANMulticast {
+			info.LanRanges = append(info.LanRanges, ipNetListToStrings(netinfo.GetMulticastAddresses())...)
+		}
+	}
+}
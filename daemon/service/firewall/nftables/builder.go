@@ -0,0 +1,371 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package nftables
+
+import (
+	"net"
+)
+
+// TableName - name of the nftables table which contains all IVPN rules
+// (the table has 'inet' family: it is processing both IPv4 and IPv6 packets)
+const TableName = "ivpn"
+
+// Names of the base chains
+const (
+	ChainInput   = "input"
+	ChainOutput  = "output"
+	ChainForward = "forward"
+	// ChainDnsOnly - base chain which is in use only when the firewall is disabled but
+	// it is required to allow DNS requests only to a specific server (Inverse Split Tunnel mode)
+	ChainDnsOnly = "dns_only"
+)
+
+// Names of the regular chains (the same structure as in the firewall script)
+const (
+	chainInVpn0     = "in_vpn0"      // exceptions which must be processed before DNS rules
+	chainOutVpn0    = "out_vpn0"     // (e.g. the VPN server; the hosts for the current connection)
+	chainOutDns     = "out_dns"      // DNS rules
+	chainInVpn      = "in_vpn"       // VPN interface
+	chainOutVpn     = "out_vpn"      //
+	chainForwardVpn = "forward_vpn"  //
+	chainInStatExp  = "in_stat_exp"  // non-VPN dependent exceptions (e.g. 'Allow LAN')
+	chainOutStatExp = "out_stat_exp" //
+	chainInUserExp  = "in_user_exp"  // user-defined exceptions
+	chainOutUserExp = "out_user_exp" //
+	chainInIcmpExp  = "in_icmp_exp"  // exceptions only for ICMP protocol (ping)
+	chainOutIcmpExp = "out_icmp_exp" //
+)
+
+const (
+	// The 'mark' value for packets coming from the Split-Tunneling environment
+	// (the same as WireGuard marking packets which were processed)
+	splitTunFwMark = 0xca6c
+	// Split Tunnel cgroup class ID
+	splitTunCgroupClassID = 0x4956504e
+	splitTunComment       = "IVPN Split Tunneling"
+)
+
+// Endpoint - remote host address and port
+type Endpoint struct {
+	IP    net.IP
+	Port  int
+	IsTCP bool
+}
+
+// ExceptionDirection - direction of the connections allowed by the user exception
+type ExceptionDirection int
+
+const (
+	DirectionBoth ExceptionDirection = iota
+	DirectionIn                      // incoming connections from the remote host (and replies)
+	DirectionOut                     // outgoing connections to the remote host (and replies)
+)
+
+// UserException - user-defined allowed host (optionally restricted by protocol, ports and direction)
+type UserException struct {
+	Net net.IPNet
+	// Protocol - ProtoTCP or ProtoUDP (0 - any protocol; when ports are defined - both TCP and UDP)
+	Protocol uint32
+	// PortFrom, PortTo - remote port range for outgoing connections or local port range for incoming connections (0 - any port)
+	PortFrom, PortTo uint16
+	Direction        ExceptionDirection
+}
+
+// Config - the firewall state to be converted to the ruleset
+type Config struct {
+	IsEnabled bool
+
+	// VpnInterface - name of the VPN interface (empty when VPN is not connected)
+	VpnInterface string
+	// VpnServer - allow communication with the VPN server only on the port/protocol in use
+	VpnServer *Endpoint
+	// DnsIP - the only allowed DNS server (nil - all DNS requests are blocked except via VPN interface)
+	DnsIP net.IP
+
+	// Exceptions - allowed hosts which are processed before DNS restrictions
+	// (e.g. the hosts related to the current connection)
+	Exceptions []net.IPNet
+	// StaticExceptions - allowed hosts independent of the VPN connection (e.g. 'Allow LAN')
+	StaticExceptions []net.IPNet
+	// UserExceptions - user-defined allowed hosts
+	UserExceptions []UserException
+	// IcmpExceptions - hosts allowed only for ICMP (ping)
+	IcmpExceptions []net.IP
+
+	// SingleDnsIP - applicable only when the firewall is disabled.
+	// If defined: DNS requests are allowed only to this IP (in use by Inverse Split Tunnel mode)
+	SingleDnsIP net.IP
+	// SingleDnsExceptions - hosts allowed to be accessed on port 53 in 'SingleDnsIP' mode
+	// (e.g. connection to VPN server trough V2Ray/QUIC on UDP 53)
+	SingleDnsExceptions []net.IP
+}
+
+// Build converts the firewall state to the ruleset.
+// Returns nil if there are no rules required (the IVPN table has to be removed).
+func Build(cfg Config) *Ruleset {
+	if cfg.IsEnabled {
+		return buildEnabled(cfg)
+	}
+	if cfg.SingleDnsIP != nil {
+		return buildSingleDns(cfg)
+	}
+	return nil
+}
+
+func buildEnabled(cfg Config) *Ruleset {
+	input := Chain{Name: ChainInput, Hook: HookInput, Policy: VerdictDrop}
+	output := Chain{Name: ChainOutput, Hook: HookOutput, Policy: VerdictDrop}
+	forward := Chain{Name: ChainForward, Hook: HookForward, Policy: VerdictDrop}
+
+	// Split Tunnel: allow packets from/to cgroup (bypass IVPN firewall)
+	output.add(rule(VerdictAccept, Match{Type: MatchCgroup, Value: splitTunCgroupClassID}).withComment(splitTunComment))
+	output.add(rule(VerdictAccept, Match{Type: MatchMark, Value: splitTunFwMark}).withComment(splitTunComment)) // cgroup v2: packets are marked before the 'filter' hook
+	input.add(rule(VerdictAccept, Match{Type: MatchCgroup, Value: splitTunCgroupClassID}).withComment(splitTunComment))
+	input.add(rule(VerdictAccept, Match{Type: MatchMark, Value: splitTunFwMark}).withComment(splitTunComment))
+
+	// IPv6: block DNS before allowing link-local and unique-local addresses!
+	// It will prevent potential DNS leaking in some situations (for example, from VM to a host machine)
+	for _, proto := range []uint32{ProtoUDP, ProtoTCP} {
+		output.add(rule(VerdictDrop, append([]Match{nfProto(NfProtoIPv6)}, port(true, proto, 53)...)...))
+	}
+
+	// allow local (lo) interface
+	output.add(rule(VerdictAccept, Match{Type: MatchOIFName, Name: "lo"}))
+	input.add(rule(VerdictAccept, Match{Type: MatchIIFName, Name: "lo"}))
+
+	// IPv6: allow link-local and unique-local addresses
+	for _, n := range []string{"fe80::/10", "fd00::/8"} {
+		_, ipNet, _ := net.ParseCIDR(n)
+		input.add(rule(VerdictAccept, addr(false, *ipNet, false)...))
+		output.add(rule(VerdictAccept, addr(true, *ipNet, false)...))
+	}
+
+	// allow DHCP (67 out, 68 in)
+	output.add(rule(VerdictAccept, append([]Match{nfProto(NfProtoIPv4)}, port(true, ProtoUDP, 67)...)...))
+	input.add(rule(VerdictAccept, append([]Match{nfProto(NfProtoIPv4)}, port(true, ProtoUDP, 68)...)...))
+
+	// exceptions which must be processed before DNS rules
+	inVpn0 := Chain{Name: chainInVpn0}
+	outVpn0 := Chain{Name: chainOutVpn0}
+	addExceptions(&inVpn0, &outVpn0, cfg.Exceptions)
+	if srv := cfg.VpnServer; srv != nil && srv.IP != nil && len(cfg.VpnInterface) > 0 {
+		proto := uint32(ProtoUDP)
+		if srv.IsTCP {
+			proto = ProtoTCP
+		}
+		srvNet := hostNet(srv.IP)
+		inVpn0.add(rule(VerdictAccept, append(addr(false, srvNet, false), port(false, proto, uint32(srv.Port))...)...))
+		outVpn0.add(rule(VerdictAccept, append(addr(true, srvNet, false), port(true, proto, uint32(srv.Port))...)...))
+	}
+
+	// DNS rules (IPv4): block all DNS requests except to the defined server
+	outDns := Chain{Name: chainOutDns}
+	for _, proto := range []uint32{ProtoTCP, ProtoUDP} {
+		if cfg.DnsIP != nil && cfg.DnsIP.To4() != nil {
+			outDns.add(rule(VerdictDrop, append(addr(true, hostNet(cfg.DnsIP), true), port(true, proto, 53)...)...))
+		} else {
+			outDns.add(rule(VerdictDrop, append([]Match{nfProto(NfProtoIPv4)}, port(true, proto, 53)...)...))
+		}
+	}
+
+	// allow all packets to VPN interface
+	inVpn := Chain{Name: chainInVpn}
+	outVpn := Chain{Name: chainOutVpn}
+	forwardVpn := Chain{Name: chainForwardVpn}
+	if len(cfg.VpnInterface) > 0 {
+		inVpn.add(rule(VerdictAccept, Match{Type: MatchIIFName, Name: cfg.VpnInterface}))
+		outVpn.add(rule(VerdictAccept, Match{Type: MatchOIFName, Name: cfg.VpnInterface}))
+		forwardVpn.add(rule(VerdictAccept, Match{Type: MatchIIFName, Name: cfg.VpnInterface}))
+		forwardVpn.add(rule(VerdictAccept, Match{Type: MatchOIFName, Name: cfg.VpnInterface}))
+	}
+
+	inStatExp := Chain{Name: chainInStatExp}
+	outStatExp := Chain{Name: chainOutStatExp}
+	addExceptions(&inStatExp, &outStatExp, cfg.StaticExceptions)
+
+	inUserExp := Chain{Name: chainInUserExp}
+	outUserExp := Chain{Name: chainOutUserExp}
+	for _, e := range cfg.UserExceptions {
+		addUserException(&inUserExp, &outUserExp, e)
+	}
+
+	inIcmpExp := Chain{Name: chainInIcmpExp}
+	outIcmpExp := Chain{Name: chainOutIcmpExp}
+	for _, ip := range cfg.IcmpExceptions {
+		if ip.To4() == nil {
+			continue // ICMP exceptions are applicable only for IPv4
+		}
+		n := hostNet(ip)
+		inIcmpExp.add(rule(VerdictAccept, append(addr(false, n, false),
+			Match{Type: MatchL4Proto, Value: ProtoICMP},
+			Match{Type: MatchIcmpType, Value: IcmpEchoReply},
+			Match{Type: MatchCtState, Value: CtStateEstablished | CtStateRelated})...))
+		outIcmpExp.add(rule(VerdictAccept, append(addr(true, n, false),
+			Match{Type: MatchL4Proto, Value: ProtoICMP},
+			Match{Type: MatchIcmpType, Value: IcmpEchoRequest},
+			Match{Type: MatchCtState, Value: CtStateNew | CtStateEstablished | CtStateRelated})...))
+	}
+
+	// assign regular chains to the base chains (the order is important!)
+	output.jump(chainOutVpn0, chainOutDns, chainOutVpn, chainOutStatExp, chainOutUserExp, chainOutIcmpExp)
+	input.jump(chainInVpn0, chainInVpn, chainInStatExp, chainInUserExp, chainInIcmpExp)
+	forward.jump(chainForwardVpn)
+
+	return &Ruleset{
+		Table: TableName,
+		Chains: []Chain{input, output, forward,
+			inVpn0, outVpn0, outDns,
+			inVpn, outVpn, forwardVpn,
+			inStatExp, outStatExp,
+			inUserExp, outUserExp,
+			inIcmpExp, outIcmpExp},
+	}
+}
+
+// buildSingleDns - allow only specific DNS address: in use by Inverse Split Tunnel mode
+// (Inverse Split Tunnel mode does not allow to enable "firewall" but have to block unwanted DNS requests anyway)
+func buildSingleDns(cfg Config) *Ruleset {
+	dnsOnly := Chain{Name: ChainDnsOnly, Hook: HookOutput, Policy: VerdictAccept}
+
+	// Allow communication with IP addresses from exceptions list.
+	// It avoids situation of blocking communication with VPN server over port 53 (e.g. connection trough V2Ray/QUICK on UDP 53)
+	for _, ip := range cfg.SingleDnsExceptions {
+		dnsOnly.add(rule(VerdictAccept, append(addr(true, hostNet(ip), false), port(true, ProtoUDP, 53)...)...))
+	}
+	dnsOnly.add(rule(VerdictAccept, Match{Type: MatchOIFName, Name: "lo"}))
+	for _, proto := range []uint32{ProtoTCP, ProtoUDP} {
+		dnsOnly.add(rule(VerdictDrop, append(addr(true, hostNet(cfg.SingleDnsIP), true), port(true, proto, 53)...)...))
+	}
+
+	return &Ruleset{Table: TableName, Chains: []Chain{dnsOnly}}
+}
+
+//---------------------------------------------------------------------
+
+func (c *Chain) add(r Rule) {
+	c.Rules = append(c.Rules, r)
+}
+
+func (c *Chain) jump(targets ...string) {
+	for _, t := range targets {
+		c.Rules = append(c.Rules, Rule{Verdict: VerdictJump, Target: t})
+	}
+}
+
+func (r Rule) withComment(comment string) Rule {
+	r.Comment = comment
+	return r
+}
+
+func rule(verdict Verdict, matches ...Match) Rule {
+	return Rule{Matches: matches, Verdict: verdict}
+}
+
+func addExceptions(in, out *Chain, exceptions []net.IPNet) {
+	for _, e := range exceptions {
+		in.add(rule(VerdictAccept, addr(false, e, false)...))
+		out.add(rule(VerdictAccept, addr(true, e, false)...))
+	}
+}
+
+func addUserException(in, out *Chain, e UserException) {
+	if e.Protocol == 0 && e.PortFrom == 0 && e.Direction == DirectionBoth {
+		addExceptions(in, out, []net.IPNet{e.Net})
+		return
+	}
+
+	protocols := []uint32{e.Protocol}
+	if e.Protocol == 0 && e.PortFrom > 0 {
+		protocols = []uint32{ProtoTCP, ProtoUDP}
+	}
+	replies := Match{Type: MatchCtState, Value: CtStateEstablished | CtStateRelated}
+
+	for _, proto := range protocols {
+		// matches for: remote host address + protocol + port (remote or local)
+		matches := func(isOutgoing, isRemotePort bool) []Match {
+			ret := addr(isOutgoing, e.Net, false)
+			if proto != 0 {
+				ret = append(ret, Match{Type: MatchL4Proto, Value: proto})
+			}
+			if e.PortFrom > 0 {
+				// remote port: destination for outgoing packets, source for incoming
+				portType := MatchSPort
+				if isOutgoing == isRemotePort {
+					portType = MatchDPort
+				}
+				ret = append(ret, Match{Type: portType, Value: uint32(e.PortFrom), ValueTo: uint32(e.PortTo)})
+			}
+			return ret
+		}
+
+		if e.Direction != DirectionIn {
+			// outgoing connections to the remote host (port - remote) and replies
+			out.add(rule(VerdictAccept, matches(true, true)...))
+			in.add(rule(VerdictAccept, append(matches(false, true), replies)...))
+		}
+		if e.Direction != DirectionOut {
+			// incoming connections from the remote host (port - local) and replies
+			in.add(rule(VerdictAccept, matches(false, false)...))
+			out.add(rule(VerdictAccept, append(matches(true, false), replies)...))
+		}
+	}
+}
+
+func nfProto(proto uint32) Match {
+	return Match{Type: MatchNfProto, Value: proto}
+}
+
+// addr returns matches for source/destination address
+// (the address family check is required before checking the address in the 'inet' table)
+func addr(isDestination bool, n net.IPNet, negate bool) []Match {
+	family := uint32(NfProtoIPv4)
+	if n.IP.To4() == nil {
+		family = NfProtoIPv6
+	} else {
+		n.IP = n.IP.To4()
+		if len(n.Mask) == net.IPv6len {
+			n.Mask = n.Mask[12:]
+		}
+	}
+	t := MatchSAddr
+	if isDestination {
+		t = MatchDAddr
+	}
+	return []Match{nfProto(family), {Type: t, Net: n, Negate: negate}}
+}
+
+// port returns matches for source/destination port of TCP/UDP protocol
+func port(isDestination bool, proto uint32, portNum uint32) []Match {
+	t := MatchSPort
+	if isDestination {
+		t = MatchDPort
+	}
+	return []Match{{Type: MatchL4Proto, Value: proto}, {Type: t, Value: portNum}}
+}
+
+func hostNet(ip net.IP) net.IPNet {
+	if ip4 := ip.To4(); ip4 != nil {
+		return net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+	return net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
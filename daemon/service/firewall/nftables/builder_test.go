@@ -0,0 +1,209 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package nftables_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/service/firewall/nftables"
+)
+
+func mustParseCIDR(t *testing.T, s string) net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *n
+}
+
+func chainRules(rs *nftables.Ruleset, chain string) []string {
+	c := rs.Chain(chain)
+	if c == nil {
+		return nil
+	}
+	ret := make([]string, 0, len(c.Rules))
+	for _, r := range c.Rules {
+		ret = append(ret, r.String())
+	}
+	return ret
+}
+
+func TestBuildDisabled(t *testing.T) {
+	if rs := nftables.Build(nftables.Config{}); rs != nil {
+		t.Errorf("expected no ruleset for disabled firewall; got:\n%s", rs)
+	}
+}
+
+func TestBuildSingleDns(t *testing.T) {
+	rs := nftables.Build(nftables.Config{
+		SingleDnsIP:         net.ParseIP("10.0.254.1"),
+		SingleDnsExceptions: []net.IP{net.ParseIP("198.51.100.1")},
+	})
+	if rs == nil || len(rs.Chains) != 1 {
+		t.Fatalf("expected single chain ruleset; got:\n%s", rs)
+	}
+
+	c := rs.Chains[0]
+	if c.Name != nftables.ChainDnsOnly || c.Hook != nftables.HookOutput || c.Policy != nftables.VerdictAccept {
+		t.Errorf("unexpected chain definition: %+v", c)
+	}
+
+	expected := []string{
+		`meta nfproto ipv4 ip daddr 198.51.100.1 meta l4proto udp th dport 53 accept`,
+		`oifname "lo" accept`,
+		`meta nfproto ipv4 ip daddr != 10.0.254.1 meta l4proto tcp th dport 53 drop`,
+		`meta nfproto ipv4 ip daddr != 10.0.254.1 meta l4proto udp th dport 53 drop`,
+	}
+	if got := chainRules(rs, nftables.ChainDnsOnly); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected rules:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestBuildEnabled(t *testing.T) {
+	rs := nftables.Build(nftables.Config{
+		IsEnabled:        true,
+		VpnInterface:     "wgivpn",
+		VpnServer:        &nftables.Endpoint{IP: net.ParseIP("203.0.113.10"), Port: 2049},
+		DnsIP:            net.ParseIP("10.0.254.1"),
+		Exceptions:       []net.IPNet{mustParseCIDR(t, "198.51.100.1/32")},
+		StaticExceptions: []net.IPNet{mustParseCIDR(t, "192.168.0.0/16")},
+		UserExceptions:   []nftables.UserException{{Net: mustParseCIDR(t, "2001:db8::/32")}},
+		IcmpExceptions:   []net.IP{net.ParseIP("203.0.113.20")},
+	})
+	if rs == nil {
+		t.Fatal("expected ruleset")
+	}
+	if rs.Table != nftables.TableName {
+		t.Errorf("unexpected table name '%s'", rs.Table)
+	}
+
+	// base chains: everything is blocked by default
+	for _, name := range []string{nftables.ChainInput, nftables.ChainOutput, nftables.ChainForward} {
+		c := rs.Chain(name)
+		if c == nil || c.Hook == nftables.HookNone || c.Policy != nftables.VerdictDrop {
+			t.Errorf("base chain '%s' is not defined or has wrong policy: %+v", name, c)
+		}
+	}
+
+	// all jump targets must exist
+	for _, c := range rs.Chains {
+		for _, r := range c.Rules {
+			if r.Verdict == nftables.VerdictJump && rs.Chain(r.Target) == nil {
+				t.Errorf("chain '%s': jump to undefined chain '%s'", c.Name, r.Target)
+			}
+		}
+	}
+
+	// the exceptions must be processed before DNS rules; the VPN interface - after
+	output := strings.Join(chainRules(rs, nftables.ChainOutput), "\n")
+	iVpn0 := strings.Index(output, "jump out_vpn0")
+	iDns := strings.Index(output, "jump out_dns")
+	iVpn := strings.Index(output, "jump out_vpn\n")
+	if iVpn0 < 0 || iDns < 0 || iVpn < 0 || !(iVpn0 < iDns && iDns < iVpn) {
+		t.Errorf("wrong order of chains in output chain:\n%s", output)
+	}
+	// split tunnel rule must be the first one
+	if !strings.HasPrefix(output, `meta cgroup 0x4956504e accept comment "IVPN Split Tunneling"`) {
+		t.Errorf("split tunnel rule is not the first rule in output chain:\n%s", output)
+	}
+
+	contains := []struct {
+		chain string
+		rule  string
+	}{
+		{"out_vpn0", "meta nfproto ipv4 ip daddr 198.51.100.1 accept"},
+		{"in_vpn0", "meta nfproto ipv4 ip saddr 198.51.100.1 accept"},
+		{"out_vpn0", "meta nfproto ipv4 ip daddr 203.0.113.10 meta l4proto udp th dport 2049 accept"},
+		{"in_vpn0", "meta nfproto ipv4 ip saddr 203.0.113.10 meta l4proto udp th sport 2049 accept"},
+		{"out_dns", "meta nfproto ipv4 ip daddr != 10.0.254.1 meta l4proto udp th dport 53 drop"},
+		{"out_vpn", `oifname "wgivpn" accept`},
+		{"in_vpn", `iifname "wgivpn" accept`},
+		{"forward_vpn", `oifname "wgivpn" accept`},
+		{"out_stat_exp", "meta nfproto ipv4 ip daddr 192.168.0.0/16 accept"},
+		{"in_user_exp", "meta nfproto ipv6 ip6 saddr 2001:db8::/32 accept"},
+		{"out_icmp_exp", "meta nfproto ipv4 ip daddr 203.0.113.20 meta l4proto icmp icmp type echo-request ct state new,established,related accept"},
+		{"in_icmp_exp", "meta nfproto ipv4 ip saddr 203.0.113.20 meta l4proto icmp icmp type echo-reply ct state established,related accept"},
+	}
+	for _, c := range contains {
+		found := false
+		for _, r := range chainRules(rs, c.chain) {
+			if r == c.rule {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("chain '%s' does not contain rule '%s':\n%s", c.chain, c.rule, strings.Join(chainRules(rs, c.chain), "\n"))
+		}
+	}
+}
+
+func TestBuildEnabledNoVpn(t *testing.T) {
+	rs := nftables.Build(nftables.Config{IsEnabled: true})
+
+	// no VPN connection: VPN chains are empty; all DNS requests are blocked
+	for _, name := range []string{"in_vpn", "out_vpn", "forward_vpn", "in_vpn0", "out_vpn0"} {
+		if rules := chainRules(rs, name); len(rules) != 0 {
+			t.Errorf("chain '%s' expected to be empty: %v", name, rules)
+		}
+	}
+	expected := []string{
+		"meta nfproto ipv4 meta l4proto tcp th dport 53 drop",
+		"meta nfproto ipv4 meta l4proto udp th dport 53 drop",
+	}
+	if got := chainRules(rs, "out_dns"); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected DNS rules:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestBuildUserExceptions(t *testing.T) {
+	rs := nftables.Build(nftables.Config{
+		IsEnabled: true,
+		UserExceptions: []nftables.UserException{
+			{Net: mustParseCIDR(t, "10.0.0.5/32"), Protocol: nftables.ProtoTCP, PortFrom: 22, PortTo: 22, Direction: nftables.DirectionOut},
+			{Net: mustParseCIDR(t, "192.168.1.0/24"), Protocol: nftables.ProtoUDP, PortFrom: 5353, PortTo: 5353, Direction: nftables.DirectionIn},
+			{Net: mustParseCIDR(t, "10.0.0.6/32"), PortFrom: 8000, PortTo: 8080, Direction: nftables.DirectionOut},
+		},
+	})
+
+	expectedIn := []string{
+		"meta nfproto ipv4 ip saddr 10.0.0.5 meta l4proto tcp th sport 22 ct state established,related accept",
+		"meta nfproto ipv4 ip saddr 192.168.1.0/24 meta l4proto udp th dport 5353 accept",
+		"meta nfproto ipv4 ip saddr 10.0.0.6 meta l4proto tcp th sport 8000-8080 ct state established,related accept",
+		"meta nfproto ipv4 ip saddr 10.0.0.6 meta l4proto udp th sport 8000-8080 ct state established,related accept",
+	}
+	expectedOut := []string{
+		"meta nfproto ipv4 ip daddr 10.0.0.5 meta l4proto tcp th dport 22 accept",
+		"meta nfproto ipv4 ip daddr 192.168.1.0/24 meta l4proto udp th sport 5353 ct state established,related accept",
+		"meta nfproto ipv4 ip daddr 10.0.0.6 meta l4proto tcp th dport 8000-8080 accept",
+		"meta nfproto ipv4 ip daddr 10.0.0.6 meta l4proto udp th dport 8000-8080 accept",
+	}
+	if got := chainRules(rs, "in_user_exp"); strings.Join(got, "\n") != strings.Join(expectedIn, "\n") {
+		t.Errorf("unexpected rules in 'in_user_exp':\n%s", strings.Join(got, "\n"))
+	}
+	if got := chainRules(rs, "out_user_exp"); strings.Join(got, "\n") != strings.Join(expectedOut, "\n") {
+		t.Errorf("unexpected rules in 'out_user_exp':\n%s", strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,711 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package nftables
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// netfilter verdicts
+const (
+	nfDrop   = 0
+	nfAccept = 1
+)
+
+// NFTNL_UDATA_RULE_COMMENT: type of the rule comment in the rule 'userdata' (the format used by 'nft' utility)
+const udataRuleComment = 0
+
+const ifNameSize = 16 // IFNAMSIZ
+
+const receiveTimeout = 5 * time.Second
+
+// nft_socket expression attributes (not defined in golang.org/x/sys/unix)
+const (
+	nftaSocketKey     = 1 // NFTA_SOCKET_KEY
+	nftaSocketDreg    = 2 // NFTA_SOCKET_DREG
+	nftaSocketLevel   = 3 // NFTA_SOCKET_LEVEL
+	nftSocketCgroupV2 = 3 // NFT_SOCKET_CGROUPV2
+)
+
+// CheckAvailable returns error if the nftables functionality is not available in the system
+// (e.g. the kernel was built without nf_tables support)
+func CheckAvailable() error {
+	conn, err := netlink.Dial(unix.NETLINK_NETFILTER, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open netfilter netlink socket: %w", err)
+	}
+	defer conn.Close()
+
+	ae := newEncoder()
+	ae.String(unix.NFTA_TABLE_NAME, TableName)
+	_, err = execute(conn, unix.NFT_MSG_GETTABLE, ae)
+	if err != nil && !errors.Is(err, syscall.ENOENT) {
+		return fmt.Errorf("nftables is not available: %w", err)
+	}
+	return nil
+}
+
+// IsEnabled returns 'true' when the IVPN table exists and contains the firewall base chains
+func IsEnabled() (bool, error) {
+	conn, err := netlink.Dial(unix.NETLINK_NETFILTER, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to open netfilter netlink socket: %w", err)
+	}
+	defer conn.Close()
+
+	ae := newEncoder()
+	ae.String(unix.NFTA_CHAIN_TABLE, TableName)
+	ae.String(unix.NFTA_CHAIN_NAME, ChainOutput)
+	if _, err = execute(conn, unix.NFT_MSG_GETCHAIN, ae); err != nil {
+		if errors.Is(err, syscall.ENOENT) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// IsTableExists returns 'true' when the table exists
+func IsTableExists(table string) (bool, error) {
+	conn, err := netlink.Dial(unix.NETLINK_NETFILTER, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to open netfilter netlink socket: %w", err)
+	}
+	defer conn.Close()
+
+	ae := newEncoder()
+	ae.String(unix.NFTA_TABLE_NAME, table)
+	if _, err = execute(conn, unix.NFT_MSG_GETTABLE, ae); err != nil {
+		if errors.Is(err, syscall.ENOENT) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Apply replaces the content of the IVPN table by the ruleset.
+// All changes are applied in a single atomic transaction: the packets are never processed by a partially defined ruleset.
+// If the ruleset is nil - the IVPN table is removed.
+func Apply(rs *Ruleset) error {
+	return ApplyTable(TableName, rs)
+}
+
+// ApplyTable replaces the content of the table by the ruleset (the same as Apply() but for any table).
+// If the ruleset is nil - the table is removed.
+func ApplyTable(table string, rs *Ruleset) error {
+	if rs != nil && rs.Table != table {
+		return fmt.Errorf("the ruleset is defined for table '%s' (expected '%s')", rs.Table, table)
+	}
+
+	msgs, err := buildBatch(table, rs)
+	if err != nil {
+		return fmt.Errorf("failed to prepare nftables transaction: %w", err)
+	}
+
+	conn, err := netlink.Dial(unix.NETLINK_NETFILTER, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open netfilter netlink socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.SendMessages(msgs); err != nil {
+		return fmt.Errorf("failed to send nftables transaction: %w", err)
+	}
+
+	// Wait for acknowledgement of each message in the batch (except the batch begin/end markers).
+	// In case of an error the kernel rejects the whole transaction.
+	if err := conn.SetReadDeadline(time.Now().Add(receiveTimeout)); err != nil {
+		return err
+	}
+	for acks, expected := 0, len(msgs)-2; acks < expected; {
+		replies, err := conn.Receive()
+		if err != nil {
+			return fmt.Errorf("nftables transaction failed: %w", err)
+		}
+		for _, r := range replies {
+			if r.Header.Type == netlink.Error {
+				acks++
+			}
+		}
+	}
+	return nil
+}
+
+// ReadCounters returns the current values of counters in the table.
+// Only the rules with a comment are processed: the comment is the key of the map
+// (the values of rules with the same comment are summed up).
+// Returns an empty map when the table does not exist.
+func ReadCounters(table string) (map[string]Counter, error) {
+	conn, err := netlink.Dial(unix.NETLINK_NETFILTER, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netfilter netlink socket: %w", err)
+	}
+	defer conn.Close()
+
+	ae := newEncoder()
+	ae.String(unix.NFTA_RULE_TABLE, table)
+	m, err := message(unix.NFT_MSG_GETRULE, netlink.Request|netlink.Dump, ae)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(receiveTimeout)); err != nil {
+		return nil, err
+	}
+	replies, err := conn.Execute(m)
+	if err != nil {
+		if errors.Is(err, syscall.ENOENT) {
+			return map[string]Counter{}, nil
+		}
+		return nil, fmt.Errorf("failed to read nftables rules: %w", err)
+	}
+
+	ret := make(map[string]Counter)
+	for _, r := range replies {
+		comment, counter, ok, err := parseRuleCounter(r.Data)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || len(comment) == 0 {
+			continue
+		}
+		c := ret[comment]
+		c.Packets += counter.Packets
+		c.Bytes += counter.Bytes
+		ret[comment] = c
+	}
+	return ret, nil
+}
+
+// parseRuleCounter parses the NFT_MSG_NEWRULE message and returns the rule comment and the counter value
+// (ok = false when the rule has no counter)
+func parseRuleCounter(data []byte) (comment string, counter Counter, ok bool, err error) {
+	if len(data) < 4 {
+		return "", Counter{}, false, fmt.Errorf("bad nftables rule message")
+	}
+	ad, err := netlink.NewAttributeDecoder(data[4:]) // skip 'nfgenmsg' header
+	if err != nil {
+		return "", Counter{}, false, err
+	}
+	ad.ByteOrder = binary.BigEndian
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_RULE_USERDATA:
+			comment = parseUserdataComment(ad.Bytes())
+		case unix.NFTA_RULE_EXPRESSIONS:
+			ad.Nested(func(lad *netlink.AttributeDecoder) error {
+				for lad.Next() {
+					lad.Nested(func(ead *netlink.AttributeDecoder) error {
+						name := ""
+						for ead.Next() {
+							switch ead.Type() {
+							case unix.NFTA_EXPR_NAME:
+								name = ead.String()
+							case unix.NFTA_EXPR_DATA:
+								if name != "counter" {
+									continue
+								}
+								ok = true
+								ead.Nested(func(cad *netlink.AttributeDecoder) error {
+									for cad.Next() {
+										switch cad.Type() {
+										case unix.NFTA_COUNTER_BYTES:
+											counter.Bytes = cad.Uint64()
+										case unix.NFTA_COUNTER_PACKETS:
+											counter.Packets = cad.Uint64()
+										}
+									}
+									return nil
+								})
+							}
+						}
+						return nil
+					})
+				}
+				return nil
+			})
+		}
+	}
+	return comment, counter, ok, ad.Err()
+}
+
+// parseUserdataComment returns the rule comment from the rule 'userdata' (TLV format)
+func parseUserdataComment(udata []byte) string {
+	for len(udata) >= 2 {
+		t, l := udata[0], int(udata[1])
+		if len(udata) < 2+l {
+			break
+		}
+		if t == udataRuleComment {
+			return strings.TrimRight(string(udata[2:2+l]), "\x00")
+		}
+		udata = udata[2+l:]
+	}
+	return ""
+}
+
+// buildBatch converts the ruleset to the list of netlink messages (single transaction)
+func buildBatch(table string, rs *Ruleset) ([]netlink.Message, error) {
+	msgs := []netlink.Message{batchMessage(unix.NFNL_MSG_BATCH_BEGIN)}
+
+	add := func(msgType uint16, flags netlink.HeaderFlags, ae *netlink.AttributeEncoder) error {
+		m, err := message(msgType, flags|netlink.Request|netlink.Acknowledge, ae)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, m)
+		return nil
+	}
+	tableAttrs := func() *netlink.AttributeEncoder {
+		ae := newEncoder()
+		ae.String(unix.NFTA_TABLE_NAME, table)
+		return ae
+	}
+
+	// Remove the table (if exists).
+	// Creating the table before removing it: it avoids an error when the table does not exist
+	if err := add(unix.NFT_MSG_NEWTABLE, netlink.Create, tableAttrs()); err != nil {
+		return nil, err
+	}
+	if err := add(unix.NFT_MSG_DELTABLE, 0, tableAttrs()); err != nil {
+		return nil, err
+	}
+
+	if rs != nil {
+		if err := add(unix.NFT_MSG_NEWTABLE, netlink.Create, tableAttrs()); err != nil {
+			return nil, err
+		}
+
+		// all chains must be created before the rules (the rules can jump to any chain)
+		for _, c := range rs.Chains {
+			if err := add(unix.NFT_MSG_NEWCHAIN, netlink.Create, chainAttrs(rs.Table, c)); err != nil {
+				return nil, err
+			}
+		}
+		for _, c := range rs.Chains {
+			for _, r := range c.Rules {
+				ae, err := ruleAttrs(rs.Table, c.Name, r)
+				if err != nil {
+					return nil, fmt.Errorf("chain '%s', rule '%s': %w", c.Name, r.String(), err)
+				}
+				if err := add(unix.NFT_MSG_NEWRULE, netlink.Create|netlink.Append, ae); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return append(msgs, batchMessage(unix.NFNL_MSG_BATCH_END)), nil
+}
+
+func chainAttrs(table string, c Chain) *netlink.AttributeEncoder {
+	ae := newEncoder()
+	ae.String(unix.NFTA_CHAIN_TABLE, table)
+	ae.String(unix.NFTA_CHAIN_NAME, c.Name)
+	if c.Hook == HookNone {
+		return ae
+	}
+
+	hookNum := uint32(unix.NF_INET_LOCAL_IN)
+	switch c.Hook {
+	case HookOutput:
+		hookNum = unix.NF_INET_LOCAL_OUT
+	case HookForward:
+		hookNum = unix.NF_INET_FORWARD
+	case HookPrerouting:
+		hookNum = unix.NF_INET_PRE_ROUTING
+	case HookPostrouting:
+		hookNum = unix.NF_INET_POST_ROUTING
+	}
+	ae.Nested(unix.NFTA_CHAIN_HOOK, func(nae *netlink.AttributeEncoder) error {
+		nae.Uint32(unix.NFTA_HOOK_HOOKNUM, hookNum)
+		nae.Uint32(unix.NFTA_HOOK_PRIORITY, uint32(c.Priority))
+		return nil
+	})
+	policy := uint32(nfAccept)
+	if c.Policy == VerdictDrop {
+		policy = nfDrop
+	}
+	ae.Uint32(unix.NFTA_CHAIN_POLICY, policy)
+	ae.String(unix.NFTA_CHAIN_TYPE, c.Type.String())
+	return ae
+}
+
+func ruleAttrs(table, chain string, r Rule) (*netlink.AttributeEncoder, error) {
+	exprs := make([]expr, 0, len(r.Matches)*3+len(r.Statements)*2+1)
+	for _, m := range r.Matches {
+		e, err := matchExprs(m)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e...)
+	}
+	for _, st := range r.Statements {
+		e, err := statementExprs(st)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e...)
+	}
+	if r.Verdict != VerdictNone {
+		exprs = append(exprs, verdictExpr(r.Verdict, r.Target))
+	}
+
+	ae := newEncoder()
+	ae.String(unix.NFTA_RULE_TABLE, table)
+	ae.String(unix.NFTA_RULE_CHAIN, chain)
+	ae.Nested(unix.NFTA_RULE_EXPRESSIONS, func(nae *netlink.AttributeEncoder) error {
+		for _, e := range exprs {
+			e := e
+			nae.Nested(unix.NFTA_LIST_ELEM, func(eae *netlink.AttributeEncoder) error {
+				eae.String(unix.NFTA_EXPR_NAME, e.name)
+				eae.Nested(unix.NFTA_EXPR_DATA, e.data)
+				return nil
+			})
+		}
+		return nil
+	})
+	if len(r.Comment) > 0 {
+		comment := append([]byte(r.Comment), 0)
+		if len(comment) > 0xff {
+			return nil, fmt.Errorf("comment is too long")
+		}
+		ae.Bytes(unix.NFTA_RULE_USERDATA, append([]byte{udataRuleComment, byte(len(comment))}, comment...))
+	}
+	return ae, nil
+}
+
+//---------------------------------------------------------------------
+// expressions
+
+type expr struct {
+	name string
+	data func(ae *netlink.AttributeEncoder) error
+}
+
+func matchExprs(m Match) ([]expr, error) {
+	cmpOp := uint32(unix.NFT_CMP_EQ)
+	if m.Negate {
+		cmpOp = unix.NFT_CMP_NEQ
+	}
+
+	switch m.Type {
+	case MatchIIFName, MatchOIFName:
+		if len(m.Name) == 0 || len(m.Name) >= ifNameSize {
+			return nil, fmt.Errorf("bad interface name '%s'", m.Name)
+		}
+		key := uint32(unix.NFT_META_IIFNAME)
+		if m.Type == MatchOIFName {
+			key = unix.NFT_META_OIFNAME
+		}
+		name := make([]byte, ifNameSize)
+		copy(name, m.Name)
+		return []expr{metaExpr(key), cmpExpr(cmpOp, name)}, nil
+
+	case MatchNfProto:
+		return []expr{metaExpr(unix.NFT_META_NFPROTO), cmpExpr(cmpOp, []byte{byte(m.Value)})}, nil
+
+	case MatchL4Proto:
+		return []expr{metaExpr(unix.NFT_META_L4PROTO), cmpExpr(cmpOp, []byte{byte(m.Value)})}, nil
+
+	case MatchSAddr, MatchDAddr:
+		ip := m.Net.IP.To4()
+		offset := uint32(12) // IPv4 source address
+		if m.Type == MatchDAddr {
+			offset = 16
+		}
+		if ip == nil {
+			ip = m.Net.IP.To16()
+			offset = 8 // IPv6 source address
+			if m.Type == MatchDAddr {
+				offset = 24
+			}
+		}
+		if ip == nil || len(m.Net.Mask) != len(ip) {
+			return nil, fmt.Errorf("bad address '%s'", m.Net.String())
+		}
+		ret := []expr{payloadExpr(unix.NFT_PAYLOAD_NETWORK_HEADER, offset, uint32(len(ip)))}
+		if ones, bits := m.Net.Mask.Size(); ones != bits {
+			ret = append(ret, bitwiseExpr([]byte(m.Net.Mask)))
+		}
+		return append(ret, cmpExpr(cmpOp, []byte(ip.Mask(m.Net.Mask)))), nil
+
+	case MatchSPort, MatchDPort:
+		offset := uint32(0) // source port
+		if m.Type == MatchDPort {
+			offset = 2
+		}
+		port := make([]byte, 2)
+		binary.BigEndian.PutUint16(port, uint16(m.Value))
+		if m.ValueTo > m.Value {
+			// port range: (port >= Value) && (port <= ValueTo)
+			if m.Negate {
+				return nil, fmt.Errorf("negation of port range is not supported")
+			}
+			portTo := make([]byte, 2)
+			binary.BigEndian.PutUint16(portTo, uint16(m.ValueTo))
+			return []expr{payloadExpr(unix.NFT_PAYLOAD_TRANSPORT_HEADER, offset, 2),
+				cmpExpr(unix.NFT_CMP_GTE, port),
+				cmpExpr(unix.NFT_CMP_LTE, portTo)}, nil
+		}
+		return []expr{payloadExpr(unix.NFT_PAYLOAD_TRANSPORT_HEADER, offset, 2), cmpExpr(cmpOp, port)}, nil
+
+	case MatchIcmpType:
+		return []expr{payloadExpr(unix.NFT_PAYLOAD_TRANSPORT_HEADER, 0, 1), cmpExpr(cmpOp, []byte{byte(m.Value)})}, nil
+
+	case MatchCtState:
+		// the state matches when any of the bits is set: (ct_state & mask) != 0
+		op := uint32(unix.NFT_CMP_NEQ)
+		if m.Negate {
+			op = unix.NFT_CMP_EQ
+		}
+		return []expr{ctExpr(unix.NFT_CT_STATE), bitwiseExpr(nativeUint32(m.Value)), cmpExpr(op, nativeUint32(0))}, nil
+
+	case MatchMark:
+		return []expr{metaExpr(unix.NFT_META_MARK), cmpExpr(cmpOp, nativeUint32(m.Value))}, nil
+
+	case MatchCgroup:
+		return []expr{metaExpr(unix.NFT_META_CGROUP), cmpExpr(cmpOp, nativeUint32(m.Value))}, nil
+
+	case MatchCtMark:
+		return []expr{ctExpr(unix.NFT_CT_MARK), cmpExpr(cmpOp, nativeUint32(m.Value))}, nil
+
+	case MatchSocketCgroupV2:
+		if m.CgroupID == 0 {
+			return nil, fmt.Errorf("cgroup ID is not defined for '%s'", m.Name)
+		}
+		id := make([]byte, 8)
+		binary.NativeEndian.PutUint64(id, m.CgroupID)
+		return []expr{socketCgroupV2Expr(m.Value), cmpExpr(cmpOp, id)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported match type %d", m.Type)
+}
+
+func statementExprs(st Statement) ([]expr, error) {
+	switch st.Type {
+	case StatementSetMark:
+		return []expr{immediateExpr(nativeUint32(st.Value)), metaSetExpr(unix.NFT_META_MARK)}, nil
+
+	case StatementSetCtMark:
+		return []expr{immediateExpr(nativeUint32(st.Value)), ctSetExpr(unix.NFT_CT_MARK)}, nil
+
+	case StatementMasquerade:
+		return []expr{{name: "masq", data: func(ae *netlink.AttributeEncoder) error { return nil }}}, nil
+
+	case StatementCounter:
+		return []expr{{name: "counter", data: func(ae *netlink.AttributeEncoder) error {
+			ae.Uint64(unix.NFTA_COUNTER_BYTES, st.Counter.Bytes)
+			ae.Uint64(unix.NFTA_COUNTER_PACKETS, st.Counter.Packets)
+			return nil
+		}}}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported statement type %d", st.Type)
+}
+
+func metaExpr(key uint32) expr {
+	return expr{name: "meta", data: func(ae *netlink.AttributeEncoder) error {
+		ae.Uint32(unix.NFTA_META_KEY, key)
+		ae.Uint32(unix.NFTA_META_DREG, unix.NFT_REG_1)
+		return nil
+	}}
+}
+
+func ctExpr(key uint32) expr {
+	return expr{name: "ct", data: func(ae *netlink.AttributeEncoder) error {
+		ae.Uint32(unix.NFTA_CT_KEY, key)
+		ae.Uint32(unix.NFTA_CT_DREG, unix.NFT_REG_1)
+		return nil
+	}}
+}
+
+// metaSetExpr: meta[key] = reg1
+func metaSetExpr(key uint32) expr {
+	return expr{name: "meta", data: func(ae *netlink.AttributeEncoder) error {
+		ae.Uint32(unix.NFTA_META_KEY, key)
+		ae.Uint32(unix.NFTA_META_SREG, unix.NFT_REG_1)
+		return nil
+	}}
+}
+
+// ctSetExpr: ct[key] = reg1
+func ctSetExpr(key uint32) expr {
+	return expr{name: "ct", data: func(ae *netlink.AttributeEncoder) error {
+		ae.Uint32(unix.NFTA_CT_KEY, key)
+		ae.Uint32(unix.NFTA_CT_SREG, unix.NFT_REG_1)
+		return nil
+	}}
+}
+
+// immediateExpr: reg1 = data
+func immediateExpr(data []byte) expr {
+	return expr{name: "immediate", data: func(ae *netlink.AttributeEncoder) error {
+		ae.Uint32(unix.NFTA_IMMEDIATE_DREG, unix.NFT_REG_1)
+		ae.Nested(unix.NFTA_IMMEDIATE_DATA, dataValue(data))
+		return nil
+	}}
+}
+
+// socketCgroupV2Expr: reg1 = ID of the socket cgroup ancestor on the specified level
+// (the expression is breaking the rule when the packet has no local socket)
+func socketCgroupV2Expr(level uint32) expr {
+	return expr{name: "socket", data: func(ae *netlink.AttributeEncoder) error {
+		ae.Uint32(nftaSocketKey, nftSocketCgroupV2)
+		ae.Uint32(nftaSocketDreg, unix.NFT_REG_1)
+		ae.Uint32(nftaSocketLevel, level)
+		return nil
+	}}
+}
+
+func payloadExpr(base, offset, length uint32) expr {
+	return expr{name: "payload", data: func(ae *netlink.AttributeEncoder) error {
+		ae.Uint32(unix.NFTA_PAYLOAD_DREG, unix.NFT_REG_1)
+		ae.Uint32(unix.NFTA_PAYLOAD_BASE, base)
+		ae.Uint32(unix.NFTA_PAYLOAD_OFFSET, offset)
+		ae.Uint32(unix.NFTA_PAYLOAD_LEN, length)
+		return nil
+	}}
+}
+
+// bitwiseExpr: reg1 = reg1 & mask
+func bitwiseExpr(mask []byte) expr {
+	return expr{name: "bitwise", data: func(ae *netlink.AttributeEncoder) error {
+		ae.Uint32(unix.NFTA_BITWISE_SREG, unix.NFT_REG_1)
+		ae.Uint32(unix.NFTA_BITWISE_DREG, unix.NFT_REG_1)
+		ae.Uint32(unix.NFTA_BITWISE_LEN, uint32(len(mask)))
+		ae.Nested(unix.NFTA_BITWISE_MASK, dataValue(mask))
+		ae.Nested(unix.NFTA_BITWISE_XOR, dataValue(make([]byte, len(mask))))
+		return nil
+	}}
+}
+
+func cmpExpr(op uint32, data []byte) expr {
+	return expr{name: "cmp", data: func(ae *netlink.AttributeEncoder) error {
+		ae.Uint32(unix.NFTA_CMP_SREG, unix.NFT_REG_1)
+		ae.Uint32(unix.NFTA_CMP_OP, op)
+		ae.Nested(unix.NFTA_CMP_DATA, dataValue(data))
+		return nil
+	}}
+}
+
+func verdictExpr(v Verdict, target string) expr {
+	code := int32(nfAccept)
+	switch v {
+	case VerdictDrop:
+		code = nfDrop
+	case VerdictJump:
+		code = unix.NFT_JUMP
+	}
+	return expr{name: "immediate", data: func(ae *netlink.AttributeEncoder) error {
+		ae.Uint32(unix.NFTA_IMMEDIATE_DREG, unix.NFT_REG_VERDICT)
+		ae.Nested(unix.NFTA_IMMEDIATE_DATA, func(dae *netlink.AttributeEncoder) error {
+			dae.Nested(unix.NFTA_DATA_VERDICT, func(vae *netlink.AttributeEncoder) error {
+				vae.Int32(unix.NFTA_VERDICT_CODE, code)
+				if v == VerdictJump {
+					vae.String(unix.NFTA_VERDICT_CHAIN, target)
+				}
+				return nil
+			})
+			return nil
+		})
+		return nil
+	}}
+}
+
+func dataValue(b []byte) func(ae *netlink.AttributeEncoder) error {
+	return func(ae *netlink.AttributeEncoder) error {
+		ae.Bytes(unix.NFTA_DATA_VALUE, b)
+		return nil
+	}
+}
+
+// nativeUint32 - some values (e.g. mark, cgroup, ct state) are compared in the host byte order
+func nativeUint32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.NativeEndian.PutUint32(b, v)
+	return b
+}
+
+//---------------------------------------------------------------------
+// netlink messages
+
+func newEncoder() *netlink.AttributeEncoder {
+	ae := netlink.NewAttributeEncoder()
+	ae.ByteOrder = binary.BigEndian // nf_tables attributes are in network byte order
+	return ae
+}
+
+// nfgenmsg - header of the netfilter netlink message
+func nfgenmsg(family uint8, resID uint16) []byte {
+	b := []byte{family, unix.NFNETLINK_V0, 0, 0}
+	binary.BigEndian.PutUint16(b[2:], resID)
+	return b
+}
+
+func message(msgType uint16, flags netlink.HeaderFlags, ae *netlink.AttributeEncoder) (netlink.Message, error) {
+	data, err := ae.Encode()
+	if err != nil {
+		return netlink.Message{}, err
+	}
+	return netlink.Message{
+		Header: netlink.Header{
+			Type:  netlink.HeaderType(unix.NFNL_SUBSYS_NFTABLES<<8 | msgType),
+			Flags: flags,
+		},
+		Data: append(nfgenmsg(unix.NFPROTO_INET, 0), data...),
+	}, nil
+}
+
+func batchMessage(msgType uint16) netlink.Message {
+	return netlink.Message{
+		Header: netlink.Header{
+			Type:  netlink.HeaderType(msgType),
+			Flags: netlink.Request,
+		},
+		Data: nfgenmsg(unix.AF_UNSPEC, unix.NFNL_SUBSYS_NFTABLES),
+	}
+}
+
+func execute(conn *netlink.Conn, msgType uint16, ae *netlink.AttributeEncoder) ([]netlink.Message, error) {
+	m, err := message(msgType, netlink.Request|netlink.Acknowledge, ae)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(receiveTimeout)); err != nil {
+		return nil, err
+	}
+	return conn.Execute(m)
+}
@@ -0,0 +1,218 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package nftables_test
+
+import (
+	"net"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/service/firewall/nftables"
+	"golang.org/x/sys/unix"
+)
+
+// inNetworkNamespace runs the function in a new (temporary) network namespace.
+// It avoids any changes of the firewall configuration of the host.
+// The test is skipped if it is not possible to create the namespace (e.g. not enough privileges).
+func inNetworkNamespace(t *testing.T, f func()) {
+	if os.Geteuid() != 0 {
+		t.Skip("root privileges are required")
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		// The thread is not unlocked intentionally: it is destroyed when the goroutine exits
+		// (the network namespace of the thread is changed)
+		runtime.LockOSThread()
+		if err := unix.Unshare(unix.CLONE_NEWNET); err != nil {
+			errCh <- err
+			return
+		}
+		f()
+		errCh <- nil
+	}()
+
+	if err := <-errCh; err != nil {
+		t.Skip("unable to create network namespace: ", err)
+	}
+}
+
+func TestApplyInNetworkNamespace(t *testing.T) {
+	inNetworkNamespace(t, func() {
+		if err := nftables.CheckAvailable(); err != nil {
+			t.Error("nftables is not available: ", err)
+			return
+		}
+
+		rs := nftables.Build(nftables.Config{
+			IsEnabled:        true,
+			VpnInterface:     "wgivpn",
+			VpnServer:        &nftables.Endpoint{IP: net.ParseIP("203.0.113.10"), Port: 443, IsTCP: true},
+			DnsIP:            net.ParseIP("10.0.254.1"),
+			Exceptions:       []net.IPNet{{IP: net.ParseIP("198.51.100.1").To4(), Mask: net.CIDRMask(32, 32)}},
+			StaticExceptions: []net.IPNet{{IP: net.ParseIP("192.168.0.0").To4(), Mask: net.CIDRMask(16, 32)}},
+			UserExceptions: []nftables.UserException{
+				{Net: net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(32, 128)}},
+				{Net: net.IPNet{IP: net.ParseIP("10.0.0.5").To4(), Mask: net.CIDRMask(32, 32)}, Protocol: nftables.ProtoTCP, PortFrom: 8000, PortTo: 8080, Direction: nftables.DirectionOut},
+			},
+			IcmpExceptions: []net.IP{net.ParseIP("203.0.113.20")},
+		})
+
+		// apply twice: the second transaction must replace the table content
+		for i := 0; i < 2; i++ {
+			if err := nftables.Apply(rs); err != nil {
+				t.Error("failed to apply ruleset: ", err)
+				return
+			}
+		}
+		if enabled, err := nftables.IsEnabled(); err != nil || !enabled {
+			t.Errorf("expected enabled firewall (err: %v)", err)
+		}
+
+		// 'single DNS' mode: the firewall base chains must not exist
+		if err := nftables.Apply(nftables.Build(nftables.Config{SingleDnsIP: net.ParseIP("10.0.254.1")})); err != nil {
+			t.Error("failed to apply 'single DNS' ruleset: ", err)
+		}
+		if enabled, err := nftables.IsEnabled(); err != nil || enabled {
+			t.Errorf("expected disabled firewall (err: %v)", err)
+		}
+
+		// remove table
+		for i := 0; i < 2; i++ {
+			if err := nftables.Apply(nil); err != nil {
+				t.Error("failed to remove table: ", err)
+			}
+		}
+		if enabled, err := nftables.IsEnabled(); err != nil || enabled {
+			t.Errorf("expected disabled firewall (err: %v)", err)
+		}
+	})
+}
+
+func TestApplyTableInNetworkNamespace(t *testing.T) {
+	inNetworkNamespace(t, func() {
+		const table = "ivpn_test"
+		mark := nftables.Match{Type: nftables.MatchMark, Value: 0xca6c}
+		rs := &nftables.Ruleset{Table: table, Chains: []nftables.Chain{
+			{Name: "output", Hook: nftables.HookOutput, Type: nftables.ChainTypeRoute, Priority: -150, Rules: []nftables.Rule{{
+				Matches:    []nftables.Match{{Type: nftables.MatchSocketCgroupV2, Name: "test", Value: 1, CgroupID: 1234}},
+				Statements: []nftables.Statement{{Type: nftables.StatementSetMark, Value: 0xca6c}}}}},
+			{Name: "postrouting", Hook: nftables.HookPostrouting, Priority: -150, Rules: []nftables.Rule{{
+				Matches:    []nftables.Match{mark},
+				Statements: []nftables.Statement{{Type: nftables.StatementSetCtMark, Value: 0xca6c}}}}},
+			{Name: "prerouting", Hook: nftables.HookPrerouting, Priority: -150, Rules: []nftables.Rule{{
+				Matches:    []nftables.Match{{Type: nftables.MatchCtMark, Value: 0xca6c}},
+				Statements: []nftables.Statement{{Type: nftables.StatementSetMark, Value: 0xca6c}}}}},
+			{Name: "nat", Hook: nftables.HookPostrouting, Type: nftables.ChainTypeNat, Priority: 100, Rules: []nftables.Rule{{
+				Matches:    []nftables.Match{mark, {Type: nftables.MatchOIFName, Name: "eth0"}},
+				Statements: []nftables.Statement{{Type: nftables.StatementMasquerade}}}}},
+		}}
+
+		if err := nftables.ApplyTable(table, rs); err != nil {
+			t.Error("failed to apply ruleset: ", err)
+			return
+		}
+		if exists, err := nftables.IsTableExists(table); err != nil || !exists {
+			t.Errorf("expected existing table (err: %v)", err)
+		}
+		// the IVPN firewall table is not affected
+		if enabled, err := nftables.IsEnabled(); err != nil || enabled {
+			t.Errorf("expected disabled firewall (err: %v)", err)
+		}
+
+		if err := nftables.ApplyTable(table, nil); err != nil {
+			t.Error("failed to remove table: ", err)
+		}
+		if exists, err := nftables.IsTableExists(table); err != nil || exists {
+			t.Errorf("expected removed table (err: %v)", err)
+		}
+	})
+}
+
+func TestCountersInNetworkNamespace(t *testing.T) {
+	inNetworkNamespace(t, func() {
+		const table = "ivpn_test"
+		counter := func(initial nftables.Counter) []nftables.Statement {
+			return []nftables.Statement{{Type: nftables.StatementCounter, Counter: initial}}
+		}
+		rs := &nftables.Ruleset{Table: table, Chains: []nftables.Chain{
+			{Name: "output", Hook: nftables.HookOutput, Priority: 10, Rules: []nftables.Rule{
+				{Matches: []nftables.Match{{Type: nftables.MatchOIFName, Name: "lo"}}, Statements: counter(nftables.Counter{Packets: 10, Bytes: 1000}), Comment: "lo"},
+				{Matches: []nftables.Match{{Type: nftables.MatchSocketCgroupV2, Name: "test/app", Value: 2, CgroupID: 1234}}, Statements: counter(nftables.Counter{}), Comment: "app"},
+			}},
+			{Name: "input", Hook: nftables.HookInput, Priority: 0, Rules: []nftables.Rule{
+				{Matches: []nftables.Match{{Type: nftables.MatchSocketCgroupV2, Name: "test/app", Value: 2, CgroupID: 1234}}, Statements: counter(nftables.Counter{}), Comment: "app"},
+			}},
+		}}
+		if err := nftables.ApplyTable(table, rs); err != nil {
+			t.Error("failed to apply ruleset: ", err)
+			return
+		}
+		defer nftables.ApplyTable(table, nil)
+
+		// generate traffic on the loopback interface
+		if err := loopbackUp(); err != nil {
+			t.Skip("unable to configure loopback interface: ", err)
+		}
+		if conn, err := net.Dial("udp", "127.0.0.1:9"); err == nil {
+			conn.Write([]byte("test"))
+			conn.Close()
+		}
+
+		counters, err := nftables.ReadCounters(table)
+		if err != nil {
+			t.Error("failed to read counters: ", err)
+			return
+		}
+		if c := counters["lo"]; c.Packets < 11 || c.Bytes < 1000+32 {
+			t.Errorf("unexpected counter value: %+v", c)
+		}
+		if c, ok := counters["app"]; !ok || c.Packets != 0 {
+			t.Errorf("unexpected counter value: %+v (exists: %v)", c, ok)
+		}
+
+		if counters, err := nftables.ReadCounters("ivpn_not_exists"); err != nil || len(counters) != 0 {
+			t.Errorf("unexpected result for not existing table: %v, %v", counters, err)
+		}
+	})
+}
+
+// loopbackUp sets 'up' state of the loopback interface (it is 'down' in a new network namespace)
+func loopbackUp() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	ifr, err := unix.NewIfreq("lo")
+	if err != nil {
+		return err
+	}
+	ifr.SetUint16(unix.IFF_UP | unix.IFF_LOOPBACK | unix.IFF_RUNNING)
+	return unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifr)
+}
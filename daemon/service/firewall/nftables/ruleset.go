@@ -0,0 +1,379 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package nftables
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Verdict - the action to be performed for a packet matching a rule
+type Verdict int
+
+const (
+	VerdictNone Verdict = iota // continue processing (or the chain policy)
+	VerdictAccept
+	VerdictDrop
+	VerdictJump
+)
+
+// Hook - netfilter hook the base chain is attached to
+type Hook int
+
+const (
+	HookNone Hook = iota // regular chain (used as a jump target)
+	HookInput
+	HookOutput
+	HookForward
+	HookPrerouting
+	HookPostrouting
+)
+
+// ChainType - type of the base chain
+type ChainType int
+
+const (
+	ChainTypeFilter ChainType = iota // packets filtering
+	ChainTypeRoute                   // (only 'output' hook) the packet is re-routed when its mark or address was changed
+	ChainTypeNat                     // only the first packet of a connection is passing the chain
+)
+
+// MatchType - type of the packet property to compare
+type MatchType int
+
+const (
+	MatchIIFName        MatchType = iota // input interface name (Match.Name)
+	MatchOIFName                         // output interface name (Match.Name)
+	MatchNfProto                         // layer 3 protocol family (Match.Value: NfProtoIPv4 or NfProtoIPv6)
+	MatchL4Proto                         // layer 4 protocol (Match.Value: ProtoTCP, ProtoUDP, ...)
+	MatchSAddr                           // source address (Match.Net)
+	MatchDAddr                           // destination address (Match.Net)
+	MatchSPort                           // transport header source port (Match.Value; port range: Match.Value-Match.ValueTo)
+	MatchDPort                           // transport header destination port (Match.Value; port range: Match.Value-Match.ValueTo)
+	MatchIcmpType                        // ICMP type (Match.Value)
+	MatchCtState                         // conntrack state bitmask (Match.Value: CtState... flags)
+	MatchMark                            // packet mark (Match.Value)
+	MatchCgroup                          // net_cls cgroup class ID (Match.Value)
+	MatchCtMark                          // conntrack mark (Match.Value)
+	MatchSocketCgroupV2                  // cgroup v2 of the local socket (Match.CgroupID; Match.Value - level of the cgroup in hierarchy; Match.Name - cgroup path, informational)
+)
+
+// Values for MatchNfProto
+const (
+	NfProtoIPv4 = 2
+	NfProtoIPv6 = 10
+)
+
+// Values for MatchL4Proto
+const (
+	ProtoICMP   = 1
+	ProtoTCP    = 6
+	ProtoUDP    = 17
+	ProtoICMPv6 = 58
+)
+
+// Values for MatchIcmpType
+const (
+	IcmpEchoReply   = 0
+	IcmpEchoRequest = 8
+)
+
+// Flags for MatchCtState
+const (
+	CtStateEstablished = 1 << 1
+	CtStateRelated     = 1 << 2
+	CtStateNew         = 1 << 3
+)
+
+// Match - single comparison of a packet property.
+// The rule is matching the packet only when all its matches are true.
+type Match struct {
+	Type   MatchType
+	Negate bool
+	Name   string
+	Net    net.IPNet
+	Value  uint32
+	// ValueTo - the upper bound of the range [Value, ValueTo] (applicable for ports; 0 - no range)
+	ValueTo uint32
+	// CgroupID - cgroup v2 ID (inode number of the cgroup directory)
+	CgroupID uint64
+}
+
+// StatementType - type of the action performed on a packet
+type StatementType int
+
+const (
+	StatementSetMark    StatementType = iota // set packet mark (Statement.Value)
+	StatementSetCtMark                       // set conntrack mark (Statement.Value)
+	StatementMasquerade                      // replace the source address by the address of the output interface (only in 'nat' chains)
+	StatementCounter                         // count packets and bytes (Statement.Counter - initial values)
+)
+
+// Statement - action performed on a packet matching a rule (before the verdict)
+type Statement struct {
+	Type    StatementType
+	Value   uint32
+	Counter Counter
+}
+
+// Counter - number of packets and bytes processed by the rule
+type Counter struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// Rule - list of matches, statements and the verdict
+type Rule struct {
+	Matches    []Match
+	Statements []Statement
+	Verdict    Verdict
+	Target     string // chain name (applicable for VerdictJump)
+	Comment    string
+}
+
+// Chain - list of rules.
+// The chain with a hook defined is the 'base' chain: it is receiving packets from the network stack.
+type Chain struct {
+	Name     string
+	Hook     Hook
+	Type     ChainType // applicable only for base chains
+	Priority int32
+	Policy   Verdict // applicable only for base chains: VerdictAccept or VerdictDrop
+	Rules    []Rule
+}
+
+// Ruleset - the complete content of the table
+type Ruleset struct {
+	Table  string
+	Chains []Chain
+}
+
+// Chain returns chain by name (nil if not exists)
+func (rs *Ruleset) Chain(name string) *Chain {
+	for i := range rs.Chains {
+		if rs.Chains[i].Name == name {
+			return &rs.Chains[i]
+		}
+	}
+	return nil
+}
+
+// String returns the ruleset in the 'nft' syntax (as it is accepted by 'nft -f')
+func (rs *Ruleset) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "table inet %s {\n", rs.Table)
+	for i, c := range rs.Chains {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "\tchain %s {\n", c.Name)
+		if c.Hook != HookNone {
+			fmt.Fprintf(&b, "\t\ttype %s hook %s priority %d; policy %s;\n", c.Type, c.Hook, c.Priority, c.Policy)
+		}
+		for _, r := range c.Rules {
+			fmt.Fprintf(&b, "\t\t%s\n", r.String())
+		}
+		b.WriteString("\t}\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (r Rule) String() string {
+	parts := make([]string, 0, len(r.Matches)+len(r.Statements)+2)
+	for _, m := range r.Matches {
+		parts = append(parts, m.String())
+	}
+	for _, st := range r.Statements {
+		parts = append(parts, st.String())
+	}
+	switch r.Verdict {
+	case VerdictJump:
+		parts = append(parts, "jump "+r.Target)
+	case VerdictAccept, VerdictDrop:
+		parts = append(parts, r.Verdict.String())
+	}
+	if len(r.Comment) > 0 {
+		parts = append(parts, fmt.Sprintf("comment %q", r.Comment))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (m Match) String() string {
+	op := ""
+	if m.Negate {
+		op = "!= "
+	}
+
+	switch m.Type {
+	case MatchIIFName:
+		return fmt.Sprintf("iifname %s%q", op, m.Name)
+	case MatchOIFName:
+		return fmt.Sprintf("oifname %s%q", op, m.Name)
+	case MatchNfProto:
+		return fmt.Sprintf("meta nfproto %s%s", op, nfProtoName(m.Value))
+	case MatchL4Proto:
+		return fmt.Sprintf("meta l4proto %s%s", op, l4ProtoName(m.Value))
+	case MatchSAddr, MatchDAddr:
+		family := "ip"
+		if m.Net.IP.To4() == nil {
+			family = "ip6"
+		}
+		dir := "saddr"
+		if m.Type == MatchDAddr {
+			dir = "daddr"
+		}
+		return fmt.Sprintf("%s %s %s%s", family, dir, op, netString(m.Net))
+	case MatchSPort, MatchDPort:
+		dir := "sport"
+		if m.Type == MatchDPort {
+			dir = "dport"
+		}
+		if m.ValueTo > m.Value {
+			return fmt.Sprintf("th %s %s%d-%d", dir, op, m.Value, m.ValueTo)
+		}
+		return fmt.Sprintf("th %s %s%d", dir, op, m.Value)
+	case MatchIcmpType:
+		return fmt.Sprintf("icmp type %s%s", op, icmpTypeName(m.Value))
+	case MatchCtState:
+		return fmt.Sprintf("ct state %s%s", op, ctStateNames(m.Value))
+	case MatchMark:
+		return fmt.Sprintf("meta mark %s0x%x", op, m.Value)
+	case MatchCgroup:
+		return fmt.Sprintf("meta cgroup %s0x%x", op, m.Value)
+	case MatchCtMark:
+		return fmt.Sprintf("ct mark %s0x%x", op, m.Value)
+	case MatchSocketCgroupV2:
+		return fmt.Sprintf("socket cgroupv2 level %d %s%q", m.Value, op, m.Name)
+	}
+	return fmt.Sprintf("<unknown match %d>", m.Type)
+}
+
+func (st Statement) String() string {
+	switch st.Type {
+	case StatementSetMark:
+		return fmt.Sprintf("meta mark set 0x%x", st.Value)
+	case StatementSetCtMark:
+		return fmt.Sprintf("ct mark set 0x%x", st.Value)
+	case StatementMasquerade:
+		return "masquerade"
+	case StatementCounter:
+		return fmt.Sprintf("counter packets %d bytes %d", st.Counter.Packets, st.Counter.Bytes)
+	}
+	return fmt.Sprintf("<unknown statement %d>", st.Type)
+}
+
+func (v Verdict) String() string {
+	switch v {
+	case VerdictAccept:
+		return "accept"
+	case VerdictDrop:
+		return "drop"
+	case VerdictJump:
+		return "jump"
+	}
+	return ""
+}
+
+func (h Hook) String() string {
+	switch h {
+	case HookInput:
+		return "input"
+	case HookOutput:
+		return "output"
+	case HookForward:
+		return "forward"
+	case HookPrerouting:
+		return "prerouting"
+	case HookPostrouting:
+		return "postrouting"
+	}
+	return ""
+}
+
+func (t ChainType) String() string {
+	switch t {
+	case ChainTypeRoute:
+		return "route"
+	case ChainTypeNat:
+		return "nat"
+	}
+	return "filter"
+}
+
+// netString returns the address in a short form: the prefix length is omitted for single host
+func netString(n net.IPNet) string {
+	if ones, bits := n.Mask.Size(); ones == bits {
+		return n.IP.String()
+	}
+	return n.String()
+}
+
+func nfProtoName(v uint32) string {
+	switch v {
+	case NfProtoIPv4:
+		return "ipv4"
+	case NfProtoIPv6:
+		return "ipv6"
+	}
+	return fmt.Sprint(v)
+}
+
+func l4ProtoName(v uint32) string {
+	switch v {
+	case ProtoICMP:
+		return "icmp"
+	case ProtoTCP:
+		return "tcp"
+	case ProtoUDP:
+		return "udp"
+	case ProtoICMPv6:
+		return "ipv6-icmp"
+	}
+	return fmt.Sprint(v)
+}
+
+func icmpTypeName(v uint32) string {
+	switch v {
+	case IcmpEchoReply:
+		return "echo-reply"
+	case IcmpEchoRequest:
+		return "echo-request"
+	}
+	return fmt.Sprint(v)
+}
+
+func ctStateNames(v uint32) string {
+	names := make([]string, 0, 3)
+	if v&CtStateNew != 0 {
+		names = append(names, "new")
+	}
+	if v&CtStateEstablished != 0 {
+		names = append(names, "established")
+	}
+	if v&CtStateRelated != 0 {
+		names = append(names, "related")
+	}
+	return strings.Join(names, ",")
+}
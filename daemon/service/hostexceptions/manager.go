@@ -0,0 +1,321 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// Package hostexceptions keeps the firewall exceptions defined by hostnames up to date:
+// the hostnames are periodically resolved (honoring TTL of the DNS records) and
+// the resolved addresses are allowed by the firewall.
+package hostexceptions
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/logger"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
+)
+
+var log *logger.Logger
+
+func init() {
+	log = logger.NewLogger("fwhost")
+}
+
+const (
+	// MinRefreshInterval - the minimal interval between resolving of the same host (applied also to short TTL values)
+	MinRefreshInterval = 30 * time.Second
+	// MaxRefreshInterval - the maximal interval between resolving of the same host (applied also to long TTL values)
+	MaxRefreshInterval = time.Hour
+	// RetryInterval - the interval before the next attempt to resolve the host after failure
+	RetryInterval = 30 * time.Second
+)
+
+// ResolveFunc - resolves the hostname
+type ResolveFunc func(hostname string) ([]Address, error)
+
+// ApplyFunc - sets the full list of the addresses to be allowed
+// (the list replaces the previously applied one; the caller keeps it separately from the other firewall exceptions)
+type ApplyFunc func(ips []net.IP) error
+
+type hostState struct {
+	// resolved addresses: key - IP address; value - expiration time
+	addresses    map[string]time.Time
+	lastResolved time.Time
+	nextRefresh  time.Time
+	lastErr      error
+}
+
+// Manager keeps the resolved addresses of the hostname exceptions allowed by the firewall.
+//
+// An address is removed from the firewall exceptions only when it was not returned by the DNS server
+// and its TTL expired. When the host can not be resolved - the known addresses are kept
+// (the connectivity to the host is not broken by temporary DNS problems).
+type Manager struct {
+	mutex sync.Mutex
+	// serializes resolving (the 'mutex' is not locked while resolving)
+	refreshMutex sync.Mutex
+
+	resolve   ResolveFunc
+	apply     ApplyFunc
+	onChanged func()
+	now       func() time.Time
+
+	hosts map[string]*hostState
+	// addresses which are currently allowed in the firewall
+	allowed map[string]net.IP
+
+	wakeup chan struct{}
+}
+
+// NewManager creates the Manager.
+// apply (optional) is called with the full list of the allowed addresses when the list is changed;
+// onChanged (optional) is called when the set of allowed addresses is changed.
+func NewManager(resolve ResolveFunc, apply ApplyFunc, onChanged func()) *Manager {
+	return &Manager{
+		resolve:   resolve,
+		apply:     apply,
+		onChanged: onChanged,
+		now:       time.Now,
+		hosts:     make(map[string]*hostState),
+		allowed:   make(map[string]net.IP),
+		wakeup:    make(chan struct{}, 1),
+	}
+}
+
+// SetHosts sets the list of hostnames.
+// The addresses of the removed hosts are removed from the firewall exceptions immediately;
+// the new hosts will be resolved in the background.
+func (m *Manager) SetHosts(hostnames []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	newHosts := make(map[string]*hostState, len(hostnames))
+	for _, h := range hostnames {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if len(h) == 0 {
+			continue
+		}
+		if s, ok := m.hosts[h]; ok {
+			newHosts[h] = s
+		} else {
+			newHosts[h] = &hostState{addresses: make(map[string]time.Time)}
+		}
+	}
+	m.hosts = newHosts
+
+	m.applyChanges()
+	m.notifyWakeup()
+}
+
+// RefreshNow initiates resolving all hosts (e.g. when the DNS configuration was changed)
+func (m *Manager) RefreshNow() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, s := range m.hosts {
+		s.nextRefresh = time.Time{}
+	}
+	m.notifyWakeup()
+}
+
+// Run processes the hosts resolving. The function never returns.
+func (m *Manager) Run() {
+	for {
+		next := m.refresh()
+
+		wait := MaxRefreshInterval
+		if !next.IsZero() {
+			wait = next.Sub(m.now())
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-m.wakeup:
+			timer.Stop()
+		}
+	}
+}
+
+// Status returns the state of all hostname exceptions (sorted by hostname)
+func (m *Manager) Status() []service_types.FirewallHostExceptionStatus {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	ret := make([]service_types.FirewallHostExceptionStatus, 0, len(m.hosts))
+	for h, s := range m.hosts {
+		st := service_types.FirewallHostExceptionStatus{
+			Hostname:     h,
+			LastResolved: s.lastResolved,
+			NextRefresh:  s.nextRefresh,
+		}
+		for ip := range s.addresses {
+			st.IPs = append(st.IPs, ip)
+		}
+		sort.Strings(st.IPs)
+		if s.lastErr != nil {
+			st.Error = s.lastErr.Error()
+		}
+		ret = append(ret, st)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Hostname < ret[j].Hostname })
+	return ret
+}
+
+// Addresses returns the currently allowed addresses of all hosts
+func (m *Manager) Addresses() []net.IP {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	ret := make([]net.IP, 0, len(m.allowed))
+	for _, ip := range m.allowed {
+		ret = append(ret, ip)
+	}
+	return ret
+}
+
+// refresh resolves all hosts which are due for refresh.
+// Returns the time of the next required refresh (zero - no hosts defined).
+func (m *Manager) refresh() (next time.Time) {
+	m.refreshMutex.Lock()
+	defer m.refreshMutex.Unlock()
+
+	now := m.now()
+
+	// get the list of hosts to be resolved
+	m.mutex.Lock()
+	var due []string
+	for h, s := range m.hosts {
+		if !s.nextRefresh.After(now) {
+			due = append(due, h)
+		}
+	}
+	m.mutex.Unlock()
+
+	type result struct {
+		addrs []Address
+		err   error
+	}
+	results := make(map[string]result, len(due))
+	for _, h := range due {
+		addrs, err := m.resolve(h)
+		results[h] = result{addrs: addrs, err: err}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for h, r := range results {
+		s, ok := m.hosts[h]
+		if !ok {
+			continue // the host was removed while resolving
+		}
+
+		if r.err != nil {
+			s.lastErr = r.err
+			s.nextRefresh = now.Add(RetryInterval)
+			continue
+		}
+
+		s.lastErr = nil
+		s.lastResolved = now
+		for _, a := range r.addrs {
+			s.addresses[a.IP.String()] = now.Add(clampDuration(a.TTL, MinRefreshInterval, MaxRefreshInterval))
+		}
+		// remove stale addresses (not returned by DNS server and expired)
+		nextRefresh := now.Add(MaxRefreshInterval)
+		for ip, expiration := range s.addresses {
+			if !expiration.After(now) {
+				delete(s.addresses, ip)
+				continue
+			}
+			if expiration.Before(nextRefresh) {
+				nextRefresh = expiration
+			}
+		}
+		s.nextRefresh = nextRefresh
+	}
+
+	m.applyChanges()
+
+	for _, s := range m.hosts {
+		if next.IsZero() || s.nextRefresh.Before(next) {
+			next = s.nextRefresh
+		}
+	}
+	return next
+}
+
+// applyChanges updates the firewall exceptions according to the resolved addresses of all hosts
+// (must be called under locked mutex)
+func (m *Manager) applyChanges() {
+	required := make(map[string]net.IP)
+	for _, s := range m.hosts {
+		for ip := range s.addresses {
+			required[ip] = net.ParseIP(ip)
+		}
+	}
+
+	isChanged := len(required) != len(m.allowed)
+	for ip := range required {
+		if _, ok := m.allowed[ip]; !ok {
+			isChanged = true
+			break
+		}
+	}
+	if !isChanged {
+		return
+	}
+
+	m.allowed = required
+	if m.apply != nil {
+		ips := make([]net.IP, 0, len(required))
+		for _, addr := range required {
+			ips = append(ips, addr)
+		}
+		if err := m.apply(ips); err != nil {
+			log.Error("failed to apply hostname exceptions: ", err)
+		}
+	}
+
+	if m.onChanged != nil {
+		go m.onChanged()
+	}
+}
+
+func (m *Manager) notifyWakeup() {
+	select {
+	case m.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func clampDuration(d, min, max time.Duration) time.Duration {
+	if d < min {
+		return min
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
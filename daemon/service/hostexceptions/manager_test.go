@@ -0,0 +1,181 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package hostexceptions
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type testFirewall struct {
+	allowed map[string]struct{}
+}
+
+func (f *testFirewall) set(ips []net.IP) error {
+	f.allowed = make(map[string]struct{}, len(ips))
+	for _, ip := range ips {
+		f.allowed[ip.String()] = struct{}{}
+	}
+	return nil
+}
+
+func (f *testFirewall) list() string {
+	ret := make([]string, 0, len(f.allowed))
+	for ip := range f.allowed {
+		ret = append(ret, ip)
+	}
+	sort.Strings(ret)
+	return fmt.Sprint(ret)
+}
+
+func TestManagerRefresh(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	answers := map[string][]Address{
+		"a.example.com": {{IP: net.ParseIP("192.0.2.1"), TTL: 60 * time.Second}, {IP: net.ParseIP("192.0.2.2"), TTL: 60 * time.Second}},
+		"b.example.com": {{IP: net.ParseIP("198.51.100.1"), TTL: 2 * time.Hour}},
+	}
+	var resolveErr error
+
+	fw := &testFirewall{allowed: make(map[string]struct{})}
+	m := NewManager(func(h string) ([]Address, error) {
+		if resolveErr != nil {
+			return nil, resolveErr
+		}
+		return answers[h], nil
+	}, fw.set, nil)
+	m.now = func() time.Time { return now }
+
+	m.SetHosts([]string{"a.example.com", "B.example.com"})
+	next := m.refresh()
+	if got := fw.list(); got != "[192.0.2.1 192.0.2.2 198.51.100.1]" {
+		t.Fatalf("unexpected allowed addresses: %s", got)
+	}
+	if !next.Equal(now.Add(60 * time.Second)) {
+		t.Errorf("unexpected next refresh time: %v", next)
+	}
+	for _, st := range m.Status() {
+		if st.Hostname == "b.example.com" && !st.NextRefresh.Equal(now.Add(MaxRefreshInterval)) {
+			t.Errorf("long TTL must be limited by MaxRefreshInterval: %v", st.NextRefresh)
+		}
+	}
+
+	// the address is not returned anymore: it must be kept until its TTL expired
+	answers["a.example.com"] = []Address{{IP: net.ParseIP("192.0.2.1"), TTL: 300 * time.Second}}
+	now = now.Add(30 * time.Second)
+	m.RefreshNow()
+	m.refresh()
+	if got := fw.list(); got != "[192.0.2.1 192.0.2.2 198.51.100.1]" {
+		t.Fatalf("unexpected allowed addresses: %s", got)
+	}
+	now = now.Add(31 * time.Second)
+	m.refresh()
+	if got := fw.list(); got != "[192.0.2.1 198.51.100.1]" {
+		t.Fatalf("stale address was not removed: %s", got)
+	}
+
+	// resolving error: known addresses are kept
+	resolveErr = fmt.Errorf("DNS timeout")
+	now = now.Add(time.Hour)
+	next = m.refresh()
+	if got := fw.list(); got != "[192.0.2.1 198.51.100.1]" {
+		t.Fatalf("addresses must be kept on resolving error: %s", got)
+	}
+	if !next.Equal(now.Add(RetryInterval)) {
+		t.Errorf("unexpected retry time: %v", next)
+	}
+	if st := m.Status(); len(st) != 2 || st[0].Error == "" {
+		t.Errorf("error is not reported in status: %+v", st)
+	}
+
+	// host removed: its addresses are removed immediately
+	m.SetHosts([]string{"b.example.com"})
+	if got := fw.list(); got != "[198.51.100.1]" {
+		t.Fatalf("unexpected allowed addresses after host removal: %s", got)
+	}
+	m.SetHosts(nil)
+	if got := fw.list(); got != "[]" {
+		t.Fatalf("unexpected allowed addresses: %s", got)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	srv, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("unable to listen UDP: ", err)
+	}
+	defer srv.Close()
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := srv.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var req dnsmessage.Message
+			if err := req.Unpack(buf[:n]); err != nil {
+				continue
+			}
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: req.Header.ID, Response: true},
+				Questions: req.Questions,
+			}
+			q := req.Questions[0]
+			if q.Type == dnsmessage.TypeA {
+				resp.Answers = []dnsmessage.Resource{
+					{Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET, TTL: 10},
+						Body: &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("cdn.example.net.")}},
+					{Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("cdn.example.net."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 120},
+						Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 10}}},
+				}
+			}
+			data, _ := resp.Pack()
+			srv.WriteTo(data, addr)
+		}
+	}()
+
+	conn, err := net.Dial("udp", srv.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	addrs, err := query(conn, "git.example.com", dnsmessage.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || !addrs[0].IP.Equal(net.ParseIP("192.0.2.10")) || addrs[0].TTL != 120*time.Second {
+		t.Errorf("unexpected result: %+v", addrs)
+	}
+
+	addrs, err = query(conn, "git.example.com", dnsmessage.TypeAAAA)
+	if err != nil || len(addrs) != 0 {
+		t.Errorf("unexpected result: %+v (%v)", addrs, err)
+	}
+}
@@ -0,0 +1,149 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package hostexceptions
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DefaultTTL - TTL of the addresses resolved by the system resolver (it does not provide TTL information)
+const DefaultTTL = 5 * time.Minute
+
+// Address - resolved IP address and TTL of the DNS record
+type Address struct {
+	IP  net.IP
+	TTL time.Duration
+}
+
+// Resolve resolves IPv4 and IPv6 addresses of the host.
+// If dnsServer is defined - the DNS request is sent directly to this server (UDP, port 53) and the TTL
+// of the DNS records is returned; otherwise - the system resolver is in use (TTL = DefaultTTL).
+func Resolve(hostname string, dnsServer net.IP, timeout time.Duration) ([]Address, error) {
+	if dnsServer == nil {
+		return resolveSystem(hostname, timeout)
+	}
+
+	conn, err := net.DialTimeout("udp", net.JoinHostPort(dnsServer.String(), "53"), timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to DNS server %s: %w", dnsServer, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	var ret []Address
+	var retErr error
+	for _, qType := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		addrs, err := query(conn, hostname, qType)
+		if err != nil {
+			retErr = err
+			continue
+		}
+		ret = append(ret, addrs...)
+	}
+	if len(ret) == 0 {
+		if retErr == nil {
+			retErr = fmt.Errorf("no addresses found for '%s'", hostname)
+		}
+		return nil, retErr
+	}
+	return ret, nil
+}
+
+func resolveSystem(hostname string, timeout time.Duration) ([]Address, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]Address, 0, len(addrs))
+	for _, a := range addrs {
+		ret = append(ret, Address{IP: a.IP, TTL: DefaultTTL})
+	}
+	return ret, nil
+}
+
+func query(conn net.Conn, hostname string, qType dnsmessage.Type) ([]Address, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(hostname, ".") + ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, err
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qType, Class: dnsmessage.ClassINET}},
+	}
+	req, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(req); err != nil {
+		return nil, fmt.Errorf("failed to send DNS request: %w", err)
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to receive DNS response: %w", err)
+		}
+
+		var resp dnsmessage.Message
+		if err := resp.Unpack(buf[:n]); err != nil || resp.Header.ID != id || !resp.Header.Response {
+			continue // not a response to our request
+		}
+		if resp.Header.RCode != dnsmessage.RCodeSuccess {
+			return nil, fmt.Errorf("DNS request for '%s' failed: %s", hostname, resp.Header.RCode)
+		}
+		return parseAnswers(resp.Answers), nil
+	}
+}
+
+func parseAnswers(answers []dnsmessage.Resource) []Address {
+	var ret []Address
+	for _, a := range answers {
+		ttl := time.Duration(a.Header.TTL) * time.Second
+		switch r := a.Body.(type) {
+		case *dnsmessage.AResource:
+			ret = append(ret, Address{IP: net.IP(r.A[:]), TTL: ttl})
+		case *dnsmessage.AAAAResource:
+			ret = append(ret, Address{IP: net.IP(r.AAAA[:]), TTL: ttl})
+		}
+	}
+	return ret
+}
@@ -45,6 +45,9 @@ var (
 	serversFile     string
 	logFile         string
 
+	// serviceSocketFile path to the unix domain socket file (empty when not supported by the platform)
+	serviceSocketFile string
+
 	openVpnBinaryPath     string
 	openvpnCaKeyFile      string
 	openvpnTaKeyFile      string
@@ -251,11 +254,22 @@ func SettingsFile() string {
 	return settingsFile
 }
 
+// SecretsKeyFile path to a file which contains the key to encrypt sensitive data stored in the settings file
+// This file should be accessible only for 'privilaged' user
+func SecretsKeyFile() string {
+	return settingsFile + ".key"
+}
+
 // ServicePortFile path to service port file
 func ServicePortFile() string {
 	return servicePortFile
 }
 
+// ServiceSocketFile path to the unix domain socket file (empty when not supported by the platform)
+func ServiceSocketFile() string {
+	return serviceSocketFile
+}
+
 // ParanoidModeSecretFile path to a file which contains 'secret' (password) for 'Paranoid mode'
 // If 'paranoid mode' enabled - this 'secret' must be used in each request to a daemon.
 // This file should be accessible to read only for 'privilaged' user
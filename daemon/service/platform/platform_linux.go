@@ -141,6 +141,7 @@ func doInitConstants() {
 
 	serversFile = path.Join(tmpDir, "servers.json")
 	servicePortFile = path.Join(tmpDir, "port.txt")
+	serviceSocketFile = path.Join(tmpDir, "ivpn.sock")
 	paranoidModeSecretFile = path.Join(tmpDir, "eaa")
 
 	logFile = path.Join(logDir, "IVPN_Agent.log")
@@ -0,0 +1,62 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import (
+	"fmt"
+	"strings"
+
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
+)
+
+// ConnectionProfile - named set of connection parameters
+type ConnectionProfile struct {
+	Name   string                         `json:"name"`
+	Params service_types.ConnectionParams `json:"params"`
+}
+
+// Validate checks if the profile can be saved
+func (cp ConnectionProfile) Validate() error {
+	if len(strings.TrimSpace(cp.Name)) == 0 {
+		return fmt.Errorf("connection profile name is not defined")
+	}
+	if err := cp.Params.CheckIsDefined(); err != nil {
+		return fmt.Errorf("connection profile '%s': %w", cp.Name, err)
+	}
+	return nil
+}
+
+// IsNameEqual returns 'true' if the profile has the given name (case-insensitive)
+func (cp ConnectionProfile) IsNameEqual(name string) bool {
+	return strings.EqualFold(strings.TrimSpace(cp.Name), strings.TrimSpace(name))
+}
+
+// ConnectionProfileGet returns connection profile by name (case-insensitive)
+func (p *Preferences) ConnectionProfileGet(name string) (profile ConnectionProfile, ok bool) {
+	for _, cp := range p.ConnectionProfiles {
+		if cp.IsNameEqual(name) {
+			return cp, true
+		}
+	}
+	return profile, false
+}
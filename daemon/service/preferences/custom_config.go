@@ -0,0 +1,121 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ivpn/desktop-app/daemon/helpers"
+	"github.com/ivpn/desktop-app/daemon/service/platform"
+)
+
+// SetWireGuardCustomConfig saves the custom WireGuard configuration in encrypted form
+// (empty string - remove configuration)
+func (p *Preferences) SetWireGuardCustomConfig(config string) error {
+	encrypted, err := encryptSecret(config)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt custom WireGuard configuration: %w", err)
+	}
+	p.WireGuardCustomConfigEncrypted = encrypted
+	return nil
+}
+
+// GetWireGuardCustomConfig returns the custom WireGuard configuration (empty string - configuration not defined)
+func (p *Preferences) GetWireGuardCustomConfig() (string, error) {
+	config, err := decryptSecret(p.WireGuardCustomConfigEncrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt custom WireGuard configuration: %w", err)
+	}
+	return config, nil
+}
+
+// SetOpenVpnCustomConfig saves the custom OpenVPN configuration ('.ovpn' profile) in encrypted form
+// (empty string - remove configuration)
+func (p *Preferences) SetOpenVpnCustomConfig(config string) error {
+	encrypted, err := encryptSecret(config)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt custom OpenVPN configuration: %w", err)
+	}
+	p.OpenVpnCustomConfigEncrypted = encrypted
+	return nil
+}
+
+// GetOpenVpnCustomConfig returns the custom OpenVPN configuration (empty string - configuration not defined)
+func (p *Preferences) GetOpenVpnCustomConfig() (string, error) {
+	config, err := decryptSecret(p.OpenVpnCustomConfigEncrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt custom OpenVPN configuration: %w", err)
+	}
+	return config, nil
+}
+
+func encryptSecret(text string) (string, error) {
+	if len(text) == 0 {
+		return "", nil
+	}
+	key, err := secretsKey()
+	if err != nil {
+		return "", err
+	}
+	return helpers.EncryptString(key, text)
+}
+
+func decryptSecret(encrypted string) (string, error) {
+	if len(encrypted) == 0 {
+		return "", nil
+	}
+	key, err := secretsKey()
+	if err != nil {
+		return "", err
+	}
+	return helpers.DecryptString(key, encrypted)
+}
+
+// secretsKey returns the key to encrypt sensitive data stored in preferences.
+// The key is generated on first use and saved into a separate file (accessible only for privileged user),
+// so the settings file itself does not contain the data in plain text.
+func secretsKey() ([]byte, error) {
+	const keyLen = 32 // AES-256
+
+	keyFile := platform.SecretsKeyFile()
+	if data, err := os.ReadFile(keyFile); err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err == nil && len(key) == keyLen {
+			return key, nil
+		}
+		log.Warning(fmt.Sprintf("bad secrets key file '%s'. Generating new key (the data encrypted by the old key will not be available)", keyFile))
+	}
+
+	key := make([]byte, keyLen)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate secrets key: %w", err)
+	}
+	if err := helpers.WriteFile(keyFile, []byte(hex.EncodeToString(key)), 0600); err != nil { // read\write only for privileged user
+		return nil, fmt.Errorf("failed to save secrets key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,136 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import (
+	"fmt"
+	"strings"
+
+	api_types "github.com/ivpn/desktop-app/daemon/api/types"
+)
+
+// MaxHostsSelectionItems - max number of pinned (or blacklisted) hosts
+const MaxHostsSelectionItems = 256
+
+// HostsSelectionParams - restrictions of the server hosts in use for connections (host names, e.g. "nl-ams-wg-001").
+// By default, a random host of the server is in use for each connection.
+//   - Pinned: when the server contains pinned hosts - only the pinned hosts are in use for connections to this server
+//   - Blacklisted: hosts which must not be in use (servers with all hosts blacklisted are skipped by automatic server selection)
+type HostsSelectionParams struct {
+	Pinned      []string `json:",omitempty"`
+	Blacklisted []string `json:",omitempty"`
+}
+
+type hostInfo interface {
+	GetHostInfoBase() api_types.HostInfoBase
+}
+
+func normalizeHostname(hostname string) string {
+	return strings.ToLower(strings.TrimSpace(hostname))
+}
+
+func containsHostname(list []string, hostname string) bool {
+	hostname = normalizeHostname(hostname)
+	for _, h := range list {
+		if normalizeHostname(h) == hostname {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks parameters
+func (p HostsSelectionParams) Validate() error {
+	if len(p.Pinned) > MaxHostsSelectionItems || len(p.Blacklisted) > MaxHostsSelectionItems {
+		return fmt.Errorf("too many hosts (max %d)", MaxHostsSelectionItems)
+	}
+	for _, h := range append(append([]string{}, p.Pinned...), p.Blacklisted...) {
+		if len(normalizeHostname(h)) == 0 {
+			return fmt.Errorf("host name is empty")
+		}
+	}
+	for _, h := range p.Pinned {
+		if containsHostname(p.Blacklisted, h) {
+			return fmt.Errorf("host '%s' can not be pinned and blacklisted at the same time", h)
+		}
+	}
+	return nil
+}
+
+// IsEmpty returns true when no restrictions defined
+func (p HostsSelectionParams) IsEmpty() bool {
+	return len(p.Pinned) == 0 && len(p.Blacklisted) == 0
+}
+
+// IsPinned returns true when the host is pinned
+func (p HostsSelectionParams) IsPinned(hostname string) bool {
+	return containsHostname(p.Pinned, hostname)
+}
+
+// IsBlacklisted returns true when the host is blacklisted
+func (p HostsSelectionParams) IsBlacklisted(hostname string) bool {
+	return containsHostname(p.Blacklisted, hostname)
+}
+
+// ApplyHostsSelection returns the server hosts which are allowed to use for connection:
+// blacklisted hosts are removed; if the server contains pinned hosts - only pinned hosts are returned.
+// Returns error when no hosts left.
+func ApplyHostsSelection[H hostInfo](p HostsSelectionParams, hosts []H) ([]H, error) {
+	if p.IsEmpty() || len(hosts) == 0 {
+		return hosts, nil
+	}
+
+	allowed := make([]H, 0, len(hosts))
+	pinned := make([]H, 0, len(hosts))
+	for _, h := range hosts {
+		hostname := h.GetHostInfoBase().Hostname
+		if p.IsBlacklisted(hostname) {
+			continue
+		}
+		allowed = append(allowed, h)
+		if p.IsPinned(hostname) {
+			pinned = append(pinned, h)
+		}
+	}
+
+	if len(pinned) > 0 {
+		return pinned, nil
+	}
+	if len(allowed) == 0 {
+		return nil, fmt.Errorf("all hosts of the server are blacklisted")
+	}
+	return allowed, nil
+}
+
+// IsServerAllowed returns false when all the server hosts are blacklisted
+func (p HostsSelectionParams) IsServerAllowed(hosts []api_types.HostInfoBase) bool {
+	if len(p.Blacklisted) == 0 {
+		return true
+	}
+	for _, h := range hosts {
+		if !p.IsBlacklisted(h.Hostname) {
+			return true
+		}
+	}
+	return false
+}
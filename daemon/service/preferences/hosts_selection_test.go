@@ -0,0 +1,91 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences_test
+
+import (
+	"testing"
+
+	api_types "github.com/ivpn/desktop-app/daemon/api/types"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+)
+
+func wgHosts(names ...string) []api_types.WireGuardServerHostInfo {
+	ret := make([]api_types.WireGuardServerHostInfo, 0, len(names))
+	for _, n := range names {
+		h := api_types.WireGuardServerHostInfo{}
+		h.Hostname = n
+		ret = append(ret, h)
+	}
+	return ret
+}
+
+func TestApplyHostsSelection(t *testing.T) {
+	hosts := wgHosts("nl1", "nl2", "nl3")
+
+	// no restrictions
+	ret, err := preferences.ApplyHostsSelection(preferences.HostsSelectionParams{}, hosts)
+	if err != nil || len(ret) != 3 {
+		t.Fatalf("all hosts expected: %v %v", ret, err)
+	}
+
+	// blacklist
+	p := preferences.HostsSelectionParams{Blacklisted: []string{"NL2"}}
+	ret, err = preferences.ApplyHostsSelection(p, hosts)
+	if err != nil || len(ret) != 2 || ret[0].Hostname != "nl1" || ret[1].Hostname != "nl3" {
+		t.Fatalf("blacklisted host must be removed: %v %v", ret, err)
+	}
+
+	// pinned host has priority
+	p = preferences.HostsSelectionParams{Pinned: []string{"nl3", "de1"}, Blacklisted: []string{"nl1"}}
+	ret, err = preferences.ApplyHostsSelection(p, hosts)
+	if err != nil || len(ret) != 1 || ret[0].Hostname != "nl3" {
+		t.Fatalf("only pinned host expected: %v %v", ret, err)
+	}
+
+	// pinned host of another server does not affect the server
+	p = preferences.HostsSelectionParams{Pinned: []string{"de1"}}
+	if ret, _ = preferences.ApplyHostsSelection(p, hosts); len(ret) != 3 {
+		t.Fatalf("all hosts expected")
+	}
+
+	// all hosts blacklisted
+	p = preferences.HostsSelectionParams{Blacklisted: []string{"nl1", "nl2", "nl3"}}
+	if _, err = preferences.ApplyHostsSelection(p, hosts); err == nil {
+		t.Fatalf("error expected when all hosts blacklisted")
+	}
+	if p.IsServerAllowed([]api_types.HostInfoBase{{Hostname: "nl1"}, {Hostname: "nl2"}}) {
+		t.Fatalf("server with all hosts blacklisted must not be allowed")
+	}
+}
+
+func TestHostsSelectionValidate(t *testing.T) {
+	if err := (preferences.HostsSelectionParams{Pinned: []string{"nl1"}, Blacklisted: []string{"NL1"}}).Validate(); err == nil {
+		t.Fatalf("error expected: host pinned and blacklisted")
+	}
+	if err := (preferences.HostsSelectionParams{Pinned: []string{" "}}).Validate(); err == nil {
+		t.Fatalf("error expected: empty host name")
+	}
+	if err := (preferences.HostsSelectionParams{Pinned: []string{"nl1"}, Blacklisted: []string{"nl2"}}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,111 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// NetworkTrustRule - trust rule for a network identified by the default-route parameters
+// (wired/Ethernet networks, docking stations etc.).
+// All defined (non-empty) conditions must match the current network.
+type NetworkTrustRule struct {
+	Name      string `json:"name"` // user-defined rule name
+	IsTrusted bool   `json:"isTrusted"`
+
+	// Conditions (empty - not in use)
+	InterfaceName string `json:"interfaceName,omitempty"` // name of the default-route interface (e.g. 'enp0s31f6')
+	GatewayIP     string `json:"gatewayIP,omitempty"`     // IP address of the default gateway
+	GatewayMAC    string `json:"gatewayMAC,omitempty"`    // hardware address of the default gateway
+	DhcpDomain    string `json:"dhcpDomain,omitempty"`    // domain name received by DHCP (e.g. 'office.example.com')
+
+	// (optional) Rule-specific parameters. When defined - they are overriding common 'Actions'
+	Rule *WiFiNetworkRule `json:"rule,omitempty"`
+}
+
+// NetworkTrustParams - trust rules for networks identified by the default route.
+// The trusted/untrusted actions are common with the WiFi control ('WiFiParams.Actions').
+// WiFi rules have priority: network rules are applied only when the current WiFi network has no action.
+type NetworkTrustParams struct {
+	IsEnabled                 bool               `json:"isEnabled"`
+	DefaultTrustStatusTrusted *bool              `json:"defaultTrustStatusTrusted"` // nil - no trust action for networks which are not matching any rule
+	Rules                     []NetworkTrustRule `json:"rules"`
+}
+
+// Validate checks rule configuration
+func (r NetworkTrustRule) Validate() error {
+	if len(strings.TrimSpace(r.Name)) == 0 {
+		return fmt.Errorf("network rule name is not defined")
+	}
+	if len(r.InterfaceName) == 0 && len(r.GatewayIP) == 0 && len(r.GatewayMAC) == 0 && len(r.DhcpDomain) == 0 {
+		return fmt.Errorf("network rule '%s': no conditions defined", r.Name)
+	}
+	if len(r.GatewayIP) > 0 && net.ParseIP(r.GatewayIP) == nil {
+		return fmt.Errorf("network rule '%s': bad gateway IP address '%s'", r.Name, r.GatewayIP)
+	}
+	if len(r.GatewayMAC) > 0 {
+		if _, err := net.ParseMAC(r.GatewayMAC); err != nil {
+			return fmt.Errorf("network rule '%s': bad gateway hardware address '%s'", r.Name, r.GatewayMAC)
+		}
+	}
+	if r.Rule != nil && r.Rule.ConnectionParams != nil {
+		if err := r.Rule.ConnectionParams.CheckIsDefined(); err != nil {
+			return fmt.Errorf("network rule '%s': bad connection parameters: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// IsMatch returns 'true' when all defined conditions of the rule are matching the network parameters
+func (r NetworkTrustRule) IsMatch(interfaceName string, gatewayIP net.IP, gatewayMAC net.HardwareAddr, dhcpDomain string) bool {
+	if len(r.InterfaceName) > 0 && r.InterfaceName != interfaceName {
+		return false
+	}
+	if len(r.GatewayIP) > 0 {
+		if ip := net.ParseIP(r.GatewayIP); ip == nil || !ip.Equal(gatewayIP) {
+			return false
+		}
+	}
+	if len(r.GatewayMAC) > 0 {
+		if mac, err := net.ParseMAC(r.GatewayMAC); err != nil || len(gatewayMAC) == 0 || !bytes.Equal(mac, gatewayMAC) {
+			return false
+		}
+	}
+	if len(r.DhcpDomain) > 0 && !strings.EqualFold(strings.TrimSuffix(r.DhcpDomain, "."), strings.TrimSuffix(dhcpDomain, ".")) {
+		return false
+	}
+	return true
+}
+
+// FindRule returns the first rule matching the network parameters
+func (p NetworkTrustParams) FindRule(interfaceName string, gatewayIP net.IP, gatewayMAC net.HardwareAddr, dhcpDomain string) (NetworkTrustRule, bool) {
+	for _, r := range p.Rules {
+		if r.IsMatch(interfaceName, gatewayIP, gatewayMAC, dhcpDomain) {
+			return r, true
+		}
+	}
+	return NetworkTrustRule{}, false
+}
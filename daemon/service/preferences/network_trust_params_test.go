@@ -0,0 +1,61 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+)
+
+func TestNetworkTrustRuleIsMatch(t *testing.T) {
+	gwIP := net.ParseIP("192.168.1.1")
+	gwMAC, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+
+	tests := []struct {
+		rule     preferences.NetworkTrustRule
+		expected bool
+	}{
+		{preferences.NetworkTrustRule{InterfaceName: "eth0"}, true},
+		{preferences.NetworkTrustRule{InterfaceName: "eth1"}, false},
+		{preferences.NetworkTrustRule{GatewayIP: "192.168.1.1", InterfaceName: "eth0"}, true},
+		{preferences.NetworkTrustRule{GatewayIP: "192.168.1.2"}, false},
+		{preferences.NetworkTrustRule{GatewayMAC: "AA-BB-CC-DD-EE-FF"}, true},
+		{preferences.NetworkTrustRule{GatewayMAC: "aa:bb:cc:dd:ee:00"}, false},
+		{preferences.NetworkTrustRule{DhcpDomain: "Office.Example.com."}, true},
+		{preferences.NetworkTrustRule{DhcpDomain: "example.com"}, false},
+	}
+
+	for i, tc := range tests {
+		if v := tc.rule.IsMatch("eth0", gwIP, gwMAC, "office.example.com"); v != tc.expected {
+			t.Errorf("test %d: expected %v, got %v", i, tc.expected, v)
+		}
+	}
+
+	// unknown gateway MAC must not match
+	r := preferences.NetworkTrustRule{GatewayMAC: "aa:bb:cc:dd:ee:ff"}
+	if r.IsMatch("eth0", gwIP, nil, "") {
+		t.Error("rule with MAC condition must not match unknown MAC")
+	}
+}
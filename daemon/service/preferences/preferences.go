@@ -54,10 +54,39 @@ const (
 	DefaultWGKeysInterval = time.Hour * 24 * 1
 )
 
+// LinuxFirewallBackend - implementation of the firewall on Linux
+type LinuxFirewallBackend string
+
+const (
+	// LinuxFirewallBackendScript - the firewall script (based on iptables). Default.
+	LinuxFirewallBackendScript LinuxFirewallBackend = ""
+	// LinuxFirewallBackendNftables - the native nftables implementation (netlink)
+	LinuxFirewallBackendNftables LinuxFirewallBackend = "nftables"
+)
+
+// LinuxWireGuardBackend - implementation of WireGuard on Linux
+type LinuxWireGuardBackend string
+
+const (
+	// LinuxWireGuardBackendAuto - the kernel module (if available) or the embedded userspace implementation. Default.
+	LinuxWireGuardBackendAuto LinuxWireGuardBackend = ""
+	// LinuxWireGuardBackendKernel - the kernel module and 'wg-quick'
+	LinuxWireGuardBackendKernel LinuxWireGuardBackend = "kernel"
+	// LinuxWireGuardBackendUserspace - the embedded userspace implementation (wireguard-go); requires only TUN device
+	LinuxWireGuardBackendUserspace LinuxWireGuardBackend = "userspace"
+)
+
 type LinuxSpecificUserPrefs struct {
 	// If true - use old style DNS management mechanism
 	// by direct modifying file '/etc/resolv.conf'
 	IsDnsMgmtOldStyle bool
+
+	// Firewall implementation.
+	// The firewall script is in use when the native nftables backend is not available.
+	FirewallBackend LinuxFirewallBackend
+
+	// WireGuard implementation
+	WireGuardBackend LinuxWireGuardBackend
 }
 
 // UserPreferences - IVPN service preferences which can be exposed to client
@@ -82,7 +111,8 @@ type Preferences struct {
 	IsFwAllowLAN             bool
 	IsFwAllowLANMulticast    bool
 	IsFwAllowApiServers      bool
-	FwUserExceptions         string // Firewall exceptions: comma separated list of IP addresses (masks) in format: x.x.x.x[/xx]
+	FwUserExceptions         string                            // Firewall exceptions: comma separated list (string representation of 'FwUserExceptionsList'; kept for compatibility with clients)
+	FwUserExceptionsList     []service_types.FirewallException // Firewall exceptions: IP addresses or subnets (optionally restricted by protocol, ports and direction)
 	IsStopOnClientDisconnect bool
 
 	// IsAutoconnectOnLaunch: if 'true' - daemon will perform automatic connection (see 'IsAutoconnectOnLaunchDaemon' for details)
@@ -94,13 +124,18 @@ type Preferences struct {
 	//		-	after daemon initialization
 	//		-	on user session LogOn
 	IsAutoconnectOnLaunchDaemon bool
+	// AutoconnectProfile: name of the connection profile used for automatic connections
+	// (empty - use 'LastConnectionParams')
+	AutoconnectProfile string
 
 	// split-tunnelling
 	IsSplitTunnel             bool // Split Tunnel on/off
 	SplitTunnelApps           []string
-	SplitTunnelInversed       bool // Inverse Split Tunnel: only 'splitted' apps use VPN tunnel (applicable only when IsSplitTunnel=true)
-	SplitTunnelAnyDns         bool // (only for Inverse Split Tunnel) When false: Allow only DNS servers specified by the IVPN application
-	SplitTunnelAllowWhenNoVpn bool // (only for Inverse Split Tunnel) Allow connectivity for Split Tunnel apps when VPN is disabled
+	SplitTunnelInversed       bool     // Inverse Split Tunnel: only 'splitted' apps use VPN tunnel (applicable only when IsSplitTunnel=true)
+	SplitTunnelAnyDns         bool     // (only for Inverse Split Tunnel) When false: Allow only DNS servers specified by the IVPN application
+	SplitTunnelAllowWhenNoVpn bool     // (only for Inverse Split Tunnel) Allow connectivity for Split Tunnel apps when VPN is disabled
+	SplitTunnelRoutes         []string // Destination networks (CIDR) which are excluded from the VPN (or, for Inverse Split Tunnel, the only ones using it)
+	SplitTunnelDomains        []string // Destination domains; the addresses from DNS answers are processed the same way as 'SplitTunnelRoutes'
 
 	// last known account status
 	Session SessionStatus
@@ -111,6 +146,34 @@ type Preferences struct {
 
 	LastConnectionParams service_types.ConnectionParams
 	WiFiControl          WiFiParams
+
+	// named sets of connection parameters
+	ConnectionProfiles []ConnectionProfile
+
+	// trust rules for wired (non-WiFi) networks
+	NetworkTrust NetworkTrustParams
+
+	// scheduled actions (connect/disconnect, firewall ...)
+	ScheduleRules []ScheduleRule
+
+	// monitoring of the active WireGuard connection (stale handshake detection)
+	WireGuardHealthCheck WireGuardHealthCheckParams
+
+	// weights of the factors for the 'Best' server selection (default weights, if not defined)
+	ServerScoring service_types.ServerScoringWeights
+
+	// pinned and blacklisted hosts of the servers
+	HostsSelection HostsSelectionParams
+
+	// favourite servers (protocol-independent gateway IDs, e.g. "nl-ams")
+	FavouriteServers []string
+	// named groups of servers
+	ServerGroups []ServerGroup
+
+	// custom WireGuard configuration ('wg-quick' format); encrypted (see SetWireGuardCustomConfig())
+	WireGuardCustomConfigEncrypted string
+	// custom OpenVPN configuration ('.ovpn' profile); encrypted (see SetOpenVpnCustomConfig())
+	OpenVpnCustomConfigEncrypted string
 }
 
 type SessionMutableData struct {
@@ -295,6 +358,11 @@ func (p *Preferences) LoadPreferences() error {
 		}
 	}
 
+	// Firewall exceptions were stored only as a string in older versions
+	if p.FwUserExceptionsList == nil && len(p.FwUserExceptions) > 0 {
+		p.FwUserExceptionsList, _ = service_types.ParseFirewallExceptions(p.FwUserExceptions, true)
+	}
+
 	return nil
 }
 
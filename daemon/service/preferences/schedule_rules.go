@@ -0,0 +1,135 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ivpn/desktop-app/daemon/service/scheduler"
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
+)
+
+// ScheduleActionType - action performed by the scheduler rule
+type ScheduleActionType string
+
+const (
+	ScheduleAction_Connect               ScheduleActionType = "connect"
+	ScheduleAction_Disconnect            ScheduleActionType = "disconnect"
+	ScheduleAction_Resume                ScheduleActionType = "resume" // resume paused connection
+	ScheduleAction_FirewallOn            ScheduleActionType = "firewall_on"
+	ScheduleAction_FirewallOff           ScheduleActionType = "firewall_off"
+	ScheduleAction_FirewallPersistentOn  ScheduleActionType = "firewall_persistent_on"
+	ScheduleAction_FirewallPersistentOff ScheduleActionType = "firewall_persistent_off"
+)
+
+// ScheduleActions - all supported scheduler actions
+var ScheduleActions = []ScheduleActionType{
+	ScheduleAction_Connect,
+	ScheduleAction_Disconnect,
+	ScheduleAction_Resume,
+	ScheduleAction_FirewallOn,
+	ScheduleAction_FirewallOff,
+	ScheduleAction_FirewallPersistentOn,
+	ScheduleAction_FirewallPersistentOff,
+}
+
+// ScheduleRule - action performed by the daemon according to the cron-like schedule
+type ScheduleRule struct {
+	Name      string `json:"name"`
+	IsEnabled bool   `json:"isEnabled"`
+	// cron-like expression: "MINUTE HOUR DAY_OF_MONTH MONTH DAY_OF_WEEK" (e.g. "0 8 * * mon-fri")
+	// (the daemon local time is in use)
+	Schedule string             `json:"schedule"`
+	Action   ScheduleActionType `json:"action"`
+	// Name of the connection profile to use for 'connect' action (empty - use the default connection parameters)
+	ConnectionProfile string `json:"connectionProfile,omitempty"`
+	// (optional) Entry server selection for 'connect' action: Fastest/Best/Random
+	// (Default - the server defined by the connection profile or by the default connection parameters)
+	ServerSelection service_types.ServerSelectionEnum `json:"serverSelection,omitempty"`
+	// (optional) Location of the servers to choose from: country code ("NL"), country, city or gateway ID ("nl-ams").
+	// Applicable only in combination with ServerSelection (e.g. "fastest NL server")
+	ServerLocation string `json:"serverLocation,omitempty"`
+}
+
+// Validate checks if the rule can be saved
+func (r ScheduleRule) Validate() error {
+	if len(strings.TrimSpace(r.Name)) == 0 {
+		return fmt.Errorf("schedule rule name is not defined")
+	}
+	if _, err := scheduler.Parse(r.Schedule); err != nil {
+		return fmt.Errorf("schedule rule '%s': %w", r.Name, err)
+	}
+	isActionSupported := false
+	for _, a := range ScheduleActions {
+		if a == r.Action {
+			isActionSupported = true
+			break
+		}
+	}
+	if !isActionSupported {
+		return fmt.Errorf("schedule rule '%s': unsupported action '%s'", r.Name, r.Action)
+	}
+	if len(r.ConnectionProfile) > 0 && r.Action != ScheduleAction_Connect {
+		return fmt.Errorf("schedule rule '%s': connection profile is applicable only for '%s' action", r.Name, ScheduleAction_Connect)
+	}
+	switch r.ServerSelection {
+	case service_types.Default:
+		if len(r.ServerLocation) > 0 {
+			return fmt.Errorf("schedule rule '%s': server location is applicable only in combination with server selection (fastest/best/random)", r.Name)
+		}
+	case service_types.Fastest, service_types.Best, service_types.Random:
+		if r.Action != ScheduleAction_Connect {
+			return fmt.Errorf("schedule rule '%s': server selection is applicable only for '%s' action", r.Name, ScheduleAction_Connect)
+		}
+	default:
+		return fmt.Errorf("schedule rule '%s': unsupported server selection", r.Name)
+	}
+	return nil
+}
+
+// IsNameEqual returns 'true' if the rule has the given name (case-insensitive)
+func (r ScheduleRule) IsNameEqual(name string) bool {
+	return strings.EqualFold(strings.TrimSpace(r.Name), strings.TrimSpace(name))
+}
+
+// ScheduleRulesUsingProfile returns names of the scheduler rules which are using the connection profile
+func (p *Preferences) ScheduleRulesUsingProfile(profileName string) []string {
+	var ret []string
+	for _, r := range p.ScheduleRules {
+		if len(r.ConnectionProfile) > 0 && strings.EqualFold(r.ConnectionProfile, strings.TrimSpace(profileName)) {
+			ret = append(ret, r.Name)
+		}
+	}
+	return ret
+}
+
+// ScheduleRuleGet returns scheduler rule by name (case-insensitive)
+func (p *Preferences) ScheduleRuleGet(name string) (rule ScheduleRule, ok bool) {
+	for _, r := range p.ScheduleRules {
+		if r.IsNameEqual(name) {
+			return r, true
+		}
+	}
+	return rule, false
+}
@@ -0,0 +1,121 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// FavouritesGroupName - reserved name of the group which contains favourite servers
+	FavouritesGroupName = "favourites"
+	// MaxServerGroups - max number of server groups
+	MaxServerGroups = 64
+	// MaxServerGroupItems - max number of servers in a group (or in favourites)
+	MaxServerGroupItems = 256
+)
+
+// ServerGroup - named group of servers (e.g. "EU-low-latency").
+// Servers are defined by the gateway ID without protocol-specific suffix (e.g. "nl-ams"),
+// so the same group is applicable for both OpenVPN and WireGuard.
+type ServerGroup struct {
+	Name     string   `json:"name"`
+	Gateways []string `json:"gateways"`
+}
+
+// NormalizeGatewayID returns the protocol-independent gateway ID: "us-tx.wg.ivpn.net" => "us-tx"; " US-TX " => "us-tx"
+func NormalizeGatewayID(gateway string) string {
+	return strings.ToLower(strings.Split(strings.TrimSpace(gateway), ".")[0])
+}
+
+// NormalizeGateways returns the list of normalized gateway IDs (empty and duplicate items are removed)
+func NormalizeGateways(gateways []string) []string {
+	var ret []string
+	added := make(map[string]struct{}, len(gateways))
+	for _, gw := range gateways {
+		gw = NormalizeGatewayID(gw)
+		if len(gw) == 0 {
+			continue
+		}
+		if _, ok := added[gw]; ok {
+			continue
+		}
+		added[gw] = struct{}{}
+		ret = append(ret, gw)
+	}
+	return ret
+}
+
+// Normalized returns the group with trimmed name and normalized gateway IDs
+func (g ServerGroup) Normalized() ServerGroup {
+	return ServerGroup{Name: strings.TrimSpace(g.Name), Gateways: NormalizeGateways(g.Gateways)}
+}
+
+// Validate checks if the group can be saved
+func (g ServerGroup) Validate() error {
+	name := strings.TrimSpace(g.Name)
+	if len(name) == 0 {
+		return fmt.Errorf("server group name is not defined")
+	}
+	if strings.EqualFold(name, FavouritesGroupName) {
+		return fmt.Errorf("server group name '%s' is reserved", FavouritesGroupName)
+	}
+	if len(NormalizeGateways(g.Gateways)) == 0 {
+		return fmt.Errorf("server group '%s' contains no servers", name)
+	}
+	if len(g.Gateways) > MaxServerGroupItems {
+		return fmt.Errorf("server group '%s' contains too many servers (max %d)", name, MaxServerGroupItems)
+	}
+	return nil
+}
+
+// IsNameEqual returns 'true' if the group has the given name (case-insensitive)
+func (g ServerGroup) IsNameEqual(name string) bool {
+	return strings.EqualFold(strings.TrimSpace(g.Name), strings.TrimSpace(name))
+}
+
+// Contains returns 'true' if the group contains the gateway (e.g. "nl-ams" or "nl-ams.wg.ivpn.net")
+func (g ServerGroup) Contains(gateway string) bool {
+	gateway = NormalizeGatewayID(gateway)
+	for _, gw := range g.Gateways {
+		if NormalizeGatewayID(gw) == gateway {
+			return true
+		}
+	}
+	return false
+}
+
+// ServerGroupGet returns the server group by name (case-insensitive).
+// The reserved name 'FavouritesGroupName' returns the group of favourite servers.
+func (p *Preferences) ServerGroupGet(name string) (group ServerGroup, ok bool) {
+	if strings.EqualFold(strings.TrimSpace(name), FavouritesGroupName) {
+		return ServerGroup{Name: FavouritesGroupName, Gateways: p.FavouriteServers}, len(p.FavouriteServers) > 0
+	}
+	for _, g := range p.ServerGroups {
+		if g.IsNameEqual(name) {
+			return g, true
+		}
+	}
+	return group, false
+}
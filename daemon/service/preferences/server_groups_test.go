@@ -0,0 +1,79 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences_test
+
+import (
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+)
+
+func TestServerGroupNormalized(t *testing.T) {
+	g := preferences.ServerGroup{Name: " EU-low-latency ", Gateways: []string{"nl-ams.wg.ivpn.net", " NL-AMS ", "de-fra.gw.ivpn.net", ""}}.Normalized()
+	if g.Name != "EU-low-latency" {
+		t.Fatalf("unexpected name '%s'", g.Name)
+	}
+	if len(g.Gateways) != 2 || g.Gateways[0] != "nl-ams" || g.Gateways[1] != "de-fra" {
+		t.Fatalf("unexpected gateways %v", g.Gateways)
+	}
+	if !g.Contains("DE-FRA.wg.ivpn.net") || g.Contains("us-tx") {
+		t.Fatalf("Contains() failed")
+	}
+}
+
+func TestServerGroupValidate(t *testing.T) {
+	if err := (preferences.ServerGroup{Name: "eu", Gateways: []string{"nl-ams"}}).Validate(); err != nil {
+		t.Fatalf("valid group: %v", err)
+	}
+	if err := (preferences.ServerGroup{Name: " ", Gateways: []string{"nl-ams"}}).Validate(); err == nil {
+		t.Fatalf("error expected for empty name")
+	}
+	if err := (preferences.ServerGroup{Name: "Favourites", Gateways: []string{"nl-ams"}}).Validate(); err == nil {
+		t.Fatalf("error expected for reserved name")
+	}
+	if err := (preferences.ServerGroup{Name: "eu", Gateways: []string{" "}}).Validate(); err == nil {
+		t.Fatalf("error expected for group without servers")
+	}
+}
+
+func TestServerGroupGet(t *testing.T) {
+	p := preferences.Preferences{
+		FavouriteServers: []string{"us-tx"},
+		ServerGroups:     []preferences.ServerGroup{{Name: "EU", Gateways: []string{"nl-ams"}}},
+	}
+
+	if g, ok := p.ServerGroupGet("eu"); !ok || !g.Contains("nl-ams") {
+		t.Fatalf("group 'EU' expected")
+	}
+	if g, ok := p.ServerGroupGet("FAVOURITES"); !ok || !g.Contains("us-tx") {
+		t.Fatalf("favourites group expected")
+	}
+	if _, ok := p.ServerGroupGet("us"); ok {
+		t.Fatalf("group 'us' must not exist")
+	}
+
+	p.FavouriteServers = nil
+	if _, ok := p.ServerGroupGet(preferences.FavouritesGroupName); ok {
+		t.Fatalf("empty favourites must not be returned")
+	}
+}
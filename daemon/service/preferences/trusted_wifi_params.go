@@ -22,9 +22,37 @@
 
 package preferences
 
+import (
+	service_types "github.com/ivpn/desktop-app/daemon/service/types"
+)
+
 type WiFiNetwork struct {
 	SSID      string `json:"ssid"`
 	IsTrusted bool   `json:"isTrusted"`
+
+	// (optional) Network-specific parameters. When defined - they are overriding common 'Actions'
+	Rule *WiFiNetworkRule `json:"rule,omitempty"`
+}
+
+// WiFiNetworkRule - network-specific parameters applied when joining the network
+type WiFiNetworkRule struct {
+	// Connection parameters to use when connecting VPN on this network
+	// (nil - use the default connection parameters)
+	ConnectionParams *service_types.ConnectionParams `json:"connectionParams,omitempty"`
+	// Firewall policy to apply when joining this network
+	// (nil - use common 'Actions')
+	Firewall *WiFiFirewallPolicy `json:"firewall,omitempty"`
+}
+
+// WiFiFirewallPolicy - firewall state to apply when joining the network
+type WiFiFirewallPolicy struct {
+	IsEnabled bool `json:"isEnabled"`
+	BlockLan  bool `json:"blockLan"` // applicable only when IsEnabled
+}
+
+// IsEmpty returns 'true' when no network-specific parameters defined
+func (r *WiFiNetworkRule) IsEmpty() bool {
+	return r == nil || (r.ConnectionParams == nil && r.Firewall == nil)
 }
 
 type WiFiParams struct {
@@ -45,6 +73,9 @@ type WiFiParams struct {
 		UnTrustedBlockLan       bool `json:"unTrustedBlockLan"`
 		TrustedDisconnectVpn    bool `json:"trustedDisconnectVpn"`
 		TrustedDisableFirewall  bool `json:"trustedDisableFirewall"`
+		// Name of the connection profile to use when connecting VPN on untrusted network
+		// (empty - use the default connection parameters)
+		UnTrustedConnectVpnProfile string `json:"unTrustedConnectVpnProfile,omitempty"`
 	} `json:"actions"`
 }
 
@@ -0,0 +1,69 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import (
+	"fmt"
+	"time"
+)
+
+// Limits of the WireGuard handshake timeout (seconds)
+const (
+	// DefaultWireGuardHandshakeTimeoutSec - WireGuard rejects data after 180 seconds without a handshake (REJECT_AFTER_TIME)
+	DefaultWireGuardHandshakeTimeoutSec = 180
+	MinWireGuardHandshakeTimeoutSec     = 130 // must be greater than the rekey interval (120 seconds)
+	MaxWireGuardHandshakeTimeoutSec     = 60 * 60
+)
+
+// WireGuardHealthCheckParams - monitoring of the active WireGuard connection.
+// The connection is considered stale (and it is reconnected) when there were no handshakes
+// during 'HandshakeTimeoutSec' while data is being sent to the server
+// (e.g. after a NAT rebinding or server restart).
+// Default (zero) values: monitoring enabled with the default timeout.
+type WireGuardHealthCheckParams struct {
+	IsDisabled bool `json:"isDisabled"`
+	// HandshakeTimeoutSec - max age of the latest handshake (0 - default value)
+	HandshakeTimeoutSec int `json:"handshakeTimeoutSec,omitempty"`
+	// IsPingGateway - additionally, ping the internal gateway of the VPN server through the tunnel
+	// (the connection is also considered stale when the gateway does not respond during 'HandshakeTimeoutSec')
+	IsPingGateway bool `json:"isPingGateway,omitempty"`
+}
+
+// Validate checks parameters
+func (p WireGuardHealthCheckParams) Validate() error {
+	if p.HandshakeTimeoutSec == 0 {
+		return nil
+	}
+	if p.HandshakeTimeoutSec < MinWireGuardHandshakeTimeoutSec || p.HandshakeTimeoutSec > MaxWireGuardHandshakeTimeoutSec {
+		return fmt.Errorf("handshake timeout must be in range %d-%d seconds", MinWireGuardHandshakeTimeoutSec, MaxWireGuardHandshakeTimeoutSec)
+	}
+	return nil
+}
+
+// HandshakeTimeout returns max age of the latest handshake
+func (p WireGuardHealthCheckParams) HandshakeTimeout() time.Duration {
+	if p.HandshakeTimeoutSec <= 0 {
+		return DefaultWireGuardHandshakeTimeoutSec * time.Second
+	}
+	return time.Duration(p.HandshakeTimeoutSec) * time.Second
+}
@@ -0,0 +1,52 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+)
+
+func TestWireGuardHealthCheckParams(t *testing.T) {
+	var p preferences.WireGuardHealthCheckParams
+	if err := p.Validate(); err != nil {
+		t.Fatalf("default parameters must be valid: %v", err)
+	}
+	if p.HandshakeTimeout() != preferences.DefaultWireGuardHandshakeTimeoutSec*time.Second {
+		t.Errorf("unexpected default timeout: %v", p.HandshakeTimeout())
+	}
+
+	p.HandshakeTimeoutSec = 300
+	if err := p.Validate(); err != nil || p.HandshakeTimeout() != 5*time.Minute {
+		t.Errorf("unexpected result: %v (err: %v)", p.HandshakeTimeout(), err)
+	}
+
+	for _, v := range []int{-1, 60, preferences.MaxWireGuardHandshakeTimeoutSec + 1} {
+		p.HandshakeTimeoutSec = v
+		if err := p.Validate(); err == nil {
+			t.Errorf("%d: error expected", v)
+		}
+	}
+}
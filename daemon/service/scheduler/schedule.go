@@ -0,0 +1,243 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// Package scheduler contains the parser of cron-like schedule expressions
+// which are used by the daemon scheduler rules.
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule - parsed cron-like schedule expression.
+//
+// Expression format (5 fields separated by spaces):
+//
+//	MINUTE HOUR DAY_OF_MONTH MONTH DAY_OF_WEEK
+//
+// Each field can contain: '*', value ('5'), range ('1-5'), list ('1,3,5') and step ('*/15', '8-18/2').
+// Months and days of week can be defined by names ('jan'...'dec'; 'sun'...'sat').
+// Day of week: 0 or 7 - Sunday.
+// Predefined expressions: '@hourly', '@daily' ('@midnight'), '@weekly', '@monthly', '@weekdays', '@weekends'.
+//
+// Example: "0 8 * * mon-fri" - at 08:00 on every day-of-week from Monday through Friday
+type Schedule struct {
+	expression string
+
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+
+	// when both 'day of month' and 'day of week' are restricted - it is enough to match one of them (as in cron)
+	isDaysRestricted     bool
+	isWeekdaysRestricted bool
+}
+
+type fieldBounds struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	boundsMinutes  = fieldBounds{name: "minute", min: 0, max: 59}
+	boundsHours    = fieldBounds{name: "hour", min: 0, max: 23}
+	boundsDays     = fieldBounds{name: "day of month", min: 1, max: 31}
+	boundsMonths   = fieldBounds{name: "month", min: 1, max: 12, names: map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}}
+	boundsWeekdays = fieldBounds{name: "day of week", min: 0, max: 7, names: map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}}
+)
+
+var predefinedExpressions = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@weekdays": "0 0 * * 1-5",
+	"@weekends": "0 0 * * 0,6",
+}
+
+// maxSearchPeriod - the period to search for the next activation time
+const maxSearchPeriod = time.Hour * 24 * 366 * 5
+
+// Parse parses cron-like schedule expression
+func Parse(expression string) (*Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	expr := strings.ToLower(expression)
+	if predefined, ok := predefinedExpressions[expr]; ok {
+		expr = predefined
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("bad schedule expression '%s': expected 5 fields (MINUTE HOUR DAY_OF_MONTH MONTH DAY_OF_WEEK)", expression)
+	}
+
+	ret := &Schedule{expression: expression}
+	var err error
+	if ret.minutes, err = parseField(fields[0], boundsMinutes); err != nil {
+		return nil, err
+	}
+	if ret.hours, err = parseField(fields[1], boundsHours); err != nil {
+		return nil, err
+	}
+	if ret.days, err = parseField(fields[2], boundsDays); err != nil {
+		return nil, err
+	}
+	if ret.months, err = parseField(fields[3], boundsMonths); err != nil {
+		return nil, err
+	}
+	if ret.weekdays, err = parseField(fields[4], boundsWeekdays); err != nil {
+		return nil, err
+	}
+	// 7 - is Sunday
+	if ret.weekdays&(1<<7) != 0 {
+		ret.weekdays |= 1 << 0
+	}
+	ret.isDaysRestricted = fields[2] != "*"
+	ret.isWeekdaysRestricted = fields[4] != "*"
+
+	return ret, nil
+}
+
+// String returns the original schedule expression
+func (s *Schedule) String() string {
+	return s.expression
+}
+
+// IsMatch returns 'true' when the time (with minute precision) matches the schedule
+func (s *Schedule) IsMatch(t time.Time) bool {
+	return s.minutes&(1<<uint(t.Minute())) != 0 &&
+		s.hours&(1<<uint(t.Hour())) != 0 &&
+		s.months&(1<<uint(t.Month())) != 0 &&
+		s.isDayMatch(t)
+}
+
+// Next returns the next activation time after the given time
+// (zero time - when no activation time found in the reasonable period)
+func (s *Schedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearchPeriod)
+
+	for t.Before(limit) {
+		if s.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.isDayMatch(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Last returns the most recent activation time in the interval (from, to]
+// (zero time - when there are no activation times in the interval)
+func (s *Schedule) Last(from, to time.Time) time.Time {
+	var ret time.Time
+	for t := s.Next(from); !t.IsZero() && !t.After(to); t = s.Next(t) {
+		ret = t
+	}
+	return ret
+}
+
+func (s *Schedule) isDayMatch(t time.Time) bool {
+	dayMatch := s.days&(1<<uint(t.Day())) != 0
+	weekdayMatch := s.weekdays&(1<<uint(t.Weekday())) != 0
+	if s.isDaysRestricted && s.isWeekdaysRestricted {
+		return dayMatch || weekdayMatch
+	}
+	return dayMatch && weekdayMatch
+}
+
+// parseField parses one field of the expression. Returns bitset of allowed values.
+func parseField(field string, b fieldBounds) (uint64, error) {
+	var ret uint64
+	for _, item := range strings.Split(field, ",") {
+		rangeStr, stepStr, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("bad %s step '%s'", b.name, stepStr)
+			}
+		}
+
+		var from, to int
+		if rangeStr == "*" {
+			from, to = b.min, b.max
+			if b.max == 7 { // day of week: 7 is an alias for Sunday
+				to = 6
+			}
+		} else {
+			fromStr, toStr, isRange := strings.Cut(rangeStr, "-")
+			var err error
+			if from, err = parseValue(fromStr, b); err != nil {
+				return 0, err
+			}
+			to = from
+			if isRange {
+				if to, err = parseValue(toStr, b); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				to = b.max // e.g. '5/15' - from 5 to the end of range
+			}
+			if to < from {
+				return 0, fmt.Errorf("bad %s range '%s'", b.name, rangeStr)
+			}
+		}
+
+		for v := from; v <= to; v += step {
+			ret |= 1 << uint(v)
+		}
+	}
+	return ret, nil
+}
+
+func parseValue(str string, b fieldBounds) (int, error) {
+	if v, ok := b.names[str]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(str)
+	if err != nil || v < b.min || v > b.max {
+		return 0, fmt.Errorf("bad %s value '%s' (expected %d-%d)", b.name, str, b.min, b.max)
+	}
+	return v, nil
+}
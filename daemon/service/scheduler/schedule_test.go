@@ -0,0 +1,102 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/service/scheduler"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "* * * * xyz"} {
+		if _, err := scheduler.Parse(expr); err == nil {
+			t.Errorf("expected error for expression '%s'", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Friday
+	base := time.Date(2024, time.March, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, time.March, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 8 * * mon-fri", time.Date(2024, time.March, 18, 8, 0, 0, 0, time.UTC)},
+		{"0 22 * * *", time.Date(2024, time.March, 15, 22, 0, 0, 0, time.UTC)},
+		{"@midnight", time.Date(2024, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.March, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 feb *", time.Date(2028, time.February, 29, 12, 0, 0, 0, time.UTC)},
+		// day-of-month OR day-of-week (both restricted)
+		{"0 0 20 * sat", time.Date(2024, time.March, 16, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range tests {
+		s, err := scheduler.Parse(tc.expr)
+		if err != nil {
+			t.Errorf("'%s': unexpected error: %v", tc.expr, err)
+			continue
+		}
+		next := s.Next(base)
+		if !next.Equal(tc.expected) {
+			t.Errorf("'%s': expected %v, got %v", tc.expr, tc.expected, next)
+		}
+		if !s.IsMatch(next) {
+			t.Errorf("'%s': next time %v does not match schedule", tc.expr, next)
+		}
+	}
+}
+
+func TestLast(t *testing.T) {
+	from := time.Date(2024, time.March, 15, 17, 30, 0, 0, time.UTC) // Friday
+	to := time.Date(2024, time.March, 18, 9, 30, 0, 0, time.UTC)    // Monday
+
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"0 8 * * mon-fri", time.Date(2024, time.March, 18, 8, 0, 0, 0, time.UTC)},
+		{"0 18 * * mon-fri", time.Date(2024, time.March, 15, 18, 0, 0, 0, time.UTC)},
+		{"0 12 * * sat", time.Date(2024, time.March, 16, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * tue", time.Time{}},
+		{"0 18 * * *", time.Date(2024, time.March, 17, 18, 0, 0, 0, time.UTC)},
+		{"30 9 * * *", to},
+		{"30 17 * * fri", time.Time{}}, // 'from' is not included
+	}
+
+	for _, tc := range tests {
+		s, err := scheduler.Parse(tc.expr)
+		if err != nil {
+			t.Errorf("'%s': unexpected error: %v", tc.expr, err)
+			continue
+		}
+		if last := s.Last(from, to); !last.Equal(tc.expected) {
+			t.Errorf("'%s': expected %v, got %v", tc.expr, tc.expected, last)
+		}
+	}
+}
@@ -27,6 +27,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ivpn/desktop-app/daemon/api"
@@ -36,7 +38,10 @@ import (
 )
 
 type serversUpdater struct {
-	servers           *types.ServersInfoResponse
+	servers *types.ServersInfoResponse
+	// the highest timestamp of the accepted signed servers list (rollback protection; persisted in serversTimestampFile()).
+	// 0 - no signed servers list with timestamp was accepted yet (see checkServersList())
+	maxTimestamp      int64
 	api               *api.API
 	updatedNotifyChan chan struct{}
 }
@@ -44,6 +49,9 @@ type serversUpdater struct {
 // CreateServersUpdater - constructor for serversUpdater object
 func CreateServersUpdater(apiObj *api.API) (IServersUpdater, error) {
 	updater := &serversUpdater{api: apiObj}
+	if updater.isCacheEnabled() {
+		updater.maxTimestamp = readServersTimestamp()
+	}
 
 	updater.updatedNotifyChan = make(chan struct{}, 1)
 
@@ -62,7 +70,11 @@ func (s *serversUpdater) GetServers() (*types.ServersInfoResponse, error) {
 		return s.servers, nil
 	}
 
-	servers, apiIPsV4, apiIPsV6, err := readServersFromCache()
+	if !s.isCacheEnabled() {
+		return s.updateServers()
+	}
+
+	servers, apiIPsV4, apiIPsV6, err := readServersFromCache(s.api, s.maxTimestamp)
 	if err != nil {
 		log.Warning(err)
 
@@ -116,9 +128,9 @@ func (s *serversUpdater) StartUpdater() error {
 
 // UpdateServers - download servers list
 func (s *serversUpdater) updateServers() (*types.ServersInfoResponse, error) {
-	servers, err := s.api.DownloadServersList()
+	servers, signed, err := s.api.DownloadServersList()
 	if err != nil {
-		return servers, fmt.Errorf("failed to download servers list: %w", err)
+		return nil, fmt.Errorf("failed to download servers list: %w", err)
 	}
 
 	if len(servers.Config.Ports.OpenVPN) <= 0 {
@@ -128,11 +140,28 @@ func (s *serversUpdater) updateServers() (*types.ServersInfoResponse, error) {
 		return servers, fmt.Errorf("no ports info for WireGuard in servers.json; skipping received data from backend")
 	}
 
+	// rollback protection: do not accept the servers list which is older than the newest one ever accepted
+	if err := checkServersList(signed.IsSigned(), servers.Timestamp, s.maxTimestamp); err != nil {
+		return nil, fmt.Errorf("%w; skipping received data from backend", err)
+	}
+
 	log.Info(fmt.Sprintf("Updated servers info (%d OpenVPN; %d WireGuard)\n", len(servers.OpenvpnServers), len(servers.WireguardServers)))
 
 	s.servers = servers
-	if err := writeServersToCache(servers); err != nil {
-		log.Error("failed to save servers cache file: ", err)
+	// only the timestamp of the signed list is trusted
+	isNewMaxTimestamp := signed.IsSigned() && servers.Timestamp > s.maxTimestamp
+	if isNewMaxTimestamp {
+		s.maxTimestamp = servers.Timestamp
+	}
+	if s.isCacheEnabled() {
+		if err := writeServersToCache(signed); err != nil {
+			log.Error("failed to save servers cache file: ", err)
+		}
+		if isNewMaxTimestamp {
+			if err := writeServersTimestamp(servers.Timestamp); err != nil {
+				log.Error("failed to save servers list timestamp: ", err)
+			}
+		}
 	}
 
 	select {
@@ -145,12 +174,80 @@ func (s *serversUpdater) updateServers() (*types.ServersInfoResponse, error) {
 	return servers, nil
 }
 
+// checkServersList returns error if the servers list can not be accepted:
+// the list is not signed, its timestamp is not defined or the list is older than the newest one ever accepted.
+//
+// Transition path: the backends which do not publish the servers list signature and timestamp are still supported.
+// Until the first signed servers list with timestamp is accepted ('maxTimestamp' is 0) - the signature and the timestamp are optional.
+// Once such a list was accepted - the backend is known to support them, so they are mandatory since then.
+func checkServersList(isSigned bool, timestamp, maxTimestamp int64) error {
+	if maxTimestamp <= 0 {
+		return nil
+	}
+	if !isSigned {
+		return fmt.Errorf("servers list is not signed")
+	}
+	if timestamp <= 0 {
+		return fmt.Errorf("servers list timestamp is not defined")
+	}
+	if timestamp < maxTimestamp {
+		return fmt.Errorf("servers list is older than the newest accepted one (timestamp %d < %d)", timestamp, maxTimestamp)
+	}
+	return nil
+}
+
+// isCacheEnabled returns false when a custom API backend is in use:
+// its servers list (and the timestamp) must not replace the cached data of the IVPN servers
+func (s *serversUpdater) isCacheEnabled() bool {
+	return !s.api.IsCustomEndpoint()
+}
+
 // UpdateNotifierChannel returns channel which is notifying when servers was updated
 func (s *serversUpdater) UpdateNotifierChannel() chan struct{} {
 	return s.updatedNotifyChan
 }
 
-func readServersFromCache() (svrs *types.ServersInfoResponse, apiIPsV4 []string, apiIPsV6 []string, e error) {
+// serversSignatureFile returns path to the signature file of the cached servers list
+func serversSignatureFile() string {
+	return platform.ServersFile() + ".sign.sha256.base64"
+}
+
+// serversTimestampFile returns path to the file which keeps the highest accepted timestamp of the servers list
+func serversTimestampFile() string {
+	return platform.ServersFile() + ".timestamp"
+}
+
+// readServersTimestamp returns the highest accepted timestamp of the servers list (0 - not known)
+func readServersTimestamp() int64 {
+	file := serversTimestampFile()
+	if _, err := os.Stat(file); err != nil {
+		return 0
+	}
+	if err := filerights.CheckFileAccessRightsConfig(file); err != nil {
+		log.Warning("skip reading servers list timestamp: ", err)
+		return 0
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		log.Warning("failed to read servers list timestamp: ", err)
+		return 0
+	}
+	timestamp, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		log.Warning("failed to parse servers list timestamp: ", err)
+		return 0
+	}
+	return timestamp
+}
+
+// writeServersTimestamp saves the highest accepted timestamp of the servers list
+func writeServersTimestamp(timestamp int64) error {
+	return os.WriteFile(serversTimestampFile(), []byte(strconv.FormatInt(timestamp, 10)), filerights.DefaultFilePermissionsForConfig())
+}
+
+// readServersFromCache reads the cached servers list.
+// The list is rejected when its signature is not valid, or when it can not be accepted according to 'minTimestamp' (see checkServersList())
+func readServersFromCache(apiObj *api.API, minTimestamp int64) (svrs *types.ServersInfoResponse, apiIPsV4 []string, apiIPsV6 []string, e error) {
 
 	serversFile := platform.ServersFile()
 
@@ -181,22 +278,42 @@ func readServersFromCache() (svrs *types.ServersInfoResponse, apiIPsV4 []string,
 		return nil, servers.Config.API.IPAddresses, servers.Config.API.IPv6Addresses, fmt.Errorf("skip reading servers cache file: %w", err)
 	}
 
+	// check the signature of cached data
+	// (the same note as above: alternate API IP's can be used even if the data is not trusted)
+	signed := api.SignedData{Data: data}
+	signatureFile := serversSignatureFile()
+	if _, err := os.Stat(signatureFile); err == nil {
+		if err := filerights.CheckFileAccessRightsConfig(signatureFile); err != nil {
+			return nil, servers.Config.API.IPAddresses, servers.Config.API.IPv6Addresses, fmt.Errorf("skip reading servers cache file (signature not available): %w", err)
+		}
+		if signed.Signature, err = os.ReadFile(signatureFile); err != nil {
+			return nil, servers.Config.API.IPAddresses, servers.Config.API.IPv6Addresses, fmt.Errorf("skip reading servers cache file (signature not available): %w", err)
+		}
+		verifiedServers, err := apiObj.VerifyServersList(signed)
+		if err != nil {
+			return nil, servers.Config.API.IPAddresses, servers.Config.API.IPv6Addresses, fmt.Errorf("skip reading servers cache file: %w", err)
+		}
+		servers = verifiedServers
+	}
+	if err := checkServersList(signed.IsSigned(), servers.Timestamp, minTimestamp); err != nil {
+		return nil, servers.Config.API.IPAddresses, servers.Config.API.IPv6Addresses, fmt.Errorf("skip reading servers cache file: %w", err)
+	}
+
 	return servers, servers.Config.API.IPAddresses, servers.Config.API.IPv6Addresses, nil
 }
 
-func writeServersToCache(servers *types.ServersInfoResponse) error {
-	if servers == nil {
-		return errors.New("nothing to save. Servers is null")
+// writeServersToCache saves the servers list to the cache (the raw data and the signature, if the list is signed)
+func writeServersToCache(signed api.SignedData) error {
+	if len(signed.Data) == 0 {
+		return errors.New("nothing to save. Servers data is empty")
 	}
 
-	data, err := json.Marshal(servers)
-	if err != nil {
-		return fmt.Errorf("failed to marshal servers into a cache: %w", err)
-	}
-
-	if data == nil {
-		return errors.New("failed to serialize servers")
+	if signed.IsSigned() {
+		if err := os.WriteFile(serversSignatureFile(), signed.Signature, filerights.DefaultFilePermissionsForConfig()); err != nil {
+			return fmt.Errorf("failed to save servers signature: %w", err)
+		}
+	} else if err := os.Remove(serversSignatureFile()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove servers signature: %w", err)
 	}
-
-	return os.WriteFile(platform.ServersFile(), data, filerights.DefaultFilePermissionsForConfig())
+	return os.WriteFile(platform.ServersFile(), signed.Data, filerights.DefaultFilePermissionsForConfig())
 }
@@ -0,0 +1,52 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2026 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import "testing"
+
+func TestCheckServersList(t *testing.T) {
+	tests := []struct {
+		name         string
+		isSigned     bool
+		timestamp    int64
+		maxTimestamp int64
+		accepted     bool
+	}{
+		// transition path: no signed list with timestamp was accepted yet (the backend may not support them)
+		{"unsigned, no timestamp (transition)", false, 0, 0, true},
+		{"unsigned with timestamp (transition)", false, 100, 0, true},
+		{"signed, no timestamp (transition)", true, 0, 0, true},
+		{"signed with timestamp (first)", true, 100, 0, true},
+		// signed list with timestamp was accepted before: signature and timestamp are mandatory
+		{"unsigned", false, 200, 100, false},
+		{"signed, no timestamp", true, 0, 100, false},
+		{"signed, older", true, 99, 100, false},
+		{"signed, same", true, 100, 100, true},
+		{"signed, newer", true, 101, 100, true},
+	}
+	for _, tc := range tests {
+		if err := checkServersList(tc.isSigned, tc.timestamp, tc.maxTimestamp); (err == nil) != tc.accepted {
+			t.Errorf("%s: accepted=%v expected; error: %v", tc.name, tc.accepted, err)
+		}
+	}
+}
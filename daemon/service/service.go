@@ -42,6 +42,7 @@ import (
 	protocolTypes "github.com/ivpn/desktop-app/daemon/protocol/types"
 	"github.com/ivpn/desktop-app/daemon/service/dns"
 	"github.com/ivpn/desktop-app/daemon/service/firewall"
+	"github.com/ivpn/desktop-app/daemon/service/hostexceptions"
 	"github.com/ivpn/desktop-app/daemon/service/platform"
 	"github.com/ivpn/desktop-app/daemon/service/platform/filerights"
 	"github.com/ivpn/desktop-app/daemon/service/preferences"
@@ -84,6 +85,7 @@ type Service struct {
 	_api               *api.API
 	_serversUpdater    IServersUpdater
 	_netChangeDetector INetChangeDetector
+	_netTrustDetector  INetChangeDetector // detects network changes for 'trusted network' rules
 	_wgKeysMgr         IWgKeysManager
 	_vpn               vpn.Process
 	_preferences       preferences.Preferences
@@ -118,6 +120,13 @@ type Service struct {
 		_singleRequestLimitMutex sync.Mutex
 	}
 
+	// connection history of the hosts and the current geo-location (in use for the 'Best' server selection)
+	_serverHistory struct {
+		_mutex    sync.Mutex
+		_hosts    map[string]*serverConnHistory // [host]history
+		_location *api_types.GeoLookupResponse
+	}
+
 	// variables needed for automatic resume
 	_pause struct {
 		_mutex           sync.Mutex
@@ -135,6 +144,22 @@ type Service struct {
 	// (UI may send us new connection settings while VPN is connected, e.g., when the user changes connection settings in the UI)
 	_tmpParams      types.ConnectionParams
 	_tmpParamsMutex sync.Mutex
+
+	// resolves firewall exceptions defined by hostnames
+	_fwHostExceptions *hostexceptions.Manager
+	// addresses of the Split Tunnel destination domains (received in DNS answers)
+	_stDomains struct {
+		_mutex   sync.Mutex
+		_domains map[string]*splitTunnelDomainState
+	}
+
+	// samples of the tunnel traffic counters (required to calculate throughput)
+	_tunnelStats struct {
+		_mutex    sync.Mutex
+		_vpnObj   vpn.Process            // VPN object the samples belong to
+		_base     types.TunnelStatistics // the latest sample used as a reference
+		_prevBase types.TunnelStatistics // the reference sample before '_base'
+	}
 }
 
 // VpnSessionInfo - Additional information about current VPN connection
@@ -152,6 +177,7 @@ func CreateService(evtReceiver IServiceEventsReceiver,
 	api *api.API,
 	updater IServersUpdater,
 	netChDetector INetChangeDetector,
+	netTrustDetector INetChangeDetector,
 	wgKeysMgr IWgKeysManager,
 	globalEvents <-chan ServiceEventType,
 	systemLog chan<- SystemLogMessage) (*Service, error) {
@@ -166,6 +192,7 @@ func CreateService(evtReceiver IServiceEventsReceiver,
 		_api:                         api,
 		_serversUpdater:              updater,
 		_netChangeDetector:           netChDetector,
+		_netTrustDetector:            netTrustDetector,
 		_wgKeysMgr:                   wgKeysMgr,
 		_globalEvents:                globalEvents,
 		_systemLog:                   systemLog,
@@ -216,7 +243,10 @@ func (s *Service) init() error {
 	}
 
 	// initialize firewall functionality
-	if err := firewall.Initialize(); err != nil {
+	funcGetFirewallExtraSettings := func() firewall.FirewallExtraSettings {
+		return firewall.FirewallExtraSettings{Linux_IsNftablesBackend: s._preferences.UserPrefs.Linux.FirewallBackend == preferences.LinuxFirewallBackendNftables}
+	}
+	if err := firewall.Initialize(funcGetFirewallExtraSettings); err != nil {
 		return fmt.Errorf("service initialization error : %w", err)
 	}
 
@@ -224,10 +254,22 @@ func (s *Service) init() error {
 	funcGetDnsExtraSettings := func() dns.DnsExtraSettings {
 		return dns.DnsExtraSettings{Linux_IsDnsMgmtOldStyle: s._preferences.UserPrefs.Linux.IsDnsMgmtOldStyle}
 	}
-	if err := dns.Initialize(firewall.OnChangeDNS, funcGetDnsExtraSettings); err != nil {
+	funcOnDnsChanged := func(dnsCfg *dns.DnsSettings) error {
+		err := firewall.OnChangeDNS(dnsCfg)
+		// resolve hostname firewall exceptions using the new DNS (e.g. manual DNS was changed)
+		s.refreshFirewallHostExceptions()
+		return err
+	}
+	if err := dns.Initialize(funcOnDnsChanged, funcGetDnsExtraSettings); err != nil {
 		log.Error(fmt.Sprintf("failed to initialize DNS : %s", err))
 	}
 
+	// initialize WireGuard functionality
+	funcGetWireGuardExtraSettings := func() wireguard.WireGuardExtraSettings {
+		return wireguard.WireGuardExtraSettings{Linux_Backend: string(s._preferences.UserPrefs.Linux.WireGuardBackend)}
+	}
+	wireguard.Initialize(funcGetWireGuardExtraSettings)
+
 	// initialize split-tunnel functionality
 	if err := splittun.Initialize(); err != nil {
 		log.Warning(fmt.Errorf("Split-Tunnelling initialization error : %w", err))
@@ -249,9 +291,11 @@ func (s *Service) init() error {
 	}
 
 	//log.Info("Applying firewal exceptions (user configuration)")
-	if err := firewall.SetUserExceptions(s._preferences.FwUserExceptions, true); err != nil {
+	if _, err := firewall.SetUserExceptions(s._preferences.FwUserExceptionsList, true); err != nil {
 		log.Error("Failed to apply firewall exceptions: ", err)
 	}
+	s.initFirewallHostExceptions()
+	s.initSplitTunnelDomains()
 
 	if s._preferences.IsFwPersistant {
 		log.Info("Enabling firewal (persistant configuration)")
@@ -278,6 +322,10 @@ func (s *Service) init() error {
 		log.Error("Failed to init WiFi functionality:", err)
 	}
 
+	if err := s.initNetworkTrustFunctionality(); err != nil {
+		log.Error("Failed to init trusted network functionality:", err)
+	}
+
 	// Start session status checker
 	go func() {
 		<-s._ipStackInitializationWaiter // Wait for IP stack initialization
@@ -316,6 +364,12 @@ func (s *Service) init() error {
 		s.autoConnectIfRequired(OnDaemonStarted, nil)
 	}()
 
+	// Start scheduler
+	go func() {
+		<-s._ipStackInitializationWaiter // Wait for IP stack initialization
+		s.startScheduler()
+	}()
+
 	// Start processing power events in separate routine (Windows)
 	go func() {
 		<-s._ipStackInitializationWaiter // Wait for IP stack initialization
@@ -382,7 +436,7 @@ func (s *Service) unInitialise() error {
 		log.Error(err)
 		updateRetErr(err)
 	}
-	if err := splittun.ApplyConfig(false, false, false, false, splittun.ConfigAddresses{}, []string{}); err != nil {
+	if err := splittun.ApplyConfig(false, false, false, false, splittun.ConfigAddresses{}, []string{}, nil); err != nil {
 		log.Error(err)
 		updateRetErr(err)
 	}
@@ -521,6 +575,8 @@ func (s *Service) GetDisabledFunctions() protocolTypes.DisabledFunctionality {
 			wgErr = fmt.Errorf("WireGuard tools binary: %w", err)
 		}
 	}
+	// (Linux) WireGuard can work without external binaries (the embedded userspace implementation)
+	wgErr = s.implWireGuardAvailabilityError(wgErr)
 
 	// returns non-nil error object if Split-Tunneling functionality not available
 	splitTunErr, splitTunInversedErr = splittun.GetFuncNotAvailableError()
@@ -1079,16 +1135,24 @@ func (s *Service) KillSwitchState() (status types.KillSwitchStatus, err error) {
 	enabled, isLanAllowed, _, err := firewall.GetState()
 
 	return types.KillSwitchStatus{
-		IsEnabled:         enabled,
-		IsPersistent:      prefs.IsFwPersistant,
-		IsAllowLAN:        prefs.IsFwAllowLAN,
-		IsAllowMulticast:  prefs.IsFwAllowLANMulticast,
-		IsAllowApiServers: prefs.IsFwAllowApiServers,
-		UserExceptions:    prefs.FwUserExceptions,
-		StateLanAllowed:   isLanAllowed,
+		IsEnabled:           enabled,
+		IsPersistent:        prefs.IsFwPersistant,
+		IsAllowLAN:          prefs.IsFwAllowLAN,
+		IsAllowMulticast:    prefs.IsFwAllowLANMulticast,
+		IsAllowApiServers:   prefs.IsFwAllowApiServers,
+		UserExceptions:      prefs.FwUserExceptions,
+		UserExceptionsList:  prefs.FwUserExceptionsList,
+		UserExceptionsHosts: s.firewallHostExceptionsStatus(),
+		StateLanAllowed:     isLanAllowed,
 	}, err
 }
 
+// KillSwitchRules returns description of the firewall rules
+// (if isDryRun - description of rules which would be applied when the firewall is enabled)
+func (s *Service) KillSwitchRules(isDryRun bool) (types.KillSwitchRules, error) {
+	return firewall.GetRulesInfo(isDryRun)
+}
+
 // SetKillSwitchIsPersistent change kill-switch value
 func (s *Service) SetKillSwitchIsPersistent(isPersistant bool) error {
 	if s.IsPaused() {
@@ -1165,13 +1229,29 @@ func (s *Service) SetKillSwitchAllowAPIServers(isAllowAPIServers bool) error {
 
 // SetKillSwitchUserExceptions set ip/mask to be excluded from FW block
 // Parameters:
-//   - exceptions - comma separated list of IP addresses in format: x.x.x.x[/xx]
+//   - exceptions - comma separated list of exceptions in format: [PROTOCOL:]ADDRESS[:PORT[-PORT]][:DIRECTION]
+//     (see service_types.FirewallException for details)
 func (s *Service) SetKillSwitchUserExceptions(exceptions string, ignoreParsingErrors bool) error {
+	list, err := types.ParseFirewallExceptions(exceptions, ignoreParsingErrors)
+	if err != nil {
+		return err
+	}
+	return s.SetKillSwitchUserExceptionsList(list, ignoreParsingErrors)
+}
+
+// SetKillSwitchUserExceptionsList set the list of exceptions to be excluded from FW block
+func (s *Service) SetKillSwitchUserExceptionsList(exceptions []types.FirewallException, ignoreErrors bool) error {
+	applied, err := firewall.SetUserExceptions(exceptions, ignoreErrors)
+	if err != nil && applied == nil {
+		return err // validation error: nothing changed
+	}
+
 	prefs := s._preferences
-	prefs.FwUserExceptions = exceptions
+	prefs.FwUserExceptionsList = applied
+	prefs.FwUserExceptions = types.FirewallExceptionsToString(applied)
 	s.setPreferences(prefs)
+	s.updateFirewallHostExceptions(applied)
 
-	err := firewall.SetUserExceptions(exceptions, ignoreParsingErrors)
 	if err == nil {
 		s.onKillSwitchStateChanged()
 	}
@@ -1212,6 +1292,18 @@ func (s *Service) SetPreference(key protocolTypes.ServicePreference, val string)
 			prefs.IsAutoconnectOnLaunchDaemon = val
 		}
 
+	case protocolTypes.Prefs_AutoconnectProfile:
+		val = strings.TrimSpace(val)
+		if len(val) > 0 {
+			profile, ok := prefs.ConnectionProfileGet(val)
+			if !ok {
+				return false, fmt.Errorf("connection profile '%s' not found", val)
+			}
+			val = profile.Name
+		}
+		isChanged = val != prefs.AutoconnectProfile
+		prefs.AutoconnectProfile = val
+
 	default:
 		log.Warning(fmt.Sprintf("Preference key '%s' not supported", key))
 	}
@@ -1283,11 +1375,27 @@ func (s *Service) SetWiFiSettings(params preferences.WiFiParams) error {
 		}
 	}
 
+	if profileName := params.Actions.UnTrustedConnectVpnProfile; len(profileName) > 0 {
+		prefs := s._preferences
+		profile, ok := prefs.ConnectionProfileGet(profileName)
+		if !ok {
+			return fmt.Errorf("connection profile '%s' not found", profileName)
+		}
+		params.Actions.UnTrustedConnectVpnProfile = profile.Name
+	}
+
 	// remove duplicate networks from 'trusted' list
 	newNets := []preferences.WiFiNetwork{}
 	keys := make(map[string]struct{})
 	for _, n := range params.Networks {
 		if _, exists := keys[n.SSID]; !exists && len(n.SSID) > 0 {
+			if n.Rule.IsEmpty() {
+				n.Rule = nil
+			} else if n.Rule.ConnectionParams != nil {
+				if err := n.Rule.ConnectionParams.CheckIsDefined(); err != nil {
+					return fmt.Errorf("bad connection parameters for network '%s': %w", n.SSID, err)
+				}
+			}
 			newNets = append(newNets, n)
 			keys[n.SSID] = struct{}{}
 		}
@@ -1351,7 +1459,20 @@ func (s *Service) SplitTunnelling_GetStatus() (protocolTypes.SplitTunnelStatus,
 		IsAllowWhenNoVpn:            isAllowWhenNoVpn,
 		IsCanGetAppIconForBinary:    oshelpers.IsCanGetAppIconForBinary(),
 		SplitTunnelApps:             prefs.SplitTunnelApps,
-		RunningApps:                 runningProcesses}
+		RunningApps:                 runningProcesses,
+		SplitTunnelRoutes:           prefs.SplitTunnelRoutes,
+		SplitTunnelDomains:          prefs.SplitTunnelDomains,
+		SplitTunnelDomainsResolved:  s.splitTunnelDomainsStatus()}
+
+	if err := splittun.GetDestinationsNotAvailableError(); err != nil {
+		ret.DestinationsError = err.Error()
+	}
+
+	if isEnabled {
+		if ret.AppsTraffic, err = splittun.GetAppsTraffic(); err != nil {
+			ret.AppsTrafficError = err.Error()
+		}
+	}
 
 	return ret, nil
 }
@@ -1412,7 +1533,10 @@ func (s *Service) splitTunnelling_Reset() error {
 	prefs.SplitTunnelAnyDns = false
 	prefs.SplitTunnelAllowWhenNoVpn = false
 	prefs.SplitTunnelApps = make([]string, 0)
+	prefs.SplitTunnelRoutes = nil
+	prefs.SplitTunnelDomains = nil
 	s.setPreferences(prefs)
+	s.updateSplitTunnelDomains()
 
 	splittun.Reset()
 
@@ -1487,7 +1611,7 @@ func (s *Service) splitTunnelling_ApplyConfig() (retError error) {
 	}
 
 	// Apply Split-Tun config
-	return splittun.ApplyConfig(prefs.IsSplitTunnel, prefs.IsInverseSplitTunneling(), prefs.SplitTunnelAllowWhenNoVpn, isVpnConnected, addressesCfg, prefs.SplitTunnelApps)
+	return splittun.ApplyConfig(prefs.IsSplitTunnel, prefs.IsInverseSplitTunneling(), prefs.SplitTunnelAllowWhenNoVpn, isVpnConnected, addressesCfg, prefs.SplitTunnelApps, s.splitTunnelDestinations(prefs))
 }
 
 func (s *Service) SplitTunnelling_AddApp(exec string) (cmdToExecute string, isAlreadyRunning bool, err error) {
@@ -1505,6 +1629,20 @@ func (s *Service) SplitTunnelling_RemoveApp(pid int, exec string) (err error) {
 	return s.implSplitTunnelling_RemoveApp(pid, exec)
 }
 
+// SplitTunnelling_AddAppRule adds persistent rule to the Split Tunnel configuration
+// (Linux: absolute path to the executable or desktop file ID; all processes of this executable are added to ST automatically)
+func (s *Service) SplitTunnelling_AddAppRule(rule string) error {
+	// apply ST configuration after function ends
+	defer s.splitTunnelling_ApplyConfig()
+	return s.implSplitTunnelling_AddAppRule(rule)
+}
+
+func (s *Service) SplitTunnelling_RemoveAppRule(rule string) error {
+	// apply ST configuration after function ends
+	defer s.splitTunnelling_ApplyConfig()
+	return s.implSplitTunnelling_RemoveAppRule(rule)
+}
+
 // Inform the daemon about started process in ST environment
 // Parameters:
 // pid 			- process PID
@@ -1942,6 +2080,9 @@ func (s *Service) WireGuardSaveNewKeys(wgPublicKey string, wgPrivateKey string,
 		if vpnObj.Type() != vpn.WireGuard {
 			return
 		}
+		if wgObj, ok := vpnObj.(*wireguard.WireGuard); ok && wgObj.IsCustomConfig() {
+			return // IVPN credentials are not in use by the custom WireGuard configuration
+		}
 		if !s.Connected() || (s.Connected() && s.IsPaused()) {
 			// IMPORTANT! : WireGuard 'pause/resume' state is based on complete VPN disconnection and connection back (on all platforms)
 			// If this will be changed (e.g. just changing routing) - it will be necessary to implement reconnection even in 'pause' state
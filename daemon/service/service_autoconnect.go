@@ -30,6 +30,7 @@ import (
 	"strings"
 
 	apiTypes "github.com/ivpn/desktop-app/daemon/api/types"
+	"github.com/ivpn/desktop-app/daemon/netinfo"
 	protocolTypes "github.com/ivpn/desktop-app/daemon/protocol/types"
 	"github.com/ivpn/desktop-app/daemon/service/preferences"
 	"github.com/ivpn/desktop-app/daemon/service/types"
@@ -44,6 +45,7 @@ const (
 	OnUiClientConnected autoConnectReason = iota
 	OnSessionLogon      autoConnectReason = iota
 	OnWifiChanged       autoConnectReason = iota
+	OnNetworkChanged    autoConnectReason = iota
 )
 
 func (cr autoConnectReason) ToString() string {
@@ -54,6 +56,8 @@ func (cr autoConnectReason) ToString() string {
 		return "UIAppLaunch"
 	case OnWifiChanged:
 		return "WiFiChanged"
+	case OnNetworkChanged:
+		return "NetworkChanged"
 	case OnSessionLogon:
 		return "UserSessionLogon"
 	default:
@@ -81,11 +85,17 @@ const (
 type automaticAction struct {
 	Vpn      actionTypeVpn
 	Firewall actionTypeFirewall
+	// Name of the connection profile to use for 'VPN_On' action (empty - use last connection parameters)
+	ConnectionProfile string
+	// Connection parameters to use for 'VPN_On' action (nil - not defined; has priority over ConnectionProfile)
+	ConnectionParams *types.ConnectionParams
 }
 
 type lastProcessedWiFiInfo struct {
-	wifi   wifiNotifier.WifiInfo
-	params preferences.WiFiParams
+	wifi          wifiNotifier.WifiInfo
+	params        preferences.WiFiParams
+	network       netinfo.DefaultNetworkInfo
+	networkParams preferences.NetworkTrustParams
 }
 
 var autoconnectLastProcessedWifi lastProcessedWiFiInfo
@@ -127,6 +137,9 @@ func (s *Service) isTrustedWifiForcingToBlockLan(wifiInfoPtr *wifiNotifier.WifiI
 	}
 
 	action := s.getActionForWifiNetwork(wifiInfo)
+	if !action.IsHasAction() {
+		action = s.getActionForDefaultNetwork(s.getDefaultNetworkInfo())
+	}
 
 	return action.Firewall == FW_On_and_blockLan
 }
@@ -155,15 +168,18 @@ func (s *Service) autoConnectIfRequired(reason autoConnectReason, wifiInfoPtr *w
 		wifiInfo = *wifiInfoPtr
 	}
 
+	// Check current network (default route)
+	netInfo := s.getDefaultNetworkInfo()
+
 	// Check if WiFi already processed
 	isWifiProcessedAlready := false
 
-	currWiFi := lastProcessedWiFiInfo{wifi: wifiInfo, params: prefs.WiFiControl}
+	currWiFi := lastProcessedWiFiInfo{wifi: wifiInfo, params: prefs.WiFiControl, network: netInfo, networkParams: prefs.NetworkTrust}
 	lastWifi := autoconnectLastProcessedWifi
 
 	if reflect.DeepEqual(lastWifi, currWiFi) {
 		// this wifi network change has been processed already
-		if reason == OnWifiChanged {
+		if reason == OnWifiChanged || reason == OnNetworkChanged {
 			return nil
 		}
 		isWifiProcessedAlready = true
@@ -177,8 +193,8 @@ func (s *Service) autoConnectIfRequired(reason autoConnectReason, wifiInfoPtr *w
 		}
 		// Check if the untrusted WiFi settings were forced to block LAN.
 		// We have to restore LAN connectivity if there is not required to block LAN for current network
-		prevSettingsBlockLan := lastWifi.params.TrustedNetworksControl && lastWifi.params.Actions.UnTrustedBlockLan
-		currSettingsBlockLan := prefs.WiFiControl.TrustedNetworksControl && prefs.WiFiControl.Actions.UnTrustedBlockLan
+		prevSettingsBlockLan := isSettingsCanBlockLan(lastWifi.params, lastWifi.networkParams)
+		currSettingsBlockLan := isSettingsCanBlockLan(prefs.WiFiControl, prefs.NetworkTrust)
 		if prefs.IsFwAllowLAN && (prevSettingsBlockLan || currSettingsBlockLan) {
 			if err := s.applyKillSwitchAllowLAN(&wifiInfo); err != nil {
 				log.Info(fmt.Sprintf("Automatic connection manager: failed to restore Firewall rules to allow LAN: %s", err.Error()))
@@ -192,6 +208,10 @@ func (s *Service) autoConnectIfRequired(reason autoConnectReason, wifiInfoPtr *w
 
 	// Check "Trusted WiFi" actions
 	action := s.getActionForWifiNetwork(wifiInfo)
+	if !action.IsHasAction() {
+		// Check "Trusted network" (wired) actions
+		action = s.getActionForDefaultNetwork(netInfo)
+	}
 
 	isVpnOffRequired := false
 	if isWifiProcessedAlready {
@@ -202,7 +222,7 @@ func (s *Service) autoConnectIfRequired(reason autoConnectReason, wifiInfoPtr *w
 	}
 
 	if action.IsHasAction() {
-		log.Info("Automatic connection manager: applying 'Trusted-WiFi' action...")
+		log.Info("Automatic connection manager: applying 'Trusted-Network' action...")
 	}
 
 	// Check "Auto-connect on APP/daemon launch" action
@@ -212,9 +232,11 @@ func (s *Service) autoConnectIfRequired(reason autoConnectReason, wifiInfoPtr *w
 			if (reason == OnDaemonStarted || reason == OnSessionLogon) && prefs.IsAutoconnectOnLaunchDaemon {
 				log.Info(fmt.Sprintf("Automatic connection manager: applying Auto-Connect action on '%s' ...", reason.ToString()))
 				action.Vpn = VPN_On
+				action.ConnectionProfile = prefs.AutoconnectProfile
 			} else if reason == OnUiClientConnected {
 				log.Info(fmt.Sprintf("Automatic connection manager: applying Auto-Connect action on '%s' ...", reason.ToString()))
 				action.Vpn = VPN_On
+				action.ConnectionProfile = prefs.AutoconnectProfile
 			}
 		}
 	}
@@ -228,6 +250,7 @@ func (s *Service) autoConnectIfRequired(reason autoConnectReason, wifiInfoPtr *w
 		if s.isCanApplyWiFiActions() {
 			log.Info("Automatic connection manager: applying Auto-Connect 'On joining WiFi networks without encryption' action...")
 			action.Vpn = VPN_On
+			action.ConnectionProfile = prefs.AutoconnectProfile
 		}
 	}
 
@@ -242,6 +265,17 @@ func (s *Service) autoConnectIfRequired(reason autoConnectReason, wifiInfoPtr *w
 
 	var retErr error = nil
 	connParams := prefs.LastConnectionParams
+	if action.ConnectionParams != nil && action.Vpn == VPN_On {
+		log.Info("Automatic connection manager: using network-specific connection parameters")
+		connParams = *action.ConnectionParams
+	} else if len(action.ConnectionProfile) > 0 && action.Vpn == VPN_On {
+		if profile, ok := prefs.ConnectionProfileGet(action.ConnectionProfile); ok {
+			log.Info(fmt.Sprintf("Automatic connection manager: using connection profile '%s'", profile.Name))
+			connParams = profile.Params
+		} else {
+			log.Warning(fmt.Sprintf("Automatic connection manager: connection profile '%s' not found (using default connection parameters)", action.ConnectionProfile))
+		}
+	}
 
 	// Firewall
 	switch action.Firewall {
@@ -284,22 +318,9 @@ func (s *Service) autoConnectIfRequired(reason autoConnectReason, wifiInfoPtr *w
 	case VPN_On:
 		if !s.Connected() {
 			log.Info("Automatic connection manager: connecting VPN")
-
-			connParams, retErr = s.updateParamsAccordingToMetadata(connParams)
-			if retErr != nil {
-				log.Info("[WARNING] Auto connection: failed updating connection parameters: ", retErr)
-			}
-
-			const canFixParams bool = true
-			if connParams, retErr = s.ValidateConnectionParameters(connParams, canFixParams); retErr != nil {
-				log.Error("Auto connection: error validating connection parameters: ", retErr)
+			if retErr = s.registerAutomaticConnection(connParams); retErr != nil {
 				return retErr
 			}
-
-			if retErr = s._evtReceiver.RegisterConnectionRequest(connParams); retErr != nil {
-				log.Error("Auto connection: connecting: ", retErr)
-			}
-
 		}
 	default:
 	}
@@ -307,6 +328,56 @@ func (s *Service) autoConnectIfRequired(reason autoConnectReason, wifiInfoPtr *w
 	return retErr
 }
 
+// registerAutomaticConnection - prepares connection parameters and registers the connection request
+// (used by automatic actions: auto-connect, trusted networks, scheduler ...)
+func (s *Service) registerAutomaticConnection(connParams types.ConnectionParams) error {
+	connParams, err := s.updateParamsAccordingToMetadata(connParams)
+	if err != nil {
+		log.Info("[WARNING] Auto connection: failed updating connection parameters: ", err)
+	}
+
+	const canFixParams bool = true
+	if connParams, err = s.ValidateConnectionParameters(connParams, canFixParams); err != nil {
+		log.Error("Auto connection: error validating connection parameters: ", err)
+		return err
+	}
+
+	if err = s._evtReceiver.RegisterConnectionRequest(connParams); err != nil {
+		log.Error("Auto connection: connecting: ", err)
+		return err
+	}
+	return nil
+}
+
+// isSettingsCanBlockLan returns 'true' when trusted network settings can force to block LAN
+func isSettingsCanBlockLan(wifiParams preferences.WiFiParams, networkParams preferences.NetworkTrustParams) bool {
+	isRuleBlockLan := func(r *preferences.WiFiNetworkRule) bool {
+		return r != nil && r.Firewall != nil && r.Firewall.IsEnabled && r.Firewall.BlockLan
+	}
+
+	if wifiParams.TrustedNetworksControl {
+		if wifiParams.Actions.UnTrustedBlockLan {
+			return true
+		}
+		for _, n := range wifiParams.Networks {
+			if isRuleBlockLan(n.Rule) {
+				return true
+			}
+		}
+	}
+	if networkParams.IsEnabled {
+		if wifiParams.Actions.UnTrustedBlockLan {
+			return true
+		}
+		for _, r := range networkParams.Rules {
+			if isRuleBlockLan(r.Rule) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (s *Service) isCanApplyWiFiActions() bool {
 	prefs := s.Preferences()
 	const onlyUiClients = true
@@ -333,6 +404,7 @@ func (s *Service) getActionForWifiNetwork(wifiInfo wifiNotifier.WifiInfo) (retAc
 	}
 
 	var isNetworkTrusted *bool // nil - no action
+	var networkRule *preferences.WiFiNetworkRule
 
 	// get config for ssid
 	for _, w := range wifiParams.Networks {
@@ -341,6 +413,7 @@ func (s *Service) getActionForWifiNetwork(wifiInfo wifiNotifier.WifiInfo) (retAc
 		}
 
 		isNetworkTrusted = &w.IsTrusted
+		networkRule = w.Rule
 		break
 	}
 
@@ -353,10 +426,17 @@ func (s *Service) getActionForWifiNetwork(wifiInfo wifiNotifier.WifiInfo) (retAc
 		return
 	}
 
-	if !*isNetworkTrusted {
+	return getActionForTrustStatus(wifiParams, *isNetworkTrusted, networkRule)
+}
+
+// getActionForTrustStatus returns action for trusted/untrusted network
+// (common actions from 'wifiParams.Actions' are overridden by network-specific rule, if defined)
+func getActionForTrustStatus(wifiParams preferences.WiFiParams, isNetworkTrusted bool, networkRule *preferences.WiFiNetworkRule) (retAction automaticAction) {
+	if !isNetworkTrusted {
 		// UnTrusted
 		if wifiParams.Actions.UnTrustedConnectVpn {
 			retAction.Vpn = VPN_On
+			retAction.ConnectionProfile = wifiParams.Actions.UnTrustedConnectVpnProfile
 		}
 		if wifiParams.Actions.UnTrustedEnableFirewall {
 			retAction.Firewall = FW_On
@@ -374,10 +454,28 @@ func (s *Service) getActionForWifiNetwork(wifiInfo wifiNotifier.WifiInfo) (retAc
 		}
 	}
 
+	// network-specific rule overrides common actions
+	if networkRule != nil {
+		if networkRule.ConnectionParams != nil && retAction.Vpn == VPN_On {
+			params := *networkRule.ConnectionParams
+			retAction.ConnectionParams = &params
+		}
+		if fw := networkRule.Firewall; fw != nil {
+			switch {
+			case !fw.IsEnabled:
+				retAction.Firewall = FW_Off
+			case fw.BlockLan:
+				retAction.Firewall = FW_On_and_blockLan
+			default:
+				retAction.Firewall = FW_On
+			}
+		}
+	}
+
 	return
 }
 
-// updateParamsAccordingToMetadata - update Entry/Exit servers if connection requires 'Fastest' or 'Random'
+// updateParamsAccordingToMetadata - update Entry/Exit servers if connection requires 'Fastest', 'Best' or 'Random'
 func (s *Service) updateParamsAccordingToMetadata(params types.ConnectionParams) (types.ConnectionParams, error) {
 	if params.Metadata.ServerSelectionEntry == types.Default && params.Metadata.ServerSelectionExit == types.Default {
 		return params, nil
@@ -409,7 +507,18 @@ func (s *Service) updateParamsAccordingToMetadata(params types.ConnectionParams)
 					applicableEntryServers = append(applicableEntryServers, s)
 				}
 			}
-			// Random/Fastest
+			// only members of the server group (if defined)
+			if applicableEntryServers, err = filterServersByGroup(s._preferences, params.Metadata.ServerGroupEntry, applicableEntryServers); err != nil {
+				return params, err
+			}
+			// only servers from the location (if defined)
+			applicableEntryServers = filterServersByLocation(params.Metadata.ServerLocationEntry, applicableEntryServers)
+			// skip servers with all hosts blacklisted
+			applicableEntryServers = filterServersByHostsSelection(s._preferences.HostsSelection, applicableEntryServers)
+			if len(applicableEntryServers) == 0 {
+				return params, fmt.Errorf("no applicable servers")
+			}
+			// Random/Fastest/Best
 			switch params.Metadata.ServerSelectionEntry {
 			case types.Random: // RANDOM SERVER (OpenVPN)
 				rndIdx, err := rand.Int(rand.Reader, big.NewInt(int64(len(applicableEntryServers))))
@@ -423,6 +532,12 @@ func (s *Service) updateParamsAccordingToMetadata(params types.ConnectionParams)
 					return params, err
 				}
 				params.OpenVpnParameters.EntryVpnServer.Hosts = fastestSvr.Hosts
+			case types.Best: // BEST SERVER BY SCORE (OpenVPN)
+				bestSvr, err := getBestServer(s, vpn.OpenVPN, applicableEntryServers, params.Metadata.FastestGatewaysExcludeList)
+				if err != nil {
+					return params, err
+				}
+				params.OpenVpnParameters.EntryVpnServer.Hosts = bestSvr.Hosts
 			default:
 			}
 		} else {
@@ -438,7 +553,18 @@ func (s *Service) updateParamsAccordingToMetadata(params types.ConnectionParams)
 					applicableEntryServers = append(applicableEntryServers, s)
 				}
 			}
-			// Random/Fastest
+			// only members of the server group (if defined)
+			if applicableEntryServers, err = filterServersByGroup(s._preferences, params.Metadata.ServerGroupEntry, applicableEntryServers); err != nil {
+				return params, err
+			}
+			// only servers from the location (if defined)
+			applicableEntryServers = filterServersByLocation(params.Metadata.ServerLocationEntry, applicableEntryServers)
+			// skip servers with all hosts blacklisted
+			applicableEntryServers = filterServersByHostsSelection(s._preferences.HostsSelection, applicableEntryServers)
+			if len(applicableEntryServers) == 0 {
+				return params, fmt.Errorf("no applicable servers")
+			}
+			// Random/Fastest/Best
 			switch params.Metadata.ServerSelectionEntry {
 			case types.Random: // RANDOM SERVER (WireGuard)
 				rndIdx, err := rand.Int(rand.Reader, big.NewInt(int64(len(applicableEntryServers))))
@@ -452,13 +578,19 @@ func (s *Service) updateParamsAccordingToMetadata(params types.ConnectionParams)
 					return params, err
 				}
 				params.WireGuardParameters.EntryVpnServer.Hosts = fastestSvr.Hosts
+			case types.Best: // BEST SERVER BY SCORE (WireGuard)
+				bestSvr, err := getBestServer(s, vpn.WireGuard, applicableEntryServers, params.Metadata.FastestGatewaysExcludeList)
+				if err != nil {
+					return params, err
+				}
+				params.WireGuardParameters.EntryVpnServer.Hosts = bestSvr.Hosts
 			default:
 			}
 		}
 	}
 
-	// EXIT server (Fastest server is not applicable for exit server)
-	if params.IsMultiHop() && params.Metadata.ServerSelectionExit == types.Random {
+	// EXIT server (Fastest/Best server is applicable for exit server only when the server group is defined)
+	if params.IsMultiHop() && params.Metadata.IsExitServerSelectionApplicable() {
 
 		// Get countryCode of exit server (do not choose exit server from same country)
 		entrySvrCountryCode := s.getServerCountryCode(params, true)
@@ -476,17 +608,38 @@ func (s *Service) updateParamsAccordingToMetadata(params types.ConnectionParams)
 					applicableExitServers = append(applicableExitServers, s)
 				}
 			}
-			// Random/Fastest
-			switch params.Metadata.ServerSelectionEntry {
+			// only members of the server group (if defined)
+			if applicableExitServers, err = filterServersByGroup(s._preferences, params.Metadata.ServerGroupExit, applicableExitServers); err != nil {
+				return params, err
+			}
+			// skip servers with all hosts blacklisted
+			applicableExitServers = filterServersByHostsSelection(s._preferences.HostsSelection, applicableExitServers)
+			if len(applicableExitServers) == 0 {
+				return params, fmt.Errorf("no applicable servers")
+			}
+			// Random/Fastest/Best
+			switch params.Metadata.ServerSelectionExit {
 			case types.Random: // RANDOM SERVER (OpenVPN)
 				rndIdx, err := rand.Int(rand.Reader, big.NewInt(int64(len(applicableExitServers))))
 				if err != nil {
 					return params, err
 				}
 				params.OpenVpnParameters.MultihopExitServer.Hosts = applicableExitServers[rndIdx.Int64()].Hosts
-
-			case types.Fastest: // FASTEST SERVER (OpenVPN)
-				// fastest server not applicable for ExitServer
+				params.OpenVpnParameters.MultihopExitServer.ExitSrvID = strings.Split(applicableExitServers[rndIdx.Int64()].Gateway, ".")[0]
+			case types.Fastest: // FASTEST SERVER OF THE GROUP (OpenVPN)
+				fastestSvr, err := getFastestServer(s, vpn.OpenVPN, applicableExitServers, nil)
+				if err != nil {
+					return params, err
+				}
+				params.OpenVpnParameters.MultihopExitServer.Hosts = fastestSvr.Hosts
+				params.OpenVpnParameters.MultihopExitServer.ExitSrvID = strings.Split(fastestSvr.Gateway, ".")[0]
+			case types.Best: // BEST SERVER OF THE GROUP (OpenVPN)
+				bestSvr, err := getBestServer(s, vpn.OpenVPN, applicableExitServers, nil)
+				if err != nil {
+					return params, err
+				}
+				params.OpenVpnParameters.MultihopExitServer.Hosts = bestSvr.Hosts
+				params.OpenVpnParameters.MultihopExitServer.ExitSrvID = strings.Split(bestSvr.Gateway, ".")[0]
 			default:
 			}
 		} else {
@@ -503,17 +656,38 @@ func (s *Service) updateParamsAccordingToMetadata(params types.ConnectionParams)
 					applicableExitServers = append(applicableExitServers, s)
 				}
 			}
-			// Random/Fastest
-			switch params.Metadata.ServerSelectionEntry {
+			// only members of the server group (if defined)
+			if applicableExitServers, err = filterServersByGroup(s._preferences, params.Metadata.ServerGroupExit, applicableExitServers); err != nil {
+				return params, err
+			}
+			// skip servers with all hosts blacklisted
+			applicableExitServers = filterServersByHostsSelection(s._preferences.HostsSelection, applicableExitServers)
+			if len(applicableExitServers) == 0 {
+				return params, fmt.Errorf("no applicable servers")
+			}
+			// Random/Fastest/Best
+			switch params.Metadata.ServerSelectionExit {
 			case types.Random: // RANDOM SERVER (WireGuard)
 				rndIdx, err := rand.Int(rand.Reader, big.NewInt(int64(len(applicableExitServers))))
 				if err != nil {
 					return params, err
 				}
 				params.WireGuardParameters.MultihopExitServer.Hosts = applicableExitServers[rndIdx.Int64()].Hosts
-
-			case types.Fastest: // FASTEST SERVER (WireGuard)
-				// fastest server not applicable for ExitServer
+				params.WireGuardParameters.MultihopExitServer.ExitSrvID = strings.Split(applicableExitServers[rndIdx.Int64()].Gateway, ".")[0]
+			case types.Fastest: // FASTEST SERVER OF THE GROUP (WireGuard)
+				fastestSvr, err := getFastestServer(s, vpn.WireGuard, applicableExitServers, nil)
+				if err != nil {
+					return params, err
+				}
+				params.WireGuardParameters.MultihopExitServer.Hosts = fastestSvr.Hosts
+				params.WireGuardParameters.MultihopExitServer.ExitSrvID = strings.Split(fastestSvr.Gateway, ".")[0]
+			case types.Best: // BEST SERVER OF THE GROUP (WireGuard)
+				bestSvr, err := getBestServer(s, vpn.WireGuard, applicableExitServers, nil)
+				if err != nil {
+					return params, err
+				}
+				params.WireGuardParameters.MultihopExitServer.Hosts = bestSvr.Hosts
+				params.WireGuardParameters.MultihopExitServer.ExitSrvID = strings.Split(bestSvr.Gateway, ".")[0]
 			default:
 			}
 		}
@@ -576,15 +750,6 @@ func getFastestServer[S serverBaseInterface](service *Service, vpnTypePrioritize
 	if err != nil {
 		return ret, err
 	}
-	// looking for IP with minimum ping time
-	minPingTime := -1
-	minPingTimeIp := ""
-	for ip, msTime := range hosts {
-		if minPingTime == -1 || minPingTime > msTime {
-			minPingTime = msTime
-			minPingTimeIp = ip
-		}
-	}
 
 	// Remove everything after symbol '.': "us-tx.wg.ivpn.net" => "us-tx"; or "us-tx" => "us-tx"
 	normalizeGwId := func(gwId string) string {
@@ -598,7 +763,12 @@ func getFastestServer[S serverBaseInterface](service *Service, vpnTypePrioritize
 		}
 	}
 
-	// looking for server info which contains host with lower ping time
+	hostsSelection := service._preferences.HostsSelection
+
+	// looking for server which contains host with minimum ping time
+	// (only the servers from the list are taken into account)
+	minPingTime := -1
+	found := false
 	for _, s := range servers {
 		if len(excludedGatewaysHashed) > 0 {
 			gw := normalizeGwId(s.GetServerInfoBase().Gateway)
@@ -607,11 +777,25 @@ func getFastestServer[S serverBaseInterface](service *Service, vpnTypePrioritize
 			}
 		}
 
-		for _, h := range s.GetHostsInfoBase() {
-			if h.Host == minPingTimeIp {
-				return s, nil
+		// only the hosts which can be used for connection (see 'preferences.HostsSelectionParams')
+		allowedHosts, err := preferences.ApplyHostsSelection(hostsSelection, s.GetHostsInfoBase())
+		if err != nil {
+			continue
+		}
+		for _, h := range allowedHosts {
+			msTime, ok := hosts[h.Host]
+			if !ok {
+				continue
+			}
+			if minPingTime == -1 || minPingTime > msTime {
+				minPingTime = msTime
+				ret = s
+				found = true
 			}
 		}
 	}
-	return ret, fmt.Errorf("unable to determine servers latency")
+	if !found {
+		return ret, fmt.Errorf("unable to determine servers latency")
+	}
+	return ret, nil
 }
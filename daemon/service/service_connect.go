@@ -58,7 +58,17 @@ type svrConnInfo struct {
 }
 
 func (s *Service) ValidateConnectionParameters(params types.ConnectionParams, isCanFix bool) (types.ConnectionParams, error) {
-	if params.VpnType == vpn.WireGuard {
+	if params.IsWireGuardCustomConfig() {
+		// Custom WireGuard configuration
+		if len(s.Preferences().WireGuardCustomConfigEncrypted) == 0 {
+			return params, fmt.Errorf("custom WireGuard configuration not defined")
+		}
+	} else if params.IsOpenVpnCustomConfig() {
+		// Custom OpenVPN configuration
+		if len(s.Preferences().OpenVpnCustomConfigEncrypted) == 0 {
+			return params, fmt.Errorf("custom OpenVPN configuration not defined")
+		}
+	} else if params.VpnType == vpn.WireGuard {
 		// WireGuard connection parameters
 		if len(params.WireGuardParameters.EntryVpnServer.Hosts) <= 0 {
 			return params, fmt.Errorf("no hosts defined for WireGuard connection")
@@ -117,7 +127,8 @@ func (s *Service) Connect(params types.ConnectionParams) (err error) {
 	prefs := s.Preferences()
 
 	// if account not active (OR subscription expired) - request account status from backend
-	if !prefs.Account.Active || time.Now().After(time.Unix(prefs.Account.ActiveUntil, 0)) {
+	// (not applicable for the custom configurations: IVPN servers are not in use)
+	if !params.IsCustomConfig() && (!prefs.Account.Active || time.Now().After(time.Unix(prefs.Account.ActiveUntil, 0))) {
 		// update account info
 		if _, _, _, _, err := s.RequestSessionStatus(); err == nil {
 			// If account info update success: check actual account status
@@ -132,6 +143,11 @@ func (s *Service) Connect(params types.ConnectionParams) (err error) {
 		}
 	}
 
+	// Apply pinned/blacklisted hosts
+	if params, err = s.applyHostsSelection(params); err != nil {
+		return fmt.Errorf("failed to apply hosts selection: %w", err)
+	}
+
 	// Normalize hosts list
 	// - in case of multiple entry hosts - take one random host from the list
 	// - in case of multiple exit hosts - take one random host from the list
@@ -149,6 +165,24 @@ func (s *Service) Connect(params types.ConnectionParams) (err error) {
 	}
 	// ------------------------ Inverse Split Tunnel block end --------------------------
 
+	if params.IsWireGuardCustomConfig() {
+		// V2Ray, Multi-Hop and other IVPN-specific options are not applicable for the custom configuration
+		return s.connectWireGuardCustom(params.ManualDNS, params.Metadata.AntiTracker, params.FirewallOn, params.FirewallOnDuringConnection)
+	}
+	if params.IsOpenVpnCustomConfig() {
+		// V2Ray, obfsproxy, Multi-Hop and other IVPN-specific options are not applicable for the custom configuration
+		return s.connectOpenVPNCustom(params.ManualDNS, params.Metadata.AntiTracker, params.FirewallOn, params.FirewallOnDuringConnection)
+	}
+
+	// register the connection attempt (in use for the 'Best' server selection)
+	if vpn.Type(params.VpnType) == vpn.OpenVPN {
+		if len(params.OpenVpnParameters.EntryVpnServer.Hosts) > 0 {
+			s.serverHistory_onConnecting(params.OpenVpnParameters.EntryVpnServer.Hosts[0].Host)
+		}
+	} else if len(params.WireGuardParameters.EntryVpnServer.Hosts) > 0 {
+		s.serverHistory_onConnecting(params.WireGuardParameters.EntryVpnServer.Hosts[0].Host)
+	}
+
 	// ------------------------ V2RAY block start ------------------------
 	// 'originalEntryServerInfo' - will contain original info about EntryServer/Port (it is not 'nil' for V2Ray connections).
 	//  We need this info to notify correct data about vpn.CONNECTED state: for V2Ray connection the original parameters are overwriten by local V2Ray proxy params ('127.0.0.1:local_port')
@@ -490,6 +524,167 @@ func (s *Service) connectWireGuard(originalEntryServerInfo *svrConnInfo, connect
 	return s.keepConnection(originalEntryServerInfo, createVpnObjfunc, manualDNS, antiTracker, firewallOn, firewallDuringConnection, v2rayWrapper)
 }
 
+// connectWireGuardCustom start WireGuard connection based on the custom WireGuard configuration (see WireGuardCustomConfigSet())
+func (s *Service) connectWireGuardCustom(manualDNS dns.DnsSettings, antiTracker types.AntiTrackerMetadata, firewallOn bool, firewallDuringConnection bool) error {
+	prefs := s.Preferences()
+	cfgText, err := prefs.GetWireGuardCustomConfig()
+	if err != nil {
+		return err
+	}
+	if len(cfgText) == 0 {
+		return fmt.Errorf("custom WireGuard configuration not defined")
+	}
+	cfg, err := wireguard.ParseCustomConfig(cfgText)
+	if err != nil {
+		return fmt.Errorf("bad custom WireGuard configuration: %w", err)
+	}
+
+	// stop active connection (if exists)
+	if err := s.Disconnect(); err != nil {
+		return fmt.Errorf("failed to connect. Unable to stop active connection: %w", err)
+	}
+
+	// checking if functionality accessible
+	disabledFuncs := s.GetDisabledFunctions()
+	if len(disabledFuncs.WireGuardError) > 0 {
+		return fmt.Errorf(disabledFuncs.WireGuardError)
+	}
+
+	// Peer endpoints are resolved only once (before connection).
+	// On re-connection, the DNS may be not accessible (e.g. blocked by firewall)
+	connectionParams, err := wireguard.CreateConnectionParamsCustom(cfg)
+	if err != nil {
+		return err
+	}
+
+	createVpnObjfunc := func() (vpn.Process, error) {
+		vpnObj, err := wireguard.NewWireGuardObject(
+			platform.WgBinaryPath(),
+			platform.WgToolBinaryPath(),
+			platform.WGConfigFilePath(),
+			connectionParams)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new WireGuard object: %w", err)
+		}
+		return vpnObj, nil
+	}
+
+	return s.keepConnection(nil, createVpnObjfunc, manualDNS, antiTracker, firewallOn, firewallDuringConnection, nil)
+}
+
+// WireGuardCustomConfigSet validates and saves the custom WireGuard configuration ('wg-quick' format)
+// The configuration is stored encrypted. Empty string - remove configuration.
+func (s *Service) WireGuardCustomConfigSet(config string) error {
+	if len(strings.TrimSpace(config)) > 0 {
+		if _, err := wireguard.ParseCustomConfig(config); err != nil {
+			return fmt.Errorf("bad custom WireGuard configuration: %w", err)
+		}
+	} else {
+		config = ""
+	}
+
+	prefs := s._preferences
+	if err := prefs.SetWireGuardCustomConfig(config); err != nil {
+		return err
+	}
+	s.setPreferences(prefs)
+	return nil
+}
+
+// connectOpenVPNCustom start OpenVPN connection based on the custom OpenVPN configuration (see OpenVpnCustomConfigSet())
+func (s *Service) connectOpenVPNCustom(manualDNS dns.DnsSettings, antiTracker types.AntiTrackerMetadata, firewallOn bool, firewallDuringConnection bool) error {
+	prefs := s.Preferences()
+	cfgText, err := prefs.GetOpenVpnCustomConfig()
+	if err != nil {
+		return err
+	}
+	if len(cfgText) == 0 {
+		return fmt.Errorf("custom OpenVPN configuration not defined")
+	}
+	cfg, err := openvpn.ParseCustomConfig(cfgText)
+	if err != nil {
+		return fmt.Errorf("bad custom OpenVPN configuration: %w", err)
+	}
+
+	// stop active connection (if exists)
+	if err := s.Disconnect(); err != nil {
+		return fmt.Errorf("failed to connect. Unable to stop active connection: %w", err)
+	}
+
+	// checking if functionality accessible
+	disabledFuncs := s.GetDisabledFunctions()
+	if len(disabledFuncs.OpenVPNError) > 0 {
+		return fmt.Errorf(disabledFuncs.OpenVPNError)
+	}
+
+	// Remote hosts are resolved only once (before connection).
+	// On re-connection, the DNS may be not accessible (e.g. blocked by firewall)
+	connectionParams, err := openvpn.CreateConnectionParamsCustom(cfg)
+	if err != nil {
+		return err
+	}
+
+	createVpnObjfunc := func() (vpn.Process, error) {
+		// User-defined extra parameters (OpenvpnUserParamsFile) and obfsproxy are not applicable for the custom configuration
+		vpnObj, err := openvpn.NewOpenVpnObject(
+			platform.OpenVpnBinaryPath(),
+			platform.OpenvpnConfigFile(),
+			"",
+			openvpn.ObfsParams{},
+			"",
+			connectionParams)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new OpenVPN object: %w", err)
+		}
+		return vpnObj, nil
+	}
+
+	return s.keepConnection(nil, createVpnObjfunc, manualDNS, antiTracker, firewallOn, firewallDuringConnection, nil)
+}
+
+// OpenVpnCustomConfigSet validates, sanitises and saves the custom OpenVPN configuration ('.ovpn' profile)
+// The 'username' and 'password' are optional: required only when profile contains 'auth-user-pass' without inline credentials.
+// The configuration is stored encrypted. Empty string - remove configuration.
+// Returns the list of directives removed from the profile by sanitising (e.g. 'up', 'script-security').
+func (s *Service) OpenVpnCustomConfigSet(config, username, password string) (removedDirectives []string, retErr error) {
+	if len(strings.TrimSpace(config)) > 0 {
+		cfg, err := openvpn.ParseCustomConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("bad custom OpenVPN configuration: %w", err)
+		}
+
+		if len(username) > 0 || len(password) > 0 {
+			if len(cfg.Username) > 0 {
+				return nil, fmt.Errorf("credentials are already defined in the configuration")
+			}
+			if !cfg.IsAuthUserPass {
+				return nil, fmt.Errorf("credentials are not required by the configuration ('auth-user-pass' not defined)")
+			}
+			if len(username) == 0 || len(password) == 0 {
+				return nil, fmt.Errorf("both username and password must be defined")
+			}
+			if config, err = openvpn.AddCredentials(config, username, password); err != nil {
+				return nil, err
+			}
+		} else if cfg.IsAuthUserPass && len(cfg.Username) == 0 {
+			return nil, fmt.Errorf("the configuration requires credentials ('auth-user-pass'): username and password not defined")
+		}
+
+		removedDirectives = cfg.RemovedDirectives
+	} else {
+		config = ""
+	}
+
+	prefs := s._preferences
+	if err := prefs.SetOpenVpnCustomConfig(config); err != nil {
+		return nil, err
+	}
+	s.setPreferences(prefs)
+	return removedDirectives, nil
+}
+
 func (s *Service) keepConnection(originalEntryServerInfo *svrConnInfo, createVpnObj func() (vpn.Process, error), initialManualDNS dns.DnsSettings, initialAntiTracker types.AntiTrackerMetadata, firewallOn bool, firewallDuringConnection bool, v2rayWrapper *v2r.V2RayWrapper) (retError error) {
 	prefs := s.Preferences()
 	if !prefs.Session.IsLoggedIn() {
@@ -648,6 +843,8 @@ func (s *Service) connect(originalEntryServerInfo *svrConnInfo, vpnProc vpn.Proc
 
 		// ensure firewall removed rules for DNS
 		firewall.OnChangeDNS(nil)
+		// resolve hostname firewall exceptions using the system DNS
+		s.refreshFirewallHostExceptions()
 
 		// notify firewall that client is disconnected
 		err := firewall.ClientDisconnected()
@@ -691,7 +888,11 @@ func (s *Service) connect(originalEntryServerInfo *svrConnInfo, vpnProc vpn.Proc
 
 	destinationIpAddresses := make([]net.IP, 0)
 	// Add VPN server IP to firewall exceptions
-	destinationIpAddresses = append(destinationIpAddresses, vpnProc.DestinationIP())
+	if mdProc, ok := vpnProc.(vpn.MultiDestinationProcess); ok {
+		destinationIpAddresses = append(destinationIpAddresses, mdProc.DestinationIPs()...)
+	} else {
+		destinationIpAddresses = append(destinationIpAddresses, vpnProc.DestinationIP())
+	}
 
 	if v2rayWrapper != nil {
 		// Configure firewall to allow V2Ray remote IP
@@ -786,6 +987,8 @@ func (s *Service) connect(originalEntryServerInfo *svrConnInfo, vpnProc vpn.Proc
 							s._requiredVpnState = KeepConnection
 						}
 
+						s.serverHistory_onConnected(state.ServerIP)
+
 						// If no any clients connected - connection notification will not be passed to user
 						// In this case we are trying to save info message into system log
 						if !s._evtReceiver.IsClientConnected(false) {
@@ -807,6 +1010,9 @@ func (s *Service) connect(originalEntryServerInfo *svrConnInfo, vpnProc vpn.Proc
 							firewall.OnChangeDNS(&d)
 						}
 
+						// resolve hostname firewall exceptions using VPN DNS
+						s.refreshFirewallHostExceptions()
+
 						// save ClientIP/ClientIPv6 into vpn-session-info
 						sInfo := s.GetVpnSessionInfo()
 						sInfo.VpnLocalIPv4 = state.ClientIP
@@ -905,6 +1111,13 @@ func (s *Service) connect(originalEntryServerInfo *svrConnInfo, vpnProc vpn.Proc
 		}
 	}()
 
+	// monitoring of the connection health (WireGuard: stale handshake detection)
+	connectRoutinesWaiter.Add(1)
+	go func() {
+		defer connectRoutinesWaiter.Done()
+		s.wireGuardHealthMonitor(vpnProc, internalStateChan, stopChannel)
+	}()
+
 	// Initialize VPN: ensure everything is prepared for a new connection
 	// (e.g. correct OpenVPN version or a previously started WireGuard service is stopped)
 	log.Info("Initializing connection...")
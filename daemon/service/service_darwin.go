@@ -42,6 +42,10 @@ func (s *Service) implGetDisabledFuncForPlatform() protocolTypes.DisabledFunctio
 	return protocolTypes.DisabledFunctionalityForPlatform{}
 }
 
+func (s *Service) implWireGuardAvailabilityError(binariesErr error) error {
+	return binariesErr
+}
+
 func (s *Service) implPingServersStarting(hosts []net.IP) error {
 	const onlyForICMP = true
 	const isPersistent = false
@@ -61,6 +65,12 @@ func (s *Service) implSplitTunnelling_RemoveApp(pid int, binaryPath string) (err
 	// Split Tunneling is not implemented for macOS
 	return nil
 }
+func (s *Service) implSplitTunnelling_AddAppRule(rule string) error {
+	return fmt.Errorf("function not applicable for this platform")
+}
+func (s *Service) implSplitTunnelling_RemoveAppRule(rule string) error {
+	return fmt.Errorf("function not applicable for this platform")
+}
 func (s *Service) implSplitTunnelling_AddedPidInfo(pid int, exec string, cmdToExecute string) error {
 	return fmt.Errorf("function not applicable for this platform")
 }
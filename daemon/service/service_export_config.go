@@ -0,0 +1,226 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	api_types "github.com/ivpn/desktop-app/daemon/api/types"
+	"github.com/ivpn/desktop-app/daemon/service/dns"
+	"github.com/ivpn/desktop-app/daemon/service/platform"
+	"github.com/ivpn/desktop-app/daemon/service/srverrors"
+	"github.com/ivpn/desktop-app/daemon/service/types"
+	"github.com/ivpn/desktop-app/daemon/v2r"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+	"github.com/ivpn/desktop-app/daemon/vpn/openvpn"
+	"github.com/ivpn/desktop-app/daemon/vpn/wireguard"
+)
+
+// ExportConnectionConfig renders the current connection parameters (see 'LastConnectionParams') into a standalone
+// configuration which can be used without IVPN client (e.g. on headless servers or routers):
+// 'wg-quick' configuration for WireGuard or '.ovpn' profile for OpenVPN.
+// The host info is taken from the actual servers list; Multi-Hop connections are exported using port mapping on the entry server.
+// If 'includeCredentials' is true - OpenVPN credentials are inlined into the profile (not applicable for WireGuard: the keys are always included).
+func (s *Service) ExportConnectionConfig(includeCredentials bool) (vpnType vpn.Type, fileName string, config string, err error) {
+	prefs := s.Preferences()
+	if !prefs.Session.IsLoggedIn() {
+		return 0, "", "", srverrors.ErrorNotLoggedIn{}
+	}
+
+	params := prefs.LastConnectionParams
+	if err := params.CheckIsDefined(); err != nil {
+		return 0, "", "", fmt.Errorf("connection parameters not defined: %w", err)
+	}
+	if params.IsCustomConfig() {
+		return 0, "", "", fmt.Errorf("export is not applicable for custom configurations")
+	}
+	if params.V2Ray() != v2r.None {
+		return 0, "", "", fmt.Errorf("export is not applicable for V2Ray connections")
+	}
+	if params.VpnType == vpn.OpenVPN && params.OpenVpnParameters.Obfs4proxy.IsObfsproxy() {
+		return 0, "", "", fmt.Errorf("export is not applicable for obfsproxy connections")
+	}
+
+	svrs, err := s.ServersList()
+	if err != nil {
+		return 0, "", "", fmt.Errorf("failed to get servers list: %w", err)
+	}
+
+	if err := params.NormalizeHosts(); err != nil {
+		return 0, "", "", err
+	}
+
+	dnsServers, err := s.exportDnsServers(params)
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	if params.VpnType == vpn.WireGuard {
+		fileName, config, err = s.exportWireGuardConfig(params, svrs, dnsServers)
+	} else {
+		fileName, config, err = s.exportOpenVpnConfig(params, svrs, includeCredentials, dnsServers)
+	}
+	if err != nil {
+		return 0, "", "", err
+	}
+	return params.VpnType, fileName, config, nil
+}
+
+func (s *Service) exportWireGuardConfig(params types.ConnectionParams, svrs *api_types.ServersInfoResponse, dnsServers []net.IP) (fileName string, config string, err error) {
+	session := s.Preferences().Session
+	if len(session.WGPrivateKey) == 0 || len(session.WGLocalIP) == 0 {
+		return "", "", fmt.Errorf("WireGuard keys not defined")
+	}
+
+	// use the actual host info from the servers list (if host still exists)
+	entryHost := findWireGuardHost(params.WireGuardParameters.EntryVpnServer.Hosts[0], svrs.WireguardServers)
+	hostname, port, publicKey := entryHost.Hostname, params.WireGuardParameters.Port.Port, entryHost.PublicKey
+	multihopExitHostname := ""
+	if len(params.WireGuardParameters.MultihopExitServer.Hosts) > 0 {
+		// Multi-Hop: connecting to the entry server using the port mapped to the exit server
+		exitHost := findWireGuardHost(params.WireGuardParameters.MultihopExitServer.Hosts[0], svrs.WireguardServers)
+		multihopExitHostname, port, publicKey = exitHost.Hostname, exitHost.MultihopPort, exitHost.PublicKey
+	}
+
+	ipv6Prefix := ""
+	if params.IPv6 {
+		ipv6Prefix = strings.Split(entryHost.IPv6.LocalIP, "/")[0]
+	}
+
+	connectionParams := wireguard.CreateConnectionParams(
+		multihopExitHostname,
+		port,
+		net.ParseIP(entryHost.Host),
+		publicKey,
+		net.ParseIP(strings.Split(entryHost.LocalIP, "/")[0]),
+		ipv6Prefix,
+		params.WireGuardParameters.Mtu)
+	connectionParams.SetCredentials(session.WGPrivateKey, session.WGPresharedKey, net.ParseIP(session.WGLocalIP))
+
+	config, err = connectionParams.ExportConfig(dnsServers)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to export WireGuard configuration: %w", err)
+	}
+	return exportFileName(hostname, multihopExitHostname, ".conf"), exportHeader(hostname, multihopExitHostname) + config, nil
+}
+
+func (s *Service) exportOpenVpnConfig(params types.ConnectionParams, svrs *api_types.ServersInfoResponse, includeCredentials bool, dnsServers []net.IP) (fileName string, config string, err error) {
+	session := s.Preferences().Session
+
+	entryHost, err := s.findOpenVpnHost(params.OpenVpnParameters.EntryVpnServer.Hosts[0].Hostname, nil, svrs.OpenvpnServers)
+	if err != nil {
+		entryHost = params.OpenVpnParameters.EntryVpnServer.Hosts[0]
+	}
+	port := params.OpenVpnParameters.Port.Port
+	multihopExitHostname := ""
+	if len(params.OpenVpnParameters.MultihopExitServer.Hosts) > 0 {
+		// Multi-Hop: connecting to the entry server using the port mapped to the exit server
+		exitHost, err := s.findOpenVpnHost(params.OpenVpnParameters.MultihopExitServer.Hosts[0].Hostname, nil, svrs.OpenvpnServers)
+		if err != nil {
+			exitHost = params.OpenVpnParameters.MultihopExitServer.Hosts[0]
+		}
+		multihopExitHostname, port = exitHost.Hostname, exitHost.MultihopPort
+	}
+
+	// proxy settings are specific for the local environment and are not exported
+	connectionParams := openvpn.CreateConnectionParams(
+		multihopExitHostname,
+		params.OpenVpnParameters.Port.Protocol > 0, // is TCP
+		port,
+		net.ParseIP(entryHost.Host),
+		"", nil, 0, "", "")
+	connectionParams.SetCredentials(session.OpenVPNUser, session.OpenVPNPass)
+
+	config, err = connectionParams.ExportConfig(platform.OpenvpnCaKeyFile(), platform.OpenvpnTaKeyFile(), includeCredentials, dnsServers)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to export OpenVPN configuration: %w", err)
+	}
+
+	header := exportHeader(entryHost.Hostname, multihopExitHostname)
+	if !includeCredentials {
+		header += fmt.Sprintf("# Username: %s\n", session.OpenVPNUser)
+	}
+	return exportFileName(entryHost.Hostname, multihopExitHostname, ".ovpn"), header + config, nil
+}
+
+// findWireGuardHost returns the actual info about the host from the servers list
+// (or the original host info if it is not found in the list)
+func findWireGuardHost(host api_types.WireGuardServerHostInfo, svrs []api_types.WireGuardServerInfo) api_types.WireGuardServerHostInfo {
+	for _, svr := range svrs {
+		for _, h := range svr.Hosts {
+			if strings.EqualFold(h.Hostname, host.Hostname) {
+				return h
+			}
+		}
+	}
+	return host
+}
+
+// exportDnsServers returns DNS servers for exported configuration (nil - use default DNS of the VPN server).
+// The DNS is selected the same way as for the connection: AntiTracker DNS (if enabled) or manual DNS.
+func (s *Service) exportDnsServers(params types.ConnectionParams) ([]net.IP, error) {
+	dnsCfg := params.ManualDNS
+	if params.Metadata.AntiTracker.Enabled {
+		atDns, err := s.getAntiTrackerDns(params.Metadata.AntiTracker.Hardcore, params.Metadata.AntiTracker.AntiTrackerBlockListName)
+		if err != nil {
+			return nil, err
+		}
+		dnsCfg = atDns
+	}
+	return exportDnsServers(dnsCfg)
+}
+
+// exportDnsServers converts DNS settings into the list of DNS servers for exported configuration
+// (nil - use default DNS of the VPN server).
+// Encrypted DNS (DoH/DoT) can not be used in the standalone WireGuard/OpenVPN configuration.
+func exportDnsServers(dnsCfg dns.DnsSettings) ([]net.IP, error) {
+	if dnsCfg.IsEmpty() {
+		return nil, nil
+	}
+	if dnsCfg.Encryption != dns.EncryptionNone {
+		return nil, fmt.Errorf("export is not applicable for encrypted DNS (DNS over HTTPS/TLS)")
+	}
+	ip := net.ParseIP(dnsCfg.DnsHost)
+	if ip == nil {
+		return nil, fmt.Errorf("bad DNS server address '%s'", dnsCfg.DnsHost)
+	}
+	return []net.IP{ip}, nil
+}
+
+func exportHeader(entryHostname, exitHostname string) string {
+	if len(exitHostname) > 0 {
+		return fmt.Sprintf("# IVPN Multi-Hop: %s -> %s\n", entryHostname, exitHostname)
+	}
+	return fmt.Sprintf("# IVPN: %s\n", entryHostname)
+}
+
+// exportFileName returns suggested file name for the exported configuration (e.g. "ivpn-nl4.conf" or "ivpn-nl4-ch1.conf")
+func exportFileName(entryHostname, exitHostname, extension string) string {
+	name := "ivpn-" + strings.Split(entryHostname, ".")[0]
+	if len(exitHostname) > 0 {
+		name += "-" + strings.Split(exitHostname, ".")[0]
+	}
+	return name + extension
+}
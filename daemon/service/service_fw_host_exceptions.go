@@ -0,0 +1,104 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"net"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/service/dns"
+	"github.com/ivpn/desktop-app/daemon/service/firewall"
+	"github.com/ivpn/desktop-app/daemon/service/hostexceptions"
+	"github.com/ivpn/desktop-app/daemon/service/types"
+)
+
+//////////////////////////////////////////////////////////
+// FIREWALL EXCEPTIONS DEFINED BY HOSTNAMES
+//////////////////////////////////////////////////////////
+
+const fwHostExceptionsResolveTimeout = 5 * time.Second
+
+// initFirewallHostExceptions - start resolving of hostname firewall exceptions
+func (s *Service) initFirewallHostExceptions() {
+	// The resolved addresses are applied as a separate set of the user exceptions
+	// (not mixed with the hosts allowed for the API or VPN servers)
+	s._fwHostExceptions = hostexceptions.NewManager(
+		s.resolveFirewallHostException,
+		firewall.SetHostnameExceptions,
+		s.onKillSwitchStateChanged)
+
+	s.updateFirewallHostExceptions(s._preferences.FwUserExceptionsList)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("PANIC in firewall hostname exceptions resolver!: ", r)
+				if err, ok := r.(error); ok {
+					log.ErrorTrace(err)
+				}
+			}
+		}()
+
+		<-s._ipStackInitializationWaiter // Wait for IP stack initialization
+		s._fwHostExceptions.Run()
+	}()
+}
+
+// updateFirewallHostExceptions - update the list of hostnames to be resolved
+func (s *Service) updateFirewallHostExceptions(exceptions []types.FirewallException) {
+	if s._fwHostExceptions == nil {
+		return
+	}
+	var hosts []string
+	for _, e := range exceptions {
+		if e.IsHostname() {
+			hosts = append(hosts, e.Hostname)
+		}
+	}
+	s._fwHostExceptions.SetHosts(hosts)
+}
+
+// refreshFirewallHostExceptions - resolve hostname exceptions again (e.g. DNS configuration changed)
+func (s *Service) refreshFirewallHostExceptions() {
+	if s._fwHostExceptions != nil {
+		s._fwHostExceptions.RefreshNow()
+	}
+}
+
+func (s *Service) firewallHostExceptionsStatus() []types.FirewallHostExceptionStatus {
+	if s._fwHostExceptions == nil {
+		return nil
+	}
+	return s._fwHostExceptions.Status()
+}
+
+// resolveFirewallHostException resolves the hostname.
+// When VPN is connected: the request is sent directly to the VPN DNS server (if it is not an encrypted DNS);
+// otherwise - the system resolver is in use.
+func (s *Service) resolveFirewallHostException(hostname string) ([]hostexceptions.Address, error) {
+	var dnsServer net.IP
+	if cfg, isInitialized := firewall.GetDnsInfo(); isInitialized && cfg.Encryption == dns.EncryptionNone {
+		dnsServer = cfg.Ip()
+	}
+	return hostexceptions.Resolve(hostname, dnsServer, fwHostExceptionsResolveTimeout)
+}
@@ -0,0 +1,170 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/ping"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+// interval of the WireGuard connection health checks
+const wgHealthCheckInterval = 10 * time.Second
+
+// SetWireGuardHealthCheckSettings saves parameters of the WireGuard connection monitoring
+// (the parameters are applied to the active connection immediately)
+func (s *Service) SetWireGuardHealthCheckSettings(params preferences.WireGuardHealthCheckParams) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	prefs := s._preferences
+	prefs.WireGuardHealthCheck = params
+	s.setPreferences(prefs)
+	return nil
+}
+
+// wireGuardHealthMonitor checks the health of the active WireGuard connection until 'stop' channel is closed
+// (see 'preferences.WireGuardHealthCheckParams').
+// When the connection is stale - the 'RECONNECTING' state is sent to 'stateChan' and the reconnection is requested
+// (the reconnection is performed by 'keepConnection()').
+func (s *Service) wireGuardHealthMonitor(vpnProc vpn.Process, stateChan chan<- vpn.StateInfo, stop <-chan bool) {
+	sp, ok := vpnProc.(vpn.StatisticsProcess)
+	if !ok || vpnProc.Type() != vpn.WireGuard {
+		return
+	}
+
+	log.Info("WireGuard health monitor started")
+	defer log.Info("WireGuard health monitor stopped")
+
+	ticker := time.NewTicker(wgHealthCheckInterval)
+	defer ticker.Stop()
+
+	var prevTxBytes uint64
+	lastGatewayResponse := time.Now()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		params := s._preferences.WireGuardHealthCheck
+		// 'KeepConnection' state means that the connection was established (CONNECTED)
+		if params.IsDisabled || s._requiredVpnState != KeepConnection || vpnProc.IsPaused() {
+			prevTxBytes = 0
+			lastGatewayResponse = time.Now()
+			continue
+		}
+		timeout := params.HandshakeTimeout()
+
+		stats, err := sp.Statistics()
+		if err != nil {
+			log.Debug("Health monitor: failed to get WireGuard statistics: ", err)
+			continue
+		}
+
+		now := time.Now()
+		reason := ""
+
+		// The handshake is performed (at least) every 2 minutes while data is being sent (PersistentKeepalive is sending data even on idle tunnel).
+		// So, the old handshake is a problem only when we are sending data.
+		handshakeAge, txBytes, isHandshakeDetected := latestHandshake(stats, now)
+		isSending := txBytes > prevTxBytes
+		prevTxBytes = txBytes
+		if isHandshakeDetected && isSending && handshakeAge > timeout {
+			reason = fmt.Sprintf("no WireGuard handshake for %v", handshakeAge.Round(time.Second))
+		}
+
+		if len(reason) == 0 && params.IsPingGateway {
+			if gw := vpnProc.DefaultDNS(); gw != nil {
+				if isHostResponding(gw, wgHealthCheckInterval/2) {
+					lastGatewayResponse = now
+				} else if now.Sub(lastGatewayResponse) > timeout {
+					reason = fmt.Sprintf("VPN server gateway %s is not responding for %v", gw, now.Sub(lastGatewayResponse).Round(time.Second))
+				}
+			}
+		}
+
+		if len(reason) == 0 {
+			continue
+		}
+
+		log.Warning(fmt.Sprintf("Connection is stale (%s). Reconnecting...", reason))
+		select {
+		case stateChan <- vpn.NewStateInfo(vpn.RECONNECTING, "Reconnecting: "+reason):
+		case <-stop:
+			return
+		}
+
+		// Reconnect in separate routine (the current routine must be stopped on disconnection)
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Error("PANIC: ", r)
+				}
+			}()
+			s.reconnect()
+		}()
+		return
+	}
+}
+
+// latestHandshake returns the age of the most recent handshake of all peers and the total number of sent bytes.
+// 'isHandshakeDetected' is false when there were no handshakes yet.
+func latestHandshake(stats vpn.TunnelStatistics, now time.Time) (age time.Duration, txBytes uint64, isHandshakeDetected bool) {
+	for _, p := range stats.Peers {
+		txBytes += p.TxBytes
+		if p.LastHandshake.IsZero() {
+			continue
+		}
+		a := now.Sub(p.LastHandshake)
+		if !isHandshakeDetected || a < age {
+			age = a
+		}
+		isHandshakeDetected = true
+	}
+	return age, txBytes, isHandshakeDetected
+}
+
+// isHostResponding sends ICMP echo request to the host and returns true if the response received
+func isHostResponding(ip net.IP, timeout time.Duration) bool {
+	pinger, err := ping.NewPinger(ip.String())
+	if err != nil {
+		log.Debug("Pinger creation error: ", err)
+		return false
+	}
+	pinger.SetPrivileged(true)
+	pinger.Count = 1
+	pinger.Timeout = timeout
+	if err := pinger.Run(); err != nil {
+		log.Debug("Ping error: ", err)
+		return false
+	}
+	return pinger.Statistics().PacketsRecv > 0
+}
@@ -0,0 +1,101 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/service/types"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+// SetHostsSelection saves pinned and blacklisted hosts (see 'preferences.HostsSelectionParams').
+// The hosts are selected when the connection is established, so when the settings change
+// while VPN is connected - the VPN is reconnected with the last connection parameters.
+func (s *Service) SetHostsSelection(params preferences.HostsSelectionParams) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	prefs := s._preferences
+	isChanged := !reflect.DeepEqual(prefs.HostsSelection, params)
+	prefs.HostsSelection = params
+	s.setPreferences(prefs)
+
+	if !isChanged || !s.Connected() {
+		return nil
+	}
+	connParams := prefs.LastConnectionParams
+	if connParams.IsCustomConfig() || connParams.CheckIsDefined() != nil {
+		return nil
+	}
+	log.Info("Hosts selection changed: reconnecting...")
+	// the automatic server selection (fastest/best/random) is performed again, taking into account the new hosts selection
+	// (errors are logged by registerAutomaticConnection())
+	s.registerAutomaticConnection(connParams)
+	return nil
+}
+
+// applyHostsSelection removes from connection parameters the hosts which are not allowed to use
+// (blacklisted hosts or not pinned hosts of the server which has pinned hosts)
+func (s *Service) applyHostsSelection(params types.ConnectionParams) (types.ConnectionParams, error) {
+	hs := s._preferences.HostsSelection
+	if hs.IsEmpty() || params.IsCustomConfig() {
+		return params, nil
+	}
+
+	var err error
+	if vpn.Type(params.VpnType) == vpn.OpenVPN {
+		if params.OpenVpnParameters.EntryVpnServer.Hosts, err = preferences.ApplyHostsSelection(hs, params.OpenVpnParameters.EntryVpnServer.Hosts); err != nil {
+			return params, fmt.Errorf("entry server: %w", err)
+		}
+		if params.OpenVpnParameters.MultihopExitServer.Hosts, err = preferences.ApplyHostsSelection(hs, params.OpenVpnParameters.MultihopExitServer.Hosts); err != nil {
+			return params, fmt.Errorf("exit server: %w", err)
+		}
+	} else {
+		if params.WireGuardParameters.EntryVpnServer.Hosts, err = preferences.ApplyHostsSelection(hs, params.WireGuardParameters.EntryVpnServer.Hosts); err != nil {
+			return params, fmt.Errorf("entry server: %w", err)
+		}
+		if params.WireGuardParameters.MultihopExitServer.Hosts, err = preferences.ApplyHostsSelection(hs, params.WireGuardParameters.MultihopExitServer.Hosts); err != nil {
+			return params, fmt.Errorf("exit server: %w", err)
+		}
+	}
+	return params, nil
+}
+
+// filterServersByHostsSelection removes servers which have all hosts blacklisted
+// (in use for automatic server selection: Random, Fastest, Best)
+func filterServersByHostsSelection[S serverBaseInterface](hs preferences.HostsSelectionParams, servers []S) []S {
+	if len(hs.Blacklisted) == 0 {
+		return servers
+	}
+	ret := make([]S, 0, len(servers))
+	for _, svr := range servers {
+		if hs.IsServerAllowed(svr.GetHostsInfoBase()) {
+			ret = append(ret, svr)
+		}
+	}
+	return ret
+}
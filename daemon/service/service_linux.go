@@ -39,6 +39,7 @@ import (
 	"github.com/ivpn/desktop-app/daemon/service/preferences"
 	"github.com/ivpn/desktop-app/daemon/shell"
 	"github.com/ivpn/desktop-app/daemon/splittun"
+	"github.com/ivpn/desktop-app/daemon/vpn/wireguard"
 )
 
 func (s *Service) implIsCanApplyUserPreferences(userPrefs preferences.UserPreferences) error {
@@ -52,6 +53,33 @@ func (s *Service) implIsCanApplyUserPreferences(userPrefs preferences.UserPrefer
 			return fmt.Errorf("the old-style DNS management is not applicable to the current environment: %s", dnsMgmtOldErr)
 		}
 	}
+	if userPrefs.Linux.FirewallBackend != s._preferences.UserPrefs.Linux.FirewallBackend {
+		switch userPrefs.Linux.FirewallBackend {
+		case preferences.LinuxFirewallBackendScript:
+		case preferences.LinuxFirewallBackendNftables:
+			if err := firewall.NftablesAvailabilityError(); err != nil {
+				return fmt.Errorf("the nftables firewall backend is not applicable to the current environment: %w", err)
+			}
+		default:
+			return fmt.Errorf("unknown firewall backend '%s'", userPrefs.Linux.FirewallBackend)
+		}
+
+		// the rules of the current backend must be removed before switching to another one
+		if enabled, err := firewall.GetEnabled(); err != nil {
+			return err
+		} else if enabled {
+			return fmt.Errorf("unable to change firewall backend when the firewall is enabled")
+		}
+	}
+	switch userPrefs.Linux.WireGuardBackend {
+	case preferences.LinuxWireGuardBackendAuto, preferences.LinuxWireGuardBackendKernel:
+	case preferences.LinuxWireGuardBackendUserspace:
+		if err := wireguard.UserspaceAvailabilityError(); err != nil {
+			return fmt.Errorf("the WireGuard userspace implementation is not applicable to the current environment: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown WireGuard implementation '%s'", userPrefs.Linux.WireGuardBackend)
+	}
 	return nil
 }
 
@@ -64,10 +92,35 @@ func (s *Service) implGetDisabledFuncForPlatform() protocolTypes.DisabledFunctio
 	if envs := platform.GetSnapEnvs(); envs != nil {
 		linuxFuncs.DnsMgmtOldResolvconfError = "it is not allowed to modify 'resolv.conf' from the snap environment"
 	}
+	if err := firewall.NftablesAvailabilityError(); err != nil {
+		linuxFuncs.NftablesBackendError = err.Error()
+	}
+	if err := wireguard.UserspaceAvailabilityError(); err != nil {
+		linuxFuncs.WireGuardUserspaceError = err.Error()
+	}
 
 	return protocolTypes.DisabledFunctionalityForPlatform{Linux: linuxFuncs}
 }
 
+// implWireGuardAvailabilityError returns error if WireGuard can not be used with the current WireGuard implementation (see 'UserPrefs.Linux.WireGuardBackend')
+// 'binariesErr' - error of WireGuard binaries check ('wg-quick' and 'wg' are required only for the kernel implementation)
+func (s *Service) implWireGuardAvailabilityError(binariesErr error) error {
+	switch s._preferences.UserPrefs.Linux.WireGuardBackend {
+	case preferences.LinuxWireGuardBackendKernel:
+		return binariesErr
+	case preferences.LinuxWireGuardBackendUserspace:
+		if err := wireguard.UserspaceAvailabilityError(); err != nil {
+			return fmt.Errorf("WireGuard userspace implementation: %w", err)
+		}
+		return nil
+	default:
+		if binariesErr != nil && wireguard.UserspaceAvailabilityError() == nil {
+			return nil // the userspace implementation will be used
+		}
+		return binariesErr
+	}
+}
+
 func (s *Service) implPingServersStarting(hosts []net.IP) error {
 	const onlyForICMP = true
 	const isPersistent = false
@@ -118,6 +171,47 @@ func (s *Service) implSplitTunnelling_AddedPidInfo(pid int, exec string, cmdToEx
 	return splittun.AddPid(pid, exec)
 }
 
+// Persistent rule: any process executing the binary defined by the rule (absolute path or desktop file ID)
+// is added to the Split Tunnel environment automatically
+func (s *Service) implSplitTunnelling_AddAppRule(rule string) error {
+	rule, err := splittun.CheckAppRule(rule)
+	if err != nil {
+		return err
+	}
+
+	prefs := s._preferences
+	for _, r := range prefs.SplitTunnelApps {
+		if r == rule {
+			return nil // the rule is already in configuration
+		}
+	}
+	prefs.SplitTunnelApps = append(append([]string{}, prefs.SplitTunnelApps...), rule)
+	s.setPreferences(prefs)
+	return nil
+}
+
+func (s *Service) implSplitTunnelling_RemoveAppRule(rule string) error {
+	rule = strings.TrimSpace(rule)
+	// the rule can be defined with or without the '.desktop' suffix
+	variants := map[string]struct{}{rule: {}, rule + ".desktop": {}, filepath.Clean(rule): {}}
+
+	prefs := s._preferences
+	newStApps := make([]string, 0, len(prefs.SplitTunnelApps))
+	for _, r := range prefs.SplitTunnelApps {
+		if _, ok := variants[r]; ok {
+			continue
+		}
+		newStApps = append(newStApps, r)
+	}
+	if len(newStApps) == len(prefs.SplitTunnelApps) {
+		return fmt.Errorf("rule '%s' is not in the Split Tunnel configuration", rule)
+	}
+
+	prefs.SplitTunnelApps = newStApps
+	s.setPreferences(prefs)
+	return nil
+}
+
 func (s *Service) implGetDiagnosticExtraInfo() (string, error) {
 	ifconfig := s.diagnosticGetCommandOutput("ifconfig")
 	netstat := s.diagnosticGetCommandOutput("netstat", "-nr", "--protocol", "inet,inet6")
@@ -0,0 +1,168 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+
+	"github.com/ivpn/desktop-app/daemon/netinfo"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/service/srverrors"
+)
+
+// initNetworkTrustFunctionality - start processing network changes for 'trusted network' rules
+func (s *Service) initNetworkTrustFunctionality() error {
+	if s._netTrustDetector == nil {
+		return fmt.Errorf("network change detector not defined")
+	}
+
+	netChangedChan := make(chan INetChangeDetectorMessage, 1)
+	if err := s._netTrustDetector.Init(netChangedChan, nil); err != nil {
+		return fmt.Errorf("failed to init network change detector: %w", err)
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("PANIC in network change processing!: ", r)
+			}
+		}()
+
+		<-s._ipStackInitializationWaiter // Wait for IP stack initialization
+		s.updateNetworkTrustMonitoring()
+
+		for {
+			<-netChangedChan
+			// 'trusted-network' functionality: auto-connect if necessary
+			s.autoConnectIfRequired(OnNetworkChanged, nil)
+		}
+	}()
+
+	return nil
+}
+
+// updateNetworkTrustMonitoring - start/stop network changes monitoring (according to preferences)
+func (s *Service) updateNetworkTrustMonitoring() {
+	if s._netTrustDetector == nil {
+		return
+	}
+
+	if s.Preferences().NetworkTrust.IsEnabled {
+		if err := s._netTrustDetector.Start(); err != nil {
+			log.Error(fmt.Errorf("failed to start network change detection: %w", err))
+		}
+	} else {
+		s._netTrustDetector.Stop()
+	}
+}
+
+// SetNetworkTrustSettings - set trust rules for wired (non-WiFi) networks
+func (s *Service) SetNetworkTrustSettings(params preferences.NetworkTrustParams) error {
+	names := make(map[string]struct{})
+	for _, r := range params.Rules {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+		if _, exists := names[r.Name]; exists {
+			return fmt.Errorf("network rule '%s' already exists", r.Name)
+		}
+		names[r.Name] = struct{}{}
+	}
+
+	if params.IsEnabled {
+		// the rules are applied in background: the default connection parameters must be defined
+		prefs := s._preferences
+		if e := prefs.LastConnectionParams.CheckIsDefined(); e != nil {
+			return srverrors.ErrorBackgroundConnectionNoParams{}
+		}
+	}
+
+	// Save settings
+	prefs := s._preferences
+	prefs.NetworkTrust = params
+	s.setPreferences(prefs)
+
+	s.updateNetworkTrustMonitoring()
+
+	// 'trusted-network' functionality: auto-connect if necessary
+	s.autoConnectIfRequired(OnNetworkChanged, nil)
+	return nil
+}
+
+// GetNetworkCurrentInfo returns info about the network which is in use by the default route
+// and the trust rule matching it (nil - no rule matches)
+func (s *Service) GetNetworkCurrentInfo() (info netinfo.DefaultNetworkInfo, matchedRule *preferences.NetworkTrustRule, err error) {
+	info, err = netinfo.GetDefaultNetworkInfo()
+	if err != nil {
+		return info, nil, err
+	}
+
+	if rule, ok := s.Preferences().NetworkTrust.FindRule(info.InterfaceName, info.GatewayIP, info.GatewayMAC, info.DhcpDomain); ok {
+		matchedRule = &rule
+	}
+	return info, matchedRule, nil
+}
+
+// getDefaultNetworkInfo returns info about the network which is in use by the default route
+// (empty - when 'trusted network' functionality disabled or the info is not available)
+func (s *Service) getDefaultNetworkInfo() netinfo.DefaultNetworkInfo {
+	if !s.Preferences().NetworkTrust.IsEnabled {
+		return netinfo.DefaultNetworkInfo{}
+	}
+	info, err := netinfo.GetDefaultNetworkInfo()
+	if err != nil {
+		log.Warning("Unable to obtain info about current network: ", err)
+		return netinfo.DefaultNetworkInfo{}
+	}
+	return info
+}
+
+// getActionForDefaultNetwork returns action according to 'trusted network' rules
+func (s *Service) getActionForDefaultNetwork(netInfo netinfo.DefaultNetworkInfo) (retAction automaticAction) {
+	prefs := s.Preferences()
+	if !prefs.Session.IsLoggedIn() {
+		return
+	}
+
+	networkParams := prefs.NetworkTrust
+	if !networkParams.IsEnabled || len(netInfo.InterfaceName) == 0 {
+		return
+	}
+
+	var isNetworkTrusted *bool // nil - no action
+	var networkRule *preferences.WiFiNetworkRule
+
+	if rule, ok := networkParams.FindRule(netInfo.InterfaceName, netInfo.GatewayIP, netInfo.GatewayMAC, netInfo.DhcpDomain); ok {
+		isNetworkTrusted = &rule.IsTrusted
+		networkRule = rule.Rule
+	} else {
+		// network does not match any rule. Using default configuration
+		isNetworkTrusted = networkParams.DefaultTrustStatusTrusted
+	}
+
+	if isNetworkTrusted == nil {
+		return
+	}
+
+	return getActionForTrustStatus(prefs.WiFiControl, *isNetworkTrusted, networkRule)
+}
@@ -145,6 +145,7 @@ func (s *Service) PingServers(firstPhaseTimeoutMs int, vpnTypePrioritized vpn.Ty
 				log.Warning("(pinging) unable to obtain geo-location (fastest server detection could be not accurate):", err)
 				return
 			}
+			s.serverScoring_setLocation(geoLocation)
 			onGeoLookupChan <- geoLocation
 		}()
 	} else {
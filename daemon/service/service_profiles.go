@@ -0,0 +1,151 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+)
+
+//////////////////////////////////////////////////////////
+// CONNECTION PROFILES
+//////////////////////////////////////////////////////////
+
+// ConnectionProfiles returns all saved connection profiles
+func (s *Service) ConnectionProfiles() []preferences.ConnectionProfile {
+	return s._preferences.ConnectionProfiles
+}
+
+// ConnectionProfileCreate saves new connection profile
+func (s *Service) ConnectionProfileCreate(profile preferences.ConnectionProfile) error {
+	profile.Name = strings.TrimSpace(profile.Name)
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+
+	prefs := s._preferences
+	if _, exists := prefs.ConnectionProfileGet(profile.Name); exists {
+		return fmt.Errorf("connection profile '%s' already exists", profile.Name)
+	}
+
+	profiles := make([]preferences.ConnectionProfile, 0, len(prefs.ConnectionProfiles)+1)
+	profiles = append(profiles, prefs.ConnectionProfiles...)
+	prefs.ConnectionProfiles = append(profiles, profile)
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("Connection profile '%s' created", profile.Name))
+	return nil
+}
+
+// ConnectionProfileUpdate updates existing connection profile.
+// The profile can be renamed (all references to the profile will be updated).
+func (s *Service) ConnectionProfileUpdate(name string, profile preferences.ConnectionProfile) error {
+	profile.Name = strings.TrimSpace(profile.Name)
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+
+	prefs := s._preferences
+	if !strings.EqualFold(strings.TrimSpace(name), profile.Name) {
+		if _, exists := prefs.ConnectionProfileGet(profile.Name); exists {
+			return fmt.Errorf("connection profile '%s' already exists", profile.Name)
+		}
+	}
+
+	isFound := false
+	profiles := make([]preferences.ConnectionProfile, 0, len(prefs.ConnectionProfiles))
+	for _, p := range prefs.ConnectionProfiles {
+		if p.IsNameEqual(name) {
+			p = profile
+			isFound = true
+		}
+		profiles = append(profiles, p)
+	}
+	if !isFound {
+		return fmt.Errorf("connection profile '%s' not found", name)
+	}
+	prefs.ConnectionProfiles = profiles
+
+	// update references to the renamed profile
+	if strings.EqualFold(prefs.AutoconnectProfile, name) {
+		prefs.AutoconnectProfile = profile.Name
+	}
+	if strings.EqualFold(prefs.WiFiControl.Actions.UnTrustedConnectVpnProfile, name) {
+		prefs.WiFiControl.Actions.UnTrustedConnectVpnProfile = profile.Name
+	}
+	if len(prefs.ScheduleRules) > 0 {
+		rules := make([]preferences.ScheduleRule, 0, len(prefs.ScheduleRules))
+		for _, r := range prefs.ScheduleRules {
+			if strings.EqualFold(r.ConnectionProfile, name) {
+				r.ConnectionProfile = profile.Name
+			}
+			rules = append(rules, r)
+		}
+		prefs.ScheduleRules = rules
+	}
+
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("Connection profile '%s' updated", profile.Name))
+	return nil
+}
+
+// ConnectionProfileDelete removes connection profile (and all references to it).
+// The profile which is in use by scheduler rules can not be removed
+// (otherwise, the rules would silently connect with different parameters).
+func (s *Service) ConnectionProfileDelete(name string) error {
+	prefs := s._preferences
+
+	if rules := prefs.ScheduleRulesUsingProfile(name); len(rules) > 0 {
+		return fmt.Errorf("connection profile '%s' is in use by scheduler rules: %s (update or remove the rules first)", name, strings.Join(rules, ", "))
+	}
+
+	isFound := false
+	profiles := make([]preferences.ConnectionProfile, 0, len(prefs.ConnectionProfiles))
+	for _, p := range prefs.ConnectionProfiles {
+		if p.IsNameEqual(name) {
+			isFound = true
+			continue
+		}
+		profiles = append(profiles, p)
+	}
+	if !isFound {
+		return fmt.Errorf("connection profile '%s' not found", name)
+	}
+	prefs.ConnectionProfiles = profiles
+
+	// erase references to the removed profile
+	if strings.EqualFold(prefs.AutoconnectProfile, name) {
+		prefs.AutoconnectProfile = ""
+	}
+	if strings.EqualFold(prefs.WiFiControl.Actions.UnTrustedConnectVpnProfile, name) {
+		prefs.WiFiControl.Actions.UnTrustedConnectVpnProfile = ""
+	}
+
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("Connection profile '%s' removed", name))
+	return nil
+}
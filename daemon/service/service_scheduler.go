@@ -0,0 +1,320 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/service/scheduler"
+	"github.com/ivpn/desktop-app/daemon/service/types"
+)
+
+//////////////////////////////////////////////////////////
+// SCHEDULER
+//////////////////////////////////////////////////////////
+
+// startScheduler - start processing scheduler rules (once per minute)
+func (s *Service) startScheduler() {
+	log.Info("Scheduler started")
+	// Note: the wall clock is in use ('Round(0)' strips the monotonic clock reading)
+	// because the monotonic clock does not count the time when the system was suspended
+	lastProcessed := time.Now().Round(0).Truncate(time.Minute)
+	for {
+		// wait for the beginning of the next minute
+		now := time.Now()
+		time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+
+		now = time.Now().Round(0)
+		current := now.Truncate(time.Minute)
+		s.processSchedulerTick(lastProcessed, current, now)
+		lastProcessed = current
+	}
+}
+
+// processSchedulerTick - apply the rules for the current minute (and the missed ones, if some minutes were skipped).
+// A panic is recovered here, so it does not stop the scheduler: the next minute is processed as usual.
+func (s *Service) processSchedulerTick(lastProcessed, current, now time.Time) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("PANIC in scheduler!: ", r)
+			if err, ok := r.(error); ok {
+				log.ErrorTrace(err)
+			}
+		}
+	}()
+
+	if current.Sub(lastProcessed) > time.Minute {
+		// some minutes were skipped (e.g. the system was suspended)
+		s.processMissedScheduleRules(lastProcessed, current.Add(-time.Minute))
+	}
+	s.processScheduleRules(now)
+}
+
+// processScheduleRules - apply all enabled rules which are matching the time
+func (s *Service) processScheduleRules(t time.Time) {
+	prefs := s.Preferences()
+	if !prefs.Session.IsLoggedIn() {
+		return
+	}
+
+	for _, r := range prefs.ScheduleRules {
+		if !r.IsEnabled {
+			continue
+		}
+		schedule, err := scheduler.Parse(r.Schedule)
+		if err != nil {
+			log.Error(fmt.Sprintf("Scheduler: rule '%s': %s", r.Name, err))
+			continue
+		}
+		if !schedule.IsMatch(t) {
+			continue
+		}
+
+		log.Info(fmt.Sprintf("Scheduler: applying rule '%s' (action '%s')", r.Name, r.Action))
+		if err := s.applyScheduleRule(r); err != nil {
+			log.Error(fmt.Sprintf("Scheduler: rule '%s' failed: %s", r.Name, err))
+		}
+	}
+}
+
+// processMissedScheduleRules - apply the rules which were triggered in the interval (from, to] but were not processed
+// (e.g. the system was suspended). Only the most recent missed trigger for each kind of action is applied
+// (e.g. when both 'connect' at 08:00 and 'disconnect' at 18:00 were missed - only 'disconnect' is applied).
+func (s *Service) processMissedScheduleRules(from, to time.Time) {
+	prefs := s.Preferences()
+	if !prefs.Session.IsLoggedIn() {
+		return
+	}
+
+	type missedRule struct {
+		rule preferences.ScheduleRule
+		time time.Time
+	}
+	missed := make(map[string]missedRule)
+	for _, r := range prefs.ScheduleRules {
+		if !r.IsEnabled {
+			continue
+		}
+		schedule, err := scheduler.Parse(r.Schedule)
+		if err != nil {
+			continue // error will be logged by processScheduleRules()
+		}
+		t := schedule.Last(from, to)
+		if t.IsZero() {
+			continue
+		}
+		kind := scheduleActionKind(r.Action)
+		if m, ok := missed[kind]; !ok || t.After(m.time) {
+			missed[kind] = missedRule{rule: r, time: t}
+		}
+	}
+
+	rules := make([]missedRule, 0, len(missed))
+	for _, m := range missed {
+		rules = append(rules, m)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].time.Before(rules[j].time) })
+
+	for _, m := range rules {
+		log.Info(fmt.Sprintf("Scheduler: applying missed rule '%s' (action '%s'; scheduled at %s)", m.rule.Name, m.rule.Action, m.time.Format("2006-01-02 15:04")))
+		if err := s.applyScheduleRule(m.rule); err != nil {
+			log.Error(fmt.Sprintf("Scheduler: rule '%s' failed: %s", m.rule.Name, err))
+		}
+	}
+}
+
+// scheduleActionKind returns the kind of the action: the actions of the same kind are overriding each other
+func scheduleActionKind(a preferences.ScheduleActionType) string {
+	switch a {
+	case preferences.ScheduleAction_Connect, preferences.ScheduleAction_Disconnect, preferences.ScheduleAction_Resume:
+		return "vpn"
+	case preferences.ScheduleAction_FirewallOn, preferences.ScheduleAction_FirewallOff:
+		return "firewall"
+	case preferences.ScheduleAction_FirewallPersistentOn, preferences.ScheduleAction_FirewallPersistentOff:
+		return "firewall_persistent"
+	}
+	return string(a)
+}
+
+func (s *Service) applyScheduleRule(r preferences.ScheduleRule) error {
+	switch r.Action {
+	case preferences.ScheduleAction_Connect:
+		if s.Connected() {
+			return nil
+		}
+		if !s._evtReceiver.IsCanDoBackgroundAction() {
+			return fmt.Errorf("background actions not allowed")
+		}
+		prefs := s.Preferences()
+		connParams := prefs.LastConnectionParams
+		if len(r.ConnectionProfile) > 0 {
+			profile, ok := prefs.ConnectionProfileGet(r.ConnectionProfile)
+			if !ok {
+				return fmt.Errorf("connection profile '%s' not found", r.ConnectionProfile)
+			}
+			connParams = profile.Params
+		}
+		if r.ServerSelection != types.Default {
+			connParams.Metadata.ServerSelectionEntry = r.ServerSelection
+			connParams.Metadata.ServerLocationEntry = r.ServerLocation
+			connParams.Metadata.ServerGroupEntry = ""
+		}
+		return s.registerAutomaticConnection(connParams)
+
+	case preferences.ScheduleAction_Disconnect:
+		if !s.Connected() {
+			return nil
+		}
+		return s.Disconnect()
+
+	case preferences.ScheduleAction_Resume:
+		if !s.IsPaused() {
+			return nil
+		}
+		return s.Resume()
+
+	case preferences.ScheduleAction_FirewallOn:
+		return s.SetKillSwitchState(true)
+
+	case preferences.ScheduleAction_FirewallOff:
+		return s.SetKillSwitchState(false)
+
+	case preferences.ScheduleAction_FirewallPersistentOn:
+		return s.SetKillSwitchIsPersistent(true)
+
+	case preferences.ScheduleAction_FirewallPersistentOff:
+		return s.SetKillSwitchIsPersistent(false)
+	}
+
+	return fmt.Errorf("unsupported action '%s'", r.Action)
+}
+
+// ScheduleRules returns all scheduler rules
+func (s *Service) ScheduleRules() []preferences.ScheduleRule {
+	return s._preferences.ScheduleRules
+}
+
+// ScheduleRuleCreate saves new scheduler rule
+func (s *Service) ScheduleRuleCreate(rule preferences.ScheduleRule) error {
+	prefs := s._preferences
+	if err := s.scheduleRuleNormalize(&rule, &prefs); err != nil {
+		return err
+	}
+	if _, exists := prefs.ScheduleRuleGet(rule.Name); exists {
+		return fmt.Errorf("schedule rule '%s' already exists", rule.Name)
+	}
+
+	rules := make([]preferences.ScheduleRule, 0, len(prefs.ScheduleRules)+1)
+	rules = append(rules, prefs.ScheduleRules...)
+	prefs.ScheduleRules = append(rules, rule)
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("Schedule rule '%s' created", rule.Name))
+	return nil
+}
+
+// ScheduleRuleUpdate updates existing scheduler rule (the rule can be renamed)
+func (s *Service) ScheduleRuleUpdate(name string, rule preferences.ScheduleRule) error {
+	prefs := s._preferences
+	if err := s.scheduleRuleNormalize(&rule, &prefs); err != nil {
+		return err
+	}
+	if !strings.EqualFold(strings.TrimSpace(name), rule.Name) {
+		if _, exists := prefs.ScheduleRuleGet(rule.Name); exists {
+			return fmt.Errorf("schedule rule '%s' already exists", rule.Name)
+		}
+	}
+
+	isFound := false
+	rules := make([]preferences.ScheduleRule, 0, len(prefs.ScheduleRules))
+	for _, r := range prefs.ScheduleRules {
+		if r.IsNameEqual(name) {
+			r = rule
+			isFound = true
+		}
+		rules = append(rules, r)
+	}
+	if !isFound {
+		return fmt.Errorf("schedule rule '%s' not found", name)
+	}
+	prefs.ScheduleRules = rules
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("Schedule rule '%s' updated", rule.Name))
+	return nil
+}
+
+// ScheduleRuleDelete removes scheduler rule
+func (s *Service) ScheduleRuleDelete(name string) error {
+	prefs := s._preferences
+
+	isFound := false
+	rules := make([]preferences.ScheduleRule, 0, len(prefs.ScheduleRules))
+	for _, r := range prefs.ScheduleRules {
+		if r.IsNameEqual(name) {
+			isFound = true
+			continue
+		}
+		rules = append(rules, r)
+	}
+	if !isFound {
+		return fmt.Errorf("schedule rule '%s' not found", name)
+	}
+	prefs.ScheduleRules = rules
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("Schedule rule '%s' removed", name))
+	return nil
+}
+
+// scheduleRuleNormalize validates the rule, normalizes the connection profile name and checks the server location
+func (s *Service) scheduleRuleNormalize(rule *preferences.ScheduleRule, prefs *preferences.Preferences) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	rule.Schedule = strings.TrimSpace(rule.Schedule)
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	if len(rule.ConnectionProfile) > 0 {
+		profile, ok := prefs.ConnectionProfileGet(rule.ConnectionProfile)
+		if !ok {
+			return fmt.Errorf("connection profile '%s' not found", rule.ConnectionProfile)
+		}
+		rule.ConnectionProfile = profile.Name
+	}
+	rule.ServerLocation = strings.TrimSpace(rule.ServerLocation)
+	if len(rule.ServerLocation) > 0 {
+		servers, err := s.ServersList()
+		if err != nil {
+			return fmt.Errorf("failed to check server location: %w", err)
+		}
+		if len(filterServersByLocation(rule.ServerLocation, servers.WireguardServers)) == 0 &&
+			len(filterServersByLocation(rule.ServerLocation, servers.OpenvpnServers)) == 0 {
+			return fmt.Errorf("no servers found for location '%s'", rule.ServerLocation)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+)
+
+//////////////////////////////////////////////////////////
+// FAVOURITE SERVERS AND SERVER GROUPS
+//////////////////////////////////////////////////////////
+
+// FavouriteServersSet saves the list of favourite servers (gateway IDs, e.g. "nl-ams" or "nl-ams.wg.ivpn.net")
+func (s *Service) FavouriteServersSet(gateways []string) error {
+	gateways = preferences.NormalizeGateways(gateways)
+	if len(gateways) > preferences.MaxServerGroupItems {
+		return fmt.Errorf("too many favourite servers (max %d)", preferences.MaxServerGroupItems)
+	}
+
+	prefs := s._preferences
+	prefs.FavouriteServers = gateways
+	s.setPreferences(prefs)
+	return nil
+}
+
+// ServerGroupSet saves the server group (the existing group with the same name will be replaced)
+func (s *Service) ServerGroupSet(group preferences.ServerGroup) error {
+	if err := group.Validate(); err != nil {
+		return err
+	}
+	group = group.Normalized()
+
+	prefs := s._preferences
+	isFound := false
+	groups := make([]preferences.ServerGroup, 0, len(prefs.ServerGroups)+1)
+	for _, g := range prefs.ServerGroups {
+		if g.IsNameEqual(group.Name) {
+			g = group
+			isFound = true
+		}
+		groups = append(groups, g)
+	}
+	if !isFound {
+		if len(groups) >= preferences.MaxServerGroups {
+			return fmt.Errorf("too many server groups (max %d)", preferences.MaxServerGroups)
+		}
+		groups = append(groups, group)
+	}
+	prefs.ServerGroups = groups
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("Server group '%s' saved", group.Name))
+	return nil
+}
+
+// ServerGroupDelete removes the server group
+func (s *Service) ServerGroupDelete(name string) error {
+	prefs := s._preferences
+
+	isFound := false
+	groups := make([]preferences.ServerGroup, 0, len(prefs.ServerGroups))
+	for _, g := range prefs.ServerGroups {
+		if g.IsNameEqual(name) {
+			isFound = true
+			continue
+		}
+		groups = append(groups, g)
+	}
+	if !isFound {
+		return fmt.Errorf("server group '%s' not found", name)
+	}
+	prefs.ServerGroups = groups
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("Server group '%s' removed", name))
+	return nil
+}
+
+// filterServersByGroup returns only the servers which are members of the server group (empty group name - no filtering)
+func filterServersByGroup[S serverBaseInterface](prefs preferences.Preferences, groupName string, servers []S) ([]S, error) {
+	if len(strings.TrimSpace(groupName)) == 0 {
+		return servers, nil
+	}
+	group, ok := prefs.ServerGroupGet(groupName)
+	if !ok {
+		return nil, fmt.Errorf("server group '%s' not found (or empty)", groupName)
+	}
+
+	ret := make([]S, 0, len(group.Gateways))
+	for _, svr := range servers {
+		if group.Contains(svr.GetServerInfoBase().Gateway) {
+			ret = append(ret, svr)
+		}
+	}
+	return ret, nil
+}
+
+// filterServersByLocation returns only the servers from the location (empty location - no filtering).
+// Location: country code ("NL"), country, city or gateway ID ("nl-ams") (case-insensitive)
+func filterServersByLocation[S serverBaseInterface](location string, servers []S) []S {
+	location = strings.TrimSpace(location)
+	if len(location) == 0 {
+		return servers
+	}
+
+	gatewayID := preferences.NormalizeGatewayID(location)
+	ret := make([]S, 0, len(servers))
+	for _, svr := range servers {
+		base := svr.GetServerInfoBase()
+		if strings.EqualFold(base.CountryCode, location) ||
+			strings.EqualFold(base.Country, location) ||
+			strings.EqualFold(base.City, location) ||
+			preferences.NormalizeGatewayID(base.Gateway) == gatewayID {
+			ret = append(ret, svr)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,227 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	apiTypes "github.com/ivpn/desktop-app/daemon/api/types"
+	"github.com/ivpn/desktop-app/daemon/helpers"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/service/types"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+type serverConnHistory struct {
+	attempts  int
+	successes int
+}
+
+// SetServerScoringWeights saves weights of the factors for the 'Best' server selection
+// (see 'types.ServerScoringWeights')
+func (s *Service) SetServerScoringWeights(weights types.ServerScoringWeights) error {
+	if err := weights.Validate(); err != nil {
+		return err
+	}
+
+	prefs := s._preferences
+	prefs.ServerScoring = weights
+	s.setPreferences(prefs)
+	return nil
+}
+
+// ServerScores returns the servers sorted by score (the best server is the first one)
+// vpnType - the servers list to use (WireGuard or OpenVPN)
+// gateways - (optional) the servers to take into account (e.g. "us-tx.wg.ivpn.net"); empty - all servers
+func (s *Service) ServerScores(vpnType vpn.Type, gateways []string) ([]types.ServerScore, error) {
+	allServers, err := s.ServersList()
+	if err != nil {
+		return nil, err
+	}
+
+	var inputs []types.ServerScoreInput
+	if vpnType == vpn.OpenVPN {
+		inputs = serverScoreInputs(s, vpnType, filterServersByGateway(allServers.OpenvpnServers, gateways))
+	} else {
+		inputs = serverScoreInputs(s, vpnType, filterServersByGateway(allServers.WireguardServers, gateways))
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no servers to score")
+	}
+
+	return types.ScoreServers(inputs, s._preferences.ServerScoring), nil
+}
+
+func filterServersByGateway[S serverBaseInterface](servers []S, gateways []string) []S {
+	if len(gateways) == 0 {
+		return servers
+	}
+	required := make(map[string]struct{}, len(gateways))
+	for _, gw := range gateways {
+		required[strings.ToLower(gw)] = struct{}{}
+	}
+	ret := make([]S, 0, len(gateways))
+	for _, svr := range servers {
+		if _, ok := required[strings.ToLower(svr.GetServerInfoBase().Gateway)]; ok {
+			ret = append(ret, svr)
+		}
+	}
+	return ret
+}
+
+// serverScoreInputs collects all the information required to calculate servers scores
+// (ping results, servers load, distance to the servers and connection history)
+func serverScoreInputs[S serverBaseInterface](service *Service, vpnTypePrioritized vpn.Type, servers []S) []types.ServerScoreInput {
+	pingResults, err := service.PingServers(4000, vpnTypePrioritized, true)
+	if err != nil {
+		log.Warning("(server scoring) latency info not available: ", err)
+	}
+	location := service.serverScoring_getLocation()
+	hostsSelection := service._preferences.HostsSelection
+
+	service._serverHistory._mutex.Lock()
+	defer service._serverHistory._mutex.Unlock()
+
+	ret := make([]types.ServerScoreInput, 0, len(servers))
+	for _, svr := range servers {
+		info := svr.GetServerInfoBase()
+		// only the hosts which can be used for connection (see 'preferences.HostsSelectionParams')
+		hosts, err := preferences.ApplyHostsSelection(hostsSelection, svr.GetHostsInfoBase())
+		if err != nil || len(hosts) == 0 {
+			continue
+		}
+
+		in := types.ServerScoreInput{Gateway: info.Gateway, DistanceKm: -1}
+		if location != nil {
+			in.DistanceKm = helpers.GetDistanceFromLatLonInKm(float64(location.Latitude), float64(location.Longitude), float64(info.Latitude), float64(info.Longitude))
+		}
+
+		var loadSum float32
+		for _, h := range hosts {
+			loadSum += h.Load
+			if latency, ok := pingResults[h.Host]; ok && latency > 0 && (in.LatencyMs == 0 || latency < in.LatencyMs) {
+				in.LatencyMs = latency
+			}
+			if hist, ok := service._serverHistory._hosts[h.Host]; ok {
+				in.ConnectAttempts += hist.attempts
+				in.ConnectSuccesses += hist.successes
+			}
+		}
+		in.Load = loadSum / float32(len(hosts))
+
+		ret = append(ret, in)
+	}
+	return ret
+}
+
+// getBestServer returns the server with the best score (see 'types.ScoreServers()')
+func getBestServer[S serverBaseInterface](service *Service, vpnTypePrioritized vpn.Type, servers []S, excludedGateways []string) (ret S, err error) {
+	// Remove everything after symbol '.': "us-tx.wg.ivpn.net" => "us-tx"; or "us-tx" => "us-tx"
+	normalizeGwId := func(gwId string) string {
+		return strings.Split(gwId, ".")[0]
+	}
+	excludedGatewaysHashed := make(map[string]struct{})
+	for _, gw := range excludedGateways {
+		excludedGatewaysHashed[normalizeGwId(gw)] = struct{}{}
+	}
+
+	applicableServers := make([]S, 0, len(servers))
+	for _, svr := range servers {
+		if _, ok := excludedGatewaysHashed[normalizeGwId(svr.GetServerInfoBase().Gateway)]; ok {
+			continue
+		}
+		applicableServers = append(applicableServers, svr)
+	}
+
+	scores := types.ScoreServers(serverScoreInputs(service, vpnTypePrioritized, applicableServers), service._preferences.ServerScoring)
+	if len(scores) == 0 {
+		return ret, fmt.Errorf("unable to determine the best server: no applicable servers")
+	}
+
+	best := scores[0]
+	log.Info(fmt.Sprintf("Best server: %s (score=%.1f; latency=%dms; load=%.1f%%; distance=%.0fkm; connections=%d/%d)",
+		best.Gateway, best.Score, best.LatencyMs, best.Load, best.DistanceKm, best.ConnectSuccesses, best.ConnectAttempts))
+
+	for _, svr := range applicableServers {
+		if svr.GetServerInfoBase().Gateway == best.Gateway {
+			return svr, nil
+		}
+	}
+	return ret, fmt.Errorf("unable to determine the best server")
+}
+
+// serverScoring_setLocation saves the current geo-location (it is in use to calculate the distance to servers).
+// Must be called only when VPN is disconnected (otherwise, the location of the VPN server will be saved).
+func (s *Service) serverScoring_setLocation(location *apiTypes.GeoLookupResponse) {
+	if location == nil {
+		return
+	}
+	l := *location
+
+	s._serverHistory._mutex.Lock()
+	defer s._serverHistory._mutex.Unlock()
+	s._serverHistory._location = &l
+}
+
+func (s *Service) serverScoring_getLocation() *apiTypes.GeoLookupResponse {
+	s._serverHistory._mutex.Lock()
+	defer s._serverHistory._mutex.Unlock()
+	return s._serverHistory._location
+}
+
+// serverHistory_onConnecting registers the connection attempt to the host
+func (s *Service) serverHistory_onConnecting(host string) {
+	if net.ParseIP(host) == nil {
+		return
+	}
+
+	s._serverHistory._mutex.Lock()
+	defer s._serverHistory._mutex.Unlock()
+
+	if s._serverHistory._hosts == nil {
+		s._serverHistory._hosts = make(map[string]*serverConnHistory)
+	}
+	hist, ok := s._serverHistory._hosts[host]
+	if !ok {
+		hist = &serverConnHistory{}
+		s._serverHistory._hosts[host] = hist
+	}
+	hist.attempts++
+}
+
+// serverHistory_onConnected registers the successful connection to the host
+func (s *Service) serverHistory_onConnected(host net.IP) {
+	if host == nil {
+		return
+	}
+
+	s._serverHistory._mutex.Lock()
+	defer s._serverHistory._mutex.Unlock()
+
+	// take into account only one success for each attempt (e.g. reconnections are not counted)
+	if hist, ok := s._serverHistory._hosts[host.String()]; ok && hist.successes < hist.attempts {
+		hist.successes++
+	}
+}
@@ -0,0 +1,332 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/service/dns"
+	"github.com/ivpn/desktop-app/daemon/service/dns/dnsforwarder"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/service/types"
+	"github.com/ivpn/desktop-app/daemon/splittun"
+)
+
+//////////////////////////////////////////////////////////
+// SPLIT TUNNEL DESTINATIONS (NETWORKS AND DOMAINS)
+//////////////////////////////////////////////////////////
+
+// The addresses received in DNS answers are kept at least this time (even if TTL of the DNS record is shorter):
+// applications can cache the addresses and keep the connections open longer than TTL
+const splitTunnelDomainAddrMinLifetime = time.Hour
+
+type splitTunnelDomainState struct {
+	addrs   map[string]time.Time // IP address -> expiration time
+	updated time.Time            // time of the last DNS answer with the addresses of the domain
+}
+
+// initSplitTunnelDomains - start tracking addresses of the Split Tunnel destination domains.
+//
+// The addresses are taken from the DNS answers received by the applications: when domains are configured,
+// the DNS requests of the system are passed through the local DNS forwarder of the daemon (see dns.SetAnswersHandler()).
+// The new addresses are applied to the Split Tunnel configuration before the DNS answer is passed to the application.
+// Subdomains of the configured domain are covered too (e.g. "example.com" covers "www.example.com").
+// NOTE: the DNS answers are tracked only while the daemon manages the DNS configuration (VPN connected);
+// applications which use own DNS resolvers (e.g. DNS-over-HTTPS in a browser) are not covered.
+func (s *Service) initSplitTunnelDomains() {
+	s.updateSplitTunnelDomains()
+
+	// remove expired addresses
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("PANIC in Split Tunnel domains tracker!: ", r)
+				if err, ok := r.(error); ok {
+					log.ErrorTrace(err)
+				}
+			}
+		}()
+
+		for {
+			time.Sleep(time.Minute)
+			if s.removeExpiredSplitTunnelDomainAddrs(time.Now()) {
+				s.applySplitTunnelDomains()
+			}
+		}
+	}()
+}
+
+// updateSplitTunnelDomains - update the list of the tracked domains (according to the preferences)
+func (s *Service) updateSplitTunnelDomains() {
+	domains := s._preferences.SplitTunnelDomains
+
+	func() {
+		s._stDomains._mutex.Lock()
+		defer s._stDomains._mutex.Unlock()
+		for d := range s._stDomains._domains {
+			if !contains(domains, d) {
+				delete(s._stDomains._domains, d)
+			}
+		}
+	}()
+
+	var handler dnsforwarder.AnswerHandler
+	if len(domains) > 0 {
+		handler = s.onSplitTunnelDnsAnswer
+	}
+	if err := dns.SetAnswersHandler(handler); err != nil {
+		log.Error("Failed to update tracking of DNS answers for Split Tunnel domains: ", err)
+	}
+}
+
+// onSplitTunnelDnsAnswer - called for each DNS answer passed through the local DNS forwarder
+// (the answer is passed to the application only after this function returns)
+func (s *Service) onSplitTunnelDnsAnswer(name string, addrs []dnsforwarder.Address) {
+	domain, ok := types.SplitTunnelDomainMatch(s._preferences.SplitTunnelDomains, name)
+	if !ok {
+		return
+	}
+	if s.addSplitTunnelDomainAddrs(domain, addrs, time.Now()) {
+		s.applySplitTunnelDomains()
+	}
+}
+
+// addSplitTunnelDomainAddrs - keep the addresses of the domain; returns true when new addresses were added
+func (s *Service) addSplitTunnelDomainAddrs(domain string, addrs []dnsforwarder.Address, now time.Time) (isAdded bool) {
+	s._stDomains._mutex.Lock()
+	defer s._stDomains._mutex.Unlock()
+
+	if s._stDomains._domains == nil {
+		s._stDomains._domains = make(map[string]*splitTunnelDomainState)
+	}
+	st, ok := s._stDomains._domains[domain]
+	if !ok {
+		st = &splitTunnelDomainState{addrs: make(map[string]time.Time)}
+		s._stDomains._domains[domain] = st
+	}
+
+	st.updated = now
+	for _, a := range addrs {
+		ttl := a.TTL
+		if ttl < splitTunnelDomainAddrMinLifetime {
+			ttl = splitTunnelDomainAddrMinLifetime
+		}
+		ip := a.IP.String()
+		expires, exists := st.addrs[ip]
+		if !exists {
+			isAdded = true
+		}
+		if !exists || now.Add(ttl).After(expires) {
+			st.addrs[ip] = now.Add(ttl)
+		}
+	}
+	return isAdded
+}
+
+// removeExpiredSplitTunnelDomainAddrs - returns true when any address was removed
+func (s *Service) removeExpiredSplitTunnelDomainAddrs(now time.Time) (isRemoved bool) {
+	s._stDomains._mutex.Lock()
+	defer s._stDomains._mutex.Unlock()
+
+	for _, st := range s._stDomains._domains {
+		for ip, expires := range st.addrs {
+			if now.After(expires) {
+				delete(st.addrs, ip)
+				isRemoved = true
+			}
+		}
+	}
+	return isRemoved
+}
+
+// applySplitTunnelDomains - apply the changed addresses of the domains
+func (s *Service) applySplitTunnelDomains() {
+	if !s._preferences.IsSplitTunnel {
+		// nothing to apply; just notify clients about the addresses
+		s._evtReceiver.OnSplitTunnelStatusChanged()
+		return
+	}
+	if err := s.splitTunnelling_ApplyConfig(); err != nil {
+		log.Error("Failed to apply Split Tunnel configuration for the domains: ", err)
+	}
+}
+
+func (s *Service) splitTunnelDomainsStatus() []types.SplitTunnelDomainStatus {
+	domains := s._preferences.SplitTunnelDomains
+	if len(domains) == 0 {
+		return nil
+	}
+
+	var errTracking string
+	if err := dns.AnswersTrackingError(); err != nil {
+		errTracking = "DNS answers are not tracked: " + err.Error()
+	}
+
+	s._stDomains._mutex.Lock()
+	defer s._stDomains._mutex.Unlock()
+
+	ret := make([]types.SplitTunnelDomainStatus, 0, len(domains))
+	for _, d := range domains {
+		status := types.SplitTunnelDomainStatus{Domain: d, Error: errTracking}
+		if st, ok := s._stDomains._domains[d]; ok {
+			status.LastUpdated = st.updated
+			for ip, expires := range st.addrs {
+				status.IPs = append(status.IPs, ip)
+				if expires.After(status.Expires) {
+					status.Expires = expires
+				}
+			}
+			sort.Strings(status.IPs)
+		}
+		ret = append(ret, status)
+	}
+	return ret
+}
+
+// splitTunnelDestinations returns all Split Tunnel destinations: the configured networks and the addresses of the domains
+func (s *Service) splitTunnelDestinations(prefs preferences.Preferences) []net.IPNet {
+	ret := types.SplitTunnelRoutesToNets(prefs.SplitTunnelRoutes)
+	if len(prefs.SplitTunnelDomains) == 0 {
+		return ret
+	}
+
+	s._stDomains._mutex.Lock()
+	defer s._stDomains._mutex.Unlock()
+
+	for _, d := range prefs.SplitTunnelDomains {
+		st, ok := s._stDomains._domains[d]
+		if !ok {
+			continue
+		}
+		for addr := range st.addrs {
+			ip := net.ParseIP(addr)
+			if ip4 := ip.To4(); ip4 != nil {
+				ret = append(ret, net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)})
+			} else if ip != nil {
+				ret = append(ret, net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)})
+			}
+		}
+	}
+	return ret
+}
+
+func (s *Service) SplitTunnelling_AddRoute(route string) error {
+	if err := splittun.GetDestinationsNotAvailableError(); err != nil {
+		return err
+	}
+	r, err := types.ParseSplitTunnelRoute(route)
+	if err != nil {
+		return err
+	}
+	// apply ST configuration after function ends
+	defer s.splitTunnelling_ApplyConfig()
+
+	prefs := s._preferences
+	if !contains(prefs.SplitTunnelRoutes, r) {
+		prefs.SplitTunnelRoutes = append(append([]string{}, prefs.SplitTunnelRoutes...), r)
+		s.setPreferences(prefs)
+	}
+	return nil
+}
+
+func (s *Service) SplitTunnelling_RemoveRoute(route string) error {
+	r, err := types.ParseSplitTunnelRoute(route)
+	if err != nil {
+		return err
+	}
+	// apply ST configuration after function ends
+	defer s.splitTunnelling_ApplyConfig()
+
+	prefs := s._preferences
+	routes, removed := removeString(prefs.SplitTunnelRoutes, r)
+	if !removed {
+		return fmt.Errorf("route '%s' is not in the Split Tunnel configuration", r)
+	}
+	prefs.SplitTunnelRoutes = routes
+	s.setPreferences(prefs)
+	return nil
+}
+
+func (s *Service) SplitTunnelling_AddDomain(domain string) error {
+	if err := splittun.GetDestinationsNotAvailableError(); err != nil {
+		return err
+	}
+	d, err := types.ParseSplitTunnelDomain(domain)
+	if err != nil {
+		return err
+	}
+	// apply ST configuration after function ends
+	defer s.splitTunnelling_ApplyConfig()
+
+	prefs := s._preferences
+	if !contains(prefs.SplitTunnelDomains, d) {
+		prefs.SplitTunnelDomains = append(append([]string{}, prefs.SplitTunnelDomains...), d)
+		s.setPreferences(prefs)
+		// the addresses will be applied when they are received in DNS answers
+		s.updateSplitTunnelDomains()
+	}
+	return nil
+}
+
+func (s *Service) SplitTunnelling_RemoveDomain(domain string) error {
+	d, err := types.ParseSplitTunnelDomain(domain)
+	if err != nil {
+		return err
+	}
+	// apply ST configuration after function ends
+	defer s.splitTunnelling_ApplyConfig()
+
+	prefs := s._preferences
+	domains, removed := removeString(prefs.SplitTunnelDomains, d)
+	if !removed {
+		return fmt.Errorf("domain '%s' is not in the Split Tunnel configuration", d)
+	}
+	prefs.SplitTunnelDomains = domains
+	s.setPreferences(prefs)
+	s.updateSplitTunnelDomains()
+	return nil
+}
+
+func contains(list []string, v string) bool {
+	for _, e := range list {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(list []string, v string) (ret []string, removed bool) {
+	ret = make([]string, 0, len(list))
+	for _, e := range list {
+		if e == v {
+			removed = true
+			continue
+		}
+		ret = append(ret, e)
+	}
+	return ret, removed
+}
@@ -0,0 +1,97 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/service/types"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+// the minimal interval between samples of traffic counters to calculate throughput
+// (OpenVPN updates the counters once per second)
+const tunnelStatsMinInterval = time.Second
+
+// TunnelStatistics returns traffic statistics of the active VPN tunnel: per-peer counters, latest handshake age and current throughput.
+// If VPN is not connected - returns empty object ('IsConnected' = false).
+// Note: when there is no recent sample of the counters, the function blocks for 'tunnelStatsMinInterval' to measure the throughput.
+func (s *Service) TunnelStatistics() (types.TunnelStatistics, error) {
+	s._tunnelStats._mutex.Lock()
+	defer s._tunnelStats._mutex.Unlock()
+
+	stats, vpnObj, err := s.tunnelStatisticsSample()
+	if err != nil || vpnObj == nil {
+		return stats, err
+	}
+
+	st := &s._tunnelStats
+	if st._vpnObj != vpnObj {
+		// new connection: forget samples of the previous one
+		st._vpnObj = vpnObj
+		st._base = types.TunnelStatistics{}
+		st._prevBase = types.TunnelStatistics{}
+	}
+
+	if st._base.Time.IsZero() {
+		// no previous samples: measure the throughput during the minimal interval
+		st._base = stats
+		time.Sleep(tunnelStatsMinInterval)
+		if stats, vpnObj, err = s.tunnelStatisticsSample(); err != nil || vpnObj != st._vpnObj {
+			return stats, err
+		}
+	}
+
+	// ensure the reference sample is not too close to the current one
+	ref := st._base
+	if stats.Time.Sub(ref.Time) < tunnelStatsMinInterval {
+		ref = st._prevBase
+	}
+	stats.CalculateThroughput(ref)
+
+	if stats.Time.Sub(st._base.Time) >= tunnelStatsMinInterval {
+		st._prevBase = st._base
+		st._base = stats
+	}
+
+	return stats, nil
+}
+
+func (s *Service) tunnelStatisticsSample() (types.TunnelStatistics, vpn.Process, error) {
+	vpnObj := s._vpn
+	if vpnObj == nil {
+		return types.TunnelStatistics{}, nil, nil
+	}
+
+	sp, ok := vpnObj.(vpn.StatisticsProcess)
+	if !ok {
+		return types.TunnelStatistics{}, nil, fmt.Errorf("traffic statistics are not supported for %s connection", vpnObj.Type())
+	}
+
+	counters, err := sp.Statistics()
+	if err != nil {
+		return types.TunnelStatistics{}, nil, err
+	}
+	return types.NewTunnelStatistics(vpnObj.Type(), counters, time.Now()), vpnObj, nil
+}
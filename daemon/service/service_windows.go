@@ -44,6 +44,10 @@ func (s *Service) implGetDisabledFuncForPlatform() protocolTypes.DisabledFunctio
 	return protocolTypes.DisabledFunctionalityForPlatform{}
 }
 
+func (s *Service) implWireGuardAvailabilityError(binariesErr error) error {
+	return binariesErr
+}
+
 func (s *Service) implPingServersStarting(hosts []net.IP) error {
 	// nothing to do for Windows implementation
 	// firewall configured to allow all connectivity for service
@@ -114,6 +118,16 @@ func (s *Service) implSplitTunnelling_RemoveApp(pid int, binaryPath string) (err
 	return nil
 }
 
+// On Windows, the applications configuration is always persistent (the same as SplitTunnelAddApp/SplitTunnelRemoveApp)
+func (s *Service) implSplitTunnelling_AddAppRule(rule string) error {
+	_, _, err := s.implSplitTunnelling_AddApp(rule)
+	return err
+}
+
+func (s *Service) implSplitTunnelling_RemoveAppRule(rule string) error {
+	return s.implSplitTunnelling_RemoveApp(0, rule)
+}
+
 func (s *Service) implSplitTunnelling_AddedPidInfo(pid int, exec string, cmdToExecute string) error {
 	return fmt.Errorf("function not applicable for this platform")
 }
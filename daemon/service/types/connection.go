@@ -38,8 +38,9 @@ type ServerSelectionEnum int
 
 const (
 	Default ServerSelectionEnum = iota // Server is manually defined
-	Fastest ServerSelectionEnum = iota // Fastest server in use (only for 'Entry' server)
+	Fastest ServerSelectionEnum = iota // Fastest server in use (for 'Exit' server: only in combination with the servers group)
 	Random  ServerSelectionEnum = iota // Random server in use
+	Best    ServerSelectionEnum = iota // Best server by score: latency, load, distance and connection history (for 'Exit' server: only in combination with the servers group; see ServerScoringWeights)
 )
 
 type AntiTrackerMetadata struct {
@@ -59,15 +60,38 @@ func (a AntiTrackerMetadata) Equal(b AntiTrackerMetadata) bool {
 type ConnectMetadata struct {
 	// How the entry server was chosen
 	ServerSelectionEntry ServerSelectionEnum
-	// How the exit server was chosen ('Fastest' is not applicable for 'Exit' server)
+	// How the exit server was chosen ('Fastest' and 'Best' are applicable for 'Exit' server only when 'ServerGroupExit' defined)
 	ServerSelectionExit ServerSelectionEnum
 
+	// (optional) Name of the servers group (see preferences.ServerGroup; "favourites" - favourite servers).
+	// When defined, the Random/Fastest/Best server is chosen only from the group members.
+	ServerGroupEntry string
+	ServerGroupExit  string
+
+	// (optional) Location of the entry servers for Random/Fastest/Best selection:
+	// country code ("NL"), country, city or gateway ID ("nl-ams") (case-insensitive)
+	ServerLocationEntry string
+
 	AntiTracker AntiTrackerMetadata
 
-	// (only if Fastest server in use) List of fastest servers which must be ignored (only gateway ID in use: e.g."us-tx.wg.ivpn.net" => "us-tx")
+	// (only if Fastest or Best server in use) List of servers which must be ignored (only gateway ID in use: e.g."us-tx.wg.ivpn.net" => "us-tx")
 	FastestGatewaysExcludeList []string
 }
 
+// IsExitServerSelectionApplicable returns 'true' when the Multi-Hop exit server has to be chosen automatically:
+// a random server, or the Fastest/Best server of the group 'ServerGroupExit'.
+// Note: the 'Fastest' exit server is determined by the latency measured from this device (not from the entry server).
+func (m ConnectMetadata) IsExitServerSelectionApplicable() bool {
+	switch m.ServerSelectionExit {
+	case Random:
+		return true
+	case Fastest, Best:
+		return len(m.ServerGroupExit) > 0
+	default:
+		return false
+	}
+}
+
 // Connect request to establish new VPN connection
 type ConnectionParams struct {
 	Metadata ConnectMetadata
@@ -104,6 +128,10 @@ type ConnectionParams struct {
 		Mtu int // Set 0 to use default MTU value
 
 		V2RayProxy v2r.V2RayTransportType // V2Ray config
+
+		// Use the custom WireGuard configuration (stored by the daemon) instead of IVPN servers.
+		// When 'true' - all the rest WireGuard parameters are ignored
+		CustomConfig bool
 	}
 
 	OpenVpnParameters struct {
@@ -129,6 +157,10 @@ type ConnectionParams struct {
 
 		Obfs4proxy obfsproxy.Config       // Obfsproxy config (ignored when 'V2RayProxy' defined)
 		V2RayProxy v2r.V2RayTransportType // V2Ray config (this option takes precedence over the 'Obfs4proxy')
+
+		// Use the custom OpenVPN configuration (stored by the daemon) instead of IVPN servers.
+		// When 'true' - all the rest OpenVPN parameters are ignored
+		CustomConfig bool
 	}
 }
 
@@ -139,7 +171,25 @@ func (p ConnectionParams) IsMultiHop() bool {
 	return len(p.WireGuardParameters.MultihopExitServer.Hosts) > 0
 }
 
+// IsWireGuardCustomConfig returns 'true' when the connection uses the custom WireGuard configuration
+func (p ConnectionParams) IsWireGuardCustomConfig() bool {
+	return p.VpnType == vpn.WireGuard && p.WireGuardParameters.CustomConfig
+}
+
+// IsOpenVpnCustomConfig returns 'true' when the connection uses the custom OpenVPN configuration
+func (p ConnectionParams) IsOpenVpnCustomConfig() bool {
+	return p.VpnType == vpn.OpenVPN && p.OpenVpnParameters.CustomConfig
+}
+
+// IsCustomConfig returns 'true' when the connection uses the custom (WireGuard or OpenVPN) configuration
+func (p ConnectionParams) IsCustomConfig() bool {
+	return p.IsWireGuardCustomConfig() || p.IsOpenVpnCustomConfig()
+}
+
 func (p ConnectionParams) CheckIsDefined() error {
+	if p.IsCustomConfig() {
+		return nil
+	}
 	if p.VpnType == vpn.WireGuard {
 		if len(p.WireGuardParameters.EntryVpnServer.Hosts) <= 0 {
 			return fmt.Errorf("no hosts defined for WireGuard connection")
@@ -174,6 +224,10 @@ func (p ConnectionParams) V2Ray() v2r.V2RayTransportType {
 // 4.1) each exit server must have initialized 'multihop_port' field
 // 4.2) (in case of IPv6Only) IPv6 local address should be defined
 func (p *ConnectionParams) NormalizeHosts() error {
+	if p.IsCustomConfig() {
+		// nothing to normalize: hosts are defined by the custom configuration
+		return nil
+	}
 
 	if vpn.Type(p.VpnType) == vpn.OpenVPN {
 		// in case of multiple entry hosts - take random host from the list
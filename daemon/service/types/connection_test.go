@@ -0,0 +1,52 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types_test
+
+import (
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/service/types"
+)
+
+func TestIsExitServerSelectionApplicable(t *testing.T) {
+	tests := []struct {
+		metadata types.ConnectMetadata
+		expected bool
+	}{
+		{types.ConnectMetadata{}, false},
+		{types.ConnectMetadata{ServerSelectionExit: types.Random}, true},
+		{types.ConnectMetadata{ServerSelectionExit: types.Fastest}, false},
+		{types.ConnectMetadata{ServerSelectionExit: types.Best}, false},
+		{types.ConnectMetadata{ServerSelectionExit: types.Fastest, ServerGroupExit: "favourites"}, true},
+		{types.ConnectMetadata{ServerSelectionExit: types.Best, ServerGroupExit: "favourites"}, true},
+		{types.ConnectMetadata{ServerSelectionExit: types.Default, ServerGroupExit: "favourites"}, false},
+		// the entry server selection must not affect the exit server
+		{types.ConnectMetadata{ServerSelectionEntry: types.Fastest, ServerGroupExit: "favourites"}, false},
+		{types.ConnectMetadata{ServerSelectionEntry: types.Random}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.metadata.IsExitServerSelectionApplicable(); got != tt.expected {
+			t.Errorf("IsExitServerSelectionApplicable(%+v) = %v, expected %v", tt.metadata, got, tt.expected)
+		}
+	}
+}
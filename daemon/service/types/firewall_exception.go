@@ -0,0 +1,329 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// FirewallExceptionProtocol - transport protocol the firewall exception is restricted to
+type FirewallExceptionProtocol string
+
+const (
+	FirewallExceptionProtocolAny FirewallExceptionProtocol = ""
+	FirewallExceptionProtocolTCP FirewallExceptionProtocol = "tcp"
+	FirewallExceptionProtocolUDP FirewallExceptionProtocol = "udp"
+)
+
+// FirewallExceptionDirection - direction of the connections allowed by the firewall exception
+type FirewallExceptionDirection string
+
+const (
+	// FirewallExceptionDirectionBoth - connections in both directions are allowed (default)
+	FirewallExceptionDirectionBoth FirewallExceptionDirection = ""
+	// FirewallExceptionDirectionIn - only incoming connections from the remote host (and replies) are allowed
+	FirewallExceptionDirectionIn FirewallExceptionDirection = "in"
+	// FirewallExceptionDirectionOut - only outgoing connections to the remote host (and replies) are allowed
+	FirewallExceptionDirectionOut FirewallExceptionDirection = "out"
+)
+
+// FirewallException - user-defined firewall exception.
+// String format: [PROTOCOL:]ADDRESS[:PORT[-PORT]][:DIRECTION]
+//
+//	PROTOCOL  - 'tcp' or 'udp' (if not defined - any protocol; when ports are defined - both TCP and UDP)
+//	ADDRESS   - IP address or subnet in CIDR notation (IPv6 address must be enclosed in square brackets when followed by PORT or DIRECTION);
+//	            or hostname (the daemon periodically resolves it; hostname exceptions can not be restricted by PROTOCOL, PORT or DIRECTION)
+//	PORT      - remote port (for outgoing connections) or local port (for incoming connections); single port or range
+//	DIRECTION - 'in' or 'out' (if not defined - both directions)
+//
+// Examples: "192.0.2.1", "198.51.100.0/24", "git.example.com", "tcp:10.0.0.5:22", "udp:192.168.1.0/24:5353", "tcp:10.0.0.5:8000-8080:out", "tcp:[2001:db8::1]:443"
+type FirewallException struct {
+	// Network - IP address or subnet in CIDR notation (empty for hostname exceptions)
+	Network string
+	// Hostname - the host name to be resolved (empty for IP/subnet exceptions)
+	Hostname  string                     `json:",omitempty"`
+	Protocol  FirewallExceptionProtocol  `json:",omitempty"`
+	PortFrom  uint16                     `json:",omitempty"` // 0 - any port
+	PortTo    uint16                     `json:",omitempty"` // the last port of the range (equal to PortFrom for single port)
+	Direction FirewallExceptionDirection `json:",omitempty"`
+}
+
+// IPNet returns the network of the exception
+// (single IP address is converted to the network with /32 or /128 mask)
+func (e FirewallException) IPNet() (*net.IPNet, error) {
+	if len(e.Hostname) > 0 {
+		return nil, fmt.Errorf("'%s' is a hostname exception", e.Hostname)
+	}
+	if strings.Contains(e.Network, "/") {
+		_, n, err := net.ParseCIDR(e.Network)
+		return n, err
+	}
+	addr := net.ParseIP(e.Network)
+	if addr == nil {
+		return nil, fmt.Errorf("'%s' not a IP address", e.Network)
+	}
+	if ip4 := addr.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: addr, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// IsRestricted returns true when the exception is restricted by the protocol, ports or direction
+// (false - all communication with the host is allowed)
+func (e FirewallException) IsRestricted() bool {
+	return e.Protocol != FirewallExceptionProtocolAny || e.PortFrom > 0 || e.Direction != FirewallExceptionDirectionBoth
+}
+
+// IsHostname returns true when the exception is defined by hostname
+func (e FirewallException) IsHostname() bool {
+	return len(e.Hostname) > 0
+}
+
+// Normalize returns the validated copy of the exception in canonical form
+func (e FirewallException) Normalize() (FirewallException, error) {
+	e.Network = strings.TrimSpace(e.Network)
+	e.Hostname = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(e.Hostname)), ".")
+	e.Protocol = FirewallExceptionProtocol(strings.ToLower(strings.TrimSpace(string(e.Protocol))))
+	e.Direction = FirewallExceptionDirection(strings.ToLower(strings.TrimSpace(string(e.Direction))))
+
+	if len(e.Hostname) > 0 {
+		if len(e.Network) > 0 {
+			return e, fmt.Errorf("both hostname and network are defined")
+		}
+		if !isValidHostname(e.Hostname) {
+			return e, fmt.Errorf("'%s' not a valid hostname", e.Hostname)
+		}
+		if e.IsRestricted() || e.PortTo > 0 {
+			return e, fmt.Errorf("hostname exceptions can not be restricted by protocol, port or direction")
+		}
+		return e, nil
+	}
+
+	n, err := e.IPNet()
+	if err != nil {
+		return e, err
+	}
+	if ones, bits := n.Mask.Size(); ones == bits {
+		e.Network = n.IP.String()
+	} else {
+		e.Network = n.String()
+	}
+
+	switch e.Protocol {
+	case FirewallExceptionProtocolAny, FirewallExceptionProtocolTCP, FirewallExceptionProtocolUDP:
+	default:
+		return e, fmt.Errorf("unsupported protocol '%s'", e.Protocol)
+	}
+
+	switch e.Direction {
+	case FirewallExceptionDirectionBoth, FirewallExceptionDirectionIn, FirewallExceptionDirectionOut:
+	default:
+		return e, fmt.Errorf("unsupported direction '%s'", e.Direction)
+	}
+
+	if e.PortFrom == 0 && e.PortTo > 0 {
+		return e, fmt.Errorf("port range start is not defined")
+	}
+	if e.PortTo == 0 {
+		e.PortTo = e.PortFrom
+	}
+	if e.PortTo < e.PortFrom {
+		return e, fmt.Errorf("bad port range %d-%d", e.PortFrom, e.PortTo)
+	}
+	return e, nil
+}
+
+// String returns the exception in the format: [PROTOCOL:]ADDRESS[:PORT[-PORT]][:DIRECTION]
+func (e FirewallException) String() string {
+	var b strings.Builder
+	if e.Protocol != FirewallExceptionProtocolAny {
+		b.WriteString(string(e.Protocol) + ":")
+	}
+	if len(e.Hostname) > 0 {
+		b.WriteString(e.Hostname)
+	} else if strings.Contains(e.Network, ":") && (e.PortFrom > 0 || e.Direction != FirewallExceptionDirectionBoth) {
+		b.WriteString("[" + e.Network + "]")
+	} else {
+		b.WriteString(e.Network)
+	}
+	if e.PortFrom > 0 {
+		b.WriteString(":" + strconv.Itoa(int(e.PortFrom)))
+		if e.PortTo > e.PortFrom {
+			b.WriteString("-" + strconv.Itoa(int(e.PortTo)))
+		}
+	}
+	if e.Direction != FirewallExceptionDirectionBoth {
+		b.WriteString(":" + string(e.Direction))
+	}
+	return b.String()
+}
+
+// FirewallExceptionsToString returns comma separated list of exceptions
+func FirewallExceptionsToString(exceptions []FirewallException) string {
+	ret := make([]string, 0, len(exceptions))
+	for _, e := range exceptions {
+		ret = append(ret, e.String())
+	}
+	return strings.Join(ret, ",")
+}
+
+// ParseFirewallExceptions parses the list of exceptions separated by comma, semicolon or whitespace
+// (see FirewallException for the format description).
+// When ignoreErrors is true - the unparsable entries are skipped.
+func ParseFirewallExceptions(exceptions string, ignoreErrors bool) ([]FirewallException, error) {
+	splitFunc := func(c rune) bool {
+		return c == ',' || c == ';' || unicode.IsSpace(c)
+	}
+
+	ret := []FirewallException{}
+	for _, s := range strings.FieldsFunc(exceptions, splitFunc) {
+		e, err := ParseFirewallException(s)
+		if err != nil {
+			if !ignoreErrors {
+				return nil, err
+			}
+			continue
+		}
+		ret = append(ret, e)
+	}
+	return ret, nil
+}
+
+// ParseFirewallException parses single exception (see FirewallException for the format description)
+func ParseFirewallException(s string) (FirewallException, error) {
+	ret := FirewallException{}
+	rest := strings.ToLower(strings.TrimSpace(s))
+
+	for _, p := range []FirewallExceptionProtocol{FirewallExceptionProtocolTCP, FirewallExceptionProtocolUDP} {
+		if strings.HasPrefix(rest, string(p)+":") {
+			ret.Protocol = p
+			rest = rest[len(p)+1:]
+			break
+		}
+	}
+
+	var fields []string
+	if strings.HasPrefix(rest, "[") {
+		// IPv6 address in square brackets
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return ret, fmt.Errorf("unable to parse firewall exception '%s': missing ']'", s)
+		}
+		ret.Network = rest[1:end]
+		rest = rest[end+1:]
+		if len(rest) > 0 {
+			if rest[0] != ':' {
+				return ret, fmt.Errorf("unable to parse firewall exception '%s'", s)
+			}
+			fields = strings.Split(rest[1:], ":")
+		}
+	} else if isIPOrNetwork(rest) {
+		ret.Network = rest
+	} else {
+		fields = strings.Split(rest, ":")
+		ret.Network, fields = fields[0], fields[1:]
+	}
+
+	if len(fields) > 0 {
+		if d := FirewallExceptionDirection(fields[len(fields)-1]); d == FirewallExceptionDirectionIn || d == FirewallExceptionDirectionOut {
+			ret.Direction = d
+			fields = fields[:len(fields)-1]
+		}
+	}
+	if len(fields) > 1 {
+		return ret, fmt.Errorf("unable to parse firewall exception '%s'", s)
+	}
+	if len(fields) == 1 {
+		from, to, err := parsePortRange(fields[0])
+		if err != nil {
+			return ret, fmt.Errorf("unable to parse firewall exception '%s': %w", s, err)
+		}
+		ret.PortFrom, ret.PortTo = from, to
+	}
+
+	if !isIPOrNetwork(ret.Network) && isValidHostname(strings.TrimSuffix(ret.Network, ".")) {
+		ret.Hostname, ret.Network = ret.Network, ""
+	}
+
+	ret, err := ret.Normalize()
+	if err != nil {
+		return ret, fmt.Errorf("unable to parse firewall exception '%s': %w", s, err)
+	}
+	return ret, nil
+}
+
+func isIPOrNetwork(s string) bool {
+	if _, _, err := net.ParseCIDR(s); err == nil {
+		return true
+	}
+	return net.ParseIP(s) != nil
+}
+
+// isValidHostname checks the hostname syntax (RFC 1123).
+// The top-level label must not be numeric (to not confuse with IP addresses).
+func isValidHostname(s string) bool {
+	if len(s) == 0 || len(s) > 253 {
+		return false
+	}
+	labels := strings.Split(s, ".")
+	for _, l := range labels {
+		if len(l) == 0 || len(l) > 63 || l[0] == '-' || l[len(l)-1] == '-' {
+			return false
+		}
+		for _, c := range l {
+			if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c != '-' {
+				return false
+			}
+		}
+	}
+	if _, err := strconv.Atoi(labels[len(labels)-1]); err == nil {
+		return false
+	}
+	return true
+}
+
+func parsePortRange(s string) (from, to uint16, err error) {
+	parsePort := func(p string) (uint16, error) {
+		v, err := strconv.ParseUint(p, 10, 16)
+		if err != nil || v == 0 {
+			return 0, fmt.Errorf("bad port '%s'", p)
+		}
+		return uint16(v), nil
+	}
+
+	fromStr, toStr, isRange := strings.Cut(s, "-")
+	if from, err = parsePort(fromStr); err != nil {
+		return 0, 0, err
+	}
+	to = from
+	if isRange {
+		if to, err = parsePort(toStr); err != nil {
+			return 0, 0, err
+		}
+	}
+	return from, to, nil
+}
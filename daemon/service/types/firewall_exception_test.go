@@ -0,0 +1,123 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types_test
+
+import (
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/service/types"
+)
+
+func TestParseFirewallException(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected types.FirewallException
+		str      string
+	}{
+		{"192.0.2.1", types.FirewallException{Network: "192.0.2.1"}, "192.0.2.1"},
+		{"198.51.100.7/24", types.FirewallException{Network: "198.51.100.0/24"}, "198.51.100.0/24"},
+		{"2001:db8::1", types.FirewallException{Network: "2001:db8::1"}, "2001:db8::1"},
+		{"2001:db8::/32", types.FirewallException{Network: "2001:db8::/32"}, "2001:db8::/32"},
+		{"tcp:10.0.0.5:22", types.FirewallException{Network: "10.0.0.5", Protocol: "tcp", PortFrom: 22, PortTo: 22}, "tcp:10.0.0.5:22"},
+		{"UDP:192.168.1.0/24:5353", types.FirewallException{Network: "192.168.1.0/24", Protocol: "udp", PortFrom: 5353, PortTo: 5353}, "udp:192.168.1.0/24:5353"},
+		{"tcp:10.0.0.5:8000-8080:out", types.FirewallException{Network: "10.0.0.5", Protocol: "tcp", PortFrom: 8000, PortTo: 8080, Direction: "out"}, "tcp:10.0.0.5:8000-8080:out"},
+		{"10.0.0.5:in", types.FirewallException{Network: "10.0.0.5", Direction: "in"}, "10.0.0.5:in"},
+		{"10.0.0.5:53", types.FirewallException{Network: "10.0.0.5", PortFrom: 53, PortTo: 53}, "10.0.0.5:53"},
+		{"tcp:[2001:db8::1]:443:out", types.FirewallException{Network: "2001:db8::1", Protocol: "tcp", PortFrom: 443, PortTo: 443, Direction: "out"}, "tcp:[2001:db8::1]:443:out"},
+		{"tcp:2001:db8::1", types.FirewallException{Network: "2001:db8::1", Protocol: "tcp"}, "tcp:2001:db8::1"},
+		{"Git.Example.com.", types.FirewallException{Hostname: "git.example.com"}, "git.example.com"},
+		{"localhost", types.FirewallException{Hostname: "localhost"}, "localhost"},
+	}
+
+	for _, tc := range tests {
+		e, err := types.ParseFirewallException(tc.in)
+		if err != nil {
+			t.Errorf("'%s': unexpected error: %v", tc.in, err)
+			continue
+		}
+		if e != tc.expected {
+			t.Errorf("'%s': expected %+v, got %+v", tc.in, tc.expected, e)
+		}
+		if e.String() != tc.str {
+			t.Errorf("'%s': expected string '%s', got '%s'", tc.in, tc.str, e.String())
+		}
+	}
+}
+
+func TestParseFirewallExceptionErrors(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"host_name.example.com",
+		"tcp:git.example.com:22",
+		"git.example.com:out",
+		"-bad.example.com",
+		"icmp:10.0.0.5",
+		"10.0.0.5:0",
+		"10.0.0.5:70000",
+		"10.0.0.5:8080-8000",
+		"10.0.0.5:22:23",
+		"10.0.0.5:22:sideways",
+		"[2001:db8::1",
+		"2001:db8::1:out",
+	} {
+		if e, err := types.ParseFirewallException(in); err == nil {
+			t.Errorf("'%s': error expected, got %+v", in, e)
+		}
+	}
+}
+
+func TestParseFirewallExceptions(t *testing.T) {
+	list := "192.0.2.1, tcp:10.0.0.5:22;bad_value\n udp:192.168.1.0/24:5353"
+
+	if _, err := types.ParseFirewallExceptions(list, false); err == nil {
+		t.Error("error expected")
+	}
+
+	exps, err := types.ParseFirewallExceptions(list, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exps) != 3 {
+		t.Fatalf("expected 3 exceptions, got %d", len(exps))
+	}
+	if s := types.FirewallExceptionsToString(exps); s != "192.0.2.1,tcp:10.0.0.5:22,udp:192.168.1.0/24:5353" {
+		t.Errorf("unexpected string: '%s'", s)
+	}
+}
+
+func TestFirewallExceptionNormalize(t *testing.T) {
+	e, err := types.FirewallException{Network: "10.0.0.5", Protocol: "TCP", PortFrom: 22}.Normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.PortTo != 22 || e.Protocol != types.FirewallExceptionProtocolTCP || !e.IsRestricted() {
+		t.Errorf("unexpected result %+v", e)
+	}
+
+	if _, err := (types.FirewallException{Network: "10.0.0.5", PortTo: 22}).Normalize(); err == nil {
+		t.Error("error expected for range without start port")
+	}
+	if (types.FirewallException{Network: "10.0.0.5"}).IsRestricted() {
+		t.Error("address-only exception must not be restricted")
+	}
+}
@@ -0,0 +1,144 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Normalization limits for the server scoring factors
+const (
+	serverScoreMaxLatencyMs  = 500   // latency >= this value gives the worst latency factor
+	serverScoreMaxDistanceKm = 10000 // distance >= this value gives the worst distance factor
+	serverScoreMaxWeight     = 100
+)
+
+// ServerScoringWeights - relative importance of the factors for the 'Best' server selection.
+// Only proportions between weights matter. A weight of 0 disables the factor.
+// Default (zero) values: default weights (see DefaultServerScoringWeights())
+type ServerScoringWeights struct {
+	Latency  float64 // ping time to the server
+	Load     float64 // load of the server reported by the backend
+	Distance float64 // distance between the server and the current geo-location
+	History  float64 // ratio of failed connection attempts to the server
+}
+
+// DefaultServerScoringWeights returns default weights for the server scoring
+func DefaultServerScoringWeights() ServerScoringWeights {
+	return ServerScoringWeights{Latency: 0.5, Load: 0.25, Distance: 0.1, History: 0.15}
+}
+
+// IsDefault returns true when no custom weights defined
+func (w ServerScoringWeights) IsDefault() bool {
+	return w.Latency == 0 && w.Load == 0 && w.Distance == 0 && w.History == 0
+}
+
+// Validate checks weights
+func (w ServerScoringWeights) Validate() error {
+	for _, v := range []float64{w.Latency, w.Load, w.Distance, w.History} {
+		if math.IsNaN(v) || v < 0 || v > serverScoreMaxWeight {
+			return fmt.Errorf("server scoring weights must be in range 0-%d", serverScoreMaxWeight)
+		}
+	}
+	return nil
+}
+
+// Effective returns weights to use (default weights if not defined)
+func (w ServerScoringWeights) Effective() ServerScoringWeights {
+	if w.IsDefault() || w.Validate() != nil {
+		return DefaultServerScoringWeights()
+	}
+	return w
+}
+
+// ServerScoreInput - the information about a server which is in use to calculate its score
+type ServerScoreInput struct {
+	Gateway string // server ID (e.g. "us-tx.wg.ivpn.net")
+	// LatencyMs - minimal ping time among the server hosts (0 - unknown)
+	LatencyMs int
+	// Load - average load of the server hosts (percent)
+	Load float32
+	// DistanceKm - distance to the server (negative value - unknown)
+	DistanceKm float64
+	// Connection history
+	ConnectAttempts  int
+	ConnectSuccesses int
+}
+
+// ServerScore - the calculated score of the server
+type ServerScore struct {
+	ServerScoreInput
+	// Score: 0-100 (higher is better)
+	Score float64
+}
+
+// ScoreServers calculates scores for the servers.
+// Returns the servers sorted by score: the best server is the first one.
+func ScoreServers(servers []ServerScoreInput, weights ServerScoringWeights) []ServerScore {
+	w := weights.Effective()
+	wSum := w.Latency + w.Load + w.Distance + w.History
+
+	ret := make([]ServerScore, 0, len(servers))
+	for _, s := range servers {
+		penalty := w.Latency*latencyPenalty(s.LatencyMs) +
+			w.Load*loadPenalty(s.Load) +
+			w.Distance*distancePenalty(s.DistanceKm) +
+			w.History*historyPenalty(s.ConnectAttempts, s.ConnectSuccesses)
+
+		ret = append(ret, ServerScore{ServerScoreInput: s, Score: 100 * (1 - penalty/wSum)})
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].Score > ret[j].Score })
+	return ret
+}
+
+// All the penalty functions return value in range 0-1 (0 - the best; 1 - the worst)
+
+func latencyPenalty(latencyMs int) float64 {
+	if latencyMs <= 0 {
+		return 1 // unknown latency: host is not reachable (or not pinged)
+	}
+	return math.Min(float64(latencyMs)/serverScoreMaxLatencyMs, 1)
+}
+
+func loadPenalty(load float32) float64 {
+	return math.Max(math.Min(float64(load)/100, 1), 0)
+}
+
+func distancePenalty(distanceKm float64) float64 {
+	if distanceKm < 0 {
+		return 0.5 // unknown location: neutral value
+	}
+	return math.Min(distanceKm/serverScoreMaxDistanceKm, 1)
+}
+
+func historyPenalty(attempts, successes int) float64 {
+	if successes > attempts {
+		successes = attempts
+	}
+	// Laplace smoothing: no history gives neutral value (0.5);
+	// the more attempts were made - the more accurate the failure ratio is
+	return 1 - float64(successes+1)/float64(attempts+2)
+}
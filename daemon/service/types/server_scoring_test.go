@@ -0,0 +1,77 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types_test
+
+import (
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/service/types"
+)
+
+func TestScoreServers(t *testing.T) {
+	servers := []types.ServerScoreInput{
+		{Gateway: "slow", LatencyMs: 300, Load: 10, DistanceKm: 500},
+		{Gateway: "fast", LatencyMs: 20, Load: 10, DistanceKm: 500},
+		{Gateway: "unreachable", LatencyMs: 0, Load: 0, DistanceKm: 100},
+	}
+
+	scores := types.ScoreServers(servers, types.ServerScoringWeights{})
+	if scores[0].Gateway != "fast" || scores[2].Gateway != "unreachable" {
+		t.Fatalf("unexpected order: %s, %s, %s", scores[0].Gateway, scores[1].Gateway, scores[2].Gateway)
+	}
+	for _, s := range scores {
+		if s.Score < 0 || s.Score > 100 {
+			t.Fatalf("score out of range: %v", s.Score)
+		}
+	}
+
+	// only load is taken into account
+	scores = types.ScoreServers([]types.ServerScoreInput{
+		{Gateway: "loaded", LatencyMs: 10, Load: 90},
+		{Gateway: "free", LatencyMs: 400, Load: 5},
+	}, types.ServerScoringWeights{Load: 1})
+	if scores[0].Gateway != "free" {
+		t.Fatalf("expected the least loaded server first")
+	}
+
+	// connection history: failed attempts decrease the score
+	scores = types.ScoreServers([]types.ServerScoreInput{
+		{Gateway: "failing", LatencyMs: 20, ConnectAttempts: 3, ConnectSuccesses: 0},
+		{Gateway: "reliable", LatencyMs: 25, ConnectAttempts: 3, ConnectSuccesses: 3},
+	}, types.ServerScoringWeights{})
+	if scores[0].Gateway != "reliable" {
+		t.Fatalf("expected the server with successful connections first")
+	}
+}
+
+func TestServerScoringWeights(t *testing.T) {
+	if err := (types.ServerScoringWeights{Latency: -1}).Validate(); err == nil {
+		t.Fatalf("negative weight must be rejected")
+	}
+	if w := (types.ServerScoringWeights{}).Effective(); w != types.DefaultServerScoringWeights() {
+		t.Fatalf("default weights expected for empty configuration")
+	}
+	if w := (types.ServerScoringWeights{Load: 2}).Effective(); w.Load != 2 || w.Latency != 0 {
+		t.Fatalf("custom weights must be kept")
+	}
+}
@@ -0,0 +1,102 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// SplitTunnelDomainStatus - addresses of the Split Tunnel destination domain
+type SplitTunnelDomainStatus struct {
+	Domain string
+	// IPs - the addresses of the domain in use by the Split Tunnel configuration
+	IPs []string `json:",omitempty"`
+	// LastUpdated - time of the last update of the domain addresses (zero - no addresses yet)
+	LastUpdated time.Time
+	// Expires - time when the addresses expire (based on TTL of the DNS records)
+	Expires time.Time
+	// Error - the last error (empty when no errors)
+	Error string `json:",omitempty"`
+}
+
+// ParseSplitTunnelRoute checks the Split Tunnel destination (IP address or network in CIDR notation)
+// and returns it in the normalized form (e.g. "10.0.0.1" -> "10.0.0.1/32"; "10.1.2.3/8" -> "10.0.0.0/8")
+func ParseSplitTunnelRoute(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if !isIPOrNetwork(s) {
+		return "", fmt.Errorf("bad IP address or network '%s'", s)
+	}
+	n := routeToNet(s)
+	if ones, _ := n.Mask.Size(); ones == 0 {
+		return "", fmt.Errorf("default route '%s' is not allowed", s)
+	}
+	return n.String(), nil
+}
+
+// ParseSplitTunnelDomain checks the Split Tunnel destination domain name and returns it in lower case
+func ParseSplitTunnelDomain(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(s), "."))
+	if isIPOrNetwork(s) || !isValidHostname(s) {
+		return "", fmt.Errorf("bad domain name '%s'", s)
+	}
+	return s, nil
+}
+
+// SplitTunnelDomainMatch returns the Split Tunnel domain which covers the domain name:
+// the same domain or its parent domain (e.g. "example.com" covers "example.com", "www.example.com", "cdn.eu.example.com").
+// When several domains cover the name - the most specific one is returned.
+func SplitTunnelDomainMatch(domains []string, name string) (domain string, ok bool) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for _, d := range domains {
+		if (name == d || strings.HasSuffix(name, "."+d)) && len(d) > len(domain) {
+			domain, ok = d, true
+		}
+	}
+	return domain, ok
+}
+
+// SplitTunnelRoutesToNets converts the Split Tunnel destinations to the list of networks (bad values are ignored)
+func SplitTunnelRoutesToNets(routes []string) []net.IPNet {
+	ret := make([]net.IPNet, 0, len(routes))
+	for _, r := range routes {
+		if !isIPOrNetwork(r) {
+			continue
+		}
+		ret = append(ret, routeToNet(r))
+	}
+	return ret
+}
+
+func routeToNet(s string) net.IPNet {
+	if _, n, err := net.ParseCIDR(s); err == nil {
+		return *n
+	}
+	ip := net.ParseIP(s)
+	if ip4 := ip.To4(); ip4 != nil {
+		return net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+	return net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
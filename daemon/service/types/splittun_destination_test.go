@@ -0,0 +1,96 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types_test
+
+import (
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/service/types"
+)
+
+func TestParseSplitTunnelRoute(t *testing.T) {
+	tests := []struct {
+		in, expected string
+	}{
+		{"192.0.2.1", "192.0.2.1/32"},
+		{" 198.51.100.7/24 ", "198.51.100.0/24"},
+		{"2001:db8::1", "2001:db8::1/128"},
+		{"2001:db8::1/32", "2001:db8::/32"},
+	}
+	for _, tt := range tests {
+		ret, err := types.ParseSplitTunnelRoute(tt.in)
+		if err != nil {
+			t.Errorf("'%s': unexpected error: %v", tt.in, err)
+			continue
+		}
+		if ret != tt.expected {
+			t.Errorf("'%s': expected '%s', got '%s'", tt.in, tt.expected, ret)
+		}
+	}
+
+	for _, in := range []string{"", "example.com", "192.0.2.1/33", "0.0.0.0/0", "::/0", "192.0.2.1:443"} {
+		if _, err := types.ParseSplitTunnelRoute(in); err == nil {
+			t.Errorf("'%s': expected error", in)
+		}
+	}
+}
+
+func TestParseSplitTunnelDomain(t *testing.T) {
+	if ret, err := types.ParseSplitTunnelDomain(" WWW.Example.com. "); err != nil || ret != "www.example.com" {
+		t.Errorf("unexpected result '%s' (err: %v)", ret, err)
+	}
+	for _, in := range []string{"", "192.0.2.1", "bad_domain.com", "-example.com", "example.123"} {
+		if _, err := types.ParseSplitTunnelDomain(in); err == nil {
+			t.Errorf("'%s': expected error", in)
+		}
+	}
+}
+
+func TestSplitTunnelDomainMatch(t *testing.T) {
+	domains := []string{"example.com", "eu.example.com", "example.net"}
+	tests := []struct {
+		name, expected string
+	}{
+		{"example.com", "example.com"},
+		{"WWW.Example.com.", "example.com"},
+		{"cdn.eu.example.com", "eu.example.com"},
+		{"eu.example.com", "eu.example.com"},
+		{"example.net", "example.net"},
+		{"badexample.com", ""},
+		{"example.org", ""},
+		{"com", ""},
+	}
+	for _, tt := range tests {
+		ret, ok := types.SplitTunnelDomainMatch(domains, tt.name)
+		if ok != (len(tt.expected) > 0) || ret != tt.expected {
+			t.Errorf("'%s': expected '%s', got '%s'", tt.name, tt.expected, ret)
+		}
+	}
+}
+
+func TestSplitTunnelRoutesToNets(t *testing.T) {
+	nets := types.SplitTunnelRoutesToNets([]string{"192.0.2.0/24", "bad", "2001:db8::1"})
+	if len(nets) != 2 || nets[0].String() != "192.0.2.0/24" || nets[1].String() != "2001:db8::1/128" {
+		t.Errorf("unexpected result: %v", nets)
+	}
+}
@@ -0,0 +1,121 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types
+
+import (
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+// TunnelStatistics - traffic statistics of the active VPN tunnel
+type TunnelStatistics struct {
+	IsConnected bool // false - VPN is not connected (all other fields are empty)
+	VpnType     vpn.Type
+	Time        time.Time // time of the sample
+
+	// total traffic counters (sum for all peers)
+	RxBytes uint64
+	TxBytes uint64
+	// current throughput (bytes per second); zero until the throughput is measured
+	RxBytesPerSec uint64
+	TxBytesPerSec uint64
+
+	Peers []TunnelPeerStatistics `json:",omitempty"`
+}
+
+// TunnelPeerStatistics - traffic statistics of a single peer (VPN server) of the active tunnel
+type TunnelPeerStatistics struct {
+	Endpoint  string `json:",omitempty"` // "ip:port"
+	PublicKey string `json:",omitempty"` // WireGuard only
+
+	RxBytes       uint64
+	TxBytes       uint64
+	RxBytesPerSec uint64
+	TxBytesPerSec uint64
+
+	// LastHandshakeAgeSec - seconds elapsed since the latest handshake (WireGuard only).
+	// -1 - when not applicable or no handshake yet
+	LastHandshakeAgeSec int64
+}
+
+// NewTunnelStatistics creates statistics object from the counters reported by the VPN object
+func NewTunnelStatistics(vpnType vpn.Type, counters vpn.TunnelStatistics, now time.Time) TunnelStatistics {
+	ret := TunnelStatistics{
+		IsConnected: true,
+		VpnType:     vpnType,
+		Time:        now,
+		Peers:       make([]TunnelPeerStatistics, 0, len(counters.Peers)),
+	}
+
+	for _, p := range counters.Peers {
+		peer := TunnelPeerStatistics{
+			Endpoint:            p.Endpoint,
+			PublicKey:           p.PublicKey,
+			RxBytes:             p.RxBytes,
+			TxBytes:             p.TxBytes,
+			LastHandshakeAgeSec: -1,
+		}
+		if !p.LastHandshake.IsZero() {
+			peer.LastHandshakeAgeSec = int64(now.Sub(p.LastHandshake) / time.Second)
+			if peer.LastHandshakeAgeSec < 0 {
+				peer.LastHandshakeAgeSec = 0
+			}
+		}
+		ret.RxBytes += p.RxBytes
+		ret.TxBytes += p.TxBytes
+		ret.Peers = append(ret.Peers, peer)
+	}
+	return ret
+}
+
+// CalculateThroughput calculates current throughput based on the previous sample of the same tunnel.
+// Throughput stays zero when the previous sample is empty or when the counters were reset (e.g. on reconnection).
+func (s *TunnelStatistics) CalculateThroughput(prev TunnelStatistics) {
+	if prev.Time.IsZero() || !s.Time.After(prev.Time) {
+		return
+	}
+	elapsed := s.Time.Sub(prev.Time).Seconds()
+
+	s.RxBytesPerSec = bytesPerSec(prev.RxBytes, s.RxBytes, elapsed)
+	s.TxBytesPerSec = bytesPerSec(prev.TxBytes, s.TxBytes, elapsed)
+
+	for i := range s.Peers {
+		for _, pp := range prev.Peers {
+			// peers are identified by the public key (OpenVPN has the only one peer with empty key)
+			if pp.PublicKey != s.Peers[i].PublicKey {
+				continue
+			}
+			s.Peers[i].RxBytesPerSec = bytesPerSec(pp.RxBytes, s.Peers[i].RxBytes, elapsed)
+			s.Peers[i].TxBytesPerSec = bytesPerSec(pp.TxBytes, s.Peers[i].TxBytes, elapsed)
+			break
+		}
+	}
+}
+
+func bytesPerSec(prev, cur uint64, elapsedSec float64) uint64 {
+	if cur < prev || elapsedSec <= 0 {
+		return 0
+	}
+	return uint64(float64(cur-prev) / elapsedSec)
+}
@@ -0,0 +1,70 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/service/types"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+func TestTunnelStatisticsThroughput(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	prev := types.NewTunnelStatistics(vpn.WireGuard, vpn.TunnelStatistics{Peers: []vpn.PeerStatistics{
+		{PublicKey: "A", RxBytes: 1000, TxBytes: 500, LastHandshake: t0.Add(-10 * time.Second)},
+		{PublicKey: "B", RxBytes: 100, TxBytes: 100},
+	}}, t0)
+
+	if prev.RxBytes != 1100 || prev.TxBytes != 600 {
+		t.Fatalf("unexpected totals: %d/%d", prev.RxBytes, prev.TxBytes)
+	}
+	if prev.Peers[0].LastHandshakeAgeSec != 10 || prev.Peers[1].LastHandshakeAgeSec != -1 {
+		t.Fatalf("unexpected handshake age: %d/%d", prev.Peers[0].LastHandshakeAgeSec, prev.Peers[1].LastHandshakeAgeSec)
+	}
+
+	// no previous sample: throughput unknown
+	first := prev
+	first.CalculateThroughput(types.TunnelStatistics{})
+	if first.RxBytesPerSec != 0 || first.TxBytesPerSec != 0 {
+		t.Fatalf("throughput expected to be zero without previous sample")
+	}
+
+	cur := types.NewTunnelStatistics(vpn.WireGuard, vpn.TunnelStatistics{Peers: []vpn.PeerStatistics{
+		{PublicKey: "A", RxBytes: 5000, TxBytes: 1500},
+		{PublicKey: "B", RxBytes: 50, TxBytes: 300}, // 'Rx' counter reset
+	}}, t0.Add(2*time.Second))
+	cur.CalculateThroughput(prev)
+
+	if cur.RxBytesPerSec != 1975 || cur.TxBytesPerSec != 600 {
+		t.Errorf("unexpected total throughput: %d/%d", cur.RxBytesPerSec, cur.TxBytesPerSec)
+	}
+	if cur.Peers[0].RxBytesPerSec != 2000 || cur.Peers[0].TxBytesPerSec != 500 {
+		t.Errorf("unexpected peer 'A' throughput: %d/%d", cur.Peers[0].RxBytesPerSec, cur.Peers[0].TxBytesPerSec)
+	}
+	if cur.Peers[1].RxBytesPerSec != 0 || cur.Peers[1].TxBytesPerSec != 100 {
+		t.Errorf("unexpected peer 'B' throughput: %d/%d", cur.Peers[1].RxBytesPerSec, cur.Peers[1].TxBytesPerSec)
+	}
+}
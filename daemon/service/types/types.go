@@ -22,13 +22,64 @@
 
 package types
 
+import "time"
+
 type KillSwitchStatus struct {
 	IsEnabled         bool   // FW state
 	IsPersistent      bool   // configuration: true - when persistent
 	IsAllowLAN        bool   // configuration: 'Allow LAN'
 	IsAllowMulticast  bool   // configuration: 'Allow multicast'
 	IsAllowApiServers bool   // configuration: 'Allow API servers'
-	UserExceptions    string // configuration: Firewall exceptions: comma separated list of IP addresses (masks) in format: x.x.x.x[/xx]
+	UserExceptions    string // configuration: Firewall exceptions: comma separated list (string representation of 'UserExceptionsList')
+
+	UserExceptionsList  []FirewallException           // configuration: Firewall exceptions
+	UserExceptionsHosts []FirewallHostExceptionStatus // state of the hostname exceptions (resolved addresses)
 
 	StateLanAllowed bool // real state of 'Allow LAN'
 }
+
+// FirewallHostExceptionStatus - state of the hostname firewall exception
+type FirewallHostExceptionStatus struct {
+	Hostname string
+	// IPs - the resolved addresses allowed by the firewall
+	IPs []string `json:",omitempty"`
+	// LastResolved - time of the last successful resolving (zero - never resolved)
+	LastResolved time.Time
+	// NextRefresh - time of the next resolving (based on TTL of the DNS records)
+	NextRefresh time.Time
+	// Error - the last resolving error (empty if the last resolving was successful)
+	Error string `json:",omitempty"`
+}
+
+// KillSwitchRules - structured description of the firewall rules
+type KillSwitchRules struct {
+	// IsEnabled - the firewall is enabled
+	IsEnabled bool
+	// IsDryRun - the rules are not applied: it is the description of rules which would be applied
+	// when the firewall is enabled (based on the current state)
+	IsDryRun bool
+	// Backend - the firewall implementation (e.g. "nftables")
+	Backend string
+
+	// VpnInterface - all communication trough this interface is allowed (empty when VPN is not connected)
+	VpnInterface string `json:",omitempty"`
+	// VpnServer - the allowed connection to the VPN server (example: "192.0.2.1:2049 UDP")
+	VpnServer string `json:",omitempty"`
+	// DnsServer - the only allowed DNS server (port 53).
+	// If empty - all DNS requests are blocked (except requests trough the VPN interface)
+	DnsServer string `json:",omitempty"`
+
+	// AllowedHosts - hosts allowed for the current connection (removed on disconnection)
+	AllowedHosts []string `json:",omitempty"`
+	// AllowedHostsPersistent - hosts allowed independently of the connection state
+	AllowedHostsPersistent []string `json:",omitempty"`
+	// IcmpExceptions - hosts allowed only for ICMP protocol (ping)
+	IcmpExceptions []string `json:",omitempty"`
+	// LanRanges - allowed local network ranges ('Allow LAN' functionality)
+	LanRanges []string `json:",omitempty"`
+	// UserExceptions - user-defined allowed IP masks
+	UserExceptions []string `json:",omitempty"`
+
+	// RawRules - the backend-specific representation of the rules (if available)
+	RawRules string `json:",omitempty"`
+}
@@ -61,6 +61,19 @@ type RunningApp struct {
 	ExtModifiedCmdLine string
 }
 
+// AppTraffic - traffic counters of the application in Split-Tunnel environment
+// (the process added to the Split-Tunnel and all its child processes)
+type AppTraffic struct {
+	Pid     int // PID of the root process of the application
+	Cmdline string
+	// All traffic of the application is counted.
+	// Normal mode: this traffic is bypassing the VPN tunnel (except DNS requests); inverse mode: it is using the VPN tunnel.
+	PacketsSent     uint64
+	BytesSent       uint64
+	PacketsReceived uint64
+	BytesReceived   uint64
+}
+
 // Initialize must be called first (before accessing any ST functionality)
 // Normally, it should check if the ST functionality available
 // Returns non-nil error object if Split-Tunneling functionality not available
@@ -90,8 +103,14 @@ func Reset() error {
 	return implReset()
 }
 
+// GetDestinationsNotAvailableError returns non-nil error object if Split-Tunneling for destinations (networks/domains) is not available
+func GetDestinationsNotAvailableError() error {
+	return implDestinationsNotAvailableError()
+}
+
 // ApplyConfig control split-tunnel functionality
-func ApplyConfig(isStEnabled, isStInverse, isStInverseAllowWhenNoVpn, isVpnEnabled bool, addrConfig ConfigAddresses, splitTunnelApps []string) error {
+// splitTunnelDestinations - networks excluded from the VPN tunnel (in inverse mode: the only networks which are using the VPN tunnel)
+func ApplyConfig(isStEnabled, isStInverse, isStInverseAllowWhenNoVpn, isVpnEnabled bool, addrConfig ConfigAddresses, splitTunnelApps []string, splitTunnelDestinations []net.IPNet) error {
 	mutex.Lock()
 	defer mutex.Unlock()
 
@@ -100,27 +119,52 @@ func ApplyConfig(isStEnabled, isStInverse, isStInverseAllowWhenNoVpn, isVpnEnabl
 		addrConfig.IPv6Tunnel = nil
 	}
 
-	retErr := implApplyConfig(isStEnabled, isStInverse, isStInverseAllowWhenNoVpn, isVpnEnabled, addrConfig, splitTunnelApps)
+	retErr := implApplyConfig(isStEnabled, isStInverse, isStInverseAllowWhenNoVpn, isVpnEnabled, addrConfig, splitTunnelApps, splitTunnelDestinations)
 	if retErr != nil {
 		log.Error(retErr)
 	}
 	return retErr
 }
 
+// CheckAppRule checks the persistent Split Tunnel rule (absolute path to the executable or the desktop file ID)
+// and returns it in the normalized form
+// (applicable for Linux)
+func CheckAppRule(rule string) (string, error) {
+	return implCheckAppRule(rule)
+}
+
 // AddPid add process to Split-Tunnel environment
 // (applicable for Linux)
 func AddPid(pid int, commandToExecute string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
 	return implAddPid(pid, commandToExecute)
 }
 
 // RemovePid remove process to Split-Tunnel environment
 // (applicable for Linux)
 func RemovePid(pid int) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
 	return implRemovePid(pid)
 }
 
 // Get information about active applications running in Split-Tunnel environment
 // (applicable for Linux)
 func GetRunningApps() (allProcesses []RunningApp, err error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
 	return implGetRunningApps()
 }
+
+// GetAppsTraffic returns traffic counters of applications running in Split-Tunnel environment
+// (applicable for Linux)
+func GetAppsTraffic() ([]AppTraffic, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return implGetAppsTraffic()
+}
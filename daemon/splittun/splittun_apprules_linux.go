@@ -0,0 +1,412 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package splittun
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/oshelpers/linux/applist"
+	"github.com/ivpn/desktop-app/daemon/oshelpers/linux/netlink"
+)
+
+// Persistent Split Tunnel rules (the 'SplitTunnelApps' configuration on Linux).
+//
+// The rule is an absolute path to the executable or the desktop file ID (e.g. 'firefox.desktop').
+// Any process which is executing the binary defined by the rule is added to the Split Tunnel environment automatically:
+//   - the already running processes are added when the configuration is applied;
+//   - the new processes are detected by the kernel process events connector (PROC_EVENT_EXEC).
+//
+// When the rule is removed - the running processes of this rule are moved back out of the Split Tunnel environment.
+//
+// Note: the process is added to the Split Tunnel environment right after it started,
+// so the connections opened by the process at the very beginning may not be affected.
+
+type appRule struct {
+	rule string
+	// the binaries the rule is applicable to (the path and the target path of the symlink)
+	binaries []string
+	// the binary is a script (executed by an interpreter)
+	isScript bool
+}
+
+var (
+	// active rules (protected by 'mutex')
+	appRules []appRule
+	// the process events listener is started once (on first use)
+	isAppRulesWatcherStarted bool
+)
+
+func implCheckAppRule(rule string) (string, error) {
+	rule = normalizeAppRule(rule)
+	if _, _, err := resolveAppRule(rule); err != nil {
+		return "", err
+	}
+	return rule, nil
+}
+
+func normalizeAppRule(rule string) string {
+	rule = strings.TrimSpace(rule)
+	if filepath.IsAbs(rule) {
+		return filepath.Clean(rule)
+	}
+	if len(rule) > 0 && !strings.HasSuffix(rule, ".desktop") {
+		rule += ".desktop"
+	}
+	return rule
+}
+
+// resolveAppRule returns the binaries the rule is applicable to
+func resolveAppRule(rule string) (binaries []string, isScript bool, err error) {
+	if len(rule) == 0 {
+		return nil, false, fmt.Errorf("empty rule")
+	}
+
+	bin := rule
+	if !filepath.IsAbs(rule) {
+		if bin, err = applist.GetDesktopEntryBinary(rule, ""); err != nil {
+			return nil, false, err
+		}
+	}
+
+	fi, err := os.Stat(bin)
+	if err != nil {
+		return nil, false, err
+	}
+	if fi.IsDir() || fi.Mode()&0111 == 0 {
+		return nil, false, fmt.Errorf("'%s' is not an executable file", bin)
+	}
+
+	binaries = []string{bin}
+	if target, err := filepath.EvalSymlinks(bin); err == nil && target != bin {
+		binaries = append(binaries, target)
+	}
+	return binaries, isScriptFile(bin), nil
+}
+
+// isScriptFile returns true when the file starts with the interpreter directive ('#!')
+func isScriptFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	buf := make([]byte, 2)
+	n, _ := f.Read(buf)
+	return n == 2 && string(buf) == "#!"
+}
+
+// setAppRules updates the active rules.
+// The running processes of the removed rules are removed from the Split Tunnel environment.
+// (must be called under locked 'mutex')
+func setAppRules(rules []string) {
+	oldRules := appRules
+	newRules := make([]appRule, 0, len(rules))
+	for _, r := range rules {
+		r = normalizeAppRule(r)
+		binaries, isScript, err := resolveAppRule(r)
+		if err != nil {
+			log.Warning(fmt.Sprintf("Split Tunnel rule '%s' ignored: %v", r, err))
+			continue
+		}
+		newRules = append(newRules, appRule{rule: r, binaries: binaries, isScript: isScript})
+	}
+	appRules = newRules
+
+	var removedRules []appRule
+	for _, old := range oldRules {
+		isRemoved := true
+		for _, r := range newRules {
+			if r.rule == old.rule {
+				isRemoved = false
+				break
+			}
+		}
+		if isRemoved {
+			removedRules = append(removedRules, old)
+		}
+	}
+	removeAppRulesProcesses(removedRules)
+}
+
+// removeAppRulesProcesses removes the processes which are matching the rules from the Split Tunnel environment
+// (the processes started manually in the Split Tunnel environment are not affected)
+// (must be called under locked 'mutex')
+func removeAppRulesProcesses(rules []appRule) {
+	if len(rules) == 0 || backend == nil {
+		return
+	}
+
+	isRuleName := func(cmd string) bool {
+		for _, r := range rules {
+			if r.rule == cmd {
+				return true
+			}
+		}
+		return false
+	}
+	stPids := readStPids()
+	// isStartedManually returns true when the process (or its parent in the Split Tunnel environment)
+	// was started manually in the Split Tunnel environment (e.g. 'ivpn exclude ...')
+	isStartedManually := func(pid int) bool {
+		for i := 0; i < 64; i++ { // limit the depth (protection against loops)
+			if cmd, ok := _addedRootProcesses[pid]; ok && !isRuleName(cmd) {
+				return true
+			}
+			pid = readProcPpid(pid)
+			if _, ok := stPids[pid]; !ok {
+				return false
+			}
+		}
+		return false
+	}
+
+	toRemove := make(map[int]string)
+	for pid := range stPids {
+		if isStartedManually(pid) {
+			continue
+		}
+		exe, err := filepath.EvalSymlinks(fmt.Sprintf("/proc/%d/exe", pid))
+		if err != nil {
+			continue
+		}
+		if rule, ok := matchAppRule(rules, exe, readProcArgs(pid)); ok {
+			toRemove[pid] = rule
+		}
+	}
+
+	for pid, rule := range toRemove {
+		if _, ok := toRemove[readProcPpid(pid)]; ok {
+			continue // the parent process will be removed together with its child processes
+		}
+		log.Info(fmt.Sprintf("Removing PID:%d (rule '%s' removed)", pid, rule))
+		if err := implRemovePid(pid); err != nil {
+			log.Error(fmt.Errorf("failed to remove PID:%d (rule '%s'): %w", pid, rule, err))
+		}
+	}
+}
+
+// matchAppRule returns the rule applicable to the process
+// exe - the actual pathname of the executed binary; args - the command line arguments of the process
+func matchAppRule(rules []appRule, exe string, args []string) (string, bool) {
+	// The scripts are executed by an interpreter (e.g. '/bin/sh /usr/bin/app'):
+	// the script path is in the first or the second argument
+	var scriptCandidates []string
+	for i := 0; i < len(args) && i < 2; i++ {
+		if filepath.IsAbs(args[i]) {
+			scriptCandidates = append(scriptCandidates, filepath.Clean(args[i]))
+		}
+	}
+
+	for _, r := range rules {
+		for _, b := range r.binaries {
+			if b == exe {
+				return r.rule, true
+			}
+			if !r.isScript {
+				continue
+			}
+			for _, c := range scriptCandidates {
+				if c == b {
+					return r.rule, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// processAppRules adds the process to the Split Tunnel environment if it matches any rule.
+// stPids - the processes which are already in the Split Tunnel environment
+// (must be called under locked 'mutex')
+func processAppRules(pid int, stPids map[int]struct{}) {
+	if _, ok := stPids[pid]; ok {
+		return
+	}
+	exe, err := filepath.EvalSymlinks(fmt.Sprintf("/proc/%d/exe", pid))
+	if err != nil {
+		return // the process is already finished or it is a kernel thread
+	}
+	rule, ok := matchAppRule(appRules, exe, readProcArgs(pid))
+	if !ok {
+		return
+	}
+	if _, ok := stPids[readProcPpid(pid)]; ok {
+		return // the parent process is already in the Split Tunnel environment (the child process inherits it)
+	}
+
+	log.Info(fmt.Sprintf("Adding PID:%d (rule '%s')", pid, rule))
+	if err := backend.addPid(pid); err != nil {
+		log.Error(fmt.Errorf("failed to add PID:%d (rule '%s'): %w", pid, rule, err))
+		return
+	}
+	_addedRootProcesses[pid] = rule
+	stPids[pid] = struct{}{}
+}
+
+// applyAppRulesToRunningProcesses adds all running processes which are matching the rules to the Split Tunnel environment
+// (must be called under locked 'mutex')
+func applyAppRulesToRunningProcesses() {
+	if !isActive || len(appRules) == 0 {
+		return
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	stPids := readStPids()
+	for _, e := range entries {
+		if pid, err := strconv.Atoi(e.Name()); err == nil && pid > 1 {
+			processAppRules(pid, stPids)
+		}
+	}
+}
+
+// startAppRulesWatcher starts the detection of new processes (if not started yet)
+// (must be called under locked 'mutex')
+func startAppRulesWatcher() {
+	if isAppRulesWatcherStarted {
+		return
+	}
+
+	listener, err := netlink.CreateProcListener()
+	if err != nil {
+		log.Error(fmt.Errorf("failed to start the detection of new processes (the Split Tunnel rules will be applied only to the running processes): %w", err))
+		return
+	}
+	isAppRulesWatcherStarted = true
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("PANIC in Split Tunnel process watcher!: ", r)
+				if err, ok := r.(error); ok {
+					log.ErrorTrace(err)
+				}
+			}
+		}()
+		defer func() {
+			mutex.Lock()
+			isAppRulesWatcherStarted = false
+			mutex.Unlock()
+			listener.Close()
+		}()
+
+		log.Info("Process watcher started")
+		defer log.Info("Process watcher stopped")
+
+		isReadError := false
+		for {
+			pids, err := listener.ReadExecEvents()
+			if err != nil {
+				switch {
+				case errors.Is(err, syscall.EBADF):
+					return // the listener is closed
+				case errors.Is(err, syscall.EINTR):
+				case errors.Is(err, syscall.ENOBUFS):
+					// the socket receive buffer overflowed (some events are lost): check all running processes
+					log.Warning("Process events are lost (receive buffer overflow); checking all running processes")
+					mutex.Lock()
+					applyAppRulesToRunningProcesses()
+					mutex.Unlock()
+				default:
+					if !isReadError {
+						log.Error(err)
+					}
+					isReadError = true
+					time.Sleep(time.Second)
+				}
+				continue
+			}
+			isReadError = false
+			if len(pids) == 0 {
+				continue
+			}
+
+			func() { // using anonymous function to unlock mutex correctly
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				if !isActive || len(appRules) == 0 {
+					return
+				}
+				stPids := readStPids()
+				for _, pid := range pids {
+					processAppRules(pid, stPids)
+				}
+			}()
+		}
+	}()
+}
+
+// readStPids returns PIDs of all processes in the Split Tunnel environment
+func readStPids() map[int]struct{} {
+	ret := make(map[int]struct{})
+	pids, err := backend.pids()
+	if err != nil {
+		return ret
+	}
+	for _, pid := range pids {
+		ret[pid] = struct{}{}
+	}
+	return ret
+}
+
+func readProcArgs(pid int) []string {
+	bytes, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(string(bytes), "\x00"), "\x00")
+}
+
+func readProcPpid(pid int) int {
+	bytes, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0
+	}
+	// format: 'pid (comm) state ppid ...' (the 'comm' can contain spaces)
+	s := string(bytes)
+	idx := strings.LastIndex(s, ")")
+	if idx < 0 {
+		return 0
+	}
+	fields := strings.Fields(s[idx+1:])
+	if len(fields) < 2 {
+		return 0
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	return ppid
+}
@@ -0,0 +1,78 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package splittun
+
+import (
+	"os"
+	"testing"
+)
+
+func TestNormalizeAppRule(t *testing.T) {
+	tests := map[string]string{
+		" /usr/bin/../bin/firefox ":  "/usr/bin/firefox",
+		"firefox":                    "firefox.desktop",
+		"org.gnome.Epiphany.desktop": "org.gnome.Epiphany.desktop",
+		"":                           "",
+	}
+	for in, expected := range tests {
+		if out := normalizeAppRule(in); out != expected {
+			t.Errorf("normalizeAppRule(%q) = %q; expected %q", in, out, expected)
+		}
+	}
+}
+
+func TestMatchAppRule(t *testing.T) {
+	rules := []appRule{
+		{rule: "/usr/bin/firefox", binaries: []string{"/usr/bin/firefox", "/usr/lib/firefox/firefox.sh"}, isScript: true},
+		{rule: "chromium.desktop", binaries: []string{"/usr/bin/chromium"}},
+	}
+
+	tests := []struct {
+		exe      string
+		args     []string
+		expected string
+	}{
+		{exe: "/usr/bin/chromium", args: []string{"chromium"}, expected: "chromium.desktop"},
+		// script executed by an interpreter
+		{exe: "/usr/bin/bash", args: []string{"/bin/sh", "/usr/lib/firefox/firefox.sh", "--new-window"}, expected: "/usr/bin/firefox"},
+		{exe: "/usr/bin/bash", args: []string{"bash", "-c", "/usr/bin/firefox"}, expected: ""},
+		// the argument is not a script
+		{exe: "/usr/bin/curl", args: []string{"curl", "/usr/bin/chromium"}, expected: ""},
+		{exe: "/usr/bin/curl", args: nil, expected: ""},
+	}
+	for _, tc := range tests {
+		rule, ok := matchAppRule(rules, tc.exe, tc.args)
+		if rule != tc.expected || ok != (len(tc.expected) > 0) {
+			t.Errorf("matchAppRule(%q, %q) = %q, %v; expected %q", tc.exe, tc.args, rule, ok, tc.expected)
+		}
+	}
+}
+
+func TestReadProcPpid(t *testing.T) {
+	if ppid := readProcPpid(os.Getpid()); ppid != os.Getppid() {
+		t.Errorf("readProcPpid() = %d; expected %d", ppid, os.Getppid())
+	}
+}
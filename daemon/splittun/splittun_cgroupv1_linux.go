@@ -0,0 +1,227 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package splittun
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/ivpn/desktop-app/daemon/service/firewall/nftables"
+	"github.com/ivpn/desktop-app/daemon/shell"
+)
+
+const (
+	// The cgroup v2 hierarchy which is mounted in the hybrid mode (in addition to the cgroup v1 controllers)
+	cgroupV1UnifiedRoot = "/sys/fs/cgroup/unified"
+	// nftables table name of the traffic accounting rules of the cgroup v1 Split Tunnel
+	stAccountingTableName = "ivpn_st_accounting"
+)
+
+// cgroupV1Backend - Split Tunnel implementation based on cgroup v1 'net_cls' controller and iptables.
+// All the configuration is performed by the external script (platform.SplitTunScript()).
+//
+// All the processes in the Split Tunnel are sharing the same 'net_cls' class ID, so the traffic of each application
+// can not be distinguished by the iptables rules. For traffic accounting, each process added to the Split Tunnel is
+// also placed into its own child cgroup of the cgroup v2 hierarchy (<cgroupV1UnifiedRoot>/ivpn-exclude/app-<PID>), and
+// its traffic is counted by nftables ('socket cgroupv2' match) the same way as the cgroup v2 implementation does.
+// The traffic accounting is not available when the system has no cgroup v2 hierarchy mounted
+// (and the counters are not updated on kernels older than 5.15, where 'net_cls' disables the cgroup v2 socket matching).
+type cgroupV1Backend struct {
+	scriptPath string
+	// original cgroups (cgroup v2 hierarchy) of the processes added to the Split Tunnel (map[<PID>]<cgroup path>)
+	pidsOrigin map[int]string
+}
+
+func newCgroupV1Backend(scriptPath string) *cgroupV1Backend {
+	return &cgroupV1Backend{scriptPath: scriptPath, pidsOrigin: map[int]string{}}
+}
+
+func (b *cgroupV1Backend) name() string {
+	return "cgroup v1 (net_cls)"
+}
+
+func (b *cgroupV1Backend) pids() ([]int, error) {
+	return readPidsFile("/sys/fs/cgroup/net_cls/ivpn-exclude/cgroup.procs")
+}
+
+func (b *cgroupV1Backend) test() (err, inverseModeErr error) {
+	// Hardcoded text for detection of inverse mode not available error
+	const inverseModeErrorDetectionText = "Warning: Inverse mode for IVPN Split Tunnel functionality is not applicable."
+	// check if ST functionality accessible
+	outProcessFunc := func(text string, isError bool) {
+		if strings.HasPrefix(text, inverseModeErrorDetectionText) {
+			text = strings.TrimSpace(strings.TrimPrefix(text, "Warning: "))
+			inverseModeErr = fmt.Errorf("%s", text)
+			log.Warning(text)
+			return
+		}
+		if isError {
+			log.Error("Split Tunnel test: " + text)
+		} else {
+			log.Info("Split Tunnel test: " + text)
+		}
+	}
+	err = shell.ExecAndProcessOutput(nil, outProcessFunc, "", b.scriptPath, "test")
+	return err, inverseModeErr
+}
+
+func (b *cgroupV1Backend) isEnabled() (bool, error) {
+	err := shell.Exec(nil, b.scriptPath, "status")
+	if err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (b *cgroupV1Backend) enable(isStInversed, isInverseBlock, isInverseBlockIPv6 bool, destinations []net.IPNet) error {
+	if len(destinations) > 0 {
+		log.Warning("Split Tunnel destinations are ignored: not supported by the cgroup v1 implementation")
+	}
+
+	inversedArg := ""
+	inverseBlockArg := ""
+	if isStInversed {
+		inversedArg = "-inverse"
+		if isInverseBlock {
+			inverseBlockArg = "-inverse_block"
+		} else if isInverseBlockIPv6 {
+			inverseBlockArg = "-inverse_block_ipv6"
+		}
+	}
+	_, outErrText, _, _, err := shell.ExecAndGetOutput(log, 1024, "", b.scriptPath, "start", inversedArg, inverseBlockArg)
+	if err != nil {
+		if len(outErrText) > 0 {
+			err = fmt.Errorf("(%w) %s", err, outErrText)
+		}
+		// if ST start failed - clean everything (by command 'stop')
+		shell.Exec(nil, b.scriptPath, "stop")
+		return err
+	}
+	return nil
+}
+
+func (b *cgroupV1Backend) disable() error {
+	b.resetAccounting()
+	return shell.Exec(log, b.scriptPath, "stop")
+}
+
+func (b *cgroupV1Backend) updateRoutes() error {
+	return shell.Exec(nil, b.scriptPath, "update-routes")
+}
+
+func (b *cgroupV1Backend) addPid(pid int) error {
+	if err := shell.Exec(nil, b.scriptPath, "addpid", strconv.Itoa(pid)); err != nil {
+		return err
+	}
+	if err := b.addAccounting(pid); err != nil {
+		log.Warning(fmt.Errorf("traffic accounting is not available for PID:%d: %w", pid, err))
+	}
+	return nil
+}
+
+func (b *cgroupV1Backend) removePid(pid int) error {
+	if cg, err := processCgroup(pid); err == nil && strings.HasPrefix(cg, "/"+cgroupV2Name+"/") {
+		if err := restoreProcessCgroup(cgroupV1UnifiedRoot, pid, b.pidsOrigin); err != nil && !errors.Is(err, syscall.ESRCH) {
+			log.Warning(err)
+		}
+		b.applyAccounting()
+	}
+	return shell.Exec(nil, b.scriptPath, "removepid", strconv.Itoa(pid))
+}
+
+func (b *cgroupV1Backend) reset() error {
+	b.resetAccounting()
+	return shell.Exec(nil, b.scriptPath, "reset")
+}
+
+func (b *cgroupV1Backend) appsTraffic() ([]AppTraffic, error) {
+	if !isCgroupV2Mounted(cgroupV1UnifiedRoot) {
+		return nil, fmt.Errorf("traffic accounting is not available: the cgroup v2 hierarchy is not mounted (%s)", cgroupV1UnifiedRoot)
+	}
+	return readAppsTraffic(b.appsCgroupPath(), stAccountingTableName)
+}
+
+// appsCgroupPath returns the path of the parent cgroup for the applications cgroups (in the cgroup v2 hierarchy)
+func (b *cgroupV1Backend) appsCgroupPath() string {
+	return filepath.Join(cgroupV1UnifiedRoot, cgroupV2Name)
+}
+
+// addAccounting places the process into its own cgroup (in the cgroup v2 hierarchy) and updates the traffic accounting rules
+func (b *cgroupV1Backend) addAccounting(pid int) error {
+	if !isCgroupV2Mounted(cgroupV1UnifiedRoot) {
+		return fmt.Errorf("the cgroup v2 hierarchy is not mounted (%s)", cgroupV1UnifiedRoot)
+	}
+	if err := moveToAppCgroup(cgroupV1UnifiedRoot, pid, b.pidsOrigin); err != nil {
+		return err
+	}
+	return b.applyAccounting()
+}
+
+// applyAccounting updates the traffic accounting rules according to the current applications cgroups
+// (the table is removed when there are no applications)
+func (b *cgroupV1Backend) applyAccounting() error {
+	removeEmptyAppCgroups(b.appsCgroupPath())
+
+	apps, err := appCgroupsWithCounters(b.appsCgroupPath(), stAccountingTableName)
+	if err != nil {
+		return err
+	}
+	var rs *nftables.Ruleset
+	if len(apps) > 0 {
+		rs = &nftables.Ruleset{Table: stAccountingTableName, Chains: appsAccountingChains(apps)}
+	}
+	return nftables.ApplyTable(stAccountingTableName, rs)
+}
+
+// resetAccounting moves all processes out of the applications cgroups and removes the traffic accounting rules
+func (b *cgroupV1Backend) resetAccounting() {
+	apps, _ := appCgroups(b.appsCgroupPath())
+	for _, a := range apps {
+		pids, err := readPidsFile(filepath.Join(b.appsCgroupPath(), a.name(), "cgroup.procs"))
+		if err != nil {
+			continue // the cgroup was removed
+		}
+		for _, pid := range pids {
+			if err := restoreProcessCgroup(cgroupV1UnifiedRoot, pid, b.pidsOrigin); err != nil && !errors.Is(err, syscall.ESRCH) {
+				log.Warning(err)
+			}
+		}
+	}
+	removeEmptyAppCgroups(b.appsCgroupPath())
+	// Note: the cgroup folder will be removed only in case when no active process are in that cgroup
+	if err := os.Remove(b.appsCgroupPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Info(fmt.Sprintf("Split Tunnel accounting cgroup not removed: %s", err))
+	}
+	if err := nftables.ApplyTable(stAccountingTableName, nil); err != nil {
+		log.Warning(fmt.Errorf("failed to remove traffic accounting rules: %w", err))
+	}
+}
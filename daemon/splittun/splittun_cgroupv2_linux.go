@@ -0,0 +1,701 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package splittun
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/ivpn/desktop-app/daemon/service/firewall/nftables"
+	"golang.org/x/sys/unix"
+)
+
+// cgroup v2 Split Tunnel implementation:
+//   - the processes are placed into the dedicated cgroup (/sys/fs/cgroup/ivpn-exclude);
+//   - nftables 'route' chain marks packets of the sockets which belong to the cgroup ('socket cgroupv2' match);
+//   - the routing rule forwards marked packets to the dedicated routing table which contains the default route of the main table;
+//   - the marked packets are masqueraded on the default interface; the mark is restored for incoming packets (by conntrack).
+// The IVPN firewall allows all marked packets.
+//
+// Each process added to the Split Tunnel is placed into its own child cgroup (/sys/fs/cgroup/ivpn-exclude/app-<PID>);
+// the child processes inherit it. It allows to count the traffic of each application (nftables counters).
+
+const (
+	cgroupV2Root = "/sys/fs/cgroup"
+	cgroupV2Name = "ivpn-exclude"
+	// prefix of the child cgroup name for the application (app-<PID>)
+	cgroupV2AppPrefix = "app-"
+
+	// The 'mark' value for packets coming from the Split-Tunneling environment
+	// (the same as WireGuard marking packets which were processed; the same as the cgroup v1 implementation uses)
+	stFwMark = 0xca6c
+	// Routing table for the packets coming from the Split-Tunneling environment (the same as the cgroup v1 implementation uses)
+	stRoutingTable = 17
+	// WireGuard routing table (the rule 'not from all fwmark 0xca6c lookup 51820' is added by 'wg-quick' or by the userspace WireGuard implementation)
+	wgRoutingTable = 51820
+
+	// nftables table name of the Split Tunnel rules
+	stTableName = "ivpn_st"
+
+	// nftables standard priorities
+	nftPriorityMangle = -150
+	nftPriorityFilter = 0
+	nftPrioritySrcNat = 100
+)
+
+// isUnifiedCgroupV2 returns 'true' if the system is using unified cgroup v2 hierarchy only
+func isUnifiedCgroupV2() bool {
+	return isCgroupV2Mounted(cgroupV2Root)
+}
+
+// isCgroupV2Mounted returns 'true' if the cgroup v2 hierarchy is mounted to the path
+func isCgroupV2Mounted(path string) bool {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return false
+	}
+	return st.Type == unix.CGROUP2_SUPER_MAGIC
+}
+
+// cgroupV2RulesConfig - parameters of the nftables ruleset for the cgroup v2 Split Tunnel
+type cgroupV2RulesConfig struct {
+	CgroupID   uint64
+	IsInversed bool
+	// IsInverseBlock - (inverse mode) block the 'splitted' apps (e.g. when VPN is not connected)
+	IsInverseBlock bool
+	// IsInverseBlockIPv6 - (inverse mode) block IPv6 for the 'splitted' apps (e.g. when VPN does not support IPv6)
+	IsInverseBlockIPv6 bool
+	// Default interfaces. Empty value - the interface is not defined: all packets from Split Tunnel environment are blocked for this address family
+	InterfaceIPv4 string
+	InterfaceIPv6 string
+	// Destinations - networks excluded from the VPN tunnel (in inverse mode: the only networks which are using the VPN tunnel)
+	Destinations []net.IPNet
+	// Apps - child cgroups of the applications (the traffic counters are defined for each of them)
+	Apps []cgroupV2App
+}
+
+// cgroupV2App - child cgroup of the application in the Split Tunnel environment
+type cgroupV2App struct {
+	Pid      int // PID of the root process (the cgroup name is 'app-<PID>')
+	CgroupID uint64
+	// initial values of the counters (the counters are not reset when the ruleset is re-applied)
+	Sent, Received nftables.Counter
+}
+
+func (a cgroupV2App) name() string {
+	return cgroupV2AppPrefix + strconv.Itoa(a.Pid)
+}
+
+// counter comments: the counters are identified by the rule comment
+func (a cgroupV2App) sentCounterName() string     { return "tx:" + a.name() }
+func (a cgroupV2App) receivedCounterName() string { return "rx:" + a.name() }
+
+// buildCgroupV2Ruleset returns the nftables ruleset for the cgroup v2 Split Tunnel
+func buildCgroupV2Ruleset(cfg cgroupV2RulesConfig) *nftables.Ruleset {
+	// In inverse mode - the matching is inversed:
+	// 'splitted' apps use only VPN connection, all the rest apps use default connection settings (bypassing VPN)
+	stApps := nftables.Match{Type: nftables.MatchSocketCgroupV2, Negate: cfg.IsInversed, Name: cgroupV2Name, Value: 1, CgroupID: cfg.CgroupID}
+	cgroupApps := stApps
+	cgroupApps.Negate = false
+	marked := nftables.Match{Type: nftables.MatchMark, Value: stFwMark}
+	dnsPort := func(proto uint32) []nftables.Match {
+		return []nftables.Match{{Type: nftables.MatchL4Proto, Value: proto}, {Type: nftables.MatchDPort, Value: 53}}
+	}
+	family := func(isIPv6 bool) nftables.Match {
+		if isIPv6 {
+			return nftables.Match{Type: nftables.MatchNfProto, Value: nftables.NfProtoIPv6}
+		}
+		return nftables.Match{Type: nftables.MatchNfProto, Value: nftables.NfProtoIPv4}
+	}
+
+	setMark := []nftables.Statement{{Type: nftables.StatementSetMark, Value: stFwMark}}
+
+	// Mark packets of the Split Tunnel environment (the packets are re-routed according to the new mark).
+	// Important! DNS requests should not be marked.
+	output := nftables.Chain{Name: "output", Hook: nftables.HookOutput, Type: nftables.ChainTypeRoute, Priority: nftPriorityMangle, Policy: nftables.VerdictAccept}
+	if cfg.IsInversed {
+		// Inverse mode: packets to the destinations are always using the VPN tunnel (not marked)
+		for _, n := range cfg.Destinations {
+			output.Rules = append(output.Rules, nftables.Rule{Matches: dstMatches(n), Verdict: nftables.VerdictAccept})
+		}
+	}
+	for _, proto := range []uint32{nftables.ProtoUDP, nftables.ProtoTCP} {
+		output.Rules = append(output.Rules, nftables.Rule{Matches: append([]nftables.Match{stApps}, dnsPort(proto)...), Verdict: nftables.VerdictAccept})
+	}
+	output.Rules = append(output.Rules, nftables.Rule{Matches: []nftables.Match{stApps}, Statements: setMark})
+	if !cfg.IsInversed {
+		// packets to the destinations are excluded from the VPN tunnel (for all apps)
+		for _, n := range cfg.Destinations {
+			output.Rules = append(output.Rules, nftables.Rule{Matches: dstMatches(n), Statements: setMark})
+		}
+	}
+
+	// Save packets mark (to be able to restore mark for incoming packets of the same connection)
+	postrouting := nftables.Chain{Name: "postrouting", Hook: nftables.HookPostrouting, Priority: nftPriorityMangle, Policy: nftables.VerdictAccept,
+		Rules: []nftables.Rule{{Matches: []nftables.Match{marked}, Statements: []nftables.Statement{{Type: nftables.StatementSetCtMark, Value: stFwMark}}}}}
+	// Restore packets mark for incoming packets
+	prerouting := nftables.Chain{Name: "prerouting", Hook: nftables.HookPrerouting, Priority: nftPriorityMangle, Policy: nftables.VerdictAccept,
+		Rules: []nftables.Rule{{Matches: []nftables.Match{{Type: nftables.MatchCtMark, Value: stFwMark}}, Statements: []nftables.Statement{{Type: nftables.StatementSetMark, Value: stFwMark}}}}}
+
+	// Change the source IP address of packets to the IP address of the interface they're going out on
+	nat := nftables.Chain{Name: "postrouting_nat", Hook: nftables.HookPostrouting, Type: nftables.ChainTypeNat, Priority: nftPrioritySrcNat, Policy: nftables.VerdictAccept}
+	// Block packets which can not be processed (or blocked by the configuration)
+	filter := nftables.Chain{Name: "output_filter", Hook: nftables.HookOutput, Priority: nftPriorityFilter, Policy: nftables.VerdictAccept}
+	// Just ensure that packets to localhost will not be blocked
+	filter.Rules = append(filter.Rules, nftables.Rule{Matches: []nftables.Match{{Type: nftables.MatchOIFName, Name: "lo"}}, Verdict: nftables.VerdictAccept})
+	if cfg.IsInversed {
+		// Allow or block communication for 'splitted' apps in inverse mode
+		if cfg.IsInverseBlock {
+			filter.Rules = append(filter.Rules, nftables.Rule{Matches: []nftables.Match{cgroupApps}, Verdict: nftables.VerdictDrop})
+			for _, n := range cfg.Destinations {
+				filter.Rules = append(filter.Rules, nftables.Rule{Matches: dstMatches(n), Verdict: nftables.VerdictDrop})
+			}
+		} else if cfg.IsInverseBlockIPv6 {
+			filter.Rules = append(filter.Rules, nftables.Rule{Matches: []nftables.Match{family(true), cgroupApps}, Verdict: nftables.VerdictDrop})
+			for _, n := range cfg.Destinations {
+				if n.IP.To4() == nil {
+					filter.Rules = append(filter.Rules, nftables.Rule{Matches: dstMatches(n), Verdict: nftables.VerdictDrop})
+				}
+			}
+		}
+		// Important! Do not drop DNS requests (process DNS request before blocking rules below)
+		for _, proto := range []uint32{nftables.ProtoUDP, nftables.ProtoTCP} {
+			filter.Rules = append(filter.Rules, nftables.Rule{Matches: dnsPort(proto), Verdict: nftables.VerdictAccept})
+		}
+	}
+
+	for _, isIPv6 := range []bool{false, true} {
+		ifName := cfg.InterfaceIPv4
+		if isIPv6 {
+			ifName = cfg.InterfaceIPv6
+		}
+		if len(ifName) > 0 {
+			nat.Rules = append(nat.Rules, nftables.Rule{
+				Matches:    []nftables.Match{family(isIPv6), marked, {Type: nftables.MatchOIFName, Name: ifName}},
+				Statements: []nftables.Statement{{Type: nftables.StatementMasquerade}}})
+		} else {
+			// If default interface not defined - block all packets from cgroup
+			// (for example: IPv6 interface may be empty when IPv6 not configured on the system)
+			filter.Rules = append(filter.Rules, nftables.Rule{Matches: []nftables.Match{family(isIPv6), stApps}, Verdict: nftables.VerdictDrop})
+		}
+	}
+
+	chains := []nftables.Chain{output, postrouting, prerouting, nat, filter}
+
+	// Traffic accounting for each application
+	chains = append(chains, appsAccountingChains(cfg.Apps)...)
+
+	return &nftables.Ruleset{Table: stTableName, Chains: chains}
+}
+
+// appsAccountingChains returns the nftables chains which are counting the traffic of each application.
+// The outgoing packets are counted after the filtering (the blocked packets are not counted).
+func appsAccountingChains(apps []cgroupV2App) []nftables.Chain {
+	if len(apps) == 0 {
+		return nil
+	}
+	acctOut := nftables.Chain{Name: "accounting_out", Hook: nftables.HookOutput, Priority: nftPriorityFilter + 1, Policy: nftables.VerdictAccept}
+	acctIn := nftables.Chain{Name: "accounting_in", Hook: nftables.HookInput, Priority: nftPriorityFilter, Policy: nftables.VerdictAccept}
+	for _, a := range apps {
+		app := nftables.Match{Type: nftables.MatchSocketCgroupV2, Name: cgroupV2Name + "/" + a.name(), Value: 2, CgroupID: a.CgroupID}
+		acctOut.Rules = append(acctOut.Rules, nftables.Rule{Matches: []nftables.Match{app},
+			Statements: []nftables.Statement{{Type: nftables.StatementCounter, Counter: a.Sent}}, Comment: a.sentCounterName()})
+		acctIn.Rules = append(acctIn.Rules, nftables.Rule{Matches: []nftables.Match{app},
+			Statements: []nftables.Statement{{Type: nftables.StatementCounter, Counter: a.Received}}, Comment: a.receivedCounterName()})
+	}
+	return []nftables.Chain{acctOut, acctIn}
+}
+
+// dstMatches returns matches for the destination network
+// (the address family check is required before checking the address in the 'inet' table)
+func dstMatches(n net.IPNet) []nftables.Match {
+	family := uint32(nftables.NfProtoIPv6)
+	if ip4 := n.IP.To4(); ip4 != nil {
+		family = nftables.NfProtoIPv4
+		n.IP = ip4
+		if len(n.Mask) == net.IPv6len {
+			n.Mask = n.Mask[12:]
+		}
+	}
+	return []nftables.Match{{Type: nftables.MatchNfProto, Value: family}, {Type: nftables.MatchDAddr, Net: n}}
+}
+
+//---------------------------------------------------------------------
+
+// cgroupV2Backend - Split Tunnel implementation based on cgroup v2, nftables and policy routing (netlink)
+type cgroupV2Backend struct {
+	isInversed, isInverseBlock, isInverseBlockIPv6 bool
+	destinations                                   []net.IPNet
+
+	// original rp_filter values of the interfaces (map[<interface name>]<value>)
+	rpFilterBackup map[string]string
+	// original cgroups of the processes added to the Split Tunnel (map[<PID>]<cgroup path>)
+	pidsOrigin map[int]string
+}
+
+func newCgroupV2Backend() *cgroupV2Backend {
+	return &cgroupV2Backend{rpFilterBackup: map[string]string{}, pidsOrigin: map[int]string{}}
+}
+
+func (b *cgroupV2Backend) name() string {
+	return "cgroup v2 (nftables)"
+}
+
+func (b *cgroupV2Backend) cgroupPath() string {
+	return filepath.Join(cgroupV2Root, cgroupV2Name)
+}
+
+func (b *cgroupV2Backend) pids() ([]int, error) {
+	ret, err := readPidsFile(filepath.Join(b.cgroupPath(), "cgroup.procs"))
+	if err != nil {
+		return nil, err
+	}
+	apps, err := appCgroups(b.cgroupPath())
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range apps {
+		pids, err := readPidsFile(filepath.Join(b.cgroupPath(), a.name(), "cgroup.procs"))
+		if err != nil {
+			continue // the cgroup was removed
+		}
+		ret = append(ret, pids...)
+	}
+	return ret, nil
+}
+
+func (b *cgroupV2Backend) test() (err, inverseModeErr error) {
+	if err := nftables.CheckAvailable(); err != nil {
+		return err, nil
+	}
+
+	// Check if the kernel supports all required nftables functionality
+	// (e.g. 'socket cgroupv2' expression is available since Linux 5.13)
+	id, err := b.createCgroup()
+	if err != nil {
+		return err, nil
+	}
+	defer b.removeCgroup()
+
+	rs := buildCgroupV2Ruleset(cgroupV2RulesConfig{CgroupID: id, InterfaceIPv4: "lo", InterfaceIPv6: "lo"})
+	if err := nftables.ApplyTable(stTableName, rs); err != nil {
+		return fmt.Errorf("required nftables functionality is not supported by the kernel: %w", err), nil
+	}
+	if err := nftables.ApplyTable(stTableName, nil); err != nil {
+		return err, nil
+	}
+	return nil, nil
+}
+
+func (b *cgroupV2Backend) isEnabled() (bool, error) {
+	if _, err := os.Stat(b.cgroupPath()); err != nil {
+		return false, nil
+	}
+	return nftables.IsTableExists(stTableName)
+}
+
+func (b *cgroupV2Backend) enable(isStInversed, isInverseBlock, isInverseBlockIPv6 bool, destinations []net.IPNet) error {
+	b.isInversed, b.isInverseBlock, b.isInverseBlockIPv6 = isStInversed, isInverseBlock, isInverseBlockIPv6
+	b.destinations = destinations
+
+	if err := b.apply(); err != nil {
+		// if ST start failed - clean everything
+		b.disable()
+		return err
+	}
+	return nil
+}
+
+// apply (re)configures the Split Tunnel according to the current default routes
+func (b *cgroupV2Backend) apply() error {
+	routeIPv4, err := getDefaultRoute(unix.AF_INET)
+	if err != nil {
+		return err
+	}
+	if routeIPv4 == nil {
+		return fmt.Errorf("default gateway is not defined. Please, check internet connectivity")
+	}
+	routeIPv6, err := getDefaultRoute(unix.AF_INET6)
+	if err != nil {
+		log.Warning(fmt.Errorf("default IPv6 gateway: %w", err))
+	}
+
+	id, err := b.createCgroup()
+	if err != nil {
+		return err
+	}
+
+	apps, err := appCgroupsWithCounters(b.cgroupPath(), stTableName)
+	if err != nil {
+		return err
+	}
+
+	cfg := cgroupV2RulesConfig{
+		Apps:               apps,
+		CgroupID:           id,
+		IsInversed:         b.isInversed,
+		IsInverseBlock:     b.isInverseBlock,
+		IsInverseBlockIPv6: b.isInverseBlockIPv6,
+		InterfaceIPv4:      routeIPv4.IfName,
+		Destinations:       b.destinations,
+	}
+	if routeIPv6 != nil {
+		cfg.InterfaceIPv6 = routeIPv6.IfName
+	}
+	if err := nftables.ApplyTable(stTableName, buildCgroupV2Ruleset(cfg)); err != nil {
+		return err
+	}
+
+	// Set required reverse path filtering parameter (loose mode): the incoming packets are coming to the interface
+	// which is not in use by the main routing table
+	if err := b.setRpFilter(routeIPv4.IfName); err != nil {
+		log.Warning(err)
+	}
+
+	for _, r := range []struct {
+		family uint8
+		route  *defaultRoute
+	}{{unix.AF_INET, routeIPv4}, {unix.AF_INET6, routeIPv6}} {
+		if r.route == nil {
+			continue
+		}
+		// Packets with mark will use Split Tunnel routing table
+		if err := b.ensureRule(r.family); err != nil {
+			return err
+		}
+		// The Split Tunnel routing table has a default gateway route to the default interface
+		if err := routeReplaceDefault(r.family, stRoutingTable, *r.route); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *cgroupV2Backend) ensureRule(family uint8) error {
+	stRule := routingRule{Family: family, Table: stRoutingTable, Mark: stFwMark, SuppressPrefixLen: -1}
+
+	rules, err := rulesList(family)
+	if err != nil {
+		return err
+	}
+	isRuleExists, isWgRuleExists := false, false
+	for _, r := range rules {
+		if r == stRule {
+			isRuleExists = true
+		}
+		if r.Invert && r.Mark == stFwMark && r.Table == wgRoutingTable {
+			isWgRuleExists = true
+		}
+	}
+	if !isRuleExists {
+		if err := ruleAdd(stRule); err != nil {
+			return err
+		}
+	}
+
+	// Compatibility with WireGuard rules.
+	// Ensure rule 'from all lookup main suppress_prefixlength 0' has higher priority than Split Tunnel rule.
+	// This WireGuard rule respects the manually configured routes in the main table
+	// (routing decision is ignored for routes with a prefix length of 0: the 'default' route).
+	//
+	// Info: WireGuard adds such rules:
+	//   from all lookup main suppress_prefixlength 0
+	//   not from all fwmark 0xca6c lookup 51820
+	if isWgRuleExists {
+		wgRule := routingRule{Family: family, Table: unix.RT_TABLE_MAIN, SuppressPrefixLen: 0}
+		if err := ruleDel(wgRule); err != nil {
+			return err
+		}
+		if err := ruleAdd(wgRule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *cgroupV2Backend) disable() error {
+	var retErr error
+	setErr := func(err error) {
+		if err != nil {
+			log.Error(err)
+			if retErr == nil {
+				retErr = err
+			}
+		}
+	}
+
+	setErr(nftables.ApplyTable(stTableName, nil))
+	for _, family := range []uint8{unix.AF_INET, unix.AF_INET6} {
+		setErr(ruleDel(routingRule{Family: family, Table: stRoutingTable, Mark: stFwMark, SuppressPrefixLen: -1}))
+		setErr(routesFlushTable(family, stRoutingTable))
+	}
+	b.restoreRpFilter()
+	// Note: the cgroup folder will be removed only in case when no active process are in that cgroup
+	b.removeCgroup()
+	return retErr
+}
+
+func (b *cgroupV2Backend) updateRoutes() error {
+	if enabled, err := b.isEnabled(); err != nil || !enabled {
+		return err
+	}
+	return b.apply()
+}
+
+func (b *cgroupV2Backend) addPid(pid int) error {
+	if err := moveToAppCgroup(cgroupV2Root, pid, b.pidsOrigin); err != nil {
+		return err
+	}
+	// update the traffic accounting rules
+	if err := b.apply(); err != nil {
+		log.Warning(fmt.Errorf("failed to update traffic accounting rules: %w", err))
+	}
+	return nil
+}
+
+func (b *cgroupV2Backend) removePid(pid int) error {
+	defer removeEmptyAppCgroups(b.cgroupPath())
+	return restoreProcessCgroup(cgroupV2Root, pid, b.pidsOrigin)
+}
+
+func (b *cgroupV2Backend) reset() error {
+	pids, err := b.pids()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	var retErr error
+	for _, pid := range pids {
+		if err := b.removePid(pid); err != nil && !errors.Is(err, syscall.ESRCH) && retErr == nil {
+			retErr = err
+		}
+	}
+	return retErr
+}
+
+func (b *cgroupV2Backend) appsTraffic() ([]AppTraffic, error) {
+	return readAppsTraffic(b.cgroupPath(), stTableName)
+}
+
+// createCgroup creates the Split Tunnel cgroup (if not exists) and returns its ID
+func (b *cgroupV2Backend) createCgroup() (uint64, error) {
+	path := b.cgroupPath()
+	if err := os.Mkdir(path, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+		return 0, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	// cgroup v2 ID is the inode number of the cgroup directory
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return 0, fmt.Errorf("failed to get cgroup ID: %w", err)
+	}
+	return st.Ino, nil
+}
+
+func (b *cgroupV2Backend) removeCgroup() {
+	removeEmptyAppCgroups(b.cgroupPath())
+	if err := os.Remove(b.cgroupPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Info(fmt.Sprintf("Split Tunnel cgroup not removed: %s", err))
+	}
+}
+
+func (b *cgroupV2Backend) setRpFilter(ifName string) error {
+	file := fmt.Sprintf("/proc/sys/net/ipv4/conf/%s/rp_filter", ifName)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if _, ok := b.rpFilterBackup[ifName]; !ok {
+		b.rpFilterBackup[ifName] = strings.TrimSpace(string(data))
+	}
+	return os.WriteFile(file, []byte("2"), 0)
+}
+
+func (b *cgroupV2Backend) restoreRpFilter() {
+	for ifName, val := range b.rpFilterBackup {
+		file := fmt.Sprintf("/proc/sys/net/ipv4/conf/%s/rp_filter", ifName)
+		if err := os.WriteFile(file, []byte(val), 0); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Warning(err)
+		}
+	}
+	b.rpFilterBackup = map[string]string{}
+}
+
+//---------------------------------------------------------------------
+// Application cgroups (cgroup v2 hierarchy) and traffic accounting.
+// Used also by the cgroup v1 implementation (when the cgroup v2 hierarchy is mounted in the hybrid mode).
+
+// moveToAppCgroup places the process into its own child cgroup of the Split Tunnel cgroup (<root>/ivpn-exclude/app-<PID>).
+// The original cgroup of the process is saved in 'origins' (to be able to restore it on removing from Split Tunnel).
+func moveToAppCgroup(root string, pid int, origins map[int]string) error {
+	if origin, err := processCgroup(pid); err != nil {
+		log.Warning(err)
+	} else if origin != "/"+cgroupV2Name && !strings.HasPrefix(origin, "/"+cgroupV2Name+"/") {
+		origins[pid] = origin
+	}
+
+	// the application cgroup (the child processes are inheriting it)
+	appPath := filepath.Join(root, cgroupV2Name, cgroupV2AppPrefix+strconv.Itoa(pid))
+	if err := os.MkdirAll(appPath, 0755); err != nil {
+		return fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(appPath, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0); err != nil {
+		os.Remove(appPath)
+		return err
+	}
+	return nil
+}
+
+// restoreProcessCgroup moves the process back to its original cgroup (or to the root cgroup if the original one is unknown)
+func restoreProcessCgroup(root string, pid int, origins map[int]string) error {
+	pidStr := []byte(strconv.Itoa(pid))
+	if origin, ok := origins[pid]; ok {
+		delete(origins, pid)
+		err := os.WriteFile(filepath.Join(root, origin, "cgroup.procs"), pidStr, 0)
+		if err == nil {
+			return nil
+		}
+		log.Warning(fmt.Errorf("unable to move PID:%d to original cgroup '%s' (moving to root cgroup): %w", pid, origin, err))
+	}
+	return os.WriteFile(filepath.Join(root, "cgroup.procs"), pidStr, 0)
+}
+
+// appCgroups returns the child cgroups of the applications
+func appCgroups(cgroupPath string) ([]cgroupV2App, error) {
+	entries, err := os.ReadDir(cgroupPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ret []cgroupV2App
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		pidStr, ok := strings.CutPrefix(e.Name(), cgroupV2AppPrefix)
+		if !ok {
+			continue
+		}
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil {
+			continue
+		}
+		var st unix.Stat_t
+		if err := unix.Stat(filepath.Join(cgroupPath, e.Name()), &st); err != nil {
+			continue
+		}
+		ret = append(ret, cgroupV2App{Pid: pid, CgroupID: st.Ino})
+	}
+	return ret, nil
+}
+
+// appCgroupsWithCounters returns the child cgroups of the applications with the current values of their traffic counters
+// (the counters are not reset when the ruleset is re-applied)
+func appCgroupsWithCounters(cgroupPath, table string) ([]cgroupV2App, error) {
+	apps, err := appCgroups(cgroupPath)
+	if err != nil || len(apps) == 0 {
+		return apps, err
+	}
+	counters, err := nftables.ReadCounters(table)
+	if err != nil {
+		log.Warning(fmt.Errorf("failed to read traffic counters: %w", err))
+		return apps, nil
+	}
+	for i := range apps {
+		apps[i].Sent = counters[apps[i].sentCounterName()]
+		apps[i].Received = counters[apps[i].receivedCounterName()]
+	}
+	return apps, nil
+}
+
+// removeEmptyAppCgroups removes the child cgroups of the applications which have no running processes
+func removeEmptyAppCgroups(cgroupPath string) {
+	apps, _ := appCgroups(cgroupPath)
+	for _, a := range apps {
+		// Note: the cgroup folder will be removed only in case when no active process are in that cgroup
+		os.Remove(filepath.Join(cgroupPath, a.name()))
+	}
+}
+
+// readAppsTraffic returns the traffic counters of the applications
+func readAppsTraffic(cgroupPath, table string) ([]AppTraffic, error) {
+	// the applications which are not running anymore are not reported
+	removeEmptyAppCgroups(cgroupPath)
+
+	apps, err := appCgroups(cgroupPath)
+	if err != nil {
+		return nil, err
+	}
+	counters, err := nftables.ReadCounters(table)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]AppTraffic, 0, len(apps))
+	for _, a := range apps {
+		sent, received := counters[a.sentCounterName()], counters[a.receivedCounterName()]
+		app := AppTraffic{
+			Pid:             a.Pid,
+			PacketsSent:     sent.Packets,
+			BytesSent:       sent.Bytes,
+			PacketsReceived: received.Packets,
+			BytesReceived:   received.Bytes,
+		}
+		// command line of the first process in the cgroup (the root process may be already finished)
+		if pids, err := readPidsFile(filepath.Join(cgroupPath, a.name(), "cgroup.procs")); err == nil && len(pids) > 0 {
+			app.Cmdline = strings.Join(readProcArgs(pids[0]), " ")
+		}
+		ret = append(ret, app)
+	}
+	return ret, nil
+}
+
+// processCgroup returns cgroup v2 path of the process (relative to the cgroup root)
+func processCgroup(pid int) (string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("unable to determine cgroup of the process PID:%d", pid)
+}
@@ -0,0 +1,157 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package splittun
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/service/firewall/nftables"
+)
+
+func TestBuildCgroupV2Ruleset(t *testing.T) {
+	rs := buildCgroupV2Ruleset(cgroupV2RulesConfig{CgroupID: 1234, InterfaceIPv4: "eth0", InterfaceIPv6: "eth0"})
+	if rs.Table != stTableName {
+		t.Fatalf("unexpected table name '%s'", rs.Table)
+	}
+	output := rs.Chain("output")
+	if output == nil || output.Rules[len(output.Rules)-1].String() != `socket cgroupv2 level 1 "ivpn-exclude" meta mark set 0xca6c` {
+		t.Fatalf("packets from cgroup are not marked:\n%s", rs)
+	}
+	// DNS requests must not be marked
+	if !strings.Contains(rs.String(), `socket cgroupv2 level 1 "ivpn-exclude" meta l4proto udp th dport 53 accept`) {
+		t.Errorf("DNS requests are marked:\n%s", rs)
+	}
+	nat := rs.Chain("postrouting_nat")
+	if nat == nil || len(nat.Rules) != 2 {
+		t.Fatalf("unexpected NAT rules:\n%s", rs)
+	}
+	if s := nat.Rules[1].String(); s != `meta nfproto ipv6 meta mark 0xca6c oifname "eth0" masquerade` {
+		t.Errorf("unexpected NAT rule: %s", s)
+	}
+	if filter := rs.Chain("output_filter"); filter == nil || len(filter.Rules) != 1 {
+		t.Errorf("unexpected blocking rules:\n%s", rs)
+	}
+}
+
+func TestBuildCgroupV2RulesetInverse(t *testing.T) {
+	rs := buildCgroupV2Ruleset(cgroupV2RulesConfig{CgroupID: 1234, IsInversed: true, IsInverseBlock: true, InterfaceIPv4: "eth0"})
+	s := rs.String()
+
+	// all apps except the apps in cgroup are bypassing VPN
+	if !strings.Contains(s, `socket cgroupv2 level 1 != "ivpn-exclude" meta mark set 0xca6c`) {
+		t.Errorf("inverse mode: packets are not marked correctly:\n%s", s)
+	}
+	// block the apps in cgroup
+	if !strings.Contains(s, `socket cgroupv2 level 1 "ivpn-exclude" drop`) {
+		t.Errorf("inverse mode: apps are not blocked:\n%s", s)
+	}
+	// no IPv6 default interface: block IPv6 for the apps bypassing VPN
+	if !strings.Contains(s, `meta nfproto ipv6 socket cgroupv2 level 1 != "ivpn-exclude" drop`) {
+		t.Errorf("IPv6 is not blocked:\n%s", s)
+	}
+	if nat := rs.Chain("postrouting_nat"); nat == nil || len(nat.Rules) != 1 {
+		t.Errorf("unexpected NAT rules:\n%s", s)
+	}
+}
+
+func TestBuildCgroupV2RulesetDestinations(t *testing.T) {
+	_, n4, _ := net.ParseCIDR("192.168.100.0/24")
+	_, n6, _ := net.ParseCIDR("fd00::/64")
+	dst := []net.IPNet{*n4, *n6}
+
+	// the traffic to destinations is bypassing VPN
+	rs := buildCgroupV2Ruleset(cgroupV2RulesConfig{CgroupID: 1234, InterfaceIPv4: "eth0", Destinations: dst})
+	output := rs.Chain("output")
+	if output == nil || len(output.Rules) != 5 {
+		t.Fatalf("unexpected output rules:\n%s", rs)
+	}
+	if s := output.Rules[3].String(); s != `meta nfproto ipv4 ip daddr 192.168.100.0/24 meta mark set 0xca6c` {
+		t.Errorf("IPv4 destination is not marked: %s", s)
+	}
+	if s := output.Rules[4].String(); s != `meta nfproto ipv6 ip6 daddr fd00::/64 meta mark set 0xca6c` {
+		t.Errorf("IPv6 destination is not marked: %s", s)
+	}
+
+	// inverse mode: only the traffic to destinations is using VPN
+	rs = buildCgroupV2Ruleset(cgroupV2RulesConfig{CgroupID: 1234, IsInversed: true, IsInverseBlock: true, InterfaceIPv4: "eth0", Destinations: dst})
+	output = rs.Chain("output")
+	if output == nil || output.Rules[0].String() != `meta nfproto ipv4 ip daddr 192.168.100.0/24 accept` {
+		t.Fatalf("inverse mode: destination is not excluded from marking:\n%s", rs)
+	}
+	if !strings.Contains(rs.String(), `meta nfproto ipv6 ip6 daddr fd00::/64 drop`) {
+		t.Errorf("inverse mode: destination is not blocked:\n%s", rs)
+	}
+}
+
+func TestBuildCgroupV2RulesetAccounting(t *testing.T) {
+	if rs := buildCgroupV2Ruleset(cgroupV2RulesConfig{CgroupID: 1234, InterfaceIPv4: "eth0"}); rs.Chain("accounting_out") != nil {
+		t.Errorf("unexpected accounting rules:\n%s", rs)
+	}
+
+	rs := buildCgroupV2Ruleset(cgroupV2RulesConfig{CgroupID: 1234, InterfaceIPv4: "eth0", Apps: []cgroupV2App{
+		{Pid: 100, CgroupID: 1, Sent: nftables.Counter{Packets: 2, Bytes: 200}},
+		{Pid: 200, CgroupID: 2},
+	}})
+	out, in := rs.Chain("accounting_out"), rs.Chain("accounting_in")
+	if out == nil || in == nil || len(out.Rules) != 2 || len(in.Rules) != 2 {
+		t.Fatalf("unexpected accounting rules:\n%s", rs)
+	}
+	if s := out.Rules[0].String(); s != `socket cgroupv2 level 2 "ivpn-exclude/app-100" counter packets 2 bytes 200 comment "tx:app-100"` {
+		t.Errorf("unexpected accounting rule: %s", s)
+	}
+	if s := in.Rules[1].String(); s != `socket cgroupv2 level 2 "ivpn-exclude/app-200" counter packets 0 bytes 0 comment "rx:app-200"` {
+		t.Errorf("unexpected accounting rule: %s", s)
+	}
+}
+
+func TestAppCgroups(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"app-100", "app-x", "other"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app-200"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	apps, err := appCgroups(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps) != 1 || apps[0].Pid != 100 || apps[0].CgroupID == 0 {
+		t.Fatalf("unexpected application cgroups: %+v", apps)
+	}
+
+	// the Split Tunnel cgroup does not exist (e.g. Split Tunnel is disabled)
+	if apps, err := appCgroups(filepath.Join(dir, "not-exists")); err != nil || len(apps) != 0 {
+		t.Errorf("unexpected result for not existing cgroup: %+v, %v", apps, err)
+	}
+}
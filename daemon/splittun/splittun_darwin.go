@@ -24,6 +24,7 @@ package splittun
 
 import (
 	"fmt"
+	"net"
 )
 
 var (
@@ -38,14 +39,26 @@ func implFuncNotAvailableError() (generalStError, inversedStError error) {
 	return notImplementedError, fmt.Errorf("Inversed Split-Tunnelling is not implemented for this platform")
 }
 
+func implDestinationsNotAvailableError() error {
+	return notImplementedError
+}
+
 func implReset() error {
 	return notImplementedError
 }
 
-func implApplyConfig(isStEnabled, isStInversed, isStInverseAllowWhenNoVpn, isVpnEnabled bool, addrConfig ConfigAddresses, splitTunnelApps []string) error {
+func implApplyConfig(isStEnabled, isStInversed, isStInverseAllowWhenNoVpn, isVpnEnabled bool, addrConfig ConfigAddresses, splitTunnelApps []string, splitTunnelDestinations []net.IPNet) error {
 	return notImplementedError
 }
 
+func implCheckAppRule(rule string) (string, error) {
+	return "", notImplementedError
+}
+
+func implGetAppsTraffic() ([]AppTraffic, error) {
+	return nil, notImplementedError
+}
+
 func implAddPid(pid int, commandToExecute string) error {
 	return notImplementedError
 }
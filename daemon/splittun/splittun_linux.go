@@ -27,6 +27,7 @@ package splittun
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -36,25 +37,47 @@ import (
 
 	"github.com/ivpn/desktop-app/daemon/oshelpers/linux/netlink"
 	"github.com/ivpn/desktop-app/daemon/service/platform"
-	"github.com/ivpn/desktop-app/daemon/shell"
 )
 
 var (
 	// error describing details if functionality not available
 	funcNotAvailableError        error
 	inverseModeNotAvailableError error
-	stScriptPath                 string
 	isActive                     bool
+	// the active Split Tunnel implementation
+	backend stBackend
 )
 
 // Information about added running process to the ST (by implAddPid())
 // (map[<PID>]<command>)
 var _addedRootProcesses map[int]string = map[int]string{}
 
-const stPidsFile = "/sys/fs/cgroup/net_cls/ivpn-exclude/cgroup.procs"
+// stBackend - Split Tunnel implementation for the specific cgroup version
+type stBackend interface {
+	// name - short description of the implementation
+	name() string
+	// pids returns PIDs of all processes in the Split Tunnel environment
+	pids() ([]int, error)
+	// test returns non-nil errors if the functionality (or the inverse mode) is not available
+	test() (err, inverseModeErr error)
+	isEnabled() (bool, error)
+	// enable activates Split Tunnel. destinations - networks excluded from the VPN tunnel
+	// (in inverse mode: the only networks which are using the VPN tunnel)
+	enable(isStInversed, isInverseBlock, isInverseBlockIPv6 bool, destinations []net.IPNet) error
+	disable() error
+	// updateRoutes restores the routing configuration (e.g. after the default interface was changed)
+	updateRoutes() error
+	addPid(pid int) error
+	removePid(pid int) error
+	// reset removes all processes from the Split Tunnel environment
+	reset() error
+	// appsTraffic returns the traffic counters of the applications (processes added by addPid() and their child processes)
+	appsTraffic() ([]AppTraffic, error)
+}
 
 func implInitialize() error {
 	funcNotAvailableError = nil
+	inverseModeNotAvailableError = nil
 
 	snapEvs := platform.GetSnapEnvs()
 	if snapEvs != nil {
@@ -62,35 +85,31 @@ func implInitialize() error {
 		return funcNotAvailableError
 	}
 
-	stScriptPath = platform.SplitTunScript()
-	if len(stScriptPath) <= 0 {
-		funcNotAvailableError = fmt.Errorf("Split-Tunnelling script is not defined")
-		return funcNotAvailableError
+	// Modern distributions are using the unified cgroup v2 hierarchy only ('net_cls' controller is not available there)
+	if isUnifiedCgroupV2() {
+		backend = newCgroupV2Backend()
+	} else {
+		stScriptPath := platform.SplitTunScript()
+		if len(stScriptPath) <= 0 {
+			funcNotAvailableError = fmt.Errorf("Split-Tunnelling script is not defined")
+			return funcNotAvailableError
+		}
+		backend = newCgroupV1Backend(stScriptPath)
 	}
+	log.Info(fmt.Sprintf("Split Tunnel implementation: %s", backend.name()))
 
-	// Hardcoded text for detection of inverse mode not available error
-	const inverseModeErrorDetectionText = "Warning: Inverse mode for IVPN Split Tunnel functionality is not applicable."
 	// check if ST functionality accessible
-	outProcessFunc := func(text string, isError bool) {
-		if strings.HasPrefix(text, inverseModeErrorDetectionText) {
-			text = strings.TrimSpace(strings.TrimPrefix(text, "Warning: "))
-			inverseModeNotAvailableError = fmt.Errorf("%s", text)
-			log.Warning(text)
-			return
-		}
-		if isError {
-			log.Error("Split Tunnel test: " + text)
-		} else {
-			log.Info("Split Tunnel test: " + text)
-		}
-	}
-	err := shell.ExecAndProcessOutput(nil, outProcessFunc, "", stScriptPath, "test")
+	err, inverseErr := backend.test()
 	if err != nil {
-		funcNotAvailableError = err
+		funcNotAvailableError = fmt.Errorf("Split Tunnel [%s]: %w", backend.name(), err)
+		log.Error(funcNotAvailableError)
+	}
+	if inverseErr != nil {
+		inverseModeNotAvailableError = fmt.Errorf("Split Tunnel [%s]: %w", backend.name(), inverseErr)
 	}
 
 	// Ensure that ST is disable on daemon startup
-	enable(false, false, false, false, false)
+	enable(false, false, false, false, false, nil)
 
 	// Register network change detector
 	//
@@ -114,9 +133,10 @@ func implInitialize() error {
 					// We can receive many 'lan change' events in a short period of time
 					// but we update routes not more often than once per 2 seconds.
 					timerDelay = time.AfterFunc(time.Second*2, func() {
-						err := shell.Exec(nil, stScriptPath, "update-routes")
-						if err != nil {
-							log.Error("failed to update routes for SplitTunneling functionality")
+						mutex.Lock()
+						defer mutex.Unlock()
+						if err := backend.updateRoutes(); err != nil {
+							log.Error(fmt.Errorf("failed to update routes for SplitTunneling functionality: %w", err))
 						}
 					})
 				}
@@ -131,33 +151,58 @@ func implFuncNotAvailableError() (generalStError, inversedStError error) {
 	return funcNotAvailableError, inverseModeNotAvailableError
 }
 
+func implDestinationsNotAvailableError() error {
+	if funcNotAvailableError != nil {
+		return funcNotAvailableError
+	}
+	if _, ok := backend.(*cgroupV1Backend); ok {
+		return fmt.Errorf("Split Tunnel [%s]: destinations (networks and domains) are supported only by the cgroup v2 implementation", backend.name())
+	}
+	return nil
+}
+
 func implReset() error {
+	if backend == nil {
+		return funcNotAvailableError
+	}
 	log.Info("Removing all PIDs")
 
-	return shell.Exec(nil, stScriptPath, "reset")
+	return backend.reset()
 }
 
-func implApplyConfig(isStEnabled, isStInversed, isStInverseAllowWhenNoVpn, isVpnEnabled bool, addrConfig ConfigAddresses, splitTunnelApps []string) error {
+func implApplyConfig(isStEnabled, isStInversed, isStInverseAllowWhenNoVpn, isVpnEnabled bool, addrConfig ConfigAddresses, splitTunnelApps []string, splitTunnelDestinations []net.IPNet) error {
 	// If VPN does not support IPv6 - block IPv6 connectivity for 'splitted' apps in inverse mode
 	vpnNoIPv6 := false
 	if isVpnEnabled && len(addrConfig.IPv6Tunnel) == 0 {
 		vpnNoIPv6 = true
 	}
 
-	err := enable(isStEnabled, isStInversed, isStInverseAllowWhenNoVpn, isVpnEnabled, vpnNoIPv6)
+	setAppRules(splitTunnelApps)
+
+	err := enable(isStEnabled, isStInversed, isStInverseAllowWhenNoVpn, isVpnEnabled, vpnNoIPv6, splitTunnelDestinations)
 	if err != nil {
 		log.Error(err)
+		return err
 	}
-	return err
+
+	// persistent rules: add the running processes and start detection of the new ones
+	if isActive && len(appRules) > 0 {
+		startAppRulesWatcher()
+		applyAppRulesToRunningProcesses()
+	}
+	return nil
 }
 
 func implAddPid(pid int, commandToExecute string) error {
 	if pid <= 0 {
 		return fmt.Errorf("PID is not defined")
 	}
+	if backend == nil {
+		return funcNotAvailableError
+	}
 	log.Info(fmt.Sprintf("Adding PID:%d", pid))
 
-	enabled, err := isEnabled()
+	enabled, err := backend.isEnabled()
 	if err != nil {
 		return fmt.Errorf("unable to check Split Tunnel status")
 	}
@@ -165,7 +210,7 @@ func implAddPid(pid int, commandToExecute string) error {
 		return fmt.Errorf("the Split Tunnel is disabled")
 	}
 
-	err = shell.Exec(nil, stScriptPath, "addpid", strconv.Itoa(pid))
+	err = backend.addPid(pid)
 	if err == nil {
 		_addedRootProcesses[pid] = commandToExecute
 	}
@@ -176,6 +221,9 @@ func implRemovePid(pid int) error {
 	if pid <= 0 {
 		return fmt.Errorf("PID is not defined")
 	}
+	if backend == nil {
+		return funcNotAvailableError
+	}
 	var retErr error
 
 	// Remove PID and all it's child processes
@@ -201,7 +249,7 @@ func implRemovePid(pid int) error {
 	// remove all required pids
 	for pidToRemove := range pids {
 		log.Info(fmt.Sprintf("Removing PID:%d", pidToRemove))
-		err := shell.Exec(nil, stScriptPath, "removepid", strconv.Itoa(pidToRemove))
+		err := backend.removePid(pidToRemove)
 		if err != nil && retErr == nil {
 			retErr = err
 		}
@@ -215,26 +263,20 @@ func implRemovePid(pid int) error {
 func implGetRunningApps() (allProcesses []RunningApp, err error) {
 	// https://man7.org/linux/man-pages/man5/proc.5.html
 
+	if backend == nil {
+		return nil, funcNotAvailableError
+	}
+
 	// read all PIDs which are active in ST environment
-	bytes, err := os.ReadFile(stPidsFile)
+	pids, err := backend.pids()
 	if err != nil {
 		return nil, err
 	}
 
-	pidStrings := strings.Split(string(bytes), "\n")
-	retMapAll := make(map[int]RunningApp, len(pidStrings))
+	retMapAll := make(map[int]RunningApp, len(pids))
 	regexpStat := regexp.MustCompile(`^([0-9]*) (\([\S ]*\)) \S ([0-9]+) ([0-9]+) ([0-9]+)`)
 
-	for _, s := range pidStrings {
-		if len(s) <= 0 {
-			continue
-		}
-
-		pid, err := strconv.Atoi(s)
-		if err != nil {
-			log.Warning(err)
-			continue
-		}
+	for _, pid := range pids {
 
 		// read PPID, ProgessGroup, Session for each pid
 		ppid := 0
@@ -365,46 +407,58 @@ func implGetRunningApps() (allProcesses []RunningApp, err error) {
 	return retAll, nil
 }
 
-func isEnabled() (bool, error) {
-	err := shell.Exec(nil, stScriptPath, "status")
+func implGetAppsTraffic() ([]AppTraffic, error) {
+	if backend == nil {
+		return nil, funcNotAvailableError
+	}
+	if !isActive {
+		return []AppTraffic{}, nil
+	}
+
+	apps, err := backend.appsTraffic()
 	if err != nil {
-		return false, nil
+		return nil, err
+	}
+	// for known root processes - use the original command used to run process
+	for i := range apps {
+		if cmdLine, ok := _addedRootProcesses[apps[i].Pid]; ok {
+			apps[i].Cmdline = cmdLine
+		}
 	}
-	return true, nil
+	return apps, nil
 }
 
-func enable(isEnable, isStInversed, isStInverseAllowWhenNoVpn, isVpnConnected, vpnNoIPv6 bool) error {
+func enable(isEnable, isStInversed, isStInverseAllowWhenNoVpn, isVpnConnected, vpnNoIPv6 bool, destinations []net.IPNet) error {
+	if backend == nil {
+		if isEnable {
+			return funcNotAvailableError
+		}
+		return nil
+	}
+
 	if !isEnable {
-		enabled, err := isEnabled()
+		enabled, err := backend.isEnabled()
 		if err == nil && !enabled {
 			return nil
 		}
-		err = shell.Exec(log, stScriptPath, "stop")
+		err = backend.disable()
 		if err != nil {
 			return fmt.Errorf("failed to disable Split Tunnel: %w", err)
 		}
 		log.Info("Split Tunnel disabled")
 	} else {
-		inversedArg := ""
-		inverseBlockArg := ""
+		isInverseBlock := false
+		isInverseBlockIPv6 := false
 		if isStInversed {
-			inversedArg = "-inverse"
 			// Block 'inversed' apps when VPN is not connected
 			if !isVpnConnected && !isStInverseAllowWhenNoVpn {
-				inverseBlockArg = "-inverse_block"
+				isInverseBlock = true
 			} else if isVpnConnected && vpnNoIPv6 {
 				// If VPN does not support IPv6 - block IPv6 connectivity for 'splitted' apps in inverse mode
-				inverseBlockArg = "-inverse_block_ipv6"
+				isInverseBlockIPv6 = true
 			}
-
 		}
-		_, outErrText, _, _, err := shell.ExecAndGetOutput(log, 1024, "", stScriptPath, "start", inversedArg, inverseBlockArg)
-		if err != nil {
-			if len(outErrText) > 0 {
-				err = fmt.Errorf("(%w) %s", err, outErrText)
-			}
-			// if ST start failed - clean everything (by command 'stop')
-			shell.Exec(nil, stScriptPath, "stop")
+		if err := backend.enable(isStInversed, isInverseBlock, isInverseBlockIPv6, destinations); err != nil {
 			return fmt.Errorf("failed to enable Split Tunnel: %w", err)
 		}
 		log.Info("Split Tunnel enabled")
@@ -461,7 +515,7 @@ func readProcEnvVarIvpnId(pid int) (int, error) {
 	}
 
 	id := 0
-	vars := strings.Split(string(bytes), string(0))
+	vars := strings.Split(string(bytes), "\x00")
 	for _, line := range vars {
 		cols := strings.Split(line, "=")
 		if len(cols) != 2 {
@@ -474,3 +528,22 @@ func readProcEnvVarIvpnId(pid int) (int, error) {
 
 	return id, nil
 }
+
+// readPidsFile reads PIDs from the 'cgroup.procs' file
+func readPidsFile(path string) ([]int, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []int
+	for _, s := range strings.Fields(string(bytes)) {
+		pid, err := strconv.Atoi(s)
+		if err != nil {
+			log.Warning(err)
+			continue
+		}
+		ret = append(ret, pid)
+	}
+	return ret, nil
+}
@@ -0,0 +1,363 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package splittun
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"unsafe"
+
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// Policy routing (routing rules and routes) managed over rtnetlink.
+// In use by the cgroup v2 Split Tunnel implementation.
+
+// fibRuleHdr - header of the routing rule message (struct fib_rule_hdr)
+type fibRuleHdr struct {
+	Family uint8
+	DstLen uint8
+	SrcLen uint8
+	Tos    uint8
+	Table  uint8
+	Res1   uint8
+	Res2   uint8
+	Action uint8
+	Flags  uint32
+}
+
+const sizeofFibRuleHdr = int(unsafe.Sizeof(fibRuleHdr{}))
+
+// routingRule - the routing rule (only the properties in use by Split Tunnel)
+type routingRule struct {
+	Family uint8
+	Table  uint32
+	// Mark - fwmark to match (0 - any packet)
+	Mark uint32
+	// Invert - the rule matches packets which are NOT matching the selector (e.g. 'not fwmark 0xca6c')
+	Invert bool
+	// SuppressPrefixLen - reject routing decisions with the prefix length less or equal to the value (-1 - not defined)
+	SuppressPrefixLen int
+}
+
+// defaultRoute - the default route from the main routing table
+type defaultRoute struct {
+	Gateway net.IP
+	IfIndex int
+	IfName  string
+	metric  uint32
+}
+
+func rtnlDial() (*netlink.Conn, error) {
+	conn, err := netlink.Dial(unix.NETLINK_ROUTE, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rtnetlink socket: %w", err)
+	}
+	return conn, nil
+}
+
+func rtnlExecute(conn *netlink.Conn, msgType uint16, flags netlink.HeaderFlags, header []byte, ae *netlink.AttributeEncoder) ([]netlink.Message, error) {
+	data := header
+	if ae != nil {
+		attrs, err := ae.Encode()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, attrs...)
+	}
+	return conn.Execute(netlink.Message{
+		Header: netlink.Header{Type: netlink.HeaderType(msgType), Flags: netlink.Request | flags},
+		Data:   data,
+	})
+}
+
+//---------------------------------------------------------------------
+// routes
+
+func rtMsgBytes(m unix.RtMsg) []byte {
+	return (*[unix.SizeofRtMsg]byte)(unsafe.Pointer(&m))[:]
+}
+
+func rtMsgParse(b []byte) (unix.RtMsg, error) {
+	if len(b) < unix.SizeofRtMsg {
+		return unix.RtMsg{}, fmt.Errorf("bad route message")
+	}
+	return *(*unix.RtMsg)(unsafe.Pointer(&b[0])), nil
+}
+
+// routesList returns all routes (raw messages) of the specified address family
+func routesList(conn *netlink.Conn, family uint8) ([]netlink.Message, error) {
+	return rtnlExecute(conn, unix.RTM_GETROUTE, netlink.Dump, rtMsgBytes(unix.RtMsg{Family: family}), nil)
+}
+
+// routeTable returns the routing table ID of the route message
+func routeTable(m netlink.Message) (table uint32, rtm unix.RtMsg, ad *netlink.AttributeDecoder, err error) {
+	if rtm, err = rtMsgParse(m.Data); err != nil {
+		return 0, rtm, nil, err
+	}
+	if ad, err = netlink.NewAttributeDecoder(m.Data[unix.SizeofRtMsg:]); err != nil {
+		return 0, rtm, nil, err
+	}
+	return uint32(rtm.Table), rtm, ad, nil
+}
+
+// getDefaultRoute returns the default route (with the lowest metric) from the main routing table.
+// Returns nil if the default route does not exist.
+func getDefaultRoute(family uint8) (*defaultRoute, error) {
+	conn, err := rtnlDial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	msgs, err := routesList(conn, family)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get routes: %w", err)
+	}
+
+	var ret *defaultRoute
+	for _, m := range msgs {
+		table, rtm, ad, err := routeTable(m)
+		if err != nil {
+			return nil, err
+		}
+		if rtm.Dst_len != 0 || rtm.Type != unix.RTN_UNICAST {
+			continue
+		}
+		r := defaultRoute{}
+		for ad.Next() {
+			switch ad.Type() {
+			case unix.RTA_TABLE:
+				table = ad.Uint32()
+			case unix.RTA_GATEWAY:
+				r.Gateway = net.IP(ad.Bytes())
+			case unix.RTA_OIF:
+				r.IfIndex = int(ad.Uint32())
+			case unix.RTA_PRIORITY:
+				r.metric = ad.Uint32()
+			}
+		}
+		if err := ad.Err(); err != nil {
+			return nil, err
+		}
+		if table != unix.RT_TABLE_MAIN || r.Gateway == nil || r.IfIndex <= 0 {
+			continue
+		}
+		if ret == nil || r.metric < ret.metric {
+			ret = &r
+		}
+	}
+
+	if ret != nil {
+		ifc, err := net.InterfaceByIndex(ret.IfIndex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get default interface: %w", err)
+		}
+		ret.IfName = ifc.Name
+	}
+	return ret, nil
+}
+
+// routeReplaceDefault sets the default route in the routing table
+func routeReplaceDefault(family uint8, table uint32, r defaultRoute) error {
+	conn, err := rtnlDial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	rtm := unix.RtMsg{
+		Family:   family,
+		Table:    unix.RT_TABLE_UNSPEC,
+		Protocol: unix.RTPROT_BOOT,
+		Scope:    unix.RT_SCOPE_UNIVERSE,
+		Type:     unix.RTN_UNICAST,
+	}
+	ae := netlink.NewAttributeEncoder()
+	ae.Uint32(unix.RTA_TABLE, table)
+	ae.Bytes(unix.RTA_GATEWAY, ipBytes(family, r.Gateway))
+	ae.Uint32(unix.RTA_OIF, uint32(r.IfIndex))
+
+	if _, err := rtnlExecute(conn, unix.RTM_NEWROUTE, netlink.Acknowledge|netlink.Create|netlink.Replace, rtMsgBytes(rtm), ae); err != nil {
+		return fmt.Errorf("failed to set default route (table %d, via %s dev %s): %w", table, r.Gateway, r.IfName, err)
+	}
+	return nil
+}
+
+// routesFlushTable removes all routes from the routing table
+func routesFlushTable(family uint8, table uint32) error {
+	conn, err := rtnlDial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	msgs, err := routesList(conn, family)
+	if err != nil {
+		return fmt.Errorf("failed to get routes: %w", err)
+	}
+
+	var retErr error
+	for _, m := range msgs {
+		t, _, ad, err := routeTable(m)
+		if err != nil {
+			return err
+		}
+		for ad.Next() {
+			if ad.Type() == unix.RTA_TABLE {
+				t = ad.Uint32()
+			}
+		}
+		if t != table {
+			continue
+		}
+		// the route is removed by the same description as it was received
+		if _, err := rtnlExecute(conn, unix.RTM_DELROUTE, netlink.Acknowledge, m.Data, nil); err != nil && !errors.Is(err, syscall.ESRCH) {
+			retErr = fmt.Errorf("failed to remove route (table %d): %w", table, err)
+		}
+	}
+	return retErr
+}
+
+//---------------------------------------------------------------------
+// rules
+
+func (r routingRule) message() ([]byte, *netlink.AttributeEncoder) {
+	hdr := fibRuleHdr{Family: r.Family, Action: unix.FR_ACT_TO_TBL}
+	if r.Invert {
+		hdr.Flags = unix.FIB_RULE_INVERT
+	}
+	ae := netlink.NewAttributeEncoder()
+	ae.Uint32(unix.FRA_TABLE, r.Table)
+	if r.Mark != 0 {
+		ae.Uint32(unix.FRA_FWMARK, r.Mark)
+	}
+	if r.SuppressPrefixLen >= 0 {
+		ae.Uint32(unix.FRA_SUPPRESS_PREFIXLEN, uint32(r.SuppressPrefixLen))
+	}
+	return (*[sizeofFibRuleHdr]byte)(unsafe.Pointer(&hdr))[:], ae
+}
+
+func (r routingRule) String() string {
+	ret := "from all"
+	if r.Mark != 0 {
+		ret = fmt.Sprintf("from all fwmark 0x%x", r.Mark)
+	}
+	if r.Invert {
+		ret = "not " + ret
+	}
+	ret += fmt.Sprintf(" lookup %d", r.Table)
+	if r.SuppressPrefixLen >= 0 {
+		ret += fmt.Sprintf(" suppress_prefixlength %d", r.SuppressPrefixLen)
+	}
+	return ret
+}
+
+// ruleAdd adds the routing rule.
+// The rule is added with the highest priority (the same as 'ip rule add' does when the priority is not defined).
+func ruleAdd(r routingRule) error {
+	conn, err := rtnlDial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	hdr, ae := r.message()
+	if _, err := rtnlExecute(conn, unix.RTM_NEWRULE, netlink.Acknowledge|netlink.Create|netlink.Excl, hdr, ae); err != nil {
+		return fmt.Errorf("failed to add routing rule '%s': %w", r, err)
+	}
+	return nil
+}
+
+// ruleDel removes the routing rule. It is not an error if the rule does not exist.
+func ruleDel(r routingRule) error {
+	conn, err := rtnlDial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	hdr, ae := r.message()
+	if _, err := rtnlExecute(conn, unix.RTM_DELRULE, netlink.Acknowledge, hdr, ae); err != nil && !errors.Is(err, syscall.ENOENT) {
+		return fmt.Errorf("failed to remove routing rule '%s': %w", r, err)
+	}
+	return nil
+}
+
+// rulesList returns all routing rules of the specified address family
+func rulesList(family uint8) ([]routingRule, error) {
+	conn, err := rtnlDial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	hdr, _ := routingRule{Family: family}.message()
+	msgs, err := rtnlExecute(conn, unix.RTM_GETRULE, netlink.Dump, hdr, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get routing rules: %w", err)
+	}
+
+	ret := make([]routingRule, 0, len(msgs))
+	for _, m := range msgs {
+		if len(m.Data) < sizeofFibRuleHdr {
+			return nil, fmt.Errorf("bad routing rule message")
+		}
+		hdr := *(*fibRuleHdr)(unsafe.Pointer(&m.Data[0]))
+		r := routingRule{Family: hdr.Family, Table: uint32(hdr.Table), Invert: hdr.Flags&unix.FIB_RULE_INVERT != 0, SuppressPrefixLen: -1}
+
+		ad, err := netlink.NewAttributeDecoder(m.Data[sizeofFibRuleHdr:])
+		if err != nil {
+			return nil, err
+		}
+		for ad.Next() {
+			switch ad.Type() {
+			case unix.FRA_TABLE:
+				r.Table = ad.Uint32()
+			case unix.FRA_FWMARK:
+				r.Mark = ad.Uint32()
+			case unix.FRA_SUPPRESS_PREFIXLEN:
+				// the kernel reports -1 when the value is not defined
+				r.SuppressPrefixLen = int(int32(ad.Uint32()))
+			}
+		}
+		if err := ad.Err(); err != nil {
+			return nil, err
+		}
+		ret = append(ret, r)
+	}
+	return ret, nil
+}
+
+func ipBytes(family uint8, ip net.IP) []byte {
+	if family == unix.AF_INET {
+		return ip.To4()
+	}
+	return ip.To16()
+}
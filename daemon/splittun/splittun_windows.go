@@ -165,11 +165,15 @@ func implFuncNotAvailableError() (generalStError, inversedStError error) {
 	return funcNotAvailableError, nil
 }
 
+func implDestinationsNotAvailableError() error {
+	return fmt.Errorf("Split-Tunnelling for destinations is not implemented for this platform (only applications are supported)")
+}
+
 func implReset() error {
 	return nil
 }
 
-func implApplyConfig(isStEnabled, isStInversed, isStInverseAllowWhenNoVpn, isVpnEnabled bool, addrConfig ConfigAddresses, splitTunnelApps []string) error {
+func implApplyConfig(isStEnabled, isStInversed, isStInverseAllowWhenNoVpn, isVpnEnabled bool, addrConfig ConfigAddresses, splitTunnelApps []string, splitTunnelDestinations []net.IPNet) error {
 	// Check if functionality available
 	splitTunErr, splitTunInversedErr := GetFuncNotAvailableError()
 	isFunctionalityNotAvailable := splitTunErr != nil || (isStInversed && splitTunInversedErr != nil)
@@ -272,6 +276,14 @@ func implApplyConfig(isStEnabled, isStInversed, isStInverseAllowWhenNoVpn, isVpn
 	return nil
 }
 
+func implCheckAppRule(rule string) (string, error) {
+	return "", fmt.Errorf("operation not applicable for current platform")
+}
+
+func implGetAppsTraffic() ([]AppTraffic, error) {
+	return nil, fmt.Errorf("operation not applicable for current platform")
+}
+
 func implAddPid(pid int, commandToExecute string) error {
 	return fmt.Errorf("operation not applicable for current platform")
 }
@@ -50,6 +50,8 @@ type ConnectionParams struct {
 	proxyPassword        string
 	proxyAuthFileData    string // required for for obfs4 socks(!) proxy `--socks-proxy server [port] [authfile]`. If this parameter is defined - `proxyUsername` and `proxyPassword`` will be ignored.
 	// (e.g. the obfs4 requires the key to be stored in 'authfile': `cert=E50PjFC...6R7jzP0gYQ;iat-mode=0`)
+
+	customConfig *CustomConfig // custom (imported) OpenVPN configuration; nil for IVPN servers
 }
 
 func (c *ConnectionParams) IsMultihop() bool {
@@ -94,6 +96,35 @@ func CreateConnectionParams(
 		proxyPassword:        proxyPassword}
 }
 
+// CreateConnectionParamsCustom initializing connection parameters object for the custom OpenVPN configuration
+// Note: remote hostnames are resolved here
+func CreateConnectionParamsCustom(cfg CustomConfig) (ConnectionParams, error) {
+	remotes := make([]CustomConfigRemote, 0, len(cfg.Remotes))
+	for _, r := range cfg.Remotes {
+		ip, err := resolveRemote(r.Host)
+		if err != nil {
+			return ConnectionParams{}, fmt.Errorf("custom OpenVPN configuration: %w", err)
+		}
+		r.Host = ip.String()
+		remotes = append(remotes, r)
+	}
+	cfg.Remotes = remotes
+
+	return ConnectionParams{
+		username:     cfg.Username,
+		password:     cfg.Password,
+		tcp:          remotes[0].IsTCP,
+		hostPort:     remotes[0].Port,
+		hostIP:       net.ParseIP(remotes[0].Host),
+		customConfig: &cfg,
+	}, nil
+}
+
+// IsCustomConfig returns true when connection parameters are based on the custom OpenVPN configuration
+func (c *ConnectionParams) IsCustomConfig() bool {
+	return c.customConfig != nil
+}
+
 // WriteConfigFile saves OpenVPN connection parameters into a config file
 func (c *ConnectionParams) WriteConfigFile(
 	localPort int,
@@ -105,7 +136,15 @@ func (c *ConnectionParams) WriteConfigFile(
 	isCanUseV24Params bool,
 	upDownScriptArgs string) error {
 
-	cfg, err := c.generateConfiguration(localPort, miAddr, miPort, logFile, extraParameters, isCanUseV24Params, upDownScriptArgs)
+	var (
+		cfg []string
+		err error
+	)
+	if c.customConfig != nil {
+		cfg, err = c.generateCustomConfiguration(miAddr, miPort, logFile)
+	} else {
+		cfg, err = c.generateConfiguration(localPort, miAddr, miPort, logFile, extraParameters, isCanUseV24Params, upDownScriptArgs)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to generate openvpn configuration : %w", err)
 	}
@@ -119,7 +158,7 @@ func (c *ConnectionParams) WriteConfigFile(
 
 	log.Info("Configuring OpenVPN...\n",
 		"=====================\n",
-		configText,
+		hideInlineSecrets(cfg),
 		"\n=====================\n")
 
 	return nil
@@ -278,6 +317,75 @@ func (c *ConnectionParams) generateConfiguration(
 	return cfg, nil
 }
 
+// generateCustomConfiguration generates configuration based on the sanitised custom OpenVPN profile
+// (the management interface and logging are the same as for IVPN servers).
+// No scripts are allowed for custom profiles ('script-security' is not defined): DNS is managed by the daemon.
+func (c *ConnectionParams) generateCustomConfiguration(
+	miAddr string,
+	miPort int,
+	logFile string) (cfg []string, err error) {
+
+	cfg = make([]string, 0, 32+len(c.customConfig.Lines))
+
+	cfg = append(cfg, "client")
+	cfg = append(cfg, fmt.Sprintf("management %s %d", miAddr, miPort))
+	cfg = append(cfg, "management-client")
+	cfg = append(cfg, "management-hold")
+	if c.customConfig.IsAuthUserPass {
+		cfg = append(cfg, "auth-user-pass")
+		cfg = append(cfg, "auth-nocache")
+		cfg = append(cfg, "management-query-passwords")
+	}
+	cfg = append(cfg, "management-signal")
+
+	if len(logFile) > 0 && logger.IsEnabled() {
+		cfg = append(cfg, fmt.Sprintf(`log "%s"`, logFile))
+	}
+
+	cfg = append(cfg, "dev tun")
+
+	for _, r := range c.customConfig.Remotes {
+		ip := net.ParseIP(r.Host)
+		if ip == nil || ip.IsUnspecified() {
+			return nil, fmt.Errorf("unable to connect. Remote host IP not defined ('%s')", r.Host)
+		}
+		proto := "udp"
+		if r.IsTCP {
+			proto = "tcp-client"
+		}
+		cfg = append(cfg, fmt.Sprintf("remote %s %d %s", ip, r.Port, proto))
+	}
+
+	cfg = append(cfg, "resolv-retry infinite")
+	cfg = append(cfg, "nobind")
+	cfg = append(cfg, "verb 4")
+
+	cfg = append(cfg, c.customConfig.Lines...)
+
+	return cfg, nil
+}
+
+// hideInlineSecrets returns configuration text with the content of inline blocks (keys, certificates) hidden.
+// Used to avoid printing private keys into the log.
+func hideInlineSecrets(cfg []string) string {
+	ret := make([]string, 0, len(cfg))
+	isInline := false
+	for _, l := range cfg {
+		switch {
+		case strings.HasPrefix(l, "</"):
+			isInline = false
+		case strings.HasPrefix(l, "<") && strings.HasSuffix(l, ">"):
+			isInline = true
+			ret = append(ret, l, "***")
+			continue
+		}
+		if !isInline {
+			ret = append(ret, l)
+		}
+	}
+	return strings.Join(ret, "\n")
+}
+
 // merge current parameters with user-defined parameters
 func addUserDefinedParameters(currParams []string, userParams string) ([]string, error) {
 	if len(userParams) <= 0 {
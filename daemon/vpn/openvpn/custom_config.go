@@ -0,0 +1,311 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package openvpn
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// CustomConfig - OpenVPN configuration imported from a third-party '.ovpn' profile
+type CustomConfig struct {
+	Remotes []CustomConfigRemote
+	// The profile requires username/password authentication ('auth-user-pass')
+	IsAuthUserPass bool
+	// Credentials from the inline '<auth-user-pass>' block (if defined)
+	Username string
+	Password string
+	// Sanitised profile lines (including inline blocks).
+	// The connection-specific directives ('remote', 'proto', 'dev', 'auth-user-pass' ...) are excluded: they are generated by the daemon.
+	Lines []string
+	// Directives removed from the profile by sanitising (e.g. 'client', 'verb', 'up')
+	RemovedDirectives []string
+}
+
+// CustomConfigRemote - the remote server defined by the 'remote' directive
+type CustomConfigRemote struct {
+	Host  string
+	Port  int
+	IsTCP bool
+}
+
+// Client directives accepted in the profile (passed to OpenVPN as is).
+// The profile with any other directive is rejected (except the directives processed by the parser:
+// 'remote', 'proto', 'port', 'dev', 'auth-user-pass' and the removed ones).
+var customConfigAllowedDirectives = map[string]struct{}{
+	// crypto
+	"cipher": {}, "data-ciphers": {}, "data-ciphers-fallback": {}, "ncp-ciphers": {}, "ncp-disable": {}, "auth": {},
+	"tls-client": {}, "tls-cipher": {}, "tls-ciphersuites": {}, "tls-groups": {}, "tls-cert-profile": {}, "ecdh-curve": {},
+	"tls-version-min": {}, "tls-version-max": {}, "key-direction": {}, "key-method": {},
+	"remote-cert-tls": {}, "remote-cert-ku": {}, "remote-cert-eku": {}, "ns-cert-type": {}, "verify-x509-name": {},
+	"replay-window": {}, "mute-replay-warnings": {}, "reneg-sec": {}, "reneg-bytes": {}, "reneg-pkts": {},
+	"hand-window": {}, "tran-window": {}, "tls-timeout": {}, "tls-exit": {},
+	// inline keys and certificates ('[inline]' argument only)
+	"ca": {}, "cert": {}, "key": {}, "tls-auth": {}, "tls-crypt": {}, "tls-crypt-v2": {},
+	"extra-certs": {}, "pkcs12": {}, "crl-verify": {},
+	// connection
+	"remote-random": {}, "remote-random-hostname": {}, "float": {}, "connect-retry": {}, "connect-retry-max": {},
+	"connect-timeout": {}, "server-poll-timeout": {}, "explicit-exit-notify": {}, "inactive": {},
+	"keepalive": {}, "ping": {}, "ping-restart": {}, "ping-exit": {}, "ping-timer-rem": {},
+	"persist-key": {}, "persist-tun": {}, "push-peer-info": {},
+	// tunnel and routing
+	"pull": {}, "pull-filter": {}, "topology": {}, "tun-ipv6": {}, "tun-mtu": {}, "tun-mtu-extra": {},
+	"mssfix": {}, "fragment": {}, "sndbuf": {}, "rcvbuf": {}, "txqueuelen": {},
+	"redirect-gateway": {}, "route": {}, "route-ipv6": {}, "route-delay": {}, "route-metric": {}, "route-nopull": {},
+	"dhcp-option": {}, "block-outside-dns": {}, "block-ipv6": {},
+	// compression
+	"compress": {}, "comp-lzo": {}, "allow-compression": {},
+}
+
+// Directives which are removed from the profile: they are generated or controlled by the daemon
+// (the DNS update scripts 'up'/'down' are replaced by the DNS management of the daemon;
+// no scripts are executed for custom profiles)
+var customConfigRemovedDirectives = map[string]struct{}{
+	"client": {}, "nobind": {}, "resolv-retry": {}, "verb": {}, "mute": {}, "auth-nocache": {},
+	"script-security": {}, "up": {}, "down": {},
+}
+
+// Directives which are not supported (the profile with such directive is rejected)
+var customConfigUnsupportedDirectives = map[string]string{
+	"http-proxy":           "proxy is not supported",
+	"socks-proxy":          "proxy is not supported",
+	"http-proxy-user-pass": "proxy is not supported",
+	"askpass":              "private key passphrase is not supported",
+	"dev-node":             "custom device is not supported",
+	"dev-type":             "custom device is not supported",
+	"lladdr":               "custom device is not supported",
+	"server":               "server configuration is not supported",
+	"mode":                 "server configuration is not supported",
+	"secret":               "static key mode is not supported",
+}
+
+// Directives which refer to a file. Only inline blocks (e.g. '<ca>...</ca>') are accepted for them
+var customConfigFileDirectives = map[string]struct{}{
+	"ca": {}, "cert": {}, "key": {}, "tls-auth": {}, "tls-crypt": {}, "tls-crypt-v2": {},
+	"extra-certs": {}, "pkcs12": {}, "crl-verify": {},
+}
+
+// Inline blocks accepted in the profile
+var customConfigInlineBlocks = map[string]struct{}{
+	"ca": {}, "cert": {}, "key": {}, "tls-auth": {}, "tls-crypt": {}, "tls-crypt-v2": {},
+	"extra-certs": {}, "pkcs12": {}, "crl-verify": {}, "auth-user-pass": {},
+}
+
+// ParseCustomConfig parses and sanitises the third-party '.ovpn' profile
+func ParseCustomConfig(text string) (CustomConfig, error) {
+	var (
+		cfg         CustomConfig
+		isTCP       bool
+		defaultPort = 1194
+		remotes     [][]string // arguments of 'remote' directives
+		hasCA       bool
+	)
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		// inline block
+		if strings.HasPrefix(line, "<") && strings.HasSuffix(line, ">") && !strings.HasPrefix(line, "</") {
+			tag := strings.ToLower(line[1 : len(line)-1])
+			if _, ok := customConfigInlineBlocks[tag]; !ok {
+				return CustomConfig{}, fmt.Errorf("line %d: inline block '<%s>' is not supported", lineNo, tag)
+			}
+
+			var content []string
+			isClosed := false
+			for scanner.Scan() {
+				lineNo++
+				l := strings.TrimSpace(scanner.Text())
+				if strings.EqualFold(l, "</"+tag+">") {
+					isClosed = true
+					break
+				}
+				content = append(content, l)
+			}
+			if !isClosed {
+				return CustomConfig{}, fmt.Errorf("inline block '<%s>' is not closed", tag)
+			}
+
+			switch tag {
+			case "auth-user-pass":
+				if len(content) < 2 {
+					return CustomConfig{}, fmt.Errorf("inline block '<auth-user-pass>': username and password expected")
+				}
+				cfg.Username, cfg.Password = content[0], content[1]
+			default:
+				if tag == "ca" {
+					hasCA = true
+				}
+				cfg.Lines = append(cfg.Lines, "<"+tag+">")
+				cfg.Lines = append(cfg.Lines, content...)
+				cfg.Lines = append(cfg.Lines, "</"+tag+">")
+			}
+			continue
+		}
+
+		args := strings.Fields(line)
+		directive := strings.ToLower(strings.TrimPrefix(args[0], "--"))
+		args = args[1:]
+
+		if _, ok := customConfigRemovedDirectives[directive]; ok {
+			cfg.RemovedDirectives = append(cfg.RemovedDirectives, directive)
+			continue
+		}
+		if reason, ok := customConfigUnsupportedDirectives[directive]; ok {
+			return CustomConfig{}, fmt.Errorf("line %d: '%s': %s", lineNo, directive, reason)
+		}
+		if _, ok := customConfigFileDirectives[directive]; ok {
+			if len(args) == 0 || args[0] != "[inline]" {
+				return CustomConfig{}, fmt.Errorf("line %d: '%s': external files are not supported (use inline block '<%s>')", lineNo, directive, directive)
+			}
+		}
+
+		switch directive {
+		case "remote":
+			if len(args) == 0 {
+				return CustomConfig{}, fmt.Errorf("line %d: 'remote': host not defined", lineNo)
+			}
+			remotes = append(remotes, args)
+			continue
+		case "proto":
+			if len(args) == 0 {
+				return CustomConfig{}, fmt.Errorf("line %d: 'proto': value not defined", lineNo)
+			}
+			var err error
+			if isTCP, err = parseProto(args[0]); err != nil {
+				return CustomConfig{}, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			continue
+		case "port", "rport":
+			if len(args) == 0 {
+				return CustomConfig{}, fmt.Errorf("line %d: '%s': value not defined", lineNo, directive)
+			}
+			var err error
+			if defaultPort, err = parsePort(args[0]); err != nil {
+				return CustomConfig{}, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			continue
+		case "dev":
+			if len(args) == 0 || !strings.HasPrefix(strings.ToLower(args[0]), "tun") {
+				return CustomConfig{}, fmt.Errorf("line %d: 'dev': only 'tun' devices are supported", lineNo)
+			}
+			continue
+		case "auth-user-pass":
+			if len(args) > 0 {
+				return CustomConfig{}, fmt.Errorf("line %d: 'auth-user-pass': credentials file is not supported (use inline block '<auth-user-pass>')", lineNo)
+			}
+			cfg.IsAuthUserPass = true
+			continue
+		}
+
+		if _, ok := customConfigAllowedDirectives[directive]; !ok {
+			return CustomConfig{}, fmt.Errorf("line %d: directive '%s' is not supported", lineNo, directive)
+		}
+		// prevent injection of extra lines (e.g. by using special characters)
+		if strings.ContainsAny(line, "\r\x00") {
+			return CustomConfig{}, fmt.Errorf("line %d: unexpected characters", lineNo)
+		}
+		cfg.Lines = append(cfg.Lines, strings.Join(append([]string{directive}, args...), " "))
+	}
+	if err := scanner.Err(); err != nil {
+		return CustomConfig{}, err
+	}
+
+	for _, r := range remotes {
+		remote := CustomConfigRemote{Host: r[0], Port: defaultPort, IsTCP: isTCP}
+		var err error
+		if len(r) > 1 {
+			if remote.Port, err = parsePort(r[1]); err != nil {
+				return CustomConfig{}, fmt.Errorf("'remote %s': %w", r[0], err)
+			}
+		}
+		if len(r) > 2 {
+			if remote.IsTCP, err = parseProto(r[2]); err != nil {
+				return CustomConfig{}, fmt.Errorf("'remote %s': %w", r[0], err)
+			}
+		}
+		cfg.Remotes = append(cfg.Remotes, remote)
+	}
+
+	if len(cfg.Remotes) == 0 {
+		return CustomConfig{}, fmt.Errorf("'remote' not defined")
+	}
+	if !hasCA {
+		return CustomConfig{}, fmt.Errorf("inline block '<ca>' not defined")
+	}
+	if len(cfg.Username) > 0 && !cfg.IsAuthUserPass {
+		cfg.IsAuthUserPass = true
+	}
+	return cfg, nil
+}
+
+// AddCredentials returns the profile text extended by the inline '<auth-user-pass>' block
+func AddCredentials(text, username, password string) (string, error) {
+	if strings.ContainsAny(username, "\r\n") || strings.ContainsAny(password, "\r\n") {
+		return "", fmt.Errorf("credentials must not contain line breaks")
+	}
+	return strings.TrimRight(text, "\r\n") + "\n<auth-user-pass>\n" + username + "\n" + password + "\n</auth-user-pass>\n", nil
+}
+
+func parseProto(proto string) (isTCP bool, err error) {
+	p := strings.ToLower(proto)
+	switch {
+	case strings.HasPrefix(p, "udp"):
+		return false, nil
+	case p == "tcp" || p == "tcp4" || p == "tcp6" || strings.HasPrefix(p, "tcp-client") || strings.HasPrefix(p, "tcp4-client") || strings.HasPrefix(p, "tcp6-client"):
+		return true, nil
+	}
+	return false, fmt.Errorf("unsupported protocol '%s'", proto)
+}
+
+func parsePort(port string) (int, error) {
+	p, err := strconv.Atoi(port)
+	if err != nil || p <= 0 || p > 65535 {
+		return 0, fmt.Errorf("bad port '%s'", port)
+	}
+	return p, nil
+}
+
+// resolveRemote returns IP address of the remote host (IPv4 preferred)
+func resolveRemote(host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return nil, fmt.Errorf("failed to resolve remote host '%s': %w", host, err)
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip, nil
+		}
+	}
+	return ips[0], nil
+}
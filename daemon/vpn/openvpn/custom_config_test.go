@@ -0,0 +1,173 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package openvpn_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/vpn/openvpn"
+)
+
+func TestParseCustomConfig(t *testing.T) {
+	cfgText := `
+# third-party profile
+client
+dev tun
+proto tcp
+port 1443
+remote vpn1.example.com
+remote 192.0.2.10 1194 udp
+resolv-retry infinite
+nobind
+persist-key
+cipher AES-256-GCM
+auth-user-pass
+script-security 2
+up /etc/openvpn/update-resolv-conf
+down /etc/openvpn/update-resolv-conf
+verb 3
+<ca>
+-----BEGIN CERTIFICATE-----
+MIIB
+-----END CERTIFICATE-----
+</ca>
+<tls-crypt>
+-----BEGIN OpenVPN Static key V1-----
+abcdef
+-----END OpenVPN Static key V1-----
+</tls-crypt>
+`
+	cfg, err := openvpn.ParseCustomConfig(cfgText)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfg.Remotes) != 2 {
+		t.Fatalf("expected 2 remotes, got %d", len(cfg.Remotes))
+	}
+	if r := cfg.Remotes[0]; r.Host != "vpn1.example.com" || r.Port != 1443 || !r.IsTCP {
+		t.Errorf("unexpected remote #1: %+v", r)
+	}
+	if r := cfg.Remotes[1]; r.Host != "192.0.2.10" || r.Port != 1194 || r.IsTCP {
+		t.Errorf("unexpected remote #2: %+v", r)
+	}
+	if !cfg.IsAuthUserPass || cfg.Username != "" {
+		t.Errorf("unexpected authentication: %v '%s'", cfg.IsAuthUserPass, cfg.Username)
+	}
+
+	if removed := strings.Join(cfg.RemovedDirectives, ","); removed != "client,resolv-retry,nobind,script-security,up,down,verb" {
+		t.Errorf("unexpected removed directives: %s", removed)
+	}
+
+	lines := strings.Join(cfg.Lines, "\n")
+	for _, l := range []string{"persist-key", "cipher AES-256-GCM", "<ca>", "MIIB", "</tls-crypt>"} {
+		if !strings.Contains(lines, l) {
+			t.Errorf("line '%s' expected in sanitised configuration", l)
+		}
+	}
+	for _, l := range []string{"update-resolv-conf", "script-security", "remote", "proto", "auth-user-pass"} {
+		if strings.Contains(lines, l) {
+			t.Errorf("unexpected '%s' in sanitised configuration", l)
+		}
+	}
+}
+
+func TestParseCustomConfigCredentials(t *testing.T) {
+	cfgText := "remote 192.0.2.10\n<ca>\nCA\n</ca>\n"
+	cfgText, err := openvpn.AddCredentials(cfgText, "user", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := openvpn.ParseCustomConfig(cfgText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.IsAuthUserPass || cfg.Username != "user" || cfg.Password != "secret" {
+		t.Errorf("unexpected credentials: %v '%s' '%s'", cfg.IsAuthUserPass, cfg.Username, cfg.Password)
+	}
+	if strings.Contains(strings.Join(cfg.Lines, "\n"), "secret") {
+		t.Error("credentials must not be a part of sanitised configuration")
+	}
+
+	if _, err := openvpn.AddCredentials(cfgText, "user\nremote 1.2.3.4", "secret"); err == nil {
+		t.Error("expected error for credentials with line breaks")
+	}
+}
+
+func TestParseCustomConfigErrors(t *testing.T) {
+	const ca = "<ca>\nCA\n</ca>\n"
+	tests := map[string]string{
+		"no remote":        ca,
+		"no ca":            "remote 192.0.2.10\n",
+		"ca file":          "remote 192.0.2.10\nca /etc/ca.crt\n",
+		"key file":         "remote 192.0.2.10\nkey client.key\n" + ca,
+		"credentials file": "remote 192.0.2.10\nauth-user-pass creds.txt\n" + ca,
+		"proxy":            "remote 192.0.2.10\nhttp-proxy 10.0.0.1 8080\n" + ca,
+		"tap device":       "remote 192.0.2.10\ndev tap\n" + ca,
+		"bad port":         "remote 192.0.2.10 70000\n" + ca,
+		"bad proto":        "remote 192.0.2.10\nproto sctp\n" + ca,
+		"unknown block":    "remote 192.0.2.10\n<connection>\nremote 1.1.1.1\n</connection>\n" + ca,
+		"unclosed block":   "remote 192.0.2.10\n<ca>\nCA\n",
+		"incomplete creds": "remote 192.0.2.10\n<auth-user-pass>\nuser\n</auth-user-pass>\n" + ca,
+		"plugin":           "remote 192.0.2.10\nplugin /usr/lib/openvpn/plugin.so\n" + ca,
+		"management":       "remote 192.0.2.10\nmanagement 127.0.0.1 7505\n" + ca,
+		"route-up script":  "remote 192.0.2.10\nroute-up /tmp/evil.sh\n" + ca,
+		"setenv":           "remote 192.0.2.10\nsetenv PATH /tmp\n" + ca,
+		"server directive": "remote 192.0.2.10\nifconfig-pool-persist ipp.txt\n" + ca,
+		"unknown":          "remote 192.0.2.10\nsome-new-directive 1\n" + ca,
+	}
+	for name, cfgText := range tests {
+		if _, err := openvpn.ParseCustomConfig(cfgText); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestCustomConfigNoScripts(t *testing.T) {
+	cfg, err := openvpn.ParseCustomConfig("remote 192.0.2.10\nscript-security 2\nup /tmp/up.sh\n<ca>\nCA\n</ca>\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := openvpn.CreateConnectionParamsCustom(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfgFile := filepath.Join(t.TempDir(), "client.ovpn")
+	if err := params.WriteConfigFile(0, cfgFile, "127.0.0.1", 7505, "", "", true, ""); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range strings.Split(string(data), "\n") {
+		f := strings.Fields(l)
+		if len(f) > 0 && (f[0] == "script-security" || f[0] == "up" || f[0] == "down") {
+			t.Errorf("unexpected line in configuration of custom profile: '%s'", l)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package openvpn
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// ExportConfig returns the standalone OpenVPN configuration ('.ovpn' profile) based on the connection parameters.
+// The CA certificate and TLS auth key are inlined into the profile.
+// If 'includeCredentials' is true - the credentials (see SetCredentials()) are inlined as '<auth-user-pass>' block (requires OpenVPN 2.6 or newer).
+// 'dnsServers' - DNS servers to be used in tunnel (if empty - the DNS server pushed by the VPN host is in use)
+func (c *ConnectionParams) ExportConfig(caKeyFile, taKeyFile string, includeCredentials bool, dnsServers []net.IP) (string, error) {
+	if c.customConfig != nil {
+		return "", fmt.Errorf("export of custom OpenVPN configuration is not supported")
+	}
+	if c.hostIP == nil || c.hostIP.IsUnspecified() {
+		return "", errors.New("host IP not defined")
+	}
+	if c.hostPort <= 0 || c.hostPort > 65535 {
+		return "", errors.New("invalid port")
+	}
+
+	ca, err := os.ReadFile(caKeyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	ta, err := os.ReadFile(taKeyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read TLS auth key: %w", err)
+	}
+
+	proto := "udp"
+	if c.tcp {
+		proto = "tcp"
+	}
+
+	cfg := []string{
+		"client",
+		"dev tun",
+		"proto " + proto,
+		fmt.Sprintf("remote %s %d", c.hostIP, c.hostPort),
+		"resolv-retry infinite",
+		"nobind",
+		"persist-key",
+		"persist-tun",
+		"auth-user-pass",
+		"cipher AES-256-CBC",
+		"remote-cert-tls server",
+		"key-direction 1",
+		"verb 3",
+		"<ca>", strings.TrimSpace(string(ca)), "</ca>",
+		"<tls-auth>", strings.TrimSpace(string(ta)), "</tls-auth>",
+	}
+
+	if len(dnsServers) > 0 {
+		// ignore the DNS server pushed by the VPN host
+		cfg = append(cfg, `pull-filter ignore "dhcp-option DNS"`)
+		for _, ip := range dnsServers {
+			cfg = append(cfg, "dhcp-option DNS "+ip.String())
+		}
+	}
+
+	if includeCredentials {
+		if len(c.username) == 0 || len(c.password) == 0 {
+			return "", fmt.Errorf("OpenVPN user credentials not defined")
+		}
+		if strings.ContainsAny(c.username+c.password, "\r\n") {
+			return "", fmt.Errorf("bad OpenVPN user credentials")
+		}
+		cfg = append(cfg, "<auth-user-pass>", c.username, c.password, "</auth-user-pass>")
+	}
+
+	return strings.Join(cfg, "\n") + "\n", nil
+}
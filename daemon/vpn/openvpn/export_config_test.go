@@ -0,0 +1,102 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package openvpn_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/vpn/openvpn"
+)
+
+func TestExportConfig(t *testing.T) {
+	dir := t.TempDir()
+	caFile, taFile := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ta.key")
+	if err := os.WriteFile(caFile, []byte("-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(taFile, []byte("-----BEGIN OpenVPN Static key V1-----\nabcdef\n-----END OpenVPN Static key V1-----\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Multi-Hop: entry server IP with the port mapped to the exit server
+	cp := openvpn.CreateConnectionParams("ch1.gw.ivpn.net", true, 20301, net.ParseIP("192.0.2.10"), "", nil, 0, "", "")
+	cp.SetCredentials("ivpnXXXXXXXX", "secret")
+
+	text, err := cp.ExportConfig(caFile, taFile, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(text, "secret") {
+		t.Error("credentials must not be exported")
+	}
+
+	// exported profile must be accepted by the '.ovpn' profile parser
+	cfg, err := openvpn.ParseCustomConfig(text)
+	if err != nil {
+		t.Fatalf("exported configuration can not be parsed: %v\n%s", err, text)
+	}
+	if len(cfg.Remotes) != 1 || cfg.Remotes[0] != (openvpn.CustomConfigRemote{Host: "192.0.2.10", Port: 20301, IsTCP: true}) {
+		t.Errorf("unexpected remotes: %+v", cfg.Remotes)
+	}
+	if !cfg.IsAuthUserPass || len(cfg.Username) > 0 {
+		t.Errorf("unexpected authentication: %v '%s'", cfg.IsAuthUserPass, cfg.Username)
+	}
+	lines := strings.Join(cfg.Lines, "\n")
+	if !strings.Contains(lines, "<ca>\n-----BEGIN CERTIFICATE-----\nMIIB") || !strings.Contains(lines, "<tls-auth>\n-----BEGIN OpenVPN Static key V1-----") {
+		t.Errorf("CA certificate or TLS auth key not inlined:\n%s", lines)
+	}
+
+	// inline credentials
+	if text, err = cp.ExportConfig(caFile, taFile, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = openvpn.ParseCustomConfig(text); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Username != "ivpnXXXXXXXX" || cfg.Password != "secret" {
+		t.Errorf("unexpected credentials: '%s' '%s'", cfg.Username, cfg.Password)
+	}
+
+	if strings.Contains(text, "dhcp-option") {
+		t.Error("DNS of the VPN server must be used by default")
+	}
+
+	// custom DNS
+	if text, err = cp.ExportConfig(caFile, taFile, false, []net.IP{net.ParseIP("10.0.254.2")}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = openvpn.ParseCustomConfig(text); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Join(cfg.Lines, "\n"); !strings.Contains(lines, "pull-filter ignore \"dhcp-option DNS\"\ndhcp-option DNS 10.0.254.2") {
+		t.Errorf("custom DNS not exported:\n%s", lines)
+	}
+
+	if _, err = cp.ExportConfig(filepath.Join(dir, "not-exists"), taFile, false, nil); err == nil {
+		t.Error("expected error for missing CA certificate")
+	}
+}
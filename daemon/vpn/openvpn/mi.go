@@ -60,6 +60,12 @@ type ManagementInterface struct {
 
 	pushReplyCmds []string
 	pushReplyDNS  net.IP
+
+	// traffic counters (received by 'bytecount' real-time notifications)
+	statsMutex     sync.Mutex
+	bytesIn        uint64
+	bytesOut       uint64
+	remoteEndpoint string // "ip:port" of the remote server (known after 'CONNECTED' state)
 }
 
 // StartManagementInterface - starts TCP interface to communicate with IVPN application (server to listen incoming connections)
@@ -151,6 +157,13 @@ func (i *ManagementInterface) GetRouteAddCommands() []string {
 	return ret
 }
 
+// GetByteCount returns the traffic counters of the current connection and the remote server address
+func (i *ManagementInterface) GetByteCount() (bytesIn, bytesOut uint64, remoteEndpoint string) {
+	i.statsMutex.Lock()
+	defer i.statsMutex.Unlock()
+	return i.bytesIn, i.bytesOut, i.remoteEndpoint
+}
+
 func (i *ManagementInterface) HasRouteAddCommands() bool {
 	i.routeAddCmdsMutex.Lock()
 	defer i.routeAddCmdsMutex.Unlock()
@@ -283,7 +296,10 @@ func (i *ManagementInterface) miCommunication() {
 			continue
 		}
 
-		i.log.Info("[<-]: ", message)
+		// 'BYTECOUNT' notifications are received every second: do not flood the log
+		if !strings.HasPrefix(message, ">BYTECOUNT:") {
+			i.log.Info("[<-]: ", message)
+		}
 
 		columns := mesRegexp.FindStringSubmatch(message)
 		if len(columns) <= 2 {
@@ -322,6 +338,17 @@ func (i *ManagementInterface) miCommunication() {
 				}
 			}
 
+		case "BYTECOUNT":
+			// >BYTECOUNT:{BYTES_IN},{BYTES_OUT}
+			bytesIn, bytesOut, err := parseByteCount(msgText)
+			if err != nil {
+				i.log.Debug(err)
+				continue
+			}
+			i.statsMutex.Lock()
+			i.bytesIn, i.bytesOut = bytesIn, bytesOut
+			i.statsMutex.Unlock()
+
 		case "INFO":
 
 		case "HOLD":
@@ -385,6 +412,18 @@ func (i *ManagementInterface) miCommunication() {
 					if len(params) > 4 {
 						serverIP = net.ParseIP(strings.TrimSpace(params[4]))
 					}
+					if serverIP != nil {
+						endpoint := serverIP.String()
+						// (f) remote port (OpenVPN 2.4 or higher)
+						if len(params) > 5 && len(strings.TrimSpace(params[5])) > 0 {
+							endpoint = net.JoinHostPort(endpoint, strings.TrimSpace(params[5]))
+						}
+						i.statsMutex.Lock()
+						i.remoteEndpoint = endpoint
+						i.statsMutex.Unlock()
+					}
+					// enable real-time traffic counters notifications (every second)
+					i.sendResponse("bytecount 1")
 
 				} else if state == vpn.EXITING {
 					//>STATE:1563526742,EXITING,auth-failure,,,,,
@@ -505,3 +544,18 @@ func (i *ManagementInterface) eraseRouteAddCommands() {
 	}
 	i.routeAddCmds = nil
 }
+
+// parseByteCount parses the 'BYTECOUNT' real-time notification text: "{BYTES_IN},{BYTES_OUT}"
+func parseByteCount(msgText string) (bytesIn, bytesOut uint64, err error) {
+	cols := strings.Split(strings.TrimSpace(msgText), ",")
+	if len(cols) != 2 {
+		return 0, 0, fmt.Errorf("unexpected BYTECOUNT format: '%s'", msgText)
+	}
+	if bytesIn, err = strconv.ParseUint(cols[0], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("unexpected BYTECOUNT format: %w", err)
+	}
+	if bytesOut, err = strconv.ParseUint(cols[1], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("unexpected BYTECOUNT format: %w", err)
+	}
+	return bytesIn, bytesOut, nil
+}
@@ -0,0 +1,58 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package openvpn
+
+import "testing"
+
+func TestParseByteCount(t *testing.T) {
+	tests := []struct {
+		text          string
+		in, out       uint64
+		isErrExpected bool
+	}{
+		{text: "0,0"},
+		{text: "123456,7890\r", in: 123456, out: 7890},
+		{text: "18446744073709551615,1", in: 18446744073709551615, out: 1},
+		{text: "123", isErrExpected: true},
+		{text: "1,2,3", isErrExpected: true},
+		{text: "-1,2", isErrExpected: true},
+		{text: "a,b", isErrExpected: true},
+	}
+
+	for _, tt := range tests {
+		in, out, err := parseByteCount(tt.text)
+		if tt.isErrExpected {
+			if err == nil {
+				t.Errorf("%q: error expected", tt.text)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.text, err)
+			continue
+		}
+		if in != tt.in || out != tt.out {
+			t.Errorf("%q: got (%d, %d), expected (%d, %d)", tt.text, in, out, tt.in, tt.out)
+		}
+	}
+}
@@ -145,7 +145,8 @@ func NewOpenVpnObject(
 	extraParameters string,
 	connectionParams ConnectionParams) (*OpenVPN, error) {
 
-	if len(connectionParams.username) == 0 || len(connectionParams.password) == 0 {
+	isCredentialsRequired := connectionParams.customConfig == nil || connectionParams.customConfig.IsAuthUserPass
+	if isCredentialsRequired && (len(connectionParams.username) == 0 || len(connectionParams.password) == 0) {
 		return nil, fmt.Errorf("OpenVPN user credentials not defined")
 	}
 
@@ -169,6 +170,37 @@ func (o *OpenVPN) DestinationIP() net.IP {
 	return o.connectParams.hostIP
 }
 
+// DestinationIPs - Get IP addresses of all remote hosts
+// (the custom OpenVPN configuration can contain more than one 'remote')
+func (o *OpenVPN) DestinationIPs() []net.IP {
+	if o.connectParams.customConfig == nil {
+		return []net.IP{o.DestinationIP()}
+	}
+	ret := make([]net.IP, 0, len(o.connectParams.customConfig.Remotes))
+	for _, r := range o.connectParams.customConfig.Remotes {
+		if ip := net.ParseIP(r.Host); ip != nil {
+			ret = append(ret, ip)
+		}
+	}
+	return ret
+}
+
+// Statistics returns current traffic counters of the tunnel
+// (OpenVPN has the only one peer; the counters are updated every second)
+func (o *OpenVPN) Statistics() (vpn.TunnelStatistics, error) {
+	mi := o.managementInterface
+	if mi == nil || !mi.isConnected {
+		return vpn.TunnelStatistics{}, fmt.Errorf("OpenVPN management interface is not connected")
+	}
+	bytesIn, bytesOut, endpoint := mi.GetByteCount()
+	return vpn.TunnelStatistics{Peers: []vpn.PeerStatistics{{Endpoint: endpoint, RxBytes: bytesIn, TxBytes: bytesOut}}}, nil
+}
+
+// IsCustomConfig returns true when connected using the custom OpenVPN configuration
+func (o *OpenVPN) IsCustomConfig() bool {
+	return o.connectParams.customConfig != nil
+}
+
 // Type just returns VPN type
 func (o *OpenVPN) Type() vpn.Type { return vpn.OpenVPN }
 
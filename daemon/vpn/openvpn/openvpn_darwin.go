@@ -34,12 +34,22 @@ func (o *OpenVPN) implInit() error             { return nil }
 func (o *OpenVPN) implIsCanUseParamsV24() bool { return true }
 
 func (o *OpenVPN) implOnConnected() error {
-	// not in use in macOS implementation
+	// The DNS is normally configured by the 'up' script.
+	// The custom profiles are started without scripts, so the DNS pushed by the server is applied here
+	// (if the manual DNS is not defined)
+	if o.IsCustomConfig() && dns.GetLastManualDNS().IsEmpty() {
+		if defDns := o.DefaultDNS(); defDns != nil {
+			return dns.SetDefault(dns.DnsSettingsCreate(defDns), nil)
+		}
+	}
 	return nil
 }
 
 func (o *OpenVPN) implOnDisconnected() error {
-	// not in use in macOS implementation
+	// The DNS is normally restored by the 'down' script (the custom profiles are started without scripts)
+	if o.IsCustomConfig() {
+		return dns.DeleteManual(nil, nil)
+	}
 	return nil
 }
 
@@ -85,7 +85,10 @@ func (o *OpenVPN) implOnConnected() error {
 }
 
 func (o *OpenVPN) implOnDisconnected() error {
-	// TODO: not implemented
+	// The DNS is normally restored by the 'down' script (the custom profiles are started without scripts)
+	if o.IsCustomConfig() {
+		return dns.DeleteManual(nil, o.clientIP)
+	}
 	return nil
 }
 
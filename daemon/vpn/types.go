@@ -27,6 +27,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
 
 	"github.com/ivpn/desktop-app/daemon/obfsproxy"
 	"github.com/ivpn/desktop-app/daemon/service/dns"
@@ -221,6 +222,33 @@ type Process interface {
 	DefaultRouteGatewayIP() net.IP
 }
 
+// MultiDestinationProcess - optional interface of the VPN object which has more than one destination
+// (e.g. custom WireGuard configuration with multiple peers)
+type MultiDestinationProcess interface {
+	// DestinationIPs - Get IP addresses of all destinations (including DestinationIP())
+	DestinationIPs() []net.IP
+}
+
+// PeerStatistics - traffic statistics of a single peer (VPN server) of the active tunnel
+type PeerStatistics struct {
+	Endpoint      string    // remote address of the peer ("ip:port"); empty when unknown
+	PublicKey     string    // peer public key (WireGuard only)
+	RxBytes       uint64    // bytes received from the peer
+	TxBytes       uint64    // bytes sent to the peer
+	LastHandshake time.Time // time of the latest handshake (WireGuard only; zero - no handshake yet)
+}
+
+// TunnelStatistics - traffic statistics of the active tunnel
+type TunnelStatistics struct {
+	Peers []PeerStatistics
+}
+
+// StatisticsProcess - optional interface of the VPN object which is able to report traffic statistics
+type StatisticsProcess interface {
+	// Statistics - Get current traffic counters of the tunnel
+	Statistics() (TunnelStatistics, error)
+}
+
 // ReconnectionRequiredError object can be returned by vpn.Process.Connect() function
 // which means that it requesting to do re-connect immediately
 type ReconnectionRequiredError struct {
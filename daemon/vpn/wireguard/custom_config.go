@@ -0,0 +1,292 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package wireguard
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// CustomConfig - WireGuard configuration imported from a 'wg-quick' style configuration file
+// (e.g. configuration of a self-hosted WireGuard server)
+type CustomConfig struct {
+	Interface CustomConfigInterface
+	Peers     []CustomConfigPeer
+}
+
+// CustomConfigInterface - the '[Interface]' section of the custom WireGuard configuration
+type CustomConfigInterface struct {
+	PrivateKey string
+	Addresses  []string // local addresses in CIDR notation (e.g. "10.0.0.2/32")
+	DNS        []net.IP
+	MTU        int // 0 - use default MTU value
+	ListenPort int // 0 - use random free port
+}
+
+// CustomConfigPeer - the '[Peer]' section of the custom WireGuard configuration
+type CustomConfigPeer struct {
+	PublicKey           string
+	PresharedKey        string
+	Endpoint            string   // "host:port"
+	AllowedIPs          []string // CIDR notation (e.g. "0.0.0.0/0")
+	PersistentKeepalive int      // 0 - disabled
+}
+
+// ParseCustomConfig parses and validates the 'wg-quick' style configuration.
+// Options which are not compatible with the daemon (e.g. 'PostUp' scripts or custom routing tables) are rejected.
+func ParseCustomConfig(text string) (CustomConfig, error) {
+	var (
+		cfg          CustomConfig
+		section      string
+		hasInterface bool
+		peer         *CustomConfigPeer
+	)
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			switch section {
+			case "interface":
+				if hasInterface {
+					return CustomConfig{}, fmt.Errorf("line %d: duplicate [Interface] section", lineNo)
+				}
+				hasInterface = true
+			case "peer":
+				cfg.Peers = append(cfg.Peers, CustomConfigPeer{})
+				peer = &cfg.Peers[len(cfg.Peers)-1]
+			default:
+				return CustomConfig{}, fmt.Errorf("line %d: unknown section '%s'", lineNo, line)
+			}
+			continue
+		}
+
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return CustomConfig{}, fmt.Errorf("line %d: 'key = value' expected", lineNo)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+
+		var err error
+		switch section {
+		case "interface":
+			err = cfg.Interface.set(key, val)
+		case "peer":
+			err = peer.set(key, val)
+		default:
+			err = fmt.Errorf("option is outside of a section")
+		}
+		if err != nil {
+			return CustomConfig{}, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return CustomConfig{}, err
+	}
+
+	if err := cfg.validate(hasInterface); err != nil {
+		return CustomConfig{}, err
+	}
+	return cfg, nil
+}
+
+func (c CustomConfig) validate(hasInterface bool) error {
+	if !hasInterface {
+		return fmt.Errorf("[Interface] section not defined")
+	}
+	if len(c.Interface.PrivateKey) == 0 {
+		return fmt.Errorf("[Interface] 'PrivateKey' not defined")
+	}
+	if len(c.Interface.Addresses) == 0 {
+		return fmt.Errorf("[Interface] 'Address' not defined")
+	}
+	if len(c.Peers) == 0 {
+		return fmt.Errorf("[Peer] section not defined")
+	}
+
+	publicKeys := make(map[string]struct{}, len(c.Peers))
+	for i, p := range c.Peers {
+		if len(p.PublicKey) == 0 {
+			return fmt.Errorf("[Peer] #%d: 'PublicKey' not defined", i+1)
+		}
+		if len(p.Endpoint) == 0 {
+			return fmt.Errorf("[Peer] #%d: 'Endpoint' not defined", i+1)
+		}
+		if len(p.AllowedIPs) == 0 {
+			return fmt.Errorf("[Peer] #%d: 'AllowedIPs' not defined", i+1)
+		}
+		if _, ok := publicKeys[p.PublicKey]; ok {
+			return fmt.Errorf("[Peer] #%d: duplicate 'PublicKey'", i+1)
+		}
+		publicKeys[p.PublicKey] = struct{}{}
+	}
+	return nil
+}
+
+func (i *CustomConfigInterface) set(key, val string) (err error) {
+	switch key {
+	case "privatekey":
+		i.PrivateKey, err = parseKey(key, val)
+	case "address":
+		var addrs []string
+		if addrs, err = parseAddressList(key, val); err == nil {
+			i.Addresses = append(i.Addresses, addrs...)
+		}
+	case "dns":
+		for _, v := range splitList(val) {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return fmt.Errorf("'DNS': '%s' is not an IP address (DNS search domains are not supported)", v)
+			}
+			i.DNS = append(i.DNS, ip)
+		}
+	case "mtu":
+		// According to Windows specification: "... For IPv4 the minimum value is 576 bytes. For IPv6 the minimum is value is 1280 bytes... "
+		// Using the same limitations for all platforms
+		i.MTU, err = parseInt(key, val, 1280, 65535)
+	case "listenport":
+		i.ListenPort, err = parseInt(key, val, 1, 65535)
+	case "preup", "postup", "predown", "postdown":
+		return fmt.Errorf("'%s': executing custom commands is not allowed", key)
+	case "table", "fwmark", "saveconfig":
+		return fmt.Errorf("'%s': option is not supported (routing is managed by the daemon)", key)
+	default:
+		return fmt.Errorf("unknown [Interface] option '%s'", key)
+	}
+	return err
+}
+
+func (p *CustomConfigPeer) set(key, val string) (err error) {
+	switch key {
+	case "publickey":
+		p.PublicKey, err = parseKey(key, val)
+	case "presharedkey":
+		p.PresharedKey, err = parseKey(key, val)
+	case "endpoint":
+		host, port, e := net.SplitHostPort(val)
+		if e != nil {
+			return fmt.Errorf("'Endpoint': %w", e)
+		}
+		if len(host) == 0 || strings.ContainsAny(host, " \t") {
+			return fmt.Errorf("'Endpoint': bad host '%s'", host)
+		}
+		if _, err = parseInt(key, port, 1, 65535); err != nil {
+			return err
+		}
+		p.Endpoint = net.JoinHostPort(host, port)
+	case "allowedips":
+		var addrs []string
+		if addrs, err = parseAddressList(key, val); err == nil {
+			p.AllowedIPs = append(p.AllowedIPs, addrs...)
+		}
+	case "persistentkeepalive":
+		if strings.ToLower(val) == "off" {
+			p.PersistentKeepalive = 0
+			return nil
+		}
+		p.PersistentKeepalive, err = parseInt(key, val, 0, 65535)
+	default:
+		return fmt.Errorf("unknown [Peer] option '%s'", key)
+	}
+	return err
+}
+
+// EndpointHostPort returns the host (hostname or IP address) and port of the peer endpoint
+func (p CustomConfigPeer) EndpointHostPort() (host string, port int) {
+	h, portStr, err := net.SplitHostPort(p.Endpoint)
+	if err != nil {
+		return "", 0
+	}
+	port, _ = strconv.Atoi(portStr)
+	return h, port
+}
+
+// prevent user-defined data injection: ensure that nothing except the base64 key (32 bytes) will be stored in the configuration
+func parseKey(key, val string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(val)
+	if err != nil || len(b) != 32 {
+		return "", fmt.Errorf("'%s': bad key value", key)
+	}
+	return val, nil
+}
+
+func parseInt(key, val string, min, max int) (int, error) {
+	v, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("'%s': bad numeric value '%s'", key, val)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("'%s': value is out of range [%d - %d]", key, min, max)
+	}
+	return v, nil
+}
+
+// parseAddressList parses comma separated list of IP addresses or networks (e.g. "10.0.0.2/32, fd00::2")
+// and returns them in CIDR notation
+func parseAddressList(key, val string) ([]string, error) {
+	var ret []string
+	for _, v := range splitList(val) {
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("'%s': bad address '%s'", key, v)
+			}
+			if ip.To4() != nil {
+				v += "/32"
+			} else {
+				v += "/128"
+			}
+		}
+		ip, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("'%s': bad address '%s'", key, v)
+		}
+		ones, _ := n.Mask.Size()
+		ret = append(ret, ip.String()+"/"+strconv.Itoa(ones))
+	}
+	return ret, nil
+}
+
+func splitList(val string) []string {
+	var ret []string
+	for _, v := range strings.Split(val, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
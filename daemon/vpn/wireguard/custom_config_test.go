@@ -0,0 +1,117 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package wireguard_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/vpn/wireguard"
+)
+
+const (
+	testKey1 = "yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk="
+	testKey2 = "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="
+	testKey3 = "TrMvSoP4jYQlY6RIzBgbssQqY3vxI2Pi+y71lOWWXX0="
+)
+
+func TestParseCustomConfig(t *testing.T) {
+	cfgText := `
+# lab network
+[Interface]
+PrivateKey = ` + testKey1 + `
+Address = 10.0.0.2/24, fd00::2
+Address = 10.0.1.2
+DNS = 10.0.0.1, fd00::1
+MTU = 1380
+
+[Peer]
+PublicKey = ` + testKey2 + `
+PresharedKey = ` + testKey3 + `
+Endpoint = vpn.example.com:51820
+AllowedIPs = 0.0.0.0/0, ::/0
+PersistentKeepalive = 25
+
+[peer]
+publickey = ` + testKey3 + `
+endpoint = [2001:db8::1]:51821
+allowedips = 192.168.10.0/24 # second network
+`
+	cfg, err := wireguard.ParseCustomConfig(cfgText)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Interface.PrivateKey != testKey1 || cfg.Interface.MTU != 1380 {
+		t.Errorf("unexpected interface configuration: %+v", cfg.Interface)
+	}
+	if strings.Join(cfg.Interface.Addresses, ",") != "10.0.0.2/24,fd00::2/128,10.0.1.2/32" {
+		t.Errorf("unexpected addresses: %v", cfg.Interface.Addresses)
+	}
+	if len(cfg.Interface.DNS) != 2 || cfg.Interface.DNS[0].String() != "10.0.0.1" {
+		t.Errorf("unexpected DNS: %v", cfg.Interface.DNS)
+	}
+	if len(cfg.Peers) != 2 {
+		t.Fatalf("expected 2 peers, got %d", len(cfg.Peers))
+	}
+	if p := cfg.Peers[0]; p.PublicKey != testKey2 || p.PresharedKey != testKey3 || p.PersistentKeepalive != 25 || strings.Join(p.AllowedIPs, ",") != "0.0.0.0/0,::/0" {
+		t.Errorf("unexpected peer #1: %+v", p)
+	}
+	if host, port := cfg.Peers[1].EndpointHostPort(); host != "2001:db8::1" || port != 51821 {
+		t.Errorf("unexpected peer #2 endpoint: %s %d", host, port)
+	}
+}
+
+func TestParseCustomConfigErrors(t *testing.T) {
+	iface := "[Interface]\nPrivateKey = " + testKey1 + "\nAddress = 10.0.0.2/32\n"
+	peer := "[Peer]\nPublicKey = " + testKey2 + "\nEndpoint = 192.0.2.1:51820\nAllowedIPs = 0.0.0.0/0\n"
+
+	if _, err := wireguard.ParseCustomConfig(iface + peer); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]string{
+		"no interface":       peer,
+		"no peers":           iface,
+		"duplicate peers":    iface + peer + peer,
+		"PostUp script":      iface + "PostUp = rm -rf /\n" + peer,
+		"routing table":      iface + "Table = off\n" + peer,
+		"unknown option":     iface + "Unknown = 1\n" + peer,
+		"bad key":            strings.Replace(iface, testKey1, "bad\nkey", 1) + peer,
+		"short key":          strings.Replace(iface, testKey1, "AAAA", 1) + peer,
+		"bad address":        iface + "Address = 10.0.0.300\n" + peer,
+		"DNS search domain":  iface + "DNS = example.com\n" + peer,
+		"bad MTU":            iface + "MTU = 100\n" + peer,
+		"no endpoint":        iface + strings.Replace(peer, "Endpoint = 192.0.2.1:51820\n", "", 1),
+		"bad endpoint port":  iface + strings.Replace(peer, ":51820", ":0", 1),
+		"no allowed IPs":     iface + strings.Replace(peer, "AllowedIPs = 0.0.0.0/0\n", "", 1),
+		"no section":         "PrivateKey = " + testKey1 + "\n" + iface + peer,
+		"unknown section":    "[Something]\n" + iface + peer,
+		"not key-value line": iface + "Address\n" + peer,
+	}
+	for name, cfgText := range tests {
+		if _, err := wireguard.ParseCustomConfig(cfgText); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
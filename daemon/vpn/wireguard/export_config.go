@@ -0,0 +1,105 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package wireguard
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/ivpn/desktop-app/daemon/helpers"
+)
+
+// ExportConfig returns the standalone WireGuard configuration ('wg-quick' format) based on the connection parameters.
+// Credentials must be initialized (see SetCredentials()).
+// 'dnsServers' - DNS servers to be used in tunnel (if empty - the DNS server of the VPN host is in use)
+func (cp *ConnectionParams) ExportConfig(dnsServers []net.IP) (string, error) {
+	if cp.customConfig != nil {
+		return "", fmt.Errorf("export of custom WireGuard configuration is not supported")
+	}
+	if len(cp.clientPrivateKey) == 0 || cp.clientLocalIP == nil {
+		return "", fmt.Errorf("WireGuard credentials not defined")
+	}
+	// prevent user-defined data injection: ensure that nothing except the base64 keys will be stored in the configuration
+	if !helpers.ValidateBase64(cp.hostPublicKey) {
+		return "", fmt.Errorf("WG public key is not base64 string")
+	}
+	if !helpers.ValidateBase64(cp.clientPrivateKey) {
+		return "", fmt.Errorf("WG private key is not base64 string")
+	}
+	if len(cp.presharedKey) > 0 && !helpers.ValidateBase64(cp.presharedKey) {
+		return "", fmt.Errorf("WG PresharedKey is not base64 string")
+	}
+	if cp.hostIP == nil || cp.hostIP.IsUnspecified() {
+		return "", fmt.Errorf("host IP not defined")
+	}
+	if cp.hostPort <= 0 || cp.hostPort > 65535 {
+		return "", fmt.Errorf("invalid port")
+	}
+
+	addresses := []string{cp.clientLocalIP.String() + "/32"}
+	allowedIPs := []string{"0.0.0.0/0"}
+	if ipv6 := cp.GetIPv6ClientLocalIP(); ipv6 != nil {
+		addresses = append(addresses, ipv6.String()+"/128")
+		allowedIPs = append(allowedIPs, "::/0")
+	}
+
+	if len(dnsServers) == 0 {
+		if cp.hostLocalIP != nil {
+			dnsServers = append(dnsServers, cp.hostLocalIP)
+		}
+		if ipv6 := cp.GetIPv6HostLocalIP(); ipv6 != nil {
+			dnsServers = append(dnsServers, ipv6)
+		}
+	}
+	dns := make([]string, 0, len(dnsServers))
+	for _, ip := range dnsServers {
+		dns = append(dns, ip.String())
+	}
+
+	cfg := []string{
+		"[Interface]",
+		"PrivateKey = " + cp.clientPrivateKey,
+		"Address = " + strings.Join(addresses, ", ")}
+	if len(dns) > 0 {
+		cfg = append(cfg, "DNS = "+strings.Join(dns, ", "))
+	}
+	if cp.mtu > 0 {
+		cfg = append(cfg, "MTU = "+strconv.Itoa(cp.mtu))
+	}
+
+	cfg = append(cfg,
+		"",
+		"[Peer]",
+		"PublicKey = "+cp.hostPublicKey)
+	if len(cp.presharedKey) > 0 {
+		cfg = append(cfg, "PresharedKey = "+cp.presharedKey)
+	}
+	cfg = append(cfg,
+		"Endpoint = "+net.JoinHostPort(cp.hostIP.String(), strconv.Itoa(cp.hostPort)),
+		"AllowedIPs = "+strings.Join(allowedIPs, ", "),
+		"PersistentKeepalive = 25")
+
+	return strings.Join(cfg, "\n") + "\n", nil
+}
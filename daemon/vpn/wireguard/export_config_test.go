@@ -0,0 +1,80 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package wireguard_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/vpn/wireguard"
+)
+
+func TestExportConfig(t *testing.T) {
+	// Multi-Hop: entry server IP with the port mapped to the exit server
+	cp := wireguard.CreateConnectionParams("ch1.wg.ivpn.net", 30201, net.ParseIP("192.0.2.10"), testKey2, net.ParseIP("172.16.0.1"), "fd00:4956:504e:ffff::", 1380)
+	cp.SetCredentials(testKey1, testKey3, net.ParseIP("172.28.1.2"))
+
+	text, err := cp.ExportConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// exported configuration must be accepted by the 'wg-quick' format parser
+	cfg, err := wireguard.ParseCustomConfig(text)
+	if err != nil {
+		t.Fatalf("exported configuration can not be parsed: %v\n%s", err, text)
+	}
+	if cfg.Interface.PrivateKey != testKey1 || cfg.Interface.MTU != 1380 {
+		t.Errorf("unexpected interface configuration: %+v", cfg.Interface)
+	}
+	if strings.Join(cfg.Interface.Addresses, ",") != "172.28.1.2/32,fd00:4956:504e:ffff::ac1c:102/128" {
+		t.Errorf("unexpected addresses: %v", cfg.Interface.Addresses)
+	}
+	if len(cfg.Interface.DNS) != 2 || cfg.Interface.DNS[0].String() != "172.16.0.1" {
+		t.Errorf("unexpected DNS: %v", cfg.Interface.DNS)
+	}
+	if len(cfg.Peers) != 1 {
+		t.Fatalf("expected 1 peer, got %d", len(cfg.Peers))
+	}
+	p := cfg.Peers[0]
+	if p.PublicKey != testKey2 || p.PresharedKey != testKey3 || p.Endpoint != "192.0.2.10:30201" || strings.Join(p.AllowedIPs, ",") != "0.0.0.0/0,::/0" {
+		t.Errorf("unexpected peer: %+v", p)
+	}
+
+	// manual DNS
+	cp = wireguard.CreateConnectionParams("", 2049, net.ParseIP("192.0.2.10"), testKey2, net.ParseIP("172.16.0.1"), "", 0)
+	cp.SetCredentials(testKey1, "", net.ParseIP("172.28.1.2"))
+	if text, err = cp.ExportConfig([]net.IP{net.ParseIP("1.1.1.1")}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "DNS = 1.1.1.1\n") || strings.Contains(text, "PresharedKey") || strings.Contains(text, "::/0") {
+		t.Errorf("unexpected configuration:\n%s", text)
+	}
+
+	// credentials not defined
+	cp = wireguard.CreateConnectionParams("", 2049, net.ParseIP("192.0.2.10"), testKey2, net.ParseIP("172.16.0.1"), "", 0)
+	if _, err = cp.ExportConfig(nil); err == nil {
+		t.Error("expected error for undefined credentials")
+	}
+}
@@ -0,0 +1,369 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package wireguard
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ivpn/desktop-app/daemon/helpers"
+	"github.com/ivpn/desktop-app/daemon/netinfo"
+	"github.com/ivpn/desktop-app/daemon/shell"
+	"golang.zx2c4.com/wireguard/conn"
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/ipc"
+	"golang.zx2c4.com/wireguard/tun"
+)
+
+const (
+	// The same values are in use by 'wg-quick' (the Split Tunnel routing relies on them)
+	userspaceFwMark       = 51820
+	userspaceRoutingTable = 51820
+	// Default MTU of the userspace implementation (the same as in 'wireguard-go')
+	userspaceDefaultMTU = 1420
+)
+
+// UserspaceAvailabilityError returns error when the embedded userspace WireGuard implementation can not be used
+// (e.g. TUN device is not available in the container)
+func UserspaceAvailabilityError() error {
+	f, err := os.OpenFile("/dev/net/tun", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("TUN device is not available: %w", err)
+	}
+	f.Close()
+	return nil
+}
+
+// kernelAvailabilityError returns error when the kernel WireGuard implementation ('wg-quick' + kernel module) can not be used
+func kernelAvailabilityError(binaryPath, toolBinaryPath string) error {
+	for _, bin := range []string{binaryPath, toolBinaryPath} {
+		if _, err := os.Stat(bin); err != nil {
+			return fmt.Errorf("WireGuard tools not found: %w", err)
+		}
+	}
+	if _, err := os.Stat("/sys/module/wireguard"); err == nil {
+		return nil // the module is loaded
+	}
+	// the module can be loaded on demand: try to create temporary WireGuard interface
+	const testInterfaceName = "wgivpntest"
+	if err := shell.Exec(nil, "ip", "link", "add", "dev", testInterfaceName, "type", "wireguard"); err != nil {
+		return fmt.Errorf("WireGuard kernel module is not available")
+	}
+	shell.Exec(nil, "ip", "link", "delete", "dev", testInterfaceName)
+	return nil
+}
+
+// userspaceTunnel - WireGuard tunnel based on the embedded userspace implementation (wireguard-go)
+// The network configuration (addresses, routes, routing rules) is the same as 'wg-quick' does for the kernel implementation
+type userspaceTunnel struct {
+	name   string
+	device *device.Device
+	uapi   net.Listener
+	// routing rules for default routes were added (IPv4, IPv6)
+	isDefaultRouteV4 bool
+	isDefaultRouteV6 bool
+}
+
+// userspaceConfig returns configuration for the userspace tunnel
+func (wg *WireGuard) userspaceConfig() (CustomConfig, error) {
+	if wg.connectParams.customConfig != nil {
+		cfg := *wg.connectParams.customConfig
+		if cfg.Interface.ListenPort > 0 {
+			wg.localPort = cfg.Interface.ListenPort
+		}
+		cfg.Interface.ListenPort = wg.localPort
+		return cfg, nil
+	}
+
+	cp := wg.connectParams
+	// prevent user-defined data injection: ensure that nothing except the base64 keys will be stored in the configuration
+	if !helpers.ValidateBase64(cp.hostPublicKey) {
+		return CustomConfig{}, fmt.Errorf("WG public key is not base64 string")
+	}
+	if !helpers.ValidateBase64(cp.clientPrivateKey) {
+		return CustomConfig{}, fmt.Errorf("WG private key is not base64 string")
+	}
+	if len(cp.presharedKey) > 0 && !helpers.ValidateBase64(cp.presharedKey) {
+		return CustomConfig{}, fmt.Errorf("WG PresharedKey is not base64 string")
+	}
+
+	cfg := CustomConfig{
+		Interface: CustomConfigInterface{
+			PrivateKey: cp.clientPrivateKey,
+			Addresses:  []string{cp.clientLocalIP.String() + "/32"},
+			MTU:        cp.mtu,
+			ListenPort: wg.localPort,
+		},
+		Peers: []CustomConfigPeer{{
+			PublicKey:           cp.hostPublicKey,
+			PresharedKey:        cp.presharedKey,
+			Endpoint:            net.JoinHostPort(cp.hostIP.String(), strconv.Itoa(cp.hostPort)),
+			AllowedIPs:          []string{"0.0.0.0/0"},
+			PersistentKeepalive: 25,
+		}},
+	}
+	if ipv6 := cp.GetIPv6ClientLocalIP(); ipv6 != nil {
+		cfg.Interface.Addresses = append(cfg.Interface.Addresses, ipv6.String()+"/128")
+		cfg.Peers[0].AllowedIPs = append(cfg.Peers[0].AllowedIPs, "::/0")
+	}
+	return cfg, nil
+}
+
+// startUserspace starts the userspace tunnel
+func (wg *WireGuard) startUserspace() (*userspaceTunnel, error) {
+	localPort, err := netinfo.GetFreeUDPPort()
+	if err != nil {
+		return nil, fmt.Errorf("unable to obtain free local port: %w", err)
+	}
+	wg.localPort = localPort
+
+	cfg, err := wg.userspaceConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("Starting WireGuard (userspace implementation)...")
+	t, err := startUserspaceTunnel(wg.getTunnelName(), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start WireGuard (userspace implementation): %w", err)
+	}
+	return t, nil
+}
+
+func startUserspaceTunnel(name string, cfg CustomConfig) (_ *userspaceTunnel, retErr error) {
+	uapiConfig, err := userspaceUapiConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	mtu := cfg.Interface.MTU
+	if mtu <= 0 {
+		mtu = userspaceDefaultMTU
+	}
+
+	tunDev, err := tun.CreateTUN(name, mtu)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TUN device: %w", err)
+	}
+
+	dev := device.NewDevice(tunDev, conn.NewDefaultBind(), &device.Logger{
+		Verbosef: func(format string, args ...any) {},
+		Errorf: func(format string, args ...any) {
+			log.Error("(wireguard-go) " + fmt.Sprintf(format, args...))
+		},
+	})
+	t := &userspaceTunnel{name: name, device: dev}
+	defer func() {
+		if retErr != nil {
+			t.stop()
+		}
+	}()
+
+	if err := dev.IpcSet(uapiConfig); err != nil {
+		return nil, fmt.Errorf("failed to configure WireGuard device: %w", err)
+	}
+
+	// UAPI socket ('/var/run/wireguard/<name>.sock') allows to control the device in the same way as kernel implementation
+	// (the 'wgctrl' and 'wg' tools are working with it)
+	uapiFile, err := ipc.UAPIOpen(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open UAPI socket: %w", err)
+	}
+	if t.uapi, err = ipc.UAPIListen(name, uapiFile); err != nil {
+		uapiFile.Close()
+		return nil, fmt.Errorf("failed to listen UAPI socket: %w", err)
+	}
+	go func() {
+		for {
+			c, err := t.uapi.Accept()
+			if err != nil {
+				return // listener closed
+			}
+			go dev.IpcHandle(c)
+		}
+	}()
+
+	if err := dev.Up(); err != nil {
+		return nil, fmt.Errorf("failed to bring WireGuard device up: %w", err)
+	}
+
+	if err := t.configureNetwork(cfg, mtu); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// configureNetwork configures addresses, routes and routing rules for the tunnel interface (the same way as 'wg-quick' does)
+func (t *userspaceTunnel) configureNetwork(cfg CustomConfig, mtu int) error {
+	for _, addr := range cfg.Interface.Addresses {
+		family := "-4"
+		if strings.Contains(addr, ":") {
+			family = "-6"
+		}
+		if err := shell.Exec(log, "ip", family, "address", "add", addr, "dev", t.name); err != nil {
+			return fmt.Errorf("failed to set interface address: %w", err)
+		}
+	}
+	if err := shell.Exec(log, "ip", "link", "set", "mtu", strconv.Itoa(mtu), "up", "dev", t.name); err != nil {
+		return fmt.Errorf("failed to set interface up: %w", err)
+	}
+
+	for _, p := range cfg.Peers {
+		for _, allowedIP := range p.AllowedIPs {
+			_, ipNet, err := net.ParseCIDR(allowedIP)
+			if err != nil {
+				return fmt.Errorf("bad allowed IP '%s': %w", allowedIP, err)
+			}
+			isV6 := ipNet.IP.To4() == nil
+			if ones, _ := ipNet.Mask.Size(); ones == 0 {
+				if err := t.addDefaultRoute(isV6); err != nil {
+					return err
+				}
+				continue
+			}
+			family := "-4"
+			if isV6 {
+				family = "-6"
+			}
+			if err := shell.Exec(log, "ip", family, "route", "add", ipNet.String(), "dev", t.name); err != nil {
+				return fmt.Errorf("failed to add route '%s': %w", ipNet, err)
+			}
+		}
+	}
+	return nil
+}
+
+// addDefaultRoute routes all traffic through the tunnel using policy routing (the same rules as 'wg-quick' creates):
+// the encrypted tunnel packets are marked by 'fwmark' and use main routing table; all the rest traffic goes to the tunnel
+func (t *userspaceTunnel) addDefaultRoute(isV6 bool) error {
+	family, defaultRoute := "-4", "0.0.0.0/0"
+	if isV6 {
+		family, defaultRoute = "-6", "::/0"
+	}
+	if (isV6 && t.isDefaultRouteV6) || (!isV6 && t.isDefaultRouteV4) {
+		return nil
+	}
+
+	table, mark := strconv.Itoa(userspaceRoutingTable), strconv.Itoa(userspaceFwMark)
+	if err := shell.Exec(log, "ip", family, "route", "add", defaultRoute, "dev", t.name, "table", table); err != nil {
+		return fmt.Errorf("failed to add default route: %w", err)
+	}
+	if isV6 {
+		t.isDefaultRouteV6 = true
+	} else {
+		t.isDefaultRouteV4 = true
+	}
+	if err := shell.Exec(log, "ip", family, "rule", "add", "not", "fwmark", mark, "table", table); err != nil {
+		return fmt.Errorf("failed to add routing rule: %w", err)
+	}
+	if err := shell.Exec(log, "ip", family, "rule", "add", "table", "main", "suppress_prefixlength", "0"); err != nil {
+		return fmt.Errorf("failed to add routing rule: %w", err)
+	}
+	if !isV6 {
+		// required to pass reverse path filtering for the marked packets
+		if err := os.WriteFile("/proc/sys/net/ipv4/conf/all/src_valid_mark", []byte("1"), 0644); err != nil {
+			log.Warning(fmt.Sprintf("failed to enable 'src_valid_mark': %s", err))
+		}
+	}
+	return nil
+}
+
+// stop stops the tunnel and removes the routing rules
+// (the interface and its routes are removed by the system when the TUN device closed)
+func (t *userspaceTunnel) stop() error {
+	for _, family := range []struct {
+		name    string
+		enabled bool
+	}{{"-4", t.isDefaultRouteV4}, {"-6", t.isDefaultRouteV6}} {
+		if !family.enabled {
+			continue
+		}
+		shell.Exec(log, "ip", family.name, "rule", "delete", "table", strconv.Itoa(userspaceRoutingTable))
+		shell.Exec(log, "ip", family.name, "rule", "delete", "table", "main", "suppress_prefixlength", "0")
+	}
+	t.isDefaultRouteV4, t.isDefaultRouteV6 = false, false
+
+	if t.uapi != nil {
+		t.uapi.Close()
+		t.uapi = nil
+	}
+	if t.device != nil {
+		t.device.Close()
+		t.device = nil
+	}
+	return nil
+}
+
+// userspaceUapiConfig converts configuration to the UAPI format ('wireguard-go' configuration protocol)
+func userspaceUapiConfig(cfg CustomConfig) (string, error) {
+	privateKey, err := keyToHex(cfg.Interface.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("bad private key: %w", err)
+	}
+
+	lines := []string{
+		"private_key=" + privateKey,
+		"listen_port=" + strconv.Itoa(cfg.Interface.ListenPort),
+		"fwmark=" + strconv.Itoa(userspaceFwMark),
+		"replace_peers=true",
+	}
+	for _, p := range cfg.Peers {
+		publicKey, err := keyToHex(p.PublicKey)
+		if err != nil {
+			return "", fmt.Errorf("bad peer public key: %w", err)
+		}
+		lines = append(lines, "public_key="+publicKey)
+		if len(p.PresharedKey) > 0 {
+			presharedKey, err := keyToHex(p.PresharedKey)
+			if err != nil {
+				return "", fmt.Errorf("bad peer preshared key: %w", err)
+			}
+			lines = append(lines, "preshared_key="+presharedKey)
+		}
+		lines = append(lines, "endpoint="+p.Endpoint)
+		if p.PersistentKeepalive > 0 {
+			lines = append(lines, "persistent_keepalive_interval="+strconv.Itoa(p.PersistentKeepalive))
+		}
+		lines = append(lines, "replace_allowed_ips=true")
+		for _, allowedIP := range p.AllowedIPs {
+			lines = append(lines, "allowed_ip="+allowedIP)
+		}
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+func keyToHex(key string) (string, error) {
+	k, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", err
+	}
+	if len(k) != 32 {
+		return "", fmt.Errorf("unexpected key length")
+	}
+	return hex.EncodeToString(k), nil
+}
@@ -0,0 +1,71 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2023 IVPN Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package wireguard
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUserspaceUapiConfig(t *testing.T) {
+	cfg := CustomConfig{
+		Interface: CustomConfigInterface{
+			PrivateKey: "yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk=",
+			Addresses:  []string{"10.0.0.2/32"},
+			ListenPort: 40000,
+		},
+		Peers: []CustomConfigPeer{{
+			PublicKey:           "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=",
+			PresharedKey:        "TrMvSoP4jYQlY6RIzBgbssQqY3vxI2Pi+y71lOWWXX0=",
+			Endpoint:            "192.0.2.1:51820",
+			AllowedIPs:          []string{"0.0.0.0/0", "::/0"},
+			PersistentKeepalive: 25,
+		}},
+	}
+
+	uapi, err := userspaceUapiConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		"private_key=c809f3e5317e9575c9b5ed78b638b7ce530dabe85ddab614220241801ddf0669",
+		"listen_port=40000",
+		"fwmark=51820",
+		"replace_peers=true",
+		"public_key=c53201039adba14be71f886da1d8dbe9eebded08cb111b75340078999aa9f038",
+		"preshared_key=4eb32f4a83f88d842563a448cc181bb2c42a637bf12363e2fb2ef594e5965d7d",
+		"endpoint=192.0.2.1:51820",
+		"persistent_keepalive_interval=25",
+		"replace_allowed_ips=true",
+		"allowed_ip=0.0.0.0/0",
+		"allowed_ip=::/0",
+	}, "\n") + "\n"
+	if uapi != expected {
+		t.Errorf("unexpected UAPI configuration:\n%s\nexpected:\n%s", uapi, expected)
+	}
+
+	cfg.Peers[0].PublicKey = "bad key"
+	if _, err := userspaceUapiConfig(cfg); err == nil {
+		t.Error("expected error for bad public key")
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ivpn/desktop-app/daemon/vpn"
 	"golang.zx2c4.com/wireguard/wgctrl"
 )
 
@@ -139,3 +140,32 @@ func waitForWgInterfaceChan(tunnelName string, isWaitForDisconnect bool, isStopA
 
 	return retChan
 }
+
+// GetTunnelStatistics returns traffic counters for all peers of the WireGuard tunnel
+func GetTunnelStatistics(tunnelName string) (vpn.TunnelStatistics, error) {
+	client, err := wgctrl.New()
+	if err != nil {
+		return vpn.TunnelStatistics{}, fmt.Errorf("failed to get tunnel statistics: %w", err)
+	}
+	defer client.Close()
+
+	dev, err := client.Device(tunnelName)
+	if err != nil {
+		return vpn.TunnelStatistics{}, fmt.Errorf("failed to get tunnel statistics for '%s': %w", tunnelName, err)
+	}
+
+	ret := vpn.TunnelStatistics{Peers: make([]vpn.PeerStatistics, 0, len(dev.Peers))}
+	for _, peer := range dev.Peers {
+		ps := vpn.PeerStatistics{
+			PublicKey:     peer.PublicKey.String(),
+			RxBytes:       uint64(peer.ReceiveBytes),
+			TxBytes:       uint64(peer.TransmitBytes),
+			LastHandshake: peer.LastHandshakeTime,
+		}
+		if peer.Endpoint != nil {
+			ps.Endpoint = peer.Endpoint.String()
+		}
+		ret.Peers = append(ret.Peers, ps)
+	}
+	return ret, nil
+}
@@ -42,6 +42,38 @@ func init() {
 	log = logger.NewLogger("wg")
 }
 
+// Linux WireGuard implementations (see WireGuardExtraSettings.Linux_Backend)
+const (
+	LinuxBackendAuto      = ""          // kernel module (if available) or the embedded userspace implementation
+	LinuxBackendKernel    = "kernel"    // kernel module and 'wg-quick'
+	LinuxBackendUserspace = "userspace" // embedded userspace implementation (wireguard-go)
+)
+
+type FuncGetUserSettings func() WireGuardExtraSettings
+
+type WireGuardExtraSettings struct {
+	// WireGuard implementation on Linux (LinuxBackendAuto, LinuxBackendKernel or LinuxBackendUserspace)
+	Linux_Backend string
+}
+
+var funcGetUserSettings FuncGetUserSettings
+
+// Initialize is doing initialization stuff
+// Must be called on application start
+func Initialize(getUserSettingsFunc FuncGetUserSettings) {
+	funcGetUserSettings = getUserSettingsFunc
+	if funcGetUserSettings == nil {
+		logger.Debug("WARNING! getUserSettingsFunc() function not defined!")
+	}
+}
+
+func GetExtraSettings() WireGuardExtraSettings {
+	if funcGetUserSettings != nil {
+		return funcGetUserSettings()
+	}
+	return WireGuardExtraSettings{}
+}
+
 // ConnectionParams contains all information to make new connection
 type ConnectionParams struct {
 	clientLocalIP        net.IP
@@ -54,16 +86,28 @@ type ConnectionParams struct {
 	ipv6Prefix           string
 	multihopExitHostname string // (e.g.: "nl4.wg.ivpn.net") we need it only for informing clients about connection status
 	mtu                  int    // Set 0 to use default MTU value
+
+	// custom WireGuard configuration (nil - connection to IVPN server)
+	// Peer endpoints are already resolved to IP addresses
+	customConfig *CustomConfig
 }
 
 func (cp *ConnectionParams) GetIPv6ClientLocalIP() net.IP {
+	if cp.customConfig != nil {
+		for _, addr := range cp.customConfig.Interface.Addresses {
+			if ip, _, err := net.ParseCIDR(addr); err == nil && ip.To4() == nil {
+				return ip
+			}
+		}
+		return nil
+	}
 	if len(cp.ipv6Prefix) <= 0 {
 		return nil
 	}
 	return net.ParseIP(cp.ipv6Prefix + cp.clientLocalIP.String())
 }
 func (cp *ConnectionParams) GetIPv6HostLocalIP() net.IP {
-	if len(cp.ipv6Prefix) <= 0 {
+	if cp.customConfig != nil || len(cp.ipv6Prefix) <= 0 {
 		return nil
 	}
 	return net.ParseIP(cp.ipv6Prefix + cp.hostLocalIP.String())
@@ -97,6 +141,65 @@ func CreateConnectionParams(
 	}
 }
 
+// CreateConnectionParamsCustom initializing connection parameters object for the custom WireGuard configuration
+// Note: peer endpoints defined by hostnames are resolved here
+func CreateConnectionParamsCustom(cfg CustomConfig) (ConnectionParams, error) {
+	var localIP net.IP
+	for _, addr := range cfg.Interface.Addresses {
+		if ip, _, err := net.ParseCIDR(addr); err == nil && ip.To4() != nil {
+			localIP = ip
+			break
+		}
+	}
+	if localIP == nil {
+		return ConnectionParams{}, fmt.Errorf("custom WireGuard configuration: IPv4 interface address not defined")
+	}
+
+	var dnsIP net.IP
+	for _, ip := range cfg.Interface.DNS {
+		if ip.To4() != nil {
+			dnsIP = ip
+			break
+		}
+	}
+
+	// resolve peer endpoints
+	peers := make([]CustomConfigPeer, 0, len(cfg.Peers))
+	for _, p := range cfg.Peers {
+		host, port := p.EndpointHostPort()
+		ip := net.ParseIP(host)
+		if ip == nil {
+			ips, err := net.LookupIP(host)
+			if err != nil || len(ips) == 0 {
+				return ConnectionParams{}, fmt.Errorf("custom WireGuard configuration: failed to resolve endpoint '%s': %w", host, err)
+			}
+			ip = ips[0]
+			for _, resolved := range ips {
+				if resolved.To4() != nil { // prefer IPv4
+					ip = resolved
+					break
+				}
+			}
+		}
+		p.Endpoint = net.JoinHostPort(ip.String(), strconv.Itoa(port))
+		peers = append(peers, p)
+	}
+	cfg.Peers = peers
+
+	hostIP, hostPort := peers[0].EndpointHostPort()
+
+	return ConnectionParams{
+		clientLocalIP:    localIP,
+		clientPrivateKey: cfg.Interface.PrivateKey,
+		hostPort:         hostPort,
+		hostIP:           net.ParseIP(hostIP),
+		hostPublicKey:    peers[0].PublicKey,
+		hostLocalIP:      dnsIP,
+		mtu:              cfg.Interface.MTU,
+		customConfig:     &cfg,
+	}, nil
+}
+
 // WireGuard structure represents all data of wireguard connection
 type WireGuard struct {
 	binaryPath     string
@@ -117,6 +220,11 @@ func NewWireGuardObject(wgBinaryPath string, wgToolBinaryPath string, wgConfigFi
 	if connectionParams.clientLocalIP == nil || len(connectionParams.clientPrivateKey) == 0 {
 		return nil, fmt.Errorf("WireGuard local credentials not defined")
 	}
+	if connectionParams.customConfig != nil {
+		if err := isCustomConfigSupported(); err != nil {
+			return nil, err
+		}
+	}
 
 	return &WireGuard{
 		binaryPath:     wgBinaryPath,
@@ -134,6 +242,22 @@ func (wg *WireGuard) GetTunnelName() string {
 func (wg *WireGuard) DestinationIP() net.IP {
 	return wg.connectParams.hostIP
 }
+
+// DestinationIPs - Get IP addresses of all remote peers
+// (the custom WireGuard configuration can contain more than one peer)
+func (wg *WireGuard) DestinationIPs() []net.IP {
+	if wg.connectParams.customConfig == nil {
+		return []net.IP{wg.connectParams.hostIP}
+	}
+	ret := make([]net.IP, 0, len(wg.connectParams.customConfig.Peers))
+	for _, p := range wg.connectParams.customConfig.Peers {
+		host, _ := p.EndpointHostPort()
+		if ip := net.ParseIP(host); ip != nil {
+			ret = append(ret, ip)
+		}
+	}
+	return ret
+}
 func (wg *WireGuard) DefaultDNS() net.IP {
 	if wg.isDisconnected {
 		return nil
@@ -142,6 +266,16 @@ func (wg *WireGuard) DefaultDNS() net.IP {
 	return wg.connectParams.hostLocalIP
 }
 
+// Statistics returns current traffic counters of the tunnel
+func (wg *WireGuard) Statistics() (vpn.TunnelStatistics, error) {
+	return GetTunnelStatistics(wg.GetTunnelName())
+}
+
+// IsCustomConfig returns 'true' when the connection is based on the custom WireGuard configuration
+func (wg *WireGuard) IsCustomConfig() bool {
+	return wg.connectParams.customConfig != nil
+}
+
 // Type just returns VPN type
 func (wg *WireGuard) Type() vpn.Type { return vpn.WireGuard }
 
@@ -247,6 +381,13 @@ func (wg *WireGuard) generateAndSaveConfigFile(cfgFilePath string) error {
 	if len(wg.connectParams.presharedKey) > 0 {
 		configToLog = strings.ReplaceAll(configToLog, wg.connectParams.presharedKey, "***")
 	}
+	if wg.connectParams.customConfig != nil {
+		for _, p := range wg.connectParams.customConfig.Peers {
+			if len(p.PresharedKey) > 0 {
+				configToLog = strings.ReplaceAll(configToLog, p.PresharedKey, "***")
+			}
+		}
+	}
 	log.Info("WireGuard  configuration:",
 		"\n=====================\n",
 		configToLog,
@@ -263,6 +404,10 @@ func (wg *WireGuard) generateConfig() ([]string, error) {
 
 	wg.localPort = localPort
 
+	if wg.connectParams.customConfig != nil {
+		return wg.generateCustomConfig()
+	}
+
 	// prevent user-defined data injection: ensure that nothing except the base64 public key will be stored in the configuration
 	if !helpers.ValidateBase64(wg.connectParams.hostPublicKey) {
 		return nil, fmt.Errorf("WG public key is not base64 string")
@@ -296,6 +441,42 @@ func (wg *WireGuard) generateConfig() ([]string, error) {
 	return append(interfaceCfg, peerCfg...), nil
 }
 
+// generateCustomConfig generates configuration based on the custom WireGuard configuration
+// (all values were validated by ParseCustomConfig())
+func (wg *WireGuard) generateCustomConfig() ([]string, error) {
+	cfg := wg.connectParams.customConfig
+	if cfg.Interface.ListenPort > 0 {
+		wg.localPort = cfg.Interface.ListenPort
+	}
+
+	ret := []string{
+		"[Interface]",
+		"PrivateKey = " + cfg.Interface.PrivateKey,
+		"ListenPort = " + strconv.Itoa(wg.localPort)}
+	if cfg.Interface.MTU > 0 {
+		ret = append(ret, fmt.Sprintf("MTU = %d", cfg.Interface.MTU))
+	}
+	// add some OS-specific configurations (if necessary)
+	ret = append(ret, wg.getOSSpecificCustomConfigParams()...)
+
+	for _, p := range cfg.Peers {
+		ret = append(ret,
+			"",
+			"[Peer]",
+			"PublicKey = "+p.PublicKey,
+			"Endpoint = "+p.Endpoint,
+			"AllowedIPs = "+strings.Join(customConfigAllowedIPs(p.AllowedIPs), ", "))
+		if len(p.PresharedKey) > 0 {
+			ret = append(ret, "PresharedKey = "+p.PresharedKey)
+		}
+		if p.PersistentKeepalive > 0 {
+			ret = append(ret, "PersistentKeepalive = "+strconv.Itoa(p.PersistentKeepalive))
+		}
+	}
+
+	return ret, nil
+}
+
 func (wg *WireGuard) waitHandshakeAndNotifyConnected(stateChan chan<- vpn.StateInfo) error {
 	log.Info("Initialised")
 
@@ -644,6 +644,18 @@ func (wg *WireGuard) getOSSpecificConfigParams() (interfaceCfg []string, peerCfg
 	return interfaceCfg, peerCfg
 }
 
+func isCustomConfigSupported() error {
+	return fmt.Errorf("custom WireGuard configuration is not supported on this platform")
+}
+
+func (wg *WireGuard) getOSSpecificCustomConfigParams() (interfaceCfg []string) {
+	return nil
+}
+
+func customConfigAllowedIPs(allowedIPs []string) []string {
+	return allowedIPs
+}
+
 func (wg *WireGuard) isReconnectRequiredOnRoutingChange() bool {
 	return false
 }
@@ -80,6 +80,8 @@ type internalVariables struct {
 	isPaused             atomic.Bool
 	resumeDisconnectChan chan *operationRequest // control connection pause\resume or disconnect from paused state
 	lastOpRequest        *operationRequest
+	isUserspace          bool             // use the embedded userspace implementation instead of 'wg-quick' and kernel module
+	userspaceTunnel      *userspaceTunnel // active userspace tunnel (accessible only from 'connect()' routine)
 }
 
 func (wg *WireGuard) init() error {
@@ -108,6 +110,26 @@ func (wg *WireGuard) init() error {
 		}
 	}
 
+	// choose WireGuard implementation
+	switch backend := GetExtraSettings().Linux_Backend; backend {
+	case LinuxBackendUserspace:
+		wg.internals.isUserspace = true
+	case LinuxBackendKernel:
+		wg.internals.isUserspace = false
+	case LinuxBackendAuto:
+		if err := kernelAvailabilityError(wg.binaryPath, wg.toolBinaryPath); err != nil {
+			log.Info(fmt.Sprintf("WireGuard kernel implementation is not available (%s). Using the userspace implementation", err))
+			wg.internals.isUserspace = true
+		}
+	default:
+		return fmt.Errorf("unknown WireGuard implementation '%s'", backend)
+	}
+	if wg.internals.isUserspace {
+		if err := UserspaceAvailabilityError(); err != nil {
+			return fmt.Errorf("WireGuard userspace implementation is not available: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -135,6 +157,15 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 		default:
 		}
 
+		if wg.internals.isUserspace {
+			// the interface can disappear without disconnection request (e.g. removed by user)
+			if wg.internals.userspaceTunnel != nil {
+				wg.internals.userspaceTunnel.stop()
+				wg.internals.userspaceTunnel = nil
+			}
+			return
+		}
+
 		// do not forget to remove config file after finishing configuration
 		if err := os.Remove(wg.configFilePath); err != nil {
 			log.Warning(fmt.Sprintf("failed to remove WG configuration: %s", err))
@@ -148,6 +179,14 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 		}
 	}
 	internalDisconnectFunc := func() error {
+		if wg.internals.isUserspace {
+			if wg.internals.userspaceTunnel != nil {
+				err := wg.internals.userspaceTunnel.stop()
+				wg.internals.userspaceTunnel = nil
+				return err
+			}
+			return nil
+		}
 		err := shell.Exec(log, wg.binaryPath, "down", wg.configFilePath)
 		if err != nil {
 			return fmt.Errorf("failed to stop WireGuard: %w", err)
@@ -161,24 +200,12 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 	for {
 		isResumeRequested := false
 
-		// generate configuration
-		err := wg.generateAndSaveConfigFile(wg.configFilePath)
-		if err != nil {
-			return fmt.Errorf("failed to save WG config file: %w", err)
-		}
-
 		// start WG
-		log.Info("Shell exec: ", wg.binaryPath, " up ", wg.configFilePath)
-		cmd := exec.Command(wg.binaryPath, "up", wg.configFilePath)
-		outBytes, err := cmd.CombinedOutput()
-		if err != nil {
-			if len(outBytes) > 0 {
-				log.Error(fmt.Sprintf("'%s' error. Output: %s", wg.binaryPath, string(outBytes)))
-			}
-			return fmt.Errorf("failed to start WireGuard: %w", err)
+		if err := wg.start(); err != nil {
+			return err
 		}
 
-		err = func() error {
+		err := func() error {
 			// do not forget to restore DNS
 			defer func() {
 				internalRestoreDNSFunc()
@@ -189,8 +216,8 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 				if err := dns.SetManual(wg.internals.manualDNS, wg.connectParams.clientLocalIP); err != nil {
 					return fmt.Errorf("failed to set manual DNS: %w", err)
 				}
-			} else {
-				dnsIP := dns.DnsSettingsCreate(wg.DefaultDNS())
+			} else if defaultDNS := wg.DefaultDNS(); defaultDNS != nil { // (custom WireGuard configuration may have no DNS defined)
+				dnsIP := dns.DnsSettingsCreate(defaultDNS)
 				if err := dns.SetDefault(dnsIP, wg.connectParams.clientLocalIP); err != nil {
 					return fmt.Errorf("failed to set DNS: %w", err)
 				}
@@ -292,6 +319,35 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 	return nil
 }
 
+// start starts WireGuard interface (using 'wg-quick' or the embedded userspace implementation)
+func (wg *WireGuard) start() error {
+	if wg.internals.isUserspace {
+		t, err := wg.startUserspace()
+		if err != nil {
+			return err
+		}
+		wg.internals.userspaceTunnel = t
+		return nil
+	}
+
+	// generate configuration
+	err := wg.generateAndSaveConfigFile(wg.configFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to save WG config file: %w", err)
+	}
+
+	log.Info("Shell exec: ", wg.binaryPath, " up ", wg.configFilePath)
+	cmd := exec.Command(wg.binaryPath, "up", wg.configFilePath)
+	outBytes, err := cmd.CombinedOutput()
+	if err != nil {
+		if len(outBytes) > 0 {
+			log.Error(fmt.Sprintf("'%s' error. Output: %s", wg.binaryPath, string(outBytes)))
+		}
+		return fmt.Errorf("failed to start WireGuard: %w", err)
+	}
+	return nil
+}
+
 func (wg *WireGuard) doOperation(op operation) error {
 	opr := newOperationRequest(op)
 
@@ -363,7 +419,7 @@ func (wg *WireGuard) resetManualDNS() error {
 		return nil
 	}
 
-	if wg.isRunning() {
+	if wg.isRunning() && wg.DefaultDNS() != nil {
 		// changing DNS to default value for current WireGuard connection
 		return dns.SetDefault(dns.DnsSettingsCreate(wg.DefaultDNS()), wg.connectParams.clientLocalIP)
 	}
@@ -389,6 +445,18 @@ func (wg *WireGuard) getOSSpecificConfigParams() (interfaceCfg []string, peerCfg
 	return interfaceCfg, peerCfg
 }
 
+func isCustomConfigSupported() error {
+	return nil
+}
+
+func (wg *WireGuard) getOSSpecificCustomConfigParams() (interfaceCfg []string) {
+	return []string{"Address = " + strings.Join(wg.connectParams.customConfig.Interface.Addresses, ", ")}
+}
+
+func customConfigAllowedIPs(allowedIPs []string) []string {
+	return allowedIPs
+}
+
 func (wg *WireGuard) onRoutingChanged() error {
 	// do nothing for Linux
 	return nil
@@ -288,7 +288,12 @@ func (wg *WireGuard) resetManualDNS() error {
 		return err // it is not possible set DNS when VPN is not connected
 	}
 
-	err := dns.SetDefault(dns.DnsSettingsCreate(wg.DefaultDNS()), wg.connectParams.clientLocalIP)
+	var err error
+	if defaultDNS := wg.DefaultDNS(); defaultDNS != nil {
+		err = dns.SetDefault(dns.DnsSettingsCreate(defaultDNS), wg.connectParams.clientLocalIP)
+	} else { // custom WireGuard configuration may have no DNS defined
+		err = dns.DeleteManual(nil, wg.connectParams.clientLocalIP)
+	}
 	if err == nil {
 		wg.internals.manualDNS = dns.DnsSettings{}
 	}
@@ -300,18 +305,26 @@ func (wg *WireGuard) getServiceName() string {
 	return "WireGuardTunnel$" + wg.getTunnelName() // WireGuardTunnel$IVPN
 }
 
-func (wg *WireGuard) getOSSpecificConfigParams() (interfaceCfg []string, peerCfg []string) {
+func (wg *WireGuard) getDNSConfigParams() (interfaceCfg []string) {
+	defaultDNS := wg.DefaultDNS()
 	manualDNS := wg.internals.manualDNSRequired
 	if !manualDNS.IsEmpty() {
 		if manualDNS.Encryption == dns.EncryptionNone {
 			interfaceCfg = append(interfaceCfg, "DNS = "+manualDNS.Ip().String())
 		} else {
-			interfaceCfg = append(interfaceCfg, "DNS = "+wg.DefaultDNS().String())
+			if defaultDNS != nil {
+				interfaceCfg = append(interfaceCfg, "DNS = "+defaultDNS.String())
+			}
 			log.Info("(info) The DoH/DoT custom DNS configuration will be applied after connection established")
 		}
-	} else {
-		interfaceCfg = append(interfaceCfg, "DNS = "+wg.DefaultDNS().String())
+	} else if defaultDNS != nil { // (custom WireGuard configuration may have no DNS defined)
+		interfaceCfg = append(interfaceCfg, "DNS = "+defaultDNS.String())
 	}
+	return interfaceCfg
+}
+
+func (wg *WireGuard) getOSSpecificConfigParams() (interfaceCfg []string, peerCfg []string) {
+	interfaceCfg = wg.getDNSConfigParams()
 	if wg.connectParams.mtu > 0 {
 		interfaceCfg = append(interfaceCfg, fmt.Sprintf("MTU = %d", wg.connectParams.mtu))
 	}
@@ -341,6 +354,32 @@ func (wg *WireGuard) getOSSpecificConfigParams() (interfaceCfg []string, peerCfg
 	return interfaceCfg, peerCfg
 }
 
+func isCustomConfigSupported() error {
+	return nil
+}
+
+func (wg *WireGuard) getOSSpecificCustomConfigParams() (interfaceCfg []string) {
+	interfaceCfg = wg.getDNSConfigParams()
+	return append(interfaceCfg, "Address = "+strings.Join(wg.connectParams.customConfig.Interface.Addresses, ", "))
+}
+
+// customConfigAllowedIPs replaces default routes by the equivalent pairs of networks.
+// It disables internal WireGuard-s Firewall (see comments in getOSSpecificConfigParams() for details)
+func customConfigAllowedIPs(allowedIPs []string) []string {
+	ret := make([]string, 0, len(allowedIPs))
+	for _, a := range allowedIPs {
+		switch a {
+		case "0.0.0.0/0":
+			ret = append(ret, "128.0.0.0/1", "0.0.0.0/1")
+		case "::/0":
+			ret = append(ret, "8000::/1", "::/1")
+		default:
+			ret = append(ret, a)
+		}
+	}
+	return ret
+}
+
 func (wg *WireGuard) getServiceStatus(m *mgr.Mgr) (bool, svc.State, error) {
 	service, err := m.OpenService(wg.getServiceName())
 	if err != nil {
@@ -5,6 +5,7 @@ const ServerSelectionEnum = Object.freeze({
   Default: 0, // Server is manually defined
   Fastest: 1, // Fastest server in use (only for 'Entry' server)
   Random: 2, // Random server in use
+  Best: 3, // Best server by score: latency, load, distance and connection history (only for 'Entry' server)
 });
 
 export function InitConnectionParamsObject() {